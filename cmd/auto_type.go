@@ -0,0 +1,74 @@
+package cmd
+
+import "fmt"
+
+// batchSheets are the tabs of the batch sheet in the order --auto-type writes them
+var batchSheets = []string{"Kreditoren", "Debitoren"}
+
+// sheetForType returns the tab of an invoice type: Debitoren for receivables, Kreditoren otherwise
+func sheetForType(invoiceType string) string {
+	if invoiceType == "RECEIVABLE" {
+		return "Debitoren"
+	}
+	return "Kreditoren"
+}
+
+// routeBatchResults sets the tab each result is written to. With a --type every result goes to the
+// tab of that type. With --auto-type (invoiceType "") a file goes to the tab of the type completion
+// detected for it, unless the type confidence is below typeConfidenceMin: such files, files
+// without an extracted invoice and failed files are not routed (Sheet stays empty) and are listed in
+// the review queue instead.
+func routeBatchResults(results []BatchResult, invoiceType string, typeConfidenceMin float32) {
+	for i := range results {
+		result := &results[i]
+		switch {
+		case invoiceType != "":
+			result.Sheet = sheetForType(invoiceType)
+		case unroutedReason(*result, typeConfidenceMin) == "":
+			result.Sheet = sheetForType(result.Invoice.Type)
+		}
+	}
+}
+
+// unroutedReason returns why --auto-type cannot route a result to a tab, "" if it can
+func unroutedReason(result BatchResult, typeConfidenceMin float32) string {
+	switch {
+	case result.Status == "error" || result.Status == "canceled":
+		return "nicht verarbeitet"
+	case result.Invoice == nil:
+		return "keine Rechnung extrahiert"
+	case result.Invoice.Type != "PAYABLE" && result.Invoice.Type != "RECEIVABLE":
+		return "Rechnungstyp nicht erkannt"
+	}
+	if typeConfidence, ok := result.Confidence["type"]; ok && typeConfidence < typeConfidenceMin {
+		return fmt.Sprintf("Rechnungstyp %s unsicher (Konfidenz %.2f)", result.Invoice.Type, typeConfidence)
+	}
+	return ""
+}
+
+// printRouting prints how many files --auto-type routed to each tab and the files it did not route
+func printRouting(results []BatchResult, typeConfidenceMin float32) {
+	counts := map[string]int{}
+	var unrouted []BatchResult
+	for _, result := range results {
+		switch {
+		case result.Sheet != "":
+			if isBooked(result) {
+				counts[result.Sheet]++
+			}
+		case isBooked(result):
+			unrouted = append(unrouted, result)
+		}
+	}
+
+	for _, sheet := range batchSheets {
+		fmt.Printf("%s: %d\n", sheet, counts[sheet])
+	}
+	if len(unrouted) == 0 {
+		return
+	}
+	fmt.Printf("Nicht zugeordnet: %d\n", len(unrouted))
+	for _, result := range unrouted {
+		fmt.Printf("  %s – %s\n", result.Filename, unroutedReason(result, typeConfidenceMin))
+	}
+}
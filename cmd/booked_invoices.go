@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"tools/internal/db"
+	"tools/internal/sheets"
+	"tools/pkg/models"
+)
+
+// bookedInvoices indexes the invoices booked by earlier runs, to skip files of a folder that overlaps
+// with an earlier batch. Each index maps to where the invoice was booked, for the skip message.
+type bookedInvoices struct {
+	byKey  map[string]string // sheets.BookingKey of counterparty and invoice number
+	byHash map[string]string // SHA-256 of the source PDF
+}
+
+// newBookedInvoices creates an empty index
+func newBookedInvoices() *bookedInvoices {
+	return &bookedInvoices{byKey: map[string]string{}, byHash: map[string]string{}}
+}
+
+// add records a booked invoice; empty keys and hashes are ignored and the first source of a key wins
+func (b *bookedInvoices) add(key, hash, source string) {
+	if key != "" {
+		if _, ok := b.byKey[key]; !ok {
+			b.byKey[key] = source
+		}
+	}
+	if hash != "" {
+		if _, ok := b.byHash[hash]; !ok {
+			b.byHash[hash] = source
+		}
+	}
+}
+
+// size returns the number of indexed invoice keys and file hashes
+func (b *bookedInvoices) size() (keys, hashes int) {
+	return len(b.byKey), len(b.byHash)
+}
+
+// fileBooked returns where the file with the SHA-256 was booked before, if it was
+func (b *bookedInvoices) fileBooked(hash string) (string, bool) {
+	if b == nil || hash == "" {
+		return "", false
+	}
+	source, ok := b.byHash[hash]
+	return source, ok
+}
+
+// invoiceBooked returns where an invoice with the same counterparty and invoice number was booked
+// before, if one was. Invoices without a number are only recognized by their file hash.
+func (b *bookedInvoices) invoiceBooked(invoice *models.Invoice) (string, bool) {
+	if b == nil || invoice == nil || strings.TrimSpace(invoice.InvoiceNumber) == "" {
+		return "", false
+	}
+	source, ok := b.byKey[db.RecordID(invoice, "")]
+	return source, ok
+}
+
+// bookedSources parses the --skip-booked flag into whether to index the sheet and the database
+func bookedSources(value string) (fromSheet, fromDB bool, err error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return false, false, nil
+	case "sheet":
+		return true, false, nil
+	case "db":
+		return false, true, nil
+	case "all":
+		return true, true, nil
+	}
+	return false, false, fmt.Errorf("invalid --skip-booked source: %q (must be 'sheet', 'db' or 'all')", value)
+}
+
+// loadBookedFromSheet adds the booked rows of the Kreditoren or Debitoren tab to the index. With
+// followLink, a tab of its own linked in GOOGLE_SHEET_URL is read instead, as the batch writes to it.
+func loadBookedFromSheet(ctx context.Context, booked *bookedInvoices, sheetName string, followLink bool, log zerolog.Logger) error {
+	googleSheetURL := os.Getenv("GOOGLE_SHEET_URL")
+	if googleSheetURL == "" {
+		return withExitCode(ExitConfig, fmt.Errorf("GOOGLE_SHEET_URL environment variable is required for --skip-booked sheet"))
+	}
+
+	sheetsService, err := sheets.NewSheetsService(ctx, googleSheetURL)
+	if err != nil {
+		return fmt.Errorf("failed to create Google Sheets service: %w", err)
+	}
+	if followLink {
+		sheetName, err = linkedSheetTab(ctx, sheetsService, sheetName, log)
+		if err != nil {
+			return withExitCode(ExitExternalAPI, fmt.Errorf("failed to resolve the tab linked in GOOGLE_SHEET_URL: %w", err))
+		}
+	}
+
+	rows, err := sheetsService.BookedRows(ctx, sheetName)
+	if err != nil {
+		return withExitCode(ExitExternalAPI, fmt.Errorf("failed to read booked invoices from Google Sheet: %w", err))
+	}
+	for _, row := range rows {
+		booked.add(row.Key, row.SourceSHA256, fmt.Sprintf("%s (Sheet %s, Zeile %d)", row.Filename, sheetName, row.Row))
+	}
+
+	log.Info().
+		Str("sheet", sheetName).
+		Int("rows", len(rows)).
+		Msg("Indexed invoices booked in the Google Sheet")
+	return nil
+}
+
+// loadBookedFromDB adds the invoices stored in the database to the index
+func loadBookedFromDB(ctx context.Context, booked *bookedInvoices, store *db.Store, log zerolog.Logger) error {
+	if store == nil {
+		return withExitCode(ExitConfig, fmt.Errorf("DB_PATH environment variable is required for --skip-booked db"))
+	}
+
+	records, err := store.Query(ctx, db.Filter{})
+	if err != nil {
+		return fmt.Errorf("failed to read booked invoices from database: %w", err)
+	}
+	for _, record := range records {
+		var key, hash string
+		if record.Invoice != nil && strings.TrimSpace(record.Invoice.InvoiceNumber) != "" {
+			key = db.RecordID(record.Invoice, record.Filename)
+		}
+		if record.Booking != nil {
+			hash = strings.ToLower(record.Booking.SourceSHA256)
+		}
+		booked.add(key, hash, fmt.Sprintf("%s (Datenbank, verarbeitet am %s)", record.Filename, record.ProcessedAt.Local().Format("02.01.2006")))
+	}
+
+	log.Info().
+		Str("database", store.Path()).
+		Int("records", len(records)).
+		Msg("Indexed invoices stored in the database")
+	return nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+	"tools/internal/cache"
+	"tools/internal/logger"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the extraction cache",
+	Long: `Manage the on-disk cache of Document AI and OCR results.
+
+With EXTRACTION_CACHE=true, invoice, datev and ocr store their extraction results keyed by
+the PDF's content hash and reuse them when the same file is processed again. Use --force on
+those commands to re-extract a single file (e.g. after a Document AI processor upgrade) and
+"cache clear" to wipe all entries.
+
+Optional environment variables:
+  EXTRACTION_CACHE - Set to "true" to enable the cache
+  EXTRACTION_CACHE_DIR - Cache directory (default: tax-ai-tools/extractions in the user cache directory)`,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached extractions",
+	Example: `  # Wipe the cache after changing the Document AI processor
+  tools cache clear`,
+	Args: cobra.NoArgs,
+	RunE: runCacheClear,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	log := logger.WithComponent("cache")
+
+	// Clearing works regardless of EXTRACTION_CACHE so a disabled cache can still be removed
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return err
+	}
+
+	if err := cache.NewStore(dir).Clear(); err != nil {
+		return err
+	}
+
+	log.Info().Str("dir", dir).Msg("Extraction cache cleared")
+	fmt.Printf("Cache geleert: %s\n", dir)
+	return nil
+}
+
+// openExtractionCache returns the configured extraction cache, or nil if it is disabled or unavailable
+func openExtractionCache(log zerolog.Logger) *cache.Store {
+	store, err := cache.NewStoreFromEnv()
+	if err != nil {
+		log.Warn().Err(err).Msg("Extraction cache unavailable, processing without cache")
+		return nil
+	}
+	if store != nil {
+		log.Debug().Str("dir", store.Dir()).Msg("Using extraction cache")
+	}
+	return store
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"tools/internal/invoice"
+	"tools/internal/llm"
+	"tools/internal/ocr"
+)
+
+// defaultMaxSystemicFailures is the default of --max-systemic-failures
+const defaultMaxSystemicFailures = 10
+
+// errSystemicFailure marks a batch stopped by the circuit breaker
+var errSystemicFailure = errors.New("systemic failure detected")
+
+// systemicFailureClass returns the class of a failure every further file of the batch would repeat:
+// rejected or missing credentials and exhausted quotas of Document AI, Vision and OpenAI. Failures
+// of a single file, e.g. an unreadable PDF, a timeout or an invalid answer, return "".
+func systemicFailureClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, invoice.ErrInvalidCredentials),
+		errors.Is(err, invoice.ErrMissingCredentials),
+		errors.Is(err, ocr.ErrMissingCredentials):
+		return "Google Cloud credentials"
+	case errors.Is(err, invoice.ErrQuotaExceeded):
+		return "Document AI quota"
+	}
+
+	switch llm.FailureClass(err) {
+	case llm.FailureAuth:
+		return "OpenAI API key"
+	case llm.FailureQuota:
+		return "OpenAI quota"
+	}
+	return ""
+}
+
+// circuitBreaker stops a batch once threshold files in a row failed with the same systemic failure
+// class, instead of sending every remaining file into the same error. A booked file or a failure of
+// another class starts the count over; skipped and canceled files made no requests and are ignored.
+type circuitBreaker struct {
+	threshold int // 0 disables the breaker
+
+	mu    sync.Mutex
+	class string
+	count int
+	err   error
+}
+
+// newCircuitBreaker creates a breaker that trips after threshold consecutive systemic failures
+func newCircuitBreaker(threshold int) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold}
+}
+
+// record counts a finished file. It returns the error that stops the batch when the file trips the
+// breaker, and nil otherwise, also for the files finished after it tripped.
+func (b *circuitBreaker) record(result BatchResult) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return nil
+	}
+
+	switch result.Status {
+	case "success", "warning":
+		b.class, b.count = "", 0
+		return nil
+	case "error":
+	default:
+		return nil
+	}
+
+	class := systemicFailureClass(result.Error)
+	if class != b.class {
+		b.class, b.count = class, 0
+	}
+	if class == "" {
+		return nil
+	}
+
+	b.count++
+	if b.count < b.threshold {
+		return nil
+	}
+	b.err = fmt.Errorf("%w: %d files in a row failed on the %s, last %s: %w", errSystemicFailure, b.count, class, result.Filename, result.Error)
+	return b.err
+}
+
+// tripped returns the error the breaker stopped the batch with, nil if it did not trip
+func (b *circuitBreaker) tripped() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
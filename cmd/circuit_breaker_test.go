@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"tools/internal/invoice"
+)
+
+func TestSystemicFailureClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"none", nil, ""},
+		{"Google credentials", fmt.Errorf("ProcessInvoice: %w", invoice.ErrInvalidCredentials), "Google Cloud credentials"},
+		{"Document AI quota", fmt.Errorf("ProcessInvoice: %w", invoice.ErrQuotaExceeded), "Document AI quota"},
+		{"OpenAI key", fmt.Errorf("GenerateBooking: %w", &openai.APIError{HTTPStatusCode: 401, Code: "invalid_api_key"}), "OpenAI API key"},
+		{"OpenAI quota", fmt.Errorf("GenerateBooking: %w", &openai.APIError{HTTPStatusCode: 429, Code: "insufficient_quota"}), "OpenAI quota"},
+		{"invalid PDF", fmt.Errorf("ProcessInvoice: %w", invoice.ErrInvalidPDF), ""},
+		{"rate limit", &openai.APIError{HTTPStatusCode: 429, Code: "rate_limit_exceeded"}, ""},
+		{"other", errors.New("invalid JSON"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := systemicFailureClass(tt.err); got != tt.want {
+				t.Errorf("systemicFailureClass(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerRecord(t *testing.T) {
+	auth := BatchResult{Status: "error", Error: &openai.APIError{HTTPStatusCode: 401}}
+	quota := BatchResult{Status: "error", Error: fmt.Errorf("ProcessInvoice: %w", invoice.ErrQuotaExceeded)}
+	invalid := BatchResult{Status: "error", Error: invoice.ErrInvalidPDF}
+	success := BatchResult{Status: "success"}
+	warning := BatchResult{Status: "warning"}
+	skipped := BatchResult{Status: "skipped-duplicate"}
+
+	tests := []struct {
+		name      string
+		threshold int
+		results   []BatchResult
+		wantTrip  int // Index of the result that trips the breaker, -1 if it must not trip
+	}{
+		{"trips after threshold failures", 3, []BatchResult{auth, auth, auth, auth}, 2},
+		{"success resets the count", 3, []BatchResult{auth, auth, success, auth, auth}, -1},
+		{"warning resets the count", 3, []BatchResult{quota, quota, warning, quota, quota, quota}, 5},
+		{"class change restarts the count", 3, []BatchResult{auth, auth, quota, quota, auth}, -1},
+		{"class change counts the new class", 2, []BatchResult{auth, quota, quota}, 2},
+		{"non-systemic errors never trip", 2, []BatchResult{invalid, invalid, invalid, invalid}, -1},
+		{"non-systemic error resets the count", 2, []BatchResult{auth, invalid, auth}, -1},
+		{"skipped files are ignored", 2, []BatchResult{auth, skipped, auth}, 2},
+		{"threshold 0 disables the breaker", 0, []BatchResult{auth, auth, auth}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := newCircuitBreaker(tt.threshold)
+			tripped := -1
+			for i, result := range tt.results {
+				result.Filename = fmt.Sprintf("%d.pdf", i)
+				if err := breaker.record(result); err != nil {
+					if tripped >= 0 {
+						t.Fatalf("result %d tripped the breaker again", i)
+					}
+					if !errors.Is(err, errSystemicFailure) {
+						t.Errorf("error %v does not wrap errSystemicFailure", err)
+					}
+					tripped = i
+				}
+			}
+			if tripped != tt.wantTrip {
+				t.Errorf("tripped at %d, want %d", tripped, tt.wantTrip)
+			}
+			if (breaker.tripped() != nil) != (tt.wantTrip >= 0) {
+				t.Errorf("tripped() = %v, want tripped %v", breaker.tripped(), tt.wantTrip >= 0)
+			}
+		})
+	}
+}
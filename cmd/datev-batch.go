@@ -2,9 +2,13 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,7 +16,14 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/rs/zerolog"
+	"tools/internal/booking"
+	"tools/internal/config"
+	"tools/internal/db"
+	"tools/internal/invoice"
+	"tools/internal/ledger"
+	"tools/internal/llm"
 	"tools/internal/logger"
+	"tools/internal/money"
 	"tools/internal/sheets"
 	"tools/pkg/models"
 	"tools/pkg/services"
@@ -31,6 +42,92 @@ The tool writes to different sheets based on invoice type:
 - payable (Eingangsrechnungen) → "Kreditoren" sheet
 - receivable (Ausgangsrechnungen) → "Debitoren" sheet
 
+If GOOGLE_SHEET_URL links to a specific tab (#gid=... as in the browser's address bar),
+the rows are written to that tab instead, unless it is one of the standard tabs
+Kreditoren, Debitoren, Bank or Abgleich.
+
+--auto-type replaces --type for a folder of incoming and outgoing invoices: the
+type ChatGPT detects for each file decides its tab, and both tabs are written at
+the end of the single run (the tab linked in GOOGLE_SHEET_URL is not used). A
+file whose type confidence is below TYPE_CONFIDENCE_MIN (default 0.7) is not
+written to either tab, --ledger-csv, the database or the --control-total but
+listed with the reason in the summary and in --review-queue, like failed files,
+which get no error row either. Give those to
+"tools datev" or a second run with --type.
+
+Progress is printed as files complete. The final summary, the CSV ledger and the
+sheet rows list the files sorted by filename, with failed files in a separate
+section of the summary. The summary ends with the net and VAT totals per tax key
+to check the input VAT before importing into DATEV.
+
+Payment reminders (Mahnungen) are not booked as new invoices: a reminder without a
+dunning fee is marked as skipped, one with a fee is booked with the fee only.
+
+Files with byte-identical content, e.g. a copy or a symlink of another file in the
+folder, are booked only once: every further copy is reported as skipped-duplicate
+before it is sent to OCR, whatever its name.
+
+--skip-booked also skips invoices booked by earlier runs, e.g. when the folder of
+this month overlaps with last month's: "sheet" reads the Kreditoren or Debitoren
+tab, "db" the database at DB_PATH, "all" both. A file booked before is skipped
+before processing; a different file of an invoice booked before (same
+counterparty and invoice number) is recognized once it is extracted. Both are
+reported as skipped-duplicate and not written to the sheet, the database or the
+ledger.
+
+Ctrl-C or the end of --timeout stops the batch: the remaining files and the
+files whose requests were cut off are reported as canceled. The --jsonl, --ledger and
+--review-queue output is written for the processed files, the database and the
+Google Sheet are not updated, and the command exits with code 5.
+
+--max-systemic-failures stops the batch the same way once that many files in a
+row (default 10) failed on the same credentials or quota error, e.g. an expired
+service account key, a revoked OPENAI_API_KEY or a used-up OpenAI or Document AI
+quota: every remaining file would fail the same way and cost a request. No
+further files are started, the message names the failure, and the command exits
+with code 2 for credentials and 4 for quotas. 0 disables the breaker. Completion
+does not retry ChatGPT on these errors either, whether or not the breaker is on.
+
+--processing sync|async|auto applies to every PDF of the folder, see "tools
+invoice --help": sync keeps every file off Cloud Storage, async sends every file
+through it and checks GCS_SOURCE_BUCKET and GCS_OUTPUT_BUCKET before the batch
+starts.
+
+--explicit-vat adds balanced posting lines with the VAT on the Vorsteuer or
+Umsatzsteuer account to every booking, see "tools datev --help". They are kept
+in the --jsonl output and exported by "tools export".
+
+--amount-basis net books the net amounts instead of the gross amounts, for
+--explicit-vat or setups that post the VAT without the tax key, see "tools datev
+--help". With a VAT tax key it would omit VAT in DATEV and is listed as warning.
+
+--reconcile-result takes a file saved with reconcile --save-result. Since --type
+sets the type of the whole folder, a file whose matched payment moves money the
+other way (e.g. an outgoing invoice in a folder of payables) gets a warning.
+
+--sample 10% books a random tenth of the files a second time with --sample-model
+and marks the files as warning where it chose other accounts or another tax key.
+With --auto-type the second model also reads the PDF from scratch, so that a
+different invoice type is flagged too, at the cost of a second extraction. The
+disagreements are listed in the summary and the --review-queue, added to the
+description in the sheet and written to the remarks column of --ledger-csv.
+
+--strict reports invoices without an invoice number as errors instead of booking
+them, so that they are booked by hand, e.g. with "tools datev --set
+invoice-number=...". Without --strict they are booked as a warning.
+
+--review-queue writes the files that need a human look to a CSV file of their
+own, one row per file with every reason: failed files, files booked with
+warnings (missing invoice number or amounts, low type confidence, amounts that do
+not add up, poor scans), files the --sample model booked differently and
+different files of the same invoice. It is the worklist for the bookkeeper
+instead of the ⚠️ rows of the whole sheet, and is written like --ledger-csv also
+on --dry-run and on a canceled batch.
+
+--quiet leaves out the banners, the header and the progress line of every file, e.g.
+for cron jobs: only the summary, the file list, errors and where the results were
+written are printed. The --jsonl output is not affected.
+
 Required environment variables:
   GOOGLE_APPLICATION_CREDENTIALS - Path to service account JSON file, OR
   GOOGLE_CREDENTIALS - Inline JSON credentials string
@@ -42,10 +139,15 @@ Required environment variables:
   GOOGLE_SHEET_URL - Google Sheets URL to write results
 
 Optional environment variables:
-  BATCH_WORKERS - Number of parallel workers (default: 12)`,
+  BATCH_WORKERS - Number of parallel workers (default: 12)
+  OCR_MAX_CONCURRENT - Vision OCR requests at a time, shared by all workers (default: unlimited)
+  DB_PATH - SQLite database the processed invoices are also stored in (see "tools db")`,
 	Example: `  # Process all PDFs as Eingangsrechnungen
   tools datev-batch ./invoices --type payable
 
+  # Mixed folder: route each file to Kreditoren or Debitoren by its detected type
+  tools datev-batch ./invoices --auto-type --review-queue pruefen.csv
+
   # Process as Ausgangsrechnungen with verbose output
   tools datev-batch ./invoices --type receivable --verbose
 
@@ -53,19 +155,87 @@ Optional environment variables:
   tools datev-batch ./invoices --type payable --dry-run
 
   # Use different chart of accounts
-  tools datev-batch ./invoices --type payable --skr 03`,
+  tools datev-batch ./invoices --type payable --skr 03
+
+  # Additionally write a CSV ledger (ISO dates, dot decimals) for other accounting tools
+  tools datev-batch ./invoices --type payable --ledger-csv ledger.csv
+
+  # The same ledger in Windows-1252 for tools that do not read UTF-8
+  tools datev-batch ./invoices --type payable --ledger-csv ledger.csv --ledger-encoding cp1252
+
+  # Collect the files that need a correction in a worklist
+  tools datev-batch ./invoices --type payable --review-queue pruefen.csv
+
+  # Monthly run on a folder that still contains last month's invoices
+  tools datev-batch ./invoices --type payable --skip-booked all
+
+  # Re-run after corrections, replacing existing rows instead of appending
+  tools datev-batch ./invoices --type payable --append-mode update
+
+  # Stream each result as one JSON line as soon as the file is done
+  tools datev-batch ./invoices --type payable --jsonl results.jsonl
+
+  # Nightly cron job: print only the summary and errors
+  tools datev-batch ./invoices --type payable --quiet
+
+  # Large folder of multi-page scans: more time overall and per document
+  tools datev-batch ./invoices --type payable --timeout 3600 --doc-ai-timeout 180
+
+  # Folder of receipts showing only gross totals: split them into net and VAT
+  tools datev-batch ./belege --type payable --infer-vat
+
+  # Spot-check 10% of the files with a second model and flag disagreeing bookings
+  tools datev-batch ./invoices --type payable --sample 10% --sample-model gpt-4o
+
+  # Check the booked gross total against the cover sheet (exit code 6 on deviation)
+  tools datev-batch ./invoices --type payable --control-total 12345.67 --control-tolerance 0.05`,
 	Args: cobra.ExactArgs(1),
 	RunE: runDATEVBatch,
 }
 
 // BatchResult represents the result of processing a single PDF
 type BatchResult struct {
-	Filename  string
-	Invoice   *models.Invoice
-	Booking   *services.DATEVBooking
-	Error     error
-	Status    string // "success", "warning", "error"
-	Index     int    // Original order index
+	Filename    string
+	Invoice     *models.Invoice
+	Booking     *services.DATEVBooking
+	Confidence  map[string]float32 // Per-field extraction confidence
+	Error       error
+	Status      string       // "success", "warning", "skipped", "skipped-duplicate", "error", "canceled"
+	Index       int          // Original order index
+	SampleCheck *SampleCheck // Second-model cross-check, nil if the file was not sampled
+	BookedIn    string       // Where an earlier run booked the invoice, for files skipped with --skip-booked
+	Sheet       string       // Kreditoren or Debitoren tab the result is written to; empty if --auto-type did not route it
+}
+
+// SampleCheck is the booking a second model proposed for a sampled file and where it disagrees
+type SampleCheck struct {
+	Model         string   `json:"model"`
+	Type          string   `json:"type,omitempty"` // Invoice type the second model detected; empty with --type
+	DebitAccount  string   `json:"debit_account,omitempty"`
+	CreditAccount string   `json:"credit_account,omitempty"`
+	TaxKey        string   `json:"tax_key,omitempty"`
+	Disagreements []string `json:"disagreements,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// batchSample holds the files selected for quality assurance and the service running the second model
+type batchSample struct {
+	files       map[int]bool
+	service     services.BookingService
+	model       string
+	invoiceType string // --type; empty with --auto-type, where the second model also detects the type
+	pdfPassword string
+}
+
+// BatchJSONLRecord is the line written to the --jsonl output for each processed file
+type BatchJSONLRecord struct {
+	File        string                 `json:"file"`
+	Status      string                 `json:"status"`
+	Error       string                 `json:"error,omitempty"`
+	Invoice     *InvoiceData           `json:"invoice,omitempty"`
+	Booking     *services.DATEVBooking `json:"booking,omitempty"`
+	Confidence  map[string]float32     `json:"confidence,omitempty"`
+	SampleCheck *SampleCheck           `json:"sample_check,omitempty"`
 }
 
 // WorkerJob represents a PDF processing job
@@ -77,12 +247,38 @@ type WorkerJob struct {
 func init() {
 	rootCmd.AddCommand(datevBatchCmd)
 
-	datevBatchCmd.Flags().String("type", "", "Rechnungstyp (payable=Eingangsrechnungen, receivable=Ausgangsrechnungen) [REQUIRED]")
-	datevBatchCmd.Flags().String("skr", "03", "Kontenrahmen (03=SKR03, 04=SKR04)")
+	datevBatchCmd.Flags().String("type", "", "Rechnungstyp (payable=Eingangsrechnungen, receivable=Ausgangsrechnungen) [REQUIRED unless --auto-type]")
+	datevBatchCmd.Flags().Bool("auto-type", false, "Detect the type per file and write payables to Kreditoren and receivables to Debitoren")
+	datevBatchCmd.Flags().String("skr", "", "Kontenrahmen (03=SKR03, 04=SKR04; default: CHART_OF_ACCOUNTS or 03)")
 	datevBatchCmd.Flags().Bool("dry-run", false, "Process files but don't write to Google Sheet")
 	datevBatchCmd.Flags().Bool("verbose", false, "Show detailed processing information")
+	datevBatchCmd.Flags().Bool("quiet", false, "Print only the summary and errors, without banners and per-file progress")
+	datevBatchCmd.Flags().String("ledger-csv", "", "Write successfully processed invoices to a CSV ledger at this path")
+	datevBatchCmd.Flags().String("ledger-encoding", "utf8", "Encoding of the --ledger-csv file: utf8 or cp1252 (Windows-1252)")
+	datevBatchCmd.Flags().String("review-queue", "", "Write the files that need a human look, with the reasons, to a CSV file at this path")
+	datevBatchCmd.Flags().String("skip-booked", "", "Skip invoices already booked by earlier runs, as recorded in the sheet, db (DB_PATH) or all")
+	datevBatchCmd.Flags().String("append-mode", "append", "How to write rows: append (always add) or update (replace existing rows of the same invoice)")
+	datevBatchCmd.Flags().String("jsonl", "", "Stream each file's result as one JSON object per line to this path while processing")
+	datevBatchCmd.Flags().Int("timeout", 1800, "Overall timeout in seconds for the whole batch")
+	datevBatchCmd.Flags().Int("doc-ai-timeout", 60, "Timeout in seconds for each Document AI request")
+	datevBatchCmd.Flags().Bool("infer-vat", false, "Back-calculate net and VAT for gross-only invoices from the VAT rate in the text or --vat-rate")
+	datevBatchCmd.Flags().Float64("vat-rate", 0, "Assumed VAT rate in percent for --infer-vat (default: ASSUMED_VAT_RATE or 19)")
+	datevBatchCmd.Flags().Bool("deskew", false, "Correct rotated or skewed scans (e.g. photographed receipts) in the completion OCR")
+	datevBatchCmd.Flags().Bool("explicit-vat", false, "Book the VAT as posting lines of its own on the Vorsteuer/Umsatzsteuer accounts (also EXPLICIT_VAT_POSTINGS=true)")
+	datevBatchCmd.Flags().Bool("no-summary", false, "Do not request the AI accounting summary (Kontierungsvorschlag), saving tokens (also ACCOUNTING_SUMMARY=false)")
+	datevBatchCmd.Flags().String("pdf-password", "", "Password for encrypted PDFs (tried before PDF_PASSWORDS)")
+	datevBatchCmd.Flags().String("sample", "", "Cross-check this share of files with a second model, e.g. 10%")
+	datevBatchCmd.Flags().String("sample-model", "gpt-4o", "Model used for the --sample cross-check")
+	datevBatchCmd.Flags().String("control-total", "", "Expected gross total of the booked invoices, e.g. 12345.67; a deviation fails the run")
+	datevBatchCmd.Flags().Float64("control-tolerance", 0, "Allowed deviation from --control-total in EUR")
+	datevBatchCmd.Flags().String("amount-basis", "", "Book the gross or the net amount: gross or net (default: BOOKING_AMOUNT_BASIS or gross)")
+	datevBatchCmd.Flags().Bool("strict", false, "Report invoices without an invoice number as errors instead of booking them")
+	datevBatchCmd.Flags().Int("max-systemic-failures", defaultMaxSystemicFailures, "Abort the batch after this many files in a row failed on credentials or quota (0 disables)")
+	datevBatchCmd.Flags().String("reconcile-result", "", "Check the invoice types against the payments matched in this reconcile --save-result file")
+	addProcessingFlags(datevBatchCmd)
 	
-	datevBatchCmd.MarkFlagRequired("type")
+	datevBatchCmd.MarkFlagsOneRequired("type", "auto-type")
+	datevBatchCmd.MarkFlagsMutuallyExclusive("type", "auto-type")
 }
 
 func runDATEVBatch(cmd *cobra.Command, args []string) error {
@@ -91,66 +287,192 @@ func runDATEVBatch(cmd *cobra.Command, args []string) error {
 	// Get flags
 	folderPath := args[0]
 	invoiceType, _ := cmd.Flags().GetString("type")
+	autoType, _ := cmd.Flags().GetBool("auto-type")
 	skr, _ := cmd.Flags().GetString("skr")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	verbose, _ := cmd.Flags().GetBool("verbose")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	ledgerPath, _ := cmd.Flags().GetString("ledger-csv")
+	ledgerEncodingName, _ := cmd.Flags().GetString("ledger-encoding")
+	reviewQueuePath, _ := cmd.Flags().GetString("review-queue")
+	appendMode, _ := cmd.Flags().GetString("append-mode")
+	skipBooked, _ := cmd.Flags().GetString("skip-booked")
+	jsonlPath, _ := cmd.Flags().GetString("jsonl")
+	timeoutSecs, _ := cmd.Flags().GetInt("timeout")
+	docAITimeoutSecs, _ := cmd.Flags().GetInt("doc-ai-timeout")
+	inferVAT, _ := cmd.Flags().GetBool("infer-vat")
+	vatRate, _ := cmd.Flags().GetFloat64("vat-rate")
+	deskew, _ := cmd.Flags().GetBool("deskew")
+	noSummary, _ := cmd.Flags().GetBool("no-summary")
+	explicitVAT, _ := cmd.Flags().GetBool("explicit-vat")
+	pdfPassword, _ := cmd.Flags().GetString("pdf-password")
+	sampleStr, _ := cmd.Flags().GetString("sample")
+	sampleModel, _ := cmd.Flags().GetString("sample-model")
+	controlTotalStr, _ := cmd.Flags().GetString("control-total")
+	controlTolerance, _ := cmd.Flags().GetFloat64("control-tolerance")
+	reconcileResultPath, _ := cmd.Flags().GetString("reconcile-result")
+	strict, _ := cmd.Flags().GetBool("strict")
+	amountBasis, _ := cmd.Flags().GetString("amount-basis")
+	maxSystemicFailures, _ := cmd.Flags().GetInt("max-systemic-failures")
 
-	// Validate and normalize invoice type
+	// Validate and normalize invoice type; with --auto-type it stays empty and is detected per file
 	invoiceType = strings.ToUpper(invoiceType)
-	if invoiceType != "PAYABLE" && invoiceType != "RECEIVABLE" {
+	if !autoType && invoiceType != "PAYABLE" && invoiceType != "RECEIVABLE" {
 		return fmt.Errorf("invalid invoice type: %s (must be 'payable' or 'receivable')", invoiceType)
 	}
 
-	// Validate SKR parameter
-	if skr != "03" {
-		return fmt.Errorf("only SKR03 is currently supported, got: %s", skr)
+	// --auto-type routes only the files whose type passed the confirmation threshold
+	typeConfidenceMin, err := booking.TypeConfidenceMinFromEnv()
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+
+	// Resolve and validate SKR parameter
+	skr, err = resolveChartOfAccounts(skr)
+	if err != nil {
+		return err
+	}
+
+	if timeoutSecs <= 0 || docAITimeoutSecs <= 0 {
+		return fmt.Errorf("timeouts must be positive")
+	}
+
+	if vatRate < 0 || vatRate >= 100 {
+		return fmt.Errorf("invalid VAT rate: %.2f (must be a percentage, e.g. 19)", vatRate)
+	}
+
+	processingMode, err := processingModeFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	samplePercent, err := parseSamplePercent(sampleStr)
+	if err != nil {
+		return err
+	}
+
+	var controlTotal *int64
+	if controlTotalStr != "" {
+		cents, err := money.ParseCents(controlTotalStr)
+		if err != nil {
+			return fmt.Errorf("invalid control total: %w", err)
+		}
+		controlTotal = &cents
+	}
+	if controlTolerance < 0 {
+		return fmt.Errorf("invalid control tolerance: %.2f (must not be negative)", controlTolerance)
+	}
+	controlToleranceCents := int64(math.Round(controlTolerance * 100))
+
+	ledgerEncoding, err := ledger.ParseEncoding(ledgerEncodingName)
+	if err != nil {
+		return err
+	}
+
+	// Validate append mode
+	appendMode = strings.ToLower(appendMode)
+	if appendMode != "append" && appendMode != "update" {
+		return fmt.Errorf("invalid append mode: %s (must be 'append' or 'update')", appendMode)
+	}
+
+	skipBookedInSheet, skipBookedInDB, err := bookedSources(skipBooked)
+	if err != nil {
+		return err
+	}
+
+	paymentTypes, err := loadPaymentTypes(reconcileResultPath)
+	if err != nil {
+		return err
 	}
 
 	// Validate folder path
 	folderInfo, err := os.Stat(folderPath)
 	if err != nil {
-		return fmt.Errorf("folder not found: %s", folderPath)
+		return withExitCode(ExitInput, fmt.Errorf("folder not found: %s", folderPath))
 	}
 	if !folderInfo.IsDir() {
-		return fmt.Errorf("path is not a directory: %s", folderPath)
+		return withExitCode(ExitInput, fmt.Errorf("path is not a directory: %s", folderPath))
 	}
 
 	log.Info().
 		Str("folder", folderPath).
 		Str("type", invoiceType).
+		Bool("auto_type", autoType).
 		Str("skr", skr).
 		Bool("dry_run", dryRun).
 		Bool("verbose", verbose).
 		Msg("Starting DATEV batch processing")
 
-	// Print header
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Println("                         DATEV BATCH PROCESSING")
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Printf("Ordner: %s\n", folderPath)
-	
 	invoiceTypeGerman := "Eingangsrechnungen"
 	sheetName := "Kreditoren"
 	if invoiceType == "RECEIVABLE" {
 		invoiceTypeGerman = "Ausgangsrechnungen"
 		sheetName = "Debitoren"
 	}
-	fmt.Printf("Typ: %s (%s)\n", invoiceTypeGerman, strings.ToLower(invoiceType))
-	fmt.Printf("Kontenrahmen: SKR%s\n", skr)
-	if dryRun {
-		fmt.Printf("Modus: Dry Run (keine Google Sheets Aktualisierung)\n")
+
+	// Print header
+	if !quiet {
+		fmt.Println(strings.Repeat("=", 80))
+		fmt.Println("                         DATEV BATCH PROCESSING")
+		fmt.Println(strings.Repeat("=", 80))
+		fmt.Printf("Ordner: %s\n", folderPath)
+		if autoType {
+			fmt.Println("Typ: automatisch je Datei (Kreditoren und Debitoren)")
+		} else {
+			fmt.Printf("Typ: %s (%s)\n", invoiceTypeGerman, strings.ToLower(invoiceType))
+		}
+		fmt.Printf("Kontenrahmen: SKR%s\n", skr)
+		if dryRun {
+			fmt.Printf("Modus: Dry Run (keine Google Sheets Aktualisierung)\n")
+		}
+		fmt.Println()
 	}
-	fmt.Println()
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	// Create context with timeout; Ctrl-C cancels the files not started yet
+	ctx, cancel := createContextWithTimeout(timeoutSecs, log)
 	defer cancel()
 
+	// One Document AI processor, Vision client and chat client serve all workers and the sample
+	// service, instead of a new gRPC connection per PDF
+	processor, err := createInvoiceProcessor(ctx, invoice.ProcessorOptions{
+		Timeout: time.Duration(docAITimeoutSecs) * time.Second,
+		Mode:    processingMode,
+	}, log)
+	if err != nil {
+		return err
+	}
+	defer closeInvoiceProcessor(processor, log)
+	ocrService, err := createOCRService(ctx, deskew || os.Getenv("OCR_DESKEW") == "true", log)
+	if err != nil {
+		return err
+	}
+	defer closeOCRService(ocrService, log)
+	llmClient, err := llm.NewClientFromEnv()
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+
 	// Create booking service
-	bookingService, err := createBookingService(ctx, skr, log)
+	bookingOptions := booking.BookingOptions{
+		DocumentAITimeout: time.Duration(docAITimeoutSecs) * time.Second,
+		InferVAT:          inferVAT,
+		AssumedVATRate:    vatRate,
+		Deskew:            deskew,
+		NoSummary:         noSummary,
+		ExplicitVAT:       explicitVAT,
+		Strict:            strict,
+		AmountBasis:       amountBasis,
+		PaymentTypes:      paymentTypes,
+		BookingDatePolicy: config.BookingDatePolicyFromEnv(),
+		Processor:         processor,
+		OCRService:        ocrService,
+		LLMClient:         llmClient,
+	}
+	bookingService, err := createBookingService(ctx, skr, bookingOptions, log)
 	if err != nil {
 		return err
 	}
+	defer bookingService.Close()
 
 	// Find all PDF files
 	pdfFiles, err := findPDFFiles(folderPath)
@@ -163,52 +485,231 @@ func runDATEVBatch(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// Open the invoice database before processing so a wrong DB_PATH fails before any API calls
+	invoiceDB, err := openInvoiceDB()
+	if err != nil {
+		return err
+	}
+	if invoiceDB != nil {
+		defer invoiceDB.Close()
+	}
+
+	// Index the invoices of earlier runs so that overlapping folders are not booked twice
+	var booked *bookedInvoices
+	if skipBookedInSheet || skipBookedInDB {
+		booked = newBookedInvoices()
+		if skipBookedInSheet && autoType {
+			for _, name := range batchSheets {
+				if err := loadBookedFromSheet(ctx, booked, name, false, log); err != nil {
+					return err
+				}
+			}
+		} else if skipBookedInSheet {
+			if err := loadBookedFromSheet(ctx, booked, sheetName, true, log); err != nil {
+				return err
+			}
+		}
+		if skipBookedInDB {
+			if err := loadBookedFromDB(ctx, booked, invoiceDB, log); err != nil {
+				return err
+			}
+		}
+		if !quiet {
+			keys, hashes := booked.size()
+			fmt.Printf("Bereits gebucht: %d Rechnungen, %d Dateien\n", keys, hashes)
+		}
+	}
+
+	// Open the JSONL stream before processing so results land on disk as they complete
+	var jsonlWriter *ledger.JSONLWriter
+	if jsonlPath != "" {
+		jsonlWriter, err = ledger.CreateJSONLFile(jsonlPath)
+		if err != nil {
+			return fmt.Errorf("failed to create JSONL output: %w", err)
+		}
+		defer jsonlWriter.Close()
+	}
+
+	// Select the quality-assurance sample and set up the second model. It books with the same chart and
+	// options as the main run, so disagreements come from the models alone.
+	var sample *batchSample
+	if samplePercent > 0 {
+		sampleOptions := bookingOptions
+		sampleOptions.Model = sampleModel
+		sampleOptions.CompletionModel = sampleModel
+		sampleService, err := booking.NewBookingService(ctx, skr, sampleOptions)
+		if err != nil {
+			return fmt.Errorf("failed to create booking service for sample model %s: %w", sampleModel, err)
+		}
+		defer sampleService.Close()
+		sample = &batchSample{
+			files:       selectSample(len(pdfFiles), samplePercent, rand.New(rand.NewSource(time.Now().UnixNano()))),
+			service:     sampleService,
+			model:       sampleModel,
+			invoiceType: invoiceType,
+			pdfPassword: pdfPassword,
+		}
+		if !quiet {
+			fmt.Printf("Stichprobe: %d Dateien werden zusätzlich mit %s geprüft\n", len(sample.files), sampleModel)
+		}
+	}
+
 	// Get number of workers from environment or use default
 	numWorkers := getNumWorkers()
-	fmt.Printf("Verarbeite %d PDFs mit %d parallelen Workern...\n", len(pdfFiles), numWorkers)
-	fmt.Println()
+	if !quiet {
+		fmt.Printf("Verarbeite %d PDFs mit %d parallelen Workern...\n", len(pdfFiles), numWorkers)
+		fmt.Println()
+	}
 
 	// Process all PDFs in parallel
-	results := processPDFsInParallel(ctx, pdfFiles, invoiceType, bookingService, numWorkers, log, verbose)
+	results, systemicErr := processPDFsInParallel(ctx, pdfFiles, invoiceType, pdfPassword, bookingService, numWorkers, sample, booked, jsonlWriter, newCircuitBreaker(maxSystemicFailures), log, verbose, quiet)
 
-	fmt.Println()
+	if !quiet {
+		fmt.Println()
+	}
+
+	// Progress lines appear in completion order; everything after this point lists files by name
+	results = sortResultsByFilename(results)
+	routeBatchResults(results, invoiceType, typeConfidenceMin)
 
 	// Count results
 	successCount := 0
 	warningCount := 0
+	skippedCount := 0
+	duplicateCount := 0
+	bookedCount := 0
 	errorCount := 0
+	canceledCount := 0
 	for _, result := range results {
 		switch result.Status {
 		case "success":
 			successCount++
 		case "warning":
 			warningCount++
+		case "skipped":
+			skippedCount++
+		case "skipped-duplicate":
+			duplicateCount++
+			if result.BookedIn != "" {
+				bookedCount++
+			}
 		case "error":
 			errorCount++
+		case "canceled":
+			canceledCount++
 		}
 	}
 
 	// Print summary
-	fmt.Println(strings.Repeat("=", 50))
-	fmt.Println("                 ERGEBNIS")
-	fmt.Println(strings.Repeat("=", 50))
+	if !quiet {
+		fmt.Println(strings.Repeat("=", 50))
+		fmt.Println("                 ERGEBNIS")
+		fmt.Println(strings.Repeat("=", 50))
+	}
 	fmt.Printf("Erfolgreich: %d\n", successCount)
 	if warningCount > 0 {
 		fmt.Printf("Mit Warnungen: %d\n", warningCount)
 	}
+	if skippedCount > 0 {
+		fmt.Printf("Übersprungen: %d\n", skippedCount)
+	}
+	if duplicateCount > 0 {
+		fmt.Printf("Duplikate übersprungen: %d\n", duplicateCount)
+		if bookedCount > 0 {
+			fmt.Printf("  davon bereits früher gebucht: %d\n", bookedCount)
+		}
+	}
 	if errorCount > 0 {
 		fmt.Printf("Fehler: %d\n", errorCount)
 	}
+	if canceledCount > 0 {
+		fmt.Printf("Abgebrochen: %d\n", canceledCount)
+	}
+	if autoType {
+		printRouting(results, typeConfidenceMin)
+	}
+	if sample != nil {
+		printSampleReport(results, sample.model)
+	}
 	fmt.Println()
+	printResultList(results)
+	printTaxKeySummary(results)
+	controlOK := true
+	if controlTotal != nil {
+		controlOK = printControlTotal(results, *controlTotal, controlToleranceCents)
+	}
+
+	// Write CSV ledger independently of Google Sheets
+	if ledgerPath != "" {
+		entries := ledgerEntries(results)
+		if err := ledger.WriteFile(ledgerPath, entries, ledgerEncoding); err != nil {
+			return fmt.Errorf("failed to write CSV ledger: %w", err)
+		}
+
+		fmt.Printf("CSV-Ledger: %s (%d Rechnungen)\n", ledgerPath, len(entries))
+		fmt.Println()
+	}
+
+	// The worklist of files that need a correction
+	if reviewQueuePath != "" {
+		queue := buildReviewQueue(results)
+		if err := writeReviewQueue(reviewQueuePath, queue); err != nil {
+			return err
+		}
+
+		fmt.Printf("Prüfliste: %s (%d Dateien)\n", reviewQueuePath, len(queue))
+		fmt.Println()
+	}
+
+	// A batch stopped by the circuit breaker is handled like a canceled one, but names the failure
+	if systemicErr != nil {
+		log.Error().
+			Int("total", len(pdfFiles)).
+			Int("canceled", canceledCount).
+			Msg("DATEV batch aborted on a systemic failure, database and Google Sheet not updated")
+		cmd.SilenceUsage = true
+		return fmt.Errorf("batch aborted, %d of %d files not processed: %w", canceledCount, len(pdfFiles), systemicErr)
+	}
+
+	// An interrupted or timed-out batch keeps its JSONL and CSV output but makes no further requests,
+	// also when it was canceled only after the last file finished
+	if canceledCount > 0 || ctx.Err() != nil {
+		log.Warn().
+			Int("total", len(pdfFiles)).
+			Int("canceled", canceledCount).
+			Msg("DATEV batch processing canceled, database and Google Sheet not updated")
+		cmd.SilenceUsage = true
+		if canceledCount == 0 {
+			return withExitCode(ExitPartialFailure, fmt.Errorf("batch canceled after all files were processed, database and Google Sheet not updated: %w", context.Cause(ctx)))
+		}
+		return withExitCode(ExitPartialFailure, fmt.Errorf("batch canceled, %d of %d files not processed, database and Google Sheet not updated: %w", canceledCount, len(pdfFiles), context.Cause(ctx)))
+	}
+
+	if invoiceDB != nil {
+		records := dbRecords(results)
+		if err := invoiceDB.Upsert(ctx, records...); err != nil {
+			return fmt.Errorf("failed to store invoices in database: %w", err)
+		}
+
+		fmt.Printf("Datenbank: %s (%d Rechnungen)\n", invoiceDB.Path(), len(records))
+		fmt.Println()
+	}
+
+	if jsonlWriter != nil {
+		fmt.Printf("JSONL: %s (%d Dateien)\n", jsonlPath, jsonlWriter.Count())
+		fmt.Println()
+	}
 
 	// Write to Google Sheets if not dry run
 	if !dryRun {
 		googleSheetURL := os.Getenv("GOOGLE_SHEET_URL")
 		if googleSheetURL == "" {
-			return fmt.Errorf("GOOGLE_SHEET_URL environment variable is required")
+			return withExitCode(ExitConfig, fmt.Errorf("GOOGLE_SHEET_URL environment variable is required"))
 		}
 
-		fmt.Println("Schreibe Daten in Google Sheet...")
+		if !quiet {
+			fmt.Println("Schreibe Daten in Google Sheet...")
+		}
 		
 		// Create Google Sheets service
 		sheetsService, err := sheets.NewSheetsService(ctx, googleSheetURL)
@@ -216,38 +717,93 @@ func runDATEVBatch(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to create Google Sheets service: %w", err)
 		}
 
-		// Convert results to sheets format
-		sheetResults := make([]sheets.BatchResult, len(results))
-		for i, result := range results {
-			sheetResults[i] = sheets.BatchResult{
-				Filename: result.Filename,
-				Invoice:  result.Invoice,
-				Booking:  result.Booking,
-				Error:    result.Error,
-				Status:   result.Status,
-			}
+		// With --type all rows go to one tab, with --auto-type Kreditoren and Debitoren are written in turn
+		tabs := []string{sheetName}
+		if autoType {
+			tabs = batchSheets
 		}
+		for _, tab := range tabs {
+			// A link to a tab of its own (#gid=...) replaces the Kreditoren or Debitoren tab of --type
+			target := tab
+			if !autoType {
+				target, err = linkedSheetTab(ctx, sheetsService, tab, log)
+				if err != nil {
+					return withExitCode(ExitExternalAPI, fmt.Errorf("failed to resolve the tab linked in GOOGLE_SHEET_URL: %w", err))
+				}
+			}
 
-		// Write to sheet
-		err = sheetsService.WriteBatchResults(ctx, sheetResults, sheetName)
-		if err != nil {
-			return fmt.Errorf("failed to write to Google Sheet: %w", err)
+			// Convert results to sheets format. Invoices booked by an earlier run already have their row,
+			// which a skip row would only duplicate or, with --append-mode update, overwrite.
+			var sheetResults []sheets.BatchResult
+			bookedRows := 0
+			for _, result := range results {
+				if result.BookedIn != "" || result.Sheet != tab {
+					continue
+				}
+				if isBooked(result) {
+					bookedRows++
+				}
+				sheetResults = append(sheetResults, sheets.BatchResult{
+					Filename:   result.Filename,
+					Invoice:    result.Invoice,
+					Booking:    result.Booking,
+					Error:      result.Error,
+					Status:     result.Status,
+					Confidence: result.Confidence,
+					Remarks:    resultRemarks(result),
+				})
+			}
+
+			// Write to sheet
+			fmt.Printf("Sheet: %s\n", target)
+			if len(sheetResults) == 0 {
+				if autoType {
+					fmt.Println("Zeilen hinzugefügt: 0")
+				} else {
+					fmt.Println("Zeilen hinzugefügt: 0 (alle Rechnungen bereits gebucht)")
+				}
+			} else if appendMode == "update" {
+				updated, appended, err := sheetsService.UpsertBatchResults(ctx, sheetResults, target)
+				if err != nil {
+					return withExitCode(ExitExternalAPI, fmt.Errorf("failed to write to Google Sheet: %w", err))
+				}
+				fmt.Printf("Zeilen aktualisiert: %d\n", updated)
+				fmt.Printf("Zeilen hinzugefügt: %d\n", appended)
+			} else {
+				err = sheetsService.WriteBatchResults(ctx, sheetResults, target)
+				if err != nil {
+					return withExitCode(ExitExternalAPI, fmt.Errorf("failed to write to Google Sheet: %w", err))
+				}
+				fmt.Printf("Zeilen hinzugefügt: %d\n", bookedRows)
+			}
 		}
-		
-		fmt.Printf("Sheet: %s\n", sheetName)
-		fmt.Printf("Zeilen hinzugefügt: %d\n", successCount+warningCount)
 		fmt.Printf("URL: %s\n", googleSheetURL)
 	}
 
-	fmt.Println(strings.Repeat("=", 80))
+	if !quiet {
+		fmt.Println(strings.Repeat("=", 80))
+	}
 
 	log.Info().
 		Int("total", len(pdfFiles)).
 		Int("success", successCount).
 		Int("warnings", warningCount).
+		Int("skipped", skippedCount).
+		Int("skipped_duplicates", duplicateCount).
+		Int("skipped_booked", bookedCount).
 		Int("errors", errorCount).
 		Msg("DATEV batch processing completed")
 
+	// Cron jobs and CI must notice files that need to be re-run
+	if errorCount > 0 {
+		cmd.SilenceUsage = true
+		return withExitCode(ExitPartialFailure, fmt.Errorf("%d of %d files failed", errorCount, len(pdfFiles)))
+	}
+	if !controlOK {
+		cmd.SilenceUsage = true
+		return withExitCode(ExitControlTotal, fmt.Errorf("gross total deviates from control total %s", formatStatsAmount(*controlTotal)))
+	}
+
 	return nil
 }
 
@@ -271,54 +827,38 @@ func findPDFFiles(folderPath string) ([]string, error) {
 }
 
 // processSinglePDF processes a single PDF file and returns the result
-func processSinglePDF(ctx context.Context, pdfPath string, invoiceType string, bookingService services.BookingService, log zerolog.Logger, verbose bool) BatchResult {
+func processSinglePDF(ctx context.Context, pdfPath string, invoiceType string, pdfPassword string, bookingService services.BookingService, log zerolog.Logger, verbose bool) BatchResult {
 	result := BatchResult{
 		Status:   "error",
 	}
 
-	// Open PDF file
-	pdfFile, err := os.Open(pdfPath)
+	// Read PDF file, decrypting it if it is password-protected
+	pdfFile, err := openPDF(pdfPath, pdfPassword)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to open PDF file: %w", err)
+		result.Error = err
 		return result
 	}
-	defer pdfFile.Close()
 
 	// Process with booking service with type override
-	booking, invoice, err := bookingService.GenerateBookingFromPDFWithType(ctx, pdfFile, invoiceType)
+	booking, invoice, confidence, err := bookingService.GenerateBookingFromPDFWithConfidence(ctx, pdfFile, invoiceType)
+	if reason, ok := reminderSkipReason(err); ok {
+		result.Error = errors.New(reason)
+		result.Status = "skipped"
+		return result
+	}
 	if err != nil {
 		result.Error = fmt.Errorf("booking generation failed: %w", err)
 		return result
 	}
 
+	setBookingSource(booking, pdfPath)
 	result.Invoice = invoice
 	result.Booking = booking
+	result.Confidence = confidence
 	result.Status = "success"
 
-	// Check for potential warnings that indicate data quality issues
-	hasWarnings := false
-	
-	// Warning: Missing amounts (both net and VAT are zero)
-	if invoice.NetAmount == 0 && invoice.VATAmount == 0 {
-		hasWarnings = true
-	}
-	
-	// Warning: Missing critical invoice information
-	if invoice.InvoiceNumber == "" {
-		hasWarnings = true
-	}
-	
-	// Warning: No amount information at all
-	if invoice.GrossAmount == 0 {
-		hasWarnings = true
-	}
-	
-	// Warning: Truncated booking text (check if it ends with "...")
-	if strings.HasSuffix(booking.BookingText, "...") {
-		hasWarnings = true
-	}
-	
-	if hasWarnings {
+	// Data quality issues such as missing amounts or a poor scan need a human look
+	if len(reviewReasons(invoice, booking)) > 0 {
 		result.Status = "warning"
 	}
 
@@ -336,6 +876,18 @@ func processSinglePDF(ctx context.Context, pdfPath string, invoiceType string, b
 	return result
 }
 
+// reminderSkipReason returns why a payment reminder without dunning fee was not booked, if err is one
+func reminderSkipReason(err error) (string, bool) {
+	var reminderErr *invoice.ReminderError
+	if !errors.As(err, &reminderErr) {
+		return "", false
+	}
+	if reminderErr.Reference == "" {
+		return "Mahnung ohne Mahngebühr, nicht gebucht (Rechnung bereits erfasst)", true
+	}
+	return fmt.Sprintf("Mahnung zu Rechnung %s ohne Mahngebühr, nicht gebucht (Rechnung bereits erfasst)", reminderErr.Reference), true
+}
+
 // getNumWorkers returns the number of workers from environment or default
 func getNumWorkers() int {
 	if workersStr := os.Getenv("BATCH_WORKERS"); workersStr != "" {
@@ -346,16 +898,61 @@ func getNumWorkers() int {
 	return 12 // Default number of workers
 }
 
-// processPDFsInParallel processes PDFs using a worker pool pattern
-func processPDFsInParallel(ctx context.Context, pdfFiles []string, invoiceType string, bookingService services.BookingService, numWorkers int, log zerolog.Logger, verbose bool) []BatchResult {
+// processPDFsInParallel processes PDFs using a worker pool pattern. Sampled files are cross-checked with the
+// second model right after processing. If jsonlWriter is set, every result is streamed to it as soon as its
+// file is done. Files whose content is byte-identical to an earlier file are not processed and get the
+// status "skipped-duplicate", whatever their name or path. So do files that booked is set for and that
+// an earlier run booked: before processing if the file itself was booked, after extraction if an
+// invoice with the same counterparty and number was.
+func processPDFsInParallel(ctx context.Context, pdfFiles []string, invoiceType string, pdfPassword string, bookingService services.BookingService, numWorkers int, sample *batchSample, booked *bookedInvoices, jsonlWriter *ledger.JSONLWriter, breaker *circuitBreaker, log zerolog.Logger, verbose, quiet bool) ([]BatchResult, error) {
 	// Create job channel and result slice
 	jobs := make(chan WorkerJob, len(pdfFiles))
 	results := make([]BatchResult, len(pdfFiles))
+
+	// Cancellation and the circuit breaker stop the workers from taking jobs; files already started
+	// keep ctx and are finished
+	stopCtx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
 	
 	// Create progress tracking
 	var processedCount int
 	var mu sync.Mutex
 	
+	// finish stores a result, streams it to the JSONL output and shows the progress unless quiet
+	finish := func(result BatchResult) {
+		// Store result in correct position
+		results[result.Index] = result
+
+		if err := breaker.record(result); err != nil {
+			log.Error().Err(err).Msg("Systemic failure detected, no further files are started")
+			fmt.Fprintf(os.Stderr, "Systemischer Fehler erkannt, Batch wird abgebrochen: %v\n", err)
+			stop(errSystemicFailure)
+		}
+
+		if jsonlWriter != nil {
+			if err := jsonlWriter.Write(newBatchJSONLRecord(result)); err != nil {
+				log.Warn().Err(err).Str("file", result.Filename).Msg("Failed to write JSONL record")
+			}
+		}
+
+		// Update progress and show it safely
+		status := getStatusEmoji(result.Status)
+		mu.Lock()
+		defer mu.Unlock()
+		processedCount++
+		if quiet {
+			return
+		}
+		fmt.Printf("[%d/%d] %s - %s", processedCount, len(pdfFiles), result.Filename, status)
+
+		if result.Error != nil {
+			fmt.Printf(" (%s)", result.Error.Error())
+		} else if result.Invoice != nil {
+			fmt.Printf(" (€%.2f)", float64(result.Invoice.GrossAmount)/100)
+		}
+		fmt.Println()
+	}
+
 	// Start workers
 	var wg sync.WaitGroup
 	for w := 0; w < numWorkers; w++ {
@@ -363,44 +960,92 @@ func processPDFsInParallel(ctx context.Context, pdfFiles []string, invoiceType s
 		go func(workerID int) {
 			defer wg.Done()
 			
-			for job := range jobs {
+			for {
+				// Stop taking jobs once the batch is canceled; the remaining ones are marked below
+				var job WorkerJob
+				select {
+				case <-stopCtx.Done():
+					return
+				case next, ok := <-jobs:
+					if !ok {
+						return
+					}
+					job = next
+				}
+				if stopCtx.Err() != nil {
+					finish(canceledBatchResult(job.FilePath, job.Index, context.Cause(stopCtx)))
+					return
+				}
+
 				log.Debug().
 					Int("worker", workerID).
 					Str("file", job.FilePath).
 					Int("index", job.Index+1).
 					Msg("Worker processing PDF")
 
-				result := processSinglePDF(ctx, job.FilePath, invoiceType, bookingService, log, verbose)
+				result := processSinglePDF(ctx, job.FilePath, invoiceType, pdfPassword, bookingService, log, verbose)
+				if result.Status == "error" && ctx.Err() != nil {
+					// The file failed because the batch was interrupted or timed out while it was in flight
+					result = canceledBatchResult(job.FilePath, job.Index, context.Cause(ctx))
+				}
 				result.Index = job.Index
 				result.Filename = filepath.Base(job.FilePath)
-				
-				// Store result in correct position
-				results[job.Index] = result
-				
-				// Update progress safely
-				mu.Lock()
-				processedCount++
-				currentCount := processedCount
-				mu.Unlock()
-				
-				// Show progress
-				status := getStatusEmoji(result.Status)
-				mu.Lock()
-				fmt.Printf("[%d/%d] %s - %s", currentCount, len(pdfFiles), filepath.Base(job.FilePath), status)
-				
-				if result.Error != nil {
-					fmt.Printf(" (%s)", result.Error.Error())
-				} else if result.Invoice != nil {
-					fmt.Printf(" (€%.2f)", float64(result.Invoice.GrossAmount)/100)
+				if source, ok := booked.invoiceBooked(result.Invoice); ok && (result.Status == "success" || result.Status == "warning") {
+					log.Info().
+						Str("file", job.FilePath).
+						Str("invoice_number", result.Invoice.InvoiceNumber).
+						Str("booked_in", source).
+						Msg("Skipping invoice booked by an earlier run")
+					result.Status = "skipped-duplicate"
+					result.Error = fmt.Errorf("Rechnung %s bereits gebucht: %s", result.Invoice.InvoiceNumber, source)
+					result.BookedIn = source
 				}
-				fmt.Println()
-				mu.Unlock()
+
+				if sample != nil && sample.files[job.Index] {
+					crossCheckBooking(ctx, &result, job.FilePath, sample, log)
+				}
+
+				finish(result)
 			}
 		}(w)
 	}
 	
-	// Send jobs
+	// Send jobs, skipping copies of a file already queued before any expensive processing. Unreadable files
+	// are queued anyway so that processing reports the error.
+	seen := make(map[string]string)
 	for i, pdfFile := range pdfFiles {
+		if data, err := os.ReadFile(pdfFile); err == nil {
+			hash := booking.DocumentHash(data)
+			if original, ok := seen[hash]; ok {
+				log.Info().
+					Str("file", pdfFile).
+					Str("original", original).
+					Msg("Skipping byte-identical duplicate")
+				finish(BatchResult{
+					Filename: filepath.Base(pdfFile),
+					Status:   "skipped-duplicate",
+					Error:    fmt.Errorf("identisch mit %s, nicht gebucht", filepath.Base(original)),
+					Index:    i,
+				})
+				continue
+			}
+			if source, ok := booked.fileBooked(hash); ok {
+				log.Info().
+					Str("file", pdfFile).
+					Str("booked_in", source).
+					Msg("Skipping file booked by an earlier run")
+				finish(BatchResult{
+					Filename: filepath.Base(pdfFile),
+					Status:   "skipped-duplicate",
+					Error:    fmt.Errorf("bereits gebucht: %s", source),
+					Index:    i,
+					BookedIn: source,
+				})
+				continue
+			}
+			seen[hash] = pdfFile
+		}
+
 		jobs <- WorkerJob{
 			FilePath: pdfFile,
 			Index:    i,
@@ -410,8 +1055,368 @@ func processPDFsInParallel(ctx context.Context, pdfFiles []string, invoiceType s
 	
 	// Wait for all workers to complete
 	wg.Wait()
+
+	// Jobs left in the queue after a cancellation were never started
+	for job := range jobs {
+		finish(canceledBatchResult(job.FilePath, job.Index, context.Cause(stopCtx)))
+	}
 	
-	return results
+	return results, breaker.tripped()
+}
+
+// canceledBatchResult is the result of a file not processed because the batch was canceled
+func canceledBatchResult(pdfPath string, index int, err error) BatchResult {
+	return BatchResult{
+		Filename: filepath.Base(pdfPath),
+		Status:   "canceled",
+		Error:    fmt.Errorf("nicht verarbeitet, Batch abgebrochen: %w", err),
+		Index:    index,
+	}
+}
+
+// newBatchJSONLRecord converts a batch result into its JSONL representation
+func newBatchJSONLRecord(result BatchResult) BatchJSONLRecord {
+	record := BatchJSONLRecord{
+		File:        result.Filename,
+		Status:      result.Status,
+		Booking:     result.Booking,
+		Confidence:  result.Confidence,
+		SampleCheck: result.SampleCheck,
+	}
+	if result.Error != nil {
+		record.Error = result.Error.Error()
+	}
+	if result.Invoice != nil {
+		record.Invoice = convertToInvoiceData(result.Invoice)
+	}
+	return record
+}
+
+// parseSamplePercent parses the --sample flag, e.g. "10%" or "10". An empty value disables sampling.
+func parseSamplePercent(value string) (float64, error) {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	if value == "" {
+		return 0, nil
+	}
+
+	percent, err := strconv.ParseFloat(value, 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("invalid sample %q (must be a percentage between 0 and 100, e.g. 10%%)", value)
+	}
+	return percent, nil
+}
+
+// selectSample randomly picks percent of n file indices, at least one
+func selectSample(n int, percent float64, rng *rand.Rand) map[int]bool {
+	count := int(math.Ceil(float64(n) * percent / 100))
+	if count > n {
+		count = n
+	}
+
+	selected := make(map[int]bool, count)
+	for _, index := range rng.Perm(n)[:count] {
+		selected[index] = true
+	}
+	return selected
+}
+
+// crossCheckBooking books a sampled invoice again with the second model and marks the result as a warning
+// if the accounts or tax key differ. With --type the extracted invoice is booked again; with --auto-type the
+// second model processes the PDF from scratch, so that a different invoice type is flagged as well.
+func crossCheckBooking(ctx context.Context, result *BatchResult, pdfPath string, sample *batchSample, log zerolog.Logger) {
+	// Only booked results are checked, so that a disagreement cannot turn a skipped duplicate back into a
+	// warning that is written and counted
+	if result.Invoice == nil || result.Booking == nil || (result.Status != "success" && result.Status != "warning") {
+		return
+	}
+
+	check := &SampleCheck{Model: sample.model}
+	result.SampleCheck = check
+
+	reference, referenceInvoice, err := sampleBooking(ctx, result.Invoice, pdfPath, sample)
+	if err != nil {
+		check.Error = err.Error()
+		log.Warn().Err(err).Str("file", result.Filename).Str("model", sample.model).Msg("Sample cross-check failed")
+		return
+	}
+
+	check.DebitAccount = reference.DebitAccount
+	check.CreditAccount = reference.CreditAccount
+	check.TaxKey = reference.TaxKey
+
+	compare := func(label, got, want string) {
+		if got != want {
+			check.Disagreements = append(check.Disagreements, fmt.Sprintf("%s %s ≠ %s", label, got, want))
+		}
+	}
+	compare("Sollkonto", result.Booking.DebitAccount, reference.DebitAccount)
+	compare("Habenkonto", result.Booking.CreditAccount, reference.CreditAccount)
+	compare("Steuerschlüssel", result.Booking.TaxKey, reference.TaxKey)
+	if referenceInvoice != nil {
+		check.Type = referenceInvoice.Type
+		compare("Rechnungstyp", result.Invoice.Type, referenceInvoice.Type)
+	}
+
+	if len(check.Disagreements) > 0 {
+		result.Status = "warning"
+		log.Warn().
+			Str("file", result.Filename).
+			Str("model", sample.model).
+			Strs("disagreements", check.Disagreements).
+			Msg("Sample model disagrees with booking")
+	}
+}
+
+// sampleBooking returns the second model's booking of a sampled file and, with --auto-type, the invoice it
+// extracted; with --type the invoice is not extracted again and nil is returned for it
+func sampleBooking(ctx context.Context, invoice *models.Invoice, pdfPath string, sample *batchSample) (*services.DATEVBooking, *models.Invoice, error) {
+	if sample.invoiceType != "" {
+		booking, err := sample.service.GenerateBooking(ctx, invoice)
+		return booking, nil, err
+	}
+
+	pdfFile, err := openPDF(pdfPath, sample.pdfPassword)
+	if err != nil {
+		return nil, nil, err
+	}
+	booking, referenceInvoice, _, err := sample.service.GenerateBookingFromPDFWithConfidence(ctx, pdfFile, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return booking, referenceInvoice, nil
+}
+
+// sampleRemark describes where the second model disagrees with the booking for the sheet, the ledger and the
+// review queue; empty if the file was not sampled or both models agree
+func sampleRemark(check *SampleCheck) string {
+	if check == nil || len(check.Disagreements) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Stichprobe (%s) weicht ab: %s", check.Model, strings.Join(check.Disagreements, ", "))
+}
+
+// resultRemarks returns the remarks written with a result to the sheet description and the ledger
+func resultRemarks(result BatchResult) []string {
+	if remark := sampleRemark(result.SampleCheck); remark != "" {
+		return []string{remark}
+	}
+	return nil
+}
+
+// printSampleReport prints how many sampled files were cross-checked and where the models disagree
+func printSampleReport(results []BatchResult, model string) {
+	checked, disagreeing := 0, 0
+	for _, result := range results {
+		if result.SampleCheck == nil || result.SampleCheck.Error != "" {
+			continue
+		}
+		checked++
+		if len(result.SampleCheck.Disagreements) > 0 {
+			disagreeing++
+		}
+	}
+
+	fmt.Printf("Stichprobe (%s): %d Dateien geprüft, %d Abweichungen\n", model, checked, disagreeing)
+	for _, result := range results {
+		if result.SampleCheck == nil {
+			continue
+		}
+		if result.SampleCheck.Error != "" {
+			fmt.Printf("  - %s: Prüfung fehlgeschlagen (%s)\n", result.Filename, result.SampleCheck.Error)
+		} else if len(result.SampleCheck.Disagreements) > 0 {
+			fmt.Printf("  - %s: %s\n", result.Filename, strings.Join(result.SampleCheck.Disagreements, ", "))
+		}
+	}
+}
+
+// sortResultsByFilename returns the results sorted by filename so that summaries, ledgers and sheet rows of
+// two runs over the same folder can be compared line by line. Files with the same name in different
+// subfolders keep their folder walk order.
+func sortResultsByFilename(results []BatchResult) []BatchResult {
+	sorted := make([]BatchResult, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Filename < sorted[j].Filename
+	})
+	return sorted
+}
+
+// printResultList prints the outcome of every processed file, followed by a separate section listing
+// the failed files with their errors
+func printResultList(results []BatchResult) {
+	fmt.Println("Dateien:")
+	failed := 0
+	for _, result := range results {
+		if result.Status == "error" {
+			failed++
+			continue
+		}
+		fmt.Printf("  %s %s", getStatusEmoji(result.Status), result.Filename)
+		if result.Invoice != nil {
+			fmt.Printf(" (€%.2f)", float64(result.Invoice.GrossAmount)/100)
+		}
+		if result.Booking != nil && len(result.Booking.Warnings) > 0 {
+			fmt.Printf(" – %s", strings.Join(result.Booking.Warnings, "; "))
+		}
+		if (result.Status == "skipped" || result.Status == "skipped-duplicate" || result.Status == "canceled") && result.Error != nil {
+			fmt.Printf(" – %s", result.Error.Error())
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+
+	if failed == 0 {
+		return
+	}
+	fmt.Println(strings.Repeat("-", 50))
+	fmt.Printf("FEHLER (%d)\n", failed)
+	fmt.Println(strings.Repeat("-", 50))
+	for _, result := range results {
+		if result.Status != "error" {
+			continue
+		}
+		fmt.Printf("  %s %s", getStatusEmoji(result.Status), result.Filename)
+		if result.Error != nil {
+			fmt.Printf(": %s", result.Error.Error())
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+}
+
+// taxKeySummary sums the net and VAT amounts of the bookings with one tax key
+type taxKeySummary struct {
+	taxKey   string
+	count    int
+	netCents int64
+	vatCents int64
+}
+
+// summarizeTaxKeys groups the booked files (success and warning, like printControlTotal) by tax key and
+// returns the totals per key sorted by key together with the total over all files. Split bookings are
+// counted under each of their tax keys; template bookings and bookings without a key are grouped under "".
+func summarizeTaxKeys(results []BatchResult) ([]taxKeySummary, taxKeySummary) {
+	totals := make(map[string]*taxKeySummary)
+	add := func(taxKey string, netCents, vatCents int64) {
+		total := totals[taxKey]
+		if total == nil {
+			total = &taxKeySummary{taxKey: taxKey}
+			totals[taxKey] = total
+		}
+		total.count++
+		total.netCents += netCents
+		total.vatCents += vatCents
+	}
+
+	var overall taxKeySummary
+	for _, result := range results {
+		if !isBooked(result) {
+			continue
+		}
+		overall.count++
+		overall.netCents += result.Invoice.NetAmount
+		overall.vatCents += result.Invoice.VATAmount
+
+		switch {
+		case result.Booking == nil:
+			add("", result.Invoice.NetAmount, result.Invoice.VATAmount)
+		case len(result.Booking.Splits) > 0:
+			for _, split := range result.Booking.Splits {
+				add(split.TaxKey, int64(math.Round(split.NetAmount*100)), int64(math.Round(split.VATAmount*100)))
+			}
+		default:
+			add(result.Booking.TaxKey, result.Invoice.NetAmount, result.Invoice.VATAmount)
+		}
+	}
+
+	summaries := make([]taxKeySummary, 0, len(totals))
+	for _, total := range totals {
+		summaries = append(summaries, *total)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].taxKey < summaries[j].taxKey })
+	return summaries, overall
+}
+
+// printTaxKeySummary prints the net and VAT totals per tax key of the booked files, so the input VAT
+// can be checked before importing into DATEV
+func printTaxKeySummary(results []BatchResult) {
+	summaries, overall := summarizeTaxKeys(results)
+	if overall.count == 0 {
+		return
+	}
+
+	fmt.Println("Steuerschlüssel:")
+	for _, total := range summaries {
+		label := "ohne Schlüssel"
+		if total.taxKey != "" {
+			label = "Schlüssel " + total.taxKey
+			if description := booking.TaxKeyLabel(total.taxKey); description != "" {
+				label += " (" + description + ")"
+			}
+		}
+		fmt.Printf("  %-26s %4d Belege, Netto %15s, MwSt %13s\n", label+":", total.count,
+			formatStatsAmount(total.netCents), formatStatsAmount(total.vatCents))
+	}
+	fmt.Printf("  %-26s %4d Belege, Netto %15s, MwSt %13s\n", "Gesamt:", overall.count,
+		formatStatsAmount(overall.netCents), formatStatsAmount(overall.vatCents))
+	fmt.Println()
+}
+
+// ledgerEntries returns the CSV ledger entries of the recorded results
+func ledgerEntries(results []BatchResult) []ledger.Entry {
+	var entries []ledger.Entry
+	for _, result := range results {
+		if isRecorded(result) {
+			entries = append(entries, ledger.Entry{Invoice: result.Invoice, Booking: result.Booking, Remarks: resultRemarks(result)})
+		}
+	}
+	return entries
+}
+
+// dbRecords returns the database records of the recorded results
+func dbRecords(results []BatchResult) []db.Record {
+	var records []db.Record
+	for _, result := range results {
+		if isRecorded(result) {
+			records = append(records, db.Record{
+				Filename: result.Filename,
+				Status:   result.Status,
+				Invoice:  result.Invoice,
+				Booking:  result.Booking,
+			})
+		}
+	}
+	return records
+}
+
+// recordedGrossCents returns the gross total of the recorded results
+func recordedGrossCents(results []BatchResult) int64 {
+	var totalCents int64
+	for _, result := range results {
+		if isRecorded(result) {
+			totalCents += result.Invoice.GrossAmount
+		}
+	}
+	return totalCents
+}
+
+// printControlTotal compares the gross total of the recorded files (booked with or without warnings
+// and routed to a tab) with the expected control total and reports whether the deviation is within
+// toleranceCents. A mismatch points to invoices missing from the folder or booked twice.
+func printControlTotal(results []BatchResult, controlCents, toleranceCents int64) bool {
+	totalCents := recordedGrossCents(results)
+	deviation := totalCents - controlCents
+	fmt.Println("Kontrollsumme:")
+	fmt.Printf("  %-26s %15s\n", "Brutto gebucht:", formatStatsAmount(totalCents))
+	fmt.Printf("  %-26s %15s\n", "Erwartet:", formatStatsAmount(controlCents))
+	ok := deviation <= toleranceCents && deviation >= -toleranceCents
+	if ok {
+		fmt.Printf("  %-26s %15s ✅\n", "Abweichung:", formatStatsAmount(deviation))
+	} else {
+		fmt.Printf("  %-26s %15s ❌ (Rechnungen fehlen oder sind doppelt erfasst)\n", "Abweichung:", formatStatsAmount(deviation))
+	}
+	fmt.Println()
+	return ok
 }
 
 // getStatusEmoji returns an emoji for the processing status
@@ -421,6 +1426,10 @@ func getStatusEmoji(status string) string {
 		return "✅"
 	case "warning":
 		return "⚠️"
+	case "skipped", "skipped-duplicate":
+		return "⏭️"
+	case "canceled":
+		return "⏹️"
 	case "error":
 		return "❌"
 	default:
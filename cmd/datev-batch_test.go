@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// fakeBookingService returns a fixed booking, and from the PDF also a fixed invoice, and counts the calls
+type fakeBookingService struct {
+	booking  *services.DATEVBooking
+	invoice  *models.Invoice
+	calls    int
+	pdfCalls int
+}
+
+func (f *fakeBookingService) GenerateBooking(ctx context.Context, invoice *models.Invoice) (*services.DATEVBooking, error) {
+	f.calls++
+	return f.booking, nil
+}
+
+func (f *fakeBookingService) GenerateBookingFromPDF(ctx context.Context, pdfData io.Reader) (*services.DATEVBooking, *models.Invoice, error) {
+	return nil, nil, nil
+}
+
+func (f *fakeBookingService) GenerateBookingFromPDFWithType(ctx context.Context, pdfData io.Reader, typeOverride string) (*services.DATEVBooking, *models.Invoice, error) {
+	return nil, nil, nil
+}
+
+func (f *fakeBookingService) GenerateBookingFromPDFWithConfidence(ctx context.Context, pdfData io.Reader, typeOverride string) (*services.DATEVBooking, *models.Invoice, map[string]float32, error) {
+	f.pdfCalls++
+	return f.booking, f.invoice, nil, nil
+}
+
+func (f *fakeBookingService) Close() error { return nil }
+
+func TestCrossCheckBookingSkipsUnbookedResults(t *testing.T) {
+	for _, status := range []string{"skipped-duplicate", "skipped", "error", "canceled"} {
+		t.Run(status, func(t *testing.T) {
+			service := &fakeBookingService{booking: &services.DATEVBooking{DebitAccount: "4980", CreditAccount: "70001", TaxKey: "9"}}
+			result := BatchResult{
+				Filename: "rechnung.pdf",
+				Status:   status,
+				Invoice:  &models.Invoice{Type: "PAYABLE", InvoiceNumber: "RE-1"},
+				Booking:  &services.DATEVBooking{DebitAccount: "4930", CreditAccount: "70001", TaxKey: "9"},
+			}
+
+			crossCheckBooking(context.Background(), &result, "rechnung.pdf", &batchSample{service: service, model: "gpt-4o", invoiceType: "PAYABLE"}, zerolog.Nop())
+
+			if result.Status != status {
+				t.Errorf("status = %q, want %q kept", result.Status, status)
+			}
+			if service.calls != 0 || service.pdfCalls != 0 || result.SampleCheck != nil {
+				t.Errorf("a %s result must not be cross-checked", status)
+			}
+		})
+	}
+}
+
+func TestCrossCheckBookingDisagreement(t *testing.T) {
+	pdfPath := filepath.Join(t.TempDir(), "rechnung.pdf")
+	if err := os.WriteFile(pdfPath, []byte("%PDF-1.4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name              string
+		invoiceType       string
+		reference         services.DATEVBooking
+		referenceType     string
+		wantDisagreements []string
+		wantPDF           bool
+	}{
+		{
+			name:        "agreement",
+			invoiceType: "PAYABLE",
+			reference:   services.DATEVBooking{DebitAccount: "4930", CreditAccount: "70001", TaxKey: "9"},
+		},
+		{
+			name:              "other account and tax key",
+			invoiceType:       "PAYABLE",
+			reference:         services.DATEVBooking{DebitAccount: "4980", CreditAccount: "70001", TaxKey: "8"},
+			wantDisagreements: []string{"Sollkonto 4930 ≠ 4980", "Steuerschlüssel 9 ≠ 8"},
+		},
+		{
+			name:          "auto type agreement",
+			reference:     services.DATEVBooking{DebitAccount: "4930", CreditAccount: "70001", TaxKey: "9"},
+			referenceType: "PAYABLE",
+			wantPDF:       true,
+		},
+		{
+			name:              "auto type other type",
+			reference:         services.DATEVBooking{DebitAccount: "10001", CreditAccount: "8400", TaxKey: "3"},
+			referenceType:     "RECEIVABLE",
+			wantDisagreements: []string{"Sollkonto 4930 ≠ 10001", "Habenkonto 70001 ≠ 8400", "Steuerschlüssel 9 ≠ 3", "Rechnungstyp PAYABLE ≠ RECEIVABLE"},
+			wantPDF:           true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reference := tt.reference
+			service := &fakeBookingService{booking: &reference, invoice: &models.Invoice{Type: tt.referenceType}}
+			result := BatchResult{
+				Filename: "rechnung.pdf",
+				Status:   "success",
+				Invoice:  &models.Invoice{Type: "PAYABLE", InvoiceNumber: "RE-1"},
+				Booking:  &services.DATEVBooking{DebitAccount: "4930", CreditAccount: "70001", TaxKey: "9"},
+			}
+			sample := &batchSample{service: service, model: "gpt-4o", invoiceType: tt.invoiceType}
+
+			crossCheckBooking(context.Background(), &result, pdfPath, sample, zerolog.Nop())
+
+			if (service.pdfCalls > 0) != tt.wantPDF || service.calls+service.pdfCalls != 1 {
+				t.Fatalf("calls = %d, pdfCalls = %d, want the PDF read again: %v", service.calls, service.pdfCalls, tt.wantPDF)
+			}
+			check := result.SampleCheck
+			if check == nil || check.Error != "" {
+				t.Fatalf("sample check = %+v", check)
+			}
+			if !reflect.DeepEqual(check.Disagreements, tt.wantDisagreements) {
+				t.Errorf("disagreements = %q, want %q", check.Disagreements, tt.wantDisagreements)
+			}
+			if check.Type != tt.referenceType {
+				t.Errorf("type = %q, want %q", check.Type, tt.referenceType)
+			}
+
+			wantStatus, wantRemarks := "success", []string(nil)
+			if len(tt.wantDisagreements) > 0 {
+				wantStatus = "warning"
+				wantRemarks = []string{"Stichprobe (gpt-4o) weicht ab: " + strings.Join(tt.wantDisagreements, ", ")}
+			}
+			if result.Status != wantStatus {
+				t.Errorf("status = %q, want %q", result.Status, wantStatus)
+			}
+			if got := resultRemarks(result); !reflect.DeepEqual(got, wantRemarks) {
+				t.Errorf("remarks = %q, want %q", got, wantRemarks)
+			}
+		})
+	}
+}
+
+func TestParseSamplePercent(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "  ", want: 0},
+		{value: "10%", want: 10},
+		{value: "10", want: 10},
+		{value: " 2.5 % ", want: 2.5},
+		{value: "100%", want: 100},
+		{value: "0%", wantErr: true},
+		{value: "-5", wantErr: true},
+		{value: "101%", wantErr: true},
+		{value: "zehn", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSamplePercent(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSamplePercent(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSamplePercent(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectSample(t *testing.T) {
+	tests := []struct {
+		name      string
+		n         int
+		percent   float64
+		wantCount int
+	}{
+		{name: "tenth", n: 50, percent: 10, wantCount: 5},
+		{name: "rounded up", n: 15, percent: 10, wantCount: 2},
+		{name: "at least one", n: 3, percent: 1, wantCount: 1},
+		{name: "all", n: 7, percent: 100, wantCount: 7},
+		{name: "no files", n: 0, percent: 10, wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectSample(tt.n, tt.percent, rand.New(rand.NewSource(1)))
+			if len(got) != tt.wantCount {
+				t.Fatalf("selected %d files, want %d", len(got), tt.wantCount)
+			}
+			for index, selected := range got {
+				if !selected || index < 0 || index >= tt.n {
+					t.Errorf("unexpected selection %d: %v", index, selected)
+				}
+			}
+			// The same seed selects the same files
+			if again := selectSample(tt.n, tt.percent, rand.New(rand.NewSource(1))); !reflect.DeepEqual(again, got) {
+				t.Errorf("selection with the same seed = %v, want %v", again, got)
+			}
+		})
+	}
+}
+
+func TestSummarizeTaxKeys(t *testing.T) {
+	invoice := func(net, vat int64) *models.Invoice {
+		return &models.Invoice{Type: "PAYABLE", NetAmount: net, VATAmount: vat, GrossAmount: net + vat}
+	}
+	results := []BatchResult{
+		{Status: "success", Invoice: invoice(10000, 1900), Booking: &services.DATEVBooking{TaxKey: "9"}},
+		{Status: "warning", Invoice: invoice(5000, 950), Booking: &services.DATEVBooking{TaxKey: "9"}},
+		{Status: "success", Invoice: invoice(3000, 0), Booking: &services.DATEVBooking{}},
+		{Status: "success", Invoice: invoice(20000, 2500), Booking: &services.DATEVBooking{Splits: []services.BookingSplit{
+			{TaxKey: "9", NetAmount: 100, VATAmount: 19},
+			{TaxKey: "8", NetAmount: 100, VATAmount: 7},
+		}}},
+		{Status: "success", Invoice: invoice(4000, 760)},
+		// Not booked: skipped copies, canceled and failed files stay out of the totals
+		{Status: "skipped-duplicate", Invoice: invoice(10000, 1900), Booking: &services.DATEVBooking{TaxKey: "9"}},
+		{Status: "skipped", Invoice: invoice(500, 95), Booking: &services.DATEVBooking{TaxKey: "9"}},
+		{Status: "canceled", Invoice: invoice(700, 133)},
+		{Status: "error", Invoice: invoice(900, 171)},
+		{Status: "success"},
+	}
+
+	summaries, overall := summarizeTaxKeys(results)
+
+	want := []taxKeySummary{
+		{taxKey: "", count: 2, netCents: 7000, vatCents: 760},
+		{taxKey: "8", count: 1, netCents: 10000, vatCents: 700},
+		{taxKey: "9", count: 3, netCents: 25000, vatCents: 4750},
+	}
+	if !reflect.DeepEqual(summaries, want) {
+		t.Errorf("summaries = %+v, want %+v", summaries, want)
+	}
+	wantOverall := taxKeySummary{count: 5, netCents: 42000, vatCents: 6110}
+	if overall != wantOverall {
+		t.Errorf("overall = %+v, want %+v", overall, wantOverall)
+	}
+}
+
+func TestRecordedResultsLeaveOutUnroutedFiles(t *testing.T) {
+	invoice := func(invoiceType string, gross int64) *models.Invoice {
+		return &models.Invoice{Type: invoiceType, GrossAmount: gross}
+	}
+	results := []BatchResult{
+		{Filename: "kreditor.pdf", Status: "success", Invoice: invoice("PAYABLE", 11900), Confidence: map[string]float32{"type": 0.9}},
+		{Filename: "debitor.pdf", Status: "warning", Invoice: invoice("RECEIVABLE", 5950), Confidence: map[string]float32{"type": 0.8}},
+		{Filename: "unsicher.pdf", Status: "success", Invoice: invoice("PAYABLE", 100000), Confidence: map[string]float32{"type": 0.4}},
+		{Filename: "fehler.pdf", Status: "error"},
+	}
+	routeBatchResults(results, "", 0.7)
+
+	var ledgerFiles []string
+	for _, entry := range ledgerEntries(results) {
+		ledgerFiles = append(ledgerFiles, entry.Invoice.Type)
+	}
+	if want := []string{"PAYABLE", "RECEIVABLE"}; !reflect.DeepEqual(ledgerFiles, want) {
+		t.Errorf("ledger entries = %v, want %v", ledgerFiles, want)
+	}
+
+	var dbFiles []string
+	for _, record := range dbRecords(results) {
+		dbFiles = append(dbFiles, record.Filename)
+	}
+	if want := []string{"kreditor.pdf", "debitor.pdf"}; !reflect.DeepEqual(dbFiles, want) {
+		t.Errorf("database records = %v, want %v", dbFiles, want)
+	}
+
+	if got := recordedGrossCents(results); got != 17850 {
+		t.Errorf("recorded gross = %d, want 17850", got)
+	}
+
+	var queued []string
+	for _, item := range buildReviewQueue(results) {
+		queued = append(queued, item.Filename)
+	}
+	if want := []string{"unsicher.pdf", "fehler.pdf"}; !reflect.DeepEqual(queued, want) {
+		t.Errorf("review queue = %v, want %v", queued, want)
+	}
+}
+
+// cancelingBookingService cancels the batch while the file is in flight, like Ctrl-C during a request
+type cancelingBookingService struct {
+	fakeBookingService
+	cancel context.CancelFunc
+}
+
+func (c *cancelingBookingService) GenerateBookingFromPDFWithConfidence(ctx context.Context, pdfData io.Reader, typeOverride string) (*services.DATEVBooking, *models.Invoice, map[string]float32, error) {
+	c.cancel()
+	return nil, nil, nil, ctx.Err()
+}
+
+func TestProcessPDFsInParallelCancelsInFlightFiles(t *testing.T) {
+	pdfPath := filepath.Join(t.TempDir(), "rechnung.pdf")
+	if err := os.WriteFile(pdfPath, []byte("%PDF-1.4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := &cancelingBookingService{cancel: cancel}
+
+	results, err := processPDFsInParallel(ctx, []string{pdfPath}, "PAYABLE", "", service, 1, nil, nil, nil, newCircuitBreaker(0), zerolog.Nop(), false, true)
+	if err != nil {
+		t.Fatalf("processPDFsInParallel() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Status != "canceled" {
+		t.Fatalf("results = %+v, want one canceled file", results)
+	}
+	if !strings.Contains(results[0].Error.Error(), "Batch abgebrochen") {
+		t.Errorf("error = %v, want the cancellation named", results[0].Error)
+	}
+}
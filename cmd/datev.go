@@ -2,15 +2,20 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/rs/zerolog"
 	"tools/internal/booking"
+	"tools/internal/config"
+	"tools/internal/db"
+	"tools/internal/invoice"
+	"tools/internal/llm"
 	"tools/internal/logger"
 	"tools/pkg/models"
 	"tools/pkg/services"
@@ -32,6 +37,63 @@ The tool uses ChatGPT to determine the correct:
 - Cost centers (Kostenstellen)
 - Accounting explanations
 
+With --allow-no-booking a failed ChatGPT booking (e.g. OpenAI unavailable) no
+longer fails the command: the extracted invoice is printed with a template
+booking whose accounts and tax key are left blank for manual completion
+("template": true in the JSON output).
+
+--verbose also lists the fields the ChatGPT completion filled in or overwrote
+in the Document AI extraction ("completion_changes" in the JSON output).
+
+--set field=value corrects a field of the extracted invoice before booking, e.g.
+when Document AI misreads the vendor or a date. It can be given several times.
+Amounts are given in cents (11900) or in EUR with two decimals (119,00), dates
+as YYYY-MM-DD. Supported fields: invoice-number, vendor, customer, vendor-vat-id,
+customer-vat-id, purchase-order, customer-reference, description, currency,
+issue-date, due-date, service-date, net, vat, gross. Use --type for the
+invoice type.
+
+--strict fails instead of booking an invoice without an invoice number (after
+the Document AI fallback extraction and completion), so that it is entered by
+hand, e.g. with --set invoice-number=... Without --strict such an invoice is
+booked without Belegfeld 1 and gets an ID derived from the document content,
+which stays the same on every run.
+
+--dump-prompt runs the Document AI extraction and the OCR, then prints the
+system and user prompts that would be sent to ChatGPT instead of sending them.
+Nothing is booked or stored. Since no completion answer is available, the
+booking prompt is built from the Document AI extraction alone.
+
+--explicit-vat books the VAT on lines of its own instead of leaving it to the
+tax key: the net amount per rate on the expense or revenue account, the VAT on
+the Vorsteuer (1571/1576) or Umsatzsteuer (1771/1776) account and the gross
+amount on the other account, so Soll and Haben balance ("postings" in the JSON
+output). Use it when the tax key does not post the VAT in your DATEV setup.
+
+--amount-basis gross|net selects which amount the booking carries. DATEV reads
+the amount of a line with a VAT tax key (e.g. 9 or 3) as gross and derives the
+VAT from it, so gross (the default) is right for bookings with automatic tax
+keys. net books the net amount and is only right together with --explicit-vat
+or a setup that posts the VAT otherwise; with a VAT tax key DATEV would omit
+part of the VAT, so such bookings get a warning.
+
+--processing sync|async|auto selects how the PDF is sent to Document AI, see
+"tools invoice --help". async needs GCS_SOURCE_BUCKET and GCS_OUTPUT_BUCKET and
+fails right away without them.
+
+--lang en prints the console labels and messages in English and asks ChatGPT
+for an English accounting summary. Account names, tax key descriptions and the
+booking text stay German, as DATEV expects them.
+
+--reconcile-result takes a file saved with reconcile --save-result. If a
+payment was matched to the invoice there, its direction sets the invoice type
+(outgoing → payable, incoming → receivable, reversed for credit notes),
+overriding ChatGPT's classification; a correction is listed as a warning.
+A type given with --type is kept, but a contradicting payment is reported.
+
+--quiet leaves out the header and footer banners of the console output. The
+--json output is not affected.
+
 Required environment variables:
   GOOGLE_APPLICATION_CREDENTIALS - Path to service account JSON file, OR
   GOOGLE_CREDENTIALS - Inline JSON credentials string
@@ -39,7 +101,11 @@ Required environment variables:
   GOOGLE_CLOUD_LOCATION - Processing location (us, eu, etc.)
   DOCUMENT_AI_PROCESSOR_ID - Your Document AI invoice processor ID
   OPENAI_API_KEY - OpenAI API key for ChatGPT
-  COMPANY_NAME - Your company name for invoice type determination`,
+  COMPANY_NAME - Your company name for invoice type determination
+
+Optional environment variables:
+  DB_PATH - SQLite database the invoice and booking are also stored in (see "tools db")
+  OUTPUT_LANGUAGE - Default for --lang (de or en)`,
 	Example: `  # Generate DATEV booking from PDF (console output)
   tools datev invoice.pdf
 
@@ -54,7 +120,34 @@ Required environment variables:
   tools datev invoice.pdf --type receivable  # Ausgangsrechnung
 
   # Use different chart of accounts (future feature)
-  tools datev invoice.pdf --skr 04`,
+  tools datev invoice.pdf --skr 04
+
+  # Allow more time for large scans
+  tools datev large-invoice.pdf --timeout 600
+
+  # Receipt showing only the gross total: split it into net and VAT at 7%
+  tools datev kassenbon.pdf --infer-vat --vat-rate 7
+
+  # Keep the extracted invoice even if OpenAI is down
+  tools datev invoice.pdf --allow-no-booking
+
+  # Correct misread fields before booking
+  tools datev invoice.pdf --set vendor="ACME GmbH" --set gross=11900 --set issue-date=2024-06-01
+
+  # Book net amounts with the VAT on posting lines of their own
+  tools datev invoice.pdf --amount-basis net --explicit-vat
+
+  # Never book an invoice without its invoice number
+  tools datev invoice.pdf --strict
+
+  # Take the invoice type from the payment matched by an earlier reconcile run
+  tools datev invoice.pdf --reconcile-result juni.json
+
+  # Check whether a missing field was misread by OCR or missed by ChatGPT
+  tools datev invoice.pdf --verbose --include-raw-text --dump-ocr invoice.txt
+
+  # Inspect the prompts without paying for the ChatGPT requests
+  tools datev invoice.pdf --dump-prompt`,
 	Args: cobra.ExactArgs(1),
 	RunE: runDatev,
 }
@@ -62,10 +155,29 @@ Required environment variables:
 func init() {
 	rootCmd.AddCommand(datevCmd)
 
-	datevCmd.Flags().String("skr", "03", "Kontenrahmen (03=SKR03, 04=SKR04)")
+	datevCmd.Flags().String("skr", "", "Kontenrahmen (03=SKR03, 04=SKR04; default: CHART_OF_ACCOUNTS or 03)")
 	datevCmd.Flags().String("type", "", "Rechnungstyp (payable=Eingangsrechnung, receivable=Ausgangsrechnung)")
 	datevCmd.Flags().Bool("json", false, "Output as JSON format")
 	datevCmd.Flags().Bool("verbose", false, "Show detailed explanation and reasoning")
+	datevCmd.Flags().Bool("quiet", false, "Leave out the decorative banners of the console output")
+	datevCmd.Flags().Int("timeout", 300, "Processing timeout in seconds (also used for the Document AI request)")
+	datevCmd.Flags().Bool("infer-vat", false, "Back-calculate net and VAT for gross-only invoices from the VAT rate in the text or --vat-rate")
+	datevCmd.Flags().Float64("vat-rate", 0, "Assumed VAT rate in percent for --infer-vat (default: ASSUMED_VAT_RATE or 19)")
+	datevCmd.Flags().Bool("deskew", false, "Correct rotated or skewed scans (e.g. photographed receipts) in the completion OCR")
+	datevCmd.Flags().Bool("include-raw-text", false, "Include the OCR text completion worked on in the JSON and --verbose output")
+	datevCmd.Flags().String("dump-ocr", "", "Write the OCR text completion worked on to this file")
+	datevCmd.Flags().Bool("dump-prompt", false, "Print the prompts that would be sent to ChatGPT and exit without sending them")
+	datevCmd.Flags().Bool("explicit-vat", false, "Book the VAT as posting lines of its own on the Vorsteuer/Umsatzsteuer accounts (also EXPLICIT_VAT_POSTINGS=true)")
+	datevCmd.Flags().Bool("no-summary", false, "Do not request the AI accounting summary (Kontierungsvorschlag), saving tokens (also ACCOUNTING_SUMMARY=false)")
+	datevCmd.Flags().String("pdf-password", "", "Password for encrypted PDFs (tried before PDF_PASSWORDS)")
+	datevCmd.Flags().Bool("force", false, "Re-extract the PDF even if a cached result exists and overwrite the cache entry")
+	datevCmd.Flags().Bool("allow-no-booking", false, "Output the extracted invoice with a blank template booking if the AI booking fails")
+	datevCmd.Flags().StringArray("set", nil, "Override an extracted invoice field before booking (field=value, repeatable)")
+	datevCmd.Flags().Bool("strict", false, "Fail instead of booking an invoice without an invoice number")
+	datevCmd.Flags().String("amount-basis", "", "Book the gross or the net amount: gross or net (default: BOOKING_AMOUNT_BASIS or gross)")
+	addProcessingFlags(datevCmd)
+	datevCmd.Flags().String("reconcile-result", "", "Confirm the invoice type from the payment matched in this reconcile --save-result file")
+	datevCmd.Flags().String("lang", "", "Language of the console output and accounting summary: de or en (default: OUTPUT_LANGUAGE or de)")
 }
 
 func runDatev(cmd *cobra.Command, args []string) error {
@@ -76,20 +188,59 @@ func runDatev(cmd *cobra.Command, args []string) error {
 	invoiceType, _ := cmd.Flags().GetString("type")
 	jsonOutput, _ := cmd.Flags().GetBool("json")
 	verbose, _ := cmd.Flags().GetBool("verbose")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	timeoutSecs, _ := cmd.Flags().GetInt("timeout")
+	inferVAT, _ := cmd.Flags().GetBool("infer-vat")
+	vatRate, _ := cmd.Flags().GetFloat64("vat-rate")
+	deskew, _ := cmd.Flags().GetBool("deskew")
+	noSummary, _ := cmd.Flags().GetBool("no-summary")
+	explicitVAT, _ := cmd.Flags().GetBool("explicit-vat")
+	includeRawText, _ := cmd.Flags().GetBool("include-raw-text")
+	dumpOCRPath, _ := cmd.Flags().GetString("dump-ocr")
+	dumpPrompt, _ := cmd.Flags().GetBool("dump-prompt")
+	pdfPassword, _ := cmd.Flags().GetString("pdf-password")
+	force, _ := cmd.Flags().GetBool("force")
+	allowNoBooking, _ := cmd.Flags().GetBool("allow-no-booking")
+	overrideSpecs, _ := cmd.Flags().GetStringArray("set")
+	strict, _ := cmd.Flags().GetBool("strict")
+	amountBasis, _ := cmd.Flags().GetString("amount-basis")
+	lang, _ := cmd.Flags().GetString("lang")
+	reconcileResultPath, _ := cmd.Flags().GetString("reconcile-result")
 
 	pdfPath := args[0]
 
+	// Resolve and validate SKR parameter
+	skr, err := resolveChartOfAccounts(skr)
+	if err != nil {
+		return err
+	}
+
 	log.Info().
 		Str("file", pdfPath).
 		Str("skr", skr).
 		Str("type", invoiceType).
 		Bool("json", jsonOutput).
 		Bool("verbose", verbose).
+		Int("timeout", timeoutSecs).
+		Strs("overrides", overrideSpecs).
 		Msg("Starting DATEV booking generation")
 
-	// Validate SKR parameter
-	if skr != "03" {
-		return fmt.Errorf("only SKR03 is currently supported, got: %s", skr)
+	if timeoutSecs <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+
+	if vatRate < 0 || vatRate >= 100 {
+		return fmt.Errorf("invalid VAT rate: %.2f (must be a percentage, e.g. 19)", vatRate)
+	}
+
+	lang, err = resolveLanguage(lang)
+	if err != nil {
+		return err
+	}
+
+	processingMode, err := processingModeFlag(cmd)
+	if err != nil {
+		return err
 	}
 
 	// Validate invoice type parameter if provided
@@ -100,36 +251,77 @@ func runDatev(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Validate field overrides before any API call
+	fieldOverrides, err := booking.ParseFieldOverrides(overrideSpecs)
+	if err != nil {
+		return withExitCode(ExitInput, fmt.Errorf("invalid --set: %w", err))
+	}
+	paymentTypes, err := loadPaymentTypes(reconcileResultPath)
+	if err != nil {
+		return err
+	}
+
 	// Validate and get file info
 	fileInfo, err := validateDatevPDFFile(pdfPath, log)
 	if err != nil {
-		return err
+		return withExitCode(ExitInput, err)
 	}
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	timeout := time.Duration(timeoutSecs) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	// --dump-prompt records the ChatGPT requests instead of sending them
+	var promptRecorder *llm.PromptRecorder
+	var llmClient llm.LLMClient
+	if dumpPrompt {
+		promptRecorder = llm.NewPromptRecorder()
+		llmClient = promptRecorder
+	}
+
 	// Create booking service
-	bookingService, err := createBookingService(ctx, skr, log)
+	bookingService, err := createBookingService(ctx, skr, booking.BookingOptions{
+		DocumentAITimeout: timeout,
+		DocumentAIMode:    processingMode,
+		InferVAT:          inferVAT,
+		AssumedVATRate:    vatRate,
+		Cache:             openExtractionCache(log),
+		ForceExtraction:   force,
+		Deskew:            deskew,
+		AllowNoBooking:    allowNoBooking,
+		FieldOverrides:    fieldOverrides,
+		Strict:            strict,
+		AmountBasis:       amountBasis,
+		PaymentTypes:      paymentTypes,
+		SummaryLanguage:   lang,
+		NoSummary:         noSummary,
+		ExplicitVAT:       explicitVAT,
+		IncludeRawText:    includeRawText || dumpOCRPath != "",
+		LLMClient:         llmClient,
+	}, log)
+	if err != nil {
+		return err
+	}
+	defer bookingService.Close()
+
+	invoiceDB, err := openInvoiceDB()
 	if err != nil {
 		return err
 	}
+	if invoiceDB != nil {
+		defer invoiceDB.Close()
+	}
 
-	// Open PDF file
-	pdfFile, err := os.Open(pdfPath)
+	// Read PDF file, decrypting it if it is password-protected
+	pdfFile, err := openPDF(pdfPath, pdfPassword)
 	if err != nil {
 		log.Error().
 			Err(err).
 			Str("file", pdfPath).
 			Msg("Failed to open PDF file")
-		return fmt.Errorf("failed to open PDF file: %w", err)
+		return err
 	}
-	defer func() {
-		if closeErr := pdfFile.Close(); closeErr != nil {
-			log.Warn().Err(closeErr).Msg("Failed to close PDF file")
-		}
-	}()
 
 	log.Info().
 		Str("file", pdfPath).
@@ -146,9 +338,23 @@ func runDatev(cmd *cobra.Command, args []string) error {
 	} else {
 		booking, invoice, err = bookingService.GenerateBookingFromPDF(ctx, pdfFile)
 	}
+	// The recorder fails every request, so the booking usually fails after recording its prompt
+	if promptRecorder != nil && (err == nil || len(promptRecorder.Requests()) > 0) {
+		return printRecordedPrompts(promptRecorder, log)
+	}
 	if err != nil {
 		return handleDatevError(err, log)
 	}
+	setBookingSource(booking, pdfPath)
+
+	if dumpOCRPath != "" {
+		if err := dumpOCRText(dumpOCRPath, invoice.OCRText, log); err != nil {
+			return err
+		}
+	}
+	if !includeRawText {
+		invoice.OCRText = ""
+	}
 
 	processingDuration := time.Since(startTime)
 
@@ -157,15 +363,61 @@ func runDatev(cmd *cobra.Command, args []string) error {
 		Str("debit_account", booking.DebitAccount).
 		Str("credit_account", booking.CreditAccount).
 		Float64("amount", booking.Amount).
+		Bool("template", booking.Template).
 		Dur("duration", processingDuration).
 		Msg("DATEV booking generated successfully")
 
+	if invoiceDB != nil {
+		status := "success"
+		if len(booking.Warnings) > 0 || booking.Template {
+			status = "warning"
+		}
+		record := db.Record{Filename: filepath.Base(pdfPath), Status: status, Invoice: invoice, Booking: booking}
+		if err := invoiceDB.Upsert(ctx, record); err != nil {
+			return fmt.Errorf("failed to store invoice in database: %w", err)
+		}
+	}
+
 	// Output results
 	if jsonOutput {
 		return outputDatevJSON(booking, invoice, processingDuration)
 	} else {
-		return outputDatevConsole(booking, invoice, verbose, processingDuration)
+		return outputDatevConsole(booking, invoice, verbose, quiet, processingDuration, datevCatalog[lang])
+	}
+}
+
+// printCompletionChanges shows which fields ChatGPT completion filled in or overwrote in the
+// Document AI extraction
+func printCompletionChanges(changes []models.FieldChange, m datevMessages) {
+	fmt.Println(m.ChangesSection)
+	if len(changes) == 0 {
+		fmt.Println(m.NoChanges)
+		fmt.Println()
+		return
+	}
+
+	for _, change := range changes {
+		before := change.Before
+		if before == "" {
+			before = m.EmptyValue
+		}
+		kind := m.ChangeOverwritten
+		if change.Kind == models.FieldFilled {
+			kind = m.ChangeFilled
+		}
+		fmt.Printf("  %-20s %s -> %s (%s)\n", change.Field, truncateChange(before), truncateChange(change.After), kind)
+	}
+	fmt.Println()
+}
+
+// truncateChange shortens long values such as the accounting summary for the change list
+func truncateChange(value string) string {
+	const maxLength = 50
+	runes := []rune(value)
+	if len(runes) <= maxLength {
+		return value
 	}
+	return string(runes[:maxLength-3]) + "..."
 }
 
 // validateDatevPDFFile validates the PDF file for DATEV processing
@@ -197,10 +449,10 @@ func validateDatevPDFFile(pdfPath string, log zerolog.Logger) (os.FileInfo, erro
 	}
 
 	// Check file extension
-	if !strings.HasSuffix(strings.ToLower(pdfPath), ".pdf") {
+	if !hasDocumentExtension(pdfPath) {
 		log.Warn().
 			Str("file", pdfPath).
-			Msg("File does not have .pdf extension")
+			Msg("File does not have a .pdf or .tif extension")
 	}
 
 	// Check file size
@@ -214,32 +466,51 @@ func validateDatevPDFFile(pdfPath string, log zerolog.Logger) (os.FileInfo, erro
 	return fileInfo, nil
 }
 
-// createBookingService creates the appropriate booking service based on SKR type
-func createBookingService(ctx context.Context, skr string, log zerolog.Logger) (services.BookingService, error) {
-	switch skr {
-	case "03":
-		service, err := booking.NewSKR03BookingService(ctx)
+// resolveChartOfAccounts returns the --skr value in two-digit form. Without --skr the chart comes
+// from the loaded configuration (CHART_OF_ACCOUNTS), falling back to SKR03.
+func resolveChartOfAccounts(skr string) (string, error) {
+	if skr != "" {
+		chart, err := booking.ParseChartOfAccounts(skr)
 		if err != nil {
-			if strings.Contains(err.Error(), "OPENAI_API_KEY") {
-				log.Error().
-					Err(err).
-					Msg("OpenAI API key not configured")
-				return nil, fmt.Errorf("missing OpenAI API key. Please set:\\n" +
-					"  OPENAI_API_KEY=your-openai-api-key\\n" +
-					"Original error: %w", err)
-			}
-			log.Error().
-				Err(err).
-				Msg("Failed to create SKR03 booking service")
-			return nil, fmt.Errorf("failed to create SKR03 booking service: %w", err)
+			return "", fmt.Errorf("--skr: %w", err)
 		}
+		return chart, nil
+	}
 
-		log.Debug().Msg("SKR03 booking service created successfully")
-		return service, nil
+	if loadedConfig == nil {
+		if loadConfigErr != nil {
+			// config.Load only fails without a config on an unsupported CHART_OF_ACCOUNTS
+			return "", withExitCode(ExitConfig, loadConfigErr)
+		}
+		return "03", nil
+	}
+	return loadedConfig.ChartOfAccounts, nil
+}
 
-	default:
-		return nil, fmt.Errorf("unsupported chart of accounts: SKR%s", skr)
+// createBookingService creates the appropriate booking service based on SKR type
+func createBookingService(ctx context.Context, skr string, options booking.BookingOptions, log zerolog.Logger) (services.BookingService, error) {
+	if options.BookingDatePolicy == "" {
+		options.BookingDatePolicy = config.BookingDatePolicyFromEnv()
 	}
+	service, err := booking.NewBookingService(ctx, skr, options)
+	if err != nil {
+		if strings.Contains(err.Error(), "OPENAI_API_KEY") {
+			log.Error().
+				Err(err).
+				Msg("OpenAI API key not configured")
+			return nil, withExitCode(ExitConfig, fmt.Errorf("missing OpenAI API key. Please set:\\n" +
+				"  OPENAI_API_KEY=your-openai-api-key\\n" +
+				"Original error: %w", err))
+		}
+		log.Error().
+			Err(err).
+			Str("skr", skr).
+			Msg("Failed to create booking service")
+		return nil, fmt.Errorf("failed to create SKR%s booking service: %w", skr, err)
+	}
+
+	log.Debug().Str("skr", skr).Msg("Booking service created successfully")
+	return service, nil
 }
 
 // handleDatevError provides user-friendly error messages for DATEV processing failures
@@ -249,14 +520,24 @@ func handleDatevError(err error, log zerolog.Logger) error {
 	errStr := err.Error()
 
 	switch {
+	case errors.Is(err, invoice.ErrEncryptedPDF):
+		return withExitCode(ExitInput, fmt.Errorf("PDF is password-protected. Pass the password with --pdf-password or PDF_PASSWORDS"))
+	case errors.Is(err, invoice.ErrReminder):
+		return withExitCode(ExitInput, fmt.Errorf("document is a payment reminder (Mahnung) for an invoice already booked and charges no dunning fee, nothing was booked. Book it as an invoice anyway with --set sub-type=regular"))
+	case errors.Is(err, invoice.ErrMissingInvoiceNumber):
+		return withExitCode(ExitInput, fmt.Errorf("no invoice number found in the document, nothing was booked. Book it with tools datev --set invoice-number=... or run without --strict"))
+	case errors.Is(err, invoice.ErrInternalInvoice):
+		return withExitCode(ExitInput, fmt.Errorf("vendor and customer are both our company (intercompany or self-billing). Set the invoice type with --type PAYABLE or --type RECEIVABLE"))
 	case strings.Contains(errStr, "OPENAI_API_KEY"):
-		return fmt.Errorf("OpenAI API key not configured. Please set OPENAI_API_KEY environment variable")
+		return withExitCode(ExitConfig, fmt.Errorf("OpenAI API key not configured. Please set OPENAI_API_KEY environment variable"))
+	case errors.Is(err, context.DeadlineExceeded):
+		return withExitCode(ExitExternalAPI, fmt.Errorf("DATEV booking generation timed out. Try increasing --timeout"))
 	case strings.Contains(errStr, "Document AI"):
-		return fmt.Errorf("invoice processing failed. Please check your Google Cloud configuration")
+		return withExitCode(ExitExternalAPI, fmt.Errorf("invoice processing failed. Please check your Google Cloud configuration"))
 	case strings.Contains(errStr, "invalid") && strings.Contains(errStr, "account"):
 		return fmt.Errorf("ChatGPT returned invalid account numbers. Please try again")
 	case strings.Contains(errStr, "ChatGPT"):
-		return fmt.Errorf("AI booking generation failed. Please check your OpenAI API configuration")
+		return withExitCode(ExitExternalAPI, fmt.Errorf("AI booking generation failed. Please check your OpenAI API configuration"))
 	default:
 		return fmt.Errorf("DATEV booking generation failed: %w", err)
 	}
@@ -274,7 +555,7 @@ func outputDatevJSON(booking *services.DATEVBooking, invoice *models.Invoice, du
 		},
 	}
 
-	jsonData, err := json.MarshalIndent(output, "", "  ")
+	jsonData, err := marshalJSON(output)
 	if err != nil {
 		return fmt.Errorf("failed to create JSON output: %w", err)
 	}
@@ -283,31 +564,53 @@ func outputDatevJSON(booking *services.DATEVBooking, invoice *models.Invoice, du
 	return nil
 }
 
-// outputDatevConsole outputs the booking results in a formatted console display
-func outputDatevConsole(booking *services.DATEVBooking, invoice *models.Invoice, verbose bool, duration time.Duration) error {
+// outputDatevConsole outputs the booking results in a formatted console display with the labels of m;
+// quiet leaves out the header and footer banners
+func outputDatevConsole(booking *services.DATEVBooking, invoice *models.Invoice, verbose, quiet bool, duration time.Duration, m datevMessages) error {
 	// Header
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Println("                           DATEV BUCHUNGSVORSCHLAG")
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Println()
+	if !quiet {
+		fmt.Println(strings.Repeat("=", 80))
+		fmt.Println(strings.Repeat(" ", (80-len(m.Header))/2) + m.Header)
+		fmt.Println(strings.Repeat("=", 80))
+		fmt.Println()
+	}
 
 	// Invoice Information Section
-	fmt.Println("=== RECHNUNGSDATEN ===")
-	fmt.Printf("Rechnungsnummer: %s\n", invoice.InvoiceNumber)
-	
-	invoiceType := "UNBEKANNT"
+	fmt.Println(m.InvoiceSection)
+	fmt.Printf("%s: %s\n", m.InvoiceNumber, invoice.InvoiceNumber)
+
+	invoiceType := m.TypeUnknown
 	if invoice.Type == "PAYABLE" {
-		invoiceType = "EINGANGSRECHNUNG"
+		invoiceType = m.TypePayable
 	} else if invoice.Type == "RECEIVABLE" {
-		invoiceType = "AUSGANGSRECHNUNG"
+		invoiceType = m.TypeReceivable
+	}
+	fmt.Printf("%s: %s\n", m.Type, invoiceType)
+	switch invoice.SubType {
+	case models.InvoiceSubTypePrepayment:
+		fmt.Printf("%s: %s\n", m.SubType, m.SubTypePrepayment)
+	case models.InvoiceSubTypeFinal:
+		fmt.Printf("%s: %s", m.SubType, m.SubTypeFinal)
+		if invoice.PrepaymentReference != "" {
+			fmt.Printf(" ("+m.SettlesPrepayment+")", invoice.PrepaymentReference)
+		}
+		fmt.Println()
+	case models.InvoiceSubTypeReminder:
+		fmt.Printf("%s: %s", m.SubType, m.SubTypeReminder)
+		if invoice.ReminderReference != "" {
+			fmt.Printf(" ("+m.RemindsInvoice+")", invoice.ReminderReference)
+		}
+		fmt.Println()
+		if invoice.DunningFee > 0 {
+			fmt.Printf("%s: %.2f EUR\n", m.DunningFee, float64(invoice.DunningFee)/100)
+		}
 	}
-	fmt.Printf("Typ: %s\n", invoiceType)
-	
+
 	if invoice.Vendor != "" {
-		fmt.Printf("Lieferant: %s\n", invoice.Vendor)
+		fmt.Printf("%s: %s\n", m.Vendor, invoice.Vendor)
 	}
 	if invoice.Customer != "" {
-		fmt.Printf("Kunde: %s\n", invoice.Customer)
+		fmt.Printf("%s: %s\n", m.Customer, invoice.Customer)
 	}
 
 	// Format amounts
@@ -316,70 +619,173 @@ func outputDatevConsole(booking *services.DATEVBooking, invoice *models.Invoice,
 	grossAmount := float64(invoice.GrossAmount) / 100
 
 	if invoice.NetAmount > 0 && invoice.VATAmount > 0 {
-		fmt.Printf("Betrag: %.2f EUR (Netto: %.2f EUR, MwSt: %.2f EUR)\n", 
-			grossAmount, netAmount, vatAmount)
+		fmt.Printf("%s: %.2f EUR (%s: %.2f EUR, %s: %.2f EUR)\n",
+			m.Amount, grossAmount, m.Net, netAmount, m.VAT, vatAmount)
 	} else {
-		fmt.Printf("Betrag: %.2f EUR\n", grossAmount)
+		fmt.Printf("%s: %.2f EUR\n", m.Amount, grossAmount)
+	}
+	if invoice.SmallBusiness {
+		fmt.Println("Kleinunternehmer (§ 19 UStG): keine Umsatzsteuer")
 	}
 
 	if !invoice.IssueDate.IsZero() {
-		fmt.Printf("Rechnungsdatum: %s\n", invoice.IssueDate.Format("02.01.2006"))
+		fmt.Printf("%s: %s\n", m.IssueDate, invoice.IssueDate.Format(m.DateFormat))
+	}
+	if !invoice.ServiceDate.IsZero() {
+		fmt.Printf("%s: %s\n", m.ServiceDate, invoice.ServiceDate.Format(m.DateFormat))
 	}
 	if !invoice.DueDate.IsZero() {
-		fmt.Printf("Fälligkeitsdatum: %s\n", invoice.DueDate.Format("02.01.2006"))
+		fmt.Printf("%s: %s\n", m.DueDate, invoice.DueDate.Format(m.DateFormat))
+	}
+	if invoice.IsPaid {
+		fmt.Print(m.Paid)
+		if invoice.PaymentDate != nil && !invoice.PaymentDate.IsZero() {
+			fmt.Printf(" "+m.PaidOn, invoice.PaymentDate.Format(m.DateFormat))
+		}
+		fmt.Println()
+	}
+	if len(invoice.PaymentSchedule) > 0 {
+		fmt.Printf("%s:\n", m.PaymentSchedule)
+		for _, installment := range invoice.PaymentSchedule {
+			dueDate := m.NoDate
+			if !installment.DueDate.IsZero() {
+				dueDate = installment.DueDate.Format(m.DateFormat)
+			}
+			fmt.Printf("  %s: %.2f EUR", dueDate, float64(installment.Amount)/100)
+			if installment.Description != "" {
+				fmt.Printf(" (%s)", installment.Description)
+			}
+			fmt.Println()
+		}
+	}
+	if invoice.PurchaseOrder != "" {
+		fmt.Printf("%s: %s\n", m.PurchaseOrder, invoice.PurchaseOrder)
+	}
+	if invoice.CustomerReference != "" {
+		fmt.Printf("%s: %s\n", m.CustomerReference, invoice.CustomerReference)
 	}
 
 	// Show accounting summary if available
 	if invoice.AccountingSummary != "" {
-		fmt.Printf("Beschreibung: %s\n", invoice.AccountingSummary)
+		fmt.Printf("%s: %s\n", m.Description, invoice.AccountingSummary)
 	}
 
 	fmt.Println()
 
 	// Booking Information Section
-	fmt.Printf("=== DATEV BUCHUNGSVORSCHLAG (%s) ===\n", booking.ContenrahmenType)
-	fmt.Printf("Sollkonto: %s - %s\n", booking.DebitAccount, booking.DebitAccountName)
-	fmt.Printf("Habenkonto: %s - %s\n", booking.CreditAccount, booking.CreditAccountName)
-	fmt.Printf("Betrag: %.2f EUR\n", booking.Amount)
-	fmt.Printf("Steuerschlüssel: %s (%s)\n", booking.TaxKey, booking.TaxKeyDescription)
-	fmt.Printf("Buchungstext: %s\n", booking.BookingText)
-	fmt.Printf("Belegnummer: %s\n", booking.DocumentNumber)
-	fmt.Printf("Buchungsdatum: %s\n", booking.BookingDate.Format("02.01.2006"))
-	fmt.Printf("Buchungsperiode: %s\n", booking.AccountingPeriod)
-	
-	if booking.CostCenter != "" {
-		fmt.Printf("Kostenstelle: %s\n", booking.CostCenter)
+	if booking.Template {
+		fmt.Println(m.TemplateNotice)
+		fmt.Println()
+		fmt.Printf(m.TemplateSection+"\n", booking.ContenrahmenType)
+	} else {
+		fmt.Printf(m.BookingSection+"\n", booking.ContenrahmenType)
+	}
+	fmt.Printf("%s: %s - %s\n", m.DebitAccount, booking.DebitAccount, booking.DebitAccountName)
+	fmt.Printf("%s: %s - %s\n", m.CreditAccount, booking.CreditAccount, booking.CreditAccountName)
+	if booking.AmountBasis == services.AmountBasisNet {
+		fmt.Printf("%s: %.2f EUR (%s)\n", m.Amount, booking.Amount, m.Net)
 	} else {
-		fmt.Printf("Kostenstelle: -\n")
+		fmt.Printf("%s: %.2f EUR\n", m.Amount, booking.Amount)
+	}
+	fmt.Printf("%s: %s (%s)\n", m.TaxKey, booking.TaxKey, booking.TaxKeyDescription)
+	fmt.Printf("%s: %s\n", m.BookingText, booking.BookingText)
+	fmt.Printf("%s: %s\n", m.DocumentNumber, booking.DocumentNumber)
+	fmt.Printf("%s: %s\n", m.BookingDate, booking.BookingDate.Format(m.DateFormat))
+	fmt.Printf("%s: %s\n", m.AccountingPeriod, booking.AccountingPeriod)
+
+	costCenter := booking.CostCenter
+	if costCenter == "" {
+		costCenter = "-"
+	}
+	fmt.Printf("%s: %s\n", m.CostCenter, costCenter)
+	if booking.SourceFile != "" {
+		fmt.Printf("%s: %s\n", m.SourceDocument, booking.SourceFile)
+	}
+	if booking.SourceSHA256 != "" {
+		fmt.Printf("SHA-256: %s\n", booking.SourceSHA256)
 	}
 
 	fmt.Println()
 
+	// Mixed-rate invoices are booked with one line per tax key
+	if len(booking.Splits) > 0 {
+		fmt.Println(m.SplitSection)
+		fmt.Printf("  %-10s %8s %12s %12s %12s\n", m.SplitTaxKey, m.SplitRate, m.Net, m.VAT, m.Gross)
+		for _, split := range booking.Splits {
+			fmt.Printf("  %-10s %7.0f%% %12.2f %12.2f %12.2f\n",
+				split.TaxKey, split.VATRate, split.NetAmount, split.VATAmount, split.Amount)
+		}
+		fmt.Println()
+	}
+
+	// With explicit VAT postings the VAT is booked on its own lines instead of by the tax key
+	if len(booking.Postings) > 0 {
+		fmt.Println(m.PostingSection)
+		fmt.Printf("  %-8s %-36s %12s %12s\n", m.PostingAccount, "", m.PostingDebit, m.PostingCredit)
+		for _, posting := range booking.Postings {
+			debit, credit := "", ""
+			if posting.Side == services.PostingDebit {
+				debit = fmt.Sprintf("%.2f", posting.Amount)
+			} else {
+				credit = fmt.Sprintf("%.2f", posting.Amount)
+			}
+			fmt.Printf("  %-8s %-36s %12s %12s\n", posting.Account, posting.AccountName, debit, credit)
+		}
+		fmt.Println()
+	}
+
 	// Explanation
 	if booking.Explanation != "" {
-		fmt.Printf("Erläuterung: %s\n", booking.Explanation)
+		fmt.Printf("%s: %s\n", m.Explanation, booking.Explanation)
+		fmt.Println()
+	}
+
+	// Plausibility warnings that need manual review
+	for _, warning := range booking.Warnings {
+		fmt.Printf("⚠️  %s: %s\n", m.Warning, warning)
+	}
+	if len(booking.Warnings) > 0 {
 		fmt.Println()
 	}
 
 	// Verbose information
 	if verbose {
-		fmt.Println("=== DETAILLIERTE INFORMATIONEN ===")
-		fmt.Printf("Verarbeitungszeit: %.2f Sekunden\n", duration.Seconds())
-		fmt.Printf("Generiert am: %s\n", booking.GeneratedAt.Format("02.01.2006 15:04:05"))
+		fmt.Println(m.DetailsSection)
+		fmt.Printf(m.ProcessingTime+"\n", duration.Seconds())
+		fmt.Printf("%s: %s\n", m.GeneratedAt, booking.GeneratedAt.Format(m.DateTimeFormat))
 		fmt.Println()
 
 		if booking.Explanation != "" {
-			fmt.Println("=== BUCHUNGSLOGIK ===")
+			fmt.Println(m.ReasoningSection)
 			fmt.Printf("%s\n", booking.Explanation)
 			fmt.Println()
 		}
+
+		printCompletionChanges(booking.CompletionChanges, m)
+
+		if invoice.OCRText != "" {
+			fmt.Println(m.OCRTextSection)
+			fmt.Println(strings.TrimSpace(invoice.OCRText))
+			fmt.Println()
+		}
 	}
 
 	// Footer
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Println("Hinweis: Dies ist ein KI-generierter Buchungsvorschlag.")
-	fmt.Println("Bitte prüfen Sie die Buchung vor der Übernahme in DATEV.")
-	fmt.Println(strings.Repeat("=", 80))
+	if !quiet {
+		fmt.Println(strings.Repeat("=", 80))
+		fmt.Println(m.FooterNotice)
+		fmt.Println(m.FooterReview)
+		fmt.Println(strings.Repeat("=", 80))
+	}
 
 	return nil
+}
+
+// setBookingSource records the PDF file a booking was generated from. The hash is taken from the file
+// as stored, so it also matches password-protected PDFs the service only saw decrypted.
+func setBookingSource(datevBooking *services.DATEVBooking, pdfPath string) {
+	datevBooking.SourceFile = filepath.Base(pdfPath)
+	if data, err := os.ReadFile(pdfPath); err == nil {
+		datevBooking.SourceSHA256 = booking.DocumentHash(data)
+	}
 }
\ No newline at end of file
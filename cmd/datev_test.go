@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"tools/internal/config"
+)
+
+func TestResolveChartOfAccounts(t *testing.T) {
+	defer SetConfig(loadedConfig, loadConfigErr)
+
+	tests := []struct {
+		name     string
+		skr      string
+		config   *config.Config
+		err      error
+		want     string
+		wantCode int
+	}{
+		{name: "flag", skr: "SKR03", config: &config.Config{ChartOfAccounts: "03"}, want: "03"},
+		{name: "short flag", skr: "3", want: "03"},
+		{name: "unsupported flag", skr: "04", config: &config.Config{ChartOfAccounts: "03"}, wantCode: ExitFailure},
+		{name: "from config", config: &config.Config{ChartOfAccounts: "03"}, want: "03"},
+		{name: "config with missing settings", config: &config.Config{ChartOfAccounts: "03"}, err: errors.New("GOOGLE_SHEET_URL is required"), want: "03"},
+		{name: "unsupported in config", err: errors.New("CHART_OF_ACCOUNTS: unsupported chart of accounts"), wantCode: ExitConfig},
+		{name: "no config", want: "03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetConfig(tt.config, tt.err)
+
+			got, err := resolveChartOfAccounts(tt.skr)
+			if tt.wantCode != 0 {
+				if err == nil || exitCode(err) != tt.wantCode {
+					t.Fatalf("resolveChartOfAccounts(%q) error = %v, want exit code %d", tt.skr, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolveChartOfAccounts(%q) = %q, %v, want %q", tt.skr, got, err, tt.want)
+			}
+		})
+	}
+}
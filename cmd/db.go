@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tools/internal/db"
+	"tools/internal/logger"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Query the local database of processed invoices",
+	Long: `Query the optional SQLite database of processed invoices.
+
+With DB_PATH set, datev and datev-batch store every processed invoice with its booking
+in the database. An invoice is stored once per counterparty and invoice number (or file
+name if the invoice has no number), so processing a file again updates its row.
+Without DB_PATH nothing is stored.
+
+Required environment variables:
+  DB_PATH - Path of the SQLite database file`,
+}
+
+var dbQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "List stored invoices matching vendor, date range and tax key",
+	Example: `  # All invoices of one vendor
+  tools db query --vendor hornbach
+
+  # Invoices of the first quarter booked with tax key 9
+  tools db query --from 2024-01-01 --to 2024-03-31 --tax-key 9
+
+  # Export the matches as JSON
+  tools db query --vendor amazon --json > amazon.json`,
+	Args: cobra.NoArgs,
+	RunE: runDBQuery,
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbQueryCmd)
+
+	dbQueryCmd.Flags().String("vendor", "", "Part of the vendor or customer name (case-insensitive)")
+	dbQueryCmd.Flags().String("from", "", "First invoice date (YYYY-MM-DD)")
+	dbQueryCmd.Flags().String("to", "", "Last invoice date (YYYY-MM-DD)")
+	dbQueryCmd.Flags().String("tax-key", "", "Tax key of the booking or one of its splits")
+	dbQueryCmd.Flags().Int("limit", 0, "Maximum number of invoices (default: all)")
+	dbQueryCmd.Flags().Bool("json", false, "Output the invoices and bookings as JSON")
+}
+
+func runDBQuery(cmd *cobra.Command, args []string) error {
+	log := logger.WithComponent("db")
+
+	vendor, _ := cmd.Flags().GetString("vendor")
+	fromStr, _ := cmd.Flags().GetString("from")
+	toStr, _ := cmd.Flags().GetString("to")
+	taxKey, _ := cmd.Flags().GetString("tax-key")
+	limit, _ := cmd.Flags().GetInt("limit")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	filter := db.Filter{Vendor: vendor, TaxKey: taxKey, Limit: limit}
+	var err error
+	if fromStr != "" {
+		if filter.From, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return withExitCode(ExitInput, fmt.Errorf("invalid --from date %q (expected YYYY-MM-DD)", fromStr))
+		}
+	}
+	if toStr != "" {
+		if filter.To, err = time.Parse("2006-01-02", toStr); err != nil {
+			return withExitCode(ExitInput, fmt.Errorf("invalid --to date %q (expected YYYY-MM-DD)", toStr))
+		}
+	}
+
+	store, err := openInvoiceDB()
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return withExitCode(ExitConfig, fmt.Errorf("DB_PATH environment variable is required"))
+	}
+	defer store.Close()
+
+	records, err := store.Query(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("db", store.Path()).
+		Int("records", len(records)).
+		Msg("Database query completed")
+
+	if jsonOutput {
+		if records == nil {
+			records = []db.Record{}
+		}
+		return encodeJSON(os.Stdout, records)
+	}
+
+	if len(records) == 0 {
+		fmt.Println("Keine Rechnungen gefunden.")
+		return nil
+	}
+
+	fmt.Printf("%-10s %-20.20s %-30.30s %12s %-9s %-11s\n", "Datum", "Rechnungsnr.", "Lieferant/Kunde", "Brutto", "Schlüssel", "Soll/Haben")
+	fmt.Println(strings.Repeat("-", 98))
+	var grossCents int64
+	for _, record := range records {
+		inv := record.Invoice
+		date := ""
+		if !inv.IssueDate.IsZero() {
+			date = inv.IssueDate.Format("02.01.2006")
+		}
+		counterparty := inv.Vendor
+		if inv.Type == "RECEIVABLE" {
+			counterparty = inv.Customer
+		}
+		taxKey, accounts := "", ""
+		if record.Booking != nil {
+			taxKey = record.Booking.TaxKey
+			if len(record.Booking.Splits) > 0 {
+				taxKey = "(Split)"
+			}
+			if record.Booking.DebitAccount != "" || record.Booking.CreditAccount != "" {
+				accounts = record.Booking.DebitAccount + "/" + record.Booking.CreditAccount
+			}
+		}
+		fmt.Printf("%-10s %-20.20s %-30.30s %12s %-9s %-11s\n", date, inv.InvoiceNumber, counterparty,
+			formatStatsAmount(inv.GrossAmount), taxKey, accounts)
+		grossCents += inv.GrossAmount
+	}
+	fmt.Println(strings.Repeat("-", 98))
+	fmt.Printf("%d Rechnungen, Brutto %s EUR\n", len(records), formatStatsAmount(grossCents))
+
+	return nil
+}
+
+// openInvoiceDB opens the database configured with DB_PATH, or returns nil if none is configured.
+// A configured database that cannot be opened is a configuration error rather than something to
+// skip, since the runs would silently be missing from its history.
+func openInvoiceDB() (*db.Store, error) {
+	store, err := db.OpenFromEnv()
+	if err != nil {
+		return nil, withExitCode(ExitConfig, fmt.Errorf("failed to open invoice database: %w", err))
+	}
+	return store, nil
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"tools/internal/invoice"
+	"tools/internal/llm"
+	"tools/internal/ocr"
+)
+
+// Exit codes of the CLI, so scripts can tell failure classes apart
+const (
+	ExitOK             = 0 // Success
+	ExitFailure        = 1 // Any other failure, including invalid flags
+	ExitConfig         = 2 // Missing or invalid configuration or credentials
+	ExitInput          = 3 // Missing, unreadable or unusable input file
+	ExitExternalAPI    = 4 // Document AI, Vision, OpenAI or Sheets request failed or timed out
+	ExitPartialFailure = 5 // Batch finished but some files failed
+	ExitControlTotal   = 6 // Batch finished but its gross total deviates from the control total
+)
+
+// exitError attaches an exit code to an error whose message replaced the original cause
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode marks err with an explicit exit code
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCode maps a command error to the CLI exit code. Explicit codes win over the sentinel errors
+// of the processing packages; unknown errors exit with ExitFailure.
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	switch {
+	case errors.Is(err, invoice.ErrMissingCredentials),
+		errors.Is(err, invoice.ErrInvalidCredentials),
+		errors.Is(err, invoice.ErrInvalidConfiguration),
+		errors.Is(err, invoice.ErrProcessorNotFound),
+		errors.Is(err, ocr.ErrMissingCredentials),
+		llm.FailureClass(err) == llm.FailureAuth:
+		return ExitConfig
+	case errors.Is(err, fs.ErrNotExist),
+		errors.Is(err, fs.ErrPermission),
+		errors.Is(err, invoice.ErrInvalidPDF),
+		errors.Is(err, invoice.ErrEncryptedPDF),
+		errors.Is(err, invoice.ErrDocumentTooLarge),
+		errors.Is(err, invoice.ErrUnsupportedFormat),
+		errors.Is(err, invoice.ErrTooManyPages),
+		errors.Is(err, invoice.ErrLowOCRConfidence),
+		errors.Is(err, invoice.ErrMissingInvoiceNumber),
+		errors.Is(err, ocr.ErrInvalidPDF),
+		errors.Is(err, ocr.ErrPDFTooLarge),
+		errors.Is(err, ocr.ErrTooManyPages),
+		errors.Is(err, ocr.ErrUnsupportedFormat),
+		errors.Is(err, ocr.ErrEmptyDocument):
+		return ExitInput
+	case errors.Is(err, invoice.ErrProcessingFailed),
+		errors.Is(err, invoice.ErrQuotaExceeded),
+		llm.FailureClass(err) == llm.FailureQuota,
+		errors.Is(err, ocr.ErrOCRFailed),
+		errors.Is(err, context.DeadlineExceeded):
+		return ExitExternalAPI
+	}
+
+	return ExitFailure
+}
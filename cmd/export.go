@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tools/internal/ledger"
+	"tools/internal/logger"
+	"tools/internal/sheets"
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Convert saved invoice and booking JSON to accounting import formats",
+	Long: `Convert invoices extracted earlier to an accounting import format without running
+Document AI or ChatGPT again.
+
+Input files (--in, repeatable) may contain:
+- the JSON output of "tools datev --json" (invoice and booking)
+- the JSON output of "tools invoice" (invoice only, a single object or an array)
+- the JSONL stream of "tools datev-batch --jsonl" (one result per line)
+
+Formats:
+  extf    DATEV EXTF Buchungsstapel (CSV for the DATEV import)
+  xml     DATEV XML ledger import (Belegverwaltung online)
+  csv     the Kreditoren/Debitoren sheet columns, semicolon-separated with decimal commas
+  ledger  the flat CSV ledger of datev-batch --ledger-csv (ISO dates, dot decimals)
+
+extf and xml need a booking, so invoices without one (tools invoice output, template
+bookings without accounts) are skipped. Failed batch results are only listed in csv.
+Bookings made with --explicit-vat are exported as their net and VAT posting lines
+against the creditor or debitor, without tax key.
+
+extf is written in Windows-1252 (--encoding cp1252), the encoding the DATEV import
+expects; csv and ledger are written in UTF-8 unless --encoding cp1252 is given.
+Characters Windows-1252 cannot represent are written without their accents or as "?".
+xml is always UTF-8.
+
+Optional environment variables for extf:
+  DATEV_CONSULTANT_NUMBER - Beraternummer for the EXTF header (or --consultant-number)
+  DATEV_CLIENT_NUMBER - Mandantennummer for the EXTF header (or --client-number)`,
+	Example: `  # DATEV Buchungsstapel from a batch run
+  tools export --in results.jsonl --format extf --output EXTF_Buchungsstapel.csv
+
+  # Combine several single-invoice bookings into one XML import
+  tools export --in a.json --in b.json --format xml --output ledger.xml
+
+  # Re-export a batch run as a ledger for another accounting tool
+  tools export --in results.jsonl --format ledger > ledger.csv
+
+  # EXTF in UTF-8 for a tool that does not read Windows-1252
+  tools export --in results.jsonl --format extf --encoding utf8 --output EXTF_Buchungsstapel.csv`,
+	RunE: runExport,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringArray("in", nil, "Saved JSON or JSONL file to export (repeatable)")
+	exportCmd.Flags().String("format", "", "Output format: extf, xml, csv or ledger")
+	exportCmd.Flags().StringP("output", "o", "", "Output file path (default: stdout)")
+	exportCmd.Flags().String("consultant-number", "", "DATEV Beraternummer for the EXTF header (default: DATEV_CONSULTANT_NUMBER)")
+	exportCmd.Flags().String("client-number", "", "DATEV Mandantennummer for the EXTF header (default: DATEV_CLIENT_NUMBER)")
+	exportCmd.Flags().String("description", "", "Bezeichnung of the EXTF Buchungsstapel (max. 30 characters)")
+	exportCmd.Flags().String("encoding", "", "Output encoding: cp1252 or utf8 (default: cp1252 for extf, utf8 otherwise)")
+}
+
+// exportRecord is one invoice read from a saved JSON file
+type exportRecord struct {
+	Filename   string
+	Status     string
+	Error      string
+	Invoice    *models.Invoice
+	Booking    *services.DATEVBooking
+	Confidence map[string]float32
+}
+
+// exportEnvelope holds the fields next to the invoice in the saved formats; each format sets a subset
+type exportEnvelope struct {
+	File       string                 `json:"file"`
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error"`
+	Booking    *services.DATEVBooking `json:"booking"`
+	Confidence map[string]float32     `json:"confidence"`
+	Metadata   struct {
+		FileName string `json:"file_name"`
+	} `json:"metadata"`
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	log := logger.WithComponent("export")
+
+	inputs, _ := cmd.Flags().GetStringArray("in")
+	format, _ := cmd.Flags().GetString("format")
+	outputPath, _ := cmd.Flags().GetString("output")
+	consultantNumber, _ := cmd.Flags().GetString("consultant-number")
+	clientNumber, _ := cmd.Flags().GetString("client-number")
+	description, _ := cmd.Flags().GetString("description")
+	encodingName, _ := cmd.Flags().GetString("encoding")
+
+	if len(inputs) == 0 {
+		return fmt.Errorf("at least one input file is required (--in)")
+	}
+	format = strings.ToLower(format)
+	if format != "extf" && format != "xml" && format != "csv" && format != "ledger" {
+		return fmt.Errorf("invalid format: %q (must be 'extf', 'xml', 'csv' or 'ledger')", format)
+	}
+	encoding := ledger.EncodingUTF8
+	if format == "extf" {
+		encoding = ledger.EncodingCP1252
+	}
+	if encodingName != "" {
+		var err error
+		if encoding, err = ledger.ParseEncoding(encodingName); err != nil {
+			return err
+		}
+		if format == "xml" && encoding != ledger.EncodingUTF8 {
+			return fmt.Errorf("the xml format is always UTF-8 (--encoding %s not supported)", encodingName)
+		}
+	}
+	if consultantNumber == "" {
+		consultantNumber = os.Getenv("DATEV_CONSULTANT_NUMBER")
+	}
+	if clientNumber == "" {
+		clientNumber = os.Getenv("DATEV_CLIENT_NUMBER")
+	}
+
+	var records []exportRecord
+	for _, input := range inputs {
+		fileRecords, err := readExportRecords(input)
+		if err != nil {
+			return withExitCode(ExitInput, err)
+		}
+		records = append(records, fileRecords...)
+	}
+
+	var entries []ledger.Entry
+	var results []sheets.BatchResult
+	skipped := 0
+	for _, record := range records {
+		if format == "csv" {
+			result := sheets.BatchResult{
+				Filename:   record.Filename,
+				Invoice:    record.Invoice,
+				Booking:    record.Booking,
+				Status:     record.Status,
+				Confidence: record.Confidence,
+			}
+			if record.Error != "" {
+				result.Error = errors.New(record.Error)
+			}
+			results = append(results, result)
+			continue
+		}
+
+		if record.Invoice == nil || record.Error != "" {
+			skipped++
+			continue
+		}
+		if (format == "extf" || format == "xml") && !exportableBooking(record.Booking) {
+			log.Warn().
+				Str("file", record.Filename).
+				Str("invoice_number", record.Invoice.InvoiceNumber).
+				Msg("Skipping invoice without a complete booking")
+			skipped++
+			continue
+		}
+		entries = append(entries, ledger.Entry{Invoice: record.Invoice, Booking: record.Booking})
+	}
+
+	if len(entries) == 0 && len(results) == 0 {
+		return withExitCode(ExitInput, fmt.Errorf("no exportable invoices found in %d records", len(records)))
+	}
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if outputPath != "" {
+		var err error
+		file, err = os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	encoded := ledger.NewEncodingWriter(out, encoding)
+	var err error
+	switch format {
+	case "extf":
+		err = ledger.WriteEXTF(encoded, ledger.EXTFHeader{
+			ConsultantNumber: consultantNumber,
+			ClientNumber:     clientNumber,
+			Description:      description,
+		}, entries)
+	case "xml":
+		err = ledger.WriteXML(encoded, entries)
+	case "csv":
+		err = sheets.WriteCSV(encoded, results)
+	case "ledger":
+		err = ledger.Write(encoded, entries)
+	}
+	if err == nil {
+		err = encoded.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s export: %w", format, err)
+	}
+
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to close output file: %w", err)
+		}
+		fmt.Printf("Exportiert: %d Rechnungen nach %s (%s)\n", len(entries)+len(results), outputPath, format)
+		if skipped > 0 {
+			fmt.Printf("Übersprungen: %d (ohne Rechnung oder vollständige Buchung)\n", skipped)
+		}
+	}
+
+	log.Info().
+		Int("records", len(records)).
+		Int("exported", len(entries)+len(results)).
+		Int("skipped", skipped).
+		Str("format", format).
+		Str("encoding", string(encoding)).
+		Msg("Export completed")
+
+	return nil
+}
+
+// exportableBooking reports whether a booking has the accounts DATEV needs to import it
+func exportableBooking(booking *services.DATEVBooking) bool {
+	return booking != nil && !booking.Template && booking.DebitAccount != "" && booking.CreditAccount != ""
+}
+
+// readExportRecords reads all invoices from a saved JSON file: a single object, an array of objects or
+// one object per line
+func readExportRecords(path string) ([]exportRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var records []exportRecord
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		items := []json.RawMessage{raw}
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			items = nil
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+		}
+
+		for _, item := range items {
+			record, err := parseExportRecord(item)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse record %d of %s: %w", len(records)+1, path, err)
+			}
+			if record.Filename == "" {
+				record.Filename = filepath.Base(path)
+			}
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+// parseExportRecord parses one saved object. The invoice is either nested under "invoice" next to the
+// booking or metadata, missing in failed batch results, or the object is the invoice itself.
+func parseExportRecord(raw json.RawMessage) (exportRecord, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return exportRecord{}, err
+	}
+
+	_, hasFile := fields["file"]
+	invoiceJSON, nested := fields["invoice"]
+	if !nested && !hasFile {
+		invoiceJSON = raw
+	}
+
+	var record exportRecord
+	if nested || hasFile {
+		var envelope exportEnvelope
+		if err := json.Unmarshal(raw, &envelope); err != nil {
+			return exportRecord{}, err
+		}
+		record = exportRecord{
+			Filename:   envelope.File,
+			Status:     envelope.Status,
+			Error:      envelope.Error,
+			Booking:    envelope.Booking,
+			Confidence: envelope.Confidence,
+		}
+		if record.Filename == "" {
+			record.Filename = envelope.Metadata.FileName
+		}
+	}
+
+	if len(invoiceJSON) > 0 && string(bytes.TrimSpace(invoiceJSON)) != "null" {
+		invoice, err := parseExportInvoice(invoiceJSON)
+		if err != nil {
+			return exportRecord{}, err
+		}
+		record.Invoice = invoice
+	}
+
+	return record, nil
+}
+
+// parseExportInvoice parses an invoice saved either as InvoiceData (tools invoice, datev-batch --jsonl;
+// snake_case keys and amounts in *_cents) or as the invoice model itself (tools datev --json)
+func parseExportInvoice(raw json.RawMessage) (*models.Invoice, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	if _, ok := fields["gross_amount_cents"]; ok {
+		var data InvoiceData
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, err
+		}
+		return convertFromInvoiceData(&data), nil
+	}
+
+	var invoice models.Invoice
+	if err := json.Unmarshal(raw, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
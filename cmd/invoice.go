@@ -1,20 +1,23 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 	"tools/internal/invoice"
+	"tools/internal/llm"
 	"tools/internal/logger"
 	"tools/pkg/models"
 )
@@ -29,8 +32,8 @@ using OCR and ChatGPT after Document AI processing.
 
 This command uses Google Document AI's invoice processor which is specifically 
 trained to understand invoice formats and extract key business information with
-high accuracy. The output is always in JSON format containing the structured
-invoice data.
+high accuracy. The output is JSON containing the structured invoice data; use
+--format table for a readable summary of the key fields instead.
 
 Required environment variables:
   GOOGLE_APPLICATION_CREDENTIALS - Path to service account JSON file, OR
@@ -41,7 +44,26 @@ Required environment variables:
   
 Additional for --complete flag:
   OPENAI_API_KEY - OpenAI API key for completion service
-  COMPANY_NAME - Your company name for invoice type determination`,
+  COMPANY_NAME - Your company name for invoice type determination
+
+With --complete, --dump-prompt prints the system and user prompts completion
+would send to ChatGPT and exits without sending them. No prompt is printed if
+Document AI extracted every field and completion has nothing to ask.
+
+Besides PDFs, TIFF scans and single images (GIF, JPEG, PNG, BMP, WEBP) are
+accepted; the format is detected from the file content. A multipage TIFF has the
+same 15 page limit as a PDF.
+
+--processing selects how the document is sent to Document AI: "sync" always as
+a synchronous request (documents over 15 pages fail), "async" always as a batch
+request through Cloud Storage, and "auto" (the default) as a batch request only
+for documents over 15 pages or 10 MB, or after a synchronous request timed out,
+if the buckets below are set. With "async", missing buckets fail before the
+document is read.
+
+Optional for async processing of PDFs or TIFFs with more than 15 pages or over 10 MB (forced with --processing async):
+  GCS_SOURCE_BUCKET - Bucket the document is uploaded to for the Document AI batch request
+  GCS_OUTPUT_BUCKET - Bucket Document AI writes the batch result to`,
 	Example: `  # Basic Document AI processing only
   tools invoice invoice.pdf
 
@@ -54,8 +76,23 @@ Additional for --complete flag:
   # Include confidence scores for each extracted field
   tools invoice invoice.pdf --confidence --complete
 
+  # Just look at the extracted fields instead of reading JSON
+  tools invoice invoice.pdf --complete --format table
+
   # Process with custom timeout
-  tools invoice large-invoice.pdf --timeout 120 --complete`,
+  tools invoice large-invoice.pdf --timeout 120 --complete
+
+  # Split a scan containing several invoices (outputs a JSON array if more than one is found)
+  tools invoice scan-batch.pdf --split
+
+  # Only process the first page, ignoring attached terms and conditions
+  tools invoice invoice-with-agb.pdf --pages 1
+
+  # Keep the OCR text completion extracted from, to debug a wrong field
+  tools invoice invoice.pdf --complete --dump-ocr invoice.txt
+
+  # Print the completion prompts without sending them
+  tools invoice invoice.pdf --complete --dump-prompt`,
 	Args: cobra.ExactArgs(1),
 	RunE: runInvoice,
 }
@@ -77,23 +114,57 @@ type InvoiceData struct {
 	ID            string     `json:"id"`
 	InvoiceNumber string     `json:"invoice_number"`
 	Type          string     `json:"type"`
+	TypeReasoning string     `json:"type_reasoning,omitempty"`
+	SubType       string     `json:"sub_type,omitempty"`
+	PrepaymentReference string `json:"prepayment_reference,omitempty"`
+	ReminderReference   string `json:"reminder_reference,omitempty"`
+	DunningFee          int64  `json:"dunning_fee_cents,omitempty"`
+	Internal      bool       `json:"internal,omitempty"`
 	Vendor        string     `json:"vendor"`
 	Customer      string     `json:"customer"`
+	VendorVATID   string     `json:"vendor_vat_id,omitempty"`
+	CustomerVATID string     `json:"customer_vat_id,omitempty"`
+	CustomerAddress string   `json:"customer_address,omitempty"`
+	CustomerEmail   string   `json:"customer_email,omitempty"`
+	CustomerContact string   `json:"customer_contact,omitempty"`
+	PartnerID     string     `json:"partner_id,omitempty"`
 	IssueDate     *time.Time `json:"issue_date,omitempty"`
 	DueDate       *time.Time `json:"due_date,omitempty"`
+	ServiceDate   *time.Time `json:"service_date,omitempty"`
 	PaymentDate   *time.Time `json:"payment_date,omitempty"`
 	NetAmount     int64      `json:"net_amount_cents"`
 	VATAmount     int64      `json:"vat_amount_cents"`
 	GrossAmount   int64      `json:"gross_amount_cents"`
+	VATInferredFrom string   `json:"vat_inferred_from,omitempty"` // Set if net and VAT were back-calculated from gross
 	Currency      string     `json:"currency"`
+	SmallBusiness bool       `json:"small_business,omitempty"`
 	IsPaid            bool       `json:"is_paid"`
-	Reference         string     `json:"reference,omitempty"`
+	PurchaseOrder     string     `json:"purchase_order,omitempty"`
+	CustomerReference string     `json:"customer_reference,omitempty"`
 	Description       string     `json:"description,omitempty"`
 	AccountingSummary string     `json:"accounting_summary,omitempty"`
+	OCRText           string     `json:"ocr_text,omitempty"` // Only with --include-raw-text
+	PaymentSchedule   []InstallmentData `json:"payment_schedule,omitempty"`
+	InputQuality      *InputQualityData `json:"input_quality,omitempty"`
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
+// InstallmentData is one installment of an invoice's payment schedule
+type InstallmentData struct {
+	Amount      int64      `json:"amount_cents"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Description string     `json:"description,omitempty"`
+}
+
+// InputQualityData is the scan quality Document AI reported for the document
+type InputQualityData struct {
+	Score        float32  `json:"score"`
+	Defects      []string `json:"defects,omitempty"`
+	RotatedPages []int    `json:"rotated_pages,omitempty"`
+	Low          bool     `json:"low"` // The document should be re-scanned before its extraction is trusted
+}
+
 // ProcessingMetadata contains information about the processing operation
 type ProcessingMetadata struct {
 	FileName           string        `json:"file_name"`
@@ -107,9 +178,20 @@ func init() {
 	rootCmd.AddCommand(invoiceCmd)
 
 	invoiceCmd.Flags().StringP("output", "o", "", "Output file path (default: stdout)")
+	invoiceCmd.Flags().String("format", "json", "Output format: json or table")
 	invoiceCmd.Flags().Bool("confidence", false, "Include confidence scores in output")
 	invoiceCmd.Flags().Bool("complete", false, "Complete missing invoice fields using OCR and AI after Document AI processing")
-	invoiceCmd.Flags().Int("timeout", 120, "Processing timeout in seconds")
+	invoiceCmd.Flags().Int("timeout", 120, "Processing timeout in seconds (also used for the Document AI request)")
+	invoiceCmd.Flags().Bool("split", false, "Detect multiple invoices in one PDF and extract each separately")
+	invoiceCmd.Flags().String("pages", "", "Only process these pages, e.g. 1, 1-2 or 1,3 (default: all pages)")
+	invoiceCmd.Flags().Bool("deskew", false, "Correct rotated or skewed scans in the OCR used by --complete")
+	invoiceCmd.Flags().Bool("include-raw-text", false, "Include the OCR text used by --complete in the JSON output (ocr_text)")
+	invoiceCmd.Flags().String("dump-ocr", "", "Write the OCR text used by --complete to this file")
+	invoiceCmd.Flags().Bool("dump-prompt", false, "Print the prompts --complete would send to ChatGPT and exit without sending them")
+	invoiceCmd.Flags().Bool("no-summary", false, "Do not request the AI accounting summary in --complete, saving tokens (also ACCOUNTING_SUMMARY=false)")
+	invoiceCmd.Flags().String("pdf-password", "", "Password for encrypted PDFs (tried before PDF_PASSWORDS)")
+	invoiceCmd.Flags().Bool("force", false, "Re-extract the PDF even if a cached result exists and overwrite the cache entry")
+	addProcessingFlags(invoiceCmd)
 }
 
 func runInvoice(cmd *cobra.Command, args []string) error {
@@ -120,21 +202,49 @@ func runInvoice(cmd *cobra.Command, args []string) error {
 	includeConfidence, _ := cmd.Flags().GetBool("confidence")
 	completeFlag, _ := cmd.Flags().GetBool("complete")
 	timeoutSecs, _ := cmd.Flags().GetInt("timeout")
+	splitFlag, _ := cmd.Flags().GetBool("split")
+	pagesSpec, _ := cmd.Flags().GetString("pages")
+	deskew, _ := cmd.Flags().GetBool("deskew")
+	noSummary, _ := cmd.Flags().GetBool("no-summary")
+	includeRawText, _ := cmd.Flags().GetBool("include-raw-text")
+	dumpOCRPath, _ := cmd.Flags().GetString("dump-ocr")
+	dumpPrompt, _ := cmd.Flags().GetBool("dump-prompt")
+	pdfPassword, _ := cmd.Flags().GetString("pdf-password")
+	force, _ := cmd.Flags().GetBool("force")
+	format, _ := cmd.Flags().GetString("format")
 
 	pdfPath := args[0]
 
+	format = strings.ToLower(format)
+	if format != "json" && format != "table" {
+		return fmt.Errorf("invalid output format: %s (must be 'json' or 'table')", format)
+	}
+
+	pages, err := parsePageRange(pagesSpec)
+	if err != nil {
+		return err
+	}
+	processingMode, err := processingModeFlag(cmd)
+	if err != nil {
+		return err
+	}
+	if len(pages) > 0 && splitFlag {
+		return fmt.Errorf("--pages cannot be combined with --split")
+	}
+
 	log.Info().
 		Str("file", pdfPath).
 		Str("output", outputPath).
 		Bool("confidence", includeConfidence).
 		Bool("complete", completeFlag).
 		Int("timeout", timeoutSecs).
+		Ints32("pages", pages).
 		Msg("Starting invoice processing")
 
 	// Validate and get file info
 	fileInfo, err := validateInvoicePDF(pdfPath, log)
 	if err != nil {
-		return err
+		return withExitCode(ExitInput, err)
 	}
 
 	// Create context with timeout and signal handling
@@ -142,25 +252,26 @@ func runInvoice(cmd *cobra.Command, args []string) error {
 	defer cancel()
 
 	// Create invoice processor
-	processor, err := createInvoiceProcessor(ctx, log)
+	processor, err := createInvoiceProcessor(ctx, invoice.ProcessorOptions{
+		Timeout: time.Duration(timeoutSecs) * time.Second,
+		Mode:    processingMode,
+	}, log)
 	if err != nil {
 		return err
 	}
+	if store := openExtractionCache(log); store != nil {
+		processor = invoice.NewCachedInvoiceProcessor(processor, store, force)
+	}
 
-	// Open PDF file
-	pdfFile, err := os.Open(pdfPath)
+	// Read PDF file, decrypting it if it is password-protected
+	pdfFile, err := openPDF(pdfPath, pdfPassword)
 	if err != nil {
 		log.Error().
 			Err(err).
 			Str("file", pdfPath).
 			Msg("Failed to open PDF file")
-		return fmt.Errorf("failed to open PDF file: %w", err)
+		return err
 	}
-	defer func() {
-		if closeErr := pdfFile.Close(); closeErr != nil {
-			log.Warn().Err(closeErr).Msg("Failed to close PDF file")
-		}
-	}()
 
 	log.Info().
 		Str("file", pdfPath).
@@ -172,7 +283,31 @@ func runInvoice(cmd *cobra.Command, args []string) error {
 	var modelInvoice *models.Invoice
 	var confidence map[string]float32
 
-	if includeConfidence {
+	if splitFlag {
+		invoices, err := processor.ProcessMultiInvoice(ctx, pdfFile)
+		if err != nil {
+			return handleInvoiceError(err, log)
+		}
+
+		if len(invoices) > 1 {
+			if completeFlag || includeConfidence {
+				log.Warn().Msg("--complete and --confidence are not supported for PDFs with multiple invoices, ignoring")
+			}
+			return outputMultipleInvoices(invoices, fileInfo, time.Since(startTime), outputPath, format, log)
+		}
+
+		modelInvoice = invoices[0]
+		confidence = make(map[string]float32)
+	} else if len(pages) > 0 {
+		var err error
+		modelInvoice, confidence, err = processor.ProcessInvoicePages(ctx, pdfFile, pages)
+		if err != nil {
+			return handleInvoiceError(err, log)
+		}
+		if !includeConfidence {
+			confidence = make(map[string]float32)
+		}
+	} else if includeConfidence {
 		var err error
 		modelInvoice, confidence, err = processor.ProcessInvoiceWithConfidence(ctx, pdfFile)
 		if err != nil {
@@ -192,21 +327,38 @@ func runInvoice(cmd *cobra.Command, args []string) error {
 		log.Info().Msg("Running completion service to fill missing fields")
 
 		// Initialize completion service
-		completionService, err := invoice.NewInvoiceCompletionService(ctx)
+		// OCR the same pages Document AI saw so completion does not pick up the ignored ones
+		completionConfig := invoice.CompletionConfigFromEnv()
+		completionConfig.Pages = pages
+		if deskew {
+			completionConfig.Deskew = true
+		}
+		if noSummary {
+			completionConfig.NoSummary = true
+		}
+		completionConfig.IncludeRawText = includeRawText || dumpOCRPath != ""
+		var completionService invoice.InvoiceCompletionService
+		var promptRecorder *llm.PromptRecorder
+		if dumpPrompt {
+			// Record the ChatGPT requests instead of sending them
+			ocrService, err := createOCRService(ctx, completionConfig.Deskew, log)
+			if err != nil {
+				return err
+			}
+			defer closeOCRService(ocrService, log)
+			promptRecorder = llm.NewPromptRecorder()
+			completionService = invoice.NewInvoiceCompletionServiceWithDeps(ocrService, promptRecorder, completionConfig)
+		} else {
+			completionService, err = invoice.NewInvoiceCompletionServiceWithConfig(ctx, completionConfig)
+		}
 		if err != nil {
 			log.Warn().Err(err).Msg("Failed to initialize completion service, using Document AI result only")
 		} else {
 			// Reopen PDF for completion service
-			pdfFile2, err := os.Open(pdfPath)
+			pdfFile2, err := openPDF(pdfPath, pdfPassword)
 			if err != nil {
 				log.Warn().Err(err).Msg("Failed to reopen PDF for completion, using Document AI result only")
 			} else {
-				defer func() {
-					if closeErr := pdfFile2.Close(); closeErr != nil {
-						log.Warn().Err(closeErr).Msg("Failed to close PDF file for completion")
-					}
-				}()
-
 				// Run the Document AI result through completion service
 				if includeConfidence {
 					completedInvoice, completionConfidence, err := completionService.CompleteInvoiceWithConfidence(ctx, modelInvoice, pdfFile2)
@@ -235,10 +387,28 @@ func runInvoice(cmd *cobra.Command, args []string) error {
 				}
 			}
 		}
+		if promptRecorder != nil {
+			return printRecordedPrompts(promptRecorder, log)
+		}
+	}
+
+	if !completeFlag && dumpPrompt {
+		log.Warn().Msg("--dump-prompt needs --complete, the only part of the invoice command that asks ChatGPT, ignoring")
+	}
+	if !completeFlag && (includeRawText || dumpOCRPath != "") {
+		log.Warn().Msg("--include-raw-text and --dump-ocr need --complete, which runs the OCR, ignoring")
+	}
+	if completeFlag && dumpOCRPath != "" {
+		if err := dumpOCRText(dumpOCRPath, modelInvoice.OCRText, log); err != nil {
+			return err
+		}
 	}
 
 	processingDuration := time.Since(startTime)
 	invoiceData := convertToInvoiceData(modelInvoice)
+	if !includeRawText {
+		invoiceData.OCRText = ""
+	}
 
 	log.Info().
 		Str("invoice_number", invoiceData.InvoiceNumber).
@@ -264,6 +434,10 @@ func runInvoice(cmd *cobra.Command, args []string) error {
 		output.Confidence = confidence
 	}
 
+	if format == "table" {
+		return outputInvoiceTable([]InvoiceOutput{output}, outputPath, log)
+	}
+
 	// Output results as JSON
 	return outputInvoiceResults(output, outputPath, log)
 }
@@ -297,10 +471,10 @@ func validateInvoicePDF(pdfPath string, log zerolog.Logger) (os.FileInfo, error)
 	}
 
 	// Check file extension
-	if !strings.HasSuffix(strings.ToLower(pdfPath), ".pdf") {
+	if !hasDocumentExtension(pdfPath) {
 		log.Warn().
 			Str("file", pdfPath).
-			Msg("File does not have .pdf extension")
+			Msg("File does not have a .pdf or .tif extension")
 	}
 
 	// Check file size
@@ -348,9 +522,39 @@ func createInvoiceContext(timeoutSecs int, log zerolog.Logger) (context.Context,
 	return ctx, cancel
 }
 
-// createInvoiceProcessor creates and configures the invoice processor
-func createInvoiceProcessor(ctx context.Context, log zerolog.Logger) (invoice.InvoiceProcessor, error) {
-	processor, err := invoice.NewDocumentAIInvoiceProcessor(ctx)
+// addProcessingFlags registers --processing on a command that extracts invoices with Document AI, and
+// --async, which it replaces
+func addProcessingFlags(command *cobra.Command) {
+	command.Flags().String("processing", string(invoice.ProcessingAuto), "Document AI processing: sync, async (batch via Cloud Storage) or auto (async only for large PDFs)")
+	command.Flags().Bool("async", false, "Same as --processing async")
+	command.Flags().MarkDeprecated("async", "use --processing async instead")
+}
+
+// processingModeFlag returns the Document AI processing mode selected with --processing or --async.
+// Async processing is checked to be configured before anything is processed.
+func processingModeFlag(cmd *cobra.Command) (invoice.ProcessingMode, error) {
+	value, _ := cmd.Flags().GetString("processing")
+	mode, err := invoice.ParseProcessingMode(value)
+	if err != nil {
+		return "", err
+	}
+	if async, _ := cmd.Flags().GetBool("async"); async {
+		if cmd.Flags().Changed("processing") && mode != invoice.ProcessingAsync {
+			return "", fmt.Errorf("--async cannot be combined with --processing %s", mode)
+		}
+		mode = invoice.ProcessingAsync
+	}
+
+	if err := invoice.ValidateProcessingMode(mode); err != nil {
+		return "", withExitCode(ExitConfig, fmt.Errorf("--processing async needs Cloud Storage buckets for the batch requests (set them in .env or use --processing auto): %w", err))
+	}
+	return mode, nil
+}
+
+// createInvoiceProcessor creates and configures the invoice processor. The Document AI request gets the
+// same timeout as the whole command so the inner deadline cannot fire first.
+func createInvoiceProcessor(ctx context.Context, options invoice.ProcessorOptions, log zerolog.Logger) (invoice.InvoiceProcessor, error) {
+	processor, err := invoice.NewDocumentAIInvoiceProcessorWithOptions(ctx, options)
 	if err != nil {
 		if errors.Is(err, invoice.ErrMissingCredentials) {
 			log.Error().
@@ -373,6 +577,7 @@ func createInvoiceProcessor(ctx context.Context, log zerolog.Logger) (invoice.In
 				"  GOOGLE_CLOUD_PROJECT - your Google Cloud project ID\n" +
 				"  GOOGLE_CLOUD_LOCATION - processing location (us, eu, etc.)\n" +
 				"  DOCUMENT_AI_PROCESSOR_ID - your Document AI processor ID\n" +
+				"  GCS_SOURCE_BUCKET, GCS_OUTPUT_BUCKET - Cloud Storage buckets for --processing async\n" +
 				"Original error: %w", err)
 		}
 		log.Error().
@@ -385,6 +590,15 @@ func createInvoiceProcessor(ctx context.Context, log zerolog.Logger) (invoice.In
 	return processor, nil
 }
 
+// closeInvoiceProcessor closes the Document AI client of a processor created by createInvoiceProcessor
+func closeInvoiceProcessor(processor invoice.InvoiceProcessor, log zerolog.Logger) {
+	if closer, ok := processor.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close Document AI client")
+		}
+	}
+}
+
 // handleInvoiceError provides user-friendly error messages for invoice processing failures
 func handleInvoiceError(err error, log zerolog.Logger) error {
 	log.Error().Err(err).Msg("Invoice processing failed")
@@ -393,30 +607,36 @@ func handleInvoiceError(err error, log zerolog.Logger) error {
 
 	switch {
 	case errors.Is(err, context.DeadlineExceeded):
-		return fmt.Errorf("invoice processing timed out. Try increasing --timeout or processing a smaller file")
+		return withExitCode(ExitExternalAPI, fmt.Errorf("invoice processing timed out. Try increasing --timeout or processing a smaller file"))
 	case errors.Is(err, context.Canceled):
 		return fmt.Errorf("invoice processing was canceled")
+	case errors.Is(err, invoice.ErrEncryptedPDF):
+		return withExitCode(ExitInput, fmt.Errorf("PDF is password-protected. Pass the password with --pdf-password or PDF_PASSWORDS"))
 	case errors.Is(err, invoice.ErrInvalidPDF):
-		return fmt.Errorf("invalid or corrupted PDF file. Please check the file integrity")
+		return withExitCode(ExitInput, fmt.Errorf("invalid or corrupted PDF file. Please check the file integrity"))
+	case errors.Is(err, invoice.ErrUnsupportedFormat):
+		return withExitCode(ExitInput, fmt.Errorf("unsupported file format. Use a PDF, TIFF, GIF, JPEG, PNG, BMP or WEBP document"))
+	case errors.Is(err, invoice.ErrTooManyPages):
+		return withExitCode(ExitInput, fmt.Errorf("document has too many pages for synchronous processing. Configure GCS_SOURCE_BUCKET and GCS_OUTPUT_BUCKET for async processing (--processing auto or async) or select pages with --pages"))
 	case errors.Is(err, invoice.ErrDocumentTooLarge):
-		return fmt.Errorf("PDF file is too large (maximum 20MB). Try compressing or splitting the file")
+		return withExitCode(ExitInput, fmt.Errorf("PDF file is too large (maximum 20MB). Try compressing or splitting the file"))
 	case errors.Is(err, invoice.ErrProcessorNotFound):
-		return fmt.Errorf("Document AI processor not found. Please check your GOOGLE_PROCESSOR_ID environment variable")
+		return withExitCode(ExitConfig, fmt.Errorf("Document AI processor not found. Please check your GOOGLE_PROCESSOR_ID environment variable"))
 	case errors.Is(err, invoice.ErrMissingRequiredField):
-		return fmt.Errorf("could not extract required invoice fields. The PDF may not be a valid invoice format")
+		return withExitCode(ExitInput, fmt.Errorf("could not extract required invoice fields. The PDF may not be a valid invoice format"))
 	case strings.Contains(errStr, "Unauthenticated") ||
 		strings.Contains(errStr, "invalid_grant") ||
 		strings.Contains(errStr, "auth:") ||
 		strings.Contains(errStr, "credentials"):
-		return fmt.Errorf("Google Cloud authentication failed. Please check your credentials:\n\n" +
+		return withExitCode(ExitConfig, fmt.Errorf("Google Cloud authentication failed. Please check your credentials:\n\n" +
 			"1. Set GOOGLE_APPLICATION_CREDENTIALS to your service account JSON file path\n" +
 			"2. Or set GOOGLE_CREDENTIALS with inline JSON credentials\n" +
 			"3. Ensure the service account has 'Document AI API User' role\n\n" +
-			"Original error: %v", err)
+			"Original error: %v", err))
 	case strings.Contains(errStr, "PERMISSION_DENIED"):
-		return fmt.Errorf("permission denied. Please ensure your service account has 'Document AI API User' role")
+		return withExitCode(ExitConfig, fmt.Errorf("permission denied. Please ensure your service account has 'Document AI API User' role"))
 	case strings.Contains(errStr, "QUOTA_EXCEEDED"):
-		return fmt.Errorf("Document AI API quota exceeded. Check your project quotas in Google Cloud Console")
+		return withExitCode(ExitExternalAPI, fmt.Errorf("Document AI API quota exceeded. Check your project quotas in Google Cloud Console"))
 	case errors.Is(err, invoice.ErrProcessingFailed):
 		return fmt.Errorf("Document AI processing failed. This may be due to network issues or service unavailability: %w", err)
 	default:
@@ -430,16 +650,32 @@ func convertToInvoiceData(modelInvoice *models.Invoice) *InvoiceData {
 		ID:            modelInvoice.ID,
 		InvoiceNumber: modelInvoice.InvoiceNumber,
 		Type:          modelInvoice.Type,
+		TypeReasoning: modelInvoice.TypeReasoning,
+		SubType:       modelInvoice.SubType,
+		PrepaymentReference: modelInvoice.PrepaymentReference,
+		ReminderReference:   modelInvoice.ReminderReference,
+		DunningFee:          modelInvoice.DunningFee,
+		Internal:      modelInvoice.Internal,
 		Vendor:        modelInvoice.Vendor,
 		Customer:      modelInvoice.Customer,
+		VendorVATID:   modelInvoice.VendorVATID,
+		CustomerVATID: modelInvoice.CustomerVATID,
+		CustomerAddress: modelInvoice.CustomerAddress,
+		CustomerEmail:   modelInvoice.CustomerEmail,
+		CustomerContact: modelInvoice.CustomerContact,
+		PartnerID:     modelInvoice.PartnerID,
 		NetAmount:     modelInvoice.NetAmount,
 		VATAmount:     modelInvoice.VATAmount,
 		GrossAmount:   modelInvoice.GrossAmount,
+		VATInferredFrom: modelInvoice.VATInferredFrom,
 		Currency:      modelInvoice.Currency,
+		SmallBusiness: modelInvoice.SmallBusiness,
 		IsPaid:            modelInvoice.IsPaid,
-		Reference:         modelInvoice.Reference,
+		PurchaseOrder:     modelInvoice.PurchaseOrder,
+		CustomerReference: modelInvoice.CustomerReference,
 		Description:       modelInvoice.Description,
 		AccountingSummary: modelInvoice.AccountingSummary,
+		OCRText:           modelInvoice.OCRText,
 		CreatedAt:         modelInvoice.CreatedAt,
 		UpdatedAt:         modelInvoice.UpdatedAt,
 	}
@@ -451,26 +687,260 @@ func convertToInvoiceData(modelInvoice *models.Invoice) *InvoiceData {
 	if !modelInvoice.DueDate.IsZero() {
 		data.DueDate = &modelInvoice.DueDate
 	}
+	if !modelInvoice.ServiceDate.IsZero() {
+		data.ServiceDate = &modelInvoice.ServiceDate
+	}
 	if modelInvoice.PaymentDate != nil && !modelInvoice.PaymentDate.IsZero() {
 		data.PaymentDate = modelInvoice.PaymentDate
 	}
+	for _, installment := range modelInvoice.PaymentSchedule {
+		entry := InstallmentData{Amount: installment.Amount, Description: installment.Description}
+		if !installment.DueDate.IsZero() {
+			dueDate := installment.DueDate
+			entry.DueDate = &dueDate
+		}
+		data.PaymentSchedule = append(data.PaymentSchedule, entry)
+	}
+	if quality := modelInvoice.InputQuality; quality != nil {
+		data.InputQuality = &InputQualityData{
+			Score:        quality.Score,
+			Defects:      quality.Defects,
+			RotatedPages: quality.RotatedPages,
+			Low:          quality.IsLow(),
+		}
+	}
 
 	return data
 }
 
+// convertFromInvoiceData converts saved invoice output back to the internal invoice model
+func convertFromInvoiceData(data *InvoiceData) *models.Invoice {
+	invoice := &models.Invoice{
+		ID:                  data.ID,
+		InvoiceNumber:       data.InvoiceNumber,
+		Type:                data.Type,
+		TypeReasoning:       data.TypeReasoning,
+		SubType:             data.SubType,
+		PrepaymentReference: data.PrepaymentReference,
+		ReminderReference:   data.ReminderReference,
+		DunningFee:          data.DunningFee,
+		Internal:            data.Internal,
+		Vendor:              data.Vendor,
+		Customer:            data.Customer,
+		VendorVATID:         data.VendorVATID,
+		CustomerVATID:       data.CustomerVATID,
+		CustomerAddress:     data.CustomerAddress,
+		CustomerEmail:       data.CustomerEmail,
+		CustomerContact:     data.CustomerContact,
+		PartnerID:           data.PartnerID,
+		PaymentDate:         data.PaymentDate,
+		NetAmount:           data.NetAmount,
+		VATAmount:           data.VATAmount,
+		GrossAmount:         data.GrossAmount,
+		VATInferredFrom:     data.VATInferredFrom,
+		Currency:            data.Currency,
+		IsPaid:              data.IsPaid,
+		PurchaseOrder:       data.PurchaseOrder,
+		CustomerReference:   data.CustomerReference,
+		Description:         data.Description,
+		AccountingSummary:   data.AccountingSummary,
+		OCRText:             data.OCRText,
+		CreatedAt:           data.CreatedAt,
+		UpdatedAt:           data.UpdatedAt,
+	}
+
+	if data.IssueDate != nil {
+		invoice.IssueDate = *data.IssueDate
+	}
+	if data.DueDate != nil {
+		invoice.DueDate = *data.DueDate
+	}
+	if data.ServiceDate != nil {
+		invoice.ServiceDate = *data.ServiceDate
+	}
+	for _, installment := range data.PaymentSchedule {
+		entry := models.PaymentInstallment{Amount: installment.Amount, Description: installment.Description}
+		if installment.DueDate != nil {
+			entry.DueDate = *installment.DueDate
+		}
+		invoice.PaymentSchedule = append(invoice.PaymentSchedule, entry)
+	}
+	if quality := data.InputQuality; quality != nil {
+		invoice.InputQuality = &models.InputQuality{
+			Score:        quality.Score,
+			Defects:      quality.Defects,
+			RotatedPages: quality.RotatedPages,
+		}
+	}
+
+	return invoice
+}
+
+// outputMultipleInvoices writes one InvoiceOutput per invoice found in a split PDF as a JSON array,
+// or one table per invoice
+func outputMultipleInvoices(invoices []*models.Invoice, fileInfo os.FileInfo, duration time.Duration, outputPath, format string, log zerolog.Logger) error {
+	log.Info().
+		Int("invoices", len(invoices)).
+		Dur("duration", duration).
+		Msg("Multiple invoices extracted from PDF")
+
+	processedAt := time.Now()
+	outputs := make([]InvoiceOutput, 0, len(invoices))
+	for _, modelInvoice := range invoices {
+		outputs = append(outputs, InvoiceOutput{
+			Invoice: *convertToInvoiceData(modelInvoice),
+			Metadata: ProcessingMetadata{
+				FileName:           filepath.Base(fileInfo.Name()),
+				FileSize:           fileInfo.Size(),
+				ProcessedAt:        processedAt,
+				ProcessingDuration: duration,
+				ProcessorUsed:      "Google Document AI Invoice Parser",
+			},
+		})
+	}
+
+	if format == "table" {
+		return outputInvoiceTable(outputs, outputPath, log)
+	}
+	return outputInvoiceResults(outputs, outputPath, log)
+}
+
+// outputInvoiceTable writes the key fields of each invoice as an aligned table, with the confidence
+// of each field if it was requested
+func outputInvoiceTable(outputs []InvoiceOutput, outputPath string, log zerolog.Logger) error {
+	var buf bytes.Buffer
+	for i, output := range outputs {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		if len(outputs) > 1 {
+			fmt.Fprintf(&buf, "=== RECHNUNG %d/%d ===\n", i+1, len(outputs))
+		}
+		writeInvoiceTable(&buf, &output.Invoice, output.Confidence)
+	}
+	return writeInvoiceOutput(buf.Bytes(), outputPath, log)
+}
+
+// writeInvoiceTable writes one invoice as label/value rows. Empty fields are left out, amounts are
+// shown in the invoice currency.
+func writeInvoiceTable(buf *bytes.Buffer, data *InvoiceData, confidence map[string]float32) {
+	table := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	row := func(label, value, confidenceKey string) {
+		if value == "" {
+			return
+		}
+		if score, ok := confidence[confidenceKey]; ok {
+			fmt.Fprintf(table, "%s\t%s\t(%.0f%%)\n", label, value, score*100)
+			return
+		}
+		fmt.Fprintf(table, "%s\t%s\t\n", label, value)
+	}
+	date := func(value *time.Time) string {
+		if value == nil {
+			return ""
+		}
+		return value.Format("02.01.2006")
+	}
+	amount := func(cents int64) string {
+		return fmt.Sprintf("%.2f %s", float64(cents)/100, data.Currency)
+	}
+
+	invoiceType := "UNBEKANNT"
+	if data.Type == "PAYABLE" {
+		invoiceType = "EINGANGSRECHNUNG"
+	} else if data.Type == "RECEIVABLE" {
+		invoiceType = "AUSGANGSRECHNUNG"
+	} else if data.Internal {
+		invoiceType = "INTERN (Typ mit --type festlegen)"
+	}
+	switch data.SubType {
+	case models.InvoiceSubTypePrepayment:
+		invoiceType += " (Anzahlung)"
+	case models.InvoiceSubTypeFinal:
+		invoiceType += " (Schlussrechnung)"
+	case models.InvoiceSubTypeReminder:
+		invoiceType += " (Mahnung, keine neue Rechnung)"
+	}
+
+	row("Rechnungsnummer:", data.InvoiceNumber, "invoice_number")
+	row("Typ:", invoiceType, "type")
+	row("Lieferant:", data.Vendor, "vendor")
+	row("Kunde:", data.Customer, "customer")
+	row("USt-IdNr. Lieferant:", data.VendorVATID, "")
+	row("USt-IdNr. Kunde:", data.CustomerVATID, "")
+	row("Anschrift Kunde:", data.CustomerAddress, "customer_address")
+	row("E-Mail Kunde:", data.CustomerEmail, "customer_email")
+	row("Ansprechpartner:", data.CustomerContact, "customer_contact")
+	row("Rechnungsdatum:", date(data.IssueDate), "issue_date")
+	row("Leistungsdatum:", date(data.ServiceDate), "service_date")
+	row("Fälligkeitsdatum:", date(data.DueDate), "due_date")
+	if data.IsPaid {
+		paid := "ja"
+		if data.PaymentDate != nil {
+			paid += ", am " + date(data.PaymentDate)
+		}
+		row("Bereits bezahlt:", paid, "is_paid")
+	}
+	row("Netto:", amount(data.NetAmount), "net_amount")
+	row("MwSt:", amount(data.VATAmount), "vat_amount")
+	row("Brutto:", amount(data.GrossAmount), "gross_amount")
+	if data.NetAmount != 0 {
+		rate := fmt.Sprintf("%.1f %%", float64(data.VATAmount)/float64(data.NetAmount)*100)
+		if data.VATInferredFrom != "" {
+			rate += " (aus Brutto abgeleitet)"
+		}
+		row("MwSt-Satz:", rate, "")
+	}
+	row("Bestellnummer:", data.PurchaseOrder, "purchase_order")
+	row("Kundenreferenz:", data.CustomerReference, "customer_reference")
+	row("Anzahlungsrechnung:", data.PrepaymentReference, "prepayment_reference")
+	row("Gemahnte Rechnung:", data.ReminderReference, "reminder_reference")
+	if data.DunningFee != 0 {
+		row("Mahngebühr:", amount(data.DunningFee), "dunning_fee")
+	}
+	row("Beschreibung:", data.Description, "description")
+	for _, installment := range data.PaymentSchedule {
+		label := "Rate " + date(installment.DueDate) + ":"
+		if installment.DueDate == nil {
+			label = "Rate (ohne Datum):"
+		}
+		row(label, amount(installment.Amount), "")
+	}
+	if quality := data.InputQuality; quality != nil {
+		value := fmt.Sprintf("%.0f %%", quality.Score*100)
+		if len(quality.Defects) > 0 {
+			value += " (" + strings.Join(quality.Defects, ", ") + ")"
+		}
+		if len(quality.RotatedPages) > 0 {
+			value += fmt.Sprintf(", gedrehte Seiten: %v", quality.RotatedPages)
+		}
+		if quality.Low {
+			value += " – neu scannen"
+		}
+		row("Scan-Qualität:", value, "")
+	}
+	table.Flush()
+}
+
 // outputInvoiceResults formats and outputs the invoice processing results as JSON
-func outputInvoiceResults(output InvoiceOutput, outputPath string, log zerolog.Logger) error {
-	// Marshal to JSON with pretty printing
-	jsonData, err := json.MarshalIndent(output, "", "  ")
+func outputInvoiceResults(output interface{}, outputPath string, log zerolog.Logger) error {
+	jsonData, err := marshalJSON(output)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to marshal invoice data to JSON")
 		return fmt.Errorf("failed to create JSON output: %w", err)
 	}
 
+	return writeInvoiceOutput(jsonData, outputPath, log)
+}
+
+// writeInvoiceOutput writes the formatted results to outputPath, or to stdout if it is empty
+func writeInvoiceOutput(data []byte, outputPath string, log zerolog.Logger) error {
+	var err error
+
 	// Write output
 	if outputPath != "" {
 		// Write to file
-		err = os.WriteFile(outputPath, jsonData, 0644)
+		err = os.WriteFile(outputPath, data, 0644)
 		if err != nil {
 			log.Error().
 				Err(err).
@@ -481,11 +951,11 @@ func outputInvoiceResults(output InvoiceOutput, outputPath string, log zerolog.L
 
 		log.Info().
 			Str("output_file", outputPath).
-			Int("bytes", len(jsonData)).
+			Int("bytes", len(data)).
 			Msg("Invoice data written to file")
 	} else {
 		// Write to stdout
-		_, err = os.Stdout.Write(jsonData)
+		_, err = os.Stdout.Write(data)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to write to stdout")
 			return fmt.Errorf("failed to write output: %w", err)
@@ -495,4 +965,41 @@ func outputInvoiceResults(output InvoiceOutput, outputPath string, log zerolog.L
 	}
 
 	return nil
+}
+
+// printRecordedPrompts writes the ChatGPT requests recorded for --dump-prompt to stdout
+func printRecordedPrompts(recorder *llm.PromptRecorder, log zerolog.Logger) error {
+	requests := len(recorder.Requests())
+	if requests == 0 {
+		log.Warn().Msg("No prompt to dump, no ChatGPT request was needed (Document AI extracted all fields)")
+		return nil
+	}
+
+	log.Info().Int("requests", requests).Msg("Printing the recorded prompts instead of sending them")
+	if _, err := recorder.WriteTo(os.Stdout); err != nil {
+		return fmt.Errorf("failed to write prompts: %w", err)
+	}
+	return nil
+}
+
+// dumpOCRText writes the OCR text completion extracted from to path. An empty text is not written:
+// completion only runs OCR if Document AI left fields missing.
+func dumpOCRText(path, text string, log zerolog.Logger) error {
+	if text == "" {
+		log.Warn().
+			Str("dump_file", path).
+			Msg("No OCR text to dump, completion did not run OCR (Document AI extracted all fields or completion failed)")
+		return nil
+	}
+
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		log.Error().Err(err).Str("dump_file", path).Msg("Failed to write OCR text")
+		return fmt.Errorf("failed to write OCR text: %w", err)
+	}
+
+	log.Info().
+		Str("dump_file", path).
+		Int("bytes", len(text)).
+		Msg("OCR text written to file")
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// compactJSON is set by the global --compact flag
+var compactJSON bool
+
+// marshalJSON encodes v for output: indented with two spaces by default, on a single line with
+// --compact
+func marshalJSON(v interface{}) ([]byte, error) {
+	if compactJSON {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// encodeJSON writes v to w as marshalJSON encodes it, followed by a newline
+func encodeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	if !compactJSON {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(v)
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// datevMessages holds the console labels and messages of the datev command in one language.
+// Fields whose comment names a format verb are format strings.
+type datevMessages struct {
+	Header            string
+	InvoiceSection    string
+	InvoiceNumber     string
+	Type              string
+	TypeUnknown       string
+	TypePayable       string
+	TypeReceivable    string
+	SubType           string
+	SubTypePrepayment string
+	SubTypeFinal      string
+	SettlesPrepayment string // %s: invoice number of the prepayment
+	SubTypeReminder   string
+	RemindsInvoice    string // %s: invoice number of the reminded invoice
+	DunningFee        string
+	Vendor            string
+	Customer          string
+	Amount            string
+	Net               string
+	VAT               string
+	Gross             string
+	IssueDate         string
+	ServiceDate       string
+	DueDate           string
+	PaymentSchedule   string
+	Paid              string
+	PaidOn            string // %s: payment date
+	NoDate            string
+	PurchaseOrder     string
+	CustomerReference string
+	Description       string
+	TemplateNotice    string
+	TemplateSection   string // %s: chart of accounts
+	BookingSection    string // %s: chart of accounts
+	DebitAccount      string
+	CreditAccount     string
+	TaxKey            string
+	BookingText       string
+	DocumentNumber    string
+	BookingDate       string
+	AccountingPeriod  string
+	CostCenter        string
+	SourceDocument    string
+	SplitSection      string
+	SplitTaxKey       string
+	SplitRate         string
+	PostingSection    string
+	PostingAccount    string
+	PostingDebit      string
+	PostingCredit     string
+	Explanation       string
+	Warning           string
+	DetailsSection    string
+	ProcessingTime    string // %.2f: seconds
+	GeneratedAt       string
+	ReasoningSection  string
+	ChangesSection    string
+	OCRTextSection    string
+	NoChanges         string
+	EmptyValue        string
+	ChangeOverwritten string
+	ChangeFilled      string
+	FooterNotice      string
+	FooterReview      string
+	DateFormat        string
+	DateTimeFormat    string
+}
+
+// datevCatalog maps the --lang values to their messages; German is the default
+var datevCatalog = map[string]datevMessages{
+	"de": {
+		Header:            "DATEV BUCHUNGSVORSCHLAG",
+		InvoiceSection:    "=== RECHNUNGSDATEN ===",
+		InvoiceNumber:     "Rechnungsnummer",
+		Type:              "Typ",
+		TypeUnknown:       "UNBEKANNT",
+		TypePayable:       "EINGANGSRECHNUNG",
+		TypeReceivable:    "AUSGANGSRECHNUNG",
+		SubType:           "Rechnungsart",
+		SubTypePrepayment: "ANZAHLUNGSRECHNUNG",
+		SubTypeFinal:      "SCHLUSSRECHNUNG",
+		SettlesPrepayment: "verrechnet Anzahlung %s",
+		SubTypeReminder:   "MAHNUNG (keine neue Rechnung)",
+		RemindsInvoice:    "zu Rechnung %s",
+		DunningFee:        "Mahngebühr",
+		Vendor:            "Lieferant",
+		Customer:          "Kunde",
+		Amount:            "Betrag",
+		Net:               "Netto",
+		VAT:               "MwSt",
+		Gross:             "Brutto",
+		IssueDate:         "Rechnungsdatum",
+		ServiceDate:       "Leistungsdatum",
+		DueDate:           "Fälligkeitsdatum",
+		PaymentSchedule:   "Zahlungsplan",
+		Paid:              "Bereits bezahlt",
+		PaidOn:            "am %s",
+		NoDate:            "ohne Datum",
+		PurchaseOrder:     "Bestellnummer",
+		CustomerReference: "Kundenreferenz",
+		Description:       "Beschreibung",
+		TemplateNotice:    "⚠️  KI-Buchung übersprungen: Soll-, Habenkonto und Steuerschlüssel bitte manuell ergänzen.",
+		TemplateSection:   "=== DATEV BUCHUNGSVORLAGE (%s) ===",
+		BookingSection:    "=== DATEV BUCHUNGSVORSCHLAG (%s) ===",
+		DebitAccount:      "Sollkonto",
+		CreditAccount:     "Habenkonto",
+		TaxKey:            "Steuerschlüssel",
+		BookingText:       "Buchungstext",
+		DocumentNumber:    "Belegnummer",
+		BookingDate:       "Buchungsdatum",
+		AccountingPeriod:  "Buchungsperiode",
+		CostCenter:        "Kostenstelle",
+		SourceDocument:    "Quelle",
+		SplitSection:      "=== AUFTEILUNG NACH STEUERSATZ ===",
+		SplitTaxKey:       "Schlüssel",
+		SplitRate:         "Satz",
+		PostingSection:    "=== BUCHUNGSZEILEN ===",
+		PostingAccount:    "Konto",
+		PostingDebit:      "Soll",
+		PostingCredit:     "Haben",
+		Explanation:       "Erläuterung",
+		Warning:           "Warnung",
+		DetailsSection:    "=== DETAILLIERTE INFORMATIONEN ===",
+		ProcessingTime:    "Verarbeitungszeit: %.2f Sekunden",
+		GeneratedAt:       "Generiert am",
+		ReasoningSection:  "=== BUCHUNGSLOGIK ===",
+		ChangesSection:    "=== ÄNDERUNGEN DURCH KI-VERVOLLSTÄNDIGUNG ===",
+		OCRTextSection:    "=== OCR-TEXT DER VERVOLLSTÄNDIGUNG ===",
+		NoChanges:         "Keine - alle Felder stammen aus Document AI",
+		EmptyValue:        "(leer)",
+		ChangeOverwritten: "überschrieben",
+		ChangeFilled:      "ergänzt",
+		FooterNotice:      "Hinweis: Dies ist ein KI-generierter Buchungsvorschlag.",
+		FooterReview:      "Bitte prüfen Sie die Buchung vor der Übernahme in DATEV.",
+		DateFormat:        "02.01.2006",
+		DateTimeFormat:    "02.01.2006 15:04:05",
+	},
+	"en": {
+		Header:            "DATEV BOOKING PROPOSAL",
+		InvoiceSection:    "=== INVOICE DATA ===",
+		InvoiceNumber:     "Invoice number",
+		Type:              "Type",
+		TypeUnknown:       "UNKNOWN",
+		TypePayable:       "INCOMING INVOICE (PAYABLE)",
+		TypeReceivable:    "OUTGOING INVOICE (RECEIVABLE)",
+		SubType:           "Invoice kind",
+		SubTypePrepayment: "PREPAYMENT INVOICE",
+		SubTypeFinal:      "FINAL INVOICE",
+		SettlesPrepayment: "settles prepayment %s",
+		SubTypeReminder:   "PAYMENT REMINDER (not a new invoice)",
+		RemindsInvoice:    "for invoice %s",
+		DunningFee:        "Dunning fee",
+		Vendor:            "Vendor",
+		Customer:          "Customer",
+		Amount:            "Amount",
+		Net:               "Net",
+		VAT:               "VAT",
+		Gross:             "Gross",
+		IssueDate:         "Invoice date",
+		ServiceDate:       "Service date",
+		DueDate:           "Due date",
+		PaymentSchedule:   "Payment schedule",
+		Paid:              "Already paid",
+		PaidOn:            "on %s",
+		NoDate:            "no date",
+		PurchaseOrder:     "Purchase order",
+		CustomerReference: "Customer reference",
+		Description:       "Description",
+		TemplateNotice:    "⚠️  AI booking skipped: please fill in the debit account, credit account and tax key manually.",
+		TemplateSection:   "=== DATEV BOOKING TEMPLATE (%s) ===",
+		BookingSection:    "=== DATEV BOOKING PROPOSAL (%s) ===",
+		DebitAccount:      "Debit account (Soll)",
+		CreditAccount:     "Credit account (Haben)",
+		TaxKey:            "Tax key (Steuerschlüssel)",
+		BookingText:       "Booking text",
+		DocumentNumber:    "Document number",
+		BookingDate:       "Booking date",
+		AccountingPeriod:  "Accounting period",
+		CostCenter:        "Cost center",
+		SourceDocument:    "Source document",
+		SplitSection:      "=== SPLIT BY VAT RATE ===",
+		SplitTaxKey:       "Tax key",
+		SplitRate:         "Rate",
+		PostingSection:    "=== POSTING LINES ===",
+		PostingAccount:    "Account",
+		PostingDebit:      "Debit",
+		PostingCredit:     "Credit",
+		Explanation:       "Explanation",
+		Warning:           "Warning",
+		DetailsSection:    "=== DETAILS ===",
+		ProcessingTime:    "Processing time: %.2f seconds",
+		GeneratedAt:       "Generated at",
+		ReasoningSection:  "=== BOOKING RATIONALE ===",
+		ChangesSection:    "=== CHANGES BY AI COMPLETION ===",
+		OCRTextSection:    "=== OCR TEXT USED BY COMPLETION ===",
+		NoChanges:         "None - all fields come from Document AI",
+		EmptyValue:        "(empty)",
+		ChangeOverwritten: "overwritten",
+		ChangeFilled:      "filled in",
+		FooterNotice:      "Note: this booking proposal was generated by AI.",
+		FooterReview:      "Please review the booking before importing it into DATEV.",
+		DateFormat:        "2006-01-02",
+		DateTimeFormat:    "2006-01-02 15:04:05",
+	},
+}
+
+// resolveLanguage returns the --lang value, falling back to OUTPUT_LANGUAGE and then German
+func resolveLanguage(lang string) (string, error) {
+	if lang == "" {
+		lang = os.Getenv("OUTPUT_LANGUAGE")
+	}
+	if lang == "" {
+		return "de", nil
+	}
+
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if _, ok := datevCatalog[lang]; !ok {
+		return "", fmt.Errorf("invalid language: %q (must be 'de' or 'en')", lang)
+	}
+	return lang, nil
+}
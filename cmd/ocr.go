@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -16,6 +19,7 @@ import (
 	"github.com/spf13/cobra"
 	"tools/internal/logger"
 	"tools/internal/ocr"
+	"tools/internal/pdf"
 )
 
 var ocrCmd = &cobra.Command{
@@ -27,6 +31,9 @@ This command uses Google Cloud Vision API's document text detection to extract
 text from PDF files with high accuracy. The service supports multi-page PDFs
 up to 5 pages and 20MB in size for synchronous processing.
 
+TIFF scans are accepted as well. A multipage TIFF with more than 5 pages is
+rejected instead of being cut off after page 5; select the pages with --pages.
+
 Required environment variables:
   GOOGLE_APPLICATION_CREDENTIALS - Path to service account JSON file, OR
   GOOGLE_CREDENTIALS - Inline JSON credentials string
@@ -41,21 +48,25 @@ Required environment variables:
   tools ocr invoice.pdf --metadata --json -o result.json
 
   # Process with custom timeout
-  tools ocr large-document.pdf --timeout 600`,
+  tools ocr large-document.pdf --timeout 600
+
+  # Only OCR the first two pages (e.g. skip attached terms and conditions)
+  tools ocr long-document.pdf --pages 1-2`,
 	Args: cobra.ExactArgs(1),
 	RunE: runOCR,
 }
 
 // OCROutput represents the JSON output structure when --json flag is used
 type OCROutput struct {
-	Text               string    `json:"text"`
-	PageCount          int       `json:"page_count,omitempty"`
-	Confidence         float32   `json:"confidence,omitempty"`
-	LanguageCodes      []string  `json:"language_codes,omitempty"`
-	ProcessedAt        time.Time `json:"processed_at,omitempty"`
-	ProcessingDuration string    `json:"processing_duration,omitempty"`
-	FileName           string    `json:"file_name"`
-	FileSize           int64     `json:"file_size"`
+	Text               string                `json:"text"`
+	PageCount          int                   `json:"page_count,omitempty"`
+	Confidence         float32               `json:"confidence,omitempty"`
+	LanguageCodes      []string              `json:"language_codes,omitempty"`
+	ProcessedAt        time.Time             `json:"processed_at,omitempty"`
+	ProcessingDuration string                `json:"processing_duration,omitempty"`
+	Orientations       []ocr.PageOrientation `json:"orientation_corrections,omitempty"`
+	FileName           string                `json:"file_name"`
+	FileSize           int64                 `json:"file_size"`
 }
 
 func init() {
@@ -65,6 +76,10 @@ func init() {
 	ocrCmd.Flags().BoolP("metadata", "m", false, "Include metadata in output")
 	ocrCmd.Flags().Bool("json", false, "Output as JSON")
 	ocrCmd.Flags().Int("timeout", 300, "Processing timeout in seconds")
+	ocrCmd.Flags().String("pages", "", "Only process these pages, e.g. 1, 1-2 or 1,3 (default: all pages)")
+	ocrCmd.Flags().Bool("deskew", false, "Detect rotated or skewed pages (e.g. photographed receipts) and rebuild their text in reading order")
+	ocrCmd.Flags().String("pdf-password", "", "Password for encrypted PDFs (tried before PDF_PASSWORDS)")
+	ocrCmd.Flags().Bool("force", false, "Re-extract the PDF even if a cached result exists and overwrite the cache entry")
 }
 
 func runOCR(cmd *cobra.Command, args []string) error {
@@ -75,8 +90,17 @@ func runOCR(cmd *cobra.Command, args []string) error {
 	includeMetadata, _ := cmd.Flags().GetBool("metadata")
 	jsonOutput, _ := cmd.Flags().GetBool("json")
 	timeoutSecs, _ := cmd.Flags().GetInt("timeout")
+	pagesSpec, _ := cmd.Flags().GetString("pages")
+	deskew, _ := cmd.Flags().GetBool("deskew")
+	pdfPassword, _ := cmd.Flags().GetString("pdf-password")
+	force, _ := cmd.Flags().GetBool("force")
 	
 	pdfPath := args[0]
+
+	pages, err := parsePageRange(pagesSpec)
+	if err != nil {
+		return err
+	}
 	
 	log.Info().
 		Str("file", pdfPath).
@@ -84,12 +108,13 @@ func runOCR(cmd *cobra.Command, args []string) error {
 		Bool("metadata", includeMetadata).
 		Bool("json", jsonOutput).
 		Int("timeout", timeoutSecs).
+		Ints32("pages", pages).
 		Msg("Starting OCR processing")
 
 	// Validate and get file info
 	fileInfo, err := validatePDFFile(pdfPath, log)
 	if err != nil {
-		return err
+		return withExitCode(ExitInput, err)
 	}
 
 	// Create context with timeout and signal handling
@@ -97,25 +122,23 @@ func runOCR(cmd *cobra.Command, args []string) error {
 	defer cancel()
 
 	// Create OCR service
-	ocrService, err := createOCRService(ctx, log)
+	ocrService, err := createOCRService(ctx, deskew, log)
 	if err != nil {
 		return err
 	}
+	if store := openExtractionCache(log); store != nil {
+		ocrService = ocr.NewCachedOCRService(ocrService, store, force)
+	}
 
-	// Open PDF file
-	pdfFile, err := os.Open(pdfPath)
+	// Read PDF file, decrypting it if it is password-protected
+	pdfFile, err := openPDF(pdfPath, pdfPassword)
 	if err != nil {
 		log.Error().
 			Err(err).
 			Str("file", pdfPath).
 			Msg("Failed to open PDF file")
-		return fmt.Errorf("failed to open PDF file: %w", err)
+		return err
 	}
-	defer func() {
-		if closeErr := pdfFile.Close(); closeErr != nil {
-			log.Warn().Err(closeErr).Msg("Failed to close PDF file")
-		}
-	}()
 
 	log.Info().
 		Str("file", pdfPath).
@@ -126,7 +149,9 @@ func runOCR(cmd *cobra.Command, args []string) error {
 	startTime := time.Now()
 	var result *ocr.OCRResult
 	
-	if includeMetadata || jsonOutput {
+	if len(pages) > 0 {
+		result, err = ocrService.ProcessPDFPages(ctx, pdfFile, pages)
+	} else if includeMetadata || jsonOutput {
 		result, err = ocrService.ProcessPDFWithMetadata(ctx, pdfFile)
 	} else {
 		text, processErr := ocrService.ProcessPDF(ctx, pdfFile)
@@ -154,6 +179,14 @@ func runOCR(cmd *cobra.Command, args []string) error {
 		Int("text_length", len(result.Text)).
 		Msg("OCR processing completed successfully")
 
+	for _, orientation := range result.Orientations {
+		log.Info().
+			Int("page", orientation.Page).
+			Int("rotation", orientation.Rotation).
+			Float64("skew", orientation.Skew).
+			Msg("Rebuilt text of rotated or skewed page")
+	}
+
 	// Format and output results
 	return outputResults(result, fileInfo, outputPath, jsonOutput, includeMetadata, log)
 }
@@ -187,10 +220,10 @@ func validatePDFFile(pdfPath string, log zerolog.Logger) (os.FileInfo, error) {
 	}
 
 	// Check file extension (basic validation)
-	if !strings.HasSuffix(strings.ToLower(pdfPath), ".pdf") {
+	if !hasDocumentExtension(pdfPath) {
 		log.Warn().
 			Str("file", pdfPath).
-			Msg("File does not have .pdf extension")
+			Msg("File does not have a .pdf or .tif extension")
 	}
 
 	// Check file size
@@ -214,6 +247,81 @@ func validatePDFFile(pdfPath string, log zerolog.Logger) (os.FileInfo, error) {
 	return fileInfo, nil
 }
 
+// hasDocumentExtension reports whether the path has the extension of a PDF or TIFF document. The
+// services detect the format from the content, so other extensions only get a warning.
+func hasDocumentExtension(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf", ".tif", ".tiff":
+		return true
+	}
+	return false
+}
+
+// openPDF reads the PDF and decrypts it if it is password-protected. password (from --pdf-password)
+// is tried before the passwords in PDF_PASSWORDS.
+func openPDF(pdfPath, password string) (*bytes.Reader, error) {
+	pdfBytes, err := os.ReadFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF file: %w", err)
+	}
+
+	var passwords []string
+	if password != "" {
+		passwords = append(passwords, password)
+	}
+	pdfBytes, err = pdf.Decrypt(pdfBytes, append(passwords, pdf.PasswordsFromEnv()...))
+	if err != nil {
+		return nil, withExitCode(ExitInput, fmt.Errorf("PDF is password-protected. Pass the password with --pdf-password or PDF_PASSWORDS: %w", err))
+	}
+
+	return bytes.NewReader(pdfBytes), nil
+}
+
+// maxPageNumber bounds the pages of --pages, far above the documents Document AI and Vision accept, so
+// that a mistyped range such as 1-1000000000 fails instead of allocating every page number
+const maxPageNumber = 10000
+
+// parsePageRange parses a --pages value such as "1", "1-2" or "1,3-4" into sorted, unique 1-based page numbers.
+// An empty value returns nil, meaning all pages.
+func parsePageRange(spec string) ([]int32, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	seen := make(map[int32]bool)
+	var pages []int32
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		startStr, endStr, isRange := strings.Cut(part, "-")
+		if !isRange {
+			endStr = startStr
+		}
+
+		start, err := strconv.Atoi(strings.TrimSpace(startStr))
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("invalid --pages value %q: pages must be positive numbers like 1, 1-2 or 1,3", spec)
+		}
+		end, err := strconv.Atoi(strings.TrimSpace(endStr))
+		if err != nil || end < start {
+			return nil, fmt.Errorf("invalid --pages value %q: range %q must be ascending", spec, part)
+		}
+		if end > maxPageNumber {
+			return nil, fmt.Errorf("invalid --pages value %q: pages beyond %d are not supported", spec, maxPageNumber)
+		}
+
+		for page := start; page <= end; page++ {
+			if !seen[int32(page)] {
+				seen[int32(page)] = true
+				pages = append(pages, int32(page))
+			}
+		}
+	}
+
+	sort.Slice(pages, func(i, j int) bool { return pages[i] < pages[j] })
+	return pages, nil
+}
+
 // createContextWithTimeout creates a context with timeout and signal handling
 func createContextWithTimeout(timeoutSecs int, log zerolog.Logger) (context.Context, context.CancelFunc) {
 	// Create context with timeout
@@ -228,7 +336,7 @@ func createContextWithTimeout(timeoutSecs int, log zerolog.Logger) (context.Cont
 		case sig := <-sigChan:
 			log.Info().
 				Str("signal", sig.String()).
-				Msg("Received interrupt signal, canceling OCR processing")
+				Msg("Received interrupt signal, canceling processing")
 			cancel()
 		case <-ctx.Done():
 			// Context completed normally
@@ -239,23 +347,23 @@ func createContextWithTimeout(timeoutSecs int, log zerolog.Logger) (context.Cont
 }
 
 // createOCRService creates and configures the OCR service
-func createOCRService(ctx context.Context, log zerolog.Logger) (ocr.OCRService, error) {
+func createOCRService(ctx context.Context, deskew bool, log zerolog.Logger) (ocr.OCRService, error) {
 	// Check if credentials are configured before attempting to create service
 	hasCredentials := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" || os.Getenv("GOOGLE_CREDENTIALS") != ""
 	
 	if !hasCredentials {
 		log.Error().Msg("Google Cloud credentials not configured")
-		return nil, fmt.Errorf("Google Cloud credentials not configured. Please set one of:\n\n" +
+		return nil, withExitCode(ExitConfig, fmt.Errorf("Google Cloud credentials not configured. Please set one of:\n\n" +
 			"1. Export GOOGLE_APPLICATION_CREDENTIALS with path to service account JSON:\n" +
 			"   export GOOGLE_APPLICATION_CREDENTIALS=/path/to/service-account-key.json\n\n" +
 			"2. Export GOOGLE_CREDENTIALS with inline JSON:\n" +
 			"   export GOOGLE_CREDENTIALS='{\"type\":\"service_account\",\"project_id\":\"your-project\",...}'\n\n" +
 			"3. Use Application Default Credentials (if gcloud is configured):\n" +
 			"   gcloud auth application-default login\n\n" +
-			"4. Check that your .env file contains the credentials variables")
+			"4. Check that your .env file contains the credentials variables"))
 	}
 	
-	ocrService, err := ocr.NewGoogleVisionOCRService(ctx)
+	ocrService, err := ocr.NewGoogleVisionOCRServiceWithOptions(ctx, ocr.VisionOptions{Deskew: deskew})
 	if err != nil {
 		if errors.Is(err, ocr.ErrMissingCredentials) {
 			log.Error().
@@ -274,7 +382,16 @@ func createOCRService(ctx context.Context, log zerolog.Logger) (ocr.OCRService,
 	}
 
 	log.Debug().Msg("OCR service created successfully")
-	return ocrService, nil
+	return ocr.NewLimitedOCRService(ocrService, ocr.MaxConcurrentFromEnv()), nil
+}
+
+// closeOCRService closes the Vision client of a service created by createOCRService
+func closeOCRService(ocrService ocr.OCRService, log zerolog.Logger) {
+	if closer, ok := ocrService.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close Vision client")
+		}
+	}
 }
 
 // handleOCRError provides user-friendly error messages for OCR failures
@@ -285,23 +402,27 @@ func handleOCRError(err error, log zerolog.Logger) error {
 
 	switch {
 	case errors.Is(err, context.DeadlineExceeded):
-		return fmt.Errorf("OCR processing timed out. Try increasing --timeout or processing a smaller file")
+		return withExitCode(ExitExternalAPI, fmt.Errorf("OCR processing timed out. Try increasing --timeout or processing a smaller file"))
 	case errors.Is(err, context.Canceled):
 		return fmt.Errorf("OCR processing was canceled")
 	case errors.Is(err, ocr.ErrPDFTooLarge):
-		return fmt.Errorf("PDF file is too large (maximum 20MB). Try compressing or splitting the file")
+		return withExitCode(ExitInput, fmt.Errorf("PDF file is too large (maximum 20MB). Try compressing or splitting the file"))
 	case errors.Is(err, ocr.ErrTooManyPages):
-		return fmt.Errorf("PDF has too many pages (maximum 5 pages). Try splitting into smaller files")
+		return withExitCode(ExitInput, fmt.Errorf("document has too many pages (maximum 5 pages). Select pages with --pages or split the file"))
+	case errors.Is(err, ocr.ErrUnsupportedFormat):
+		return withExitCode(ExitInput, fmt.Errorf("unsupported file format. Only PDF and TIFF documents can be processed"))
+	case errors.Is(err, ocr.ErrEncryptedPDF):
+		return withExitCode(ExitInput, fmt.Errorf("PDF is password-protected. Pass the password with --pdf-password or PDF_PASSWORDS"))
 	case errors.Is(err, ocr.ErrInvalidPDF):
-		return fmt.Errorf("invalid or corrupted PDF file. Please check the file integrity")
+		return withExitCode(ExitInput, fmt.Errorf("invalid or corrupted PDF file. Please check the file integrity"))
 	case errors.Is(err, ocr.ErrEmptyDocument):
-		return fmt.Errorf("no readable text found in the document. The PDF may contain only images or be corrupted")
+		return withExitCode(ExitInput, fmt.Errorf("no readable text found in the document. The PDF may contain only images or be corrupted"))
 	case strings.Contains(errStr, "Unauthenticated") || 
 		 strings.Contains(errStr, "invalid_grant") || 
 		 strings.Contains(errStr, "invalid_rapt") ||
 		 strings.Contains(errStr, "auth:") ||
 		 strings.Contains(errStr, "transport: per-RPC creds failed"):
-		return fmt.Errorf("Google Cloud authentication failed. Please check your credentials:\n\n" +
+		return withExitCode(ExitConfig, fmt.Errorf("Google Cloud authentication failed. Please check your credentials:\n\n" +
 			"1. Set GOOGLE_APPLICATION_CREDENTIALS to your service account JSON file path:\n" +
 			"   export GOOGLE_APPLICATION_CREDENTIALS=/path/to/service-account-key.json\n\n" +
 			"2. Or set GOOGLE_CREDENTIALS with inline JSON:\n" +
@@ -309,17 +430,17 @@ func handleOCRError(err error, log zerolog.Logger) error {
 			"3. Ensure the service account has 'Cloud Vision API User' role\n\n" +
 			"4. If using Application Default Credentials, run:\n" +
 			"   gcloud auth application-default login\n\n" +
-			"Original error: %v", err)
+			"Original error: %v", err))
 	case strings.Contains(errStr, "PERMISSION_DENIED") ||
 		 strings.Contains(errStr, "permission") ||
 		 strings.Contains(errStr, "forbidden"):
-		return fmt.Errorf("permission denied. Please ensure your Google Cloud service account has the 'Cloud Vision API User' role")
+		return withExitCode(ExitConfig, fmt.Errorf("permission denied. Please ensure your Google Cloud service account has the 'Cloud Vision API User' role"))
 	case strings.Contains(errStr, "QUOTA_EXCEEDED") ||
 		 strings.Contains(errStr, "quota"):
-		return fmt.Errorf("Google Cloud Vision API quota exceeded. Check your project quotas in the Google Cloud Console")
+		return withExitCode(ExitExternalAPI, fmt.Errorf("Google Cloud Vision API quota exceeded. Check your project quotas in the Google Cloud Console"))
 	case strings.Contains(errStr, "API_KEY") ||
 		 strings.Contains(errStr, "api key"):
-		return fmt.Errorf("invalid API key. Please check your Google Cloud credentials")
+		return withExitCode(ExitConfig, fmt.Errorf("invalid API key. Please check your Google Cloud credentials"))
 	case errors.Is(err, ocr.ErrOCRFailed):
 		return fmt.Errorf("OCR processing failed. This may be due to network issues, API quota limits, or service unavailability: %w", err)
 	default:
@@ -344,9 +465,10 @@ func outputResults(result *ocr.OCRResult, fileInfo os.FileInfo, outputPath strin
 			LanguageCodes:      result.LanguageCodes,
 			ProcessedAt:        result.ProcessedAt,
 			ProcessingDuration: result.ProcessingDuration.String(),
+			Orientations:       result.Orientations,
 		}
 		
-		outputData, err = json.MarshalIndent(ocrOutput, "", "  ")
+		outputData, err = marshalJSON(ocrOutput)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to marshal JSON output")
 			return fmt.Errorf("failed to create JSON output: %w", err)
@@ -366,6 +488,9 @@ func outputResults(result *ocr.OCRResult, fileInfo os.FileInfo, outputPath strin
 			if len(result.LanguageCodes) > 0 {
 				output.WriteString(fmt.Sprintf("Languages: %s\n", strings.Join(result.LanguageCodes, ", ")))
 			}
+			for _, orientation := range result.Orientations {
+				output.WriteString(fmt.Sprintf("Page %d deskewed: rotated %d°, skew %.1f°\n", orientation.Page, orientation.Rotation, orientation.Skew))
+			}
 			output.WriteString(fmt.Sprintf("Processing time: %v\n", result.ProcessingDuration))
 			output.WriteString(fmt.Sprintf("Processed at: %s\n", result.ProcessedAt.Format(time.RFC3339)))
 			output.WriteString("\n=== Extracted Text ===\n\n")
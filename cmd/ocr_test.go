@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePageRange(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []int32
+		wantErr bool
+	}{
+		{spec: "", want: nil},
+		{spec: "  ", want: nil},
+		{spec: "2", want: []int32{2}},
+		{spec: "1-3", want: []int32{1, 2, 3}},
+		{spec: " 1 - 2 , 5 ", want: []int32{1, 2, 5}},
+		{spec: "4,1-2,2-3", want: []int32{1, 2, 3, 4}},
+		{spec: "3-3", want: []int32{3}},
+		{spec: "10000", want: []int32{10000}},
+		{spec: "3-1", wantErr: true},
+		{spec: "0", wantErr: true},
+		{spec: "0-2", wantErr: true},
+		{spec: "-2", wantErr: true},
+		{spec: "1,", wantErr: true},
+		{spec: "a-b", wantErr: true},
+		{spec: "1-2-3", wantErr: true},
+		{spec: "10001", wantErr: true},
+		{spec: "1-4294967297", wantErr: true},
+		{spec: "99999999999999999999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parsePageRange(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePageRange(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePageRange(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+	"tools/internal/booking"
+	"tools/internal/db"
+	"tools/internal/logger"
+	"tools/internal/sheets"
+)
+
+var pipelineCmd = &cobra.Command{
+	Use:   "pipeline [pdf-file]",
+	Short: "Extract, complete and book one PDF invoice and optionally write it to Google Sheets",
+	Long: `Run the whole flow of datev-batch for a single file: Document AI extraction,
+completion with OCR and ChatGPT, and the DATEV booking. The result is printed like
+"tools datev" prints it.
+
+With --write the invoice is also written to the Google Sheet, as datev-batch
+writes each file: payables to the "Kreditoren" and receivables to the "Debitoren"
+tab, or to the tab GOOGLE_SHEET_URL links to. --append-mode update replaces the
+row of the same invoice instead of adding one. Without --write nothing is written
+to the sheet, which makes it a preview of the row datev-batch would add.
+
+A payment reminder without dunning fee is reported as skipped and not written.
+The sheet is only written if the booking succeeded; a failed file exits with an
+error instead of adding an error row. --strict makes an invoice without an
+invoice number such a failure instead of booking it.
+
+Required environment variables: as for datev-batch; GOOGLE_SHEET_URL only with
+--write.
+
+Optional environment variables:
+  DB_PATH - SQLite database the invoice and booking are also stored in (see "tools db")`,
+	Example: `  # Book an Eingangsrechnung and look at the result
+  tools pipeline rechnung.pdf --type payable
+
+  # Add a late Ausgangsrechnung to the Debitoren sheet
+  tools pipeline rechnung.pdf --type receivable --write
+
+  # Correct the existing row after re-scanning the invoice
+  tools pipeline rechnung.pdf --type payable --write --append-mode update`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPipeline,
+}
+
+func init() {
+	rootCmd.AddCommand(pipelineCmd)
+
+	pipelineCmd.Flags().String("type", "", "Rechnungstyp (payable=Eingangsrechnung, receivable=Ausgangsrechnung) [REQUIRED]")
+	pipelineCmd.Flags().String("skr", "", "Kontenrahmen (03=SKR03, 04=SKR04; default: CHART_OF_ACCOUNTS or 03)")
+	pipelineCmd.Flags().Bool("write", false, "Write the booked invoice to the Google Sheet")
+	pipelineCmd.Flags().String("append-mode", "append", "How --write writes the row: append (always add) or update (replace the row of the same invoice)")
+	pipelineCmd.Flags().Bool("json", false, "Output the booking and invoice as JSON")
+	pipelineCmd.Flags().Bool("verbose", false, "Show detailed processing information")
+	pipelineCmd.Flags().Int("timeout", 300, "Timeout in seconds for the whole pipeline")
+	pipelineCmd.Flags().Int("doc-ai-timeout", 60, "Timeout in seconds for the Document AI request")
+	pipelineCmd.Flags().String("pdf-password", "", "Password for encrypted PDFs (tried before PDF_PASSWORDS)")
+	pipelineCmd.Flags().Bool("strict", false, "Fail instead of booking an invoice without an invoice number")
+	addProcessingFlags(pipelineCmd)
+
+	pipelineCmd.MarkFlagRequired("type")
+}
+
+func runPipeline(cmd *cobra.Command, args []string) error {
+	log := logger.WithComponent("pipeline")
+
+	pdfPath := args[0]
+	invoiceType, _ := cmd.Flags().GetString("type")
+	skr, _ := cmd.Flags().GetString("skr")
+	write, _ := cmd.Flags().GetBool("write")
+	appendMode, _ := cmd.Flags().GetString("append-mode")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	timeoutSecs, _ := cmd.Flags().GetInt("timeout")
+	docAITimeoutSecs, _ := cmd.Flags().GetInt("doc-ai-timeout")
+	pdfPassword, _ := cmd.Flags().GetString("pdf-password")
+	strict, _ := cmd.Flags().GetBool("strict")
+
+	invoiceType = strings.ToUpper(invoiceType)
+	if invoiceType != "PAYABLE" && invoiceType != "RECEIVABLE" {
+		return fmt.Errorf("invalid invoice type: %s (must be 'payable' or 'receivable')", invoiceType)
+	}
+
+	skr, err := resolveChartOfAccounts(skr)
+	if err != nil {
+		return err
+	}
+
+	if timeoutSecs <= 0 || docAITimeoutSecs <= 0 {
+		return fmt.Errorf("timeouts must be positive")
+	}
+
+	appendMode = strings.ToLower(appendMode)
+	if appendMode != "append" && appendMode != "update" {
+		return fmt.Errorf("invalid append mode: %s (must be 'append' or 'update')", appendMode)
+	}
+
+	processingMode, err := processingModeFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Console labels follow OUTPUT_LANGUAGE like those of datev
+	lang, err := resolveLanguage("")
+	if err != nil {
+		return err
+	}
+
+	// Check the sheet configuration before paying for the extraction and the booking
+	googleSheetURL := os.Getenv("GOOGLE_SHEET_URL")
+	if write && googleSheetURL == "" {
+		return withExitCode(ExitConfig, fmt.Errorf("GOOGLE_SHEET_URL environment variable is required for --write"))
+	}
+
+	if _, err := validateDatevPDFFile(pdfPath, log); err != nil {
+		return withExitCode(ExitInput, err)
+	}
+
+	log.Info().
+		Str("file", pdfPath).
+		Str("type", invoiceType).
+		Str("skr", skr).
+		Bool("write", write).
+		Msg("Starting pipeline")
+
+	// Ctrl-C cancels the pipeline like a timeout
+	ctx, cancel := createContextWithTimeout(timeoutSecs, log)
+	defer cancel()
+
+	bookingService, err := createBookingService(ctx, skr, booking.BookingOptions{
+		DocumentAITimeout: time.Duration(docAITimeoutSecs) * time.Second,
+		DocumentAIMode:    processingMode,
+		Cache:             openExtractionCache(log),
+		Strict:            strict,
+	}, log)
+	if err != nil {
+		return err
+	}
+	defer bookingService.Close()
+
+	invoiceDB, err := openInvoiceDB()
+	if err != nil {
+		return err
+	}
+	if invoiceDB != nil {
+		defer invoiceDB.Close()
+	}
+
+	// Extract, complete and book exactly as a datev-batch worker does
+	startTime := time.Now()
+	result := processSinglePDF(ctx, pdfPath, invoiceType, pdfPassword, bookingService, log, verbose)
+	result.Filename = filepath.Base(pdfPath)
+	duration := time.Since(startTime)
+
+	switch result.Status {
+	case "skipped":
+		fmt.Printf("%s %s: %s\n", getStatusEmoji(result.Status), result.Filename, result.Error)
+		return nil
+	case "error":
+		return handleDatevError(result.Error, log)
+	}
+
+	if invoiceDB != nil {
+		record := db.Record{Filename: result.Filename, Status: result.Status, Invoice: result.Invoice, Booking: result.Booking}
+		if err := invoiceDB.Upsert(ctx, record); err != nil {
+			return fmt.Errorf("failed to store invoice in database: %w", err)
+		}
+	}
+
+	// With --json, stdout carries only the JSON document
+	var status io.Writer = os.Stdout
+	if jsonOutput {
+		status = os.Stderr
+		if err := outputDatevJSON(result.Booking, result.Invoice, duration); err != nil {
+			return err
+		}
+	} else {
+		if err := outputDatevConsole(result.Booking, result.Invoice, verbose, false, duration, datevCatalog[lang]); err != nil {
+			return err
+		}
+		fmt.Printf("Status: %s %s\n", getStatusEmoji(result.Status), result.Status)
+	}
+
+	if !write {
+		return nil
+	}
+	return writePipelineResult(ctx, result, invoiceType, appendMode, googleSheetURL, status, log)
+}
+
+// writePipelineResult writes the booked invoice to the Kreditoren or Debitoren tab with the batch sheet
+// writer and reports the written row to status
+func writePipelineResult(ctx context.Context, result BatchResult, invoiceType, appendMode, googleSheetURL string, status io.Writer, log zerolog.Logger) error {
+	sheetName := "Kreditoren"
+	if invoiceType == "RECEIVABLE" {
+		sheetName = "Debitoren"
+	}
+
+	sheetsService, err := sheets.NewSheetsService(ctx, googleSheetURL)
+	if err != nil {
+		return fmt.Errorf("failed to create Google Sheets service: %w", err)
+	}
+	sheetName, err = linkedSheetTab(ctx, sheetsService, sheetName, log)
+	if err != nil {
+		return withExitCode(ExitExternalAPI, fmt.Errorf("failed to resolve the tab linked in GOOGLE_SHEET_URL: %w", err))
+	}
+
+	sheetResults := []sheets.BatchResult{{
+		Filename:   result.Filename,
+		Invoice:    result.Invoice,
+		Booking:    result.Booking,
+		Status:     result.Status,
+		Confidence: result.Confidence,
+	}}
+	if appendMode == "update" {
+		updated, _, err := sheetsService.UpsertBatchResults(ctx, sheetResults, sheetName)
+		if err != nil {
+			return withExitCode(ExitExternalAPI, fmt.Errorf("failed to write to Google Sheet: %w", err))
+		}
+		if updated > 0 {
+			fmt.Fprintf(status, "Sheet: %s (Zeile aktualisiert)\n", sheetName)
+			return nil
+		}
+	} else if err := sheetsService.WriteBatchResults(ctx, sheetResults, sheetName); err != nil {
+		return withExitCode(ExitExternalAPI, fmt.Errorf("failed to write to Google Sheet: %w", err))
+	}
+
+	fmt.Fprintf(status, "Sheet: %s (Zeile hinzugefügt)\n", sheetName)
+	return nil
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/sashabaranov/go-openai"
 	"github.com/spf13/cobra"
+	"tools/internal/booking"
+	"tools/internal/fx"
+	"tools/internal/llm"
 	"tools/internal/logger"
 	"tools/internal/reconciliation"
 	"tools/internal/reconciliation/services"
@@ -21,6 +24,66 @@ var reconcileCmd = &cobra.Command{
 
 This command reads bank transactions from the "Bank" sheet and matches them with
 invoices from "Kreditoren" (payables) and "Debitoren" (receivables) sheets.
+If GOOGLE_SHEET_URL links to a tab of its own (#gid=...), e.g. "Bank 2025", the
+bank transactions are read from that tab instead of "Bank".
+
+Matching modes (--mode):
+  hybrid - Accept unambiguous candidates by rule, ask ChatGPT only for the rest (default)
+  rules  - Match by rule only; ambiguous invoices stay unmatched and no LLM is needed
+  ai     - Ask ChatGPT for every invoice that has candidate transactions
+
+A candidate is accepted by rule when it is the only one scoring at least
+--auto-accept-score, or the only one whose payment quotes the invoice's
+PO number or customer reference.
+
+Each candidate sent to ChatGPT carries a locally computed name similarity
+(0-1) between its Empfänger/Absender and the invoice's vendor or customer,
+ignoring case, punctuation and legal forms. Disable it with
+--name-similarity=false to let the model compare the names on its own.
+
+Foreign-currency invoices (--bank-currency, --fx-rates):
+  Invoices whose Währung differs from the bank account's currency (default EUR,
+  or BANK_CURRENCY) are converted at the ECB reference rate of each transaction's
+  date before the amounts are compared, with a tolerance of --fx-tolerance
+  (default 3%) for the bank's spread and fees. The rates come from the ECB's
+  eurofxref-hist.csv given with --fx-rates or FX_RATES_FILE; without it such
+  invoices stay unmatched.
+
+Bank accounts and sign convention (--accounts, --inverted-sign):
+  An optional column L (Konto) in the Bank sheet names the own account, IBAN or
+  name, each transaction was booked on. --accounts (or BANK_ACCOUNTS) maps
+  invoice types or counterparties to the accounts their payments may appear on,
+  e.g. "RECEIVABLE=DE89370400440532013000;PAYABLE=DE02120300000000202051,Tagesgeld".
+  A counterparty entry ("Muster GmbH=DE...") takes precedence over its type.
+  Transactions without a Konto match any invoice. Payables normally match
+  negative and receivables positive amounts; --inverted-sign (or
+  BANK_SIGN_CONVENTION=inverted) reverses this for exports that show outgoing
+  payments as positive.
+
+Reproducible runs (--deterministic or --seed):
+  Candidates are always ordered by score, then transaction date, then sheet order.
+  In deterministic mode the rules are also tried first in ai mode, and ChatGPT is
+  only asked for genuinely ambiguous invoices, with temperature 0 and a fixed seed.
+  The ChatGPT answers remain the one non-deterministic part: OpenAI only tries to
+  return the same answer for the same seed. Use --mode rules for fully
+  reproducible results.
+
+Review before writing (--review and --apply):
+  --review proposals.json writes the suggested matches (invoice, transaction,
+  confidence, reason) to a file instead of accepting them. Set "approved": true
+  on the matches you accept, then run reconcile --apply proposals.json to write
+  only those to the "Abgleich" sheet. Invoices already in that sheet are skipped.
+
+Iterative runs (--save-result and --continue):
+  --save-result result.json saves the whole run, including the unmatched invoices
+  and transactions. When more bank data has arrived, reconcile --continue
+  result.json matches only the invoices left unmatched in that run, against the
+  bank transactions not matched yet, and merges the new matches with the old
+  ones. Combine both flags to update the same file at every step.
+
+  The saved file also serves datev and datev-batch --reconcile-result: invoices
+  with a matched payment get the type its direction shows (outgoing payment →
+  payable, incoming → receivable) instead of the classification by ChatGPT.
 
 Required environment variables:
   GOOGLE_APPLICATION_CREDENTIALS - Path to service account JSON file, OR
@@ -33,7 +96,30 @@ Required environment variables:
   tools reconcile --cutoff-date 2025-06-30
 
   # Dry run with custom batch size
-  tools reconcile --cutoff-date 2025-06-30 --batch-size 50 --dry-run`,
+  tools reconcile --cutoff-date 2025-06-30 --batch-size 50 --dry-run
+
+  # Allow up to one hour for large sheets
+  tools reconcile --timeout 3600
+
+  # Include bank transactions up to 90 days before the oldest invoice
+  tools reconcile --window-days 90
+
+  # Deterministic matching without any ChatGPT calls
+  tools reconcile --mode rules
+
+  # Reproducible hybrid run for an audit
+  tools reconcile --cutoff-date 2025-06-30 --seed 42
+
+  # Match USD invoices against the EUR account
+  tools reconcile --fx-rates eurofxref-hist.csv
+
+  # Review the suggested matches, then write the approved ones
+  tools reconcile --cutoff-date 2025-06-30 --review proposals.json
+  tools reconcile --apply proposals.json
+
+  # Month-end close with bank data arriving in several imports
+  tools reconcile --cutoff-date 2025-06-30 --save-result juni.json
+  tools reconcile --cutoff-date 2025-06-30 --continue juni.json --save-result juni.json`,
 	RunE: runReconcile,
 }
 
@@ -43,6 +129,24 @@ func init() {
 	reconcileCmd.Flags().String("cutoff-date", "", "Cutoff date for analysis (format: YYYY-MM-DD, default: today)")
 	reconcileCmd.Flags().Bool("dry-run", false, "Analyze but don't create output sheets")
 	reconcileCmd.Flags().Int("batch-size", 10, "Number of transactions to process in each batch")
+	reconcileCmd.Flags().Int("timeout", 1800, "Overall timeout in seconds")
+	reconcileCmd.Flags().Int("window-days", 60, "Also load bank transactions up to this many days before the oldest invoice (e.g. prepayments)")
+	reconcileCmd.Flags().String("mode", "hybrid", "Matching mode: hybrid, rules or ai")
+	reconcileCmd.Flags().Int("max-candidates", 10, "Maximum candidate transactions per invoice")
+	reconcileCmd.Flags().Float64("auto-accept-score", 0.95, "Minimum candidate score for a match without ChatGPT")
+	reconcileCmd.Flags().Float64("min-confidence", 0.7, "Reject ChatGPT matches reported with a lower confidence (0-1)")
+	reconcileCmd.Flags().Bool("deterministic", false, "Prefer rule matches in every mode and call ChatGPT with temperature 0 and a fixed seed")
+	reconcileCmd.Flags().Int("seed", 0, "Seed for ChatGPT in deterministic mode (implies --deterministic)")
+	reconcileCmd.Flags().Bool("name-similarity", true, "Include the precomputed counterparty name similarity in the ChatGPT prompt")
+	reconcileCmd.Flags().String("bank-currency", "", "Currency of the bank account (default: BANK_CURRENCY or EUR)")
+	reconcileCmd.Flags().String("fx-rates", "", "ECB reference rates CSV for foreign-currency invoices (default: FX_RATES_FILE)")
+	reconcileCmd.Flags().Float64("fx-tolerance", 0.03, "Relative amount tolerance for converted foreign-currency invoices")
+	reconcileCmd.Flags().Bool("inverted-sign", false, "Bank export shows outgoing payments as positive amounts (default: BANK_SIGN_CONVENTION)")
+	reconcileCmd.Flags().String("accounts", "", "Own bank accounts per invoice type or counterparty, e.g. RECEIVABLE=DE89...;PAYABLE=DE02... (default: BANK_ACCOUNTS)")
+	reconcileCmd.Flags().String("review", "", "Write the suggested matches to this JSON file for review instead of accepting them")
+	reconcileCmd.Flags().String("apply", "", "Write the approved matches of a reviewed proposals file to the Abgleich sheet")
+	reconcileCmd.Flags().String("save-result", "", "Save the reconciliation result to this JSON file for a later --continue")
+	reconcileCmd.Flags().String("continue", "", "Re-match only the invoices left unmatched in this saved result and merge the matches")
 }
 
 func runReconcile(cmd *cobra.Command, args []string) error {
@@ -52,6 +156,57 @@ func runReconcile(cmd *cobra.Command, args []string) error {
 	cutoffDateStr, _ := cmd.Flags().GetString("cutoff-date")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	timeoutSecs, _ := cmd.Flags().GetInt("timeout")
+	windowDays, _ := cmd.Flags().GetInt("window-days")
+	modeStr, _ := cmd.Flags().GetString("mode")
+	maxCandidates, _ := cmd.Flags().GetInt("max-candidates")
+	autoAcceptScore, _ := cmd.Flags().GetFloat64("auto-accept-score")
+	minConfidence, _ := cmd.Flags().GetFloat64("min-confidence")
+	deterministic, _ := cmd.Flags().GetBool("deterministic")
+	seed, _ := cmd.Flags().GetInt("seed")
+	if cmd.Flags().Changed("seed") {
+		deterministic = true
+	}
+	nameSimilarity, _ := cmd.Flags().GetBool("name-similarity")
+	bankCurrency, _ := cmd.Flags().GetString("bank-currency")
+	if bankCurrency == "" {
+		bankCurrency = os.Getenv("BANK_CURRENCY")
+	}
+	fxRatesPath, _ := cmd.Flags().GetString("fx-rates")
+	fxTolerance, _ := cmd.Flags().GetFloat64("fx-tolerance")
+	invertedSign, _ := cmd.Flags().GetBool("inverted-sign")
+	if !cmd.Flags().Changed("inverted-sign") {
+		invertedSign = strings.EqualFold(os.Getenv("BANK_SIGN_CONVENTION"), "inverted")
+	}
+	accountsStr, _ := cmd.Flags().GetString("accounts")
+	if accountsStr == "" {
+		accountsStr = os.Getenv("BANK_ACCOUNTS")
+	}
+	accounts, err := services.ParseAccountMapping(accountsStr)
+	if err != nil {
+		return fmt.Errorf("invalid --accounts: %w", err)
+	}
+	reviewPath, _ := cmd.Flags().GetString("review")
+	applyPath, _ := cmd.Flags().GetString("apply")
+	resultPath, _ := cmd.Flags().GetString("save-result")
+	continuePath, _ := cmd.Flags().GetString("continue")
+
+	if reviewPath != "" && applyPath != "" {
+		return fmt.Errorf("--review and --apply cannot be combined")
+	}
+	if applyPath != "" && (resultPath != "" || continuePath != "") {
+		return fmt.Errorf("--apply cannot be combined with --save-result or --continue")
+	}
+
+	// Load the previous run first so a bad file fails before any API is set up
+	var prior *services.ResultFile
+	if continuePath != "" {
+		var err error
+		prior, err = services.ReadResultFile(continuePath)
+		if err != nil {
+			return withExitCode(ExitInput, err)
+		}
+	}
 
 	// Parse cutoff date
 	var cutoffDate time.Time
@@ -70,26 +225,77 @@ func runReconcile(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("batch size must be positive")
 	}
 
+	if timeoutSecs <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+
+	if windowDays < 0 {
+		return fmt.Errorf("window days must not be negative")
+	}
+
+	mode, err := services.ParseMatchMode(modeStr)
+	if err != nil {
+		return err
+	}
+
+	if maxCandidates <= 0 {
+		return fmt.Errorf("max candidates must be positive")
+	}
+
+	if autoAcceptScore <= 0 {
+		return fmt.Errorf("auto-accept score must be positive")
+	}
+
+	if minConfidence <= 0 || minConfidence > 1 {
+		return fmt.Errorf("min confidence must be between 0 and 1")
+	}
+
+	if fxTolerance <= 0 || fxTolerance >= 1 {
+		return fmt.Errorf("fx tolerance must be between 0 and 1")
+	}
+
+	var rates *fx.Rates
+	if fxRatesPath != "" {
+		rates, err = fx.LoadFile(fxRatesPath)
+	} else {
+		rates, err = fx.LoadFromEnv()
+	}
+	if err != nil {
+		return withExitCode(ExitInput, fmt.Errorf("failed to load exchange rates: %w", err))
+	}
+
 	// Check required environment variables
 	sheetURL := os.Getenv("GOOGLE_SHEET_URL")
 	if sheetURL == "" {
-		return fmt.Errorf("GOOGLE_SHEET_URL environment variable is required")
+		return withExitCode(ExitConfig, fmt.Errorf("GOOGLE_SHEET_URL environment variable is required"))
 	}
 
-	openaiAPIKey := os.Getenv("OPENAI_API_KEY")
-	if openaiAPIKey == "" {
-		return fmt.Errorf("OPENAI_API_KEY environment variable is required")
+	if applyPath != "" {
+		return applyProposals(applyPath, sheetURL, timeoutSecs, dryRun)
+	}
+
+	// Initialize LLM client for the configured provider; rules mode never calls it
+	var openaiClient llm.LLMClient
+	if mode != services.MatchModeRules {
+		openaiClient, err = llm.NewClientFromEnv()
+		if err != nil {
+			return fmt.Errorf("failed to initialize LLM client: %w", err)
+		}
 	}
 
 	log.Info().
 		Str("cutoff_date", cutoffDate.Format("2006-01-02")).
 		Bool("dry_run", dryRun).
 		Int("batch_size", batchSize).
+		Int("timeout", timeoutSecs).
+		Str("mode", string(mode)).
+		Bool("deterministic", deterministic).
 		Str("sheet_url", sheetURL).
 		Msg("Starting bank reconciliation")
 
-	// Create context
-	ctx := context.Background()
+	// Create context with timeout and signal handling
+	ctx, cancel := createContextWithTimeout(timeoutSecs, log)
+	defer cancel()
 
 	// Initialize Google Sheets client
 	sheetsService, err := sheets.NewSheetsService(ctx, sheetURL)
@@ -99,25 +305,41 @@ func runReconcile(cmd *cobra.Command, args []string) error {
 
 	log.Info().Msg("Google Sheets service initialized successfully")
 
+	// A link to a tab of its own (#gid=...) names the tab with the bank transactions
+	bankSheet, err := linkedSheetTab(ctx, sheetsService, reconciliation.DefaultBankSheet, log)
+	if err != nil {
+		return withExitCode(ExitExternalAPI, fmt.Errorf("failed to resolve the tab linked in GOOGLE_SHEET_URL: %w", err))
+	}
+
 	// Validate required sheets exist
-	requiredSheets := []string{"Bank", "Kreditoren", "Debitoren"}
+	requiredSheets := []string{bankSheet, "Kreditoren", "Debitoren"}
 	if err := validateSheetsExist(ctx, sheetsService, requiredSheets); err != nil {
 		return fmt.Errorf("sheet validation failed: %w", err)
 	}
 
 	log.Info().Strs("sheets", requiredSheets).Msg("All required sheets validated")
 
-	// Initialize OpenAI client
-	openaiClient := openai.NewClient(openaiAPIKey)
-
 	// Initialize data reader
-	dataReader := reconciliation.NewDataReader(sheetsService)
+	dataReader := reconciliation.NewDataReaderWithBankSheet(sheetsService, bankSheet)
 
 	// Initialize reconciliation service
-	reconciliationService := services.NewChatGPTReconciliationService(openaiClient)
+	reconciliationService := services.NewChatGPTReconciliationServiceWithOptions(openaiClient, services.MatchOptions{
+		Mode:               mode,
+		MaxCandidates:      maxCandidates,
+		AutoAcceptScore:    autoAcceptScore,
+		MinConfidence:      minConfidence,
+		Deterministic:      deterministic,
+		Seed:               seed,
+		OmitNameSimilarity: !nameSimilarity,
+		BankCurrency:       bankCurrency,
+		Rates:              rates,
+		FXTolerance:        fxTolerance,
+		InvertedSign:       invertedSign,
+		Accounts:           accounts,
+	})
 
 	// Read and process data
-	if err := processReconciliation(ctx, dataReader, reconciliationService, cutoffDate, batchSize, dryRun); err != nil {
+	if err := processReconciliation(ctx, dataReader, reconciliationService, cutoffDate, windowDays, batchSize, dryRun, reviewPath, resultPath, invertedSign, prior); err != nil {
 		return fmt.Errorf("reconciliation processing failed: %w", err)
 	}
 
@@ -147,8 +369,11 @@ func validateSheetsExist(ctx context.Context, sheetsService *sheets.Service, req
 	return nil
 }
 
-// processReconciliation performs the main reconciliation logic
-func processReconciliation(ctx context.Context, dataReader *reconciliation.DataReader, reconciliationService services.ReconciliationService, cutoffDate time.Time, batchSize int, dryRun bool) error {
+// processReconciliation performs the main reconciliation logic. With a reviewPath the matches are
+// written there as proposals instead of being accepted; with a resultPath the whole result is saved
+// together with the sign convention invertedSign of the bank transactions.
+// With a prior result only its unmatched invoices are matched, and the new matches are merged in.
+func processReconciliation(ctx context.Context, dataReader *reconciliation.DataReader, reconciliationService services.ReconciliationService, cutoffDate time.Time, windowDays, batchSize int, dryRun bool, reviewPath, resultPath string, invertedSign bool, prior *services.ResultFile) error {
 	const op = "processReconciliation"
 	log := logger.WithComponent("reconcile-process")
 
@@ -156,46 +381,141 @@ func processReconciliation(ctx context.Context, dataReader *reconciliation.DataR
 		Str("cutoff_date", cutoffDate.Format("2006-01-02")).
 		Int("batch_size", batchSize).
 		Bool("dry_run", dryRun).
+		Bool("continue", prior != nil).
 		Msg("Starting reconciliation processing")
 
-	// Read bank transactions
-	bankTransactions, err := dataReader.ReadBankTransactions(ctx)
+	var result *services.ReconciliationResult
+	var err error
+	if prior != nil {
+		result, err = continueReconciliation(ctx, dataReader, reconciliationService, prior, cutoffDate, windowDays)
+	} else {
+		result, err = reconcileFromSheets(ctx, dataReader, reconciliationService, cutoffDate, windowDays)
+	}
 	if err != nil {
-		return fmt.Errorf("%s: failed to read bank transactions: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
-	log.Info().Int("bank_transactions", len(bankTransactions)).Msg("Bank transactions read successfully")
+
+	// Display reconciliation results
+	displayReconciliationResults(result, dryRun)
+
+	if resultPath != "" {
+		if err := services.WriteResultFile(resultPath, result, cutoffDate, invertedSign); err != nil {
+			return fmt.Errorf("%s: failed to save result: %w", op, err)
+		}
+		log.Info().
+			Str("file", resultPath).
+			Int("matches", len(result.Matches)).
+			Int("unmatched_invoices", len(result.UnmatchedInvoices)).
+			Msg("Reconciliation result saved")
+		fmt.Printf("Ergebnis nach %s gespeichert (%d offene Rechnungen). Später fortsetzen mit:\n  tools reconcile --continue %s\n",
+			resultPath, len(result.UnmatchedInvoices), resultPath)
+	}
+
+	if reviewPath != "" {
+		if err := services.WriteProposalFile(reviewPath, services.NewProposalFile(result, cutoffDate)); err != nil {
+			return fmt.Errorf("%s: failed to write proposals: %w", op, err)
+		}
+		log.Info().
+			Str("file", reviewPath).
+			Int("proposals", len(result.Matches)).
+			Msg("Proposed matches written for review")
+		fmt.Printf("%d Zuordnungsvorschläge nach %s geschrieben.\n", len(result.Matches), reviewPath)
+		fmt.Printf("Freigegebene Vorschläge mit \"approved\": true markieren und dann übernehmen:\n  tools reconcile --apply %s\n", reviewPath)
+		return nil
+	}
+
+	if !dryRun {
+		log.Info().Msg("TODO: Create output sheets with reconciliation results")
+	}
+
+	return nil
+}
+
+// reconcileFromSheets matches all invoices of the Kreditoren and Debitoren sheets
+func reconcileFromSheets(ctx context.Context, dataReader *reconciliation.DataReader, reconciliationService services.ReconciliationService, cutoffDate time.Time, windowDays int) (*services.ReconciliationResult, error) {
+	log := logger.WithComponent("reconcile-process")
 
 	// Read payable invoices
 	payableInvoices, err := dataReader.ReadInvoices(ctx, "Kreditoren")
 	if err != nil {
-		return fmt.Errorf("%s: failed to read payable invoices: %w", op, err)
+		return nil, fmt.Errorf("failed to read payable invoices: %w", err)
 	}
 	log.Info().Int("payable_invoices", len(payableInvoices)).Msg("Payable invoices read successfully")
 
 	// Read receivable invoices
 	receivableInvoices, err := dataReader.ReadInvoices(ctx, "Debitoren")
 	if err != nil {
-		return fmt.Errorf("%s: failed to read receivable invoices: %w", op, err)
+		return nil, fmt.Errorf("failed to read receivable invoices: %w", err)
 	}
 	log.Info().Int("receivable_invoices", len(receivableInvoices)).Msg("Receivable invoices read successfully")
 
 	// Combine all invoices for processing
 	allInvoices := append(payableInvoices, receivableInvoices...)
 
+	// Read only the bank transactions that can match one of the invoices
+	dateRange := bankDateRange(allInvoices, cutoffDate, windowDays)
+	bankTransactions, err := dataReader.ReadBankTransactionsInRange(ctx, dateRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bank transactions: %w", err)
+	}
+	log.Info().
+		Int("bank_transactions", len(bankTransactions)).
+		Time("from", dateRange.From).
+		Time("to", dateRange.To).
+		Msg("Bank transactions read successfully")
+
 	// Perform ChatGPT-based reconciliation
 	result, err := reconciliationService.ReconcileAll(ctx, allInvoices, bankTransactions, cutoffDate)
 	if err != nil {
-		return fmt.Errorf("%s: failed to perform reconciliation: %w", op, err)
+		return nil, fmt.Errorf("failed to perform reconciliation: %w", err)
 	}
+	return result, nil
+}
 
-	// Display reconciliation results
-	displayReconciliationResults(result, dryRun)
+// continueReconciliation matches the invoices left unmatched in a saved result against the bank
+// transactions not matched yet, including any imported since
+func continueReconciliation(ctx context.Context, dataReader *reconciliation.DataReader, reconciliationService services.ReconciliationService, prior *services.ResultFile, cutoffDate time.Time, windowDays int) (*services.ReconciliationResult, error) {
+	log := logger.WithComponent("reconcile-process")
 
-	if !dryRun {
-		log.Info().Msg("TODO: Create output sheets with reconciliation results")
+	dateRange := bankDateRange(prior.Result.UnmatchedInvoices, cutoffDate, windowDays)
+	bankTransactions, err := dataReader.ReadBankTransactionsInRange(ctx, dateRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bank transactions: %w", err)
 	}
+	log.Info().
+		Str("previous_cutoff_date", prior.CutoffDate).
+		Int("previous_matches", len(prior.Result.Matches)).
+		Int("open_invoices", len(prior.Result.UnmatchedInvoices)).
+		Int("bank_transactions", len(bankTransactions)).
+		Msg("Continuing previous reconciliation")
 
-	return nil
+	result, err := services.ContinueReconciliation(ctx, reconciliationService, prior.Result, bankTransactions, cutoffDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to continue reconciliation: %w", err)
+	}
+	return result, nil
+}
+
+// bankDateRange spans from the oldest invoice date minus the matching window up to the cutoff date.
+// Without any dated invoice the lower bound stays open.
+func bankDateRange(invoices []reconciliation.InvoiceRow, cutoffDate time.Time, windowDays int) reconciliation.DateRange {
+	dateRange := reconciliation.DateRange{To: cutoffDate}
+
+	var oldest time.Time
+	for _, invoice := range invoices {
+		if invoice.Date.IsZero() {
+			continue
+		}
+		if oldest.IsZero() || invoice.Date.Before(oldest) {
+			oldest = invoice.Date
+		}
+	}
+
+	if !oldest.IsZero() {
+		dateRange.From = oldest.AddDate(0, 0, -windowDays)
+	}
+
+	return dateRange
 }
 
 // displayReconciliationResults displays the results of the reconciliation process
@@ -206,6 +526,9 @@ func displayReconciliationResults(result *services.ReconciliationResult, dryRun
 		Int("total_invoices", result.TotalInvoices).
 		Int("total_transactions", result.TotalTransactions).
 		Int("matched_invoices", result.MatchedCount).
+		Int("rule_matched_invoices", result.RuleMatchedCount).
+		Int("chatgpt_requests", result.ChatGPTRequests).
+		Int("rejected_low_confidence", result.RejectedCount).
 		Int("unmatched_invoices", len(result.UnmatchedInvoices)).
 		Int("unmatched_transactions", len(result.UnmatchedTransactions)).
 		Dur("processing_time", result.ProcessingTime).
@@ -229,7 +552,134 @@ func displayReconciliationResults(result *services.ReconciliationResult, dryRun
 			Msg("Multiple matches found - showing count only")
 	}
 
+	printOpenReceivables(result.UnmatchedInvoices)
+
 	if dryRun {
 		log.Info().Msg("Dry run mode: No output sheets created")
 	}
-}
\ No newline at end of file
+}
+
+// printOpenReceivables lists the receivables without a matching payment together with the customer
+// contact from the Debitoren sheet, so collections knows whom to chase. Receivables marked paid on
+// the invoice are settled without a bank transaction (cash, card, PayPal) and only counted.
+func printOpenReceivables(invoices []reconciliation.InvoiceRow) {
+	var open []reconciliation.InvoiceRow
+	paid := 0
+	for _, invoice := range invoices {
+		switch {
+		case invoice.Type != "RECEIVABLE":
+		case invoice.Paid:
+			paid++
+		default:
+			open = append(open, invoice)
+		}
+	}
+	if paid > 0 {
+		fmt.Printf("Als bezahlt markierte Forderungen ohne Zahlungseingang (nicht offen): %d\n", paid)
+	}
+	if len(open) == 0 {
+		return
+	}
+
+	fmt.Printf("Offene Forderungen ohne Zahlungseingang: %d\n", len(open))
+	for _, invoice := range open {
+		contact := strings.Join(nonEmpty(invoice.CustomerContact, invoice.CustomerEmail), ", ")
+		if contact == "" {
+			contact = "kein Kontakt erfasst"
+		}
+		fmt.Printf("  %-20s %-30s %12.2f %s  %s\n", invoice.InvoiceNumber, invoice.Customer, invoice.GrossAmount, invoice.Currency, contact)
+	}
+}
+
+// nonEmpty returns the values that are not empty
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+// applyProposals writes the approved matches of a reviewed proposals file to the Abgleich sheet
+func applyProposals(path, sheetURL string, timeoutSecs int, dryRun bool) error {
+	log := logger.WithComponent("reconcile-apply")
+
+	file, err := services.ReadProposalFile(path)
+	if err != nil {
+		return withExitCode(ExitInput, err)
+	}
+	approved, err := file.Approved()
+	if err != nil {
+		return withExitCode(ExitInput, fmt.Errorf("invalid proposals file %s: %w", path, err))
+	}
+
+	log.Info().
+		Str("file", path).
+		Int("proposals", len(file.Proposals)).
+		Int("approved", len(approved)).
+		Bool("dry_run", dryRun).
+		Msg("Applying reviewed matches")
+
+	if len(approved) == 0 {
+		fmt.Printf("Keine freigegebenen Vorschläge in %s (\"approved\": true fehlt).\n", path)
+		return nil
+	}
+
+	if dryRun {
+		fmt.Printf("Dry Run: %d von %d Vorschlägen würden in das Blatt %s geschrieben.\n", len(approved), len(file.Proposals), sheets.ReconciliationSheet)
+		return nil
+	}
+
+	rows := make([]sheets.MatchRow, 0, len(approved))
+	for _, proposal := range approved {
+		rows = append(rows, sheets.MatchRow{
+			InvoiceID:         proposal.InvoiceID,
+			InvoiceNumber:     proposal.InvoiceNumber,
+			InvoiceType:       proposal.InvoiceType,
+			InvoiceDate:       proposal.InvoiceDate,
+			Counterparty:      proposal.Counterparty,
+			GrossAmount:       proposal.GrossAmount,
+			TransactionID:     proposal.TransactionID,
+			TransactionDate:   proposal.TransactionDate,
+			TransactionAmount: proposal.TransactionAmount,
+			TransactionParty:  proposal.TransactionCounterparty,
+			Confidence:        proposal.Confidence,
+			Reason:            proposal.Reason,
+			Method:            proposal.Method,
+		})
+	}
+
+	ctx, cancel := createContextWithTimeout(timeoutSecs, log)
+	defer cancel()
+
+	sheetsService, err := sheets.NewSheetsService(ctx, sheetURL)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Google Sheets service: %w", err)
+	}
+
+	written, skipped, err := sheetsService.WriteMatches(ctx, rows, sheets.ReconciliationSheet)
+	if err != nil {
+		return withExitCode(ExitExternalAPI, fmt.Errorf("failed to write approved matches: %w", err))
+	}
+
+	fmt.Printf("%d freigegebene Zuordnungen in das Blatt %s geschrieben", written, sheets.ReconciliationSheet)
+	if skipped > 0 {
+		fmt.Printf(", %d bereits vorhanden", skipped)
+	}
+	fmt.Println(".")
+	return nil
+}
+
+// loadPaymentTypes reads a result file saved with reconcile --save-result whose matched payments confirm
+// the invoice types of datev and datev-batch, or returns nil if path is empty
+func loadPaymentTypes(path string) (booking.PaymentTypeSource, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := services.ReadResultFile(path)
+	if err != nil {
+		return nil, withExitCode(ExitInput, err)
+	}
+	return services.NewPaymentTypes(file), nil
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+	"tools/internal/booking"
+	"tools/internal/logger"
+	"tools/internal/sheets"
+	"tools/pkg/services"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review [folder-path]",
+	Short: "Review and correct extracted invoices in a terminal UI before writing them to Google Sheets",
+	Long: `Extract all PDF invoices in a folder, then review them one by one in a terminal UI.
+
+The invoices are processed like datev-batch does (Document AI, completion, booking).
+Each invoice is shown with its fields next to a summary of the proposed booking.
+Fields can be corrected, the invoice type flipped and every invoice accepted or
+rejected. Only accepted invoices are written, payables to the "Kreditoren" and
+receivables to the "Debitoren" sheet. The booking of a corrected invoice is
+generated again from the corrected data before it is written.
+
+Keys:
+  ↑/↓, j/k      select field
+  enter         edit the selected field (enter saves, esc cancels)
+  t             flip the type (Eingangs-/Ausgangsrechnung)
+  a / r         accept / reject and go to the next invoice
+  ←/→, p/n      previous / next invoice
+  w             finish and write the accepted invoices
+  q, ctrl+c     quit without writing anything
+
+Amounts are edited in EUR ("119,00") or cents ("11900"), dates as YYYY-MM-DD.
+
+Required environment variables: as for datev-batch.`,
+	Example: `  # Review a folder of incoming invoices, detecting the type per file
+  tools review ./invoices
+
+  # All files are Eingangsrechnungen; only show what would be written
+  tools review ./invoices --type payable --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReview,
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+
+	reviewCmd.Flags().String("type", "", "Rechnungstyp for all files (payable or receivable; default: detect per file)")
+	reviewCmd.Flags().String("skr", "", "Kontenrahmen (03=SKR03, 04=SKR04; default: CHART_OF_ACCOUNTS or 03)")
+	reviewCmd.Flags().Bool("dry-run", false, "Review the invoices but don't write to Google Sheet")
+	reviewCmd.Flags().Int("timeout", 1800, "Timeout in seconds for extracting the folder and, separately, for writing the accepted invoices")
+	reviewCmd.Flags().Int("doc-ai-timeout", 60, "Timeout in seconds for each Document AI request")
+	reviewCmd.Flags().String("pdf-password", "", "Password for encrypted PDFs (tried before PDF_PASSWORDS)")
+}
+
+func runReview(cmd *cobra.Command, args []string) error {
+	log := logger.WithComponent("review")
+
+	folderPath := args[0]
+	invoiceType, _ := cmd.Flags().GetString("type")
+	skr, _ := cmd.Flags().GetString("skr")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	timeoutSecs, _ := cmd.Flags().GetInt("timeout")
+	docAITimeoutSecs, _ := cmd.Flags().GetInt("doc-ai-timeout")
+	pdfPassword, _ := cmd.Flags().GetString("pdf-password")
+
+	invoiceType = strings.ToUpper(invoiceType)
+	if invoiceType != "" && invoiceType != "PAYABLE" && invoiceType != "RECEIVABLE" {
+		return fmt.Errorf("invalid invoice type: %s (must be 'payable' or 'receivable')", invoiceType)
+	}
+
+	skr, err := resolveChartOfAccounts(skr)
+	if err != nil {
+		return err
+	}
+
+	if timeoutSecs <= 0 || docAITimeoutSecs <= 0 {
+		return fmt.Errorf("timeouts must be positive")
+	}
+
+	folderInfo, err := os.Stat(folderPath)
+	if err != nil {
+		return withExitCode(ExitInput, fmt.Errorf("folder not found: %s", folderPath))
+	}
+	if !folderInfo.IsDir() {
+		return withExitCode(ExitInput, fmt.Errorf("path is not a directory: %s", folderPath))
+	}
+
+	googleSheetURL := os.Getenv("GOOGLE_SHEET_URL")
+	if !dryRun && googleSheetURL == "" {
+		return withExitCode(ExitConfig, fmt.Errorf("GOOGLE_SHEET_URL environment variable is required"))
+	}
+
+	log.Info().
+		Str("folder", folderPath).
+		Str("type", invoiceType).
+		Str("skr", skr).
+		Bool("dry_run", dryRun).
+		Msg("Starting invoice review")
+
+	// The review itself has no time limit, so only the extraction and the writing are bounded
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bookingService, err := createBookingService(ctx, skr, booking.BookingOptions{
+		DocumentAITimeout: time.Duration(docAITimeoutSecs) * time.Second,
+	}, log)
+	if err != nil {
+		return err
+	}
+	defer bookingService.Close()
+
+	pdfFiles, err := findPDFFiles(folderPath)
+	if err != nil {
+		return fmt.Errorf("failed to find PDF files: %w", err)
+	}
+	if len(pdfFiles) == 0 {
+		fmt.Println("Keine PDF-Dateien im Ordner gefunden.")
+		return nil
+	}
+
+	numWorkers := getNumWorkers()
+	fmt.Printf("Verarbeite %d PDFs mit %d parallelen Workern...\n", len(pdfFiles), numWorkers)
+	extractCtx, extractCancel := context.WithTimeout(ctx, time.Duration(timeoutSecs)*time.Second)
+	results, err := processPDFsInParallel(extractCtx, pdfFiles, invoiceType, pdfPassword, bookingService, numWorkers, nil, nil, nil, newCircuitBreaker(defaultMaxSystemicFailures), log, false, false)
+	extractCancel()
+	fmt.Println()
+	if err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("extraction aborted: %w", err)
+	}
+
+	var items []*reviewItem
+	failed := 0
+	for _, result := range results {
+		if result.Status == "error" {
+			failed++
+			continue
+		}
+		items = append(items, &reviewItem{result: result})
+	}
+	if len(items) == 0 {
+		cmd.SilenceUsage = true
+		return withExitCode(ExitPartialFailure, fmt.Errorf("no invoice could be extracted (%d files failed)", failed))
+	}
+
+	final, err := tea.NewProgram(newReviewModel(items), tea.WithAltScreen()).Run()
+	if err != nil {
+		return fmt.Errorf("review UI failed: %w", err)
+	}
+	if model := final.(reviewModel); !model.write {
+		fmt.Println("Review abgebrochen, es wurde nichts geschrieben.")
+		return nil
+	}
+
+	writeCtx, writeCancel := context.WithTimeout(ctx, time.Duration(timeoutSecs)*time.Second)
+	defer writeCancel()
+
+	bySheet, rejected := approvedResultsBySheet(writeCtx, items, bookingService, log)
+
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Println("                 REVIEW")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("Akzeptiert: %d\n", len(bySheet["Kreditoren"])+len(bySheet["Debitoren"]))
+	fmt.Printf("Verworfen oder offen: %d\n", rejected)
+	if failed > 0 {
+		fmt.Printf("Nicht extrahiert: %d\n", failed)
+	}
+
+	if dryRun {
+		fmt.Println("Modus: Dry Run (keine Google Sheets Aktualisierung)")
+		return nil
+	}
+
+	sheetsService, err := sheets.NewSheetsService(writeCtx, googleSheetURL)
+	if err != nil {
+		return fmt.Errorf("failed to create Google Sheets service: %w", err)
+	}
+	for _, sheetName := range []string{"Kreditoren", "Debitoren"} {
+		sheetResults := bySheet[sheetName]
+		if len(sheetResults) == 0 {
+			continue
+		}
+		if err := sheetsService.WriteBatchResults(writeCtx, sheetResults, sheetName); err != nil {
+			return withExitCode(ExitExternalAPI, fmt.Errorf("failed to write to Google Sheet: %w", err))
+		}
+		fmt.Printf("%s: %d Zeilen hinzugefügt\n", sheetName, len(sheetResults))
+	}
+	fmt.Printf("URL: %s\n", googleSheetURL)
+
+	log.Info().
+		Int("accepted", len(bySheet["Kreditoren"])+len(bySheet["Debitoren"])).
+		Int("rejected", rejected).
+		Int("failed", failed).
+		Msg("Invoice review completed")
+
+	return nil
+}
+
+// approvedResultsBySheet collects the accepted invoices per target sheet and returns how many were
+// rejected or left undecided. Corrected invoices get a new booking; if that fails the invoice is
+// counted as not accepted, since its old booking no longer fits the data.
+func approvedResultsBySheet(ctx context.Context, items []*reviewItem, bookingService services.BookingService, log zerolog.Logger) (map[string][]sheets.BatchResult, int) {
+	bySheet := make(map[string][]sheets.BatchResult)
+	rejected := 0
+
+	for _, item := range items {
+		if !item.approved {
+			rejected++
+			continue
+		}
+
+		result := item.result
+		if item.edited {
+			newBooking, err := bookingService.GenerateBooking(ctx, result.Invoice)
+			if err != nil {
+				log.Error().Err(err).Str("file", result.Filename).Msg("Failed to regenerate booking for corrected invoice")
+				fmt.Printf("❌ %s: Buchung konnte nicht neu erstellt werden (%v)\n", result.Filename, err)
+				rejected++
+				continue
+			}
+			newBooking.CompletionChanges = result.Booking.CompletionChanges
+			result.Booking = newBooking
+		}
+
+		sheetName := "Kreditoren"
+		if result.Invoice.Type == "RECEIVABLE" {
+			sheetName = "Debitoren"
+		}
+		bySheet[sheetName] = append(bySheet[sheetName], sheets.BatchResult{
+			Filename:   result.Filename,
+			Invoice:    result.Invoice,
+			Booking:    result.Booking,
+			Status:     result.Status,
+			Confidence: result.Confidence,
+		})
+	}
+
+	return bySheet, rejected
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+
+	"tools/internal/db"
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// reviewQueueColumns is the header row of the --review-queue file
+var reviewQueueColumns = []string{"Datei", "Status", "Rechnungsnummer", "Partner", "Brutto", "Gründe"}
+
+// reviewQueueItem is a file of a batch that needs human attention, with every reason it was queued for
+type reviewQueueItem struct {
+	Filename string
+	Status   string
+	Invoice  *models.Invoice // nil for files that failed before extraction
+	Reasons  []string
+}
+
+// reviewReasons returns the data quality problems of a booked invoice that make its batch status
+// "warning": missing invoice number or amounts, a truncated booking text, the plausibility warnings
+// of the booking (e.g. low type confidence or amounts that do not add up) and a poor scan
+func reviewReasons(invoice *models.Invoice, booking *services.DATEVBooking) []string {
+	var reasons []string
+	if invoice.InvoiceNumber == "" {
+		reasons = append(reasons, "Rechnungsnummer fehlt")
+	}
+	switch noNetOrVAT := invoice.NetAmount == 0 && invoice.VATAmount == 0; {
+	case noNetOrVAT && invoice.GrossAmount == 0:
+		reasons = append(reasons, "Keine Beträge erkannt")
+	case noNetOrVAT:
+		reasons = append(reasons, "Netto- und MwSt-Betrag fehlen")
+	case invoice.GrossAmount == 0:
+		reasons = append(reasons, "Bruttobetrag fehlt")
+	}
+	if strings.HasSuffix(booking.BookingText, "...") {
+		reasons = append(reasons, "Buchungstext gekürzt")
+	}
+	reasons = append(reasons, booking.Warnings...)
+	if quality := invoice.InputQuality; quality.IsLow() {
+		reason := fmt.Sprintf("Schlechte Scanqualität (%.0f %%)", quality.Score*100)
+		if len(quality.Defects) > 0 {
+			reason += ": " + strings.Join(quality.Defects, ", ")
+		}
+		reasons = append(reasons, reason)
+	}
+	return reasons
+}
+
+// buildReviewQueue lists the files of a batch that need human attention: failed files, files booked
+// with warnings, files the --sample model booked differently, different files of the same invoice
+// (counterparty and invoice number) that were both booked, and booked files --auto-type did not
+// route to a tab. Skipped and canceled files are left out; they are handled by a re-run rather
+// than by a correction. The results must have been routed with routeBatchResults.
+func buildReviewQueue(results []BatchResult) []reviewQueueItem {
+	// Files of the same invoice, in filename order
+	byKey := map[string][]string{}
+	for _, result := range results {
+		if isBooked(result) && result.Invoice.InvoiceNumber != "" {
+			key := db.RecordID(result.Invoice, "")
+			byKey[key] = append(byKey[key], result.Filename)
+		}
+	}
+
+	var queue []reviewQueueItem
+	for _, result := range results {
+		item := reviewQueueItem{Filename: result.Filename, Status: result.Status, Invoice: result.Invoice}
+		switch {
+		case result.Status == "error":
+			if result.Error != nil {
+				item.Reasons = append(item.Reasons, "Fehler: "+result.Error.Error())
+			} else {
+				item.Reasons = append(item.Reasons, "Fehler")
+			}
+		case isBooked(result):
+			if result.Booking != nil {
+				item.Reasons = append(item.Reasons, reviewReasons(result.Invoice, result.Booking)...)
+			}
+			if result.Sheet == "" {
+				item.Reasons = append(item.Reasons, "Keinem Sheet zugeordnet, Rechnungstyp bestätigen")
+			}
+			if result.Invoice.InvoiceNumber != "" {
+				for _, other := range byKey[db.RecordID(result.Invoice, "")] {
+					if other != result.Filename {
+						item.Reasons = append(item.Reasons, "Mögliches Duplikat von "+other)
+					}
+				}
+			}
+			if remark := sampleRemark(result.SampleCheck); remark != "" {
+				item.Reasons = append(item.Reasons, remark)
+			}
+		}
+		if len(item.Reasons) > 0 {
+			queue = append(queue, item)
+		}
+	}
+	return queue
+}
+
+// isBooked reports whether the result is a booked invoice, with or without warnings
+func isBooked(result BatchResult) bool {
+	return (result.Status == "success" || result.Status == "warning") && result.Invoice != nil
+}
+
+// isRecorded reports whether the result is a booked invoice routed to a tab. Only those are written
+// to the CSV ledger and the database and count towards the control total; booked files --auto-type
+// did not route wait in the review queue instead. The results must have been routed with
+// routeBatchResults.
+func isRecorded(result BatchResult) bool {
+	return isBooked(result) && result.Sheet != ""
+}
+
+// writeReviewQueue creates or truncates path and writes the review queue to it as CSV, one row per
+// file with its reasons separated by semicolons
+func writeReviewQueue(path string, queue []reviewQueueItem) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create review queue file: %w", err)
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write(reviewQueueColumns)
+	for _, item := range queue {
+		var invoiceNumber, partner, gross string
+		if invoice := item.Invoice; invoice != nil {
+			invoiceNumber = invoice.InvoiceNumber
+			partner = invoice.Vendor
+			if invoice.Type == "RECEIVABLE" {
+				partner = invoice.Customer
+			}
+			gross = formatStatsAmount(invoice.GrossAmount)
+		}
+		writer.Write([]string{item.Filename, item.Status, invoiceNumber, partner, gross, strings.Join(item.Reasons, "; ")})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write review queue file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close review queue file: %w", err)
+	}
+	return nil
+}
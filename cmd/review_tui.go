@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"tools/internal/booking"
+	"tools/pkg/models"
+)
+
+// reviewItem is one extracted invoice and the reviewer's decision on it
+type reviewItem struct {
+	result   BatchResult
+	approved bool
+	rejected bool
+	edited   bool // A field or the type was changed, so the booking must be generated again
+}
+
+// reviewField is an invoice field shown in the review UI. Name is the datev --set field used to
+// validate and apply edits; confidence is the key in the extraction's confidence map.
+type reviewField struct {
+	label      string
+	name       string
+	confidence string
+	value      func(*models.Invoice) string
+}
+
+var reviewFields = []reviewField{
+	{"Rechnungsnr.", "invoice-number", "invoice_number", func(inv *models.Invoice) string { return inv.InvoiceNumber }},
+	{"Lieferant", "vendor", "vendor", func(inv *models.Invoice) string { return inv.Vendor }},
+	{"Kunde", "customer", "customer", func(inv *models.Invoice) string { return inv.Customer }},
+	{"USt-IdNr. Lief.", "vendor-vat-id", "", func(inv *models.Invoice) string { return inv.VendorVATID }},
+	{"USt-IdNr. Kunde", "customer-vat-id", "", func(inv *models.Invoice) string { return inv.CustomerVATID }},
+	{"Rechnungsdatum", "issue-date", "issue_date", func(inv *models.Invoice) string { return formatReviewDate(inv.IssueDate) }},
+	{"Leistungsdatum", "service-date", "service_date", func(inv *models.Invoice) string { return formatReviewDate(inv.ServiceDate) }},
+	{"Fällig", "due-date", "due_date", func(inv *models.Invoice) string { return formatReviewDate(inv.DueDate) }},
+	{"Netto", "net", "net_amount", func(inv *models.Invoice) string { return formatReviewAmount(inv.NetAmount) }},
+	{"MwSt", "vat", "vat_amount", func(inv *models.Invoice) string { return formatReviewAmount(inv.VATAmount) }},
+	{"Brutto", "gross", "gross_amount", func(inv *models.Invoice) string { return formatReviewAmount(inv.GrossAmount) }},
+	{"Währung", "currency", "currency", func(inv *models.Invoice) string { return inv.Currency }},
+	{"Bestellnr.", "purchase-order", "purchase_order", func(inv *models.Invoice) string { return inv.PurchaseOrder }},
+	{"Kundenreferenz", "customer-reference", "customer_reference", func(inv *models.Invoice) string { return inv.CustomerReference }},
+	{"Beschreibung", "description", "description", func(inv *models.Invoice) string { return inv.Description }},
+	{"Rechnungsart", "sub-type", "sub_type", func(inv *models.Invoice) string { return reviewSubTypeLabel(inv.SubType) }},
+	{"Anzahlung Nr.", "prepayment-reference", "prepayment_reference", func(inv *models.Invoice) string { return inv.PrepaymentReference }},
+	{"Gemahnte Rg.", "reminder-reference", "reminder_reference", func(inv *models.Invoice) string { return inv.ReminderReference }},
+	{"Mahngebühr", "dunning-fee", "dunning_fee", func(inv *models.Invoice) string { return formatReviewAmount(inv.DunningFee) }},
+}
+
+// lowReviewConfidence marks fields whose extraction confidence is below this value
+const lowReviewConfidence = 0.5
+
+var (
+	reviewTitleStyle    = lipgloss.NewStyle().Bold(true)
+	reviewSelectedStyle = lipgloss.NewStyle().Reverse(true)
+	reviewDimStyle      = lipgloss.NewStyle().Faint(true)
+	reviewErrorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	reviewPanelStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+)
+
+// reviewModel is the bubbletea model of the review command
+type reviewModel struct {
+	items   []*reviewItem
+	current int
+	field   int
+	editing bool
+	input   []rune
+	message string
+	write   bool // The reviewer finished with w; false if they quit
+}
+
+func newReviewModel(items []*reviewItem) reviewModel {
+	return reviewModel{items: items}
+}
+
+func (m reviewModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if m.editing {
+		return m.updateEditing(key), nil
+	}
+
+	item := m.items[m.current]
+	m.message = ""
+
+	switch key.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "w":
+		m.write = true
+		return m, tea.Quit
+	case "up", "k":
+		if m.field > 0 {
+			m.field--
+		}
+	case "down", "j":
+		if m.field < len(reviewFields)-1 {
+			m.field++
+		}
+	case "left", "p":
+		if m.current > 0 {
+			m.current--
+		}
+	case "right", "n":
+		if m.current < len(m.items)-1 {
+			m.current++
+		}
+	case "enter":
+		m.editing = true
+		m.input = []rune(reviewFields[m.field].value(item.result.Invoice))
+	case "t":
+		if item.result.Invoice.Type == "RECEIVABLE" {
+			item.result.Invoice.Type = "PAYABLE"
+		} else {
+			item.result.Invoice.Type = "RECEIVABLE"
+		}
+		item.edited = true
+	case "a", "r":
+		item.approved, item.rejected = key.String() == "a", key.String() == "r"
+		if m.current < len(m.items)-1 {
+			m.current++
+		} else {
+			m.message = "Letzte Rechnung erreicht – w schreibt die akzeptierten Rechnungen"
+		}
+	}
+	return m, nil
+}
+
+// updateEditing handles keys while a field value is being edited
+func (m reviewModel) updateEditing(key tea.KeyMsg) reviewModel {
+	switch key.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.editing = false
+	case tea.KeyEnter:
+		field := reviewFields[m.field]
+		override, err := booking.ParseFieldOverride(field.name + "=" + string(m.input))
+		if err != nil {
+			m.message = err.Error()
+			return m
+		}
+		item := m.items[m.current]
+		override.Apply(item.result.Invoice)
+		item.edited = true
+		m.editing = false
+		m.message = field.label + " geändert"
+	case tea.KeyBackspace:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.input = append(m.input, key.Runes...)
+	}
+	return m
+}
+
+func (m reviewModel) View() string {
+	item := m.items[m.current]
+	invoice := item.result.Invoice
+
+	status := "offen"
+	switch {
+	case item.approved:
+		status = "akzeptiert"
+	case item.rejected:
+		status = "verworfen"
+	}
+	if item.edited {
+		status += ", korrigiert"
+	}
+	header := reviewTitleStyle.Render(fmt.Sprintf("Rechnung %d/%d: %s", m.current+1, len(m.items), item.result.Filename)) +
+		"  " + reviewDimStyle.Render("["+status+"]")
+
+	var fields strings.Builder
+	fmt.Fprintf(&fields, "%-16s %s\n", "Typ", reviewTypeLabel(invoice.Type))
+	for i, field := range reviewFields {
+		value := field.value(invoice)
+		if m.editing && i == m.field {
+			value = string(m.input) + "█"
+		}
+		if conf, ok := item.result.Confidence[field.confidence]; ok && conf < lowReviewConfidence && value != "" {
+			value += " (?)"
+		}
+		line := fmt.Sprintf("%-16s %s", field.label, value)
+		if i == m.field {
+			line = reviewSelectedStyle.Render(line)
+		}
+		fields.WriteString(line + "\n")
+	}
+
+	panels := lipgloss.JoinHorizontal(lipgloss.Top,
+		reviewPanelStyle.Width(60).Render(strings.TrimRight(fields.String(), "\n")),
+		reviewPanelStyle.Width(50).Render(reviewBookingSummary(item)))
+
+	help := reviewDimStyle.Render("↑/↓ Feld · enter bearbeiten · t Typ · a akzeptieren · r verwerfen · ←/→ blättern · w schreiben · q abbrechen")
+	message := ""
+	if m.message != "" {
+		message = reviewErrorStyle.Render(m.message)
+	}
+
+	return header + "\n" + panels + "\n" + message + "\n" + help + "\n"
+}
+
+// reviewBookingSummary summarizes the proposed booking and its warnings
+func reviewBookingSummary(item *reviewItem) string {
+	var summary strings.Builder
+	summary.WriteString(reviewTitleStyle.Render("Buchungsvorschlag") + "\n")
+
+	entry := item.result.Booking
+	if entry == nil {
+		summary.WriteString("keine Buchung\n")
+		return summary.String()
+	}
+	if item.edited {
+		summary.WriteString(reviewDimStyle.Render("wird beim Schreiben neu erstellt") + "\n")
+	}
+	fmt.Fprintf(&summary, "Soll:   %s %s\n", entry.DebitAccount, entry.DebitAccountName)
+	fmt.Fprintf(&summary, "Haben:  %s %s\n", entry.CreditAccount, entry.CreditAccountName)
+	fmt.Fprintf(&summary, "BU:     %s %s\n", entry.TaxKey, entry.TaxKeyDescription)
+	fmt.Fprintf(&summary, "Betrag: %.2f EUR\n", entry.Amount)
+	fmt.Fprintf(&summary, "Text:   %s\n", entry.BookingText)
+	for _, warning := range entry.Warnings {
+		summary.WriteString(reviewErrorStyle.Render("⚠ "+warning) + "\n")
+	}
+	if len(entry.CompletionChanges) > 0 {
+		fmt.Fprintf(&summary, "%d Felder durch KI ergänzt/geändert\n", len(entry.CompletionChanges))
+	}
+	return strings.TrimRight(summary.String(), "\n")
+}
+
+// reviewTypeLabel returns the German name of an invoice type
+func reviewTypeLabel(invoiceType string) string {
+	if invoiceType == "RECEIVABLE" {
+		return "Ausgangsrechnung (receivable)"
+	}
+	return "Eingangsrechnung (payable)"
+}
+
+// reviewSubTypeLabel returns the sub-type as accepted when editing it
+func reviewSubTypeLabel(subType string) string {
+	switch subType {
+	case models.InvoiceSubTypePrepayment:
+		return "prepayment"
+	case models.InvoiceSubTypeFinal:
+		return "final"
+	case models.InvoiceSubTypeReminder:
+		return "reminder"
+	}
+	return "regular"
+}
+
+// formatReviewDate formats a date as YYYY-MM-DD, the format accepted when editing, or empty if unset
+func formatReviewDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// formatReviewAmount formats cents as EUR with a decimal comma, the format accepted when editing
+func formatReviewAmount(cents int64) string {
+	return strings.Replace(fmt.Sprintf("%.2f", float64(cents)/100), ".", ",", 1)
+}
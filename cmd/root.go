@@ -2,14 +2,24 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"tools/internal/config"
 	"tools/internal/logger"
 )
 
 var version = "1.0.0"
 
+// loadedConfig is the configuration main loaded, and loadConfigErr why it failed validation.
+// loadedConfig is nil if the configuration is unusable or was never loaded, as in tests.
+var (
+	loadedConfig  *config.Config
+	loadConfigErr error
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "tools",
 	Short: "Tools CLI - A command-line interface for various utilities",
@@ -17,7 +27,22 @@ var rootCmd = &cobra.Command{
 various utilities and tools for development and automation tasks.
 
 This application is built with Go and Cobra, making it easy to extend
-with additional subcommands as needed.`,
+with additional subcommands as needed.
+
+Configuration is read from environment variables (and a .env file). Settings can
+also be kept in a YAML or TOML file, given with --config or found as
+tax-ai-tools.yaml/.toml in the working directory or config.yaml/.toml in
+~/.config/tax-ai-tools/. Environment variables override values from the config
+file. A default file that cannot be read is skipped with a warning.
+
+Exit codes:
+  0   success
+  1   other failure (e.g. invalid flags)
+  2   missing or invalid configuration or credentials
+  3   missing, unreadable or unusable input file
+  4   external API (Document AI, Vision, OpenAI, Sheets) failed or timed out
+  5   batch finished, but some files failed
+  6   batch finished, but its gross total deviates from --control-total`,
 	Version: version,
 	Run: func(cmd *cobra.Command, args []string) {
 		log := logger.WithComponent("root")
@@ -36,12 +61,33 @@ func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		log.Error().
 			Err(err).
+			Int("exit_code", exitCode(err)).
 			Msg("Command execution failed")
 		fmt.Fprintf(os.Stderr, "Error executing command: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
 	rootCmd.Flags().BoolP("version", "v", false, "Print version information")
+	rootCmd.PersistentFlags().String("config", "", "Config file (YAML or TOML); environment variables override its values")
+	rootCmd.PersistentFlags().BoolVar(&compactJSON, "compact", false, "Print JSON output on a single line instead of indented")
+}
+
+// SetConfig hands the result of config.Load to the commands. With only required settings
+// missing, cfg is still set, and the commands that do without them keep running.
+func SetConfig(cfg *config.Config, err error) {
+	loadedConfig, loadConfigErr = cfg, err
+}
+
+// ConfigPath returns the --config value from the command line. main needs the file before
+// the commands run, so it is read ahead of Cobra's own flag parsing.
+func ConfigPath(args []string) string {
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	flags.Usage = func() {}
+	configPath := flags.String("config", "", "")
+	_ = flags.Parse(args)
+	return *configPath
 }
\ No newline at end of file
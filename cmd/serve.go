@@ -0,0 +1,464 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+	"tools/internal/booking"
+	"tools/internal/docformat"
+	"tools/internal/invoice"
+	"tools/internal/llm"
+	"tools/internal/logger"
+	"tools/internal/ocr"
+	"tools/internal/pdf"
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve invoice extraction, DATEV booking and OCR over HTTP",
+	Long: `Start an HTTP server exposing the invoice, datev and ocr commands as an API, so other
+systems can integrate without running the binary.
+
+Endpoints (multipart/form-data with the PDF in the field "file", max. 20MB):
+  POST /invoice   extracted invoice as in "tools invoice --confidence"
+                  form field complete=true fills missing fields like --complete
+  POST /datev     invoice and booking as in "tools datev --json"
+                  form field type=payable|receivable overrides the invoice type
+  POST /ocr       OCR text and metadata as in "tools ocr --json"
+                  form field pages=1-2 limits the pages
+  GET  /healthz   liveness check
+
+Every endpoint accepts the form field password for encrypted PDFs (tried before
+PDF_PASSWORDS). Errors are returned as JSON {"error": {"code": ..., "message": ...}}
+with a status code matching the failure: 400/413/422 for unusable uploads, 502 when
+Document AI, Vision or OpenAI failed, 504 when the request timed out.
+
+The server stops accepting requests on SIGINT or SIGTERM and waits up to --timeout
+for requests in progress.
+
+Requires the environment variables of the invoice, datev and ocr commands.`,
+	Example: `  # Listen on port 8080
+  tools serve
+
+  # Extract an invoice
+  curl -F file=@invoice.pdf http://localhost:8080/invoice
+
+  # Generate a booking for an Eingangsrechnung
+  curl -F file=@invoice.pdf -F type=payable http://localhost:8080/datev`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("addr", ":8080", "Address to listen on")
+	serveCmd.Flags().Int("timeout", 300, "Timeout per request in seconds (also used for the Document AI request and shutdown)")
+	serveCmd.Flags().String("skr", "", "Kontenrahmen for /datev (03=SKR03, 04=SKR04; default: CHART_OF_ACCOUNTS or 03)")
+}
+
+// maxUploadBytes limits the request body: the 20MB document limit plus room for the multipart framing
+const maxUploadBytes = invoice.MaxDocumentSizeBytes + 1<<20
+
+// apiServer holds the services shared by all requests
+type apiServer struct {
+	processor  invoice.InvoiceProcessor
+	completion invoice.InvoiceCompletionService
+	ocrService ocr.OCRService
+	deskewOCR  ocr.OCRService // Completion and booking OCR with OCR_DESKEW; nil if they share ocrService
+	booking    services.BookingService
+	timeout    time.Duration
+	log        zerolog.Logger
+}
+
+// apiUpload is the PDF of a request, decrypted if it was password-protected
+type apiUpload struct {
+	filename string
+	data     []byte
+}
+
+// apiError is an error response with its HTTP status
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+// apiErrorMappings maps the sentinel errors of the processing packages to HTTP statuses, checked in order
+var apiErrorMappings = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{invoice.ErrDocumentTooLarge, http.StatusRequestEntityTooLarge, "document_too_large"},
+	{ocr.ErrPDFTooLarge, http.StatusRequestEntityTooLarge, "document_too_large"},
+	{invoice.ErrInvalidPDF, http.StatusBadRequest, "invalid_pdf"},
+	{ocr.ErrInvalidPDF, http.StatusBadRequest, "invalid_pdf"},
+	{invoice.ErrUnsupportedFormat, http.StatusBadRequest, "unsupported_format"},
+	{ocr.ErrUnsupportedFormat, http.StatusBadRequest, "unsupported_format"},
+	{invoice.ErrEncryptedPDF, http.StatusUnprocessableEntity, "encrypted_pdf"},
+	{ocr.ErrTooManyPages, http.StatusUnprocessableEntity, "too_many_pages"},
+	{invoice.ErrTooManyPages, http.StatusUnprocessableEntity, "too_many_pages"},
+	{ocr.ErrEmptyDocument, http.StatusUnprocessableEntity, "empty_document"},
+	{invoice.ErrMissingRequiredField, http.StatusUnprocessableEntity, "missing_required_field"},
+	{invoice.ErrLowOCRConfidence, http.StatusUnprocessableEntity, "low_ocr_confidence"},
+	{invoice.ErrInternalInvoice, http.StatusUnprocessableEntity, "internal_invoice"},
+	{invoice.ErrReminder, http.StatusUnprocessableEntity, "payment_reminder"},
+	{invoice.ErrQuotaExceeded, http.StatusTooManyRequests, "quota_exceeded"},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout"},
+	{invoice.ErrMissingCredentials, http.StatusInternalServerError, "configuration"},
+	{invoice.ErrInvalidCredentials, http.StatusInternalServerError, "configuration"},
+	{invoice.ErrInvalidConfiguration, http.StatusInternalServerError, "configuration"},
+	{invoice.ErrProcessorNotFound, http.StatusInternalServerError, "configuration"},
+	{ocr.ErrMissingCredentials, http.StatusInternalServerError, "configuration"},
+	{invoice.ErrProcessingFailed, http.StatusBadGateway, "external_api"},
+	{ocr.ErrOCRFailed, http.StatusBadGateway, "external_api"},
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	log := logger.WithComponent("serve")
+
+	addr, _ := cmd.Flags().GetString("addr")
+	timeoutSecs, _ := cmd.Flags().GetInt("timeout")
+	skr, _ := cmd.Flags().GetString("skr")
+
+	if timeoutSecs <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	timeout := time.Duration(timeoutSecs) * time.Second
+
+	skr, err := resolveChartOfAccounts(skr)
+	if err != nil {
+		return err
+	}
+
+	// The clients live as long as the server, so they are not bound to a request context
+	server, err := newAPIServer(context.Background(), skr, timeout, log)
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           server.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       timeout,
+		WriteTimeout:      timeout + 10*time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info().Str("addr", addr).Str("skr", skr).Dur("timeout", timeout).Msg("HTTP server listening")
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("HTTP server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Info().Msg("Shutting down, waiting for requests in progress")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("HTTP server shutdown failed: %w", err)
+	}
+	server.close()
+	log.Info().Msg("HTTP server stopped")
+
+	return nil
+}
+
+// newAPIServer creates the services once for all requests, wrapped with the extraction cache if configured
+func newAPIServer(ctx context.Context, skr string, timeout time.Duration, log zerolog.Logger) (*apiServer, error) {
+	store := openExtractionCache(log)
+
+	processor, err := createInvoiceProcessor(ctx, invoice.ProcessorOptions{Timeout: timeout}, log)
+	if err != nil {
+		return nil, err
+	}
+	ocrService, err := createOCRService(ctx, false, log)
+	if err != nil {
+		return nil, err
+	}
+
+	// Completion and the booking service share one Vision client, the one of the /ocr endpoint unless
+	// completion deskews, which the /ocr endpoint does not
+	completionConfig := invoice.CompletionConfigFromEnv()
+	var deskewOCR ocr.OCRService
+	if completionConfig.Deskew {
+		deskewOCR, err = createOCRService(ctx, true, log)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if store != nil {
+		processor = invoice.NewCachedInvoiceProcessor(processor, store, false)
+		ocrService = ocr.NewCachedOCRService(ocrService, store, false)
+		if deskewOCR != nil {
+			deskewOCR = ocr.NewCachedOCRService(deskewOCR, store, false)
+		}
+	}
+	completionOCR := ocrService
+	if deskewOCR != nil {
+		completionOCR = deskewOCR
+	}
+	llmClient, err := llm.NewClientFromEnv()
+	if err != nil {
+		return nil, withExitCode(ExitConfig, err)
+	}
+	completion := invoice.NewInvoiceCompletionServiceWithDeps(completionOCR, llmClient, completionConfig)
+
+	bookingService, err := createBookingService(ctx, skr, booking.BookingOptions{
+		DocumentAITimeout: timeout,
+		Processor:         processor,
+		OCRService:        completionOCR,
+		LLMClient:         llmClient,
+	}, log)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiServer{
+		processor:  processor,
+		completion: completion,
+		ocrService: ocrService,
+		deskewOCR:  deskewOCR,
+		booking:    bookingService,
+		timeout:    timeout,
+		log:        log,
+	}, nil
+}
+
+// close releases the clients of the services once no request uses them anymore
+func (s *apiServer) close() {
+	if err := s.booking.Close(); err != nil {
+		s.log.Warn().Err(err).Msg("Failed to close booking service")
+	}
+	closeInvoiceProcessor(s.processor, s.log)
+	closeOCRService(s.ocrService, s.log)
+	if s.deskewOCR != nil {
+		closeOCRService(s.deskewOCR, s.log)
+	}
+}
+
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/invoice", s.handle(s.handleInvoice))
+	mux.HandleFunc("/datev", s.handle(s.handleDatev))
+	mux.HandleFunc("/ocr", s.handle(s.handleOCR))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": version})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, &apiError{http.StatusNotFound, "not_found", "unknown endpoint " + r.URL.Path})
+	})
+	return mux
+}
+
+// handle wraps an endpoint: it accepts only POST, reads the uploaded PDF, applies the request timeout and
+// writes the result or error as JSON
+func (s *apiServer) handle(endpoint func(context.Context, *http.Request, *apiUpload) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeAPIError(w, &apiError{http.StatusMethodNotAllowed, "method_not_allowed", "use POST with a multipart PDF upload"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		defer cancel()
+
+		upload, err := readAPIUpload(w, r)
+		if err == nil {
+			var result interface{}
+			result, err = endpoint(ctx, r, upload)
+			if err == nil {
+				writeAPIJSON(w, http.StatusOK, result)
+				s.log.Info().
+					Str("path", r.URL.Path).
+					Str("file", upload.filename).
+					Dur("duration", time.Since(startTime)).
+					Msg("Request completed")
+				return
+			}
+		}
+
+		apiErr := toAPIError(err)
+		s.log.Warn().
+			Err(err).
+			Str("path", r.URL.Path).
+			Int("status", apiErr.status).
+			Dur("duration", time.Since(startTime)).
+			Msg("Request failed")
+		writeAPIError(w, apiErr)
+	}
+}
+
+func (s *apiServer) handleInvoice(ctx context.Context, r *http.Request, upload *apiUpload) (interface{}, error) {
+	startTime := time.Now()
+
+	modelInvoice, confidence, err := s.processor.ProcessInvoiceWithConfidence(ctx, bytes.NewReader(upload.data))
+	if err != nil {
+		return nil, err
+	}
+
+	if r.FormValue("complete") == "true" {
+		completedInvoice, completionConfidence, err := s.completion.CompleteInvoiceWithConfidence(ctx, modelInvoice, bytes.NewReader(upload.data))
+		if err != nil {
+			s.log.Warn().Err(err).Str("file", upload.filename).Msg("Completion service failed, using Document AI result")
+		} else {
+			modelInvoice = completedInvoice
+			// A cached extraction may have been stored without confidence scores
+			if confidence == nil {
+				confidence = make(map[string]float32, len(completionConfidence))
+			}
+			for k, v := range completionConfidence {
+				confidence[k] = v
+			}
+		}
+	}
+
+	return InvoiceOutput{
+		Invoice:    *convertToInvoiceData(modelInvoice),
+		Confidence: confidence,
+		Metadata: ProcessingMetadata{
+			FileName:           upload.filename,
+			FileSize:           int64(len(upload.data)),
+			ProcessedAt:        time.Now(),
+			ProcessingDuration: time.Since(startTime),
+			ProcessorUsed:      "Google Document AI Invoice Parser",
+		},
+	}, nil
+}
+
+func (s *apiServer) handleDatev(ctx context.Context, r *http.Request, upload *apiUpload) (interface{}, error) {
+	startTime := time.Now()
+
+	invoiceType := strings.ToUpper(r.FormValue("type"))
+	if invoiceType != "" && invoiceType != "PAYABLE" && invoiceType != "RECEIVABLE" {
+		return nil, &apiError{http.StatusBadRequest, "invalid_type", fmt.Sprintf("invalid invoice type: %s (must be 'payable' or 'receivable')", invoiceType)}
+	}
+
+	entry, completedInvoice, confidence, err := s.booking.GenerateBookingFromPDFWithConfidence(ctx, bytes.NewReader(upload.data), invoiceType)
+	if err != nil {
+		return nil, err
+	}
+	entry.SourceFile = upload.filename
+
+	return struct {
+		Booking    *services.DATEVBooking `json:"booking"`
+		Invoice    *models.Invoice        `json:"invoice"`
+		Confidence map[string]float32     `json:"confidence,omitempty"`
+		Metadata   map[string]interface{} `json:"metadata"`
+	}{entry, completedInvoice, confidence, map[string]interface{}{
+		"file_name":              upload.filename,
+		"processing_duration_ms": time.Since(startTime).Milliseconds(),
+		"generated_at":           time.Now(),
+		"tool_version":           version,
+	}}, nil
+}
+
+func (s *apiServer) handleOCR(ctx context.Context, r *http.Request, upload *apiUpload) (interface{}, error) {
+	pages, err := parsePageRange(r.FormValue("pages"))
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid_pages", err.Error()}
+	}
+
+	if len(pages) > 0 {
+		return s.ocrService.ProcessPDFPages(ctx, bytes.NewReader(upload.data), pages)
+	}
+	return s.ocrService.ProcessPDFWithMetadata(ctx, bytes.NewReader(upload.data))
+}
+
+// readAPIUpload reads the PDF from the multipart field "file" and decrypts it with the password form
+// field or PDF_PASSWORDS
+func readAPIUpload(w http.ResponseWriter, r *http.Request) (*apiUpload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	if err := r.ParseMultipartForm(maxUploadBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("upload exceeds %d bytes: %w", invoice.MaxDocumentSizeBytes, invoice.ErrDocumentTooLarge)
+		}
+		return nil, &apiError{http.StatusBadRequest, "invalid_request", "expected a multipart/form-data upload: " + err.Error()}
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "missing_file", `no PDF in the form field "file"`}
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid_request", "failed to read upload: " + err.Error()}
+	}
+	if len(data) > invoice.MaxDocumentSizeBytes {
+		return nil, fmt.Errorf("PDF has %d bytes: %w", len(data), invoice.ErrDocumentTooLarge)
+	}
+	// Reject other uploads before they reach the paid APIs
+	if _, ok := docformat.Detect(data); !ok {
+		return nil, fmt.Errorf("%s is neither a PDF nor a supported image: %w", header.Filename, invoice.ErrUnsupportedFormat)
+	}
+
+	var passwords []string
+	if password := r.FormValue("password"); password != "" {
+		passwords = append(passwords, password)
+	}
+	data, err = pdf.Decrypt(data, append(passwords, pdf.PasswordsFromEnv()...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiUpload{filename: header.Filename, data: data}, nil
+}
+
+// toAPIError maps an error to its response; unknown errors become 500
+func toAPIError(err error) *apiError {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	for _, mapping := range apiErrorMappings {
+		if errors.Is(err, mapping.err) {
+			return &apiError{mapping.status, mapping.code, err.Error()}
+		}
+	}
+	if strings.Contains(err.Error(), "ChatGPT") {
+		return &apiError{http.StatusBadGateway, "external_api", err.Error()}
+	}
+	return &apiError{http.StatusInternalServerError, "internal", err.Error()}
+}
+
+func writeAPIError(w http.ResponseWriter, err *apiError) {
+	writeAPIJSON(w, err.status, map[string]interface{}{
+		"error": map[string]string{"code": err.code, "message": err.message},
+	})
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = encodeJSON(w, body)
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"tools/internal/invoice"
+	"tools/internal/ocr"
+	"tools/pkg/models"
+)
+
+// fakeProcessor returns a fixed invoice without confidence scores, like a cached extraction stored as null
+type fakeProcessor struct {
+	calls int
+}
+
+func (f *fakeProcessor) ProcessInvoice(ctx context.Context, pdfData io.Reader) (*models.Invoice, error) {
+	invoice, _, err := f.ProcessInvoiceWithConfidence(ctx, pdfData)
+	return invoice, err
+}
+
+func (f *fakeProcessor) ProcessInvoiceWithConfidence(ctx context.Context, pdfData io.Reader) (*models.Invoice, map[string]float32, error) {
+	f.calls++
+	return &models.Invoice{InvoiceNumber: "RE-1", GrossAmount: 11900}, nil, nil
+}
+
+func (f *fakeProcessor) ProcessInvoicePages(ctx context.Context, pdfData io.Reader, pages []int32) (*models.Invoice, map[string]float32, error) {
+	return f.ProcessInvoiceWithConfidence(ctx, pdfData)
+}
+
+func (f *fakeProcessor) ProcessMultiInvoice(ctx context.Context, pdfData io.Reader) ([]*models.Invoice, error) {
+	invoice, err := f.ProcessInvoice(ctx, pdfData)
+	return []*models.Invoice{invoice}, err
+}
+
+// fakeCompletion fills in the vendor
+type fakeCompletion struct{}
+
+func (fakeCompletion) CompleteInvoice(ctx context.Context, inv *models.Invoice, pdfData io.Reader) (*models.Invoice, error) {
+	completed, _, err := fakeCompletion{}.CompleteInvoiceWithConfidence(ctx, inv, pdfData)
+	return completed, err
+}
+
+func (fakeCompletion) ValidateInvoice(inv *models.Invoice) (bool, []string) {
+	return inv.Vendor != "", []string{"vendor"}
+}
+
+func (fakeCompletion) CompleteInvoiceWithConfidence(ctx context.Context, inv *models.Invoice, pdfData io.Reader) (*models.Invoice, map[string]float32, error) {
+	completed := *inv
+	completed.Vendor = "Muster GmbH"
+	return &completed, map[string]float32{"vendor": 0.9}, nil
+}
+
+func newTestAPIServer(processor invoice.InvoiceProcessor) *apiServer {
+	return &apiServer{processor: processor, completion: fakeCompletion{}, timeout: time.Minute, log: zerolog.Nop()}
+}
+
+// multipartUpload builds a request body with the file and the form fields
+func multipartUpload(t *testing.T, filename string, data []byte, fields map[string]string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &body, writer.FormDataContentType()
+}
+
+// serveUpload posts the upload to the endpoint and returns the status and the error code of the response
+func serveUpload(t *testing.T, server *apiServer, path string, body io.Reader, contentType string) (int, string) {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, path, body)
+	request.Header.Set("Content-Type", contentType)
+	recorder := httptest.NewRecorder()
+	server.routes().ServeHTTP(recorder, request)
+
+	var response struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response is not JSON: %v\n%s", err, recorder.Body.String())
+	}
+	return recorder.Code, response.Error.Code
+}
+
+func TestServeRejectsOversizeUpload(t *testing.T) {
+	processor := &fakeProcessor{}
+	data := append([]byte("%PDF-1.4\n"), make([]byte, maxUploadBytes)...)
+	body, contentType := multipartUpload(t, "gross.pdf", data, nil)
+
+	status, code := serveUpload(t, newTestAPIServer(processor), "/invoice", body, contentType)
+	if status != http.StatusRequestEntityTooLarge || code != "document_too_large" {
+		t.Errorf("status = %d %q, want 413 document_too_large", status, code)
+	}
+	if processor.calls != 0 {
+		t.Error("an oversize upload must not be processed")
+	}
+}
+
+func TestServeRejectsNonPDFUpload(t *testing.T) {
+	processor := &fakeProcessor{}
+	body, contentType := multipartUpload(t, "rechnung.txt", []byte("Rechnung Nr. 1\nBetrag 119,00 EUR\n"), nil)
+
+	status, code := serveUpload(t, newTestAPIServer(processor), "/invoice", body, contentType)
+	if status != http.StatusBadRequest || code != "unsupported_format" {
+		t.Errorf("status = %d %q, want 400 unsupported_format", status, code)
+	}
+	if processor.calls != 0 {
+		t.Error("a non-PDF upload must not be processed")
+	}
+}
+
+func TestServeCompletesInvoiceWithoutConfidence(t *testing.T) {
+	body, contentType := multipartUpload(t, "rechnung.pdf", []byte("%PDF-1.4\n%%EOF\n"), map[string]string{"complete": "true"})
+
+	status, code := serveUpload(t, newTestAPIServer(&fakeProcessor{}), "/invoice", body, contentType)
+	if status != http.StatusOK {
+		t.Errorf("status = %d %q, want 200", status, code)
+	}
+}
+
+func TestToAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"api error", &apiError{http.StatusBadRequest, "invalid_type", "invalid invoice type"}, http.StatusBadRequest, "invalid_type"},
+		{"document too large", fmt.Errorf("upload: %w", invoice.ErrDocumentTooLarge), http.StatusRequestEntityTooLarge, "document_too_large"},
+		{"invalid PDF", fmt.Errorf("ProcessPDF: %w", ocr.ErrInvalidPDF), http.StatusBadRequest, "invalid_pdf"},
+		{"encrypted PDF", fmt.Errorf("Decrypt: %w", invoice.ErrEncryptedPDF), http.StatusUnprocessableEntity, "encrypted_pdf"},
+		{"reminder", fmt.Errorf("GenerateBooking: %w", invoice.ErrReminder), http.StatusUnprocessableEntity, "payment_reminder"},
+		{"quota", fmt.Errorf("ProcessInvoice: %w", invoice.ErrQuotaExceeded), http.StatusTooManyRequests, "quota_exceeded"},
+		{"timeout", fmt.Errorf("ProcessInvoice: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "timeout"},
+		{"credentials", fmt.Errorf("NewProcessor: %w", invoice.ErrMissingCredentials), http.StatusInternalServerError, "configuration"},
+		{"Document AI", fmt.Errorf("ProcessInvoice: %w", invoice.ErrProcessingFailed), http.StatusBadGateway, "external_api"},
+		{"ChatGPT", errors.New("GenerateBooking: ChatGPT booking generation failed: EOF"), http.StatusBadGateway, "external_api"},
+		{"unknown", errors.New("something broke"), http.StatusInternalServerError, "internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toAPIError(tt.err)
+			if got.status != tt.wantStatus || got.code != tt.wantCode {
+				t.Errorf("toAPIError(%v) = %d %q, want %d %q", tt.err, got.status, got.code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
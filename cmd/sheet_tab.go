@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"slices"
+
+	"github.com/rs/zerolog"
+	"tools/internal/reconciliation"
+	"tools/internal/sheets"
+)
+
+// standardSheets are the tabs the commands read and write by name. GOOGLE_SHEET_URL is shared by all
+// commands, so a link to one of them, e.g. the #gid=0 of a URL copied from the browser, keeps each
+// command on its own tabs.
+var standardSheets = []string{"Kreditoren", "Debitoren", reconciliation.DefaultBankSheet, sheets.ReconciliationSheet}
+
+// linkedSheetTab returns the tab the sheet URL links to with #gid= if it is a tab of its own, and
+// defaultSheet otherwise
+func linkedSheetTab(ctx context.Context, sheetsService *sheets.Service, defaultSheet string, log zerolog.Logger) (string, error) {
+	linked, ok, err := sheetsService.LinkedSheet(ctx)
+	if err != nil || !ok {
+		return defaultSheet, err
+	}
+	if slices.Contains(standardSheets, linked) {
+		if linked != defaultSheet {
+			log.Debug().
+				Str("linked_sheet", linked).
+				Str("sheet", defaultSheet).
+				Msg("Sheet URL links a standard tab, keeping the default tab")
+		}
+		return defaultSheet, nil
+	}
+
+	log.Info().
+		Str("linked_sheet", linked).
+		Str("default_sheet", defaultSheet).
+		Msg("Using the tab linked in the sheet URL")
+	return linked, nil
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tools/internal/logger"
+	"tools/internal/reconciliation"
+	"tools/internal/sheets"
+	"tools/internal/stats"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize a processed invoice sheet for one month",
+	Long: `Read a sheet written by datev-batch (Kreditoren or Debitoren) and print monthly totals:
+number of invoices, net/VAT/gross sums, a breakdown by tax key, the top vendors or
+customers by amount and the number of rows with warnings or errors.
+
+Invoices are assigned to a month by their invoice date; rows without one (usually
+error rows) use the processing date instead.
+
+Required environment variables:
+  GOOGLE_APPLICATION_CREDENTIALS - Path to service account JSON file, OR
+  GOOGLE_CREDENTIALS - Inline JSON credentials string
+  GOOGLE_SHEET_URL - Google Sheets URL containing the sheet`,
+	Example: `  # Overview of June's supplier invoices
+  tools stats --sheet Kreditoren --month 2024-06
+
+  # Machine-readable summary for dashboards
+  tools stats --sheet Debitoren --month 2024-06 --json`,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().String("sheet", "Kreditoren", "Sheet to summarize (Kreditoren or Debitoren)")
+	statsCmd.Flags().String("month", "", "Month to summarize (format: YYYY-MM, default: current month)")
+	statsCmd.Flags().Int("top", 5, "Number of top vendors/customers to show (0 for all)")
+	statsCmd.Flags().Bool("json", false, "Output as JSON")
+	statsCmd.Flags().Int("timeout", 120, "Timeout in seconds")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	log := logger.WithComponent("stats")
+
+	// Get flags
+	sheetName, _ := cmd.Flags().GetString("sheet")
+	monthStr, _ := cmd.Flags().GetString("month")
+	topN, _ := cmd.Flags().GetInt("top")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	timeoutSecs, _ := cmd.Flags().GetInt("timeout")
+
+	// Parse month
+	month := time.Now()
+	if monthStr != "" {
+		parsedMonth, err := time.Parse("2006-01", monthStr)
+		if err != nil {
+			return fmt.Errorf("invalid month format. Use YYYY-MM: %w", err)
+		}
+		month = parsedMonth
+	}
+
+	if timeoutSecs <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+
+	// Check required environment variables
+	sheetURL := os.Getenv("GOOGLE_SHEET_URL")
+	if sheetURL == "" {
+		return withExitCode(ExitConfig, fmt.Errorf("GOOGLE_SHEET_URL environment variable is required"))
+	}
+
+	log.Info().
+		Str("sheet", sheetName).
+		Str("month", month.Format("2006-01")).
+		Msg("Starting sheet statistics")
+
+	// Create context with timeout and signal handling
+	ctx, cancel := createContextWithTimeout(timeoutSecs, log)
+	defer cancel()
+
+	sheetsService, err := sheets.NewSheetsService(ctx, sheetURL)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Google Sheets service: %w", err)
+	}
+
+	invoices, err := reconciliation.NewDataReader(sheetsService).ReadInvoices(ctx, sheetName)
+	if err != nil {
+		return fmt.Errorf("failed to read invoices: %w", err)
+	}
+
+	summary := stats.Summarize(sheetName, invoices, month, topN)
+
+	log.Info().
+		Int("invoices", summary.InvoiceCount).
+		Int("errors", summary.Status.Error).
+		Int("warnings", summary.Status.Warning).
+		Msg("Sheet statistics completed")
+
+	if jsonOutput {
+		return encodeJSON(os.Stdout, summary)
+	}
+
+	printStatsSummary(summary)
+	return nil
+}
+
+// printStatsSummary prints the summary as human-readable German tables
+func printStatsSummary(summary stats.Summary) {
+	partyLabel := "Lieferanten"
+	if summary.Sheet == "Debitoren" {
+		partyLabel = "Kunden"
+	}
+
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Printf("STATISTIK %s - %s\n", summary.Sheet, summary.Month)
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Printf("Rechnungen:   %d\n", summary.InvoiceCount)
+	fmt.Printf("Netto:        %s EUR\n", formatStatsAmount(summary.NetCents))
+	fmt.Printf("MwSt:         %s EUR\n", formatStatsAmount(summary.VATCents))
+	fmt.Printf("Brutto:       %s EUR\n", formatStatsAmount(summary.GrossCents))
+	fmt.Printf("Status:       %d erfolgreich, %d Warnungen, %d Fehler", summary.Status.Success, summary.Status.Warning, summary.Status.Error)
+	if summary.Status.Skipped > 0 {
+		fmt.Printf(", %d übersprungen", summary.Status.Skipped)
+	}
+	if summary.Status.Unknown > 0 {
+		fmt.Printf(", %d ohne Status", summary.Status.Unknown)
+	}
+	fmt.Println()
+
+	if len(summary.TaxKeys) > 0 {
+		fmt.Println()
+		fmt.Println("Nach Steuerschlüssel:")
+		fmt.Printf("  %-10s %8s %15s %15s %15s\n", "Schlüssel", "Anzahl", "Netto", "MwSt", "Brutto")
+		for _, taxKey := range summary.TaxKeys {
+			key := taxKey.TaxKey
+			if key == "" {
+				key = "(ohne)"
+			}
+			fmt.Printf("  %-10s %8d %15s %15s %15s\n", key, taxKey.Count,
+				formatStatsAmount(taxKey.NetCents), formatStatsAmount(taxKey.VATCents), formatStatsAmount(taxKey.GrossCents))
+		}
+	}
+
+	if len(summary.TopParties) > 0 {
+		fmt.Println()
+		fmt.Printf("Top %s nach Brutto:\n", partyLabel)
+		for i, party := range summary.TopParties {
+			name := party.Name
+			if name == "" {
+				name = "(unbekannt)"
+			}
+			fmt.Printf("  %2d. %-45.45s %4d %15s\n", i+1, name, party.Count, formatStatsAmount(party.GrossCents))
+		}
+	}
+
+	fmt.Println(strings.Repeat("=", 80))
+}
+
+// formatStatsAmount formats cents in German notation, e.g. 123456 -> "1.234,56"
+func formatStatsAmount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	euros := fmt.Sprintf("%d", cents/100)
+	var grouped strings.Builder
+	for i, digit := range euros {
+		if i > 0 && (len(euros)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return fmt.Sprintf("%s%s,%02d", sign, grouped.String(), cents%100)
+}
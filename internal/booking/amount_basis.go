@@ -0,0 +1,30 @@
+package booking
+
+import (
+	"fmt"
+
+	"tools/pkg/services"
+)
+
+// netBasisTaxKeyWarning returns a warning if a net booking has a tax key with VAT on a line that
+// DATEV books with the key. DATEV reads such an amount as gross and derives the VAT from it, which
+// books too little VAT. Explicit VAT postings are exported without tax keys and are safe.
+func netBasisTaxKeyWarning(booking *services.DATEVBooking) string {
+	if booking.AmountBasis != services.AmountBasisNet || len(booking.Postings) > 0 {
+		return ""
+	}
+
+	taxKeys := []string{booking.TaxKey}
+	if len(booking.Splits) > 0 {
+		taxKeys = taxKeys[:0]
+		for _, split := range booking.Splits {
+			taxKeys = append(taxKeys, split.TaxKey)
+		}
+	}
+	for _, taxKey := range taxKeys {
+		if rate, known := taxKeyRates[taxKey]; taxKey != "" && (!known || rate > 0) {
+			return fmt.Sprintf("Nettobetrag mit Steuerschlüssel %s: DATEV rechnet die Steuer aus dem Betrag heraus und bucht zu wenig - Bruttobetrag (BOOKING_AMOUNT_BASIS=gross) oder explizite Steuerzeilen verwenden", taxKey)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package booking
+
+import (
+	"testing"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+func TestConvertToDatevBookingAmountBasis(t *testing.T) {
+	invoice := &models.Invoice{NetAmount: 10000, VATAmount: 1900, GrossAmount: 11900}
+	tests := []struct {
+		basis string
+		want  float64
+	}{
+		{"", 119},
+		{services.AmountBasisGross, 119},
+		{services.AmountBasisNet, 100},
+	}
+	for _, tt := range tests {
+		s := &SKR03BookingService{amountBasis: tt.basis}
+		booking := s.convertToDatevBooking(&ChatGPTBookingResponse{TaxKey: "9"}, invoice)
+		if booking.Amount != tt.want {
+			t.Errorf("amount basis %q: Amount = %.2f, want %.2f", tt.basis, booking.Amount, tt.want)
+		}
+	}
+}
+
+func TestNetBasisTaxKeyWarning(t *testing.T) {
+	tests := []struct {
+		name    string
+		booking *services.DATEVBooking
+		warn    bool
+	}{
+		{"gross with VAT key", &services.DATEVBooking{AmountBasis: services.AmountBasisGross, TaxKey: "9"}, false},
+		{"net with VAT key", &services.DATEVBooking{AmountBasis: services.AmountBasisNet, TaxKey: "9"}, true},
+		{"net with custom key", &services.DATEVBooking{AmountBasis: services.AmountBasisNet, TaxKey: "94"}, true},
+		{"net tax-free", &services.DATEVBooking{AmountBasis: services.AmountBasisNet, TaxKey: "0"}, false},
+		{"net without key", &services.DATEVBooking{AmountBasis: services.AmountBasisNet}, false},
+		{"net split with VAT key", &services.DATEVBooking{
+			AmountBasis: services.AmountBasisNet,
+			Splits:      []services.BookingSplit{{TaxKey: "0"}, {TaxKey: "5"}},
+		}, true},
+		{"net with explicit postings", &services.DATEVBooking{
+			AmountBasis: services.AmountBasisNet,
+			TaxKey:      "9",
+			Postings:    []services.Posting{{Account: "4930", Side: services.PostingDebit, Amount: 100}},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := netBasisTaxKeyWarning(tt.booking); (got != "") != tt.warn {
+				t.Errorf("netBasisTaxKeyWarning() = %q, want warning: %v", got, tt.warn)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package booking
+
+import (
+	"fmt"
+
+	"tools/pkg/models"
+)
+
+// amountCeilingWarning returns a warning if the gross amount exceeds MAX_INVOICE_AMOUNT, which
+// usually means a misread amount such as 4.500.000,00 instead of 45,00; empty otherwise
+func amountCeilingWarning(invoice *models.Invoice) string {
+	if invoice.ExceededAmountCeiling == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Bruttobetrag %.2f %s übersteigt die Obergrenze von %.2f (MAX_INVOICE_AMOUNT), vermutlich falsch gelesen - bitte bestätigen",
+		float64(invoice.GrossAmount)/100, invoice.Currency, float64(invoice.ExceededAmountCeiling)/100)
+}
@@ -0,0 +1,20 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+
+	"tools/pkg/models"
+)
+
+func TestAmountCeilingWarning(t *testing.T) {
+	if warning := amountCeilingWarning(&models.Invoice{GrossAmount: 4500}); warning != "" {
+		t.Errorf("plausible amount: unexpected warning %q", warning)
+	}
+
+	flagged := &models.Invoice{GrossAmount: 450000000, Currency: "EUR", ExceededAmountCeiling: 100000000}
+	warning := amountCeilingWarning(flagged)
+	if !strings.Contains(warning, "4500000.00 EUR") || !strings.Contains(warning, "1000000.00") {
+		t.Errorf("amountCeilingWarning() = %q, want gross amount and ceiling", warning)
+	}
+}
@@ -0,0 +1,60 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func validBookingResponse(text string) *ChatGPTBookingResponse {
+	return &ChatGPTBookingResponse{
+		DebitAccount:  "4930",
+		CreditAccount: "1600",
+		TaxKey:        "9",
+		BookingText:   text,
+	}
+}
+
+func TestValidateBookingResponseBookingTextLength(t *testing.T) {
+	umlauts60 := strings.Repeat("ä", 30) + strings.Repeat("Ü", 30) // 60 characters, 120 bytes
+	umlauts61 := umlauts60 + "ß"
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"ascii under limit", "Büromaterial Muster GmbH", "Büromaterial Muster GmbH"},
+		{"umlauts exactly at limit", umlauts60, umlauts60},
+		{"umlauts one over limit", umlauts61, strings.Repeat("ä", 30) + strings.Repeat("Ü", 27) + "..."},
+		{"mixed text over limit", "Gebühren für Überweisungen und Kontoführung März bis Mai Müller Bäckerei", "Gebühren für Überweisungen und Kontoführung März bis Mai ..."},
+	}
+
+	s := &SKR03BookingService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := validBookingResponse(tt.text)
+			if err := s.validateBookingResponse(response); err != nil {
+				t.Fatalf("validateBookingResponse: %v", err)
+			}
+			if response.BookingText != tt.want {
+				t.Errorf("BookingText = %q, want %q", response.BookingText, tt.want)
+			}
+			if !utf8.ValidString(response.BookingText) {
+				t.Errorf("BookingText is not valid UTF-8: %q", response.BookingText)
+			}
+			if n := utf8.RuneCountInString(response.BookingText); n > maxBookingTextLength {
+				t.Errorf("BookingText has %d characters, limit is %d", n, maxBookingTextLength)
+			}
+		})
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	if got := truncateRunes("Größe", 3); got != "Grö" {
+		t.Errorf("truncateRunes = %q, want %q", got, "Grö")
+	}
+	if got := truncateRunes("Öl", 5); got != "Öl" {
+		t.Errorf("truncateRunes = %q, want unchanged", got)
+	}
+}
@@ -0,0 +1,58 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"tools/internal/config"
+	"tools/pkg/services"
+)
+
+// bookingServices maps each chart of accounts (two-digit form, as in --skr) to the constructor
+// of its booking service. A chart is only accepted in CHART_OF_ACCOUNTS and --skr once it is
+// registered here.
+var bookingServices = map[string]func(context.Context, BookingOptions) (services.BookingService, error){
+	"03": NewSKR03BookingServiceWithOptions,
+}
+
+func init() {
+	config.RegisterChartOfAccountsParser(ParseChartOfAccounts)
+}
+
+// ParseChartOfAccounts normalizes a chart of accounts given as "SKR03", "skr03", "03" or "3" to
+// the two-digit form and rejects charts without a booking service.
+func ParseChartOfAccounts(value string) (string, error) {
+	chart := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "SKR")
+	if len(chart) == 1 {
+		chart = "0" + chart
+	}
+
+	if _, ok := bookingServices[chart]; !ok {
+		return "", fmt.Errorf("unsupported chart of accounts %q (supported: %s)", value, strings.Join(SupportedChartsOfAccounts(), ", "))
+	}
+	return chart, nil
+}
+
+// SupportedChartsOfAccounts lists the charts with a booking service, e.g. "SKR03"
+func SupportedChartsOfAccounts() []string {
+	var charts []string
+	for chart := range bookingServices {
+		charts = append(charts, "SKR"+chart)
+	}
+	sort.Strings(charts)
+	return charts
+}
+
+// NewBookingService creates the booking service for a chart of accounts accepted by
+// ParseChartOfAccounts
+func NewBookingService(ctx context.Context, chart string, options BookingOptions) (services.BookingService, error) {
+	const op = "NewBookingService"
+
+	chart, err := ParseChartOfAccounts(chart)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return bookingServices[chart](ctx, options)
+}
@@ -0,0 +1,18 @@
+package booking
+
+import "testing"
+
+func TestParseChartOfAccounts(t *testing.T) {
+	for _, value := range []string{"SKR03", "skr03", " 03 ", "3"} {
+		chart, err := ParseChartOfAccounts(value)
+		if err != nil || chart != "03" {
+			t.Errorf("ParseChartOfAccounts(%q) = %q, %v, want 03", value, chart, err)
+		}
+	}
+
+	for _, value := range []string{"SKR04", "04", "", "IFRS"} {
+		if _, err := ParseChartOfAccounts(value); err == nil {
+			t.Errorf("ParseChartOfAccounts(%q) succeeded, want error for chart without booking service", value)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package booking
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CompanyContext describes our business so account choices follow our chart-of-accounts conventions.
+// It is loaded from the JSON file named by BOOKING_COMPANY_CONTEXT_FILE, for example:
+//
+//	{
+//	  "industry": "Softwareentwicklung und IT-Beratung",
+//	  "description": "GmbH mit 12 Mitarbeitern, keine Warenbestände",
+//	  "expense_categories": [
+//	    {"category": "Cloud-Hosting und SaaS-Abos", "account": "4930"}
+//	  ],
+//	  "vendor_accounts": [
+//	    {"vendor": "Amazon Web Services", "account": "4930", "note": "Hosting, nicht Porto"}
+//	  ],
+//	  "notes": ["Hardware unter 800 EUR netto als GWG auf 4855"]
+//	}
+type CompanyContext struct {
+	Industry          string           `json:"industry"`
+	Description       string           `json:"description"`
+	ExpenseCategories []AccountMapping `json:"expense_categories"`
+	VendorAccounts    []VendorMapping  `json:"vendor_accounts"`
+	Notes             []string         `json:"notes"`
+}
+
+// AccountMapping assigns a recurring spend category to its preferred account
+type AccountMapping struct {
+	Category string `json:"category"`
+	Account  string `json:"account"`
+	Note     string `json:"note,omitempty"`
+}
+
+// VendorMapping pins a known vendor to its preferred account
+type VendorMapping struct {
+	Vendor  string `json:"vendor"`
+	Account string `json:"account"`
+	Note    string `json:"note,omitempty"`
+}
+
+// LoadCompanyContext reads and validates a company-context file
+func LoadCompanyContext(path string) (*CompanyContext, error) {
+	const op = "LoadCompanyContext"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read company context file: %w", op, err)
+	}
+
+	var companyContext CompanyContext
+	if err := json.Unmarshal(data, &companyContext); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse company context file %s: %w", op, path, err)
+	}
+
+	for _, mapping := range companyContext.ExpenseCategories {
+		if !isFourDigitAccount(mapping.Account) {
+			return nil, fmt.Errorf("%s: invalid account %q for category %q (must be 4-digit SKR03 account)", op, mapping.Account, mapping.Category)
+		}
+	}
+	for _, mapping := range companyContext.VendorAccounts {
+		if !isFourDigitAccount(mapping.Account) {
+			return nil, fmt.Errorf("%s: invalid account %q for vendor %q (must be 4-digit SKR03 account)", op, mapping.Account, mapping.Vendor)
+		}
+	}
+
+	return &companyContext, nil
+}
+
+// PromptBlock renders the context as a German section for the booking system prompt
+func (c *CompanyContext) PromptBlock() string {
+	var block strings.Builder
+
+	block.WriteString("UNTERNEHMENSKONTEXT:\n")
+	if c.Industry != "" {
+		block.WriteString(fmt.Sprintf("- Branche: %s\n", c.Industry))
+	}
+	if c.Description != "" {
+		block.WriteString(fmt.Sprintf("- Beschreibung: %s\n", c.Description))
+	}
+
+	if len(c.ExpenseCategories) > 0 {
+		block.WriteString("\nTYPISCHE AUFWANDSKATEGORIEN UND BEVORZUGTE KONTEN:\n")
+		for _, mapping := range c.ExpenseCategories {
+			block.WriteString(fmt.Sprintf("- %s → %s", mapping.Category, mapping.Account))
+			if mapping.Note != "" {
+				block.WriteString(fmt.Sprintf(" (%s)", mapping.Note))
+			}
+			block.WriteString("\n")
+		}
+	}
+
+	if len(c.VendorAccounts) > 0 {
+		block.WriteString("\nBEKANNTE LIEFERANTEN (diese Konten haben Vorrang):\n")
+		for _, mapping := range c.VendorAccounts {
+			block.WriteString(fmt.Sprintf("- %s → %s", mapping.Vendor, mapping.Account))
+			if mapping.Note != "" {
+				block.WriteString(fmt.Sprintf(" (%s)", mapping.Note))
+			}
+			block.WriteString("\n")
+		}
+	}
+
+	if len(c.Notes) > 0 {
+		block.WriteString("\nHAUSINTERNE BUCHUNGSREGELN:\n")
+		for _, note := range c.Notes {
+			block.WriteString(fmt.Sprintf("- %s\n", note))
+		}
+	}
+
+	return block.String()
+}
+
+// isFourDigitAccount checks the SKR03 account number format
+func isFourDigitAccount(account string) bool {
+	if len(account) != 4 {
+		return false
+	}
+	for _, r := range account {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
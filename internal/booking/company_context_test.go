@@ -0,0 +1,63 @@
+package booking
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeContextFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "company-context.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write context file: %v", err)
+	}
+	return path
+}
+
+func TestLoadCompanyContext(t *testing.T) {
+	path := writeContextFile(t, `{
+		"industry": "Softwareentwicklung",
+		"expense_categories": [{"category": "Cloud-Hosting", "account": "4930"}],
+		"vendor_accounts": [{"vendor": "Amazon Web Services", "account": "4930", "note": "Hosting"}],
+		"notes": ["Hardware unter 800 EUR netto als GWG"]
+	}`)
+
+	companyContext, err := LoadCompanyContext(path)
+	if err != nil {
+		t.Fatalf("LoadCompanyContext: %v", err)
+	}
+
+	block := companyContext.PromptBlock()
+	for _, want := range []string{
+		"Branche: Softwareentwicklung",
+		"- Cloud-Hosting → 4930",
+		"- Amazon Web Services → 4930 (Hosting)",
+		"- Hardware unter 800 EUR netto als GWG",
+	} {
+		if !strings.Contains(block, want) {
+			t.Errorf("prompt block missing %q:\n%s", want, block)
+		}
+	}
+}
+
+func TestLoadCompanyContextRejectsInvalidAccount(t *testing.T) {
+	path := writeContextFile(t, `{"vendor_accounts": [{"vendor": "Telekom", "account": "49a0"}]}`)
+
+	if _, err := LoadCompanyContext(path); err == nil || !strings.Contains(err.Error(), "Telekom") {
+		t.Errorf("expected invalid account error naming the vendor, got %v", err)
+	}
+}
+
+func TestSystemPromptWithoutContextIsGeneric(t *testing.T) {
+	s := &SKR03BookingService{}
+	if got := s.getSystemPrompt(); got != genericSystemPrompt {
+		t.Errorf("expected generic system prompt without company context")
+	}
+
+	s.companyContext = &CompanyContext{Industry: "Bäckerei"}
+	if got := s.getSystemPrompt(); !strings.HasPrefix(got, genericSystemPrompt) || !strings.Contains(got, "Branche: Bäckerei") {
+		t.Errorf("expected company context appended to system prompt, got:\n%s", got)
+	}
+}
@@ -0,0 +1,190 @@
+package booking
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tools/pkg/models"
+)
+
+// FieldOverride replaces one field of the extracted invoice before booking (datev --set)
+type FieldOverride struct {
+	Field string // Field name as given, e.g. "vendor" or "gross"
+	Value string // Raw value as given
+	apply func(*models.Invoice)
+}
+
+// overrideFields maps each field accepted by ParseFieldOverride to a parser that validates the
+// value and returns the setter
+var overrideFields = map[string]func(string) (func(*models.Invoice), error){
+	"invoice-number":       stringOverride(func(inv *models.Invoice, v string) { inv.InvoiceNumber = v }),
+	"vendor":               stringOverride(func(inv *models.Invoice, v string) { inv.Vendor = v }),
+	"customer":             stringOverride(func(inv *models.Invoice, v string) { inv.Customer = v }),
+	"vendor-vat-id":        stringOverride(func(inv *models.Invoice, v string) { inv.VendorVATID = v }),
+	"customer-vat-id":      stringOverride(func(inv *models.Invoice, v string) { inv.CustomerVATID = v }),
+	"purchase-order":       stringOverride(func(inv *models.Invoice, v string) { inv.PurchaseOrder = v }),
+	"customer-reference":   stringOverride(func(inv *models.Invoice, v string) { inv.CustomerReference = v }),
+	"description":          stringOverride(func(inv *models.Invoice, v string) { inv.Description = v }),
+	"prepayment-reference": stringOverride(func(inv *models.Invoice, v string) { inv.PrepaymentReference = v }),
+	"reminder-reference":   stringOverride(func(inv *models.Invoice, v string) { inv.ReminderReference = v }),
+	"sub-type": func(value string) (func(*models.Invoice), error) {
+		var subType string
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "prepayment", "anzahlung":
+			subType = models.InvoiceSubTypePrepayment
+		case "final", "schlussrechnung":
+			subType = models.InvoiceSubTypeFinal
+		case "reminder", "mahnung":
+			subType = models.InvoiceSubTypeReminder
+		case "regular", "none":
+		default:
+			return nil, fmt.Errorf("invalid sub-type %q (use prepayment, final, reminder or regular)", value)
+		}
+		return func(inv *models.Invoice) { inv.SubType = subType }, nil
+	},
+	"currency": func(value string) (func(*models.Invoice), error) {
+		currency := strings.ToUpper(strings.TrimSpace(value))
+		if len(currency) != 3 {
+			return nil, fmt.Errorf("invalid currency %q (expected ISO code, e.g. EUR)", value)
+		}
+		return func(inv *models.Invoice) { inv.Currency = currency }, nil
+	},
+	"issue-date":   dateOverride(func(inv *models.Invoice, d time.Time) { inv.IssueDate = d }),
+	"due-date":     dateOverride(func(inv *models.Invoice, d time.Time) { inv.DueDate = d }),
+	"service-date": dateOverride(func(inv *models.Invoice, d time.Time) { inv.ServiceDate = d }),
+	"net":          amountOverride(func(inv *models.Invoice, cents int64) { inv.NetAmount = cents }),
+	"vat":          amountOverride(func(inv *models.Invoice, cents int64) { inv.VATAmount = cents }),
+	"gross":        amountOverride(func(inv *models.Invoice, cents int64) { inv.GrossAmount = cents }),
+	"dunning-fee":  amountOverride(func(inv *models.Invoice, cents int64) { inv.DunningFee = cents }),
+}
+
+// OverrideFieldNames lists the fields accepted by ParseFieldOverride
+func OverrideFieldNames() []string {
+	var names []string
+	for name := range overrideFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFieldOverride parses and validates a "field=value" override. Amounts are given in cents
+// ("11900") or in EUR with two decimals ("119.00" or "119,00"), dates as YYYY-MM-DD.
+func ParseFieldOverride(spec string) (FieldOverride, error) {
+	field, value, ok := strings.Cut(spec, "=")
+	if !ok {
+		return FieldOverride{}, fmt.Errorf("invalid override %q (expected field=value)", spec)
+	}
+	field = strings.ToLower(strings.TrimSpace(field))
+
+	parse, known := overrideFields[field]
+	if !known {
+		return FieldOverride{}, fmt.Errorf("unknown override field %q (supported: %s)", field, strings.Join(OverrideFieldNames(), ", "))
+	}
+	apply, err := parse(value)
+	if err != nil {
+		return FieldOverride{}, fmt.Errorf("override %s: %w", field, err)
+	}
+
+	return FieldOverride{Field: field, Value: value, apply: apply}, nil
+}
+
+// Apply sets the overridden field on invoice
+func (o FieldOverride) Apply(invoice *models.Invoice) {
+	o.apply(invoice)
+}
+
+// ParseFieldOverrides parses several overrides; a field given twice is rejected
+func ParseFieldOverrides(specs []string) ([]FieldOverride, error) {
+	var overrides []FieldOverride
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		override, err := ParseFieldOverride(spec)
+		if err != nil {
+			return nil, err
+		}
+		if seen[override.Field] {
+			return nil, fmt.Errorf("override field %q given more than once", override.Field)
+		}
+		seen[override.Field] = true
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+// applyFieldOverrides applies the configured overrides to the invoice and returns a warning if
+// overridden amounts no longer add up (net + VAT != gross)
+func (s *SKR03BookingService) applyFieldOverrides(invoice *models.Invoice) []string {
+	amountOverridden := false
+	for _, override := range s.fieldOverrides {
+		override.Apply(invoice)
+		s.log.Info().
+			Str("field", override.Field).
+			Str("value", override.Value).
+			Msg("Invoice field overridden by user")
+		switch override.Field {
+		case "net", "vat", "gross":
+			amountOverridden = true
+		}
+	}
+
+	if amountOverridden && invoice.NetAmount+invoice.VATAmount != invoice.GrossAmount {
+		return []string{fmt.Sprintf("Überschriebene Beträge passen nicht zusammen: Netto %.2f + MwSt %.2f ≠ Brutto %.2f EUR",
+			float64(invoice.NetAmount)/100, float64(invoice.VATAmount)/100, float64(invoice.GrossAmount)/100)}
+	}
+	return nil
+}
+
+// stringOverride accepts any non-empty value
+func stringOverride(set func(*models.Invoice, string)) func(string) (func(*models.Invoice), error) {
+	return func(value string) (func(*models.Invoice), error) {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, fmt.Errorf("value must not be empty")
+		}
+		return func(inv *models.Invoice) { set(inv, value) }, nil
+	}
+}
+
+// dateOverride accepts dates in YYYY-MM-DD format
+func dateOverride(set func(*models.Invoice, time.Time)) func(string) (func(*models.Invoice), error) {
+	return func(value string) (func(*models.Invoice), error) {
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", value)
+		}
+		return func(inv *models.Invoice) { set(inv, date) }, nil
+	}
+}
+
+// amountOverride accepts amounts in cents or in EUR with two decimals
+func amountOverride(set func(*models.Invoice, int64)) func(string) (func(*models.Invoice), error) {
+	return func(value string) (func(*models.Invoice), error) {
+		cents, err := parseOverrideAmount(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(inv *models.Invoice) { set(inv, cents) }, nil
+	}
+}
+
+// parseOverrideAmount parses "11900" as cents and "119.00" or "119,00" as EUR
+func parseOverrideAmount(value string) (int64, error) {
+	trimmed := strings.TrimSpace(value)
+	digits := trimmed
+	if i := strings.LastIndexAny(trimmed, ".,"); i >= 0 {
+		if len(trimmed)-i-1 != 2 {
+			return 0, fmt.Errorf("invalid amount %q (use cents, e.g. 11900, or EUR with two decimals, e.g. 119,00)", value)
+		}
+		digits = trimmed[:i] + trimmed[i+1:]
+	}
+
+	cents, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q (use cents, e.g. 11900, or EUR with two decimals, e.g. 119,00)", value)
+	}
+	return cents, nil
+}
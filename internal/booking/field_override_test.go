@@ -0,0 +1,72 @@
+package booking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"tools/pkg/models"
+)
+
+func TestParseOverrideAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"11900", 11900},
+		{"119.00", 11900},
+		{"119,00", 11900},
+		{" -5,50 ", -550},
+	}
+	for _, tt := range tests {
+		got, err := parseOverrideAmount(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseOverrideAmount(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "119,5", "1.234,56", "abc"} {
+		if _, err := parseOverrideAmount(value); err == nil {
+			t.Errorf("parseOverrideAmount(%q): expected error", value)
+		}
+	}
+}
+
+func TestParseFieldOverrides(t *testing.T) {
+	invalid := [][]string{
+		{"vendor"},
+		{"color=red"},
+		{"vendor="},
+		{"issue-date=01.06.2024"},
+		{"gross=119.5"},
+		{"currency=EURO"},
+		{"vendor=A", "Vendor=B"},
+	}
+	for _, specs := range invalid {
+		if _, err := ParseFieldOverrides(specs); err == nil {
+			t.Errorf("ParseFieldOverrides(%q): expected error", specs)
+		}
+	}
+}
+
+func TestApplyFieldOverrides(t *testing.T) {
+	overrides, err := ParseFieldOverrides([]string{"vendor=ACME GmbH", "gross=11900", "issue-date=2024-06-01", "currency=eur"})
+	if err != nil {
+		t.Fatalf("ParseFieldOverrides: %v", err)
+	}
+
+	s := &SKR03BookingService{fieldOverrides: overrides, log: zerolog.Nop()}
+	invoice := &models.Invoice{Vendor: "ACNE", NetAmount: 10000, VATAmount: 1900, GrossAmount: 1190}
+	if warnings := s.applyFieldOverrides(invoice); len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	if invoice.Vendor != "ACME GmbH" || invoice.GrossAmount != 11900 || invoice.Currency != "EUR" ||
+		!invoice.IssueDate.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("overrides not applied: %+v", invoice)
+	}
+
+	s.fieldOverrides, _ = ParseFieldOverrides([]string{"net=9000"})
+	if warnings := s.applyFieldOverrides(invoice); len(warnings) != 1 {
+		t.Errorf("expected a warning for inconsistent amounts, got %v", warnings)
+	}
+}
@@ -0,0 +1,22 @@
+package booking
+
+import (
+	"strings"
+
+	"tools/internal/invoice"
+	"tools/pkg/models"
+)
+
+// requireInvoiceNumber returns invoice.ErrMissingInvoiceNumber in strict mode if the invoice has no
+// invoice number after completion and field overrides, so that it is entered manually instead of
+// being booked under a generated ID
+func (s *SKR03BookingService) requireInvoiceNumber(inv *models.Invoice) error {
+	if !s.strict || strings.TrimSpace(inv.InvoiceNumber) != "" {
+		return nil
+	}
+	s.log.Warn().
+		Str("generated_id", inv.ID).
+		Str("vendor", inv.Vendor).
+		Msg("No invoice number found in strict mode")
+	return invoice.ErrMissingInvoiceNumber
+}
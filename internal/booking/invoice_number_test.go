@@ -0,0 +1,33 @@
+package booking
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"tools/internal/invoice"
+	"tools/pkg/models"
+)
+
+func TestRequireInvoiceNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		strict  bool
+		number  string
+		wantErr bool
+	}{
+		{"strict with number", true, "RE-2024-001", false},
+		{"strict without number", true, "", true},
+		{"strict with blank number", true, "  ", true},
+		{"lenient without number", false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SKR03BookingService{strict: tt.strict, log: zerolog.Nop()}
+			err := s.requireInvoiceNumber(&models.Invoice{ID: "ACME-0123456789AB", InvoiceNumber: tt.number})
+			if got := errors.Is(err, invoice.ErrMissingInvoiceNumber); got != tt.wantErr {
+				t.Errorf("requireInvoiceNumber() = %v, want ErrMissingInvoiceNumber: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package booking
+
+import (
+	"fmt"
+
+	"tools/pkg/models"
+)
+
+// PaymentTypeSource confirms invoice types from the bank transactions matched to invoices, such as the
+// matches of a saved reconciliation run (services.PaymentTypes in internal/reconciliation/services)
+type PaymentTypeSource interface {
+	// PaymentType returns PAYABLE or RECEIVABLE as shown by the direction of the payment matched to the
+	// invoice with the given number and gross amount in cents, and a description of that payment
+	PaymentType(invoiceNumber string, grossAmount int64) (invoiceType, evidence string, ok bool)
+}
+
+// applyPaymentType sets the invoice type from the direction of its matched payment, which is harder
+// evidence than the classification of completion. A type set with --type is kept, but a payment
+// contradicting it is reported. The confidence of a type confirmed by a payment is raised to 1 so it no
+// longer needs confirmation. Returns a warning if the type was corrected or contradicts the payment.
+func (s *SKR03BookingService) applyPaymentType(invoice *models.Invoice, typeOverride string, confidence map[string]float32) string {
+	if s.paymentTypes == nil || invoice.Internal || invoice.InvoiceNumber == "" {
+		return ""
+	}
+
+	paymentType, evidence, ok := s.paymentTypes.PaymentType(invoice.InvoiceNumber, invoice.GrossAmount)
+	if !ok {
+		return ""
+	}
+
+	if typeOverride != "" {
+		if paymentType == typeOverride {
+			return ""
+		}
+		s.log.Warn().
+			Str("override_type", typeOverride).
+			Str("payment_type", paymentType).
+			Msg("Invoice type set by user contradicts the matched payment")
+		return fmt.Sprintf("Rechnungstyp per --type auf %s gesetzt, die zugeordnete %s spricht für %s", typeOverride, evidence, paymentType)
+	}
+
+	if confidence != nil {
+		confidence["type"] = 1
+	}
+	if invoice.Type == paymentType {
+		return ""
+	}
+
+	detectedType := invoice.Type
+	if detectedType == "" {
+		detectedType = "unbekannt"
+	}
+	invoice.Type = paymentType
+	invoice.TypeReasoning = "Zugeordnete " + evidence
+	s.log.Info().
+		Str("detected_type", detectedType).
+		Str("payment_type", paymentType).
+		Msg("Invoice type corrected from the matched payment")
+	return fmt.Sprintf("Rechnungstyp von %s auf %s korrigiert: zugeordnete %s", detectedType, paymentType, evidence)
+}
@@ -0,0 +1,52 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"tools/pkg/models"
+)
+
+// fixedPaymentType confirms one type for every invoice
+type fixedPaymentType string
+
+func (f fixedPaymentType) PaymentType(invoiceNumber string, grossAmount int64) (string, string, bool) {
+	return string(f), "ausgehende Zahlung 119.00 EUR vom 05.06.2024 (Muster GmbH)", f != ""
+}
+
+func TestApplyPaymentType(t *testing.T) {
+	tests := []struct {
+		name         string
+		detected     string
+		override     string
+		payment      fixedPaymentType
+		wantType     string
+		wantContains string
+	}{
+		{"no matched payment", "RECEIVABLE", "", "", "RECEIVABLE", ""},
+		{"payment confirms detection", "PAYABLE", "", "PAYABLE", "PAYABLE", ""},
+		{"payment corrects detection", "RECEIVABLE", "", "PAYABLE", "PAYABLE", "von RECEIVABLE auf PAYABLE korrigiert"},
+		{"payment confirms override", "PAYABLE", "PAYABLE", "PAYABLE", "PAYABLE", ""},
+		{"payment contradicts override", "RECEIVABLE", "RECEIVABLE", "PAYABLE", "RECEIVABLE", "spricht für PAYABLE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SKR03BookingService{paymentTypes: tt.payment, log: zerolog.Nop()}
+			invoice := &models.Invoice{InvoiceNumber: "R-1", GrossAmount: 11900, Type: tt.detected}
+			confidence := map[string]float32{"type": 0.4}
+
+			warning := s.applyPaymentType(invoice, tt.override, confidence)
+			if invoice.Type != tt.wantType {
+				t.Errorf("type = %s, want %s", invoice.Type, tt.wantType)
+			}
+			if (warning != "") != (tt.wantContains != "") || !strings.Contains(warning, tt.wantContains) {
+				t.Errorf("warning = %q, want it to contain %q", warning, tt.wantContains)
+			}
+			if tt.override == "" && tt.payment != "" && confidence["type"] != 1 {
+				t.Errorf("type confidence = %.2f, want 1 after a matched payment", confidence["type"])
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package booking
+
+import (
+	"fmt"
+	"strings"
+
+	"tools/pkg/models"
+)
+
+// SKR03 prepayment account ranges: Geleistete Anzahlungen (1510-1518, payables) and Erhaltene
+// Anzahlungen (1710-1722, receivables)
+const (
+	madePrepaymentFirst     = "1510"
+	madePrepaymentLast      = "1518"
+	receivedPrepaymentFirst = "1710"
+	receivedPrepaymentLast  = "1722"
+)
+
+// prepaymentPromptBlock returns the booking prompt instructions for prepayment and final invoices,
+// empty for regular invoices
+func prepaymentPromptBlock(invoice *models.Invoice) string {
+	var block strings.Builder
+
+	switch invoice.SubType {
+	case models.InvoiceSubTypePrepayment:
+		block.WriteString("Dies ist eine ANZAHLUNGSRECHNUNG (Anzahlung/Abschlag vor Erbringung der Leistung).\n")
+		if invoice.Type == "RECEIVABLE" {
+			block.WriteString("Buche NICHT auf ein Erlöskonto, sondern auf Erhaltene Anzahlungen: 1718 (19% USt) bzw. 1711 (7% USt) im Haben, Forderungen im Soll.\n")
+		} else {
+			block.WriteString("Buche NICHT auf ein Aufwandskonto, sondern auf Geleistete Anzahlungen: 1518 (19% Vorsteuer) bzw. 1511 (7% Vorsteuer) im Soll, Verbindlichkeiten im Haben.\n")
+		}
+		block.WriteString("Beginne den Buchungstext mit \"Anzahlung\".\n")
+	case models.InvoiceSubTypeFinal:
+		block.WriteString("Dies ist eine SCHLUSSRECHNUNG, die bereits gebuchte Anzahlungen verrechnet.\n")
+		if invoice.Type == "RECEIVABLE" {
+			block.WriteString("Buche die Leistung auf das übliche Erlöskonto. Die Auflösung der Anzahlung über 1718/1711 beschreibst du in der Erläuterung.\n")
+		} else {
+			block.WriteString("Buche die Leistung auf das übliche Aufwands- oder Anlagekonto. Die Auflösung der Anzahlung über 1518/1511 beschreibst du in der Erläuterung.\n")
+		}
+		if invoice.PrepaymentReference != "" {
+			block.WriteString(fmt.Sprintf("Verrechnete Anzahlungsrechnung: %s – nenne sie in der Erläuterung.\n", invoice.PrepaymentReference))
+		}
+	}
+
+	return block.String()
+}
+
+// checkPrepaymentAccounts returns a warning if a prepayment invoice was not booked to a prepayment
+// account, or a reminder to clear the prepayment for a final invoice; empty otherwise
+func checkPrepaymentAccounts(debitAccount, creditAccount string, invoice *models.Invoice) string {
+	switch invoice.SubType {
+	case models.InvoiceSubTypePrepayment:
+		if invoice.Type == "RECEIVABLE" && !inAccountRange(creditAccount, receivedPrepaymentFirst, receivedPrepaymentLast) {
+			return fmt.Sprintf("Anzahlungsrechnung, aber Habenkonto %s ist kein Konto für erhaltene Anzahlungen (%s-%s)", creditAccount, receivedPrepaymentFirst, receivedPrepaymentLast)
+		}
+		if invoice.Type == "PAYABLE" && !inAccountRange(debitAccount, madePrepaymentFirst, madePrepaymentLast) {
+			return fmt.Sprintf("Anzahlungsrechnung, aber Sollkonto %s ist kein Konto für geleistete Anzahlungen (%s-%s)", debitAccount, madePrepaymentFirst, madePrepaymentLast)
+		}
+	case models.InvoiceSubTypeFinal:
+		reference := "der zugehörigen Anzahlungsrechnung"
+		if invoice.PrepaymentReference != "" {
+			reference = "aus Rechnung " + invoice.PrepaymentReference
+		}
+		account := "1518/1511"
+		if invoice.Type == "RECEIVABLE" {
+			account = "1718/1711"
+		}
+		return fmt.Sprintf("Schlussrechnung: Anzahlung %s über %s auflösen", reference, account)
+	}
+	return ""
+}
+
+// inAccountRange reports whether a 4-digit account lies between first and last inclusive
+func inAccountRange(account, first, last string) bool {
+	return len(account) == 4 && account >= first && account <= last
+}
@@ -0,0 +1,53 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+
+	"tools/pkg/models"
+)
+
+func TestCheckPrepaymentAccounts(t *testing.T) {
+	tests := []struct {
+		name        string
+		invoice     models.Invoice
+		debit       string
+		credit      string
+		wantWarning string
+	}{
+		{"regular invoice", models.Invoice{Type: "PAYABLE"}, "4930", "1600", ""},
+		{"made prepayment", models.Invoice{Type: "PAYABLE", SubType: models.InvoiceSubTypePrepayment}, "1518", "1600", ""},
+		{"made prepayment as expense", models.Invoice{Type: "PAYABLE", SubType: models.InvoiceSubTypePrepayment}, "4930", "1600", "Sollkonto 4930"},
+		{"received prepayment", models.Invoice{Type: "RECEIVABLE", SubType: models.InvoiceSubTypePrepayment}, "1400", "1718", ""},
+		{"received prepayment as revenue", models.Invoice{Type: "RECEIVABLE", SubType: models.InvoiceSubTypePrepayment}, "1400", "8400", "Habenkonto 8400"},
+		{"final invoice", models.Invoice{Type: "RECEIVABLE", SubType: models.InvoiceSubTypeFinal, PrepaymentReference: "AR-1"}, "1400", "8400", "aus Rechnung AR-1 über 1718/1711"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warning := checkPrepaymentAccounts(tt.debit, tt.credit, &tt.invoice)
+			if tt.wantWarning == "" && warning != "" {
+				t.Errorf("unexpected warning %q", warning)
+			}
+			if !strings.Contains(warning, tt.wantWarning) {
+				t.Errorf("warning = %q, want it to contain %q", warning, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestPrepaymentPromptBlock(t *testing.T) {
+	if block := prepaymentPromptBlock(&models.Invoice{Type: "PAYABLE"}); block != "" {
+		t.Errorf("expected no instructions for a regular invoice, got %q", block)
+	}
+
+	block := prepaymentPromptBlock(&models.Invoice{Type: "PAYABLE", SubType: models.InvoiceSubTypePrepayment})
+	if !strings.Contains(block, "ANZAHLUNGSRECHNUNG") || !strings.Contains(block, "1518") {
+		t.Errorf("prepayment instructions missing account 1518: %q", block)
+	}
+
+	block = prepaymentPromptBlock(&models.Invoice{Type: "RECEIVABLE", SubType: models.InvoiceSubTypeFinal, PrepaymentReference: "AR-7"})
+	if !strings.Contains(block, "SCHLUSSRECHNUNG") || !strings.Contains(block, "AR-7") {
+		t.Errorf("final invoice instructions missing reference: %q", block)
+	}
+}
@@ -0,0 +1,79 @@
+package booking
+
+import (
+	"fmt"
+	"strings"
+
+	"tools/internal/invoice"
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// SKR03 accounts for dunning fees. Mahngebühren are damages rather than a payment for a supply, so
+// they are booked without VAT: paid fees as Nebenkosten des Geldverkehrs, charged fees as Sonstige
+// Erträge.
+const (
+	dunningFeeExpenseAccount = "4970"
+	dunningFeeIncomeAccount  = "2700"
+	payablesAccount          = "1600"
+	receivablesAccount       = "1400"
+)
+
+// reminderBooking books a payment reminder (Mahnung). The reminded invoice is already booked, so
+// only the dunning fee is; a reminder without a fee returns an *invoice.ReminderError.
+func (s *SKR03BookingService) reminderBooking(inv *models.Invoice) (*services.DATEVBooking, error) {
+	reminded := "einer bereits erfassten Rechnung"
+	if inv.ReminderReference != "" {
+		reminded = "Rechnung " + inv.ReminderReference
+	}
+
+	if inv.DunningFee <= 0 {
+		s.log.Info().
+			Str("reminder_reference", inv.ReminderReference).
+			Msg("Document is a payment reminder without dunning fee, skipping booking")
+		return nil, &invoice.ReminderError{Reference: inv.ReminderReference}
+	}
+
+	response := &ChatGPTBookingResponse{
+		DebitAccount:      dunningFeeExpenseAccount,
+		DebitAccountName:  "Nebenkosten des Geldverkehrs",
+		CreditAccount:     payablesAccount,
+		CreditAccountName: "Verbindlichkeiten aus Lieferungen und Leistungen",
+		TaxKeyDescription: "ohne Steuer (nicht steuerbarer Schadensersatz)",
+		Explanation:       fmt.Sprintf("Mahnung zu %s. Die Rechnung selbst ist bereits gebucht, gebucht wird nur die Mahngebühr ohne Umsatzsteuer.", reminded),
+	}
+	if inv.Type == "RECEIVABLE" {
+		response.DebitAccount = receivablesAccount
+		response.DebitAccountName = "Forderungen aus Lieferungen und Leistungen"
+		response.CreditAccount = dunningFeeIncomeAccount
+		response.CreditAccountName = "Sonstige Erträge"
+	}
+
+	booking := s.convertToDatevBooking(response, inv)
+	booking.Amount = float64(inv.DunningFee) / 100
+	booking.BookingText = truncateRunes(reminderBookingText(inv), maxBookingTextLength)
+	booking.Warnings = append(booking.Warnings, fmt.Sprintf("Mahnung zu %s: nur die Mahngebühr gebucht", reminded))
+
+	s.log.Info().
+		Str("reminder_reference", inv.ReminderReference).
+		Float64("dunning_fee", booking.Amount).
+		Msg("Document is a payment reminder, booking the dunning fee only")
+
+	return booking, nil
+}
+
+// reminderBookingText builds the Buchungstext of a dunning fee from counterparty and reminded invoice
+func reminderBookingText(inv *models.Invoice) string {
+	counterparty := inv.Vendor
+	if inv.Type == "RECEIVABLE" {
+		counterparty = inv.Customer
+	}
+
+	parts := []string{"Mahngebühr"}
+	for _, part := range []string{counterparty, inv.ReminderReference} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,60 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"tools/internal/invoice"
+	"tools/pkg/models"
+)
+
+func TestReminderBooking(t *testing.T) {
+	s := &SKR03BookingService{openaiClient: unavailableClient{}, allowNoBooking: true}
+
+	reminder := &models.Invoice{
+		InvoiceNumber:     "RE-2024-0815",
+		Type:              "PAYABLE",
+		SubType:           models.InvoiceSubTypeReminder,
+		Vendor:            "Muster GmbH",
+		GrossAmount:       12400,
+		ReminderReference: "RE-2024-0815",
+		DunningFee:        500,
+	}
+	booking, err := s.GenerateBooking(context.Background(), reminder)
+	if err != nil {
+		t.Fatalf("GenerateBooking: %v", err)
+	}
+	if booking.DebitAccount != "4970" || booking.CreditAccount != "1600" || booking.TaxKey != "" || booking.Amount != 5 {
+		t.Errorf("expected dunning fee of 5 EUR on 4970/1600 without tax key, got %+v", booking)
+	}
+	if booking.BookingText != "Mahngebühr Muster GmbH RE-2024-0815" {
+		t.Errorf("BookingText = %q", booking.BookingText)
+	}
+	if len(booking.Warnings) != 1 || !strings.Contains(booking.Warnings[0], "nur die Mahngebühr") {
+		t.Errorf("Warnings = %v, want the fee-only notice", booking.Warnings)
+	}
+
+	receivable := *reminder
+	receivable.Type = "RECEIVABLE"
+	booking, err = s.GenerateBooking(context.Background(), &receivable)
+	if err != nil {
+		t.Fatalf("GenerateBooking: %v", err)
+	}
+	if booking.DebitAccount != "1400" || booking.CreditAccount != "2700" {
+		t.Errorf("expected charged fee on 1400/2700, got %s/%s", booking.DebitAccount, booking.CreditAccount)
+	}
+
+	// Without a fee nothing is booked, not even a template
+	withoutFee := *reminder
+	withoutFee.DunningFee = 0
+	_, err = s.generateBookingOrTemplate(context.Background(), &withoutFee)
+	if !errors.Is(err, invoice.ErrReminder) {
+		t.Fatalf("err = %v, want ErrReminder", err)
+	}
+	var reminderErr *invoice.ReminderError
+	if !errors.As(err, &reminderErr) || reminderErr.Reference != "RE-2024-0815" {
+		t.Errorf("err = %v, want a ReminderError for RE-2024-0815", err)
+	}
+}
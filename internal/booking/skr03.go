@@ -4,26 +4,70 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/rs/zerolog"
 	"github.com/sashabaranov/go-openai"
+	"tools/internal/cache"
 	"tools/internal/invoice"
+	"tools/internal/llm"
 	"tools/internal/logger"
+	"tools/internal/ocr"
+	"tools/internal/vendors"
 	"tools/pkg/models"
 	"tools/pkg/services"
 )
 
+// Booking date policies (BOOKING_DATE_POLICY)
+const (
+	// BookingDateIssue books on the invoice issue date (default)
+	BookingDateIssue = "issue_date"
+
+	// BookingDateService books on the Leistungsdatum, falling back to the issue date if none was extracted
+	BookingDateService = "service_date"
+)
+
+// maxBookingTextLength is DATEV's limit for the Buchungstext in characters
+const maxBookingTextLength = 60
+
+// DefaultBookingModel is the chat model used for account selection unless BookingOptions.Model is set
+const DefaultBookingModel = "gpt-4"
+
 // SKR03BookingService implements BookingService using SKR03 and ChatGPT
 type SKR03BookingService struct {
-	openaiClient      *openai.Client
+	openaiClient      llm.LLMClient
 	invoiceCompletion invoice.InvoiceCompletionService
+	bookingDatePolicy string
+	companyContext    *CompanyContext    // Optional; nil keeps the generic system prompt
+	typeConfidenceMin float32            // Type confidence below which the detected type needs confirmation
+	documentAITimeout time.Duration      // Per-request Document AI timeout; zero uses the processor default
+	model             string             // Chat model for account selection
+	extractionCache   *cache.Store       // Optional; nil extracts every PDF with Document AI
+	forceExtraction   bool               // Ignore cached extractions but refresh them
+	vendorMaster      *vendors.Store     // Optional; nil keeps counterparty names as extracted
+	allowNoBooking    bool               // Return a template booking when ChatGPT fails
+	fieldOverrides    []FieldOverride    // Applied to the completed invoice before booking
+	paymentTypes      PaymentTypeSource  // Optional; nil keeps the invoice type of completion
+	taxKeys           []TaxKeyDefinition // Tax keys ChatGPT may use; nil allows the defaults of the chart
+	explicitVAT       bool               // Add explicit VAT posting lines to every booking
+	strict            bool               // Fail on invoices without an invoice number
+	amountBasis       string             // services.AmountBasisGross or AmountBasisNet
 	log               zerolog.Logger
+
+	processorMu    sync.Mutex
+	processor      invoice.InvoiceProcessor // Shared by all PDFs; created on first use unless injected
+	documentAIMode invoice.ProcessingMode   // Processing mode of the processor created on first use
+	ownedProcessor io.Closer                // Document AI client created by the service, closed by Close
+	ownedOCR       bool                     // The completion OCR client was created by the service
 }
 
 // ChatGPTBookingResponse represents the structured response from ChatGPT for booking generation
@@ -40,31 +84,193 @@ type ChatGPTBookingResponse struct {
 	ReasoningDebit      string `json:"begruendung_sollkonto"`
 	ReasoningCredit     string `json:"begruendung_habenkonto"`
 	ReasoningTax        string `json:"begruendung_steuer"`
+
+	// Warnings collects consistency problems found while validating the response
+	Warnings []string `json:"-"`
 }
 
 // NewSKR03BookingService creates a new SKR03 booking service with dependencies from environment
 func NewSKR03BookingService(ctx context.Context) (services.BookingService, error) {
-	const op = "NewSKR03BookingService"
+	return NewSKR03BookingServiceWithTimeout(ctx, 0)
+}
+
+// NewSKR03BookingServiceWithTimeout creates a booking service like NewSKR03BookingService whose Document AI
+// requests use the given timeout instead of the processor default
+func NewSKR03BookingServiceWithTimeout(ctx context.Context, documentAITimeout time.Duration) (services.BookingService, error) {
+	return NewSKR03BookingServiceWithOptions(ctx, BookingOptions{DocumentAITimeout: documentAITimeout})
+}
+
+// BookingOptions tunes a booking service beyond its environment configuration
+type BookingOptions struct {
+	DocumentAITimeout time.Duration   // Per-request Document AI timeout; zero uses the processor default
+	Model             string          // Chat model for account selection; empty uses DefaultBookingModel
+	CompletionModel   string          // Chat model for completion, which also determines the invoice type; empty keeps OPENAI_MODEL
+	InferVAT          bool            // Split gross-only invoices into net and VAT (also enabled by INFER_VAT)
+	AssumedVATRate    float64         // VAT rate in percent for InferVAT; zero keeps ASSUMED_VAT_RATE or 19
+	Cache             *cache.Store    // Cache for Document AI extractions; nil disables caching
+	ForceExtraction   bool            // Re-extract PDFs even if cached, overwriting the cache entry
+	Deskew            bool            // Correct rotated or skewed scans in the completion OCR (also enabled by OCR_DESKEW)
+	AllowNoBooking    bool            // Return a template booking (Template set, accounts blank) when ChatGPT fails
+	FieldOverrides    []FieldOverride // Invoice fields replaced after extraction, before booking (datev --set)
+	SummaryLanguage   string          // Language of the accounting summary ("de" or "en"); empty keeps OUTPUT_LANGUAGE or German
+	NoSummary         bool            // Skip the accounting summary (also disabled by ACCOUNTING_SUMMARY=false)
+	IncludeRawText    bool            // Keep the completion OCR text in the returned invoice's OCRText
+	ExplicitVAT       bool            // Add the VAT as posting lines of its own (Postings; also enabled by EXPLICIT_VAT_POSTINGS)
+	Strict            bool            // Fail with invoice.ErrMissingInvoiceNumber on invoices without an invoice number after FieldOverrides
+	AmountBasis       string          // services.AmountBasisGross or AmountBasisNet for DATEVBooking.Amount; empty keeps BOOKING_AMOUNT_BASIS or gross
+	BookingDatePolicy string          // BookingDateIssue or BookingDateService, e.g. from config.BookingDatePolicyFromEnv; empty is BookingDateIssue
+
+	// DocumentAIMode selects sync, async or auto Document AI processing of the PDFs; empty is auto.
+	// DOCUMENT_AI_ASYNC=true turns auto into async.
+	DocumentAIMode invoice.ProcessingMode
+
+	// PaymentTypes confirms or corrects the invoice type from the direction of the bank transaction
+	// matched to the invoice; nil keeps the type determined by completion
+	PaymentTypes PaymentTypeSource
+
+	// Processor is the Document AI processor shared by all PDFs; nil creates one on first use. An
+	// injected processor is not closed by the service.
+	Processor invoice.InvoiceProcessor
+	// OCRService is the Vision OCR service of completion shared by all PDFs; nil creates one. An injected
+	// service is not closed by the service, and Deskew does not apply to it.
+	OCRService ocr.OCRService
+	// LLMClient is the chat client for completion and account selection; nil creates one from the environment
+	LLMClient llm.LLMClient
+}
 
-	// Get OpenAI API key
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("%s: OPENAI_API_KEY environment variable is required", op)
+// NewSKR03BookingServiceWithOptions creates a booking service like NewSKR03BookingService with explicit options
+func NewSKR03BookingServiceWithOptions(ctx context.Context, options BookingOptions) (services.BookingService, error) {
+	const op = "NewSKR03BookingServiceWithOptions"
+
+	// Create LLM client for the configured provider
+	openaiClient := options.LLMClient
+	if openaiClient == nil {
+		var err error
+		openaiClient, err = llm.NewClientFromEnv()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
 	}
 
-	// Create OpenAI client
-	openaiClient := openai.NewClient(apiKey)
+	// Optional vendor master canonicalizing counterparty names and providing default VAT rates
+	var vendorMaster *vendors.Store
+	if path := os.Getenv("VENDOR_MASTER_FILE"); path != "" {
+		var err error
+		vendorMaster, err = vendors.LoadStore(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
 
 	// Create invoice completion service for PDF processing
-	invoiceCompletion, err := invoice.NewInvoiceCompletionService(ctx)
+	completionConfig := invoice.CompletionConfigFromEnv()
+	if vendorMaster != nil {
+		completionConfig.VendorVATRate = vendorVATRate(vendorMaster)
+	}
+	if options.InferVAT {
+		completionConfig.InferVAT = true
+	}
+	if options.AssumedVATRate > 0 {
+		completionConfig.AssumedVATRate = options.AssumedVATRate
+	}
+	if options.Deskew {
+		completionConfig.Deskew = true
+	}
+	if options.SummaryLanguage != "" {
+		completionConfig.SummaryLanguage = options.SummaryLanguage
+	}
+	if options.NoSummary {
+		completionConfig.NoSummary = true
+	}
+	if options.IncludeRawText {
+		completionConfig.IncludeRawText = true
+	}
+	if options.CompletionModel != "" {
+		completionConfig.OpenAIModel = options.CompletionModel
+	}
+	ocrService := options.OCRService
+	if ocrService == nil {
+		var err error
+		ocrService, err = ocr.NewGoogleVisionOCRServiceWithOptions(ctx, ocr.VisionOptions{Deskew: completionConfig.Deskew})
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to create invoice completion service: %w", op, err)
+		}
+		ocrService = ocr.NewLimitedOCRService(ocrService, ocr.MaxConcurrentFromEnv())
+	}
+	invoiceCompletion := invoice.NewInvoiceCompletionServiceWithDeps(ocrService, openaiClient, completionConfig)
+
+	// Determine which invoice date drives the booking date and accounting period
+	bookingDatePolicy := options.BookingDatePolicy
+	switch bookingDatePolicy {
+	case "":
+		bookingDatePolicy = BookingDateIssue
+	case BookingDateIssue, BookingDateService:
+	default:
+		return nil, fmt.Errorf("%s: invalid BOOKING_DATE_POLICY %q (must be %q or %q)", op, bookingDatePolicy, BookingDateIssue, BookingDateService)
+	}
+
+	// Decide whether bookings carry the gross or the net amount
+	amountBasis := strings.ToLower(strings.TrimSpace(options.AmountBasis))
+	if amountBasis == "" {
+		amountBasis = strings.ToLower(strings.TrimSpace(os.Getenv("BOOKING_AMOUNT_BASIS")))
+	}
+	switch amountBasis {
+	case "":
+		amountBasis = services.AmountBasisGross
+	case services.AmountBasisGross, services.AmountBasisNet:
+	default:
+		return nil, fmt.Errorf("%s: invalid amount basis %q (must be %q or %q)", op, amountBasis, services.AmountBasisGross, services.AmountBasisNet)
+	}
+
+	// Optional company context steering account selection towards our conventions
+	var companyContext *CompanyContext
+	if path := os.Getenv("BOOKING_COMPANY_CONTEXT_FILE"); path != "" {
+		var err error
+		companyContext, err = LoadCompanyContext(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	model := options.Model
+	if model == "" {
+		model = DefaultBookingModel
+	}
+
+	// Invoice types guessed with less confidence are flagged for confirmation with --type
+	typeConfidenceMin, err := TypeConfidenceMinFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The tax keys our DATEV setup accepts, e.g. with reverse charge keys
+	taxKeys, err := TaxKeysFromEnv("03")
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to create invoice completion service: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return &SKR03BookingService{
 		openaiClient:      openaiClient,
 		invoiceCompletion: invoiceCompletion,
+		bookingDatePolicy: bookingDatePolicy,
+		companyContext:    companyContext,
+		typeConfidenceMin: typeConfidenceMin,
+		documentAITimeout: options.DocumentAITimeout,
+		model:             model,
+		extractionCache:   options.Cache,
+		forceExtraction:   options.ForceExtraction,
+		vendorMaster:      vendorMaster,
+		allowNoBooking:    options.AllowNoBooking,
+		fieldOverrides:    options.FieldOverrides,
+		paymentTypes:      options.PaymentTypes,
+		taxKeys:           taxKeys,
+		explicitVAT:       options.ExplicitVAT || os.Getenv("EXPLICIT_VAT_POSTINGS") == "true",
+		strict:            options.Strict,
+		amountBasis:       amountBasis,
 		log:               logger.WithComponent("skr03-booking"),
+		processor:         options.Processor,
+		documentAIMode:    options.DocumentAIMode,
+		ownedOCR:          options.OCRService == nil,
 	}, nil
 }
 
@@ -79,6 +285,15 @@ func (s *SKR03BookingService) GenerateBooking(ctx context.Context, invoice *mode
 		Float64("amount", float64(invoice.GrossAmount)/100).
 		Msg("Generating DATEV booking for invoice")
 
+	// A reminder repeats an invoice booked before; at most its dunning fee is booked
+	if invoice.SubType == models.InvoiceSubTypeReminder {
+		booking, err := s.reminderBooking(invoice)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return booking, nil
+	}
+
 	// Convert invoice to JSON for ChatGPT
 	invoiceJSON, err := json.MarshalIndent(invoice, "", "  ")
 	if err != nil {
@@ -94,6 +309,52 @@ func (s *SKR03BookingService) GenerateBooking(ctx context.Context, invoice *mode
 	// Convert to DATEV booking
 	datevBooking := s.convertToDatevBooking(bookingResponse, invoice)
 
+	// Mixed 7%/19% invoices are booked as one split per tax rate
+	if splits := splitByVATRate(invoice); len(splits) > 0 {
+		datevBooking.Splits = splits
+		s.log.Info().
+			Int("splits", len(splits)).
+			Msg("Invoice has several VAT rates, splitting booking by tax key")
+		if warning := splitVATWarning(invoice, splits); warning != "" {
+			s.log.Warn().Int64("vat_amount", invoice.VATAmount).Msg(warning)
+			datevBooking.Warnings = append(datevBooking.Warnings, warning)
+		}
+	}
+
+	// DATEV setups whose tax keys do not post the VAT need it as posting lines of its own
+	if s.explicitVAT {
+		datevBooking.Postings = vatPostings(datevBooking, invoice)
+		if datevBooking.Postings == nil {
+			warning := fmt.Sprintf("Keine Steuerzeilen erzeugt (Konto fehlt oder kein Steuerkonto zu Steuerschlüssel %q) - USt bitte manuell buchen", datevBooking.TaxKey)
+			s.log.Warn().Str("tax_key", datevBooking.TaxKey).Msg(warning)
+			datevBooking.Warnings = append(datevBooking.Warnings, warning)
+		}
+	}
+
+	// A net amount with a VAT tax key makes DATEV derive the VAT from the net amount
+	if warning := netBasisTaxKeyWarning(datevBooking); warning != "" {
+		s.log.Warn().Str("tax_key", datevBooking.TaxKey).Msg(warning)
+		datevBooking.Warnings = append(datevBooking.Warnings, warning)
+	}
+
+	// Prepayments belong on the Anzahlungen accounts and must be cleared by the final invoice
+	if warning := checkPrepaymentAccounts(datevBooking.DebitAccount, datevBooking.CreditAccount, invoice); warning != "" {
+		s.log.Warn().Str("sub_type", invoice.SubType).Msg(warning)
+		datevBooking.Warnings = append(datevBooking.Warnings, warning)
+	}
+
+	// Net and VAT back-calculated from the gross amount must be checked against the document
+	if warning := inferredVATWarning(invoice); warning != "" {
+		s.log.Warn().Str("vat_inferred_from", invoice.VATInferredFrom).Msg(warning)
+		datevBooking.Warnings = append(datevBooking.Warnings, warning)
+	}
+
+	// An absurd gross amount is most likely misread and must be confirmed before it is booked
+	if warning := amountCeilingWarning(invoice); warning != "" {
+		s.log.Warn().Int64("gross_amount", invoice.GrossAmount).Msg(warning)
+		datevBooking.Warnings = append(datevBooking.Warnings, warning)
+	}
+
 	s.log.Info().
 		Str("debit_account", datevBooking.DebitAccount).
 		Str("credit_account", datevBooking.CreditAccount).
@@ -104,6 +365,52 @@ func (s *SKR03BookingService) GenerateBooking(ctx context.Context, invoice *mode
 	return datevBooking, nil
 }
 
+// invoiceProcessor returns the Document AI processor shared by all PDFs, wrapped with the extraction
+// cache if configured. It is created on the first call; the client outlives that call's context, so
+// it is not bound to its cancellation. Safe for concurrent use by batch workers.
+func (s *SKR03BookingService) invoiceProcessor(ctx context.Context) (invoice.InvoiceProcessor, error) {
+	s.processorMu.Lock()
+	defer s.processorMu.Unlock()
+
+	if s.processor != nil {
+		return s.processor, nil
+	}
+
+	processor, err := invoice.NewDocumentAIInvoiceProcessorWithOptions(context.WithoutCancel(ctx), invoice.ProcessorOptions{
+		Timeout: s.documentAITimeout,
+		Mode:    s.documentAIMode,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := processor.(io.Closer); ok {
+		s.ownedProcessor = closer
+	}
+	if s.extractionCache != nil {
+		processor = invoice.NewCachedInvoiceProcessor(processor, s.extractionCache, s.forceExtraction)
+	}
+	s.processor = processor
+	return processor, nil
+}
+
+// Close closes the Document AI client the service created and the OCR client of its completion
+// service. An injected processor or OCR service stays open.
+func (s *SKR03BookingService) Close() error {
+	s.processorMu.Lock()
+	defer s.processorMu.Unlock()
+
+	var errs []error
+	if s.ownedProcessor != nil {
+		errs = append(errs, s.ownedProcessor.Close())
+		s.ownedProcessor = nil
+		s.processor = nil
+	}
+	if closer, ok := s.invoiceCompletion.(io.Closer); ok && s.ownedOCR {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
 // GenerateBookingFromPDF processes PDF, extracts invoice data, and generates booking
 func (s *SKR03BookingService) GenerateBookingFromPDF(ctx context.Context, pdfData io.Reader) (*services.DATEVBooking, *models.Invoice, error) {
 	const op = "GenerateBookingFromPDF"
@@ -117,7 +424,7 @@ func (s *SKR03BookingService) GenerateBookingFromPDF(ctx context.Context, pdfDat
 	}
 
 	// Create Document AI processor
-	processor, err := invoice.NewDocumentAIInvoiceProcessor(ctx)
+	processor, err := s.invoiceProcessor(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("%s: failed to create Document AI processor: %w", op, err)
 	}
@@ -134,11 +441,16 @@ func (s *SKR03BookingService) GenerateBookingFromPDF(ctx context.Context, pdfDat
 		Msg("Invoice extracted with Document AI")
 
 	// Complete invoice with missing fields and accounting summary
-	completedInvoice, err := s.invoiceCompletion.CompleteInvoice(ctx, partialInvoice, bytes.NewReader(pdfBytes))
+	completedInvoice, completionConfidence, err := s.invoiceCompletion.CompleteInvoiceWithConfidence(ctx, partialInvoice, bytes.NewReader(pdfBytes))
+	if errors.Is(err, invoice.ErrLowOCRConfidence) {
+		// Poor scans go to manual review instead of being booked from unreliable data
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
 	if err != nil {
 		s.log.Warn().Err(err).Msg("Invoice completion failed, using Document AI result only")
 		completedInvoice = partialInvoice
 	}
+	completionChanges := invoice.DiffInvoices(partialInvoice, completedInvoice)
 
 	// Validate and reconcile amounts between Document AI and ChatGPT
 	validation := invoice.NewAmountValidation()
@@ -176,18 +488,46 @@ func (s *SKR03BookingService) GenerateBookingFromPDF(ctx context.Context, pdfDat
 		Str("accounting_summary", completedInvoice.AccountingSummary).
 		Msg("Invoice completion finished")
 
+	if completedInvoice.Internal {
+		return nil, nil, fmt.Errorf("%s: %w", op, invoice.ErrInternalInvoice)
+	}
+
+	overrideWarnings := s.applyFieldOverrides(completedInvoice)
+	if err := s.requireInvoiceNumber(completedInvoice); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if warning := s.applyPaymentType(completedInvoice, "", completionConfidence); warning != "" {
+		overrideWarnings = append(overrideWarnings, warning)
+	}
+	s.canonicalizeCounterparty(completedInvoice)
+
 	// Generate booking from completed invoice
-	booking, err := s.GenerateBooking(ctx, completedInvoice)
+	booking, err := s.generateBookingOrTemplate(ctx, completedInvoice)
 	if err != nil {
 		return nil, nil, fmt.Errorf("%s: booking generation failed: %w", op, err)
 	}
+	booking.Warnings = append(booking.Warnings, overrideWarnings...)
+	booking.CompletionChanges = completionChanges
+	booking.SourceSHA256 = DocumentHash(pdfBytes)
+
+	if warning := typeConfidenceWarning(completedInvoice, completedInvoice.Type, "", completionConfidence, s.typeConfidenceMin); warning != "" {
+		s.log.Warn().Str("type", completedInvoice.Type).Msg(warning)
+		booking.Warnings = append(booking.Warnings, warning)
+	}
 
 	return booking, completedInvoice, nil
 }
 
 // GenerateBookingFromPDFWithType processes PDF, extracts invoice data, and generates booking with type override
 func (s *SKR03BookingService) GenerateBookingFromPDFWithType(ctx context.Context, pdfData io.Reader, typeOverride string) (*services.DATEVBooking, *models.Invoice, error) {
-	const op = "GenerateBookingFromPDFWithType"
+	booking, completedInvoice, _, err := s.GenerateBookingFromPDFWithConfidence(ctx, pdfData, typeOverride)
+	return booking, completedInvoice, err
+}
+
+// GenerateBookingFromPDFWithConfidence generates a booking like GenerateBookingFromPDFWithType and also returns
+// the per-field confidence of Document AI merged with the confidence of fields filled in by completion
+func (s *SKR03BookingService) GenerateBookingFromPDFWithConfidence(ctx context.Context, pdfData io.Reader, typeOverride string) (*services.DATEVBooking, *models.Invoice, map[string]float32, error) {
+	const op = "GenerateBookingFromPDFWithConfidence"
 
 	s.log.Info().
 		Str("type_override", typeOverride).
@@ -196,19 +536,19 @@ func (s *SKR03BookingService) GenerateBookingFromPDFWithType(ctx context.Context
 	// Buffer the PDF data since we need to read it multiple times
 	pdfBytes, err := io.ReadAll(pdfData)
 	if err != nil {
-		return nil, nil, fmt.Errorf("%s: failed to read PDF data: %w", op, err)
+		return nil, nil, nil, fmt.Errorf("%s: failed to read PDF data: %w", op, err)
 	}
 
 	// Create Document AI processor
-	processor, err := invoice.NewDocumentAIInvoiceProcessor(ctx)
+	processor, err := s.invoiceProcessor(ctx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("%s: failed to create Document AI processor: %w", op, err)
+		return nil, nil, nil, fmt.Errorf("%s: failed to create Document AI processor: %w", op, err)
 	}
 
 	// Extract invoice data with Document AI
-	partialInvoice, err := processor.ProcessInvoice(ctx, bytes.NewReader(pdfBytes))
+	partialInvoice, confidence, err := processor.ProcessInvoiceWithConfidence(ctx, bytes.NewReader(pdfBytes))
 	if err != nil {
-		return nil, nil, fmt.Errorf("%s: Document AI processing failed: %w", op, err)
+		return nil, nil, nil, fmt.Errorf("%s: Document AI processing failed: %w", op, err)
 	}
 
 	s.log.Info().
@@ -217,11 +557,19 @@ func (s *SKR03BookingService) GenerateBookingFromPDFWithType(ctx context.Context
 		Msg("Invoice extracted with Document AI")
 
 	// Complete invoice with missing fields but override the type
-	completedInvoice, err := s.invoiceCompletion.CompleteInvoice(ctx, partialInvoice, bytes.NewReader(pdfBytes))
+	completedInvoice, completionConfidence, err := s.invoiceCompletion.CompleteInvoiceWithConfidence(ctx, partialInvoice, bytes.NewReader(pdfBytes))
+	if errors.Is(err, invoice.ErrLowOCRConfidence) {
+		// Poor scans go to manual review instead of being booked from unreliable data
+		return nil, nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
 	if err != nil {
 		s.log.Warn().Err(err).Msg("Invoice completion failed, using Document AI result only")
 		completedInvoice = partialInvoice
 	}
+	completionChanges := invoice.DiffInvoices(partialInvoice, completedInvoice)
+	for field, conf := range completionConfidence {
+		confidence[field] = conf
+	}
 
 	// Validate and reconcile amounts between Document AI and ChatGPT
 	validation := invoice.NewAmountValidation()
@@ -258,6 +606,7 @@ func (s *SKR03BookingService) GenerateBookingFromPDFWithType(ctx context.Context
 	}
 
 	// Override the type if provided
+	detectedType := completedInvoice.Type
 	if typeOverride != "" {
 		originalType := completedInvoice.Type
 		completedInvoice.Type = typeOverride
@@ -272,13 +621,34 @@ func (s *SKR03BookingService) GenerateBookingFromPDFWithType(ctx context.Context
 		Str("accounting_summary", completedInvoice.AccountingSummary).
 		Msg("Invoice completion finished with type override")
 
+	if completedInvoice.Internal && typeOverride == "" {
+		return nil, nil, nil, fmt.Errorf("%s: %w", op, invoice.ErrInternalInvoice)
+	}
+
+	overrideWarnings := s.applyFieldOverrides(completedInvoice)
+	if err := s.requireInvoiceNumber(completedInvoice); err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if warning := s.applyPaymentType(completedInvoice, typeOverride, confidence); warning != "" {
+		overrideWarnings = append(overrideWarnings, warning)
+	}
+	s.canonicalizeCounterparty(completedInvoice)
+
 	// Generate booking from completed invoice
-	booking, err := s.GenerateBooking(ctx, completedInvoice)
+	booking, err := s.generateBookingOrTemplate(ctx, completedInvoice)
 	if err != nil {
-		return nil, nil, fmt.Errorf("%s: booking generation failed: %w", op, err)
+		return nil, nil, nil, fmt.Errorf("%s: booking generation failed: %w", op, err)
 	}
+	booking.Warnings = append(booking.Warnings, overrideWarnings...)
+	booking.CompletionChanges = completionChanges
+	booking.SourceSHA256 = DocumentHash(pdfBytes)
 
-	return booking, completedInvoice, nil
+	if warning := typeConfidenceWarning(completedInvoice, detectedType, typeOverride, confidence, s.typeConfidenceMin); warning != "" {
+		s.log.Warn().Str("detected_type", detectedType).Str("override_type", typeOverride).Msg(warning)
+		booking.Warnings = append(booking.Warnings, warning)
+	}
+
+	return booking, completedInvoice, confidence, nil
 }
 
 // generateBookingWithChatGPT uses ChatGPT to generate booking information
@@ -292,28 +662,87 @@ func (s *SKR03BookingService) generateBookingWithChatGPT(ctx context.Context, in
 		Str("invoice_type", invoice.Type).
 		Msg("Sending booking request to ChatGPT")
 
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: s.getSystemPrompt(),
+		},
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: prompt,
+		},
+	}
+
+	bookingResponse, content, err := s.requestBooking(ctx, op, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// A Kleinunternehmer charges no VAT, so ChatGPT must not assign an input tax key
+	if replaced := applySmallBusinessTaxKey(bookingResponse, invoice); replaced != "" {
+		s.log.Warn().
+			Str("tax_key", replaced).
+			Msg("Tax key replaced with 0 for an invoice of a Kleinunternehmer (§19 UStG)")
+	}
+
+	// A tax key that contradicts the invoice's VAT rate is a common AI error, so ask once for a correction
+	if mismatch := checkTaxKeyConsistency(bookingResponse.TaxKey, invoice); mismatch != nil {
+		s.log.Warn().
+			Err(mismatch).
+			Str("tax_key", bookingResponse.TaxKey).
+			Msg("Tax key does not match invoice VAT rate, re-prompting")
+
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: fmt.Sprintf(
+				"Der Steuerschlüssel ist nicht plausibel: %s. Prüfe den Steuerschlüssel und antworte erneut mit dem vollständigen korrigierten JSON-Objekt.",
+				mismatch)},
+		)
+
+		corrected, _, retryErr := s.requestBooking(ctx, op, messages)
+		if retryErr != nil {
+			s.log.Warn().Err(retryErr).Msg("Tax key correction request failed, keeping original booking")
+			bookingResponse.Warnings = append(bookingResponse.Warnings, mismatch.Error())
+		} else if stillWrong := checkTaxKeyConsistency(corrected.TaxKey, invoice); stillWrong != nil {
+			s.log.Warn().
+				Err(stillWrong).
+				Str("tax_key", corrected.TaxKey).
+				Msg("Tax key still inconsistent after correction, flagging booking")
+			corrected.Warnings = append(corrected.Warnings, stillWrong.Error())
+			bookingResponse = corrected
+		} else {
+			s.log.Info().
+				Str("original_tax_key", bookingResponse.TaxKey).
+				Str("corrected_tax_key", corrected.TaxKey).
+				Msg("Tax key corrected after re-prompt")
+			bookingResponse = corrected
+		}
+	}
+
+	s.log.Info().
+		Str("debit_account", bookingResponse.DebitAccount).
+		Str("credit_account", bookingResponse.CreditAccount).
+		Str("tax_key", bookingResponse.TaxKey).
+		Msg("ChatGPT booking response validated")
+
+	return bookingResponse, nil
+}
+
+// requestBooking sends the conversation to ChatGPT and returns the parsed, validated booking and the raw content
+func (s *SKR03BookingService) requestBooking(ctx context.Context, op string, messages []openai.ChatCompletionMessage) (*ChatGPTBookingResponse, string, error) {
 	resp, err := s.openaiClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       "gpt-4",
+		Model:       s.model,
 		Temperature: 0.1,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: s.getSystemPrompt(),
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		MaxTokens: 1500,
+		Messages:    messages,
+		MaxTokens:   1500,
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("%s: ChatGPT request failed: %w", op, err)
+		return nil, "", fmt.Errorf("%s: ChatGPT request failed: %w", op, err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("%s: no response choices from ChatGPT", op)
+		return nil, "", fmt.Errorf("%s: no response choices from ChatGPT", op)
 	}
 
 	content := resp.Choices[0].Message.Content
@@ -323,31 +752,47 @@ func (s *SKR03BookingService) generateBookingWithChatGPT(ctx context.Context, in
 
 	// Parse JSON response
 	var bookingResponse ChatGPTBookingResponse
-	if err := json.Unmarshal([]byte(content), &bookingResponse); err != nil {
+	if err := json.Unmarshal([]byte(llm.ExtractJSON(content)), &bookingResponse); err != nil {
 		s.log.Error().
 			Err(err).
 			Str("response", content).
 			Msg("Failed to parse ChatGPT JSON response")
-		return nil, fmt.Errorf("%s: failed to parse ChatGPT JSON response: %w (response: %s)", op, err, content)
+		return nil, "", fmt.Errorf("%s: failed to parse ChatGPT JSON response: %w (response: %s)", op, err, content)
 	}
 
 	// Validate required fields
 	if err := s.validateBookingResponse(&bookingResponse); err != nil {
-		return nil, fmt.Errorf("%s: invalid booking response: %w", op, err)
+		return nil, "", fmt.Errorf("%s: invalid booking response: %w", op, err)
 	}
 
-	s.log.Info().
-		Str("debit_account", bookingResponse.DebitAccount).
-		Str("credit_account", bookingResponse.CreditAccount).
-		Str("tax_key", bookingResponse.TaxKey).
-		Msg("ChatGPT booking response validated")
-
-	return &bookingResponse, nil
+	return &bookingResponse, content, nil
 }
 
-// getSystemPrompt returns the system prompt for ChatGPT booking generation
+// getSystemPrompt returns the system prompt for ChatGPT booking generation with the allowed tax keys,
+// extended by the company context if one is configured
 func (s *SKR03BookingService) getSystemPrompt() string {
-	return `Du bist ein Experte für deutsches Rechnungswesen und DATEV-Buchungen nach SKR03 (Standardkontenrahmen 03).
+	prompt := genericSystemPrompt
+	if s.taxKeys != nil {
+		prompt = systemPromptWithTaxKeys(s.taxKeys)
+	}
+	if s.companyContext == nil {
+		return prompt
+	}
+	return prompt + "\n\n" + s.companyContext.PromptBlock() +
+		"\nBevorzuge die oben genannten Konten, wenn die Rechnung zu einer Kategorie oder einem Lieferanten passt."
+}
+
+// genericSystemPrompt is the booking system prompt with the default SKR03 tax keys and without
+// company-specific conventions
+var genericSystemPrompt = systemPromptWithTaxKeys(DefaultTaxKeys("03"))
+
+// systemPromptWithTaxKeys returns the generic booking system prompt listing the given tax keys
+func systemPromptWithTaxKeys(taxKeys []TaxKeyDefinition) string {
+	return systemPromptRules + taxKeyPromptBlock(taxKeys) + systemPromptFormat
+}
+
+// systemPromptRules is the part of the system prompt before the tax keys
+const systemPromptRules = `Du bist ein Experte für deutsches Rechnungswesen und DATEV-Buchungen nach SKR03 (Standardkontenrahmen 03).
 
 Deine Aufgabe ist es, für Eingangs- und Ausgangsrechnungen korrekte Buchungssätze zu erstellen.
 
@@ -369,19 +814,15 @@ SKR03 WICHTIGE KONTEN:
 - 8000-8999: Steuern
 - 9000-9999: Nicht betriebliche Erträge/Aufwendungen
 
-STEUERSCHLÜSSEL:
-- 0: Steuerfrei
-- 9: 19% Vorsteuer (Eingangsrechnungen)
-- 3: 19% Umsatzsteuer (Ausgangsrechnungen)
-- 5: 7% Vorsteuer
-- 2: 7% Umsatzsteuer
+`
 
+// systemPromptFormat is the part of the system prompt after the tax keys
+const systemPromptFormat = `
 CRITICAL: Antworte AUSSCHLIESSLICH mit gültigem JSON. Kein Text vor oder nach dem JSON.
 - Keine Erklärungen außerhalb des JSON
 - Keine Markdown-Formatierung
 - Keine trailing commas
 - Validiere die JSON-Syntax bevor du antwortest`
-}
 
 // buildBookingPrompt creates the user prompt for ChatGPT
 func (s *SKR03BookingService) buildBookingPrompt(invoiceJSON string, invoice *models.Invoice) string {
@@ -401,13 +842,24 @@ func (s *SKR03BookingService) buildBookingPrompt(invoiceJSON string, invoice *mo
 		prompt.WriteString("Dies ist eine AUSGANGSRECHNUNG (Kunde schuldet uns Geld).\n")
 	}
 
+	if invoice.SmallBusiness {
+		prompt.WriteString("Der Rechnungssteller ist Kleinunternehmer nach § 19 UStG und weist keine Umsatzsteuer aus: verwende Steuerschlüssel 0 und buche keine Vorsteuer.\n")
+	}
+
+	prompt.WriteString(prepaymentPromptBlock(invoice))
+
+	// The customer reference identifies the order or project and belongs in the booking text; the PO number does not
+	if invoice.CustomerReference != "" {
+		prompt.WriteString(fmt.Sprintf("Nimm die Kundenreferenz \"%s\" in den Buchungstext auf.\n", invoice.CustomerReference))
+	}
+
 	prompt.WriteString("\nGib folgende Buchungsinformationen als JSON zurück:\n")
 	prompt.WriteString("{\n")
 	prompt.WriteString(`  "sollkonto": "4-stellige SKR03 Kontonummer",` + "\n")
 	prompt.WriteString(`  "sollkonto_name": "Bezeichnung des Sollkontos",` + "\n")
 	prompt.WriteString(`  "habenkonto": "4-stellige SKR03 Kontonummer",` + "\n")
 	prompt.WriteString(`  "habenkonto_name": "Bezeichnung des Habenkontos",` + "\n")
-	prompt.WriteString(`  "steuerschluessel": "Steuerschlüssel (0,2,3,5,9)",` + "\n")
+	prompt.WriteString(fmt.Sprintf(`  "steuerschluessel": "Steuerschlüssel (%s)",`, strings.Join(taxKeyList(s.allowedTaxKeys()), ",")) + "\n")
 	prompt.WriteString(`  "steuerschluessel_beschreibung": "Beschreibung des Steuerschlüssels",` + "\n")
 	prompt.WriteString(`  "buchungstext": "Buchungstext max 60 Zeichen",` + "\n")
 	prompt.WriteString(`  "kostenstelle": "Kostenstelle falls zutreffend oder leer",` + "\n")
@@ -432,6 +884,9 @@ func (s *SKR03BookingService) validateBookingResponse(response *ChatGPTBookingRe
 	if response.TaxKey == "" {
 		return fmt.Errorf("missing tax key (Steuerschlüssel)")
 	}
+	if allowed := taxKeyList(s.allowedTaxKeys()); !slices.Contains(allowed, response.TaxKey) {
+		return fmt.Errorf("tax key %s is not allowed (allowed: %s)", response.TaxKey, strings.Join(allowed, ", "))
+	}
 	if response.BookingText == "" {
 		return fmt.Errorf("missing booking text (Buchungstext)")
 	}
@@ -444,20 +899,32 @@ func (s *SKR03BookingService) validateBookingResponse(response *ChatGPTBookingRe
 		return fmt.Errorf("invalid credit account format: %s (must be 4-digit SKR03 account)", response.CreditAccount)
 	}
 
-	// Validate and truncate booking text if necessary
-	if len(response.BookingText) > 60 {
+	// Validate and truncate booking text if necessary (DATEV counts characters, not bytes)
+	if length := utf8.RuneCountInString(response.BookingText); length > maxBookingTextLength {
 		originalText := response.BookingText
-		response.BookingText = response.BookingText[:57] + "..."
+		response.BookingText = truncateRunes(response.BookingText, maxBookingTextLength-3) + "..."
 		s.log.Warn().
 			Str("original_text", originalText).
 			Str("truncated_text", response.BookingText).
-			Int("original_length", len(originalText)).
+			Int("original_length", length).
 			Msg("Booking text truncated to fit DATEV 60-character limit")
 	}
 
 	return nil
 }
 
+// truncateRunes shortens s to at most n characters without splitting a multi-byte character
+func truncateRunes(s string, n int) string {
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i]
+		}
+		count++
+	}
+	return s
+}
+
 // isValidSKR03Account checks if the account number is a valid 4-digit SKR03 account
 func (s *SKR03BookingService) isValidSKR03Account(account string) bool {
 	if len(account) != 4 {
@@ -474,8 +941,11 @@ func (s *SKR03BookingService) isValidSKR03Account(account string) bool {
 func (s *SKR03BookingService) convertToDatevBooking(response *ChatGPTBookingResponse, invoice *models.Invoice) *services.DATEVBooking {
 	now := time.Now()
 	
-	// Use invoice issue date for booking date, fallback to today
+	// Use invoice issue date (or Leistungsdatum if configured) for booking date, fallback to today
 	bookingDate := invoice.IssueDate
+	if s.bookingDatePolicy == BookingDateService && !invoice.ServiceDate.IsZero() {
+		bookingDate = invoice.ServiceDate
+	}
 	if bookingDate.IsZero() {
 		bookingDate = now
 	}
@@ -483,11 +953,17 @@ func (s *SKR03BookingService) convertToDatevBooking(response *ChatGPTBookingResp
 	// Generate accounting period (MMYYYY)
 	accountingPeriod := fmt.Sprintf("%02d%d", bookingDate.Month(), bookingDate.Year())
 
+	// The tax key derives the VAT from the gross amount; net bookings leave the VAT to other lines
+	amount := invoice.GrossAmount
+	if s.amountBasis == services.AmountBasisNet {
+		amount = invoice.NetAmount
+	}
+
 	return &services.DATEVBooking{
 		BookingText:       response.BookingText,
 		DebitAccount:      response.DebitAccount,
 		CreditAccount:     response.CreditAccount,
-		Amount:           float64(invoice.GrossAmount) / 100, // Convert cents to EUR
+		Amount:           float64(amount) / 100, // Convert cents to EUR
 		TaxKey:           response.TaxKey,
 		CostCenter:       response.CostCenter,
 		BookingDate:      bookingDate,
@@ -495,9 +971,13 @@ func (s *SKR03BookingService) convertToDatevBooking(response *ChatGPTBookingResp
 		AccountingPeriod: accountingPeriod,
 		Explanation:      response.Explanation,
 		
+		PrepaymentReference: invoice.PrepaymentReference,
+
 		DebitAccountName:  response.DebitAccountName,
 		CreditAccountName: response.CreditAccountName,
 		TaxKeyDescription: response.TaxKeyDescription,
+		Warnings:          response.Warnings,
+		AmountBasis:       s.amountBasis,
 		
 		GeneratedAt:      now,
 		ContenrahmenType: "SKR03",
@@ -0,0 +1,174 @@
+package booking
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	"tools/internal/invoice"
+	"tools/internal/ocr"
+	"tools/pkg/models"
+)
+
+// closingProcessor is an InvoiceProcessor that counts Close calls
+type closingProcessor struct {
+	closed int
+}
+
+func (p *closingProcessor) ProcessInvoice(ctx context.Context, pdfData io.Reader) (*models.Invoice, error) {
+	return &models.Invoice{}, nil
+}
+
+func (p *closingProcessor) ProcessInvoiceWithConfidence(ctx context.Context, pdfData io.Reader) (*models.Invoice, map[string]float32, error) {
+	return &models.Invoice{}, map[string]float32{}, nil
+}
+
+func (p *closingProcessor) ProcessInvoicePages(ctx context.Context, pdfData io.Reader, pages []int32) (*models.Invoice, map[string]float32, error) {
+	return &models.Invoice{}, map[string]float32{}, nil
+}
+
+func (p *closingProcessor) ProcessMultiInvoice(ctx context.Context, pdfData io.Reader) ([]*models.Invoice, error) {
+	return []*models.Invoice{{}}, nil
+}
+
+func (p *closingProcessor) Close() error {
+	p.closed++
+	return nil
+}
+
+// closingOCRService is an OCRService that counts Close calls
+type closingOCRService struct {
+	closed int
+}
+
+func (o *closingOCRService) ProcessPDF(ctx context.Context, pdfData io.Reader) (string, error) {
+	return "", nil
+}
+
+func (o *closingOCRService) ProcessPDFWithMetadata(ctx context.Context, pdfData io.Reader) (*ocr.OCRResult, error) {
+	return &ocr.OCRResult{}, nil
+}
+
+func (o *closingOCRService) ProcessPDFPages(ctx context.Context, pdfData io.Reader, pages []int32) (*ocr.OCRResult, error) {
+	return &ocr.OCRResult{}, nil
+}
+
+func (o *closingOCRService) Close() error {
+	o.closed++
+	return nil
+}
+
+func TestInvoiceProcessorSharedAcrossWorkers(t *testing.T) {
+	injected := &closingProcessor{}
+	s := &SKR03BookingService{processor: injected}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			processor, err := s.invoiceProcessor(context.Background())
+			if err != nil {
+				t.Errorf("invoiceProcessor: %v", err)
+			} else if processor != injected {
+				t.Errorf("got a new processor instead of the injected one")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if injected.closed != 0 {
+		t.Errorf("injected processor was closed %d times, want it left open", injected.closed)
+	}
+}
+
+func TestCloseClosesOwnedProcessor(t *testing.T) {
+	owned := &closingProcessor{}
+	s := &SKR03BookingService{processor: owned, ownedProcessor: owned}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if owned.closed != 1 {
+		t.Errorf("owned processor closed %d times, want 1", owned.closed)
+	}
+}
+
+func TestCloseKeepsInjectedOCRService(t *testing.T) {
+	for _, owned := range []bool{false, true} {
+		ocrService := &closingOCRService{}
+		s := &SKR03BookingService{
+			invoiceCompletion: invoice.NewInvoiceCompletionServiceWithDeps(ocrService, nil, invoice.CompletionConfig{}),
+			ownedOCR:          owned,
+		}
+
+		if err := s.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		want := 0
+		if owned {
+			want = 1
+		}
+		if ocrService.closed != want {
+			t.Errorf("owned=%v: OCR service closed %d times, want %d", owned, ocrService.closed, want)
+		}
+	}
+}
+
+// unusedLLMClient is an LLMClient for services whose tests never reach ChatGPT
+type unusedLLMClient struct{}
+
+func (unusedLLMClient) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	return openai.ChatCompletionResponse{}, nil
+}
+
+func TestBookingDatePolicyOption(t *testing.T) {
+	// The policy comes from the options; the environment is read by the commands
+	t.Setenv("BOOKING_DATE_POLICY", "invalid")
+
+	invoice := &models.Invoice{
+		IssueDate:   time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC),
+		ServiceDate: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		GrossAmount: 11900,
+	}
+	tests := []struct {
+		policy     string
+		wantDate   time.Time
+		wantPeriod string
+		wantErr    bool
+	}{
+		{"", invoice.IssueDate, "042024", false},
+		{BookingDateIssue, invoice.IssueDate, "042024", false},
+		{BookingDateService, invoice.ServiceDate, "032024", false},
+		{"leistungsdatum", time.Time{}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			service, err := NewSKR03BookingServiceWithOptions(context.Background(), BookingOptions{
+				BookingDatePolicy: tt.policy,
+				NoSummary:         true,
+				OCRService:        &closingOCRService{},
+				LLMClient:         unusedLLMClient{},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSKR03BookingServiceWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			booking := service.(*SKR03BookingService).convertToDatevBooking(&ChatGPTBookingResponse{TaxKey: "9"}, invoice)
+			if !booking.BookingDate.Equal(tt.wantDate) || booking.AccountingPeriod != tt.wantPeriod {
+				t.Errorf("booking date %s, period %s, want %s, %s", booking.BookingDate.Format("2006-01-02"), booking.AccountingPeriod, tt.wantDate.Format("2006-01-02"), tt.wantPeriod)
+			}
+		})
+	}
+}
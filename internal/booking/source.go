@@ -0,0 +1,13 @@
+package booking
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// DocumentHash returns the hex SHA-256 of a source document, recorded in DATEVBooking.SourceSHA256 so a
+// booking can be traced back to the exact PDF it was generated from
+func DocumentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
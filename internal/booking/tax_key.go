@@ -0,0 +1,214 @@
+package booking
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"tools/pkg/models"
+)
+
+// TaxKeyDefinition is a tax key ChatGPT may choose, with the description it is shown in the prompt
+type TaxKeyDefinition struct {
+	Key         string
+	Description string
+}
+
+// defaultTaxKeys lists the tax keys offered per chart of accounts (two-digit form, as in --skr)
+// unless TAX_KEYS_SKR<chart> configures others
+var defaultTaxKeys = map[string][]TaxKeyDefinition{
+	"03": {
+		{"0", "Steuerfrei"},
+		{"9", "19% Vorsteuer (Eingangsrechnungen)"},
+		{"3", "19% Umsatzsteuer (Ausgangsrechnungen)"},
+		{"5", "7% Vorsteuer"},
+		{"2", "7% Umsatzsteuer"},
+	},
+}
+
+// DefaultTaxKeys returns the tax keys offered for a chart of accounts without configuration
+func DefaultTaxKeys(chart string) []TaxKeyDefinition {
+	return slices.Clone(defaultTaxKeys[chart])
+}
+
+// TaxKeysFromEnv returns the tax keys configured for a chart of accounts with TAX_KEYS_SKR<chart>,
+// e.g. TAX_KEYS_SKR03, or nil if the variable is unset
+func TaxKeysFromEnv(chart string) ([]TaxKeyDefinition, error) {
+	name := "TAX_KEYS_SKR" + chart
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil, nil
+	}
+
+	taxKeys, err := ParseTaxKeys(value, chart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return taxKeys, nil
+}
+
+// ParseTaxKeys parses a list of tax keys such as "0,2,3,5,9;94=19% Vorsteuer und Umsatzsteuer (§13b UStG)".
+// Entries are separated by semicolons; an entry without description may list several keys separated
+// by commas. Keys without description must be among the chart's default keys, whose description
+// they keep.
+func ParseTaxKeys(value, chart string) ([]TaxKeyDefinition, error) {
+	var taxKeys []TaxKeyDefinition
+	add := func(key, description string) error {
+		if _, err := strconv.Atoi(key); err != nil || len(key) > 3 {
+			return fmt.Errorf("tax key %q must be a number of up to 3 digits", key)
+		}
+		if description == "" {
+			index := slices.IndexFunc(defaultTaxKeys[chart], func(d TaxKeyDefinition) bool { return d.Key == key })
+			if index < 0 {
+				return fmt.Errorf("tax key %s needs a description, e.g. %s=19%% Vorsteuer", key, key)
+			}
+			description = defaultTaxKeys[chart][index].Description
+		}
+		if slices.ContainsFunc(taxKeys, func(d TaxKeyDefinition) bool { return d.Key == key }) {
+			return fmt.Errorf("tax key %s is listed twice", key)
+		}
+		taxKeys = append(taxKeys, TaxKeyDefinition{Key: key, Description: description})
+		return nil
+	}
+
+	for _, entry := range strings.Split(value, ";") {
+		if key, description, found := strings.Cut(entry, "="); found {
+			if err := add(strings.TrimSpace(key), strings.TrimSpace(description)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, key := range strings.Split(entry, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				if err := add(key, ""); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if len(taxKeys) == 0 {
+		return nil, fmt.Errorf("no tax keys listed")
+	}
+	return taxKeys, nil
+}
+
+// allowedTaxKeys returns the configured tax keys, or the SKR03 defaults if none are configured
+func (s *SKR03BookingService) allowedTaxKeys() []TaxKeyDefinition {
+	if s.taxKeys != nil {
+		return s.taxKeys
+	}
+	return defaultTaxKeys["03"]
+}
+
+// taxKeyList returns the keys in numeric order, as listed in the user prompt and error messages
+func taxKeyList(taxKeys []TaxKeyDefinition) []string {
+	keys := make([]string, len(taxKeys))
+	for i, taxKey := range taxKeys {
+		keys[i] = taxKey.Key
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return x - y
+	})
+	return keys
+}
+
+// taxKeyPromptBlock returns the STEUERSCHLÜSSEL block of the system prompt
+func taxKeyPromptBlock(taxKeys []TaxKeyDefinition) string {
+	var block strings.Builder
+	block.WriteString("STEUERSCHLÜSSEL:\n")
+	for _, taxKey := range taxKeys {
+		fmt.Fprintf(&block, "- %s: %s\n", taxKey.Key, taxKey.Description)
+	}
+	block.WriteString("- Verwende ausschließlich diese Steuerschlüssel\n")
+	return block.String()
+}
+
+// taxKeyRates maps the SKR03 tax keys used in the booking prompt to their VAT rate in percent
+var taxKeyRates = map[string]float64{
+	"0": 0,  // Steuerfrei
+	"2": 7,  // 7% Umsatzsteuer
+	"3": 19, // 19% Umsatzsteuer
+	"5": 7,  // 7% Vorsteuer
+	"9": 19, // 19% Vorsteuer
+}
+
+// smallBusinessTaxKey is the tax key of invoices from a Kleinunternehmer (§19 UStG), which charge no VAT
+const smallBusinessTaxKey = "0"
+
+// TaxKeyLabel returns a short description of an SKR03 tax key for summaries, e.g. "19% VSt" for
+// key 9, or an empty string for unknown keys
+func TaxKeyLabel(taxKey string) string {
+	rate, known := taxKeyRates[taxKey]
+	switch {
+	case !known:
+		return ""
+	case rate == 0:
+		return "steuerfrei"
+	case taxKey == "2" || taxKey == "3":
+		return fmt.Sprintf("%.0f%% USt", rate)
+	default:
+		return fmt.Sprintf("%.0f%% VSt", rate)
+	}
+}
+
+// standardVATRates are the German VAT rates an invoice's implied rate is rounded to
+var standardVATRates = []float64{0, 7, 19}
+
+// vatRateTolerance is how far (in percentage points) the implied rate may deviate from a standard
+// rate because of rounding; larger deviations usually mean mixed rates or misread amounts
+const vatRateTolerance = 0.5
+
+// impliedVATRate derives the standard VAT rate from the invoice amounts.
+// ok is false when there is no net amount or the rate matches no standard rate (e.g. mixed rates).
+func impliedVATRate(invoice *models.Invoice) (rate float64, ok bool) {
+	if invoice.NetAmount <= 0 || invoice.VATAmount < 0 {
+		return 0, false
+	}
+
+	actual := float64(invoice.VATAmount) / float64(invoice.NetAmount) * 100
+	for _, standard := range standardVATRates {
+		if math.Abs(actual-standard) <= vatRateTolerance {
+			return standard, true
+		}
+	}
+	return 0, false
+}
+
+// checkTaxKeyConsistency returns an error if the tax key's VAT rate contradicts the rate implied by
+// the invoice amounts. Unknown tax keys and invoices without a clear rate are not checked.
+func checkTaxKeyConsistency(taxKey string, invoice *models.Invoice) error {
+	keyRate, known := taxKeyRates[taxKey]
+	if !known {
+		return nil
+	}
+
+	invoiceRate, ok := impliedVATRate(invoice)
+	if !ok || invoiceRate == keyRate {
+		return nil
+	}
+
+	return fmt.Errorf("Steuerschlüssel %s entspricht %.0f%%, die Rechnung hat aber %.0f%% MwSt (Netto %.2f EUR, MwSt %.2f EUR)",
+		taxKey, keyRate, invoiceRate, float64(invoice.NetAmount)/100, float64(invoice.VATAmount)/100)
+}
+
+// applySmallBusinessTaxKey books the invoice of a Kleinunternehmer with tax key 0 whatever ChatGPT
+// chose, as there is no input tax to deduct, and notes why in the explanation. It returns the tax key
+// ChatGPT chose if it was replaced, "" otherwise.
+func applySmallBusinessTaxKey(response *ChatGPTBookingResponse, invoice *models.Invoice) (replaced string) {
+	if !invoice.SmallBusiness {
+		return ""
+	}
+	if response.TaxKey != smallBusinessTaxKey {
+		replaced = response.TaxKey
+	}
+	response.TaxKey = smallBusinessTaxKey
+	response.TaxKeyDescription = "Steuerfrei (Kleinunternehmer, § 19 UStG)"
+	response.Explanation = strings.TrimSpace(response.Explanation + " Kleinunternehmer nach § 19 UStG: keine Umsatzsteuer ausgewiesen, gebucht mit Steuerschlüssel 0.")
+	return replaced
+}
@@ -0,0 +1,119 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+
+	"tools/pkg/models"
+)
+
+func TestCheckTaxKeyConsistency(t *testing.T) {
+	tests := []struct {
+		name    string
+		taxKey  string
+		net     int64
+		vat     int64
+		wantErr bool
+	}{
+		{"19% Vorsteuer", "9", 10000, 1900, false},
+		{"7% Vorsteuer", "5", 10000, 700, false},
+		{"tax free", "0", 10000, 0, false},
+		{"19% Umsatzsteuer", "3", 4999, 950, false},
+		{"7% invoice booked with 19% key", "9", 10000, 700, true},
+		{"19% invoice booked with 7% key", "5", 10000, 1900, true},
+		{"VAT invoice booked tax free", "0", 10000, 1900, true},
+		{"tax free invoice booked with 19% key", "9", 10000, 0, true},
+		{"mixed rates are not checked", "9", 10000, 1300, false},
+		{"missing net amount is not checked", "5", 0, 1900, false},
+		{"unknown tax key is not checked", "94", 10000, 700, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &models.Invoice{NetAmount: tt.net, VATAmount: tt.vat, GrossAmount: tt.net + tt.vat}
+			err := checkTaxKeyConsistency(tt.taxKey, invoice)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkTaxKeyConsistency(%q) error = %v, wantErr %v", tt.taxKey, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplySmallBusinessTaxKey(t *testing.T) {
+	response := &ChatGPTBookingResponse{TaxKey: "9", Explanation: "Fremdleistung."}
+	if replaced := applySmallBusinessTaxKey(response, &models.Invoice{}); replaced != "" || response.TaxKey != "9" {
+		t.Fatalf("regular invoice: replaced %q, tax key %q, want the tax key kept", replaced, response.TaxKey)
+	}
+
+	invoice := &models.Invoice{SmallBusiness: true, NetAmount: 50000, GrossAmount: 50000}
+	if replaced := applySmallBusinessTaxKey(response, invoice); replaced != "9" {
+		t.Errorf("replaced = %q, want 9", replaced)
+	}
+	if response.TaxKey != "0" {
+		t.Errorf("tax key = %q, want 0", response.TaxKey)
+	}
+	if !strings.Contains(response.Explanation, "§ 19 UStG") {
+		t.Errorf("explanation %q does not mention § 19 UStG", response.Explanation)
+	}
+	if err := checkTaxKeyConsistency(response.TaxKey, invoice); err != nil {
+		t.Errorf("forced tax key is inconsistent: %v", err)
+	}
+}
+
+func TestTaxKeyLabel(t *testing.T) {
+	tests := map[string]string{
+		"0":  "steuerfrei",
+		"3":  "19% USt",
+		"5":  "7% VSt",
+		"9":  "19% VSt",
+		"94": "",
+		"":   "",
+	}
+
+	for taxKey, want := range tests {
+		if got := TaxKeyLabel(taxKey); got != want {
+			t.Errorf("TaxKeyLabel(%q) = %q, want %q", taxKey, got, want)
+		}
+	}
+}
+
+func TestParseTaxKeys(t *testing.T) {
+	taxKeys, err := ParseTaxKeys("0,9, 3;94=19% Vorsteuer und Umsatzsteuer (§13b UStG)", "03")
+	if err != nil {
+		t.Fatalf("ParseTaxKeys: %v", err)
+	}
+	if len(taxKeys) != 4 || taxKeys[1].Description != "19% Vorsteuer (Eingangsrechnungen)" || taxKeys[3].Key != "94" {
+		t.Errorf("unexpected tax keys: %+v", taxKeys)
+	}
+
+	for _, invalid := range []string{"", "8", "9a", "9,9", "1234=Zu lang"} {
+		if _, err := ParseTaxKeys(invalid, "03"); err == nil {
+			t.Errorf("ParseTaxKeys(%q) succeeded, want error", invalid)
+		}
+	}
+}
+
+func TestValidateBookingResponseTaxKeys(t *testing.T) {
+	response := func(taxKey string) *ChatGPTBookingResponse {
+		return &ChatGPTBookingResponse{DebitAccount: "3425", CreditAccount: "1600", TaxKey: taxKey, BookingText: "Reverse Charge"}
+	}
+
+	s := &SKR03BookingService{}
+	if err := s.validateBookingResponse(response("94")); err == nil {
+		t.Error("expected tax key 94 to be rejected with the default keys")
+	}
+
+	s.taxKeys = []TaxKeyDefinition{{"9", "19% Vorsteuer"}, {"94", "Reverse Charge"}}
+	if err := s.validateBookingResponse(response("94")); err != nil {
+		t.Errorf("configured tax key rejected: %v", err)
+	}
+	if err := s.validateBookingResponse(response("3")); err == nil {
+		t.Error("expected tax key 3 to be rejected when not configured")
+	}
+	if prompt := s.getSystemPrompt(); !strings.Contains(prompt, "- 94: Reverse Charge\n") || strings.Contains(prompt, "- 3:") {
+		t.Errorf("system prompt does not list exactly the configured keys:\n%s", prompt)
+	}
+	if prompt := s.buildBookingPrompt("{}", &models.Invoice{}); !strings.Contains(prompt, "Steuerschlüssel (9,94)") {
+		t.Errorf("booking prompt does not list the configured keys:\n%s", prompt)
+	}
+}
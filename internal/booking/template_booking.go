@@ -0,0 +1,62 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// generateBookingOrTemplate generates the booking with ChatGPT. If that fails and AllowNoBooking is
+// set, it returns a template booking instead so the extracted invoice is not lost. Timeouts and
+// cancellation still fail, since the caller gave up on the whole operation, and so do reminders
+// without a dunning fee, which must not be booked at all.
+func (s *SKR03BookingService) generateBookingOrTemplate(ctx context.Context, invoice *models.Invoice) (*services.DATEVBooking, error) {
+	booking, err := s.GenerateBooking(ctx, invoice)
+	if err == nil || !s.allowNoBooking || ctx.Err() != nil || invoice.SubType == models.InvoiceSubTypeReminder {
+		return booking, err
+	}
+
+	s.log.Warn().
+		Err(err).
+		Str("invoice_number", invoice.InvoiceNumber).
+		Msg("AI booking failed, returning template booking for manual completion")
+
+	return s.templateBooking(invoice, err), nil
+}
+
+// templateBooking fills a booking from the invoice alone: amount, dates, document number and the tax
+// split are known, the accounts and the tax key are left blank for the accountant
+func (s *SKR03BookingService) templateBooking(invoice *models.Invoice, cause error) *services.DATEVBooking {
+	booking := s.convertToDatevBooking(&ChatGPTBookingResponse{}, invoice)
+	booking.Template = true
+	booking.BookingText = truncateRunes(templateBookingText(invoice), maxBookingTextLength)
+	booking.Explanation = fmt.Sprintf("KI-Buchung übersprungen (%v). Soll-, Habenkonto und Steuerschlüssel bitte manuell ergänzen.", cause)
+	booking.Warnings = append(booking.Warnings, "KI-Buchung übersprungen: Konten und Steuerschlüssel fehlen")
+	booking.Splits = splitByVATRate(invoice)
+	if warning := splitVATWarning(invoice, booking.Splits); warning != "" {
+		booking.Warnings = append(booking.Warnings, warning)
+	}
+	return booking
+}
+
+// templateBookingText builds the Buchungstext from counterparty and invoice number
+func templateBookingText(invoice *models.Invoice) string {
+	counterparty := invoice.Vendor
+	if invoice.Type == "RECEIVABLE" {
+		counterparty = invoice.Customer
+	}
+
+	var parts []string
+	for _, part := range []string{counterparty, invoice.InvoiceNumber} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "Rechnung"
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,47 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"tools/pkg/models"
+)
+
+// unavailableClient fails every request like an OpenAI outage
+type unavailableClient struct{}
+
+func (unavailableClient) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	return openai.ChatCompletionResponse{}, errors.New("503 service unavailable")
+}
+
+func TestGenerateBookingOrTemplate(t *testing.T) {
+	invoice := &models.Invoice{InvoiceNumber: "RE-7", Type: "PAYABLE", Vendor: "Muster GmbH", GrossAmount: 11900, NetAmount: 10000, VATAmount: 1900}
+
+	s := &SKR03BookingService{openaiClient: unavailableClient{}}
+	if _, err := s.generateBookingOrTemplate(context.Background(), invoice); err == nil {
+		t.Fatal("expected error without AllowNoBooking")
+	}
+
+	s.allowNoBooking = true
+	booking, err := s.generateBookingOrTemplate(context.Background(), invoice)
+	if err != nil {
+		t.Fatalf("generateBookingOrTemplate: %v", err)
+	}
+	if !booking.Template || booking.DebitAccount != "" || booking.CreditAccount != "" || booking.TaxKey != "" {
+		t.Errorf("expected blank template booking, got %+v", booking)
+	}
+	if booking.Amount != 119 || booking.DocumentNumber != "RE-7" || booking.BookingText != "Muster GmbH RE-7" {
+		t.Errorf("template lost invoice data: %+v", booking)
+	}
+	if len(booking.Warnings) == 0 {
+		t.Error("expected a warning that the AI booking was skipped")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.generateBookingOrTemplate(ctx, invoice); err == nil {
+		t.Error("expected cancellation to fail instead of returning a template")
+	}
+}
@@ -0,0 +1,61 @@
+package booking
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"tools/pkg/models"
+)
+
+// defaultTypeConfidenceMin is the type confidence below which a detected PAYABLE/RECEIVABLE needs confirmation
+const defaultTypeConfidenceMin = 0.7
+
+// TypeConfidenceMinFromEnv returns the type confidence below which a detected invoice type needs
+// confirmation: TYPE_CONFIDENCE_MIN, or 0.7 if the variable is unset
+func TypeConfidenceMinFromEnv() (float32, error) {
+	value := os.Getenv("TYPE_CONFIDENCE_MIN")
+	if value == "" {
+		return defaultTypeConfidenceMin, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 32)
+	if err != nil || parsed < 0 || parsed > 1 {
+		return 0, fmt.Errorf("invalid TYPE_CONFIDENCE_MIN %q (must be between 0 and 1)", value)
+	}
+	return float32(parsed), nil
+}
+
+// typeConfidenceWarning returns a warning when completion determined the invoice type with a confidence
+// below minimum and the user has not confirmed it. A wrong type flips the whole booking, so a guess must not
+// pass silently. detectedType is the type before any override; an override equal to it counts as confirmation.
+// Internal invoices have no detected type and are only booked with an override, which is noted instead.
+// Returns "" if no warning is needed.
+func typeConfidenceWarning(invoice *models.Invoice, detectedType, typeOverride string, confidence map[string]float32, minimum float32) string {
+	if invoice.Internal {
+		if typeOverride == "" {
+			return ""
+		}
+		return fmt.Sprintf("Lieferant und Kunde sind beide unser Unternehmen, Rechnungstyp per --type auf %s gesetzt", typeOverride)
+	}
+
+	typeConfidence, ok := confidence["type"]
+	if !ok || typeConfidence >= minimum {
+		return ""
+	}
+
+	reasoning := invoice.TypeReasoning
+	if reasoning == "" {
+		reasoning = "keine Begründung"
+	}
+
+	switch typeOverride {
+	case "":
+		return fmt.Sprintf("Rechnungstyp %s unsicher erkannt (Konfidenz %.2f < %.2f): %s - bitte mit --type bestätigen",
+			detectedType, typeConfidence, minimum, reasoning)
+	case detectedType:
+		return ""
+	default:
+		return fmt.Sprintf("Rechnungstyp per --type auf %s gesetzt, erkannt wurde unsicher %s (Konfidenz %.2f): %s",
+			typeOverride, detectedType, typeConfidence, reasoning)
+	}
+}
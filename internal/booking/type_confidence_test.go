@@ -0,0 +1,75 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+
+	"tools/pkg/models"
+)
+
+func TestTypeConfidenceWarning(t *testing.T) {
+	invoice := &models.Invoice{TypeReasoning: "Unser Firmenname steht als Empfänger"}
+
+	tests := []struct {
+		name         string
+		override     string
+		confidence   map[string]float32
+		wantWarning  bool
+		wantContains string
+	}{
+		{"type not determined by completion", "", map[string]float32{}, false, ""},
+		{"confident detection", "", map[string]float32{"type": 0.95}, false, ""},
+		{"uncertain without override", "", map[string]float32{"type": 0.55}, true, "bitte mit --type bestätigen"},
+		{"uncertain but confirmed", "PAYABLE", map[string]float32{"type": 0.55}, false, ""},
+		{"uncertain and overridden", "RECEIVABLE", map[string]float32{"type": 0.55}, true, "per --type auf RECEIVABLE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warning := typeConfidenceWarning(invoice, "PAYABLE", tt.override, tt.confidence, 0.7)
+			if (warning != "") != tt.wantWarning {
+				t.Fatalf("warning = %q, want warning: %v", warning, tt.wantWarning)
+			}
+			if tt.wantWarning {
+				if !strings.Contains(warning, tt.wantContains) {
+					t.Errorf("warning %q does not contain %q", warning, tt.wantContains)
+				}
+				if !strings.Contains(warning, invoice.TypeReasoning) {
+					t.Errorf("warning %q does not include the reasoning", warning)
+				}
+			}
+		})
+	}
+}
+
+func TestTypeConfidenceWarningInternalInvoice(t *testing.T) {
+	invoice := &models.Invoice{Internal: true}
+	confidence := map[string]float32{"type": 0.1}
+
+	if warning := typeConfidenceWarning(invoice, "", "", confidence, 0.7); warning != "" {
+		t.Errorf("expected no warning without override, got %q", warning)
+	}
+	warning := typeConfidenceWarning(invoice, "", "RECEIVABLE", confidence, 0.7)
+	if !strings.Contains(warning, "beide unser Unternehmen") || !strings.Contains(warning, "RECEIVABLE") {
+		t.Errorf("warning = %q, want a note on the internal invoice and the override", warning)
+	}
+}
+
+func TestTypeConfidenceMinFromEnv(t *testing.T) {
+	t.Setenv("TYPE_CONFIDENCE_MIN", "")
+	if got, err := TypeConfidenceMinFromEnv(); err != nil || got != defaultTypeConfidenceMin {
+		t.Errorf("unset: got %.2f, %v, want %.2f", got, err, defaultTypeConfidenceMin)
+	}
+
+	t.Setenv("TYPE_CONFIDENCE_MIN", "0.85")
+	if got, err := TypeConfidenceMinFromEnv(); err != nil || got != 0.85 {
+		t.Errorf("0.85: got %.2f, %v", got, err)
+	}
+
+	for _, value := range []string{"1.5", "-0.1", "hoch"} {
+		t.Setenv("TYPE_CONFIDENCE_MIN", value)
+		if _, err := TypeConfidenceMinFromEnv(); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package booking
+
+import (
+	"math"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// vatAccount is the SKR03 account a VAT amount is posted to
+type vatAccount struct {
+	number string
+	name   string
+}
+
+// vatAccounts maps a VAT rate in percent to the SKR03 account of its explicit VAT posting per invoice
+// type: Vorsteuer for payables, Umsatzsteuer for receivables
+var vatAccounts = map[string]map[float64]vatAccount{
+	"PAYABLE": {
+		7:  {"1571", "Abziehbare Vorsteuer 7 %"},
+		19: {"1576", "Abziehbare Vorsteuer 19 %"},
+	},
+	"RECEIVABLE": {
+		7:  {"1771", "Umsatzsteuer 7 %"},
+		19: {"1776", "Umsatzsteuer 19 %"},
+	},
+}
+
+// vatPart is the net and VAT amount in cents booked at one VAT rate
+type vatPart struct {
+	rate float64
+	net  int64
+	vat  int64
+}
+
+// vatPostings breaks the booking down into balanced posting lines with the VAT on its own account: per
+// VAT rate the net amount on the expense or revenue account and the VAT on the Vorsteuer or
+// Umsatzsteuer account, and the gross amount on the creditor or debitor side. Negative amounts, as on
+// credit notes, are posted to the opposite side. It returns nil if an account is missing or a VAT
+// amount has no VAT account, e.g. under an unknown tax key, which is then left to post the VAT.
+func vatPostings(booking *services.DATEVBooking, invoice *models.Invoice) []services.Posting {
+	accounts, ok := vatAccounts[invoice.Type]
+	if !ok || booking.DebitAccount == "" || booking.CreditAccount == "" {
+		return nil
+	}
+
+	var parts []vatPart
+	for _, split := range booking.Splits {
+		parts = append(parts, vatPart{
+			rate: split.VATRate,
+			net:  int64(math.Round(split.NetAmount * 100)),
+			vat:  int64(math.Round(split.VATAmount * 100)),
+		})
+	}
+	if len(parts) == 0 {
+		rate, known := taxKeyRates[booking.TaxKey]
+		if !known && invoice.VATAmount != 0 {
+			return nil
+		}
+		parts = append(parts, vatPart{rate: rate, net: invoice.GrossAmount - invoice.VATAmount, vat: invoice.VATAmount})
+	}
+
+	// Net and VAT lines go on the side of the expense or revenue account, the gross line on the other
+	netSide, grossSide := services.PostingDebit, services.PostingCredit
+	netAccount, grossAccount := booking.DebitAccount, booking.CreditAccount
+	netAccountName, grossAccountName := booking.DebitAccountName, booking.CreditAccountName
+	if invoice.Type == "RECEIVABLE" {
+		netSide, grossSide = grossSide, netSide
+		netAccount, grossAccount = grossAccount, netAccount
+		netAccountName, grossAccountName = grossAccountName, netAccountName
+	}
+
+	var postings []services.Posting
+	var gross int64
+	for _, part := range parts {
+		postings = append(postings, newPosting(netAccount, netAccountName, netSide, part.net, part.rate, false))
+		gross += part.net
+		if part.vat == 0 {
+			continue
+		}
+		account, ok := accounts[part.rate]
+		if !ok {
+			return nil
+		}
+		postings = append(postings, newPosting(account.number, account.name, netSide, part.vat, part.rate, true))
+		gross += part.vat
+	}
+	postings = append(postings, newPosting(grossAccount, grossAccountName, grossSide, gross, 0, false))
+
+	return postings
+}
+
+// newPosting creates a posting line of the amount in cents, moving a negative amount to the other side
+func newPosting(account, name, side string, cents int64, rate float64, vat bool) services.Posting {
+	if cents < 0 {
+		cents = -cents
+		side = oppositeSide(side)
+	}
+	return services.Posting{
+		Account:     account,
+		AccountName: name,
+		Side:        side,
+		Amount:      float64(cents) / 100,
+		VATRate:     rate,
+		VAT:         vat,
+	}
+}
+
+// oppositeSide returns Haben for Soll and Soll for Haben
+func oppositeSide(side string) string {
+	if side == services.PostingDebit {
+		return services.PostingCredit
+	}
+	return services.PostingDebit
+}
@@ -0,0 +1,116 @@
+package booking
+
+import (
+	"testing"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// postingTotals sums the Soll and Haben amounts of posting lines in cents
+func postingTotals(postings []services.Posting) (debit, credit int64) {
+	for _, posting := range postings {
+		cents := int64(posting.Amount*100 + 0.5)
+		if posting.Side == services.PostingDebit {
+			debit += cents
+		} else {
+			credit += cents
+		}
+	}
+	return debit, credit
+}
+
+func TestVATPostingsPayable(t *testing.T) {
+	invoice := &models.Invoice{Type: "PAYABLE", NetAmount: 10000, VATAmount: 1900, GrossAmount: 11900}
+	booking := &services.DATEVBooking{DebitAccount: "4930", CreditAccount: "70001", TaxKey: "9"}
+
+	postings := vatPostings(booking, invoice)
+	want := []services.Posting{
+		{Account: "4930", Side: "S", Amount: 100, VATRate: 19},
+		{Account: "1576", AccountName: "Abziehbare Vorsteuer 19 %", Side: "S", Amount: 19, VATRate: 19, VAT: true},
+		{Account: "70001", Side: "H", Amount: 119},
+	}
+	if len(postings) != len(want) {
+		t.Fatalf("vatPostings() = %+v, want %+v", postings, want)
+	}
+	for i := range want {
+		if postings[i] != want[i] {
+			t.Errorf("posting %d = %+v, want %+v", i, postings[i], want[i])
+		}
+	}
+}
+
+func TestVATPostingsReceivableSplit(t *testing.T) {
+	invoice := &models.Invoice{Type: "RECEIVABLE", NetAmount: 3000, VATAmount: 330, GrossAmount: 3330}
+	booking := &services.DATEVBooking{
+		DebitAccount:  "10001",
+		CreditAccount: "8400",
+		Splits: []services.BookingSplit{
+			{TaxKey: "2", VATRate: 7, NetAmount: 20, VATAmount: 1.40, Amount: 21.40},
+			{TaxKey: "3", VATRate: 19, NetAmount: 10, VATAmount: 1.90, Amount: 11.90},
+		},
+	}
+
+	postings := vatPostings(booking, invoice)
+	if len(postings) != 5 {
+		t.Fatalf("vatPostings() = %+v, want 5 lines", postings)
+	}
+	if p := postings[1]; p.Account != "1771" || p.Side != "H" || p.Amount != 1.40 || !p.VAT {
+		t.Errorf("7%% VAT line = %+v", p)
+	}
+	if p := postings[3]; p.Account != "1776" || p.Side != "H" || p.Amount != 1.90 {
+		t.Errorf("19%% VAT line = %+v", p)
+	}
+	if p := postings[4]; p.Account != "10001" || p.Side != "S" || p.Amount != 33.30 {
+		t.Errorf("gross line = %+v", p)
+	}
+	if debit, credit := postingTotals(postings); debit != credit {
+		t.Errorf("postings do not balance: Soll %d, Haben %d", debit, credit)
+	}
+}
+
+func TestVATPostingsCreditNote(t *testing.T) {
+	invoice := &models.Invoice{Type: "PAYABLE", NetAmount: -5000, VATAmount: -350, GrossAmount: -5350}
+	booking := &services.DATEVBooking{DebitAccount: "3400", CreditAccount: "70001", TaxKey: "5"}
+
+	postings := vatPostings(booking, invoice)
+	if len(postings) != 3 {
+		t.Fatalf("vatPostings() = %+v, want 3 lines", postings)
+	}
+	for i, side := range []string{"H", "H", "S"} {
+		if postings[i].Side != side || postings[i].Amount < 0 {
+			t.Errorf("posting %d = %+v, want side %s with a positive amount", i, postings[i], side)
+		}
+	}
+	if postings[1].Account != "1571" {
+		t.Errorf("VAT account = %s, want 1571", postings[1].Account)
+	}
+}
+
+func TestVATPostingsWithoutVAT(t *testing.T) {
+	invoice := &models.Invoice{Type: "PAYABLE", NetAmount: 2500, GrossAmount: 2500}
+	booking := &services.DATEVBooking{DebitAccount: "4360", CreditAccount: "70001", TaxKey: "0"}
+
+	postings := vatPostings(booking, invoice)
+	if len(postings) != 2 || postings[0].Amount != 25 || postings[1].Amount != 25 {
+		t.Errorf("vatPostings() = %+v, want one net and one gross line of 25.00", postings)
+	}
+}
+
+func TestVATPostingsUnsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		booking *services.DATEVBooking
+	}{
+		{"unknown tax key", &services.DATEVBooking{DebitAccount: "4930", CreditAccount: "70001", TaxKey: "94"}},
+		{"missing account", &services.DATEVBooking{DebitAccount: "4930", TaxKey: "9"}},
+	}
+	invoice := &models.Invoice{Type: "PAYABLE", NetAmount: 10000, VATAmount: 1900, GrossAmount: 11900}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if postings := vatPostings(tt.booking, invoice); postings != nil {
+				t.Errorf("vatPostings() = %+v, want nil", postings)
+			}
+		})
+	}
+}
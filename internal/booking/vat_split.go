@@ -0,0 +1,199 @@
+package booking
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// vatRateTaxKeys maps a VAT rate in percent to the SKR03 tax key per invoice type, mirroring taxKeyRates
+var vatRateTaxKeys = map[string]map[float64]string{
+	"PAYABLE":    {0: "0", 7: "5", 19: "9"},
+	"RECEIVABLE": {0: "0", 7: "2", 19: "3"},
+}
+
+// splitByVATRate groups the invoice's line items by VAT rate and returns one split per rate. It returns
+// nil unless the lines carry at least two distinct rates. Lines without a printed rate are distributed
+// over the rate groups in proportion to their net amounts, and the group nets are scaled to the header
+// net amount when the invoice has one. VAT is computed per group; a rounding residual against the
+// header VAT is distributed over the groups, see tieOutVAT.
+func splitByVATRate(invoice *models.Invoice) []services.BookingSplit {
+	taxKeys, ok := vatRateTaxKeys[invoice.Type]
+	if !ok {
+		return nil
+	}
+
+	netByRate := make(map[float64]int64)
+	var unassigned int64
+	for _, item := range invoice.LineItems {
+		if item.VATRate == nil {
+			unassigned += item.NetAmount
+			continue
+		}
+		netByRate[*item.VATRate] += item.NetAmount
+	}
+	if len(netByRate) < 2 {
+		return nil
+	}
+
+	rates := make([]float64, 0, len(netByRate))
+	for rate := range netByRate {
+		if _, known := taxKeys[rate]; !known {
+			return nil
+		}
+		rates = append(rates, rate)
+	}
+	sort.Float64s(rates)
+
+	nets := make([]int64, len(rates))
+	var linesTotal int64
+	for i, rate := range rates {
+		nets[i] = netByRate[rate]
+		linesTotal += nets[i]
+	}
+	if linesTotal == 0 {
+		return nil
+	}
+
+	// Lines without a rate follow the split of the lines that have one
+	target := linesTotal + unassigned
+	if invoice.NetAmount != 0 {
+		target = invoice.NetAmount
+	}
+	nets = allocateProportionally(target, nets)
+
+	vats := make([]int64, len(rates))
+	for i, rate := range rates {
+		vats[i] = int64(math.Round(float64(nets[i]) * rate / 100))
+	}
+	vats = tieOutVAT(invoice.VATAmount, vats)
+
+	splits := make([]services.BookingSplit, 0, len(rates))
+	for i, rate := range rates {
+		splits = append(splits, services.BookingSplit{
+			Amount:    float64(nets[i]+vats[i]) / 100,
+			TaxKey:    taxKeys[rate],
+			VATRate:   rate,
+			NetAmount: float64(nets[i]) / 100,
+			VATAmount: float64(vats[i]) / 100,
+		})
+	}
+
+	return splits
+}
+
+// vatResidualTolerance is the largest difference in cents between the summed split VAT and the
+// header VAT that rounding explains: half a cent per split plus half a cent of the header itself,
+// rounded up to a cent per split
+func vatResidualTolerance(splits int) int64 {
+	return int64(splits)
+}
+
+// tieOutVAT distributes a rounding residual between the per-split VAT and the header VAT over the
+// splits in proportion to their VAT (largest remainder method), so that the split bookings sum to the
+// header exactly. A larger difference, e.g. from a missed line, is not fudged: the VAT is returned
+// unchanged and splitVATWarning flags it.
+func tieOutVAT(headerVAT int64, vats []int64) []int64 {
+	var sum int64
+	for _, vat := range vats {
+		sum += vat
+	}
+	diff := headerVAT - sum
+	if headerVAT == 0 || sum == 0 || diff == 0 || abs64(diff) > vatResidualTolerance(len(vats)) {
+		return vats
+	}
+	return allocateProportionally(headerVAT, vats)
+}
+
+// splitVATWarning returns a warning if the VAT of the splits differs from the header VAT by more than
+// rounding explains; empty if they tie out or there are no splits
+func splitVATWarning(invoice *models.Invoice, splits []services.BookingSplit) string {
+	if len(splits) == 0 || invoice.VATAmount == 0 {
+		return ""
+	}
+
+	var sum int64
+	for _, split := range splits {
+		sum += int64(math.Round(split.VATAmount * 100))
+	}
+	diff := invoice.VATAmount - sum
+	if abs64(diff) <= vatResidualTolerance(len(splits)) {
+		return ""
+	}
+	return fmt.Sprintf("USt der Aufteilung nach Steuersätzen (%.2f) weicht um %.2f von der USt laut Beleg (%.2f) ab, evtl. Position übersehen - bitte prüfen",
+		float64(sum)/100, float64(diff)/100, float64(invoice.VATAmount)/100)
+}
+
+// allocateProportionally distributes total over the weights using the largest remainder method, so
+// the parts always sum to total. The weights must not sum to zero; signs are taken from total.
+func allocateProportionally(total int64, weights []int64) []int64 {
+	var weightSum int64
+	for _, weight := range weights {
+		weightSum += abs64(weight)
+	}
+
+	sign := int64(1)
+	if total < 0 {
+		sign, total = -1, -total
+	}
+
+	parts := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	var allocated int64
+	for i, weight := range weights {
+		exact := float64(total) * float64(abs64(weight)) / float64(weightSum)
+		parts[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(parts[i])
+		allocated += parts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for i := 0; allocated < total; i++ {
+		parts[order[i%len(order)]]++
+		allocated++
+	}
+
+	for i := range parts {
+		parts[i] *= sign
+	}
+	return parts
+}
+
+// abs64 returns the absolute value of an int64
+func abs64(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// inferredVATWarning returns a warning if net and VAT were not extracted but back-calculated from the
+// gross amount, naming the rate and where it came from; empty if they were extracted
+func inferredVATWarning(invoice *models.Invoice) string {
+	var source string
+	switch invoice.VATInferredFrom {
+	case "":
+		return ""
+	case models.VATRateFromVendor:
+		source = "Standardsteuersatz laut Lieferantenstamm"
+	case models.VATRateFromText:
+		source = "im Beleg genannter Steuersatz"
+	default:
+		source = "angenommener Steuersatz"
+	}
+
+	rate := 0.0
+	if invoice.NetAmount != 0 {
+		rate = math.Round(float64(invoice.VATAmount) * 100 / float64(invoice.NetAmount))
+	}
+	return fmt.Sprintf("Netto und USt nicht aus dem Beleg gelesen, sondern aus dem Bruttobetrag abgeleitet (%g %%, %s) - bitte prüfen", rate, source)
+}
@@ -0,0 +1,151 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+
+	"tools/pkg/models"
+)
+
+func rate(value float64) *float64 {
+	return &value
+}
+
+func TestSplitByVATRate(t *testing.T) {
+	invoice := &models.Invoice{
+		Type:      "PAYABLE",
+		NetAmount: 15000,
+		LineItems: []models.LineItem{
+			{Description: "Kaffee", NetAmount: 5000, VATRate: rate(7)},
+			{Description: "Geschirr", NetAmount: 5000, VATRate: rate(19)},
+			{Description: "Lieferung", NetAmount: 5000},
+		},
+	}
+
+	splits := splitByVATRate(invoice)
+	if len(splits) != 2 {
+		t.Fatalf("expected 2 splits, got %+v", splits)
+	}
+
+	if s := splits[0]; s.TaxKey != "5" || s.NetAmount != 75 || s.VATAmount != 5.25 || s.Amount != 80.25 {
+		t.Errorf("7%% split = %+v", s)
+	}
+	if s := splits[1]; s.TaxKey != "9" || s.NetAmount != 75 || s.VATAmount != 14.25 || s.Amount != 89.25 {
+		t.Errorf("19%% split = %+v", s)
+	}
+}
+
+func TestSplitByVATRateReceivable(t *testing.T) {
+	invoice := &models.Invoice{
+		Type: "RECEIVABLE",
+		LineItems: []models.LineItem{
+			{NetAmount: 1000, VATRate: rate(19)},
+			{NetAmount: 2000, VATRate: rate(7)},
+		},
+	}
+
+	splits := splitByVATRate(invoice)
+	if len(splits) != 2 || splits[0].TaxKey != "2" || splits[1].TaxKey != "3" {
+		t.Fatalf("unexpected splits %+v", splits)
+	}
+}
+
+func TestSplitByVATRateSingleRate(t *testing.T) {
+	invoice := &models.Invoice{
+		Type: "PAYABLE",
+		LineItems: []models.LineItem{
+			{NetAmount: 1000, VATRate: rate(19)},
+			{NetAmount: 2000},
+		},
+	}
+
+	if splits := splitByVATRate(invoice); splits != nil {
+		t.Errorf("expected no split for single-rate invoice, got %+v", splits)
+	}
+}
+
+func TestAllocateProportionally(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int64
+		weights []int64
+		want    []int64
+	}{
+		{"even", 300, []int64{1, 1, 1}, []int64{100, 100, 100}},
+		{"remainder to largest fraction", 100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{"weighted", 1001, []int64{700, 300}, []int64{701, 300}},
+		{"credit note", -100, []int64{-1, -1, -1}, []int64{-34, -33, -33}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allocateProportionally(tt.total, tt.weights)
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("allocateProportionally(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestInferredVATWarning(t *testing.T) {
+	extracted := &models.Invoice{NetAmount: 10000, VATAmount: 1900, GrossAmount: 11900}
+	if warning := inferredVATWarning(extracted); warning != "" {
+		t.Errorf("expected no warning for extracted amounts, got %q", warning)
+	}
+
+	inferred := &models.Invoice{NetAmount: 10000, VATAmount: 1900, GrossAmount: 11900, VATInferredFrom: models.VATRateFromVendor}
+	if warning := inferredVATWarning(inferred); !strings.Contains(warning, "19 %") || !strings.Contains(warning, "Lieferantenstamm") {
+		t.Errorf("unexpected warning %q", warning)
+	}
+
+	foreign := &models.Invoice{NetAmount: 5000, GrossAmount: 5000, VATInferredFrom: models.VATRateFromVendor}
+	if warning := inferredVATWarning(foreign); !strings.Contains(warning, "0 %") {
+		t.Errorf("unexpected warning %q", warning)
+	}
+}
+
+func TestSplitByVATRateTiesOutHeaderVAT(t *testing.T) {
+	// Per-split rounding gives 0,70 + 19,00 = 19,70; the header shows 19,71
+	invoice := &models.Invoice{
+		Type:      "PAYABLE",
+		NetAmount: 11000,
+		VATAmount: 1971,
+		LineItems: []models.LineItem{
+			{NetAmount: 1000, VATRate: rate(7)},
+			{NetAmount: 10000, VATRate: rate(19)},
+		},
+	}
+
+	splits := splitByVATRate(invoice)
+	if len(splits) != 2 {
+		t.Fatalf("expected 2 splits, got %+v", splits)
+	}
+	if splits[0].VATAmount != 0.70 || splits[1].VATAmount != 19.01 {
+		t.Errorf("split VAT = %.2f, %.2f, want 0.70, 19.01", splits[0].VATAmount, splits[1].VATAmount)
+	}
+	if warning := splitVATWarning(invoice, splits); warning != "" {
+		t.Errorf("unexpected warning for tied-out VAT: %q", warning)
+	}
+}
+
+func TestSplitByVATRateFlagsLargeVATDifference(t *testing.T) {
+	// A missed line leaves the split VAT far below the header
+	invoice := &models.Invoice{
+		Type:      "PAYABLE",
+		VATAmount: 5000,
+		LineItems: []models.LineItem{
+			{NetAmount: 1000, VATRate: rate(7)},
+			{NetAmount: 10000, VATRate: rate(19)},
+		},
+	}
+
+	splits := splitByVATRate(invoice)
+	if splits[0].VATAmount != 0.70 || splits[1].VATAmount != 19.00 {
+		t.Errorf("split VAT changed despite large difference: %+v", splits)
+	}
+	if warning := splitVATWarning(invoice, splits); !strings.Contains(warning, "30.30") {
+		t.Errorf("splitVATWarning() = %q, want difference 30.30", warning)
+	}
+}
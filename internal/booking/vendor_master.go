@@ -0,0 +1,67 @@
+package booking
+
+import (
+	"strings"
+
+	"tools/internal/vendors"
+	"tools/pkg/models"
+)
+
+// vendorVATRate returns a lookup of the default VAT rate of an invoice's counterparty (vendor of
+// payables, customer of receivables) in the vendor master, for splitting gross-only invoices
+func vendorVATRate(master *vendors.Store) func(invoice *models.Invoice) (float64, bool) {
+	return func(invoice *models.Invoice) (float64, bool) {
+		if invoice.Type == "RECEIVABLE" {
+			return master.DefaultVATRate(invoice.Customer, invoice.CustomerVATID)
+		}
+		return master.DefaultVATRate(invoice.Vendor, invoice.VendorVATID)
+	}
+}
+
+// canonicalizeCounterparty replaces the counterparty name (vendor of payables, customer of receivables)
+// with its preferred vendor master name and attaches the vendor ID. Unknown counterparties are added
+// to the master for review. Without a vendor master the invoice is left unchanged.
+func (s *SKR03BookingService) canonicalizeCounterparty(invoice *models.Invoice) {
+	if s.vendorMaster == nil {
+		return
+	}
+
+	name, vatID := &invoice.Vendor, invoice.VendorVATID
+	if invoice.Type == "RECEIVABLE" {
+		name, vatID = &invoice.Customer, invoice.CustomerVATID
+	}
+
+	// A VAT ID alone can find a known vendor but is no name for a new one
+	if strings.TrimSpace(*name) == "" {
+		match, ok := s.vendorMaster.Lookup("", vatID)
+		if !ok {
+			return
+		}
+		*name = match.Vendor.Name
+		invoice.PartnerID = match.Vendor.ID
+		return
+	}
+
+	match, err := s.vendorMaster.Resolve(*name, vatID)
+	if err != nil {
+		s.log.Warn().Err(err).Str("counterparty", *name).Msg("Vendor master lookup failed, keeping extracted name")
+		return
+	}
+
+	if match.By == "new" {
+		s.log.Info().
+			Str("vendor_id", match.Vendor.ID).
+			Str("name", match.Vendor.Name).
+			Msg("Added new vendor to vendor master for review")
+	} else if match.Vendor.Name != *name {
+		s.log.Info().
+			Str("vendor_id", match.Vendor.ID).
+			Str("extracted_name", *name).
+			Str("canonical_name", match.Vendor.Name).
+			Str("matched_by", match.By).
+			Msg("Counterparty name canonicalized from vendor master")
+	}
+
+	*name = match.Vendor.Name
+	invoice.PartnerID = match.Vendor.ID
+}
@@ -0,0 +1,139 @@
+// Package cache stores extraction results on disk, keyed by the SHA-256 hash of the processed
+// document, so re-running a command on the same PDF does not call Document AI or Vision again.
+//
+// The cache is enabled with EXTRACTION_CACHE=true and lives in EXTRACTION_CACHE_DIR (default:
+// tax-ai-tools/extractions in the user cache directory). Entries never expire; commands offer
+// --force to re-extract a single file and `tools cache clear` wipes the whole directory.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Store reads and writes cached results as JSON files below one directory
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store in dir; the directory is created on the first write
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// NewStoreFromEnv returns the configured store, or nil if EXTRACTION_CACHE is not enabled
+func NewStoreFromEnv() (*Store, error) {
+	if os.Getenv("EXTRACTION_CACHE") != "true" {
+		return nil, nil
+	}
+
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(dir), nil
+}
+
+// DefaultDir returns EXTRACTION_CACHE_DIR or the default directory in the user cache directory
+func DefaultDir() (string, error) {
+	const op = "DefaultDir"
+
+	if dir := os.Getenv("EXTRACTION_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to determine user cache directory: %w", op, err)
+	}
+	return filepath.Join(userCacheDir, "tax-ai-tools", "extractions"), nil
+}
+
+// Dir returns the directory the store writes to
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Key derives the cache key for a document. variant distinguishes results of the same document
+// that depend on options, e.g. the selected pages; it may be empty.
+func Key(data []byte, variant string) string {
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+	if variant != "" {
+		key += "-" + variant
+	}
+	return key
+}
+
+// Load decodes the entry for kind and key into v. found is false if there is no entry.
+func (s *Store) Load(kind, key string, v interface{}) (found bool, err error) {
+	const op = "Load"
+
+	data, err := os.ReadFile(s.path(kind, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: failed to read cache entry: %w", op, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("%s: failed to decode cache entry %s/%s: %w", op, kind, key, err)
+	}
+	return true, nil
+}
+
+// Save writes v as the entry for kind and key, replacing an existing entry
+func (s *Store) Save(kind, key string, v interface{}) error {
+	const op = "Save"
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%s: failed to encode cache entry: %w", op, err)
+	}
+
+	path := s.path(kind, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("%s: failed to create cache directory: %w", op, err)
+	}
+
+	// Write to a temporary file first so concurrent readers never see a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("%s: failed to create cache entry: %w", op, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("%s: failed to write cache entry: %w", op, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("%s: failed to write cache entry: %w", op, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("%s: failed to store cache entry: %w", op, err)
+	}
+	return nil
+}
+
+// Clear removes the cache directory with all entries
+func (s *Store) Clear() error {
+	const op = "Clear"
+
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("%s: failed to remove cache directory %s: %w", op, s.dir, err)
+	}
+	return nil
+}
+
+// path returns the file of an entry
+func (s *Store) path(kind, key string) string {
+	return filepath.Join(s.dir, kind, key+".json")
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	Text string
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "cache"))
+	key := Key([]byte("%PDF-1.4 test"), "")
+
+	var got entry
+	found, err := store.Load("ocr", key, &got)
+	if err != nil || found {
+		t.Fatalf("expected miss on empty cache, got found=%v err=%v", found, err)
+	}
+
+	if err := store.Save("ocr", key, entry{Text: "first"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := store.Save("ocr", key, entry{Text: "second"}); err != nil {
+		t.Fatalf("overwriting Save failed: %v", err)
+	}
+
+	found, err = store.Load("ocr", key, &got)
+	if err != nil || !found || got.Text != "second" {
+		t.Fatalf("Load = %+v, found=%v, err=%v; want overwritten entry", got, found, err)
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if found, _ := store.Load("ocr", key, &got); found {
+		t.Error("expected miss after Clear")
+	}
+}
+
+func TestKey(t *testing.T) {
+	data := []byte("invoice")
+	if Key(data, "") == Key([]byte("other"), "") {
+		t.Error("different documents must have different keys")
+	}
+	if Key(data, "") == Key(data, "p1") {
+		t.Error("variants must have different keys")
+	}
+	if Key(data, "p1") != Key(data, "p1") {
+		t.Error("keys must be deterministic")
+	}
+}
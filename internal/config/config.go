@@ -9,7 +9,9 @@ import (
 
 type Config struct {
 	// OpenAI Configuration
-	OpenAIAPIKey string
+	OpenAIAPIKey  string
+	LLMProvider   string // openai, azure or local
+	OpenAIBaseURL string
 
 	// Google Cloud Configuration
 	GoogleCloudProject    string
@@ -29,7 +31,10 @@ type Config struct {
 	GCSOutputFolder string
 
 	// Chart of Accounts Configuration
-	ChartOfAccounts string
+	ChartOfAccounts string // two-digit form as in --skr, e.g. "03" for SKR03
+
+	// Booking Configuration
+	BookingDatePolicy string // issue_date or service_date (Leistungsdatum)
 
 	// Logging Configuration
 	LogLevel      string
@@ -38,9 +43,27 @@ type Config struct {
 	LogOutput     string
 }
 
+// chartOfAccountsParser normalizes CHART_OF_ACCOUNTS and rejects charts without a booking
+// service. The booking package registers it, so config does not depend on booking.
+var chartOfAccountsParser func(string) (string, error)
+
+// RegisterChartOfAccountsParser sets the function Load uses to normalize and validate
+// CHART_OF_ACCOUNTS
+func RegisterChartOfAccountsParser(parse func(string) (string, error)) {
+	chartOfAccountsParser = parse
+}
+
+// Load reads the configuration from the environment. Values from a config file take effect
+// through ApplyFile, which must run first.
+//
+// An unsupported CHART_OF_ACCOUNTS fails without a config. If only required settings are
+// missing, the config is returned together with the error, so commands that do without them
+// still see the other settings.
 func Load() (*Config, error) {
 	config := &Config{
 		OpenAIAPIKey:               getEnv("OPENAI_API_KEY", ""),
+		LLMProvider:                getEnv("LLM_PROVIDER", ""),
+		OpenAIBaseURL:              getEnv("OPENAI_BASE_URL", ""),
 		GoogleCloudProject:         getEnv("GOOGLE_CLOUD_PROJECT", ""),
 		GCSSourceBucket:           getEnv("GCS_SOURCE_BUCKET", ""),
 		GCSOutputBucket:           getEnv("GCS_OUTPUT_BUCKET", ""),
@@ -52,22 +75,33 @@ func Load() (*Config, error) {
 		GoogleSheetWorksheet:      getEnv("GOOGLE_SHEET_WORKSHEET", "DATEV_Bookings"),
 		GCSSourceFolder:           getEnv("GCS_SOURCE_FOLDER", ""),
 		GCSOutputFolder:           getEnv("GCS_OUTPUT_FOLDER", ""),
-		ChartOfAccounts:           getEnv("CHART_OF_ACCOUNTS", "SKR04"),
+		ChartOfAccounts:           getEnv("CHART_OF_ACCOUNTS", "SKR03"),
+		BookingDatePolicy:         BookingDatePolicyFromEnv(),
 		LogLevel:                  getEnv("LOG_LEVEL", "info"),
 		LogFormat:                 getEnv("LOG_FORMAT", "console"),
 		LogTimeFormat:             getEnv("LOG_TIME_FORMAT", "2006-01-02T15:04:05Z07:00"),
 		LogOutput:                 getEnv("LOG_OUTPUT", "stdout"),
 	}
 
+	if chartOfAccountsParser != nil {
+		chart, err := chartOfAccountsParser(config.ChartOfAccounts)
+		if err != nil {
+			return nil, fmt.Errorf("config validation failed: CHART_OF_ACCOUNTS: %w", err)
+		}
+		config.ChartOfAccounts = chart
+	}
+
 	if err := config.validate(); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+		return config, fmt.Errorf("config validation failed: %w", err)
 	}
 
 	return config, nil
 }
 
 func (c *Config) validate() error {
-	if c.OpenAIAPIKey == "" {
+	// Local OpenAI-compatible servers usually run without an API key
+	isLocal := c.LLMProvider == "local" || (c.LLMProvider == "" && c.OpenAIBaseURL != "")
+	if c.OpenAIAPIKey == "" && !isLocal {
 		return fmt.Errorf("OPENAI_API_KEY is required")
 	}
 	if c.GoogleCloudProject == "" {
@@ -98,6 +132,12 @@ func (c *Config) GetLoggerConfig() logger.LogConfig {
 	}
 }
 
+// BookingDatePolicyFromEnv returns BOOKING_DATE_POLICY, or issue_date if unset. Commands pass it to the
+// booking service, which rejects other values.
+func BookingDatePolicyFromEnv() string {
+	return getEnv("BOOKING_DATE_POLICY", "issue_date")
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
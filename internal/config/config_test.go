@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLoadChartOfAccounts(t *testing.T) {
+	// booking registers the real parser; config cannot import it in its tests
+	defer RegisterChartOfAccountsParser(chartOfAccountsParser)
+	RegisterChartOfAccountsParser(func(value string) (string, error) {
+		if chart := strings.TrimPrefix(strings.ToUpper(value), "SKR"); chart == "03" {
+			return chart, nil
+		}
+		return "", fmt.Errorf("unsupported chart of accounts %q", value)
+	})
+
+	tests := []struct {
+		name      string
+		chart     string
+		required  bool
+		wantChart string
+		wantErr   bool
+	}{
+		{name: "default", required: true, wantChart: "03"},
+		{name: "normalized", chart: "skr03", required: true, wantChart: "03"},
+		{name: "unsupported", chart: "SKR04", required: true, wantErr: true},
+		{name: "unsupported without required settings", chart: "SKR04", wantErr: true},
+		{name: "required settings missing", chart: "SKR03", wantChart: "03", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CHART_OF_ACCOUNTS", tt.chart)
+			for _, key := range []string{"OPENAI_API_KEY", "GOOGLE_CLOUD_PROJECT", "GCS_SOURCE_BUCKET", "GCS_OUTPUT_BUCKET", "DOCUMENT_AI_PROCESSOR_ID", "GOOGLE_SHEET_URL"} {
+				value := ""
+				if tt.required {
+					value = "set"
+				}
+				t.Setenv(key, value)
+			}
+
+			cfg, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantChart == "" {
+				if cfg != nil {
+					t.Errorf("Load() = %+v, want no config for an unsupported chart", cfg)
+				}
+				return
+			}
+			if cfg == nil || cfg.ChartOfAccounts != tt.wantChart {
+				t.Errorf("Load() = %+v, want ChartOfAccounts %q", cfg, tt.wantChart)
+			}
+		})
+	}
+}
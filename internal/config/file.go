@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the layout of config.yaml or config.toml. Every value stands for the environment
+// variable named in its comment; the commands keep reading the environment, so ApplyFile only
+// fills in variables that are not already set. Example:
+//
+//	openai:
+//	  api_key: sk-...
+//	  model: gpt-4o
+//	google:
+//	  project: my-project
+//	  location: eu
+//	  credentials_file: /etc/tax-ai-tools/service-account.json
+//	  processor_id: abc123
+//	sheets:
+//	  url: https://docs.google.com/spreadsheets/d/...
+//	company:
+//	  name: Muster GmbH
+//	  aliases: [Muster, Muster Software]
+//	booking:
+//	  chart_of_accounts: SKR03
+//	  workers: 8
+type File struct {
+	OpenAI  OpenAIFile  `yaml:"openai" toml:"openai"`
+	Google  GoogleFile  `yaml:"google" toml:"google"`
+	Sheets  SheetsFile  `yaml:"sheets" toml:"sheets"`
+	Company CompanyFile `yaml:"company" toml:"company"`
+	Booking BookingFile `yaml:"booking" toml:"booking"`
+	Log     LogFile     `yaml:"log" toml:"log"`
+}
+
+// OpenAIFile holds the LLM settings
+type OpenAIFile struct {
+	APIKey   string `yaml:"api_key" toml:"api_key"`   // OPENAI_API_KEY
+	Provider string `yaml:"provider" toml:"provider"` // LLM_PROVIDER
+	BaseURL  string `yaml:"base_url" toml:"base_url"` // OPENAI_BASE_URL
+	Model    string `yaml:"model" toml:"model"`       // OPENAI_MODEL
+}
+
+// GoogleFile holds the Google Cloud settings
+type GoogleFile struct {
+	Project               string `yaml:"project" toml:"project"`                                 // GOOGLE_CLOUD_PROJECT
+	Location              string `yaml:"location" toml:"location"`                               // GOOGLE_CLOUD_LOCATION
+	CredentialsFile       string `yaml:"credentials_file" toml:"credentials_file"`               // GOOGLE_APPLICATION_CREDENTIALS
+	ProcessorID           string `yaml:"processor_id" toml:"processor_id"`                       // DOCUMENT_AI_PROCESSOR_ID
+	ProcessorVersion      string `yaml:"processor_version" toml:"processor_version"`             // DOCUMENT_AI_PROCESSOR_VERSION
+	InvoiceNumberPatterns string `yaml:"invoice_number_patterns" toml:"invoice_number_patterns"` // INVOICE_NUMBER_PATTERNS_FILE
+}
+
+// SheetsFile holds the Google Sheets settings
+type SheetsFile struct {
+	URL       string `yaml:"url" toml:"url"`             // GOOGLE_SHEET_URL
+	Worksheet string `yaml:"worksheet" toml:"worksheet"` // GOOGLE_SHEET_WORKSHEET
+}
+
+// CompanyFile describes our company for invoice type detection
+type CompanyFile struct {
+	Name    string   `yaml:"name" toml:"name"`       // COMPANY_NAME
+	Aliases []string `yaml:"aliases" toml:"aliases"` // COMPANY_ALIASES
+}
+
+// BookingFile holds the booking and batch settings
+type BookingFile struct {
+	ChartOfAccounts string `yaml:"chart_of_accounts" toml:"chart_of_accounts"` // CHART_OF_ACCOUNTS
+	DatePolicy      string `yaml:"date_policy" toml:"date_policy"`             // BOOKING_DATE_POLICY
+	ContextFile     string `yaml:"context_file" toml:"context_file"`           // BOOKING_COMPANY_CONTEXT_FILE
+	VendorMaster    string `yaml:"vendor_master" toml:"vendor_master"`         // VENDOR_MASTER_FILE
+	DBPath          string `yaml:"db_path" toml:"db_path"`                     // DB_PATH
+	Workers         int    `yaml:"workers" toml:"workers"`                     // BATCH_WORKERS
+}
+
+// LogFile holds the logging settings
+type LogFile struct {
+	Level  string `yaml:"level" toml:"level"`   // LOG_LEVEL
+	Format string `yaml:"format" toml:"format"` // LOG_FORMAT
+	Output string `yaml:"output" toml:"output"` // LOG_OUTPUT
+}
+
+// DefaultFilePaths returns the locations searched when no --config is given, in order: a
+// tax-ai-tools.yaml, .yml or .toml in the working directory, then config.yaml, .yml or .toml in the
+// user config directory (e.g. ~/.config/tax-ai-tools). The working directory is only searched for the
+// tool's own file name, as a plain config.yaml there often belongs to another project.
+func DefaultFilePaths() []string {
+	var paths []string
+	for _, ext := range []string{".yaml", ".yml", ".toml"} {
+		paths = append(paths, "tax-ai-tools"+ext)
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		for _, name := range []string{"config.yaml", "config.yml", "config.toml"} {
+			paths = append(paths, filepath.Join(dir, "tax-ai-tools", name))
+		}
+	}
+	return paths
+}
+
+// ApplyFile reads the config file at path, or the first existing default location if path is
+// empty, and sets every environment variable it covers that is not already set. It returns the
+// file that was applied, or "" if path is empty and no default file exists.
+func ApplyFile(path string) (string, error) {
+	const op = "ApplyFile"
+
+	if path == "" {
+		for _, candidate := range DefaultFilePaths() {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			return "", nil
+		}
+	}
+
+	file, err := ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	for key, value := range file.Env() {
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return "", fmt.Errorf("%s: failed to set %s: %w", op, key, err)
+		}
+	}
+
+	return path, nil
+}
+
+// ReadFile parses a YAML (.yaml, .yml) or TOML (.toml) config file
+func ReadFile(path string) (*File, error) {
+	const op = "ReadFile"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read config file: %w", op, err)
+	}
+
+	var file File
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		// An empty file decodes to io.EOF
+		if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: failed to parse %s: %w", op, path, err)
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(data), &file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to parse %s: %w", op, path, err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("%s: unknown key %q in %s", op, undecoded[0].String(), path)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported config file type %q (use .yaml or .toml)", op, filepath.Ext(path))
+	}
+
+	return &file, nil
+}
+
+// Env returns the environment variables set in the file, keyed by variable name
+func (f *File) Env() map[string]string {
+	values := map[string]string{
+		"OPENAI_API_KEY":                 f.OpenAI.APIKey,
+		"LLM_PROVIDER":                   f.OpenAI.Provider,
+		"OPENAI_BASE_URL":                f.OpenAI.BaseURL,
+		"OPENAI_MODEL":                   f.OpenAI.Model,
+		"GOOGLE_CLOUD_PROJECT":           f.Google.Project,
+		"GOOGLE_CLOUD_LOCATION":          f.Google.Location,
+		"GOOGLE_APPLICATION_CREDENTIALS": f.Google.CredentialsFile,
+		"DOCUMENT_AI_PROCESSOR_ID":       f.Google.ProcessorID,
+		"DOCUMENT_AI_PROCESSOR_VERSION":  f.Google.ProcessorVersion,
+		"INVOICE_NUMBER_PATTERNS_FILE":   f.Google.InvoiceNumberPatterns,
+		"GOOGLE_SHEET_URL":               f.Sheets.URL,
+		"GOOGLE_SHEET_WORKSHEET":         f.Sheets.Worksheet,
+		"COMPANY_NAME":                   f.Company.Name,
+		"COMPANY_ALIASES":                strings.Join(f.Company.Aliases, ","),
+		"CHART_OF_ACCOUNTS":              f.Booking.ChartOfAccounts,
+		"BOOKING_DATE_POLICY":            f.Booking.DatePolicy,
+		"BOOKING_COMPANY_CONTEXT_FILE":   f.Booking.ContextFile,
+		"VENDOR_MASTER_FILE":             f.Booking.VendorMaster,
+		"DB_PATH":                        f.Booking.DBPath,
+		"LOG_LEVEL":                      f.Log.Level,
+		"LOG_FORMAT":                     f.Log.Format,
+		"LOG_OUTPUT":                     f.Log.Output,
+	}
+	if f.Booking.Workers > 0 {
+		values["BATCH_WORKERS"] = strconv.Itoa(f.Booking.Workers)
+	}
+
+	for key, value := range values {
+		if value == "" {
+			delete(values, key)
+		}
+	}
+	return values
+}
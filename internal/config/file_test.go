@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestApplyFileYAML(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+openai:
+  model: gpt-4o
+sheets:
+  url: https://example.com/from-file
+company:
+  name: Muster GmbH
+  aliases: [Muster, Muster Software]
+booking:
+  workers: 4
+`)
+
+	t.Setenv("GOOGLE_SHEET_URL", "https://example.com/from-env")
+	for _, key := range []string{"OPENAI_MODEL", "COMPANY_NAME", "COMPANY_ALIASES", "BATCH_WORKERS"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	applied, err := ApplyFile(path)
+	if err != nil {
+		t.Fatalf("ApplyFile failed: %v", err)
+	}
+	if applied != path {
+		t.Errorf("applied = %q, want %q", applied, path)
+	}
+
+	want := map[string]string{
+		"GOOGLE_SHEET_URL": "https://example.com/from-env", // Environment wins
+		"OPENAI_MODEL":     "gpt-4o",
+		"COMPANY_NAME":     "Muster GmbH",
+		"COMPANY_ALIASES":  "Muster,Muster Software",
+		"BATCH_WORKERS":    "4",
+	}
+	for key, value := range want {
+		if got := os.Getenv(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestReadFileTOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+[google]
+project = "my-project"
+location = "eu"
+`)
+
+	file, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	env := file.Env()
+	if env["GOOGLE_CLOUD_PROJECT"] != "my-project" || env["GOOGLE_CLOUD_LOCATION"] != "eu" {
+		t.Errorf("unexpected env %v", env)
+	}
+	if _, ok := env["OPENAI_API_KEY"]; ok {
+		t.Error("unset values must not be returned")
+	}
+}
+
+func TestReadFileRejectsUnknownKeys(t *testing.T) {
+	for _, tt := range []struct{ name, content string }{
+		{"config.yaml", "google:\n  projekt: typo\n"},
+		{"config.toml", "[google]\nprojekt = \"typo\"\n"},
+	} {
+		if _, err := ReadFile(writeConfigFile(t, tt.name, tt.content)); err == nil {
+			t.Errorf("%s: expected error for unknown key", tt.name)
+		}
+	}
+}
+
+func TestApplyFileIgnoresForeignConfigInWorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	if err := os.WriteFile("config.yaml", []byte("unrelated: [project, settings]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := ApplyFile("")
+	if err != nil || applied != "" {
+		t.Fatalf("ApplyFile(\"\") = %q, %v; a plain config.yaml must not be discovered", applied, err)
+	}
+
+	if err := os.WriteFile("tax-ai-tools.yaml", []byte("openai:\n  model: gpt-4o\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPENAI_MODEL", "")
+	os.Unsetenv("OPENAI_MODEL")
+
+	applied, err = ApplyFile("")
+	if err != nil || applied != "tax-ai-tools.yaml" {
+		t.Fatalf("ApplyFile(\"\") = %q, %v, want tax-ai-tools.yaml", applied, err)
+	}
+	if got := os.Getenv("OPENAI_MODEL"); got != "gpt-4o" {
+		t.Errorf("OPENAI_MODEL = %q, want gpt-4o", got)
+	}
+}
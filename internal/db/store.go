@@ -0,0 +1,305 @@
+// Package db keeps processed invoices and their bookings in an optional local SQLite database, so
+// later runs can look up earlier results without reading the Google Sheet.
+//
+// The database is enabled with DB_PATH. Every invoice is stored once under its RecordID, the key
+// the sheet upsert uses as well, so processing a file again updates the stored row. Amounts are
+// stored in cents and dates as YYYY-MM-DD text for range queries; the invoice and booking are kept
+// as JSON next to the indexed columns.
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // Registers the pure-Go "sqlite" driver
+
+	"tools/internal/sheets"
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// schema creates the invoices table. tax_keys holds all tax keys of the booking, including those of
+// split bookings, as a comma-separated list with leading and trailing commas, e.g. ",5,9,".
+const schema = `
+CREATE TABLE IF NOT EXISTS invoices (
+	id             TEXT PRIMARY KEY,
+	filename       TEXT NOT NULL,
+	status         TEXT NOT NULL,
+	type           TEXT NOT NULL,
+	invoice_number TEXT NOT NULL,
+	counterparty   TEXT NOT NULL,
+	issue_date     TEXT NOT NULL,
+	currency       TEXT NOT NULL,
+	net_cents      INTEGER NOT NULL,
+	vat_cents      INTEGER NOT NULL,
+	gross_cents    INTEGER NOT NULL,
+	tax_keys       TEXT NOT NULL,
+	debit_account  TEXT NOT NULL,
+	credit_account TEXT NOT NULL,
+	invoice_json   TEXT NOT NULL,
+	booking_json   TEXT NOT NULL,
+	processed_at   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS invoices_counterparty ON invoices (counterparty);
+CREATE INDEX IF NOT EXISTS invoices_issue_date ON invoices (issue_date);
+`
+
+// Record is one processed invoice with its booking
+type Record struct {
+	ID          string                 `json:"id"`
+	Filename    string                 `json:"file"`
+	Status      string                 `json:"status"` // datev-batch status: success or warning
+	Invoice     *models.Invoice        `json:"invoice"`
+	Booking     *services.DATEVBooking `json:"booking,omitempty"`
+	ProcessedAt time.Time              `json:"processed_at"`
+}
+
+// Filter selects the records returned by Query; zero values match everything
+type Filter struct {
+	Vendor string    // Case-insensitive part of the vendor or customer name
+	From   time.Time // First invoice date, inclusive
+	To     time.Time // Last invoice date, inclusive
+	TaxKey string    // Tax key of the booking or one of its splits
+	Limit  int       // Maximum number of records; zero or less returns all
+}
+
+// Store reads and writes records in one SQLite database
+type Store struct {
+	path string
+	conn *sql.DB
+}
+
+// Open opens the database at path, creating the file, its directory and the schema if needed
+func Open(path string) (*Store, error) {
+	const op = "Open"
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("%s: failed to create database directory: %w", op, err)
+		}
+	}
+
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to open %s: %w", op, path, err)
+	}
+	// SQLite allows one writer at a time; a single connection also keeps the pragma below in effect
+	conn.SetMaxOpenConns(1)
+
+	// Wait for another run writing to the same file instead of failing with SQLITE_BUSY
+	if _, err := conn.Exec("PRAGMA busy_timeout = 5000"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s: failed to configure %s: %w", op, path, err)
+	}
+	if _, err := conn.Exec(schema); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s: failed to create schema in %s: %w", op, path, err)
+	}
+
+	return &Store{path: path, conn: conn}, nil
+}
+
+// OpenFromEnv opens the database at DB_PATH, or returns nil if DB_PATH is not set
+func OpenFromEnv() (*Store, error) {
+	path := os.Getenv("DB_PATH")
+	if path == "" {
+		return nil, nil
+	}
+	return Open(path)
+}
+
+// Path returns the database file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.conn.Close()
+}
+
+// RecordID returns the key an invoice is stored under: counterparty and invoice number, or the
+// file name for invoices without a number, as in the sheet upsert
+func RecordID(invoice *models.Invoice, filename string) string {
+	return sheets.BookingKey(invoice.InvoiceNumber, counterpartyOf(invoice), filename)
+}
+
+// counterpartyOf returns the customer of a receivable and the vendor of any other invoice
+func counterpartyOf(invoice *models.Invoice) string {
+	if invoice.Type == "RECEIVABLE" {
+		return invoice.Customer
+	}
+	return invoice.Vendor
+}
+
+// Upsert stores the records in one transaction, replacing earlier records with the same ID.
+// Records without an invoice are skipped; a missing ID is derived with RecordID and a missing
+// ProcessedAt is set to the current time.
+func (s *Store) Upsert(ctx context.Context, records ...Record) error {
+	const op = "Upsert"
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: failed to begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO invoices (id, filename, status, type, invoice_number, counterparty, issue_date, currency,
+	net_cents, vat_cents, gross_cents, tax_keys, debit_account, credit_account, invoice_json, booking_json,
+	processed_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET
+	filename = excluded.filename, status = excluded.status, type = excluded.type,
+	invoice_number = excluded.invoice_number, counterparty = excluded.counterparty,
+	issue_date = excluded.issue_date, currency = excluded.currency, net_cents = excluded.net_cents,
+	vat_cents = excluded.vat_cents, gross_cents = excluded.gross_cents, tax_keys = excluded.tax_keys,
+	debit_account = excluded.debit_account, credit_account = excluded.credit_account,
+	invoice_json = excluded.invoice_json, booking_json = excluded.booking_json,
+	processed_at = excluded.processed_at`)
+	if err != nil {
+		return fmt.Errorf("%s: failed to prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		if record.Invoice == nil {
+			continue
+		}
+		inv := record.Invoice
+		if record.ID == "" {
+			record.ID = RecordID(inv, record.Filename)
+		}
+		if record.ProcessedAt.IsZero() {
+			record.ProcessedAt = time.Now()
+		}
+
+		invoiceJSON, err := json.Marshal(inv)
+		if err != nil {
+			return fmt.Errorf("%s: failed to encode invoice %s: %w", op, record.ID, err)
+		}
+		bookingJSON := []byte("null")
+		var debitAccount, creditAccount string
+		if record.Booking != nil {
+			if bookingJSON, err = json.Marshal(record.Booking); err != nil {
+				return fmt.Errorf("%s: failed to encode booking %s: %w", op, record.ID, err)
+			}
+			debitAccount, creditAccount = record.Booking.DebitAccount, record.Booking.CreditAccount
+		}
+
+		_, err = stmt.ExecContext(ctx,
+			record.ID, record.Filename, record.Status, inv.Type, inv.InvoiceNumber, counterpartyOf(inv),
+			formatDate(inv.IssueDate), inv.Currency, inv.NetAmount, inv.VATAmount, inv.GrossAmount,
+			taxKeysColumn(record.Booking), debitAccount, creditAccount, string(invoiceJSON),
+			string(bookingJSON), record.ProcessedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return fmt.Errorf("%s: failed to store invoice %s: %w", op, record.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: failed to commit: %w", op, err)
+	}
+	return nil
+}
+
+// Query returns the records matching the filter, ordered by invoice date and ID. Invoices without
+// a date sort first and never match a date range.
+func (s *Store) Query(ctx context.Context, filter Filter) ([]Record, error) {
+	const op = "Query"
+
+	var conditions []string
+	var args []interface{}
+	if filter.Vendor != "" {
+		conditions = append(conditions, "instr(lower(counterparty), ?) > 0")
+		args = append(args, strings.ToLower(filter.Vendor))
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "issue_date != '' AND issue_date >= ?")
+		args = append(args, formatDate(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "issue_date != '' AND issue_date <= ?")
+		args = append(args, formatDate(filter.To))
+	}
+	if filter.TaxKey != "" {
+		conditions = append(conditions, "instr(tax_keys, ?) > 0")
+		args = append(args, ","+filter.TaxKey+",")
+	}
+
+	query := "SELECT id, filename, status, invoice_json, booking_json, processed_at FROM invoices"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY issue_date, id"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var record Record
+		var invoiceJSON, bookingJSON, processedAt string
+		if err := rows.Scan(&record.ID, &record.Filename, &record.Status, &invoiceJSON, &bookingJSON, &processedAt); err != nil {
+			return nil, fmt.Errorf("%s: failed to read row: %w", op, err)
+		}
+		if err := json.Unmarshal([]byte(invoiceJSON), &record.Invoice); err != nil {
+			return nil, fmt.Errorf("%s: failed to decode invoice %s: %w", op, record.ID, err)
+		}
+		if err := json.Unmarshal([]byte(bookingJSON), &record.Booking); err != nil {
+			return nil, fmt.Errorf("%s: failed to decode booking %s: %w", op, record.ID, err)
+		}
+		record.ProcessedAt, _ = time.Parse(time.RFC3339, processedAt)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return records, nil
+}
+
+// taxKeysColumn lists the tax keys of a booking and its splits for the tax_keys column
+func taxKeysColumn(booking *services.DATEVBooking) string {
+	if booking == nil {
+		return ""
+	}
+
+	keys := []string{booking.TaxKey}
+	for _, split := range booking.Splits {
+		keys = append(keys, split.TaxKey)
+	}
+
+	var column strings.Builder
+	for _, key := range keys {
+		if key == "" || strings.Contains(column.String(), ","+key+",") {
+			continue
+		}
+		if column.Len() == 0 {
+			column.WriteString(",")
+		}
+		column.WriteString(key + ",")
+	}
+	return column.String()
+}
+
+// formatDate renders a date as YYYY-MM-DD, or empty if unset
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+func testRecord(number, vendor string, date time.Time, gross int64, booking *services.DATEVBooking) Record {
+	return Record{
+		Filename: number + ".pdf",
+		Status:   "success",
+		Invoice: &models.Invoice{
+			InvoiceNumber: number,
+			Type:          "PAYABLE",
+			Vendor:        vendor,
+			IssueDate:     date,
+			GrossAmount:   gross,
+		},
+		Booking: booking,
+	}
+}
+
+func TestStoreUpsertAndQuery(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open(filepath.Join(t.TempDir(), "nested", "invoices.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	jan := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)
+	err = store.Upsert(ctx,
+		testRecord("A-1", "Hosting AG", jan, 11900, &services.DATEVBooking{TaxKey: "9", DebitAccount: "4930"}),
+		testRecord("B-1", "Bäckerei Müller", feb, 10700, &services.DATEVBooking{TaxKey: "5"}),
+		testRecord("C-1", "Hosting AG", feb, 12600, &services.DATEVBooking{Splits: []services.BookingSplit{
+			{TaxKey: "9"}, {TaxKey: "5"},
+		}}),
+		Record{Filename: "failed.pdf", Status: "error"},
+	)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	// Processing a file again replaces the stored record
+	updated := testRecord("A-1", "Hosting AG", jan, 23800, &services.DATEVBooking{TaxKey: "9", DebitAccount: "4806"})
+	if err := store.Upsert(ctx, updated); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{"A-1", "B-1", "C-1"}},
+		{"vendor", Filter{Vendor: "hosting"}, []string{"A-1", "C-1"}},
+		{"non-ASCII vendor", Filter{Vendor: "Müller"}, []string{"B-1"}},
+		{"date range", Filter{From: feb, To: feb}, []string{"B-1", "C-1"}},
+		{"tax key includes splits", Filter{TaxKey: "5"}, []string{"B-1", "C-1"}},
+		{"limit", Filter{Limit: 1}, []string{"A-1"}},
+		{"no match", Filter{Vendor: "hosting", TaxKey: "2"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := store.Query(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Invoice.InvoiceNumber)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Query() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Query() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	records, err := store.Query(ctx, Filter{Vendor: "hosting", TaxKey: "9", Limit: 1})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if records[0].Invoice.GrossAmount != 23800 || records[0].Booking.DebitAccount != "4806" {
+		t.Errorf("Query() returned %+v, want the updated record", records[0])
+	}
+	if records[0].ID != "hosting ag|a-1" || records[0].ProcessedAt.IsZero() {
+		t.Errorf("Query() ID = %q, processed at %v", records[0].ID, records[0].ProcessedAt)
+	}
+}
@@ -0,0 +1,96 @@
+// Package docformat recognizes the file formats of scanned documents by their content and counts
+// their pages.
+//
+// Document AI and Vision take PDFs as well as TIFF scans, and a multipage TIFF needs the same page
+// limits as a PDF. Formats are detected from the leading bytes rather than the file extension, since
+// scanners and mail attachments often get the extension wrong.
+package docformat
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"tools/internal/pdf"
+)
+
+// Format is a document file format
+type Format string
+
+// Supported formats
+const (
+	PDF  Format = "PDF"
+	TIFF Format = "TIFF"
+	GIF  Format = "GIF"
+	JPEG Format = "JPEG"
+	PNG  Format = "PNG"
+	BMP  Format = "BMP"
+	WEBP Format = "WEBP"
+)
+
+// mimeTypes are the MIME types Google's APIs expect for each format
+var mimeTypes = map[Format]string{
+	PDF:  "application/pdf",
+	TIFF: "image/tiff",
+	GIF:  "image/gif",
+	JPEG: "image/jpeg",
+	PNG:  "image/png",
+	BMP:  "image/bmp",
+	WEBP: "image/webp",
+}
+
+// MimeType returns the MIME type of the format
+func (f Format) MimeType() string {
+	return mimeTypes[f]
+}
+
+// Extension returns the usual file extension of the format, including the dot
+func (f Format) Extension() string {
+	if f == JPEG {
+		return ".jpg"
+	}
+	return "." + strings.ToLower(string(f))
+}
+
+// Detect returns the format of a document from its leading bytes. ok is false for unknown formats.
+func Detect(data []byte) (format Format, ok bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF")):
+		return PDF, true
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")),
+		bytes.HasPrefix(data, []byte("II+\x00")), bytes.HasPrefix(data, []byte("MM\x00+")):
+		return TIFF, true
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return GIF, true
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return JPEG, true
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return PNG, true
+	case len(data) >= 14 && bytes.HasPrefix(data, []byte("BM")):
+		return BMP, true
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WEBP":
+		return WEBP, true
+	}
+	return "", false
+}
+
+// PageCount returns the number of pages of a document in the given format. PDFs must not be
+// encrypted. Formats other than PDF and TIFF are single images and always have one page.
+func PageCount(data []byte, format Format) (int, error) {
+	const op = "PageCount"
+
+	var count int
+	var err error
+	switch format {
+	case PDF:
+		count, err = pdf.PageCount(data)
+	case TIFF:
+		count, err = tiffPageCount(data)
+	default:
+		return 1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
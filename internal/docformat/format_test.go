@@ -0,0 +1,104 @@
+package docformat
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// buildTIFF returns a classic TIFF with one IFD per entry of subfileTypes, in the given byte order.
+// Each IFD only carries a NewSubfileType entry with the given value.
+func buildTIFF(order binary.ByteOrder, subfileTypes ...uint32) []byte {
+	data := []byte("II*\x00\x08\x00\x00\x00")
+	if order == binary.BigEndian {
+		data = []byte("MM\x00*\x00\x00\x00\x08")
+	}
+	for i, subfileType := range subfileTypes {
+		ifd := make([]byte, 2+12+4)
+		order.PutUint16(ifd[0:], 1)
+		order.PutUint16(ifd[2:], tagNewSubfileType)
+		order.PutUint16(ifd[4:], typeLong)
+		order.PutUint32(ifd[6:], 1)
+		order.PutUint32(ifd[10:], subfileType)
+		if i < len(subfileTypes)-1 {
+			order.PutUint32(ifd[14:], uint32(len(data)+len(ifd)))
+		}
+		data = append(data, ifd...)
+	}
+	return data
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		data string
+		want Format
+	}{
+		{"%PDF-1.7\n", PDF},
+		{"II*\x00\x08\x00\x00\x00", TIFF},
+		{"MM\x00*\x00\x00\x00\x08", TIFF},
+		{"MM\x00+\x00\x08\x00\x00", TIFF},
+		{"GIF89a....", GIF},
+		{"\xff\xd8\xff\xe0\x00\x10JFIF", JPEG},
+		{"\x89PNG\r\n\x1a\n....", PNG},
+		{"RIFF\x00\x00\x00\x00WEBPVP8 ", WEBP},
+	}
+	for _, tt := range tests {
+		if got, ok := Detect([]byte(tt.data)); !ok || got != tt.want {
+			t.Errorf("Detect(%q) = %s, %v, want %s", tt.data, got, ok, tt.want)
+		}
+	}
+
+	for _, data := range []string{"", "hello", "BM", "RIFF\x00\x00\x00\x00WAVE"} {
+		if got, ok := Detect([]byte(data)); ok {
+			t.Errorf("Detect(%q) = %s, want unknown", data, got)
+		}
+	}
+}
+
+func TestTIFFPageCount(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"single page", buildTIFF(binary.LittleEndian, 0), 1},
+		{"multipage little endian", buildTIFF(binary.LittleEndian, 2, 2, 2), 3},
+		{"multipage big endian", buildTIFF(binary.BigEndian, 0, 0), 2},
+		{"thumbnails are not pages", buildTIFF(binary.LittleEndian, 0, 1, 0, 1), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PageCount(tt.data, TIFF)
+			if err != nil {
+				t.Fatalf("PageCount() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("PageCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTIFFPageCountInvalid(t *testing.T) {
+	truncated := buildTIFF(binary.LittleEndian, 0, 0)
+	truncated = truncated[:len(truncated)-6]
+
+	// The second IFD points back to the first
+	loop := buildTIFF(binary.LittleEndian, 0, 0)
+	binary.LittleEndian.PutUint32(loop[len(loop)-4:], 8)
+
+	// An entry count far beyond the data
+	huge := buildTIFF(binary.LittleEndian, 0)
+	binary.LittleEndian.PutUint16(huge[8:], 0xffff)
+
+	for name, data := range map[string][]byte{"truncated": truncated, "loop": loop, "huge": huge, "header only": []byte("II*\x00")} {
+		if got, err := PageCount(data, TIFF); err == nil {
+			t.Errorf("%s: PageCount() = %d, want error", name, got)
+		}
+	}
+}
+
+func TestPageCountSingleImage(t *testing.T) {
+	if got, err := PageCount([]byte("\x89PNG\r\n\x1a\n"), PNG); err != nil || got != 1 {
+		t.Errorf("PageCount(PNG) = %d, %v, want 1", got, err)
+	}
+}
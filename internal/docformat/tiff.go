@@ -0,0 +1,126 @@
+package docformat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// errTruncatedTIFF is returned when an image file directory lies outside the data
+var errTruncatedTIFF = errors.New("truncated TIFF")
+
+// TIFF tag and field types read while counting pages
+const (
+	tagNewSubfileType = 254
+
+	typeShort = 3
+	typeLong  = 4
+	typeLong8 = 16
+)
+
+// tiffLayout holds the field sizes of classic TIFF or BigTIFF image file directories (IFDs)
+type tiffLayout struct {
+	countSize  uint64 // Size of the entry count at the start of an IFD
+	entrySize  uint64 // Size of one IFD entry
+	offsetSize uint64 // Size of the offset of the next IFD
+}
+
+var (
+	classicTIFF = tiffLayout{countSize: 2, entrySize: 12, offsetSize: 4}
+	bigTIFF     = tiffLayout{countSize: 8, entrySize: 20, offsetSize: 8}
+)
+
+// tiffPageCount counts the pages of a TIFF or BigTIFF file by following its chain of image file
+// directories. Reduced-resolution images, such as the thumbnails some scanners add after a page, are
+// marked in NewSubfileType and not counted.
+func tiffPageCount(data []byte) (int, error) {
+	if len(data) < 8 {
+		return 0, errTruncatedTIFF
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, fmt.Errorf("invalid TIFF byte order %q", data[:2])
+	}
+
+	layout := classicTIFF
+	var offset uint64
+	switch version := order.Uint16(data[2:4]); version {
+	case 42:
+		offset = uint64(order.Uint32(data[4:8]))
+	case 43:
+		if len(data) < 16 || order.Uint16(data[4:6]) != 8 {
+			return 0, fmt.Errorf("invalid BigTIFF header")
+		}
+		layout = bigTIFF
+		offset = order.Uint64(data[8:16])
+	default:
+		return 0, fmt.Errorf("invalid TIFF version %d", version)
+	}
+
+	size := uint64(len(data))
+	pages := 0
+	visited := make(map[uint64]bool)
+	for offset != 0 {
+		if visited[offset] {
+			return 0, fmt.Errorf("TIFF directory chain loops at offset %d", offset)
+		}
+		visited[offset] = true
+
+		if offset > size || size-offset < layout.countSize {
+			return 0, errTruncatedTIFF
+		}
+		entries := uint64(order.Uint16(data[offset:]))
+		if layout == bigTIFF {
+			entries = order.Uint64(data[offset:])
+		}
+		start := offset + layout.countSize
+		if entries > (size-start)/layout.entrySize || size-start-entries*layout.entrySize < layout.offsetSize {
+			return 0, errTruncatedTIFF
+		}
+		end := start + entries*layout.entrySize
+
+		reduced := false
+		for entry := start; entry < end; entry += layout.entrySize {
+			if order.Uint16(data[entry:]) == tagNewSubfileType {
+				reduced = tiffEntryValue(data[entry:entry+layout.entrySize], order, layout)&1 != 0
+			}
+		}
+		if !reduced {
+			pages++
+		}
+
+		if layout == bigTIFF {
+			offset = order.Uint64(data[end:])
+		} else {
+			offset = uint64(order.Uint32(data[end:]))
+		}
+	}
+
+	if pages == 0 {
+		return 0, fmt.Errorf("TIFF contains no pages")
+	}
+	return pages, nil
+}
+
+// tiffEntryValue returns the integer value stored inline in an IFD entry
+func tiffEntryValue(entry []byte, order binary.ByteOrder, layout tiffLayout) uint64 {
+	// The value follows the tag, the field type and the value count
+	value := entry[4+layout.offsetSize:]
+	switch order.Uint16(entry[2:4]) {
+	case typeShort:
+		return uint64(order.Uint16(value))
+	case typeLong:
+		return uint64(order.Uint32(value))
+	case typeLong8:
+		if layout == bigTIFF {
+			return order.Uint64(value)
+		}
+	}
+	return 0
+}
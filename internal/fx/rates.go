@@ -0,0 +1,168 @@
+// Package fx converts amounts between currencies with the euro foreign exchange reference rates
+// published by the European Central Bank.
+//
+// Rates are read from the ECB's historical CSV (eurofxref-hist.csv, unpacked from
+// https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.zip) configured with FX_RATES_FILE. The
+// file has one row per business day with the date in the first column and the units of each
+// currency per euro in the others; missing quotes are "N/A".
+package fx
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRateAge is how far back Convert looks for a quote; the ECB publishes none on weekends and
+// TARGET holidays, the longest of which (Easter) spans four days
+const maxRateAge = 7 * 24 * time.Hour
+
+// ErrNoRate is returned when no reference rate is known for a currency near the requested date
+var ErrNoRate = errors.New("no exchange rate")
+
+// quote is the number of currency units per euro on one day
+type quote struct {
+	date time.Time
+	rate float64
+}
+
+// Rates holds euro reference rates per currency, sorted by date
+type Rates struct {
+	quotes map[string][]quote
+}
+
+// LoadFromEnv reads the rates file configured with FX_RATES_FILE, or returns nil if none is set
+func LoadFromEnv() (*Rates, error) {
+	path := os.Getenv("FX_RATES_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return LoadFile(path)
+}
+
+// LoadFile reads an ECB historical reference rates CSV
+func LoadFile(path string) (*Rates, error) {
+	const op = "LoadFile"
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to open rates file: %w", op, err)
+	}
+	defer file.Close()
+
+	rates, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", op, path, err)
+	}
+	return rates, nil
+}
+
+// Parse reads reference rates in the ECB CSV layout: a header row "Date,USD,JPY,...", then one row
+// per day with the date as YYYY-MM-DD
+func Parse(r io.Reader) (*Rates, error) {
+	const op = "Parse"
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read header: %w", op, err)
+	}
+	if len(header) < 2 || !strings.EqualFold(strings.TrimSpace(header[0]), "Date") {
+		return nil, fmt.Errorf("%s: unexpected header %q, want Date followed by currency codes", op, strings.Join(header, ","))
+	}
+
+	rates := &Rates{quotes: make(map[string][]quote)}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", op, line, err)
+		}
+
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: invalid date %q", op, line, record[0])
+		}
+		for i := 1; i < len(record) && i < len(header); i++ {
+			currency := strings.ToUpper(strings.TrimSpace(header[i]))
+			value := strings.TrimSpace(record[i])
+			if currency == "" || value == "" || value == "N/A" {
+				continue
+			}
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || rate <= 0 {
+				return nil, fmt.Errorf("%s: line %d: invalid %s rate %q", op, line, currency, value)
+			}
+			rates.quotes[currency] = append(rates.quotes[currency], quote{date: date, rate: rate})
+		}
+	}
+
+	// The ECB file lists the newest day first
+	for _, quotes := range rates.quotes {
+		sort.Slice(quotes, func(i, j int) bool { return quotes[i].date.Before(quotes[j].date) })
+	}
+	return rates, nil
+}
+
+// currencySymbols maps the symbols found in invoice sheets to ISO 4217 codes
+var currencySymbols = map[string]string{"€": "EUR", "$": "USD", "£": "GBP"}
+
+// NormalizeCurrency returns the upper-case ISO 4217 code for a currency code or symbol
+func NormalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if code, ok := currencySymbols[currency]; ok {
+		return code
+	}
+	return currency
+}
+
+// SameCurrency reports whether a and b denote the same currency
+func SameCurrency(a, b string) bool {
+	return NormalizeCurrency(a) == NormalizeCurrency(b)
+}
+
+// Rate returns the units of currency per euro on date, using the last quote published on or before
+// it. EUR is always 1.
+func (r *Rates) Rate(currency string, date time.Time) (float64, error) {
+	currency = NormalizeCurrency(currency)
+	if currency == "EUR" {
+		return 1, nil
+	}
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	quotes := r.quotes[currency]
+	i := sort.Search(len(quotes), func(i int) bool { return quotes[i].date.After(day) })
+	if i == 0 || day.Sub(quotes[i-1].date) > maxRateAge {
+		return 0, fmt.Errorf("%w for %s on %s", ErrNoRate, currency, day.Format("2006-01-02"))
+	}
+	return quotes[i-1].rate, nil
+}
+
+// Convert converts amount from one currency to another at the reference rates of date, crossing
+// through the euro for two non-euro currencies
+func (r *Rates) Convert(amount float64, from, to string, date time.Time) (float64, error) {
+	if SameCurrency(from, to) {
+		return amount, nil
+	}
+
+	fromRate, err := r.Rate(from, date)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := r.Rate(to, date)
+	if err != nil {
+		return 0, err
+	}
+	return amount / fromRate * toRate, nil
+}
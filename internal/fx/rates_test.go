@@ -0,0 +1,56 @@
+package fx
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ecbSample = `Date,USD,JPY,CHF,
+2024-03-04,1.0850,162.50,0.9580,
+2024-03-01,1.0830,162.29,0.9554,
+2024-02-29,1.0813,N/A,0.9542,
+`
+
+func TestConvert(t *testing.T) {
+	rates, err := Parse(strings.NewReader(ecbSample))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		amount   float64
+		from, to string
+		date     time.Time
+		want     float64
+	}{
+		{"usd to eur", 108.30, "USD", "EUR", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 100},
+		{"weekend uses friday", 100, "EUR", "usd", time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC), 108.30},
+		{"cross rate", 100, "USD", "CHF", time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), 100 / 1.0850 * 0.9580},
+		{"same currency", 42, "€", "EUR", time.Time{}, 42},
+	}
+	for _, tt := range tests {
+		got, err := rates.Convert(tt.amount, tt.from, tt.to, tt.date)
+		if err != nil {
+			t.Errorf("%s: Convert failed: %v", tt.name, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 0.005 {
+			t.Errorf("%s: Convert = %.4f, want %.4f", tt.name, got, tt.want)
+		}
+	}
+
+	// Missing quotes and dates outside the file have no rate
+	for _, date := range []time.Time{
+		time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC),
+	} {
+		if _, err := rates.Convert(100, "JPY", "EUR", date); !errors.Is(err, ErrNoRate) {
+			t.Errorf("Convert JPY on %s: err = %v, want ErrNoRate", date.Format("2006-01-02"), err)
+		}
+	}
+}
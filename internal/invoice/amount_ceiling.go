@@ -0,0 +1,99 @@
+package invoice
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"tools/internal/money"
+	"tools/pkg/models"
+)
+
+// DefaultMaxInvoiceAmount is the gross amount in cents above which an invoice is flagged as a likely
+// OCR or parse error, unless MAX_INVOICE_AMOUNT sets another ceiling (€1,000,000)
+const DefaultMaxInvoiceAmount int64 = 100_000_000
+
+// amountContextRadius is how many characters of OCR text around a suspicious amount are logged
+const amountContextRadius = 60
+
+// maxInvoiceAmountFromEnv returns the ceiling configured with MAX_INVOICE_AMOUNT in cents, e.g.
+// "250.000" or "250000,00", or DefaultMaxInvoiceAmount if it is unset or invalid. Zero disables
+// the check.
+func maxInvoiceAmountFromEnv() int64 {
+	value := strings.TrimSpace(os.Getenv("MAX_INVOICE_AMOUNT"))
+	if value == "" {
+		return DefaultMaxInvoiceAmount
+	}
+	ceiling, err := money.ParseCents(value)
+	if err != nil || ceiling < 0 {
+		return DefaultMaxInvoiceAmount
+	}
+	return ceiling
+}
+
+// checkAmountCeiling flags the invoice if the magnitude of its gross amount exceeds the ceiling, so
+// that the booking carries a warning instead of an absurd amount reaching the accounting system
+// unnoticed. The amount and the OCR text around it are logged.
+func checkAmountCeiling(invoice *models.Invoice, text string, ceiling int64, log zerolog.Logger) {
+	gross := invoice.GrossAmount
+	if gross < 0 {
+		gross = -gross
+	}
+	if ceiling <= 0 || gross <= ceiling {
+		invoice.ExceededAmountCeiling = 0
+		return
+	}
+
+	invoice.ExceededAmountCeiling = ceiling
+	log.Warn().
+		Float64("gross_amount", float64(invoice.GrossAmount)/100).
+		Float64("max_invoice_amount", float64(ceiling)/100).
+		Str("currency", invoice.Currency).
+		Str("ocr_context", amountContext(text, gross)).
+		Msg("Gross amount exceeds MAX_INVOICE_AMOUNT, likely an OCR or parse error")
+}
+
+// amountContext returns the OCR text around the first mention of the amount in cents, written
+// with German or English separators, or an empty string if the text does not mention it
+func amountContext(text string, cents int64) string {
+	units, fraction := cents/100, cents%100
+	grouped := groupThousands(units)
+	candidates := []string{
+		fmt.Sprintf("%s,%02d", strings.ReplaceAll(grouped, ",", "."), fraction),
+		fmt.Sprintf("%s.%02d", grouped, fraction),
+		fmt.Sprintf("%d,%02d", units, fraction),
+		fmt.Sprintf("%d.%02d", units, fraction),
+	}
+
+	for _, candidate := range candidates {
+		i := strings.Index(text, candidate)
+		if i < 0 {
+			continue
+		}
+		start, end := max(0, i-amountContextRadius), min(len(text), i+len(candidate)+amountContextRadius)
+		// Keep the excerpt on whole runes of the UTF-8 text
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+		return strings.Join(strings.Fields(text[start:end]), " ")
+	}
+	return ""
+}
+
+// groupThousands formats units with a comma between groups of three digits, e.g. "4,500,000"
+func groupThousands(units int64) string {
+	digits := fmt.Sprintf("%d", units)
+	var grouped strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return grouped.String()
+}
@@ -0,0 +1,115 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"tools/internal/cache"
+	"tools/internal/logger"
+	"tools/pkg/models"
+)
+
+// extractionCacheKind is the cache subdirectory for Document AI extractions
+const extractionCacheKind = "document-ai"
+
+// cachedExtraction is the cache entry of one Document AI extraction
+type cachedExtraction struct {
+	Invoice    *models.Invoice    `json:"invoice"`
+	Confidence map[string]float32 `json:"confidence"`
+}
+
+// CachedInvoiceProcessor wraps an InvoiceProcessor and caches its extractions by PDF content.
+// Multi-invoice splitting is passed through uncached. Cache errors are logged and never fail
+// the extraction.
+type CachedInvoiceProcessor struct {
+	processor InvoiceProcessor
+	store     *cache.Store
+	force     bool
+	log       zerolog.Logger
+}
+
+// NewCachedInvoiceProcessor wraps processor with store. With force set, cached entries are
+// ignored but fresh results are still written back, replacing stale entries.
+func NewCachedInvoiceProcessor(processor InvoiceProcessor, store *cache.Store, force bool) InvoiceProcessor {
+	return &CachedInvoiceProcessor{
+		processor: processor,
+		store:     store,
+		force:     force,
+		log:       logger.WithComponent("document-ai-cache"),
+	}
+}
+
+// ProcessInvoice extracts the invoice, using the cache if possible
+func (p *CachedInvoiceProcessor) ProcessInvoice(ctx context.Context, pdfData io.Reader) (*models.Invoice, error) {
+	invoice, _, err := p.ProcessInvoicePages(ctx, pdfData, nil)
+	return invoice, err
+}
+
+// ProcessInvoiceWithConfidence extracts the invoice with confidence scores, using the cache if possible
+func (p *CachedInvoiceProcessor) ProcessInvoiceWithConfidence(ctx context.Context, pdfData io.Reader) (*models.Invoice, map[string]float32, error) {
+	return p.ProcessInvoicePages(ctx, pdfData, nil)
+}
+
+// ProcessInvoicePages extracts the given pages, caching each page selection separately
+func (p *CachedInvoiceProcessor) ProcessInvoicePages(ctx context.Context, pdfData io.Reader, pages []int32) (*models.Invoice, map[string]float32, error) {
+	const op = "ProcessInvoicePages"
+
+	pdfBytes, err := io.ReadAll(pdfData)
+	if err != nil {
+		return nil, nil, WrapInvoiceProcessingError(op, err, "failed to read PDF data")
+	}
+
+	key := cache.Key(pdfBytes, pagesVariant(pages))
+	if !p.force {
+		var entry cachedExtraction
+		found, err := p.store.Load(extractionCacheKind, key, &entry)
+		if err != nil {
+			p.log.Warn().Err(err).Msg("Failed to read cached extraction, processing again")
+		} else if found && entry.Invoice != nil {
+			p.log.Info().Str("key", key).Msg("Using cached Document AI extraction")
+			return entry.Invoice, entry.Confidence, nil
+		}
+	}
+
+	invoice, confidence, err := p.processor.ProcessInvoicePages(ctx, bytes.NewReader(pdfBytes), pages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := p.store.Save(extractionCacheKind, key, cachedExtraction{Invoice: invoice, Confidence: confidence}); err != nil {
+		p.log.Warn().Err(err).Msg("Failed to cache Document AI extraction")
+	}
+
+	return invoice, confidence, nil
+}
+
+// ProcessMultiInvoice splits and extracts the PDF without caching
+func (p *CachedInvoiceProcessor) ProcessMultiInvoice(ctx context.Context, pdfData io.Reader) ([]*models.Invoice, error) {
+	return p.processor.ProcessMultiInvoice(ctx, pdfData)
+}
+
+// Close closes the wrapped processor if it holds a client
+func (p *CachedInvoiceProcessor) Close() error {
+	if closer, ok := p.processor.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// pagesVariant encodes a page selection for the cache key, e.g. "p1_3"
+func pagesVariant(pages []int32) string {
+	if len(pages) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(pages))
+	for i, page := range pages {
+		parts[i] = fmt.Sprintf("%d", page)
+	}
+	return "p" + strings.Join(parts, "_")
+}
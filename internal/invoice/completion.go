@@ -7,13 +7,16 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/sashabaranov/go-openai"
+	"tools/internal/llm"
 	"tools/internal/logger"
+	"tools/internal/money"
 	"tools/internal/ocr"
 	"tools/pkg/models"
 )
@@ -26,25 +29,41 @@ type InvoiceCompletionService interface {
 	// ValidateInvoice checks if all required fields are present
 	ValidateInvoice(invoice *models.Invoice) (bool, []string)
 
-	// CompleteInvoiceWithConfidence returns completed invoice with confidence scores
+	// CompleteInvoiceWithConfidence returns completed invoice with confidence scores. When completion
+	// determined the invoice type, the "type" entry holds ChatGPT's type confidence and the invoice's
+	// TypeReasoning explains the decision.
 	CompleteInvoiceWithConfidence(ctx context.Context, invoice *models.Invoice, pdfData io.Reader) (*models.Invoice, map[string]float32, error)
 }
 
 // CompletionConfig configures the invoice completion service
 type CompletionConfig struct {
-	CompanyName       string    // Our company name for context
-	CompanyAliases    []string  // Alternative names/DBAs
-	RequireAllFields  bool      // Fail if can't complete all fields
-	MaxRetries        int       // ChatGPT retry attempts
-	OpenAIModel       string    // gpt-4, gpt-3.5-turbo
-	Temperature       float32   // ChatGPT temperature
-	OCRConfidenceMin  float32   // Minimum OCR confidence
+	CompanyName            string                                        // Our company name for context
+	CompanyAliases         []string                                      // Alternative names/DBAs
+	CompanyVATIDs          []string                                      // Our VAT IDs (USt-IdNr.); the side of the invoice they are on decides the type
+	CompanyAddress         string                                        // Our postal address, e.g. "Musterstraße 12, 10115 Berlin"; confirms PAYABLE in the recipient block
+	RequireAllFields       bool                                          // Fail if can't complete all fields
+	MaxRetries             int                                           // ChatGPT retry attempts
+	OpenAIModel            string                                        // gpt-4, gpt-3.5-turbo
+	FallbackModel          string                                        // Stronger model for the last attempt after invalid answers, e.g. gpt-4o; empty keeps OpenAIModel
+	Temperature            float32                                       // ChatGPT temperature
+	OCRConfidenceMin       float32                                       // Minimum OCR confidence
+	FailOnLowOCRConfidence bool                                          // Abort completion instead of warning when OCR confidence is below OCRConfidenceMin
+	Pages                  []int32                                       // 1-based pages to OCR; nil for all pages
+	InferVAT               bool                                          // Back-calculate net and VAT for gross-only invoices
+	AssumedVATRate         float64                                       // VAT rate in percent for InferVAT when the OCR text names none
+	VendorVATRate          func(invoice *models.Invoice) (float64, bool) // Default VAT rate of the counterparty; splits its gross-only invoices even without InferVAT
+	Deskew                 bool                                          // Rebuild the OCR text of rotated or skewed pages in reading order
+	SummaryLanguage        string                                        // "en" requests an English accounting summary; anything else keeps German
+	NoSummary              bool                                          // Do not request an accounting summary, saving tokens in large batches
+	SummaryStyle           string                                        // SummaryStyleTerse or SummaryStyleDetailed; empty for a one-sentence summary
+	NoSummaryAccount       bool                                          // Leave the Kontierungsvorschlag out of the accounting summary
+	IncludeRawText         bool                                          // Keep the OCR text completion worked on in Invoice.OCRText
 }
 
 // DefaultInvoiceCompletionService implements InvoiceCompletionService
 type DefaultInvoiceCompletionService struct {
 	ocrService   ocr.OCRService
-	openaiClient *openai.Client
+	openaiClient llm.LLMClient
 	config       CompletionConfig
 	log          zerolog.Logger
 }
@@ -60,54 +79,85 @@ type ChatGPTResponse struct {
 	InvoiceNumber     string `json:"invoice_number,omitempty"`
 	IssueDate         string `json:"issue_date,omitempty"`
 	DueDate           string `json:"due_date,omitempty"`
+	ServiceDate       string `json:"service_date,omitempty"` // Leistungsdatum
 	NetAmount         string `json:"net_amount,omitempty"`
 	VATAmount         string `json:"vat_amount,omitempty"`
 	GrossAmount       string `json:"gross_amount,omitempty"`
 	Currency          string `json:"currency,omitempty"`
-	Reference         string `json:"reference,omitempty"`
+	PurchaseOrder     string `json:"purchase_order,omitempty"`
+	CustomerReference string `json:"customer_reference,omitempty"`
+	CustomerEmail     string `json:"customer_email,omitempty"`
+	CustomerContact   string `json:"customer_contact,omitempty"`
+	CustomerAddress   string `json:"customer_address,omitempty"`
 	Description       string `json:"description,omitempty"`
+
+	// Installments if the invoice splits the payment; empty for a single payment
+	PaymentSchedule []ChatGPTInstallment `json:"payment_schedule,omitempty"`
+}
+
+// ChatGPTInstallment is one installment of a payment schedule as returned by ChatGPT
+type ChatGPTInstallment struct {
+	Amount      string `json:"amount"`
+	DueDate     string `json:"due_date"`
+	Description string `json:"description"`
 }
 
 // NewInvoiceCompletionService creates service with dependencies from environment
 func NewInvoiceCompletionService(ctx context.Context) (InvoiceCompletionService, error) {
-	const op = "NewInvoiceCompletionService"
+	return NewInvoiceCompletionServiceWithConfig(ctx, CompletionConfigFromEnv())
+}
+
+// NewInvoiceCompletionServiceWithConfig creates service with clients from environment and an explicit config
+func NewInvoiceCompletionServiceWithConfig(ctx context.Context, config CompletionConfig) (InvoiceCompletionService, error) {
+	const op = "NewInvoiceCompletionServiceWithConfig"
 
 	// Create OCR service
-	ocrService, err := ocr.NewGoogleVisionOCRService(ctx)
+	ocrService, err := ocr.NewGoogleVisionOCRServiceWithOptions(ctx, ocr.VisionOptions{Deskew: config.Deskew})
 	if err != nil {
 		return nil, fmt.Errorf("%s: failed to create OCR service: %w", op, err)
 	}
+	ocrService = ocr.NewLimitedOCRService(ocrService, ocr.MaxConcurrentFromEnv())
 
-	// Get OpenAI API key
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("%s: OPENAI_API_KEY environment variable is required", op)
+	// Create LLM client for the configured provider
+	openaiClient, err := llm.NewClientFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// Create OpenAI client
-	openaiClient := openai.NewClient(apiKey)
+	return NewInvoiceCompletionServiceWithDeps(ocrService, openaiClient, config), nil
+}
 
-	// Load configuration from environment
+// CompletionConfigFromEnv loads the completion configuration from environment variables
+func CompletionConfigFromEnv() CompletionConfig {
 	openaiModel := os.Getenv("OPENAI_MODEL")
 	if openaiModel == "" {
 		openaiModel = "gpt-3.5-turbo"
 	}
-	
+
 	companyName := os.Getenv("COMPANY_NAME")
 	if companyName == "" {
 		companyName = "YOUR_COMPANY"
 	}
 
 	config := CompletionConfig{
-		CompanyName:      companyName,
-		RequireAllFields: os.Getenv("REQUIRE_ALL_FIELDS") == "true",
-		MaxRetries:       parseIntEnv("COMPLETION_MAX_RETRIES", 3),
-		OpenAIModel:      openaiModel,
-		Temperature:      parseFloatEnv("OPENAI_TEMPERATURE", 0.1),
-		OCRConfidenceMin: parseFloatEnv("OCR_CONFIDENCE_MIN", 0.0),
+		CompanyName:            companyName,
+		RequireAllFields:       os.Getenv("REQUIRE_ALL_FIELDS") == "true",
+		MaxRetries:             parseIntEnv("COMPLETION_MAX_RETRIES", 3),
+		OpenAIModel:            openaiModel,
+		FallbackModel:          os.Getenv("OPENAI_FALLBACK_MODEL"),
+		Temperature:            parseFloatEnv("OPENAI_TEMPERATURE", 0.1),
+		OCRConfidenceMin:       parseFloatEnv("OCR_CONFIDENCE_MIN", 0.0),
+		FailOnLowOCRConfidence: os.Getenv("FAIL_ON_LOW_OCR_CONFIDENCE") == "true",
+		InferVAT:               os.Getenv("INFER_VAT") == "true",
+		AssumedVATRate:         float64(parseFloatEnv("ASSUMED_VAT_RATE", DefaultAssumedVATRate)),
+		Deskew:                 os.Getenv("OCR_DESKEW") == "true",
+		SummaryLanguage:        os.Getenv("OUTPUT_LANGUAGE"),
+		NoSummary:              os.Getenv("ACCOUNTING_SUMMARY") == "false",
+		SummaryStyle:           os.Getenv("SUMMARY_STYLE"),
+		NoSummaryAccount:       os.Getenv("SUMMARY_KONTIERUNG") == "false",
+		CompanyAddress:         strings.TrimSpace(os.Getenv("COMPANY_ADDRESS")),
 	}
 
-
 	// Parse company aliases
 	if aliases := os.Getenv("COMPANY_ALIASES"); aliases != "" {
 		config.CompanyAliases = strings.Split(aliases, ",")
@@ -116,11 +166,28 @@ func NewInvoiceCompletionService(ctx context.Context) (InvoiceCompletionService,
 		}
 	}
 
-	return NewInvoiceCompletionServiceWithDeps(ocrService, openaiClient, config), nil
+	// Parse company VAT IDs
+	if vatIDs := os.Getenv("COMPANY_VAT_IDS"); vatIDs != "" {
+		for _, vatID := range strings.Split(vatIDs, ",") {
+			if vatID = strings.TrimSpace(vatID); vatID != "" {
+				config.CompanyVATIDs = append(config.CompanyVATIDs, vatID)
+			}
+		}
+	}
+
+	return config
+}
+
+// Close closes the OCR client
+func (s *DefaultInvoiceCompletionService) Close() error {
+	if closer, ok := s.ocrService.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
 // NewInvoiceCompletionServiceWithDeps creates service with explicit dependencies
-func NewInvoiceCompletionServiceWithDeps(ocrService ocr.OCRService, openaiClient *openai.Client, config CompletionConfig) InvoiceCompletionService {
+func NewInvoiceCompletionServiceWithDeps(ocrService ocr.OCRService, openaiClient llm.LLMClient, config CompletionConfig) InvoiceCompletionService {
 	return &DefaultInvoiceCompletionService{
 		ocrService:   ocrService,
 		openaiClient: openaiClient,
@@ -140,7 +207,7 @@ func (s *DefaultInvoiceCompletionService) ValidateInvoice(invoice *models.Invoic
 	if invoice.Vendor == "" {
 		missingFields = append(missingFields, "vendor")
 	}
-	if invoice.Type == "" || (invoice.Type != "PAYABLE" && invoice.Type != "RECEIVABLE") {
+	if (invoice.Type != "PAYABLE" && invoice.Type != "RECEIVABLE") && !invoice.Internal {
 		missingFields = append(missingFields, "type")
 	}
 	if invoice.IssueDate.IsZero() {
@@ -166,7 +233,10 @@ func (s *DefaultInvoiceCompletionService) ValidateInvoice(invoice *models.Invoic
 		missingFields = append(missingFields, "due_date")
 	}
 
-	if invoice.NetAmount <= 0 && !s.config.RequireAllFields {
+	if hasNoAmounts(invoice) {
+		// Document AI found no amounts at all, so nothing can be calculated - ask for every amount
+		missingFields = append(missingFields, "net_amount", "vat_amount")
+	} else if invoice.NetAmount <= 0 && !s.config.RequireAllFields {
 		// Net amount can be calculated
 	} else if invoice.NetAmount <= 0 {
 		missingFields = append(missingFields, "net_amount")
@@ -194,7 +264,15 @@ func (s *DefaultInvoiceCompletionService) CompleteInvoiceWithConfidence(ctx cont
 	isValid, missingFields := s.ValidateInvoice(invoice)
 	if isValid {
 		s.log.Info().Msg("Invoice is already complete")
-		return invoice, make(map[string]float32), nil
+		confidence := make(map[string]float32)
+		if _, _, ok := s.defaultVATRate(invoice); ok && isGrossOnly(invoice) {
+			// Nothing to OCR for, so only the vendor or assumed rate is available
+			completedInvoice := *invoice
+			s.inferVATFromGross(&completedInvoice, "", confidence)
+			s.logCompletionChanges(invoice, &completedInvoice)
+			return &completedInvoice, confidence, nil
+		}
+		return invoice, confidence, nil
 	}
 
 	s.log.Info().
@@ -209,7 +287,7 @@ func (s *DefaultInvoiceCompletionService) CompleteInvoiceWithConfidence(ctx cont
 
 	// 3. OCR the PDF to get text
 	s.log.Info().Msg("Extracting text from PDF using OCR")
-	ocrResult, err := s.ocrService.ProcessPDFWithMetadata(ctx, bytes.NewReader(pdfBytes))
+	ocrResult, err := s.ocrService.ProcessPDFPages(ctx, bytes.NewReader(pdfBytes), s.config.Pages)
 	if err != nil {
 		return nil, nil, fmt.Errorf("%s: OCR failed: %w", op, err)
 	}
@@ -225,14 +303,23 @@ func (s *DefaultInvoiceCompletionService) CompleteInvoiceWithConfidence(ctx cont
 
 	// Check OCR confidence
 	if ocrResult.Confidence < s.config.OCRConfidenceMin {
+		if s.config.FailOnLowOCRConfidence {
+			return nil, nil, fmt.Errorf("%s: %w: %.2f < %.2f", op, ErrLowOCRConfidence, ocrResult.Confidence, s.config.OCRConfidenceMin)
+		}
 		s.log.Warn().
 			Float32("confidence", ocrResult.Confidence).
 			Float32("minimum", s.config.OCRConfidenceMin).
 			Msg("OCR confidence below minimum threshold")
 	}
 
-	// 4. Use ChatGPT to extract missing information
-	chatGPTResponse, err := s.extractInvoiceFromText(ctx, ocrResult.Text, missingFields, invoice)
+	// 4. Use ChatGPT to extract missing information, instructed in the document's language
+	prompts, promptLanguage := completionPromptsFor(ocrResult.DominantLanguage())
+	s.log.Debug().
+		Strs("language_codes", ocrResult.LanguageCodes).
+		Str("prompt_language", promptLanguage).
+		Msg("Selected completion prompt")
+	promptText := s.promptOCRText(ocrResult.Text, prompts.pageMarker)
+	chatGPTResponse, err := s.extractInvoiceFromText(ctx, prompts, promptText, missingFields, invoice)
 	if err != nil {
 		return nil, nil, fmt.Errorf("%s: ChatGPT extraction failed: %w", op, err)
 	}
@@ -240,6 +327,9 @@ func (s *DefaultInvoiceCompletionService) CompleteInvoiceWithConfidence(ctx cont
 	// 5. Create completed invoice by merging data
 	completedInvoice := *invoice // Copy original
 	confidence := make(map[string]float32)
+	if s.config.IncludeRawText {
+		completedInvoice.OCRText = ocrResult.Text
+	}
 
 	// Apply ChatGPT results to missing fields
 	err = s.mergeCompletionResults(&completedInvoice, chatGPTResponse, missingFields, confidence)
@@ -247,11 +337,40 @@ func (s *DefaultInvoiceCompletionService) CompleteInvoiceWithConfidence(ctx cont
 		return nil, nil, fmt.Errorf("%s: failed to merge completion results: %w", op, err)
 	}
 
-	// 6. Final validation
-	if err := s.validateCompletedInvoice(&completedInvoice); err != nil {
+	// Our VAT ID on one side decides the type; invoices between our own companies get no type, it
+	// must be given with --type
+	if !s.applyVATIDType(&completedInvoice, confidence) {
+		s.markInternalInvoice(&completedInvoice, confidence)
+		s.checkRecipientAddress(&completedInvoice, confidence)
+	}
+
+	// 6. Re-extract amounts on their own if the general completion still found none
+	if hasNoAmounts(&completedInvoice) {
+		s.log.Warn().Msg("No amounts found after completion, retrying amount extraction from OCR text")
+		if err := s.completeAmountsFromText(ctx, s.promptOCRText(ocrResult.Text, germanCompletionPrompts.pageMarker), &completedInvoice, confidence); err != nil {
+			s.log.Warn().Err(err).Msg("Amount re-extraction failed")
+		}
+	}
+
+	// A Kleinunternehmer note in the OCR text explains a missing VAT amount
+	if !completedInvoice.SmallBusiness && markSmallBusiness(&completedInvoice, ocrResult.Text) {
+		s.log.Info().Msg("Invoice cites §19 UStG and charges no VAT, vendor is a Kleinunternehmer")
+	}
+
+	// 7. Split gross-only receipts into net and VAT if enabled or the vendor has a default rate
+	s.inferVATFromGross(&completedInvoice, ocrResult.Text, confidence)
+
+	// The OCR text may show prepayment and payment status cues the Document AI text missed
+	applyPrepaymentDetection(&completedInvoice, ocrResult.Text, confidence)
+	applyPaymentStatusDetection(&completedInvoice, ocrResult.Text, confidence)
+
+	// 8. Final validation
+	if err := s.validateCompletedInvoice(&completedInvoice, ocrResult.Text); err != nil {
 		return nil, nil, fmt.Errorf("%s: completed invoice validation failed: %w", op, err)
 	}
 
+	s.logCompletionChanges(invoice, &completedInvoice)
+
 	s.log.Info().
 		Str("type", completedInvoice.Type).
 		Str("vendor", completedInvoice.Vendor).
@@ -263,11 +382,24 @@ func (s *DefaultInvoiceCompletionService) CompleteInvoiceWithConfidence(ctx cont
 	return &completedInvoice, confidence, nil
 }
 
+// logCompletionChanges logs every field completion filled in or overwrote, so it is visible whether
+// a value came from Document AI or from ChatGPT
+func (s *DefaultInvoiceCompletionService) logCompletionChanges(original, completed *models.Invoice) {
+	for _, change := range DiffInvoices(original, completed) {
+		s.log.Info().
+			Str("field", change.Field).
+			Str("kind", change.Kind).
+			Str("before", change.Before).
+			Str("after", change.After).
+			Msg("Invoice field changed by completion")
+	}
+}
+
 // extractInvoiceFromText uses ChatGPT to extract missing invoice information
-func (s *DefaultInvoiceCompletionService) extractInvoiceFromText(ctx context.Context, ocrText string, missingFields []string, partialInvoice *models.Invoice) (*ChatGPTResponse, error) {
+func (s *DefaultInvoiceCompletionService) extractInvoiceFromText(ctx context.Context, prompts *completionPrompts, ocrText string, missingFields []string, partialInvoice *models.Invoice) (*ChatGPTResponse, error) {
 	const op = "extractInvoiceFromText"
 
-	prompt := s.buildCompletionPrompt(ocrText, missingFields, partialInvoice)
+	prompt := s.buildCompletionPrompt(prompts, ocrText, missingFields, partialInvoice)
 
 	s.log.Debug().
 		Int("prompt_length", len(prompt)).
@@ -277,14 +409,17 @@ func (s *DefaultInvoiceCompletionService) extractInvoiceFromText(ctx context.Con
 		Msg("Sending completion request to ChatGPT")
 
 	var lastErr error
+	invalidAnswer := false
 	for attempt := 1; attempt <= s.config.MaxRetries; attempt++ {
+		model := s.attemptModel(attempt, invalidAnswer)
+		invalidAnswer = false
 		resp, err := s.openaiClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-			Model:       s.config.OpenAIModel,
+			Model:       model,
 			Temperature: s.config.Temperature,
 			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    openai.ChatMessageRoleSystem,
-					Content: s.getSystemPrompt(),
+					Content: s.getSystemPrompt(prompts),
 				},
 				{
 					Role:    openai.ChatMessageRoleUser,
@@ -295,6 +430,10 @@ func (s *DefaultInvoiceCompletionService) extractInvoiceFromText(ctx context.Con
 		})
 
 		if err != nil {
+			// A rejected API key or an exhausted quota fails every retry the same way
+			if class := llm.FailureClass(err); class != "" {
+				return nil, fmt.Errorf("%s: ChatGPT request failed (%s): %w", op, class, err)
+			}
 			lastErr = err
 			s.log.Warn().
 				Err(err).
@@ -306,6 +445,7 @@ func (s *DefaultInvoiceCompletionService) extractInvoiceFromText(ctx context.Con
 
 		if len(resp.Choices) == 0 {
 			lastErr = fmt.Errorf("no response choices from ChatGPT")
+			invalidAnswer = true
 			continue
 		}
 
@@ -316,8 +456,9 @@ func (s *DefaultInvoiceCompletionService) extractInvoiceFromText(ctx context.Con
 
 		// Parse JSON response with robust confidence handling
 		var rawResponse map[string]interface{}
-		if err := json.Unmarshal([]byte(content), &rawResponse); err != nil {
+		if err := json.Unmarshal([]byte(llm.ExtractJSON(content)), &rawResponse); err != nil {
 			lastErr = fmt.Errorf("failed to parse ChatGPT JSON response: %w", err)
+			invalidAnswer = true
 			s.log.Warn().
 				Err(err).
 				Str("response", content).
@@ -336,12 +477,18 @@ func (s *DefaultInvoiceCompletionService) extractInvoiceFromText(ctx context.Con
 			InvoiceNumber:     getString(rawResponse, "invoice_number"),
 			IssueDate:         getString(rawResponse, "issue_date"),
 			DueDate:           getString(rawResponse, "due_date"),
+			ServiceDate:       getString(rawResponse, "service_date"),
 			NetAmount:         getString(rawResponse, "net_amount"),
 			VATAmount:         getString(rawResponse, "vat_amount"),
 			GrossAmount:       getString(rawResponse, "gross_amount"),
 			Currency:          getString(rawResponse, "currency"),
-			Reference:         getString(rawResponse, "reference"),
+			PurchaseOrder:     getString(rawResponse, "purchase_order"),
+			CustomerReference: getString(rawResponse, "customer_reference"),
+			CustomerEmail:     getString(rawResponse, "customer_email"),
+			CustomerContact:   getString(rawResponse, "customer_contact"),
+			CustomerAddress:   getString(rawResponse, "customer_address"),
 			Description:       getString(rawResponse, "description"),
+			PaymentSchedule:   getInstallments(rawResponse, "payment_schedule"),
 		}
 
 		// Handle confidence as either string or number
@@ -359,8 +506,9 @@ func (s *DefaultInvoiceCompletionService) extractInvoiceFromText(ctx context.Con
 		}
 
 		// Validate type field is present and valid
-		if chatGPTResponse.Type == "" || (chatGPTResponse.Type != "PAYABLE" && chatGPTResponse.Type != "RECEIVABLE") {
+		if chatGPTResponse.Type != "PAYABLE" && chatGPTResponse.Type != "RECEIVABLE" && chatGPTResponse.Type != "INTERNAL" {
 			lastErr = fmt.Errorf("invalid or missing type in ChatGPT response: %s", chatGPTResponse.Type)
+			invalidAnswer = true
 			s.log.Warn().
 				Str("type", chatGPTResponse.Type).
 				Int("attempt", attempt).
@@ -380,6 +528,8 @@ func (s *DefaultInvoiceCompletionService) extractInvoiceFromText(ctx context.Con
 			Str("reasoning", chatGPTResponse.TypeReasoning).
 			Str("accounting_summary", chatGPTResponse.AccountingSummary).
 			Int("attempt", attempt).
+			Str("model", model).
+			Bool("escalated", model != s.config.OpenAIModel).
 			Msg("Successfully extracted invoice data from ChatGPT")
 
 		return &chatGPTResponse, nil
@@ -388,146 +538,232 @@ func (s *DefaultInvoiceCompletionService) extractInvoiceFromText(ctx context.Con
 	return nil, fmt.Errorf("%s: all %d attempts failed, last error: %w", op, s.config.MaxRetries, lastErr)
 }
 
+// attemptModel returns the model of a completion attempt: the fallback model for the last of several
+// attempts if the one before returned an invalid answer, which the same model tends to repeat, and the
+// configured model otherwise. Failed requests are retried with the same model.
+func (s *DefaultInvoiceCompletionService) attemptModel(attempt int, afterInvalidAnswer bool) string {
+	fallback := s.config.FallbackModel
+	if fallback == "" || fallback == s.config.OpenAIModel || attempt < 2 || attempt < s.config.MaxRetries || !afterInvalidAnswer {
+		return s.config.OpenAIModel
+	}
+
+	s.log.Info().
+		Str("model", s.config.OpenAIModel).
+		Str("fallback_model", fallback).
+		Int("attempt", attempt).
+		Msg("Escalating the last completion attempt to the fallback model")
+	return fallback
+}
+
+// completeAmountsFromText asks ChatGPT for the invoice amounts only, used when the full completion yielded none
+func (s *DefaultInvoiceCompletionService) completeAmountsFromText(ctx context.Context, ocrText string, invoice *models.Invoice, confidence map[string]float32) error {
+	const op = "completeAmountsFromText"
+
+	var prompt strings.Builder
+	prompt.WriteString("Extrahiere ausschließlich die Beträge aus dieser Rechnung.\n")
+	prompt.WriteString("Suche nach Begriffen wie Gesamtbetrag, Rechnungsbetrag, Summe, Brutto, Netto, MwSt, USt, Total, Amount due.\n")
+	prompt.WriteString("Bei Gutschriften sind negative Beträge erlaubt.\n\n")
+	prompt.WriteString("OCR Text:\n")
+	prompt.WriteString(ocrText)
+	prompt.WriteString("\n\nGib JSON zurück:\n")
+	prompt.WriteString("{\n")
+	prompt.WriteString(`  "net_amount": "amount before tax as string (null wenn nicht vorhanden)",` + "\n")
+	prompt.WriteString(`  "vat_amount": "tax amount as string (null wenn nicht vorhanden)",` + "\n")
+	prompt.WriteString(`  "gross_amount": "total amount as string (null wenn nicht vorhanden)",` + "\n")
+	prompt.WriteString(`  "currency": "currency code like EUR, USD"` + "\n")
+	prompt.WriteString("}\n\n")
+	prompt.WriteString("AUSSCHLIESSLICH gültiges JSON ohne Text davor oder danach!")
+
+	var lastErr error
+	for attempt := 1; attempt <= s.config.MaxRetries; attempt++ {
+		resp, err := s.openaiClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+			Model:       s.config.OpenAIModel,
+			Temperature: s.config.Temperature,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: "Du bist ein Experte für deutsche Rechnungen. Antworte nur mit gültigem JSON.",
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompt.String(),
+				},
+			},
+			MaxTokens: 300,
+		})
+		if err != nil {
+			if class := llm.FailureClass(err); class != "" {
+				return fmt.Errorf("%s: ChatGPT request failed (%s): %w", op, class, err)
+			}
+			lastErr = err
+			continue
+		}
+		if len(resp.Choices) == 0 {
+			lastErr = fmt.Errorf("no response choices from ChatGPT")
+			continue
+		}
+
+		var rawResponse map[string]interface{}
+		if err := json.Unmarshal([]byte(llm.ExtractJSON(resp.Choices[0].Message.Content)), &rawResponse); err != nil {
+			lastErr = fmt.Errorf("failed to parse ChatGPT JSON response: %w", err)
+			continue
+		}
+
+		response := &ChatGPTResponse{
+			NetAmount:   getString(rawResponse, "net_amount"),
+			VATAmount:   getString(rawResponse, "vat_amount"),
+			GrossAmount: getString(rawResponse, "gross_amount"),
+		}
+		if invoice.Currency == "" {
+			response.Currency = getString(rawResponse, "currency")
+		}
+
+		amountFields := []string{"net_amount", "vat_amount", "gross_amount", "currency"}
+		if err := s.mergeCompletionResults(invoice, response, amountFields, confidence); err != nil {
+			return fmt.Errorf("%s: failed to merge amounts: %w", op, err)
+		}
+
+		if hasNoAmounts(invoice) {
+			lastErr = fmt.Errorf("no amounts found in OCR text")
+			continue
+		}
+
+		s.log.Info().
+			Int64("gross_amount", invoice.GrossAmount).
+			Int64("net_amount", invoice.NetAmount).
+			Int64("vat_amount", invoice.VATAmount).
+			Int("attempt", attempt).
+			Msg("Recovered amounts from OCR text")
+		return nil
+	}
+
+	return fmt.Errorf("%s: all %d attempts failed, last error: %w", op, s.config.MaxRetries, lastErr)
+}
+
 // getSystemPrompt returns the system prompt for ChatGPT that emphasizes invoice type determination
-func (s *DefaultInvoiceCompletionService) getSystemPrompt() string {
-	return fmt.Sprintf(`Du analysierst Rechnungen für %s. Deine wichtigste Aufgabe ist die korrekte Bestimmung des Rechnungstyps.
-
-KRITISCH: Bestimme ob diese Rechnung PAYABLE oder RECEIVABLE ist:
-
-** PAYABLE (Eingangsrechnung) = WIR MÜSSEN ZAHLEN **
-- Rechnung VON einem Lieferanten AN unser Unternehmen
-- Wir sind der Käufer/Rechnungsempfänger
-- Zahlungsanweisungen: Geld soll AN den Lieferanten/Verkäufer
-- Bankverbindung gehört dem Verkäufer/Lieferanten
-- Typische Begriffe: "Rechnung an", "Invoice To", "Bill To" + unser Firmenname
-- Lieferant/Verkäufer ist NICHT unser Unternehmen
-
-** RECEIVABLE (Ausgangsrechnung) = WIR BEKOMMEN GELD **
-- Rechnung VON unserem Unternehmen AN einen Kunden
-- Wir sind der Verkäufer/Rechnungssteller
-- Zahlungsanweisungen: Geld soll AN unser Unternehmen
-- Bankverbindung gehört uns
-- Typische Begriffe: "From" + unser Firmenname, wir sind der Absender
-- Kunde ist NICHT unser Unternehmen
-
-ENTSCHEIDUNGSHILFEN:
-1. Wer stellt die Rechnung? (From/Absender) → Wenn wir = RECEIVABLE
-2. Wer soll zahlen? (To/Empfänger) → Wenn wir = PAYABLE  
-3. Wessen Bankdaten stehen drauf? → Wenn unsere = RECEIVABLE
-4. Deutsche Begriffe: "Lieferant", "Anbieter", "Verkäufer" → meist PAYABLE für uns
-
-ACCOUNTING SUMMARY: Create a German prose summary describing ONLY what goods/services are being billed:
-- Focus on WHAT was purchased or what service was provided
-- Do NOT mention amounts, dates, or invoice details
-- Include a suggested German accounting category (Kontierungsvorschlag)
-- Examples:
-  * "IT-Equipment bestehend aus 5 Laptops und 10 Monitoren für Arbeitsplatzausstattung, Kontierung: IT-Hardware/Anlagegüter"
-  * "Büromaterialbestellung mit Druckerpapier, Toner und Schreibwaren, Kontierung: Bürobedarf"
-  * "Monatliche Cloud-Hosting Gebühren für Produktionsserver, Kontierung: IT-Infrastruktur/laufende Kosten"
-  * "Beratungsleistungen für SAP-Migration, Kontierung: Externe Dienstleistungen/Projekte"
-
-Company Context:
-- Our company: %s
-- Aliases: %s
-
-IMPORTANT: Return ONLY valid JSON with NO trailing commas. 
-- Use null for missing values
-- Amounts should be in the original currency format (e.g., "580.00" for 580 euros)
-- Dates should be in YYYY-MM-DD format
-- Ensure the JSON is perfectly formatted with no syntax errors
-- Do NOT add a trailing comma after the last field`,
+func (s *DefaultInvoiceCompletionService) getSystemPrompt(prompts *completionPrompts) string {
+	return fmt.Sprintf(prompts.system,
 		s.config.CompanyName,
+		summaryInstructions(s.config),
 		s.config.CompanyName,
 		strings.Join(s.config.CompanyAliases, ", "))
 }
 
 // buildCompletionPrompt creates the user prompt for ChatGPT
-func (s *DefaultInvoiceCompletionService) buildCompletionPrompt(ocrText string, missingFields []string, partialInvoice *models.Invoice) string {
+func (s *DefaultInvoiceCompletionService) buildCompletionPrompt(prompts *completionPrompts, ocrText string, missingFields []string, partialInvoice *models.Invoice) string {
 	var prompt strings.Builder
+	field := func(name string) {
+		fmt.Fprintf(&prompt, "  %q: %q,\n", name, prompts.fields[name])
+	}
 
-	prompt.WriteString("Analysiere diese Rechnung und extrahiere die fehlenden Informationen:\n\n")
+	prompt.WriteString(prompts.intro)
 
 	// Current invoice data for context with type hints
-	prompt.WriteString("Bereits extrahierte Daten:\n")
+	prompt.WriteString(prompts.knownData)
 	if partialInvoice.Vendor != "" {
-		prompt.WriteString(fmt.Sprintf("Vendor/Lieferant: %s\n", partialInvoice.Vendor))
+		prompt.WriteString(fmt.Sprintf(prompts.vendor, partialInvoice.Vendor))
 		// Type hint: If we already have a vendor, this is likely PAYABLE
 		if contains(missingFields, "type") {
-			prompt.WriteString("HINWEIS: Da bereits ein Vendor/Lieferant erkannt wurde, ist dies wahrscheinlich eine PAYABLE Rechnung (Eingangsrechnung)\n")
+			prompt.WriteString(prompts.vendorHint)
 		}
 	}
 	if partialInvoice.Customer != "" {
-		prompt.WriteString(fmt.Sprintf("Customer/Kunde: %s\n", partialInvoice.Customer))
+		prompt.WriteString(fmt.Sprintf(prompts.customer, partialInvoice.Customer))
 		// Type hint: If we already have a customer, this is likely RECEIVABLE  
 		if contains(missingFields, "type") {
-			prompt.WriteString("HINWEIS: Da bereits ein Customer/Kunde erkannt wurde, ist dies wahrscheinlich eine RECEIVABLE Rechnung (Ausgangsrechnung)\n")
+			prompt.WriteString(prompts.customerHint)
 		}
 	}
 	if partialInvoice.InvoiceNumber != "" {
-		prompt.WriteString(fmt.Sprintf("Rechnungsnummer: %s\n", partialInvoice.InvoiceNumber))
+		prompt.WriteString(fmt.Sprintf(prompts.invoiceNumber, partialInvoice.InvoiceNumber))
 	}
 	if partialInvoice.GrossAmount > 0 {
-		prompt.WriteString(fmt.Sprintf("Bruttobetrag: %.2f %s\n", float64(partialInvoice.GrossAmount)/100, partialInvoice.Currency))
+		prompt.WriteString(fmt.Sprintf(prompts.grossAmount, float64(partialInvoice.GrossAmount)/100, partialInvoice.Currency))
 	}
 
 	// Add company context for type determination
 	if contains(missingFields, "type") {
-		prompt.WriteString(fmt.Sprintf("\nFIRMEN-KONTEXT für Typ-Bestimmung:\n"))
-		prompt.WriteString(fmt.Sprintf("Unser Unternehmen: %s\n", s.config.CompanyName))
+		prompt.WriteString(fmt.Sprintf(prompts.companyContext, s.config.CompanyName))
 		if len(s.config.CompanyAliases) > 0 {
-			prompt.WriteString(fmt.Sprintf("Unsere Aliases: %s\n", strings.Join(s.config.CompanyAliases, ", ")))
+			prompt.WriteString(fmt.Sprintf(prompts.aliases, strings.Join(s.config.CompanyAliases, ", ")))
 		}
-		prompt.WriteString("→ Wenn unser Name im 'Bill To'/'Rechnung an' steht = PAYABLE (wir zahlen)\n")
-		prompt.WriteString("→ Wenn unser Name im 'From'/'Von' steht = RECEIVABLE (wir bekommen Geld)\n\n")
+		if len(s.config.CompanyVATIDs) > 0 {
+			prompt.WriteString(fmt.Sprintf(prompts.vatIDs, strings.Join(s.config.CompanyVATIDs, ", ")))
+		}
+		if s.config.CompanyAddress != "" {
+			prompt.WriteString(fmt.Sprintf(prompts.companyAddress, oneLineAddress(s.config.CompanyAddress)))
+			switch s.recipientAddressMatch(partialInvoice) {
+			case addressOurs:
+				prompt.WriteString(fmt.Sprintf(prompts.recipientOurs, partialInvoice.CustomerAddress))
+			case addressOther:
+				prompt.WriteString(fmt.Sprintf(prompts.recipientOther, partialInvoice.CustomerAddress))
+			}
+		}
+		prompt.WriteString(prompts.typeRules)
 	}
 
-	prompt.WriteString("\nOCR Text:\n")
+	prompt.WriteString(prompts.ocrText)
 	prompt.WriteString(ocrText)
 
-	prompt.WriteString("\n\nGib JSON zurück mit diesen Feldern (nur fehlende Felder):\n")
+	prompt.WriteString(prompts.jsonIntro)
 	prompt.WriteString("{\n")
 
 	// Always include type since it's critical and rarely provided by Document AI
 	if contains(missingFields, "type") {
-		prompt.WriteString(`  "type": "PAYABLE oder RECEIVABLE (ERFORDERLICH - siehe Entscheidungshilfen oben)",` + "\n")
-		prompt.WriteString(`  "type_confidence": "Konfidenz-Score 0-1 (0.9+ für eindeutige Indikatoren)",` + "\n")
-		prompt.WriteString(`  "type_reasoning": "Deutsche Begründung der Typ-Bestimmung mit konkreten Textstellen",` + "\n")
+		field("type")
+		field("type_confidence")
+		field("type_reasoning")
+		// The recipient address confirms the type if Document AI did not extract it
+		if s.config.CompanyAddress != "" && partialInvoice.CustomerAddress == "" {
+			field("customer_address")
+		}
+	}
+
+	// Include the accounting summary unless disabled. The SKR terms in the rest of the prompt stay
+	// German; only the summary shown to the user is translated.
+	if summary := summaryField(s.config); summary != "" {
+		prompt.WriteString(summary + "\n")
+	}
+
+	// Leistungsdatum is optional but determines the VAT period, so ask for it whenever Document AI missed it
+	if partialInvoice.ServiceDate.IsZero() {
+		field("service_date")
 	}
 
-	// Always include accounting summary (it's always useful for German accounting)
-	prompt.WriteString(`  "accounting_summary": "German description of goods/services and Kontierungsvorschlag",` + "\n")
+	// Bestellnummer and Kundenreferenz are optional; keep them apart since they serve matching and booking text
+	if partialInvoice.PurchaseOrder == "" {
+		field("purchase_order")
+	}
+	if partialInvoice.CustomerReference == "" {
+		field("customer_reference")
+	}
+
+	// The customer's contact serves collections of unpaid receivables; on incoming invoices it would be our own
+	if partialInvoice.Type != "PAYABLE" {
+		if partialInvoice.CustomerEmail == "" {
+			field("customer_email")
+		}
+		if partialInvoice.CustomerContact == "" {
+			field("customer_contact")
+		}
+	}
+
+	// Installment plans are rare but lost entirely without asking, since Document AI has no such entity
+	if len(partialInvoice.PaymentSchedule) == 0 {
+		field("payment_schedule")
+	}
 
 	// Add other missing fields
-	for _, field := range missingFields {
-		switch field {
-		case "vendor":
-			prompt.WriteString(`  "vendor": "vendor/supplier company name",` + "\n")
-		case "customer":
-			prompt.WriteString(`  "customer": "customer/buyer company name",` + "\n")
-		case "invoice_number":
-			prompt.WriteString(`  "invoice_number": "invoice or reference number",` + "\n")
-		case "issue_date":
-			prompt.WriteString(`  "issue_date": "YYYY-MM-DD",` + "\n")
-		case "due_date":
-			prompt.WriteString(`  "due_date": "YYYY-MM-DD",` + "\n")
-		case "net_amount":
-			prompt.WriteString(`  "net_amount": "amount before tax as string",` + "\n")
-		case "vat_amount":
-			prompt.WriteString(`  "vat_amount": "tax amount as string",` + "\n")
-		case "gross_amount":
-			prompt.WriteString(`  "gross_amount": "total amount as string",` + "\n")
-		case "currency":
-			prompt.WriteString(`  "currency": "currency code like EUR, USD",` + "\n")
-		case "reference":
-			prompt.WriteString(`  "reference": "purchase order or reference number",` + "\n")
-		case "description":
-			prompt.WriteString(`  "description": "brief invoice description",` + "\n")
+	for _, name := range missingFields {
+		switch name {
+		case "vendor", "customer", "invoice_number", "issue_date", "due_date",
+			"net_amount", "vat_amount", "gross_amount", "currency", "description":
+			field(name)
 		}
 	}
 
 	prompt.WriteString("}\n\n")
-	prompt.WriteString("WICHTIG: Stelle sicher dass das JSON KEINE trailing comma nach dem letzten Feld hat. Prüfe die JSON-Syntax sorgfältig!\n")
-	prompt.WriteString("AUSSCHLIESSLICH gültiges JSON ohne Text davor oder danach!")
+	prompt.WriteString(prompts.closing)
 
 	return prompt.String()
 }
@@ -536,7 +772,13 @@ func (s *DefaultInvoiceCompletionService) buildCompletionPrompt(ocrText string,
 func (s *DefaultInvoiceCompletionService) mergeCompletionResults(invoice *models.Invoice, response *ChatGPTResponse, missingFields []string, confidence map[string]float32) error {
 	// Type field (always merge if missing since it's critical)
 	if contains(missingFields, "type") && response.Type != "" {
-		invoice.Type = response.Type
+		if response.Type == "INTERNAL" {
+			// markInternalInvoice sets the confidence once the parties are merged
+			invoice.Internal = true
+		} else {
+			invoice.Type = response.Type
+		}
+		invoice.TypeReasoning = response.TypeReasoning
 		
 		// Parse confidence from string
 		typeConfidence := float32(0.5) // default
@@ -590,6 +832,16 @@ func (s *DefaultInvoiceCompletionService) mergeCompletionResults(invoice *models
 		}
 	}
 
+	// Service Date (Leistungsdatum) - only fill if Document AI didn't provide it
+	if invoice.ServiceDate.IsZero() && response.ServiceDate != "" {
+		if date, err := time.Parse("2006-01-02", response.ServiceDate); err == nil {
+			invoice.ServiceDate = date
+			confidence["service_date"] = 0.8
+		} else {
+			s.log.Warn().Err(err).Str("date", response.ServiceDate).Msg("Failed to parse service date")
+		}
+	}
+
 	// Amounts
 	if contains(missingFields, "net_amount") && response.NetAmount != "" {
 		if amount, err := s.parseAmount(response.NetAmount); err == nil {
@@ -624,10 +876,32 @@ func (s *DefaultInvoiceCompletionService) mergeCompletionResults(invoice *models
 		confidence["currency"] = 0.9
 	}
 
-	// Reference
-	if contains(missingFields, "reference") && response.Reference != "" {
-		invoice.Reference = response.Reference
-		confidence["reference"] = 0.8
+	// Purchase order and customer reference (fill whenever Document AI missed them)
+	if invoice.PurchaseOrder == "" && response.PurchaseOrder != "" {
+		invoice.PurchaseOrder = response.PurchaseOrder
+		confidence["purchase_order"] = 0.8
+	}
+	if invoice.CustomerReference == "" && response.CustomerReference != "" {
+		invoice.CustomerReference = response.CustomerReference
+		confidence["customer_reference"] = 0.8
+	}
+
+	// Recipient address, kept for every type since it confirms the type
+	if invoice.CustomerAddress == "" && response.CustomerAddress != "" {
+		invoice.CustomerAddress = oneLineAddress(response.CustomerAddress)
+		confidence["customer_address"] = 0.7
+	}
+
+	// Customer contact, only kept for receivables
+	if invoice.Type == "RECEIVABLE" {
+		if invoice.CustomerEmail == "" && response.CustomerEmail != "" {
+			invoice.CustomerEmail = response.CustomerEmail
+			confidence["customer_email"] = 0.8
+		}
+		if invoice.CustomerContact == "" && response.CustomerContact != "" {
+			invoice.CustomerContact = response.CustomerContact
+			confidence["customer_contact"] = 0.7
+		}
 	}
 
 	// Description
@@ -636,8 +910,22 @@ func (s *DefaultInvoiceCompletionService) mergeCompletionResults(invoice *models
 		confidence["description"] = 0.8
 	}
 
-	// Accounting Summary (always apply if provided)
-	if response.AccountingSummary != "" {
+	// Payment schedule (fill whenever Document AI has none)
+	if len(invoice.PaymentSchedule) == 0 && len(response.PaymentSchedule) > 0 {
+		if schedule := s.parsePaymentSchedule(response.PaymentSchedule, invoice.GrossAmount); len(schedule) > 0 {
+			invoice.PaymentSchedule = schedule
+			confidence["payment_schedule"] = 0.7
+			if invoice.DueDate.IsZero() {
+				invoice.DueDate = schedule[0].DueDate
+			}
+			s.log.Info().
+				Int("installments", len(schedule)).
+				Msg("Payment schedule extracted")
+		}
+	}
+
+	// Accounting Summary (always apply if provided and requested)
+	if response.AccountingSummary != "" && !s.config.NoSummary {
 		invoice.AccountingSummary = response.AccountingSummary
 		confidence["accounting_summary"] = 0.8
 		s.log.Info().
@@ -651,47 +939,63 @@ func (s *DefaultInvoiceCompletionService) mergeCompletionResults(invoice *models
 	return nil
 }
 
-// parseAmount parses amount string handling both German and English formats
-func (s *DefaultInvoiceCompletionService) parseAmount(amountStr string) (int64, error) {
-	// Clean the amount string
-	cleaned := strings.TrimSpace(amountStr)
-	cleaned = strings.ReplaceAll(cleaned, " ", "")
-	cleaned = strings.ReplaceAll(cleaned, "€", "")
-	cleaned = strings.ReplaceAll(cleaned, "$", "")
-	cleaned = strings.ReplaceAll(cleaned, "EUR", "")
-	cleaned = strings.ReplaceAll(cleaned, "USD", "")
-	
-	// Handle German number format (7.303,08 -> 7303.08)
-	if strings.Contains(cleaned, ",") {
-		// If there's both . and , assume German format (. = thousands, , = decimal)
-		if strings.Contains(cleaned, ".") && strings.Contains(cleaned, ",") {
-			// Remove thousands separators (dots)
-			cleaned = strings.ReplaceAll(cleaned, ".", "")
-			// Replace decimal separator (comma) with dot
-			cleaned = strings.ReplaceAll(cleaned, ",", ".")
-		} else if strings.Contains(cleaned, ",") {
-			// Only comma, could be decimal separator
-			// Count digits after comma to determine if it's decimal
-			parts := strings.Split(cleaned, ",")
-			if len(parts) == 2 && len(parts[1]) <= 2 {
-				// Likely decimal separator (e.g., "1234,50")
-				cleaned = strings.ReplaceAll(cleaned, ",", ".")
+// parsePaymentSchedule converts ChatGPT's installments, ordered by due date. A schedule with fewer than
+// two installments, an unparsable amount or a total different from the gross amount is discarded,
+// since a wrong schedule is worse than the single implicit installment.
+func (s *DefaultInvoiceCompletionService) parsePaymentSchedule(installments []ChatGPTInstallment, grossAmount int64) []models.PaymentInstallment {
+	if len(installments) < 2 {
+		return nil
+	}
+
+	var schedule []models.PaymentInstallment
+	var total int64
+	for _, installment := range installments {
+		amount, err := s.parseAmount(installment.Amount)
+		if err != nil || amount == 0 {
+			s.log.Warn().Str("amount", installment.Amount).Msg("Failed to parse installment amount, ignoring payment schedule")
+			return nil
+		}
+		entry := models.PaymentInstallment{Amount: amount, Description: strings.TrimSpace(installment.Description)}
+		if installment.DueDate != "" {
+			if date, err := time.Parse("2006-01-02", installment.DueDate); err == nil {
+				entry.DueDate = date
+			} else {
+				s.log.Warn().Err(err).Str("date", installment.DueDate).Msg("Failed to parse installment due date")
 			}
 		}
+		schedule = append(schedule, entry)
+		total += amount
 	}
 
-	amount, err := strconv.ParseFloat(cleaned, 64)
-	if err != nil {
-		return 0, fmt.Errorf("unable to parse amount: %s (cleaned: %s)", amountStr, cleaned)
+	// Allow one cent of rounding per installment
+	if grossAmount != 0 {
+		if diff := total - grossAmount; diff > int64(len(schedule)) || diff < -int64(len(schedule)) {
+			s.log.Warn().
+				Int64("installment_total", total).
+				Int64("gross_amount", grossAmount).
+				Msg("Payment schedule does not add up to the gross amount, ignoring it")
+			return nil
+		}
 	}
 
-	return int64(amount * 100), nil
+	sort.SliceStable(schedule, func(i, j int) bool {
+		if schedule[i].DueDate.IsZero() || schedule[j].DueDate.IsZero() {
+			return !schedule[i].DueDate.IsZero() && schedule[j].DueDate.IsZero()
+		}
+		return schedule[i].DueDate.Before(schedule[j].DueDate)
+	})
+	return schedule
+}
+
+// parseAmount parses amount string in German, English or Swiss format, see money.ParseCents
+func (s *DefaultInvoiceCompletionService) parseAmount(amountStr string) (int64, error) {
+	return money.ParseCents(amountStr)
 }
 
 // validateCompletedInvoice performs final validation on the completed invoice
-func (s *DefaultInvoiceCompletionService) validateCompletedInvoice(invoice *models.Invoice) error {
+func (s *DefaultInvoiceCompletionService) validateCompletedInvoice(invoice *models.Invoice, ocrText string) error {
 	// Validate type field
-	if invoice.Type != "PAYABLE" && invoice.Type != "RECEIVABLE" {
+	if invoice.Type != "PAYABLE" && invoice.Type != "RECEIVABLE" && !invoice.Internal {
 		return fmt.Errorf("invalid invoice type: %s, must be PAYABLE or RECEIVABLE", invoice.Type)
 	}
 
@@ -700,7 +1004,7 @@ func (s *DefaultInvoiceCompletionService) validateCompletedInvoice(invoice *mode
 	// (e.g., membership fees, exam fees, etc.)
 	
 	// Check for valid amounts - allow negative amounts for credit notes/refunds
-	if invoice.GrossAmount == 0 && invoice.NetAmount == 0 && invoice.VATAmount == 0 {
+	if hasNoAmounts(invoice) {
 		// All amounts are zero - likely no amount information found
 		return fmt.Errorf("no amount information found after completion")
 	}
@@ -717,6 +1021,9 @@ func (s *DefaultInvoiceCompletionService) validateCompletedInvoice(invoice *mode
 	// Calculate missing amounts if we have enough information
 	s.calculateMissingAmounts(invoice)
 
+	// Completion may have replaced a flagged amount, so the ceiling is checked again
+	checkAmountCeiling(invoice, ocrText, maxInvoiceAmountFromEnv(), s.log)
+
 	return nil
 }
 
@@ -739,6 +1046,11 @@ func (s *DefaultInvoiceCompletionService) calculateMissingAmounts(invoice *model
 	}
 }
 
+// hasNoAmounts reports whether none of the invoice amounts were extracted
+func hasNoAmounts(invoice *models.Invoice) bool {
+	return invoice.GrossAmount == 0 && invoice.NetAmount == 0 && invoice.VATAmount == 0
+}
+
 // contains checks if a string slice contains a value
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -760,6 +1072,32 @@ func getString(m map[string]interface{}, key string) string {
 	return ""
 }
 
+// getInstallments reads a payment schedule array; amounts may be strings or numbers
+func getInstallments(m map[string]interface{}, key string) []ChatGPTInstallment {
+	entries, ok := m[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var installments []ChatGPTInstallment
+	for _, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		installment := ChatGPTInstallment{
+			Amount:      getString(fields, "amount"),
+			DueDate:     getString(fields, "due_date"),
+			Description: getString(fields, "description"),
+		}
+		if amount, ok := fields["amount"].(float64); ok {
+			installment.Amount = strconv.FormatFloat(amount, 'f', 2, 64)
+		}
+		installments = append(installments, installment)
+	}
+	return installments
+}
+
 // normalizeCurrency standardizes currency codes to consistent format
 func (s *DefaultInvoiceCompletionService) normalizeCurrency(currency string) string {
 	if currency == "" {
@@ -135,9 +135,9 @@ func ExampleInvoiceCompletionWithConfidence() {
 	fmt.Printf("  Type: %s (completion confidence: %.1f%%)\n",
 		completedInvoice.Type, completionConfidence["type"]*100)
 	fmt.Printf("  Invoice Number: %s (Document AI confidence: %.1f%%)\n",
-		completedInvoice.InvoiceNumber, documentAIConfidence["invoice_id"]*100)
+		completedInvoice.InvoiceNumber, documentAIConfidence["invoice_number"]*100)
 	fmt.Printf("  Vendor: %s (Document AI confidence: %.1f%%)\n",
-		completedInvoice.Vendor, documentAIConfidence["supplier_name"]*100)
+		completedInvoice.Vendor, documentAIConfidence["vendor"]*100)
 
 	// Show all completion confidence scores
 	fmt.Printf("\nCompletion Service Confidence Scores:\n")
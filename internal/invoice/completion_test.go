@@ -0,0 +1,56 @@
+package invoice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/sashabaranov/go-openai"
+
+	"tools/pkg/models"
+)
+
+// scriptedClient answers the chat completions in turn with the given contents
+type scriptedClient struct {
+	answers []string
+	calls   int
+}
+
+func (c *scriptedClient) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	answer := c.answers[c.calls]
+	c.calls++
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: answer}}}}, nil
+}
+
+func TestCompleteAmountsFromTextRetriesEmptyAnswer(t *testing.T) {
+	t.Setenv("AMOUNT_LOCALE", "")
+	client := &scriptedClient{answers: []string{
+		`{"net_amount": null, "vat_amount": null, "gross_amount": null, "currency": "EUR"}`,
+		`{"net_amount": "100,00", "vat_amount": "19,00", "gross_amount": "119,00", "currency": "EUR"}`,
+	}}
+	s := &DefaultInvoiceCompletionService{openaiClient: client, config: CompletionConfig{MaxRetries: 3}, log: zerolog.Nop()}
+	invoice := &models.Invoice{}
+
+	if err := s.completeAmountsFromText(context.Background(), "Summe 119,00 EUR", invoice, map[string]float32{}); err != nil {
+		t.Fatalf("completeAmountsFromText() error = %v", err)
+	}
+	if client.calls != 2 {
+		t.Errorf("calls = %d, want 2", client.calls)
+	}
+	if invoice.NetAmount != 10000 || invoice.VATAmount != 1900 || invoice.GrossAmount != 11900 {
+		t.Errorf("net, VAT, gross = %d, %d, %d, want 10000, 1900, 11900", invoice.NetAmount, invoice.VATAmount, invoice.GrossAmount)
+	}
+}
+
+func TestCompleteAmountsFromTextFailsAfterAllAttempts(t *testing.T) {
+	empty := `{"net_amount": null, "vat_amount": null, "gross_amount": null}`
+	client := &scriptedClient{answers: []string{empty, empty}}
+	s := &DefaultInvoiceCompletionService{openaiClient: client, config: CompletionConfig{MaxRetries: 2}, log: zerolog.Nop()}
+
+	if err := s.completeAmountsFromText(context.Background(), "Lieferschein", &models.Invoice{}, map[string]float32{}); err == nil {
+		t.Fatal("completeAmountsFromText() without amounts succeeded")
+	}
+	if client.calls != 2 {
+		t.Errorf("calls = %d, want 2", client.calls)
+	}
+}
@@ -0,0 +1,141 @@
+package invoice
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/documentai/apiv1/documentaipb"
+)
+
+// defaultCurrency is used when neither Document AI nor the text reveal the currency
+const defaultCurrency = "EUR"
+
+// currencyWindow is the number of characters around an amount searched for a currency marker
+const currencyWindow = 24
+
+// Confidence of each currency source, from the most to the least specific
+const (
+	currencyConfidenceNormalized = 0.95 // Currency code of Document AI's normalized money value
+	currencyConfidenceTotal      = 0.9  // Marker in the total amount's mention text
+	currencyConfidenceAmount     = 0.8  // Marker in another amount's mention text
+	currencyConfidenceNearAmount = 0.7  // Marker in the OCR text next to an amount
+	currencyConfidenceText       = 0.5  // Most frequent marker anywhere in the OCR text
+)
+
+// currencyMarkers maps currency symbols and ISO codes to ISO codes. Codes must stand alone, so
+// "EUR" matches in "119,00 EUR" but not in "EURONICS"; symbols may touch the digits.
+var currencyMarkers = regexp.MustCompile(`US\$|€|\$|£|¥|\b(?:EUR|USD|GBP|CHF|JPY|PLN|CZK|SEK|DKK|NOK|CAD|AUD|Euro|EURO)\b`)
+
+var currencyMarkerCodes = map[string]string{
+	"€": "EUR", "Euro": "EUR", "EURO": "EUR",
+	"$": "USD", "US$": "USD",
+	"£": "GBP",
+	"¥": "JPY",
+}
+
+// amountEntityTypes are the Document AI entities carrying invoice amounts, the total first
+var amountEntityTypes = []string{"total_amount", "gross_amount", "net_amount", "subtotal_amount", "total_tax_amount", "vat_amount"}
+
+// configuredDefaultCurrency returns DEFAULT_CURRENCY, or EUR if unset
+func configuredDefaultCurrency() string {
+	if currency := strings.ToUpper(strings.TrimSpace(os.Getenv("DEFAULT_CURRENCY"))); len(currency) == 3 {
+		return currency
+	}
+	return defaultCurrency
+}
+
+// inferCurrency derives the invoice currency from the amounts when Document AI emitted no currency
+// entity. Sources are tried from the most to the least specific: the normalized money values, the
+// amount mention texts (the total first), the OCR text around the amounts and finally the whole
+// OCR text. It returns the configured default and zero confidence if no source names a currency.
+func inferCurrency(doc *documentaipb.Document) (string, float32) {
+	amounts := make(map[string][]*documentaipb.Document_Entity)
+	for _, entity := range doc.Entities {
+		amounts[entity.Type] = append(amounts[entity.Type], entity)
+	}
+
+	for _, entityType := range amountEntityTypes {
+		for _, entity := range amounts[entityType] {
+			if money := entity.GetNormalizedValue().GetMoneyValue(); money != nil && len(money.CurrencyCode) == 3 {
+				return strings.ToUpper(money.CurrencyCode), currencyConfidenceNormalized
+			}
+		}
+	}
+
+	for _, entityType := range amountEntityTypes {
+		confidence := float32(currencyConfidenceAmount)
+		if entityType == "total_amount" || entityType == "gross_amount" {
+			confidence = currencyConfidenceTotal
+		}
+		for _, entity := range amounts[entityType] {
+			if currency := dominantCurrency(entity.MentionText); currency != "" {
+				return currency, confidence
+			}
+		}
+	}
+
+	for _, entityType := range amountEntityTypes {
+		for _, entity := range amounts[entityType] {
+			if currency := dominantCurrency(textAround(doc.Text, entity.GetTextAnchor())); currency != "" {
+				return currency, currencyConfidenceNearAmount
+			}
+		}
+	}
+
+	if currency := dominantCurrency(doc.Text); currency != "" {
+		return currency, currencyConfidenceText
+	}
+
+	return configuredDefaultCurrency(), 0
+}
+
+// dominantCurrency returns the currency marked most often in text, "" if none is marked. Ties go
+// to the alphabetically first code so the result does not depend on map order.
+func dominantCurrency(text string) string {
+	counts := make(map[string]int)
+	for _, marker := range currencyMarkers.FindAllString(text, -1) {
+		code, ok := currencyMarkerCodes[marker]
+		if !ok {
+			code = marker
+		}
+		counts[code]++
+	}
+
+	codes := make([]string, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if counts[codes[i]] != counts[codes[j]] {
+			return counts[codes[i]] > counts[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+
+	if len(codes) == 0 {
+		return ""
+	}
+	return codes[0]
+}
+
+// textAround returns the OCR text of an entity extended by currencyWindow characters on each side
+func textAround(text string, anchor *documentaipb.Document_TextAnchor) string {
+	var around strings.Builder
+	for _, segment := range anchor.GetTextSegments() {
+		start, end := int(segment.StartIndex)-currencyWindow, int(segment.EndIndex)+currencyWindow
+		if start < 0 {
+			start = 0
+		}
+		if end > len(text) {
+			end = len(text)
+		}
+		if start >= end {
+			continue
+		}
+		around.WriteString(text[start:end])
+		around.WriteByte(' ')
+	}
+	return around.String()
+}
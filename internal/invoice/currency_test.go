@@ -0,0 +1,127 @@
+package invoice
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/documentai/apiv1/documentaipb"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestDominantCurrency(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Summe 119,00 EUR", "EUR"},
+		{"Summe 119,00€", "EUR"},
+		{"Betrag in Euro", "EUR"},
+		{"Total US$ 50.00", "USD"},
+		{"Total $50.00", "USD"},
+		{"£20.00", "GBP"},
+		{"¥5000", "JPY"},
+		{"Netto 100,00 CHF, MwSt 8,10 CHF, umgerechnet 104,00 EUR", "CHF"},
+		{"10 USD oder 10 EUR", "EUR"},
+		{"EURONICS Filiale 12", ""},
+		{"Rechnung ohne Währung", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := dominantCurrency(tt.text); got != tt.want {
+			t.Errorf("dominantCurrency(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTextAround(t *testing.T) {
+	text := strings.Repeat("x", 40) + "119,00" + strings.Repeat("y", 40)
+
+	around := textAround(text, textAnchor(40, 46))
+	if want := strings.Repeat("x", currencyWindow) + "119,00" + strings.Repeat("y", currencyWindow) + " "; around != want {
+		t.Errorf("textAround() = %q, want %q", around, want)
+	}
+
+	// The window is clipped at the ends of the text
+	if got := textAround("CHF 5", textAnchor(4, 5)); got != "CHF 5 " {
+		t.Errorf("textAround() at the text ends = %q", got)
+	}
+	if got := textAround(text, nil); got != "" {
+		t.Errorf("textAround() without anchor = %q, want empty", got)
+	}
+}
+
+func TestInferCurrency(t *testing.T) {
+	tests := []struct {
+		name           string
+		doc            string
+		want           string
+		wantConfidence float32
+	}{
+		{
+			name:           "normalized money value",
+			doc:            `{"text": "Summe 119,00 EUR", "entities": [{"type": "net_amount", "mentionText": "100,00 EUR", "normalizedValue": {"moneyValue": {"currencyCode": "chf"}}}]}`,
+			want:           "CHF",
+			wantConfidence: currencyConfidenceNormalized,
+		},
+		{
+			name:           "total mention text",
+			doc:            `{"entities": [{"type": "net_amount", "mentionText": "100,00 USD"}, {"type": "total_amount", "mentionText": "119,00 £"}]}`,
+			want:           "GBP",
+			wantConfidence: currencyConfidenceTotal,
+		},
+		{
+			name:           "other amount mention text",
+			doc:            `{"entities": [{"type": "total_amount", "mentionText": "119,00"}, {"type": "vat_amount", "mentionText": "19,00 CHF"}]}`,
+			want:           "CHF",
+			wantConfidence: currencyConfidenceAmount,
+		},
+		{
+			name:           "text next to an amount",
+			doc:            `{"text": "Gesamtbetrag 119,00 SEK", "entities": [{"type": "total_amount", "mentionText": "119,00", "textAnchor": {"textSegments": [{"startIndex": "13", "endIndex": "19"}]}}]}`,
+			want:           "SEK",
+			wantConfidence: currencyConfidenceNearAmount,
+		},
+		{
+			name:           "whole text",
+			doc:            `{"text": "Preise in DKK\n\n` + strings.Repeat(".", 80) + `\nSumme 119,00", "entities": [{"type": "total_amount", "mentionText": "119,00", "textAnchor": {"textSegments": [{"startIndex": "102", "endIndex": "108"}]}}]}`,
+			want:           "DKK",
+			wantConfidence: currencyConfidenceText,
+		},
+		{
+			name: "no currency",
+			doc:  `{"text": "Summe 119,00", "entities": [{"type": "total_amount", "mentionText": "119,00"}]}`,
+			want: defaultCurrency,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEFAULT_CURRENCY", "")
+			var doc documentaipb.Document
+			if err := protojson.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatalf("invalid test document: %v", err)
+			}
+			currency, confidence := inferCurrency(&doc)
+			if currency != tt.want || confidence != tt.wantConfidence {
+				t.Errorf("inferCurrency() = %q, %v, want %q, %v", currency, confidence, tt.want, tt.wantConfidence)
+			}
+		})
+	}
+}
+
+func TestConfiguredDefaultCurrency(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "EUR"},
+		{" chf ", "CHF"},
+		{"Euro", "EUR"},
+	}
+	for _, tt := range tests {
+		t.Setenv("DEFAULT_CURRENCY", tt.value)
+		if got := configuredDefaultCurrency(); got != tt.want {
+			t.Errorf("DEFAULT_CURRENCY=%q: configuredDefaultCurrency() = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package invoice
+
+import (
+	"fmt"
+	"time"
+
+	"tools/pkg/models"
+)
+
+// DiffInvoices returns the fields that differ between two versions of an invoice, e.g. the Document
+// AI extraction and the completed invoice. Amounts are formatted in the currency unit, dates as
+// YYYY-MM-DD; bookkeeping fields (ID, timestamps, type reasoning) are not compared.
+func DiffInvoices(before, after *models.Invoice) []models.FieldChange {
+	fields := []struct {
+		name          string
+		before, after string
+	}{
+		{"invoice_number", before.InvoiceNumber, after.InvoiceNumber},
+		{"type", before.Type, after.Type},
+		{"sub_type", before.SubType, after.SubType},
+		{"prepayment_reference", before.PrepaymentReference, after.PrepaymentReference},
+		{"vendor", before.Vendor, after.Vendor},
+		{"customer", before.Customer, after.Customer},
+		{"vendor_vat_id", before.VendorVATID, after.VendorVATID},
+		{"customer_vat_id", before.CustomerVATID, after.CustomerVATID},
+		{"customer_address", before.CustomerAddress, after.CustomerAddress},
+		{"customer_email", before.CustomerEmail, after.CustomerEmail},
+		{"customer_contact", before.CustomerContact, after.CustomerContact},
+		{"issue_date", formatDiffDate(before.IssueDate), formatDiffDate(after.IssueDate)},
+		{"due_date", formatDiffDate(before.DueDate), formatDiffDate(after.DueDate)},
+		{"service_date", formatDiffDate(before.ServiceDate), formatDiffDate(after.ServiceDate)},
+		{"net_amount", formatDiffAmount(before.NetAmount), formatDiffAmount(after.NetAmount)},
+		{"vat_amount", formatDiffAmount(before.VATAmount), formatDiffAmount(after.VATAmount)},
+		{"gross_amount", formatDiffAmount(before.GrossAmount), formatDiffAmount(after.GrossAmount)},
+		{"currency", before.Currency, after.Currency},
+		{"line_items", formatDiffCount(len(before.LineItems)), formatDiffCount(len(after.LineItems))},
+		{"payment_schedule", formatDiffCount(len(before.PaymentSchedule)), formatDiffCount(len(after.PaymentSchedule))},
+		{"purchase_order", before.PurchaseOrder, after.PurchaseOrder},
+		{"customer_reference", before.CustomerReference, after.CustomerReference},
+		{"description", before.Description, after.Description},
+		{"accounting_summary", before.AccountingSummary, after.AccountingSummary},
+	}
+
+	var changes []models.FieldChange
+	for _, field := range fields {
+		if field.before == field.after {
+			continue
+		}
+		kind := models.FieldOverwritten
+		if field.before == "" {
+			kind = models.FieldFilled
+		}
+		changes = append(changes, models.FieldChange{Field: field.name, Kind: kind, Before: field.before, After: field.after})
+	}
+	return changes
+}
+
+// formatDiffDate formats a date for DiffInvoices, empty if unset
+func formatDiffDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// formatDiffAmount formats cents for DiffInvoices, empty if zero
+func formatDiffAmount(cents int64) string {
+	if cents == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", float64(cents)/100)
+}
+
+// formatDiffCount formats a count for DiffInvoices, empty if zero
+func formatDiffCount(count int) string {
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", count)
+}
@@ -0,0 +1,113 @@
+package invoice
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"tools/pkg/models"
+)
+
+func TestDiffInvoices(t *testing.T) {
+	extracted := models.Invoice{
+		ID:            "doc-1",
+		InvoiceNumber: "RE-1001",
+		Type:          "PAYABLE",
+		Vendor:        "Muster GmbH",
+		IssueDate:     time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		GrossAmount:   11900,
+		Currency:      "EUR",
+		LineItems:     []models.LineItem{{Description: "Papier"}},
+		CreatedAt:     time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name   string
+		change func(invoice *models.Invoice)
+		want   []models.FieldChange
+	}{
+		{
+			name:   "unchanged",
+			change: func(invoice *models.Invoice) {},
+		},
+		{
+			name: "bookkeeping fields are not compared",
+			change: func(invoice *models.Invoice) {
+				invoice.ID = "inv-2"
+				invoice.TypeReasoning = "Empfänger ist unsere Firma"
+				invoice.CreatedAt = time.Now()
+				invoice.UpdatedAt = time.Now()
+			},
+		},
+		{
+			name: "filled fields",
+			change: func(invoice *models.Invoice) {
+				invoice.NetAmount = 10000
+				invoice.VATAmount = 1900
+				invoice.DueDate = time.Date(2024, 4, 14, 0, 0, 0, 0, time.UTC)
+				invoice.PaymentSchedule = []models.PaymentInstallment{{Amount: 5950}, {Amount: 5950}}
+			},
+			want: []models.FieldChange{
+				{Field: "due_date", Kind: models.FieldFilled, After: "2024-04-14"},
+				{Field: "net_amount", Kind: models.FieldFilled, After: "100.00"},
+				{Field: "vat_amount", Kind: models.FieldFilled, After: "19.00"},
+				{Field: "payment_schedule", Kind: models.FieldFilled, After: "2"},
+			},
+		},
+		{
+			name: "overwritten fields",
+			change: func(invoice *models.Invoice) {
+				invoice.Vendor = "Muster Büro GmbH"
+				invoice.GrossAmount = -11900
+				invoice.LineItems = append(invoice.LineItems, models.LineItem{Description: "Toner"})
+			},
+			want: []models.FieldChange{
+				{Field: "vendor", Kind: models.FieldOverwritten, Before: "Muster GmbH", After: "Muster Büro GmbH"},
+				{Field: "gross_amount", Kind: models.FieldOverwritten, Before: "119.00", After: "-119.00"},
+				{Field: "line_items", Kind: models.FieldOverwritten, Before: "1", After: "2"},
+			},
+		},
+		{
+			name: "cleared fields count as overwritten",
+			change: func(invoice *models.Invoice) {
+				invoice.InvoiceNumber = ""
+				invoice.IssueDate = time.Time{}
+			},
+			want: []models.FieldChange{
+				{Field: "invoice_number", Kind: models.FieldOverwritten, Before: "RE-1001"},
+				{Field: "issue_date", Kind: models.FieldOverwritten, Before: "2024-03-15"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := extracted
+			after := extracted
+			after.LineItems = append([]models.LineItem(nil), extracted.LineItems...)
+			tt.change(&after)
+
+			if got := DiffInvoices(&before, &after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffInvoices() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDiffAmount(t *testing.T) {
+	tests := []struct {
+		cents int64
+		want  string
+	}{
+		{0, ""},
+		{1, "0.01"},
+		{11900, "119.00"},
+		{-5, "-0.05"},
+		{123456789, "1234567.89"},
+	}
+	for _, tt := range tests {
+		if got := formatDiffAmount(tt.cents); got != tt.want {
+			t.Errorf("formatDiffAmount(%d) = %q, want %q", tt.cents, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package invoice
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -13,9 +16,11 @@ import (
 	documentai "cloud.google.com/go/documentai/apiv1"
 	"cloud.google.com/go/documentai/apiv1/documentaipb"
 	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
 	"github.com/rs/zerolog"
 
 	"tools/internal/logger"
+	"tools/internal/money"
 	"tools/pkg/models"
 )
 
@@ -32,6 +37,12 @@ type DocumentAIInvoiceProcessor struct {
 	client *documentai.DocumentProcessorClient
 	config DocumentAIConfig
 	log    zerolog.Logger
+
+	// invoiceNumberPatterns are the configured invoice number fallbacks, tried after the built-in ones
+	invoiceNumberPatterns []*regexp.Regexp
+
+	// storage uploads documents and downloads results of async processing; nil if unavailable
+	storage *storage.Service
 }
 
 // NewDocumentAIInvoiceProcessor creates processor with credentials from environment.
@@ -39,14 +50,43 @@ type DocumentAIInvoiceProcessor struct {
 // Requires: GOOGLE_PROJECT_ID, GOOGLE_LOCATION (e.g., "us" or "eu")
 // Optional: GOOGLE_PROCESSOR_ID (or use default invoice processor)
 func NewDocumentAIInvoiceProcessor(ctx context.Context) (InvoiceProcessor, error) {
-	const op = "NewDocumentAIInvoiceProcessor"
+	return NewDocumentAIInvoiceProcessorWithTimeout(ctx, DefaultConfig().Timeout)
+}
+
+// NewDocumentAIInvoiceProcessorWithTimeout creates processor like NewDocumentAIInvoiceProcessor with a custom
+// per-request timeout. A timeout of zero or less uses the default of 60 seconds.
+func NewDocumentAIInvoiceProcessorWithTimeout(ctx context.Context, timeout time.Duration) (InvoiceProcessor, error) {
+	return NewDocumentAIInvoiceProcessorWithOptions(ctx, ProcessorOptions{Timeout: timeout})
+}
+
+// ProcessorOptions adjusts a Document AI processor created from the environment
+type ProcessorOptions struct {
+	Timeout time.Duration  // Per-request timeout of synchronous processing; zero uses 60 seconds
+	Mode    ProcessingMode // sync, async or auto (default); DOCUMENT_AI_ASYNC=true turns auto into async
+}
+
+// NewDocumentAIInvoiceProcessorWithOptions creates processor like NewDocumentAIInvoiceProcessor with explicit
+// options. Async (batch) processing additionally needs GCS_SOURCE_BUCKET and GCS_OUTPUT_BUCKET, with
+// GCS_SOURCE_FOLDER and GCS_OUTPUT_FOLDER as optional folders in them.
+func NewDocumentAIInvoiceProcessorWithOptions(ctx context.Context, options ProcessorOptions) (InvoiceProcessor, error) {
+	const op = "NewDocumentAIInvoiceProcessorWithOptions"
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultConfig().Timeout
+	}
 
 	// Load configuration from environment
 	config := DocumentAIConfig{
-		ProjectID:   getEnvVar("GOOGLE_PROJECT_ID", "GOOGLE_CLOUD_PROJECT"),
-		Location:    getEnvVar("GOOGLE_LOCATION", "GOOGLE_CLOUD_LOCATION"),
-		ProcessorID: getEnvVar("GOOGLE_PROCESSOR_ID", "DOCUMENT_AI_PROCESSOR_ID"),
-		Timeout:     60 * time.Second,
+		ProjectID:      getEnvVar("GOOGLE_PROJECT_ID", "GOOGLE_CLOUD_PROJECT"),
+		Location:       getEnvVar("GOOGLE_LOCATION", "GOOGLE_CLOUD_LOCATION"),
+		ProcessorID:    getEnvVar("GOOGLE_PROCESSOR_ID", "DOCUMENT_AI_PROCESSOR_ID"),
+		Timeout:        timeout,
+		AsyncInputURI:  gcsURI(os.Getenv("GCS_SOURCE_BUCKET"), os.Getenv("GCS_SOURCE_FOLDER")),
+		AsyncOutputURI: gcsURI(os.Getenv("GCS_OUTPUT_BUCKET"), os.Getenv("GCS_OUTPUT_FOLDER")),
+		Async:          options.Mode == ProcessingAsync || (options.Mode != ProcessingSync && os.Getenv("DOCUMENT_AI_ASYNC") == "true"),
+		Sync:           options.Mode == ProcessingSync,
+		AsyncTimeout:   DefaultAsyncTimeout,
 	}
 
 	// Validate required configuration
@@ -57,20 +97,38 @@ func NewDocumentAIInvoiceProcessor(ctx context.Context) (InvoiceProcessor, error
 		config.Location = "us" // Default location
 	}
 
-	// Create Document AI client with regional endpoint
-	var clientOptions []option.ClientOption
+	if config.Async && !config.asyncAvailable() {
+		return nil, WrapInvoiceProcessingError(op, ErrInvalidConfiguration, "async processing requires GCS_SOURCE_BUCKET and GCS_OUTPUT_BUCKET")
+	}
 
-	// Set regional endpoint if not us-central1
-	if config.Location != "" && config.Location != "us" {
-		endpoint := fmt.Sprintf("%s-documentai.googleapis.com:443", config.Location)
-		clientOptions = append(clientOptions, option.WithEndpoint(endpoint))
+	if value := os.Getenv("DOCUMENT_AI_MIN_CONFIDENCE"); value != "" {
+		minConfidence, err := strconv.ParseFloat(value, 32)
+		if err != nil || minConfidence < 0 || minConfidence > 1 {
+			return nil, WrapInvoiceProcessingError(op, ErrInvalidConfiguration, fmt.Sprintf("DOCUMENT_AI_MIN_CONFIDENCE must be between 0 and 1: %q", value))
+		}
+		config.MinFieldConfidence = float32(minConfidence)
+	}
+
+	invoiceNumberPatterns, err := invoiceNumberPatternsFromEnv()
+	if err != nil {
+		return nil, WrapInvoiceProcessingError(op, ErrInvalidConfiguration, fmt.Sprintf("INVOICE_NUMBER_PATTERNS_FILE: %v", err))
 	}
 
 	// Add credentials
+	var credentialOptions []option.ClientOption
 	if credJSON := os.Getenv("GOOGLE_CREDENTIALS"); credJSON != "" {
-		clientOptions = append(clientOptions, option.WithCredentialsJSON([]byte(credJSON)))
+		credentialOptions = append(credentialOptions, option.WithCredentialsJSON([]byte(credJSON)))
 	} else if credFile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); credFile != "" {
-		clientOptions = append(clientOptions, option.WithCredentialsFile(credFile))
+		credentialOptions = append(credentialOptions, option.WithCredentialsFile(credFile))
+	}
+
+	// Create Document AI client with regional endpoint
+	clientOptions := credentialOptions
+
+	// Set regional endpoint if not us-central1
+	if config.Location != "" && config.Location != "us" {
+		endpoint := fmt.Sprintf("%s-documentai.googleapis.com:443", config.Location)
+		clientOptions = append([]option.ClientOption{option.WithEndpoint(endpoint)}, clientOptions...)
 	}
 
 	// Create client with options
@@ -82,11 +140,23 @@ func NewDocumentAIInvoiceProcessor(ctx context.Context) (InvoiceProcessor, error
 		return nil, WrapInvoiceProcessingError(op, err, fmt.Sprintf("failed to create Document AI client for location: %s", config.Location))
 	}
 
-	return &DocumentAIInvoiceProcessor{
-		client: client,
-		config: config,
-		log:    logger.WithComponent("document-ai"),
-	}, nil
+	processor := &DocumentAIInvoiceProcessor{
+		client:                client,
+		config:                config,
+		log:                   logger.WithComponent("document-ai"),
+		invoiceNumberPatterns: invoiceNumberPatterns,
+	}
+
+	// Batch processing exchanges documents and results through Cloud Storage
+	if config.asyncAvailable() && !config.Sync {
+		processor.storage, err = storage.NewService(ctx, credentialOptions...)
+		if err != nil {
+			client.Close()
+			return nil, WrapInvoiceProcessingError(op, err, "failed to create Cloud Storage client for async processing")
+		}
+	}
+
+	return processor, nil
 }
 
 // NewDocumentAIInvoiceProcessorWithConfig creates processor with explicit config and client (for testing).
@@ -106,24 +176,73 @@ func (p *DocumentAIInvoiceProcessor) ProcessInvoice(ctx context.Context, pdfData
 
 // ProcessInvoiceWithConfidence extracts structured data with confidence scores.
 func (p *DocumentAIInvoiceProcessor) ProcessInvoiceWithConfidence(ctx context.Context, pdfData io.Reader) (*models.Invoice, map[string]float32, error) {
-	const op = "ProcessInvoiceWithConfidence"
+	return p.ProcessInvoicePages(ctx, pdfData, nil)
+}
+
+// ProcessInvoicePages extracts structured data with confidence scores from the given 1-based pages only.
+func (p *DocumentAIInvoiceProcessor) ProcessInvoicePages(ctx context.Context, pdfData io.Reader, pages []int32) (*models.Invoice, map[string]float32, error) {
+	const op = "ProcessInvoicePages"
+
+	source, err := p.readDocument(op, pdfData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc, err := p.processDocument(ctx, op, source, pages)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// Read PDF data
-	pdfBytes, err := io.ReadAll(pdfData)
+	// Extract invoice data
+	invoice, confidence, err := p.extractInvoiceData(doc)
 	if err != nil {
-		return nil, nil, WrapInvoiceProcessingError(op, err, "failed to read PDF data")
+		return nil, nil, WrapInvoiceProcessingError(op, err, "failed to extract invoice data")
 	}
 
-	// Validate file size
-	if len(pdfBytes) > MaxDocumentSizeBytes {
-		return nil, nil, WrapInvoiceProcessingError(op, ErrDocumentTooLarge, fmt.Sprintf("file size: %d bytes", len(pdfBytes)))
+	// Set processing metadata
+	invoice.CreatedAt = time.Now()
+	invoice.UpdatedAt = invoice.CreatedAt
+
+	return invoice, confidence, nil
+}
+
+// processDocument sends the document to Document AI. If pages is non-empty, only those 1-based pages are
+// processed. Large documents go through async batch processing when it is configured, and a synchronous
+// request that times out is retried asynchronously once. Without async processing, a document with more
+// pages than the synchronous limit of its format fails with ErrTooManyPages.
+func (p *DocumentAIInvoiceProcessor) processDocument(ctx context.Context, op string, source *sourceDocument, pages []int32) (*documentaipb.Document, error) {
+	pageCount := p.pageCount(source, pages)
+	if async, reason := p.useAsync(source, pageCount); async {
+		p.log.Info().
+			Int("size", len(source.data)).
+			Str("format", string(source.format)).
+			Str("reason", reason).
+			Msg("Processing document asynchronously")
+		return p.processDocumentAsync(ctx, op, source, pages)
+	}
+
+	if limit := syncLimits[source.format].maxPages; pageCount > limit {
+		hint := "configure GCS_SOURCE_BUCKET and GCS_OUTPUT_BUCKET for async processing or select pages"
+		if p.config.Sync {
+			hint = "use auto or async processing or select pages"
+		}
+		return nil, WrapInvoiceProcessingError(op, ErrTooManyPages,
+			fmt.Sprintf("%s has %d pages (synchronous limit %d); %s", source.format, pageCount, limit, hint))
 	}
 
-	// Validate PDF header
-	if len(pdfBytes) < 4 || string(pdfBytes[:4]) != "%PDF" {
-		return nil, nil, WrapInvoiceProcessingError(op, ErrInvalidPDF, "missing PDF header")
+	doc, err := p.processDocumentSync(ctx, op, source, pages)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && p.storage != nil && ctx.Err() == nil {
+		p.log.Warn().
+			Err(err).
+			Int("size", len(source.data)).
+			Msg("Synchronous processing timed out, retrying asynchronously")
+		return p.processDocumentAsync(ctx, op, source, pages)
 	}
+	return doc, err
+}
 
+// processDocumentSync sends the document inline with a synchronous ProcessDocument request
+func (p *DocumentAIInvoiceProcessor) processDocumentSync(ctx context.Context, op string, source *sourceDocument, pages []int32) (*documentaipb.Document, error) {
 	// Create context with timeout
 	processCtx, cancel := context.WithTimeout(ctx, p.config.Timeout)
 	defer cancel()
@@ -136,34 +255,34 @@ func (p *DocumentAIInvoiceProcessor) ProcessInvoiceWithConfidence(ctx context.Co
 		Name: processorName,
 		Source: &documentaipb.ProcessRequest_RawDocument{
 			RawDocument: &documentaipb.RawDocument{
-				Content:  pdfBytes,
-				MimeType: "application/pdf",
+				Content:  source.data,
+				MimeType: source.format.MimeType(),
 			},
 		},
 	}
 
+	if len(pages) > 0 {
+		req.ProcessOptions = &documentaipb.ProcessOptions{
+			PageRange: &documentaipb.ProcessOptions_IndividualPageSelector_{
+				IndividualPageSelector: &documentaipb.ProcessOptions_IndividualPageSelector{
+					Pages: pages,
+				},
+			},
+		}
+	}
+
 	// Process document
 	resp, err := p.client.ProcessDocument(processCtx, req)
 	if err != nil {
-		return nil, nil, p.handleProcessingError(op, err)
+		return nil, p.handleProcessingError(op, err)
 	}
 
 	// Check for processing errors
 	if resp.Document == nil {
-		return nil, nil, WrapInvoiceProcessingError(op, ErrProcessingFailed, "no document in response")
+		return nil, WrapInvoiceProcessingError(op, ErrProcessingFailed, "no document in response")
 	}
 
-	// Extract invoice data
-	invoice, confidence, err := p.extractInvoiceData(resp.Document)
-	if err != nil {
-		return nil, nil, WrapInvoiceProcessingError(op, err, "failed to extract invoice data")
-	}
-
-	// Set processing metadata
-	invoice.CreatedAt = time.Now()
-	invoice.UpdatedAt = invoice.CreatedAt
-
-	return invoice, confidence, nil
+	return resp.Document, nil
 }
 
 // getProcessorName constructs the full processor name for Document AI API.
@@ -204,91 +323,190 @@ func (p *DocumentAIInvoiceProcessor) handleProcessingError(op string, err error)
 	}
 }
 
+// entityFields maps the Document AI entity types to the invoice fields they populate. The field names
+// are the keys of the confidence map, shared with completion.
+var entityFields = map[string]string{
+	"invoice_id":         "invoice_number",
+	"invoice_number":     "invoice_number",
+	"supplier_name":      "vendor",
+	"vendor_name":        "vendor",
+	"buyer_name":         "customer",
+	"customer_name":      "customer",
+	"supplier_tax_id":    "vendor_vat_id",
+	"receiver_tax_id":    "customer_vat_id",
+	"receiver_email":     "customer_email",
+	"receiver_address":   "customer_address",
+	"invoice_date":       "issue_date",
+	"due_date":           "due_date",
+	"delivery_date":      "service_date",
+	"service_date":       "service_date",
+	"net_amount":         "net_amount",
+	"subtotal_amount":    "net_amount",
+	"total_tax_amount":   "vat_amount",
+	"vat_amount":         "vat_amount",
+	"total_amount":       "gross_amount",
+	"gross_amount":       "gross_amount",
+	"currency":           "currency",
+	"purchase_order":     "purchase_order",
+	"reference_number":   "customer_reference",
+	"customer_reference": "customer_reference",
+	"line_item":          "line_items",
+}
+
 // extractInvoiceData converts Document AI entities to Invoice model.
 func (p *DocumentAIInvoiceProcessor) extractInvoiceData(doc *documentaipb.Document) (*models.Invoice, map[string]float32, error) {
 	invoice := &models.Invoice{
 		Type:      "",    // Default to payable (incoming invoice)
-		Currency:  "EUR", // Default currency
+		Currency:  "",    // Set from the currency entity or inferred below
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
 	confidence := make(map[string]float32)
 
-	// Extract entities
+	// Extract entities. The confidence map is keyed by the invoice field an entity set, so it holds
+	// exactly the fields populated from Document AI; entities below the confidence floor are dropped.
+	var lineItemConfidence float32 = 1
 	for _, entity := range doc.Entities {
 		entityType := entity.Type
 		value := strings.TrimSpace(entity.MentionText)
 		conf := entity.Confidence
 
-		confidence[entityType] = conf
-
 		p.log.Debug().
 			Str("entity_type", entityType).
 			Str("value", value).
 			Float32("confidence", conf).
 			Msg("Processing Document AI entity")
 
-		switch entityType {
-		case "invoice_id", "invoice_number":
-			invoice.InvoiceNumber = value
-		case "supplier_name", "vendor_name":
-			invoice.Vendor = value
-		case "buyer_name", "customer_name":
-			invoice.Customer = value
-		case "invoice_date":
-			if date, err := p.extractDate(entity); err == nil {
+		field, known := entityFields[entityType]
+		if !known {
+			continue
+		}
+		if conf < p.config.MinFieldConfidence {
+			p.log.Debug().
+				Str("entity_type", entityType).
+				Float32("confidence", conf).
+				Float32("minimum", p.config.MinFieldConfidence).
+				Msg("Dropping Document AI entity below the confidence floor")
+			continue
+		}
+
+		set := value != ""
+		switch field {
+		case "invoice_number":
+			if set {
+				invoice.InvoiceNumber = value
+			}
+		case "vendor":
+			if set {
+				invoice.Vendor = value
+			}
+		case "customer":
+			if set {
+				invoice.Customer = value
+			}
+		case "vendor_vat_id":
+			if set {
+				invoice.VendorVATID = value
+			}
+		case "customer_vat_id":
+			if set {
+				invoice.CustomerVATID = value
+			}
+		case "customer_email":
+			if set {
+				invoice.CustomerEmail = value
+			}
+		case "customer_address":
+			if set {
+				invoice.CustomerAddress = oneLineAddress(value)
+			}
+		case "issue_date":
+			date, err := p.extractDate(entity)
+			if set = err == nil; set {
 				invoice.IssueDate = date
 			}
 		case "due_date":
-			if date, err := p.extractDate(entity); err == nil {
+			date, err := p.extractDate(entity)
+			if set = err == nil; set {
 				invoice.DueDate = date
 			}
-		case "net_amount", "subtotal_amount":
+		case "service_date":
+			date, err := p.extractDate(entity)
+			if set = err == nil; set {
+				invoice.ServiceDate = date
+			}
+		case "net_amount":
 			if amount, err := p.extractMoneyValue(entity); err == nil {
 				p.log.Debug().
 					Int64("amount", amount).
 					Str("raw_value", value).
 					Msg("Extracted net amount from Document AI")
 				invoice.NetAmount = amount
+				set = true
 			} else {
 				p.log.Warn().
 					Err(err).
 					Str("raw_value", value).
 					Msg("Failed to extract net amount from Document AI")
+				set = false
 			}
-		case "total_tax_amount", "vat_amount":
+		case "vat_amount":
 			if amount, err := p.extractMoneyValue(entity); err == nil {
 				p.log.Debug().
 					Int64("amount", amount).
 					Str("raw_value", value).
 					Msg("Extracted VAT amount from Document AI")
 				invoice.VATAmount = amount
+				set = true
 			} else {
 				p.log.Warn().
 					Err(err).
 					Str("raw_value", value).
 					Msg("Failed to extract VAT amount from Document AI")
+				set = false
 			}
-		case "total_amount", "gross_amount":
+		case "gross_amount":
 			if amount, err := p.extractMoneyValue(entity); err == nil {
 				p.log.Debug().
 					Int64("amount", amount).
 					Str("raw_value", value).
 					Msg("Extracted gross amount from Document AI")
 				invoice.GrossAmount = amount
+				set = true
 			} else {
 				p.log.Warn().
 					Err(err).
 					Str("raw_value", value).
 					Msg("Failed to extract gross amount from Document AI")
+				set = false
 			}
 		case "currency":
-			if value != "" {
+			if set {
 				invoice.Currency = p.normalizeCurrency(value)
 			}
-		case "purchase_order", "reference_number":
-			invoice.Reference = value
+		case "purchase_order":
+			if set {
+				invoice.PurchaseOrder = value
+			}
+		case "customer_reference":
+			if set {
+				invoice.CustomerReference = value
+			}
+		case "line_items":
+			// The line items are as reliable as the least reliable one
+			item, ok := p.extractLineItem(entity)
+			if set = ok; set {
+				invoice.LineItems = append(invoice.LineItems, item)
+				if conf > lineItemConfidence {
+					conf = lineItemConfidence
+				}
+				lineItemConfidence = conf
+			}
+		}
+
+		if set {
+			confidence[field] = conf
 		}
 	}
 
@@ -296,21 +514,60 @@ func (p *DocumentAIInvoiceProcessor) extractInvoiceData(doc *documentaipb.Docume
 	if invoice.InvoiceNumber == "" {
 		if fallbackNumber := p.extractInvoiceNumberFallback(doc); fallbackNumber != "" {
 			invoice.InvoiceNumber = fallbackNumber
-			confidence["invoice_number_fallback"] = 0.6 // Lower confidence for fallback
+			confidence["invoice_number"] = 0.6 // Lower confidence for fallback
 			p.log.Info().
 				Str("fallback_number", fallbackNumber).
 				Msg("Invoice number extracted using fallback strategy")
 		}
 	}
 
+	// Document AI often omits the currency entity; infer it from the amounts instead of assuming EUR
+	if invoice.Currency == "" {
+		currency, currencyConfidence := inferCurrency(doc)
+		invoice.Currency = currency
+		confidence["currency"] = currencyConfidence
+		p.log.Info().
+			Str("currency", currency).
+			Float32("confidence", currencyConfidence).
+			Msg("Currency inferred from amounts")
+	}
+
+	// Mahnungen refer to an invoice booked before; Anzahlungs- and Schlussrechnungen are booked
+	// differently from regular invoices
+	applyReminderDetection(invoice, doc.Text, confidence)
+	applyPrepaymentDetection(invoice, doc.Text, confidence)
+
+	// Invoices paid on issue or collected by direct debit need no payment
+	applyPaymentStatusDetection(invoice, doc.Text, confidence)
+
+	// Blurry or dark scans explain dubious extractions and should be redone rather than booked
+	if quality := extractInputQuality(doc); quality != nil {
+		invoice.InputQuality = quality
+		confidence["input_quality"] = quality.Score
+		if quality.IsLow() {
+			p.log.Warn().
+				Float32("quality_score", quality.Score).
+				Strs("defects", quality.Defects).
+				Ints("rotated_pages", quality.RotatedPages).
+				Msg("Low scan quality, consider re-scanning the document")
+		}
+	}
+
 	// Generate ID if not present
 	if invoice.ID == "" {
-		invoice.ID = p.generateInvoiceID(invoice)
+		invoice.ID = p.generateInvoiceID(invoice, doc.Text)
 	}
 
+	// Some vendor layouts make Document AI swap net_amount and total_amount
+	p.correctTransposedAmounts(invoice, confidence)
+
 	// Calculate missing amounts if possible
 	p.calculateMissingAmounts(invoice)
 
+	if markSmallBusiness(invoice, doc.Text) {
+		p.log.Info().Msg("Invoice cites §19 UStG and charges no VAT, vendor is a Kleinunternehmer")
+	}
+
 	// Log final extracted amounts
 	p.log.Info().
 		Str("invoice_number", invoice.InvoiceNumber).
@@ -321,13 +578,43 @@ func (p *DocumentAIInvoiceProcessor) extractInvoiceData(doc *documentaipb.Docume
 		Msg("Document AI extraction completed")
 
 	// Validate critical fields
-	if err := p.validateInvoice(invoice); err != nil {
+	if err := p.validateInvoice(invoice, doc.Text); err != nil {
 		return nil, nil, err
 	}
 
 	return invoice, confidence, nil
 }
 
+// correctTransposedAmounts swaps net and gross when Document AI returned a net larger than the gross
+// and the swapped values reconcile with the VAT amount.
+func (p *DocumentAIInvoiceProcessor) correctTransposedAmounts(invoice *models.Invoice, confidence map[string]float32) {
+	if invoice.NetAmount <= invoice.GrossAmount || invoice.GrossAmount <= 0 {
+		return
+	}
+
+	if !isTransposedNetGross(invoice.NetAmount, invoice.VATAmount, invoice.GrossAmount) {
+		p.log.Warn().
+			Int64("net_amount", invoice.NetAmount).
+			Int64("vat_amount", invoice.VATAmount).
+			Int64("gross_amount", invoice.GrossAmount).
+			Msg("Net amount exceeds gross amount, but swapping does not reconcile with VAT")
+		return
+	}
+
+	p.log.Warn().
+		Int64("original_net", invoice.NetAmount).
+		Int64("original_gross", invoice.GrossAmount).
+		Int64("vat_amount", invoice.VATAmount).
+		Msg("Corrected transposed net and gross amounts from Document AI")
+
+	invoice.NetAmount, invoice.GrossAmount = invoice.GrossAmount, invoice.NetAmount
+	netConf, hasNet := confidence["net_amount"]
+	grossConf, hasGross := confidence["gross_amount"]
+	if hasNet && hasGross {
+		confidence["net_amount"], confidence["gross_amount"] = grossConf, netConf
+	}
+}
+
 // extractDate safely extracts date value from Document AI entity.
 func (p *DocumentAIInvoiceProcessor) extractDate(entity *documentaipb.Document_Entity) (time.Time, error) {
 	if entity.NormalizedValue != nil {
@@ -374,10 +661,8 @@ func (p *DocumentAIInvoiceProcessor) extractDate(entity *documentaipb.Document_E
 func (p *DocumentAIInvoiceProcessor) extractMoneyValue(entity *documentaipb.Document_Entity) (int64, error) {
 	if entity.NormalizedValue != nil {
 		if moneyValue := entity.NormalizedValue.GetMoneyValue(); moneyValue != nil {
-			// Convert to cents
-			units := moneyValue.Units
-			nanos := moneyValue.Nanos
-			return units*100 + int64(nanos)/10000000, nil
+			// Convert to cents, rounding fractions of a cent instead of dropping them
+			return money.FromUnitsNanos(moneyValue.Units, moneyValue.Nanos), nil
 		}
 	}
 
@@ -396,58 +681,28 @@ func (p *DocumentAIInvoiceProcessor) extractMoneyValue(entity *documentaipb.Docu
 	return amount, nil
 }
 
-// parseAmount parses amount string handling both German and English formats
+// parseAmount parses amount string in German, English or Swiss format, see money.ParseCents
 func (p *DocumentAIInvoiceProcessor) parseAmount(amountStr string) (int64, error) {
-	// Clean the amount string
-	cleaned := strings.TrimSpace(amountStr)
-	cleaned = strings.ReplaceAll(cleaned, " ", "")
-	cleaned = strings.ReplaceAll(cleaned, "€", "")
-	cleaned = strings.ReplaceAll(cleaned, "$", "")
-	cleaned = strings.ReplaceAll(cleaned, "EUR", "")
-	cleaned = strings.ReplaceAll(cleaned, "USD", "")
-	
-	// Handle German number format (7.303,08 -> 7303.08)
-	if strings.Contains(cleaned, ",") {
-		// If there's both . and , assume German format (. = thousands, , = decimal)
-		if strings.Contains(cleaned, ".") && strings.Contains(cleaned, ",") {
-			// Remove thousands separators (dots)
-			cleaned = strings.ReplaceAll(cleaned, ".", "")
-			// Replace decimal separator (comma) with dot
-			cleaned = strings.ReplaceAll(cleaned, ",", ".")
-		} else if strings.Contains(cleaned, ",") {
-			// Only comma, could be decimal separator
-			// Count digits after comma to determine if it's decimal
-			parts := strings.Split(cleaned, ",")
-			if len(parts) == 2 && len(parts[1]) <= 2 {
-				// Likely decimal separator (e.g., "1234,50")
-				cleaned = strings.ReplaceAll(cleaned, ",", ".")
-			}
-		}
-	}
-
-	amount, err := strconv.ParseFloat(cleaned, 64)
-	if err != nil {
-		return 0, fmt.Errorf("unable to parse amount: %s (cleaned: %s)", amountStr, cleaned)
-	}
-
-	return int64(amount * 100), nil
+	return money.ParseCents(amountStr)
 }
 
-// generateInvoiceID generates a unique invoice ID if not present.
-func (p *DocumentAIInvoiceProcessor) generateInvoiceID(invoice *models.Invoice) string {
+// generateInvoiceID generates an invoice ID if not present. Without an invoice number, the ID is
+// derived from a hash of the document text, so that re-runs on the same document yield the same ID.
+func (p *DocumentAIInvoiceProcessor) generateInvoiceID(invoice *models.Invoice, text string) string {
 	if invoice.InvoiceNumber != "" {
 		return invoice.InvoiceNumber
 	}
-	// Generate based on vendor and timestamp
-	timestamp := time.Now().Format("20060102-150405")
+	// Generate based on vendor and content hash
+	sum := sha256.Sum256([]byte(text))
+	contentHash := strings.ToUpper(hex.EncodeToString(sum[:6]))
 	if invoice.Vendor != "" {
-		vendorPrefix := strings.ToUpper(strings.ReplaceAll(invoice.Vendor, " ", ""))
+		vendorPrefix := []rune(strings.ToUpper(strings.ReplaceAll(invoice.Vendor, " ", "")))
 		if len(vendorPrefix) > 8 {
 			vendorPrefix = vendorPrefix[:8]
 		}
-		return fmt.Sprintf("%s-%s", vendorPrefix, timestamp)
+		return fmt.Sprintf("%s-%s", string(vendorPrefix), contentHash)
 	}
-	return fmt.Sprintf("INV-%s", timestamp)
+	return fmt.Sprintf("INV-%s", contentHash)
 }
 
 // calculateMissingAmounts calculates missing amount fields if possible.
@@ -466,8 +721,9 @@ func (p *DocumentAIInvoiceProcessor) calculateMissingAmounts(invoice *models.Inv
 	}
 }
 
-// validateInvoice performs basic validation on extracted invoice data.
-func (p *DocumentAIInvoiceProcessor) validateInvoice(invoice *models.Invoice) error {
+// validateInvoice performs basic validation on extracted invoice data. An implausibly large gross
+// amount is flagged rather than rejected; text is the document text logged around it.
+func (p *DocumentAIInvoiceProcessor) validateInvoice(invoice *models.Invoice, text string) error {
 	if invoice.InvoiceNumber == "" && invoice.ID == "" {
 		return NewValidationError("invoice_number", "", "invoice number is required")
 	}
@@ -475,6 +731,7 @@ func (p *DocumentAIInvoiceProcessor) validateInvoice(invoice *models.Invoice) er
 	if invoice.GrossAmount < 0 && invoice.NetAmount < 0 {
 		return NewValidationError("amount", invoice.GrossAmount, "invoice amount cannot be negative")
 	}
+	checkAmountCeiling(invoice, text, maxInvoiceAmountFromEnv(), p.log)
 	return nil
 }
 
@@ -577,34 +834,16 @@ func (p *DocumentAIInvoiceProcessor) extractInvoiceNumberFallback(doc *documenta
 	return ""
 }
 
-// extractInvoiceNumberFromText searches for invoice number patterns in text
+// extractInvoiceNumberFromText searches for invoice number patterns in text: the built-in patterns
+// first, then the configured ones (INVOICE_NUMBER_PATTERNS_FILE)
 func (p *DocumentAIInvoiceProcessor) extractInvoiceNumberFromText(text string) string {
-	
-	// Common German invoice number patterns
-	patterns := []string{
-		// HORNBACH specific patterns
-		`(?i)(?:rechnung|belegnr|beleg)[\s\-:\.]*(\d{8,}|\d{4,}\-\d+|\d+\.\d+)`,
-		`(?i)(?:rechnungsnr|rg\.?nr|rg\.?)[\s\-:\.]*(\d{8,}|\d{4,}\-\d+|\d+\.\d+)`,
-		`(?i)(?:invoice|inv)[\s\-:\.]*(?:no|nr|number)[\s\-:\.]*(\d{8,}|\d{4,}\-\d+|\d+\.\d+)`,
-		
-		// Generic patterns
-		`(?i)(?:^|\s)(?:nr|no|number)[\s\-:\.]*(\d{6,})`,
-		`(?i)(?:dokument|document)[\s\-:\.]*(?:nr|no)[\s\-:\.]*(\d{6,})`,
-		`(?i)(?:^|\s)(\d{8,})(?:\s|$)`, // Standalone 8+ digit numbers
-		
-		// Date-based invoice numbers (common in Germany)
-		`(?i)(\d{4,}\-\d{4,}\-\d+)`, // Format: YYYY-MMMM-XXX
-		`(?i)(\d{6,}\.\d+)`,          // Format: YYYYMM.XXX
-	}
-	
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
+	for _, re := range builtinInvoiceNumberPatterns {
 		if matches := re.FindStringSubmatch(text); len(matches) > 1 {
 			candidate := strings.TrimSpace(matches[1])
 			// Validate candidate (basic sanity checks)
 			if len(candidate) >= 6 && len(candidate) <= 20 {
 				p.log.Debug().
-					Str("pattern", pattern).
+					Str("pattern", re.String()).
 					Str("candidate", candidate).
 					Str("source_text", text[:min(50, len(text))]).
 					Msg("Invoice number pattern matched")
@@ -612,7 +851,21 @@ func (p *DocumentAIInvoiceProcessor) extractInvoiceNumberFromText(text string) s
 			}
 		}
 	}
-	
+
+	// Configured patterns describe a known supplier format, so only the length is checked
+	for _, re := range p.invoiceNumberPatterns {
+		if matches := re.FindStringSubmatch(text); len(matches) > 1 {
+			candidate := strings.TrimSpace(matches[1])
+			if candidate != "" && len(candidate) <= maxInvoiceNumberLength {
+				p.log.Info().
+					Str("pattern", re.String()).
+					Str("candidate", candidate).
+					Msg("Invoice number matched configured pattern")
+				return candidate
+			}
+		}
+	}
+
 	return ""
 }
 
@@ -0,0 +1,338 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/documentai/apiv1/documentaipb"
+	"google.golang.org/api/storage/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// MaxPagesSync is the page limit of synchronous invoice processing; longer documents are
+	// processed asynchronously
+	MaxPagesSync = 15
+
+	// AsyncThresholdBytes is the document size above which processing is asynchronous, since
+	// synchronous requests for documents this large tend to time out
+	AsyncThresholdBytes = 10 * 1024 * 1024
+
+	// DefaultAsyncTimeout is the default maximum time of one batch operation
+	DefaultAsyncTimeout = 10 * time.Minute
+
+	// asyncCleanupTimeout bounds deleting the uploaded document and results after a batch operation
+	asyncCleanupTimeout = 30 * time.Second
+)
+
+// ProcessingMode selects between synchronous and asynchronous (batch) Document AI processing
+type ProcessingMode string
+
+const (
+	// ProcessingAuto processes large documents asynchronously when Cloud Storage is configured and
+	// retries a synchronous request that timed out asynchronously
+	ProcessingAuto ProcessingMode = "auto"
+	// ProcessingSync only sends synchronous requests; documents above the synchronous page limit fail
+	ProcessingSync ProcessingMode = "sync"
+	// ProcessingAsync sends every document through batch processing
+	ProcessingAsync ProcessingMode = "async"
+)
+
+// ParseProcessingMode parses sync, async or auto; an empty value is auto
+func ParseProcessingMode(value string) (ProcessingMode, error) {
+	switch mode := ProcessingMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return ProcessingAuto, nil
+	case ProcessingAuto, ProcessingSync, ProcessingAsync:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid processing mode: %q (must be 'sync', 'async' or 'auto')", value)
+}
+
+// ValidateProcessingMode checks that the Cloud Storage locations async processing needs are set in
+// the environment (GCS_SOURCE_BUCKET and GCS_OUTPUT_BUCKET), so that a missing bucket fails before any
+// document is processed. Sync and auto always pass.
+func ValidateProcessingMode(mode ProcessingMode) error {
+	const op = "ValidateProcessingMode"
+
+	if mode != ProcessingAsync {
+		return nil
+	}
+	var missing []string
+	for _, name := range []string{"GCS_SOURCE_BUCKET", "GCS_OUTPUT_BUCKET"} {
+		if gcsURI(os.Getenv(name), "") == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return WrapInvoiceProcessingError(op, ErrInvalidConfiguration, "async processing requires "+strings.Join(missing, " and "))
+	}
+	return nil
+}
+
+// asyncAvailable reports whether the input and output locations for batch processing are configured
+func (c DocumentAIConfig) asyncAvailable() bool {
+	return c.AsyncInputURI != "" && c.AsyncOutputURI != ""
+}
+
+// gcsURI builds gs://bucket/folder from a bucket and an optional folder; empty without a bucket
+func gcsURI(bucket, folder string) string {
+	bucket = strings.TrimPrefix(strings.TrimSpace(bucket), "gs://")
+	if bucket == "" {
+		return ""
+	}
+	folder = strings.Trim(strings.TrimSpace(folder), "/")
+	if folder == "" {
+		return "gs://" + bucket
+	}
+	return "gs://" + bucket + "/" + folder
+}
+
+// splitGCSURI splits gs://bucket/path into bucket and object path
+func splitGCSURI(uri string) (bucket, path string) {
+	bucket, path, _ = strings.Cut(strings.TrimPrefix(uri, "gs://"), "/")
+	return bucket, path
+}
+
+// useAsync decides whether a document with pageCount processed pages (zero if unknown) is processed
+// asynchronously, with the reason for the log
+func (p *DocumentAIInvoiceProcessor) useAsync(source *sourceDocument, pageCount int) (bool, string) {
+	if p.storage == nil || p.config.Sync {
+		return false, ""
+	}
+	if p.config.Async {
+		return true, "async requested"
+	}
+	if len(source.data) > AsyncThresholdBytes {
+		return true, fmt.Sprintf("document larger than %d MB", AsyncThresholdBytes/(1024*1024))
+	}
+	if limit := syncLimits[source.format].maxPages; pageCount > limit {
+		return true, fmt.Sprintf("%s with %d pages (synchronous limit %d)", source.format, pageCount, limit)
+	}
+
+	return false, ""
+}
+
+// processDocumentAsync processes the document with a BatchProcessDocuments operation: the document is
+// uploaded to the input location, the operation is polled until it completes, and the resulting
+// document is read from the output location. Uploaded and generated objects are deleted afterwards.
+func (p *DocumentAIInvoiceProcessor) processDocumentAsync(ctx context.Context, op string, source *sourceDocument, pages []int32) (*documentaipb.Document, error) {
+	timeout := p.config.AsyncTimeout
+	if timeout <= 0 {
+		timeout = DefaultAsyncTimeout
+	}
+	processCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	runID, err := newAsyncRunID()
+	if err != nil {
+		return nil, WrapInvoiceProcessingError(op, ErrProcessingFailed, fmt.Sprintf("failed to create batch run ID: %v", err))
+	}
+
+	inputBucket, inputPath := splitGCSURI(p.config.AsyncInputURI)
+	inputObject := strings.TrimPrefix(inputPath+"/"+runID+source.format.Extension(), "/")
+	outputURI := p.config.AsyncOutputURI + "/" + runID + "/"
+	outputBucket, outputPrefix := splitGCSURI(outputURI)
+
+	defer p.cleanupAsync(inputBucket, inputObject, outputBucket, outputPrefix)
+
+	upload := &storage.Object{Name: inputObject, ContentType: source.format.MimeType()}
+	if _, err := p.storage.Objects.Insert(inputBucket, upload).Media(bytes.NewReader(source.data)).Context(processCtx).Do(); err != nil {
+		return nil, WrapInvoiceProcessingError(op, ErrProcessingFailed, fmt.Sprintf("failed to upload document to gs://%s/%s: %v", inputBucket, inputObject, err))
+	}
+
+	req := &documentaipb.BatchProcessRequest{
+		Name: p.getProcessorName(),
+		InputDocuments: &documentaipb.BatchDocumentsInputConfig{
+			Source: &documentaipb.BatchDocumentsInputConfig_GcsDocuments{
+				GcsDocuments: &documentaipb.GcsDocuments{
+					Documents: []*documentaipb.GcsDocument{
+						{GcsUri: fmt.Sprintf("gs://%s/%s", inputBucket, inputObject), MimeType: source.format.MimeType()},
+					},
+				},
+			},
+		},
+		DocumentOutputConfig: &documentaipb.DocumentOutputConfig{
+			Destination: &documentaipb.DocumentOutputConfig_GcsOutputConfig_{
+				GcsOutputConfig: &documentaipb.DocumentOutputConfig_GcsOutputConfig{GcsUri: outputURI},
+			},
+		},
+	}
+	if len(pages) > 0 {
+		req.ProcessOptions = &documentaipb.ProcessOptions{
+			PageRange: &documentaipb.ProcessOptions_IndividualPageSelector_{
+				IndividualPageSelector: &documentaipb.ProcessOptions_IndividualPageSelector{
+					Pages: pages,
+				},
+			},
+		}
+	}
+
+	operation, err := p.client.BatchProcessDocuments(processCtx, req)
+	if err != nil {
+		return nil, p.handleProcessingError(op, err)
+	}
+	p.log.Debug().Str("operation", operation.Name()).Msg("Waiting for batch operation")
+
+	// Wait polls the long-running operation until it is done or the context ends
+	if _, err := operation.Wait(processCtx); err != nil {
+		return nil, p.handleProcessingError(op, err)
+	}
+
+	// The operation succeeds even if the single document failed; its status is in the metadata
+	if metadata, err := operation.Metadata(); err == nil && metadata != nil {
+		for _, status := range metadata.GetIndividualProcessStatuses() {
+			if status.GetStatus().GetCode() != 0 {
+				return nil, WrapInvoiceProcessingError(op, ErrProcessingFailed, fmt.Sprintf("Document AI error: %s", status.GetStatus().GetMessage()))
+			}
+			if destination := status.GetOutputGcsDestination(); destination != "" {
+				outputBucket, outputPrefix = splitGCSURI(strings.TrimSuffix(destination, "/") + "/")
+			}
+		}
+	}
+
+	doc, err := p.readAsyncOutput(processCtx, outputBucket, outputPrefix)
+	if err != nil {
+		return nil, WrapInvoiceProcessingError(op, ErrProcessingFailed, err.Error())
+	}
+	return doc, nil
+}
+
+// readAsyncOutput reads the document JSON written below prefix. Large documents are written in
+// several shards, which are joined in shard order.
+func (p *DocumentAIInvoiceProcessor) readAsyncOutput(ctx context.Context, bucket, prefix string) (*documentaipb.Document, error) {
+	var names []string
+	err := p.storage.Objects.List(bucket).Prefix(prefix).Pages(ctx, func(objects *storage.Objects) error {
+		for _, object := range objects.Items {
+			if strings.HasSuffix(object.Name, ".json") {
+				names = append(names, object.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list results in gs://%s/%s: %w", bucket, prefix, err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no results in gs://%s/%s", bucket, prefix)
+	}
+
+	var shards []*documentaipb.Document
+	for _, name := range names {
+		resp, err := p.storage.Objects.Get(bucket, name).Context(ctx).Download()
+		if err != nil {
+			return nil, fmt.Errorf("failed to download gs://%s/%s: %w", bucket, name, err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gs://%s/%s: %w", bucket, name, err)
+		}
+
+		var shard documentaipb.Document
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, &shard); err != nil {
+			return nil, fmt.Errorf("failed to parse gs://%s/%s: %w", bucket, name, err)
+		}
+		shards = append(shards, &shard)
+	}
+
+	return mergeDocumentShards(shards), nil
+}
+
+// mergeDocumentShards joins the shards of one document: text, pages and entities are concatenated
+// in shard order. The text anchors of a shard point into its own text, so those of later shards are
+// moved behind the text of the shards before them.
+func mergeDocumentShards(shards []*documentaipb.Document) *documentaipb.Document {
+	if len(shards) == 1 {
+		return shards[0]
+	}
+
+	sort.SliceStable(shards, func(i, j int) bool {
+		return shards[i].GetShardInfo().GetShardIndex() < shards[j].GetShardInfo().GetShardIndex()
+	})
+
+	merged := &documentaipb.Document{MimeType: shards[0].GetMimeType()}
+	var text strings.Builder
+	for _, shard := range shards {
+		if offset := int64(text.Len()); offset > 0 {
+			for _, page := range shard.GetPages() {
+				shiftTextAnchors(page.ProtoReflect(), offset)
+			}
+			for _, entity := range shard.GetEntities() {
+				shiftTextAnchors(entity.ProtoReflect(), offset)
+			}
+		}
+		text.WriteString(shard.GetText())
+		merged.Pages = append(merged.Pages, shard.GetPages()...)
+		merged.Entities = append(merged.Entities, shard.GetEntities()...)
+	}
+	merged.Text = text.String()
+	return merged
+}
+
+// shiftTextAnchors adds offset to the start and end of every text anchor segment in message, e.g. of
+// a page with its blocks, lines and tokens or of an entity with its properties
+func shiftTextAnchors(message protoreflect.Message, offset int64) {
+	if anchor, ok := message.Interface().(*documentaipb.Document_TextAnchor); ok {
+		for _, segment := range anchor.GetTextSegments() {
+			segment.StartIndex += offset
+			segment.EndIndex += offset
+		}
+		return
+	}
+
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case field.Message() == nil || field.IsMap():
+		case field.IsList():
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				shiftTextAnchors(list.Get(i).Message(), offset)
+			}
+		default:
+			shiftTextAnchors(value.Message(), offset)
+		}
+		return true
+	})
+}
+
+// cleanupAsync deletes the uploaded document and the results of a batch operation. Failures are
+// only logged, since the objects are not needed anymore.
+func (p *DocumentAIInvoiceProcessor) cleanupAsync(inputBucket, inputObject, outputBucket, outputPrefix string) {
+	ctx, cancel := context.WithTimeout(context.Background(), asyncCleanupTimeout)
+	defer cancel()
+
+	if err := p.storage.Objects.Delete(inputBucket, inputObject).Context(ctx).Do(); err != nil {
+		p.log.Warn().Err(err).Str("object", "gs://"+inputBucket+"/"+inputObject).Msg("Failed to delete uploaded document")
+	}
+
+	err := p.storage.Objects.List(outputBucket).Prefix(outputPrefix).Pages(ctx, func(objects *storage.Objects) error {
+		for _, object := range objects.Items {
+			if err := p.storage.Objects.Delete(outputBucket, object.Name).Context(ctx).Do(); err != nil {
+				p.log.Warn().Err(err).Str("object", "gs://"+outputBucket+"/"+object.Name).Msg("Failed to delete batch result")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		p.log.Warn().Err(err).Str("prefix", "gs://"+outputBucket+"/"+outputPrefix).Msg("Failed to list batch results for cleanup")
+	}
+}
+
+// newAsyncRunID returns a unique object name prefix for one batch operation
+func newAsyncRunID() (string, error) {
+	random := make([]byte, 6)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("tools-%s-%s", time.Now().Format("20060102-150405"), hex.EncodeToString(random)), nil
+}
@@ -0,0 +1,184 @@
+package invoice
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/documentai/apiv1/documentaipb"
+	"google.golang.org/api/storage/v1"
+
+	"tools/internal/docformat"
+)
+
+func TestParseProcessingMode(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ProcessingMode
+		wantErr bool
+	}{
+		{"", ProcessingAuto, false},
+		{"auto", ProcessingAuto, false},
+		{"sync", ProcessingSync, false},
+		{" ASYNC ", ProcessingAsync, false},
+		{"batch", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseProcessingMode(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseProcessingMode(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseProcessingMode(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestGCSURI(t *testing.T) {
+	tests := []struct {
+		bucket, folder string
+		want           string
+	}{
+		{"invoices", "", "gs://invoices"},
+		{"gs://invoices", "/incoming/2024/", "gs://invoices/incoming/2024"},
+		{" invoices ", " in ", "gs://invoices/in"},
+		{"", "incoming", ""},
+		{"gs://", "incoming", ""},
+	}
+	for _, tt := range tests {
+		if got := gcsURI(tt.bucket, tt.folder); got != tt.want {
+			t.Errorf("gcsURI(%q, %q) = %q, want %q", tt.bucket, tt.folder, got, tt.want)
+		}
+	}
+}
+
+func TestSplitGCSURI(t *testing.T) {
+	tests := []struct {
+		uri                  string
+		wantBucket, wantPath string
+	}{
+		{"gs://invoices", "invoices", ""},
+		{"gs://invoices/incoming/2024", "invoices", "incoming/2024"},
+		{"gs://out/run-1/", "out", "run-1/"},
+	}
+	for _, tt := range tests {
+		bucket, path := splitGCSURI(tt.uri)
+		if bucket != tt.wantBucket || path != tt.wantPath {
+			t.Errorf("splitGCSURI(%q) = %q, %q, want %q, %q", tt.uri, bucket, path, tt.wantBucket, tt.wantPath)
+		}
+	}
+}
+
+func TestUseAsync(t *testing.T) {
+	small := &sourceDocument{data: []byte("%PDF"), format: docformat.PDF}
+	large := &sourceDocument{data: make([]byte, AsyncThresholdBytes+1), format: docformat.PDF}
+	image := &sourceDocument{data: []byte{0xff, 0xd8, 0xff}, format: docformat.JPEG}
+
+	tests := []struct {
+		name      string
+		storage   bool
+		config    DocumentAIConfig
+		source    *sourceDocument
+		pageCount int
+		want      bool
+	}{
+		{"without storage", false, DocumentAIConfig{Async: true}, large, 100, false},
+		{"sync requested", true, DocumentAIConfig{Sync: true}, large, 100, false},
+		{"async requested", true, DocumentAIConfig{Async: true}, small, 1, true},
+		{"large document", true, DocumentAIConfig{}, large, 1, true},
+		{"pages over the limit", true, DocumentAIConfig{}, small, MaxPagesSync + 1, true},
+		{"pages at the limit", true, DocumentAIConfig{}, small, MaxPagesSync, false},
+		{"unknown page count", true, DocumentAIConfig{}, small, 0, false},
+		{"image with two pages", true, DocumentAIConfig{}, image, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &DocumentAIInvoiceProcessor{config: tt.config}
+			if tt.storage {
+				p.storage = &storage.Service{}
+			}
+			got, reason := p.useAsync(tt.source, tt.pageCount)
+			if got != tt.want {
+				t.Errorf("useAsync() = %v (%q), want %v", got, reason, tt.want)
+			}
+			if got && reason == "" {
+				t.Error("useAsync() gave no reason for async processing")
+			}
+		})
+	}
+}
+
+// textAnchor returns an anchor of one text segment
+func textAnchor(start, end int64) *documentaipb.Document_TextAnchor {
+	return &documentaipb.Document_TextAnchor{
+		TextSegments: []*documentaipb.Document_TextAnchor_TextSegment{{StartIndex: start, EndIndex: end}},
+	}
+}
+
+// shard returns a shard with one page and one entity covering its whole text
+func shard(index int64, text, entityType string) *documentaipb.Document {
+	end := int64(len(text))
+	return &documentaipb.Document{
+		MimeType:  "application/pdf",
+		Text:      text,
+		ShardInfo: &documentaipb.Document_ShardInfo{ShardIndex: index, ShardCount: 3},
+		Pages: []*documentaipb.Document_Page{{
+			Layout: &documentaipb.Document_Page_Layout{TextAnchor: textAnchor(0, end)},
+			Lines:  []*documentaipb.Document_Page_Line{{Layout: &documentaipb.Document_Page_Layout{TextAnchor: textAnchor(0, end)}}},
+		}},
+		Entities: []*documentaipb.Document_Entity{{
+			Type:       entityType,
+			TextAnchor: textAnchor(0, end),
+			Properties: []*documentaipb.Document_Entity{{Type: entityType + "/detail", TextAnchor: textAnchor(0, end)}},
+		}},
+	}
+}
+
+func TestMergeDocumentShards(t *testing.T) {
+	single := shard(0, "Rechnung RE-1\n", "invoice_id")
+	if got := mergeDocumentShards([]*documentaipb.Document{single}); got != single {
+		t.Error("a single shard should be returned as is")
+	}
+
+	texts := map[int64]string{0: "Rechnung RE-1\n", 1: "Positionen\n", 2: "Summe 119,00 EUR\n"}
+	types := map[int64]string{0: "invoice_id", 1: "line_item", 2: "total_amount"}
+	// Shards are listed by object name, which need not follow the shard index
+	merged := mergeDocumentShards([]*documentaipb.Document{
+		shard(2, texts[2], types[2]),
+		shard(0, texts[0], types[0]),
+		shard(1, texts[1], types[1]),
+	})
+
+	if want := texts[0] + texts[1] + texts[2]; merged.GetText() != want {
+		t.Fatalf("text = %q, want %q", merged.GetText(), want)
+	}
+	if merged.GetMimeType() != "application/pdf" || len(merged.GetPages()) != 3 || len(merged.GetEntities()) != 3 {
+		t.Fatalf("merged %d pages and %d entities, want 3 each", len(merged.GetPages()), len(merged.GetEntities()))
+	}
+
+	anchored := func(anchor *documentaipb.Document_TextAnchor) string {
+		segment := anchor.GetTextSegments()[0]
+		return merged.GetText()[segment.GetStartIndex():segment.GetEndIndex()]
+	}
+	for i := int64(0); i < 3; i++ {
+		page, entity := merged.GetPages()[i], merged.GetEntities()[i]
+		if entity.GetType() != types[i] {
+			t.Errorf("entity %d type = %q, want %q", i, entity.GetType(), types[i])
+		}
+		for name, anchor := range map[string]*documentaipb.Document_TextAnchor{
+			"page":     page.GetLayout().GetTextAnchor(),
+			"line":     page.GetLines()[0].GetLayout().GetTextAnchor(),
+			"entity":   entity.GetTextAnchor(),
+			"property": entity.GetProperties()[0].GetTextAnchor(),
+		} {
+			if got := anchored(anchor); got != texts[i] {
+				t.Errorf("shard %d %s anchor covers %q, want %q", i, name, got, texts[i])
+			}
+		}
+	}
+
+	// The page texts used for invoice boundaries follow the rebased anchors
+	if got := strings.Join(pageTexts(merged), "|"); got != texts[0]+"|"+texts[1]+"|"+texts[2] {
+		t.Errorf("pageTexts() = %q", got)
+	}
+}
@@ -0,0 +1,77 @@
+package invoice
+
+import (
+	"fmt"
+	"io"
+
+	"tools/internal/docformat"
+	"tools/internal/pdf"
+)
+
+// formatLimit is the size and page limit of synchronous processing for one document format
+type formatLimit struct {
+	maxBytes int
+	maxPages int
+}
+
+// syncLimits are the synchronous processing limits of the supported formats. PDFs and TIFF scans may
+// have several pages; the other image formats are always a single page.
+var syncLimits = map[docformat.Format]formatLimit{
+	docformat.PDF:  {maxBytes: MaxDocumentSizeBytes, maxPages: MaxPagesSync},
+	docformat.TIFF: {maxBytes: MaxDocumentSizeBytes, maxPages: MaxPagesSync},
+	docformat.GIF:  {maxBytes: MaxDocumentSizeBytes, maxPages: 1},
+	docformat.JPEG: {maxBytes: MaxDocumentSizeBytes, maxPages: 1},
+	docformat.PNG:  {maxBytes: MaxDocumentSizeBytes, maxPages: 1},
+	docformat.BMP:  {maxBytes: MaxDocumentSizeBytes, maxPages: 1},
+	docformat.WEBP: {maxBytes: MaxDocumentSizeBytes, maxPages: 1},
+}
+
+// sourceDocument is a document read for processing, with the format detected from its content
+type sourceDocument struct {
+	data   []byte
+	format docformat.Format
+}
+
+// readDocument reads the document data, detects its format and checks the size limit of that format.
+// Encrypted PDFs are decrypted with PDF_PASSWORDS.
+func (p *DocumentAIInvoiceProcessor) readDocument(op string, r io.Reader) (*sourceDocument, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, WrapInvoiceProcessingError(op, err, "failed to read document data")
+	}
+
+	format, ok := docformat.Detect(data)
+	if !ok {
+		return nil, WrapInvoiceProcessingError(op, ErrUnsupportedFormat, "expected a PDF, TIFF, GIF, JPEG, PNG, BMP or WEBP document")
+	}
+
+	if limit := syncLimits[format]; len(data) > limit.maxBytes {
+		return nil, WrapInvoiceProcessingError(op, ErrDocumentTooLarge,
+			fmt.Sprintf("%s file size: %d bytes (limit %d MB)", format, len(data), limit.maxBytes/(1024*1024)))
+	}
+
+	// Document AI cannot read encrypted PDFs
+	if format == docformat.PDF {
+		data, err = pdf.Decrypt(data, pdf.PasswordsFromEnv())
+		if err != nil {
+			return nil, WrapInvoiceProcessingError(op, err, "failed to decrypt PDF")
+		}
+	}
+
+	return &sourceDocument{data: data, format: format}, nil
+}
+
+// pageCount returns the number of pages processed of the document: the number of selected pages, or
+// all pages without a selection. Zero means the pages could not be counted.
+func (p *DocumentAIInvoiceProcessor) pageCount(source *sourceDocument, pages []int32) int {
+	if len(pages) > 0 {
+		return len(pages)
+	}
+	count, err := docformat.PageCount(source.data, source.format)
+	if err != nil {
+		// Document AI may still read what cannot be counted here; let the request decide
+		p.log.Debug().Err(err).Str("format", string(source.format)).Msg("Could not count document pages")
+		return 0
+	}
+	return count
+}
@@ -3,6 +3,8 @@ package invoice
 import (
 	"errors"
 	"fmt"
+
+	"tools/internal/pdf"
 )
 
 // Common invoice processing errors
@@ -38,11 +40,38 @@ var (
 	// ErrDocumentTooLarge is returned when the PDF exceeds size limits.
 	ErrDocumentTooLarge = errors.New("document exceeds maximum size limit")
 
+	// ErrTooManyPages is returned when a multipage PDF or TIFF has more pages than synchronous
+	// processing allows and async processing is not configured.
+	ErrTooManyPages = errors.New("document has too many pages for synchronous processing")
+
+	// ErrEncryptedPDF is returned when the PDF is password-protected and no
+	// configured password (PDF_PASSWORDS) opens it.
+	ErrEncryptedPDF = pdf.ErrEncryptedPDF
+
 	// ErrUnsupportedFormat is returned when the document format is not supported.
 	ErrUnsupportedFormat = errors.New("unsupported document format")
 
 	// ErrContextCanceled is returned when processing is canceled via context.
 	ErrContextCanceled = errors.New("invoice processing was canceled")
+
+	// ErrLowOCRConfidence is returned by completion when the scan quality is too low to trust
+	// the OCR text and FailOnLowOCRConfidence is enabled. Such invoices need manual review.
+	ErrLowOCRConfidence = errors.New("OCR confidence below minimum")
+
+	// ErrInternalInvoice is returned by booking when vendor and customer are both our company and
+	// no type was given. Whether such an invoice is payable or receivable cannot be read off the
+	// parties, so it must be set explicitly.
+	ErrInternalInvoice = errors.New("vendor and customer are both our company, the invoice type must be given")
+
+	// ErrReminder is returned by booking for a payment reminder (Mahnung) without a dunning fee,
+	// wrapped in a ReminderError. The reminded invoice is already booked, so booking the reminder
+	// would duplicate the liability.
+	ErrReminder = errors.New("document is a payment reminder for an existing invoice, not a new invoice")
+
+	// ErrMissingInvoiceNumber is returned by booking in strict mode when neither Document AI, its
+	// fallback extraction nor completion found an invoice number. Without strict mode such invoices
+	// are booked without Belegfeld 1 under an ID derived from the document content.
+	ErrMissingInvoiceNumber = errors.New("no invoice number found, it must be entered manually")
 )
 
 // InvoiceProcessingError wraps errors with additional context about invoice processing failures.
@@ -128,6 +157,26 @@ func NewValidationError(field string, value interface{}, message string) *Valida
 	}
 }
 
+// ReminderError is returned by booking for a payment reminder that is not booked. It matches
+// ErrReminder with errors.Is.
+type ReminderError struct {
+	// Reference is the number of the reminded invoice; empty if the reminder quotes none.
+	Reference string
+}
+
+// Error implements the error interface.
+func (e *ReminderError) Error() string {
+	if e.Reference == "" {
+		return ErrReminder.Error()
+	}
+	return fmt.Sprintf("%s (invoice %s)", ErrReminder, e.Reference)
+}
+
+// Unwrap returns ErrReminder.
+func (e *ReminderError) Unwrap() error {
+	return ErrReminder
+}
+
 // EntityExtractionError represents errors during entity extraction from Document AI response.
 type EntityExtractionError struct {
 	EntityType string
@@ -77,12 +77,12 @@ func ExampleWithConfidence() {
 	// Display results
 	fmt.Printf("Invoice Processing Results:\n")
 	fmt.Printf("  Invoice Number: %s (confidence: %.1f%%)\n",
-		invoiceData.InvoiceNumber, confidence["invoice_id"]*100)
+		invoiceData.InvoiceNumber, confidence["invoice_number"]*100)
 	fmt.Printf("  Vendor: %s (confidence: %.1f%%)\n",
-		invoiceData.Vendor, confidence["supplier_name"]*100)
+		invoiceData.Vendor, confidence["vendor"]*100)
 	fmt.Printf("  Total Amount: %.2f %s (confidence: %.1f%%)\n",
 		float64(invoiceData.GrossAmount)/100, invoiceData.Currency,
-		confidence["total_amount"]*100)
+		confidence["gross_amount"]*100)
 
 	// Show all confidence scores
 	fmt.Printf("\nAll extracted fields:\n")
@@ -263,7 +263,10 @@ func ExampleInvoiceDataUsage() {
 	fmt.Printf("Invoice Date: %s\n", invoiceData.IssueDate.Format("January 2, 2006"))
 	fmt.Printf("Due Date: %s\n", invoiceData.DueDate.Format("January 2, 2006"))
 
-	if invoiceData.Reference != "" {
-		fmt.Printf("Reference: %s\n", invoiceData.Reference)
+	if invoiceData.PurchaseOrder != "" {
+		fmt.Printf("Purchase Order: %s\n", invoiceData.PurchaseOrder)
+	}
+	if invoiceData.CustomerReference != "" {
+		fmt.Printf("Customer Reference: %s\n", invoiceData.CustomerReference)
 	}
 }
\ No newline at end of file
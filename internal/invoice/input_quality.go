@@ -0,0 +1,56 @@
+package invoice
+
+import (
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/documentai/apiv1/documentaipb"
+	"tools/pkg/models"
+)
+
+// extractInputQuality aggregates the image quality scores and detected orientation of the document's
+// pages. The scan is as good as its worst page, so the lowest page score is used. Returns nil if
+// Document AI reported neither; not every processor version scores image quality.
+func extractInputQuality(doc *documentaipb.Document) *models.InputQuality {
+	quality := &models.InputQuality{Score: 1}
+	reported := false
+	defects := make(map[string]bool)
+
+	for i, page := range doc.GetPages() {
+		pageNumber := int(page.GetPageNumber())
+		if pageNumber == 0 {
+			pageNumber = i + 1
+		}
+
+		orientation := page.GetLayout().GetOrientation()
+		if orientation != documentaipb.Document_Page_Layout_ORIENTATION_UNSPECIFIED {
+			reported = true
+			if orientation != documentaipb.Document_Page_Layout_PAGE_UP {
+				quality.RotatedPages = append(quality.RotatedPages, pageNumber)
+			}
+		}
+
+		scores := page.GetImageQualityScores()
+		if scores == nil {
+			continue
+		}
+		reported = true
+		if scores.GetQualityScore() < quality.Score {
+			quality.Score = scores.GetQualityScore()
+		}
+		for _, defect := range scores.GetDetectedDefects() {
+			if defect.GetConfidence() >= models.LowInputQualityScore {
+				defects[strings.TrimPrefix(defect.GetType(), "quality/defect_")] = true
+			}
+		}
+	}
+
+	if !reported {
+		return nil
+	}
+	for defect := range defects {
+		quality.Defects = append(quality.Defects, defect)
+	}
+	sort.Strings(quality.Defects)
+	return quality
+}
@@ -0,0 +1,102 @@
+package invoice
+
+import (
+	"fmt"
+
+	"tools/internal/vendors"
+	"tools/pkg/models"
+)
+
+// internalTypeConfidence is the type confidence of invoices between our own companies; it is below any
+// sensible TYPE_CONFIDENCE_MIN so the type is never taken as detected
+const internalTypeConfidence = 0.1
+
+// vatIDTypeConfidence is the type confidence of invoices typed by the side our VAT ID is on. A VAT ID
+// read with a wrong digit does not match at all, so a match is close to certain.
+const vatIDTypeConfidence = 0.95
+
+// isOurCompany reports whether name is our company name or one of its aliases
+func (s *DefaultInvoiceCompletionService) isOurCompany(name string) bool {
+	normalized := vendors.NormalizeName(name)
+	if normalized == "" {
+		return false
+	}
+	for _, ours := range append([]string{s.config.CompanyName}, s.config.CompanyAliases...) {
+		if vendors.NamesOverlap(vendors.NormalizeName(ours), normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOurVATID reports whether vatID is one of COMPANY_VAT_IDS
+func (s *DefaultInvoiceCompletionService) isOurVATID(vatID string) bool {
+	normalized := vendors.NormalizeVATID(vatID)
+	if normalized == "" {
+		return false
+	}
+	for _, ours := range s.config.CompanyVATIDs {
+		if vendors.NormalizeVATID(ours) == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// applyVATIDType sets the invoice type from the side our VAT ID is on: as the customer's VAT ID the
+// invoice is addressed to us (PAYABLE), as the vendor's we issued it (RECEIVABLE). This overrides the
+// name-based classification of ChatGPT, which OCR errors in names mislead. Returns whether the type
+// was decided; with our VAT ID on both sides the invoice is marked internal and false is returned.
+func (s *DefaultInvoiceCompletionService) applyVATIDType(invoice *models.Invoice, confidence map[string]float32) bool {
+	vendorIsUs, customerIsUs := s.isOurVATID(invoice.VendorVATID), s.isOurVATID(invoice.CustomerVATID)
+
+	var invoiceType, reasoning string
+	switch {
+	case vendorIsUs && customerIsUs:
+		invoice.Internal = true
+		return false
+	case customerIsUs:
+		invoiceType = "PAYABLE"
+		reasoning = fmt.Sprintf("Unsere USt-IdNr. %s steht beim Rechnungsempfänger", invoice.CustomerVATID)
+	case vendorIsUs:
+		invoiceType = "RECEIVABLE"
+		reasoning = fmt.Sprintf("Unsere USt-IdNr. %s steht beim Rechnungsaussteller", invoice.VendorVATID)
+	default:
+		return false
+	}
+
+	if invoice.Type != invoiceType || invoice.Internal {
+		s.log.Info().
+			Str("detected_type", invoice.Type).
+			Bool("detected_internal", invoice.Internal).
+			Str("vat_id_type", invoiceType).
+			Msg("Invoice type decided by our VAT ID")
+	}
+	invoice.Type = invoiceType
+	invoice.TypeReasoning = reasoning
+	invoice.Internal = false
+	confidence["type"] = vatIDTypeConfidence
+	return true
+}
+
+// markInternalInvoice flags intercompany and self-billing invoices, on which our company is both
+// vendor and customer. The "our name in Bill To" rule cannot decide their type, so a type ChatGPT
+// chose anyway is discarded and the invoice waits for an explicit --type.
+func (s *DefaultInvoiceCompletionService) markInternalInvoice(invoice *models.Invoice, confidence map[string]float32) {
+	if !invoice.Internal && !(s.isOurCompany(invoice.Vendor) && s.isOurCompany(invoice.Customer)) {
+		return
+	}
+
+	s.log.Warn().
+		Str("vendor", invoice.Vendor).
+		Str("customer", invoice.Customer).
+		Str("discarded_type", invoice.Type).
+		Msg("Vendor and customer are both our company, invoice type must be confirmed")
+
+	invoice.Internal = true
+	invoice.Type = ""
+	if invoice.TypeReasoning == "" {
+		invoice.TypeReasoning = "Lieferant und Kunde sind beide unser Unternehmen (konzernintern oder Gutschriftsverfahren)"
+	}
+	confidence["type"] = internalTypeConfidence
+}
@@ -0,0 +1,89 @@
+package invoice
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// builtinInvoiceNumberPatterns are tried first when Document AI found no invoice_id; the first
+// capture group is the invoice number
+var builtinInvoiceNumberPatterns = compileInvoiceNumberPatterns(
+	// HORNBACH specific patterns
+	`(?i)(?:rechnung|belegnr|beleg)[\s\-:\.]*(\d{8,}|\d{4,}\-\d+|\d+\.\d+)`,
+	`(?i)(?:rechnungsnr|rg\.?nr|rg\.?)[\s\-:\.]*(\d{8,}|\d{4,}\-\d+|\d+\.\d+)`,
+	`(?i)(?:invoice|inv)[\s\-:\.]*(?:no|nr|number)[\s\-:\.]*(\d{8,}|\d{4,}\-\d+|\d+\.\d+)`,
+
+	// Generic patterns
+	`(?i)(?:^|\s)(?:nr|no|number)[\s\-:\.]*(\d{6,})`,
+	`(?i)(?:dokument|document)[\s\-:\.]*(?:nr|no)[\s\-:\.]*(\d{6,})`,
+	`(?i)(?:^|\s)(\d{8,})(?:\s|$)`, // Standalone 8+ digit numbers
+
+	// Date-based invoice numbers (common in Germany)
+	`(?i)(\d{4,}\-\d{4,}\-\d+)`, // Format: YYYY-MMMM-XXX
+	`(?i)(\d{6,}\.\d+)`,         // Format: YYYYMM.XXX
+)
+
+// maxInvoiceNumberLength is the longest invoice number taken from a configured pattern; DATEV's
+// Belegfeld 1 holds 36 characters
+const maxInvoiceNumberLength = 36
+
+func compileInvoiceNumberPatterns(patterns ...string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		compiled[i] = regexp.MustCompile(pattern)
+	}
+	return compiled
+}
+
+// LoadInvoiceNumberPatterns reads the supplier-specific invoice number patterns tried after the
+// built-in ones, one regular expression per line. Empty lines and lines starting with # are
+// skipped. Every pattern must compile and have a capture group for the number, e.g.
+//
+//	# ACME: INV-2024-00042
+//	\b(INV-\d{4}-\d{5})\b
+//	# Beleg 4711/24
+//	(?i)beleg\s+(\d+/\d{2})
+func LoadInvoiceNumberPatterns(path string) ([]*regexp.Regexp, error) {
+	const op = "LoadInvoiceNumberPatterns"
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer file.Close()
+
+	var patterns []*regexp.Regexp
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern, err := regexp.Compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s line %d: %w", op, path, lineNumber, err)
+		}
+		if pattern.NumSubexp() == 0 {
+			return nil, fmt.Errorf("%s: %s line %d: pattern %q has no capture group for the invoice number", op, path, lineNumber, line)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: failed to read %s: %w", op, path, err)
+	}
+
+	return patterns, nil
+}
+
+// invoiceNumberPatternsFromEnv loads the patterns of INVOICE_NUMBER_PATTERNS_FILE; nil if unset
+func invoiceNumberPatternsFromEnv() ([]*regexp.Regexp, error) {
+	path := os.Getenv("INVOICE_NUMBER_PATTERNS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return LoadInvoiceNumberPatterns(path)
+}
@@ -0,0 +1,73 @@
+package invoice
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/documentai/apiv1/documentaipb"
+
+	"tools/internal/money"
+	"tools/pkg/models"
+)
+
+// lineVATRatePattern finds a German VAT rate printed on a position, e.g. "19%", "7 %" or "19,00 %"
+var lineVATRatePattern = regexp.MustCompile(`\b(0|7|19)(?:[.,]0{1,2})?\s*%`)
+
+// extractLineItem converts a Document AI line_item entity into a position. Lines without an amount
+// (headings, notes) are skipped. The VAT rate is read from a tax rate property if the processor
+// returns one, otherwise from the line text; it stays nil if the line prints none.
+func (p *DocumentAIInvoiceProcessor) extractLineItem(entity *documentaipb.Document_Entity) (models.LineItem, bool) {
+	var item models.LineItem
+	hasAmount := false
+
+	for _, property := range entity.Properties {
+		value := strings.TrimSpace(property.MentionText)
+
+		switch property.Type {
+		case "line_item/description":
+			item.Description = value
+		case "line_item/amount":
+			if amount, err := p.extractMoneyValue(property); err == nil {
+				item.NetAmount = amount
+				hasAmount = true
+			}
+		case "line_item/quantity":
+			if quantity, err := money.ParseQuantity(value); err == nil {
+				item.Quantity = quantity
+			}
+		case "line_item/tax_rate", "line_item/vat_rate":
+			if rate, ok := parseLineVATRate(value); ok {
+				item.VATRate = &rate
+			}
+		}
+	}
+
+	if !hasAmount {
+		return models.LineItem{}, false
+	}
+
+	if item.VATRate == nil {
+		if rate, ok := parseLineVATRate(entity.MentionText); ok {
+			item.VATRate = &rate
+		}
+	}
+
+	return item, true
+}
+
+// parseLineVATRate returns the VAT rate printed in text; a bare number like "19" is accepted too
+func parseLineVATRate(text string) (float64, bool) {
+	text = strings.TrimSpace(text)
+	if match := lineVATRatePattern.FindStringSubmatch(text); match != nil {
+		rate, err := strconv.ParseFloat(match[1], 64)
+		return rate, err == nil
+	}
+
+	switch text {
+	case "0", "7", "19":
+		rate, err := strconv.ParseFloat(text, 64)
+		return rate, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,185 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/documentai/apiv1/documentaipb"
+
+	"tools/pkg/models"
+)
+
+// pageHeaderChars is how much text from the top of a page is searched for an invoice header.
+const pageHeaderChars = 400
+
+var (
+	// invoiceHeaderPattern matches a document title such as "Rechnung" or "Invoice" at the top of a page.
+	invoiceHeaderPattern = regexp.MustCompile(`(?im)^\s*(?:rechnung|gutschrift|invoice|credit note)\b`)
+
+	// labelledInvoiceNumberPattern only matches explicitly labelled invoice numbers, so customer
+	// numbers, IBANs or order numbers on the page cannot fake a boundary.
+	labelledInvoiceNumberPattern = regexp.MustCompile(`(?i)(?:rechnung(?:s-?)?\s*(?:nr|nummer)|rg\.?\s*-?\s*nr|invoice\s*(?:no|nr|number|#))\.?\s*[:#]?\s*([A-Z0-9][A-Z0-9\-/\.]{2,19})`)
+
+	// pageNumberPattern matches page footers such as "Seite 2 von 3", "Page 2 of 3" or "Seite 2/3".
+	pageNumberPattern = regexp.MustCompile(`(?i)(?:seite|page|blatt)\s*(\d+)\s*(?:von|of|/)\s*(\d+)`)
+)
+
+// ProcessMultiInvoice detects invoice boundaries in a PDF that may contain several invoices
+// (e.g. a scanner batch) and extracts each one separately. A single invoice, including a genuine
+// multi-page invoice, yields a slice with one element.
+func (p *DocumentAIInvoiceProcessor) ProcessMultiInvoice(ctx context.Context, pdfData io.Reader) ([]*models.Invoice, error) {
+	const op = "ProcessMultiInvoice"
+
+	source, err := p.readDocument(op, pdfData)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := p.processDocument(ctx, op, source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := detectInvoiceSegments(pageTexts(doc))
+	if len(segments) <= 1 {
+		invoice, _, err := p.extractInvoiceData(doc)
+		if err != nil {
+			return nil, WrapInvoiceProcessingError(op, err, "failed to extract invoice data")
+		}
+		invoice.CreatedAt = time.Now()
+		invoice.UpdatedAt = invoice.CreatedAt
+		return []*models.Invoice{invoice}, nil
+	}
+
+	p.log.Info().
+		Int("pages", len(doc.Pages)).
+		Int("invoices", len(segments)).
+		Msg("Detected multiple invoices in PDF")
+
+	var invoices []*models.Invoice
+	for i, pages := range segments {
+		segmentDoc, err := p.processDocument(ctx, op, source, pages)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invoice %d (pages %v): %w", op, i+1, pages, err)
+		}
+
+		invoice, _, err := p.extractInvoiceData(segmentDoc)
+		if err != nil {
+			p.log.Warn().
+				Err(err).
+				Int("segment", i+1).
+				Ints32("pages", pages).
+				Msg("Skipping segment without valid invoice data")
+			continue
+		}
+		invoice.CreatedAt = time.Now()
+		invoice.UpdatedAt = invoice.CreatedAt
+
+		invoices = append(invoices, invoice)
+	}
+
+	if len(invoices) == 0 {
+		return nil, WrapInvoiceProcessingError(op, ErrProcessingFailed, "no valid invoice found in any segment")
+	}
+
+	return invoices, nil
+}
+
+// pageTexts returns the OCR text of each page in order.
+func pageTexts(doc *documentaipb.Document) []string {
+	texts := make([]string, len(doc.Pages))
+	for i, page := range doc.Pages {
+		if page.Layout == nil || page.Layout.TextAnchor == nil {
+			continue
+		}
+		var text strings.Builder
+		for _, segment := range page.Layout.TextAnchor.TextSegments {
+			start, end := int(segment.StartIndex), int(segment.EndIndex)
+			if start < 0 || end > len(doc.Text) || start >= end {
+				continue
+			}
+			text.WriteString(doc.Text[start:end])
+		}
+		texts[i] = text.String()
+	}
+	return texts
+}
+
+// detectInvoiceSegments groups pages into invoices and returns the 1-based page numbers of each.
+//
+// A page starts a new invoice only if it carries an invoice header at the top and either shows a
+// labelled invoice number different from the current invoice's, or is explicitly marked as page 1.
+// Pages marked "Seite n von m" with n > 1 always continue the current invoice, so a multi-page
+// invoice that repeats its header on every page is not split.
+func detectInvoiceSegments(texts []string) [][]int32 {
+	if len(texts) == 0 {
+		return nil
+	}
+
+	segments := [][]int32{{1}}
+	currentNumber := labelledInvoiceNumber(texts[0])
+
+	for i := 1; i < len(texts); i++ {
+		text := texts[i]
+		pageNumber := int32(i + 1)
+		number := labelledInvoiceNumber(text)
+
+		if isInvoiceStart(text, number, currentNumber) {
+			segments = append(segments, []int32{pageNumber})
+			currentNumber = number
+			continue
+		}
+
+		last := len(segments) - 1
+		segments[last] = append(segments[last], pageNumber)
+		if currentNumber == "" {
+			currentNumber = number
+		}
+	}
+
+	return segments
+}
+
+// isInvoiceStart decides whether a page begins a new invoice.
+func isInvoiceStart(text, number, currentNumber string) bool {
+	if page, ok := pageOf(text); ok && page > 1 {
+		return false
+	}
+
+	if !invoiceHeaderPattern.MatchString(text[:min(pageHeaderChars, len(text))]) {
+		return false
+	}
+
+	if number != "" && currentNumber != "" {
+		return !strings.EqualFold(number, currentNumber)
+	}
+
+	page, ok := pageOf(text)
+	return ok && page == 1
+}
+
+// labelledInvoiceNumber returns the first explicitly labelled invoice number on a page.
+func labelledInvoiceNumber(text string) string {
+	if matches := labelledInvoiceNumberPattern.FindStringSubmatch(text); len(matches) > 1 {
+		return strings.TrimRight(matches[1], ".-/")
+	}
+	return ""
+}
+
+// pageOf returns n from a "Seite n von m" marker.
+func pageOf(text string) (int, bool) {
+	matches := pageNumberPattern.FindStringSubmatch(text)
+	if len(matches) < 2 {
+		return 0, false
+	}
+	page, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, false
+	}
+	return page, true
+}
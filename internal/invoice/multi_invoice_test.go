@@ -0,0 +1,121 @@
+package invoice
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectInvoiceSegments(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  [][]int32
+	}{
+		{"no pages", nil, nil},
+		{"single page", []string{"Rechnung\nRechnungsnummer: RE-1001\nSumme 119,00 EUR"}, [][]int32{{1}}},
+		{"header repeated with page markers", []string{
+			"Rechnung\nRechnungsnummer: RE-1001\nSeite 1 von 3",
+			"Rechnung\nRechnungsnummer: RE-1001\nSeite 2 von 3",
+			"Rechnung\nRechnungsnummer: RE-1001\nSeite 3 von 3",
+		}, [][]int32{{1, 2, 3}}},
+		{"header repeated with the same number", []string{
+			"Rechnung\nRechnungsnummer: RE-1001\nPositionen 1-20",
+			"Rechnung\nRechnungsnummer: RE-1001\nPositionen 21-30\nSumme 119,00 EUR",
+		}, [][]int32{{1, 2}}},
+		{"header repeated without number or page marker", []string{
+			"Rechnung\nRechnungsnummer: RE-1001\nPositionen 1-20",
+			"Rechnung\nFortsetzung\nSumme 119,00 EUR",
+		}, [][]int32{{1, 2}}},
+		{"continuation page mentions Rechnung", []string{
+			"Rechnung\nRechnungsnummer: RE-1001",
+			"Übertrag\nRechnungsbetrag 119,00 EUR\nBitte überweisen Sie den Betrag dieser Rechnung bis zum 31.03.",
+		}, [][]int32{{1, 2}}},
+		{"later page with a different number marked as page 2", []string{
+			"Rechnung\nRechnungsnummer: RE-1001\nSeite 1 von 2",
+			"Rechnung\nRechnungsnummer: RE-1002\nSeite 2 von 2",
+		}, [][]int32{{1, 2}}},
+		{"number only below the header area", []string{
+			"Rechnung\nRechnungsnummer: RE-1001",
+			"Anlage Leistungsnachweis\nRechnungsnummer: RE-1002",
+		}, [][]int32{{1, 2}}},
+		{"two invoices with different numbers", []string{
+			"Rechnung\nRechnungsnummer: RE-1001\nSumme 119,00 EUR",
+			"Rechnung\nRechnungsnummer: RE-1002\nSumme 59,50 EUR",
+		}, [][]int32{{1}, {2}}},
+		{"scanner batch of multi-page invoices", []string{
+			"Rechnung\nRechnungsnummer: RE-1001\nSeite 1 von 2",
+			"Rechnung\nRechnungsnummer: RE-1001\nSeite 2 von 2",
+			"Invoice\nInvoice No: INV-77\nPage 1 of 2",
+			"Page 2 of 2\nTotal 100.00 EUR",
+			"Gutschrift\nRechnungsnummer: GS-5",
+		}, [][]int32{{1, 2}, {3, 4}, {5}}},
+		{"new invoice without number marked as page 1", []string{
+			"Rechnung\nRechnungsnummer: RE-1001",
+			"Rechnung\nSeite 1 von 1\nSumme 59,50 EUR",
+		}, [][]int32{{1}, {2}}},
+		{"first page without number takes the number of its continuation", []string{
+			"Rechnung\nSeite 1 von 2",
+			"Rechnung\nRechnungsnummer: RE-1001\nSeite 2 von 2",
+			"Rechnung\nRechnungsnummer: RE-1002",
+		}, [][]int32{{1, 2}, {3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectInvoiceSegments(tt.texts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectInvoiceSegments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsInvoiceStart(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		number        string
+		currentNumber string
+		want          bool
+	}{
+		{"different number", "Rechnung\nRechnungsnummer: RE-2", "RE-2", "RE-1", true},
+		{"same number in other case", "Rechnung\nRechnungsnummer: re-1", "re-1", "RE-1", false},
+		{"later page", "Rechnung\nRechnungsnummer: RE-2\nSeite 2 von 3", "RE-2", "RE-1", false},
+		{"later page with slash", "Invoice\nPage 2/3", "", "RE-1", false},
+		{"marked as first page", "Invoice\nPage 1 of 3", "", "RE-1", true},
+		{"no header", "Lieferschein\nRechnungsnummer: RE-2", "RE-2", "RE-1", false},
+		{"header word inside a line", "Ihre Rechnung\nRechnungsnummer: RE-2", "RE-2", "RE-1", false},
+		{"header as part of a word", "Rechnungsbetrag 119,00 EUR\nRechnungsnummer: RE-2", "RE-2", "RE-1", false},
+		{"header without number or page marker", "Rechnung\nFortsetzung", "", "RE-1", false},
+		{"credit note", "Gutschrift\nRechnungsnummer: GS-1", "GS-1", "RE-1", true},
+		{"empty page", "", "", "RE-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isInvoiceStart(tt.text, tt.number, tt.currentNumber); got != tt.want {
+				t.Errorf("isInvoiceStart(%q, %q, %q) = %v, want %v", tt.text, tt.number, tt.currentNumber, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelledInvoiceNumber(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Rechnungsnummer: RE-2024-001", "RE-2024-001"},
+		{"Rechnung Nr. 4711.", "4711"},
+		{"Rg.-Nr. 2024/17/", "2024/17"},
+		{"Invoice No: INV-77", "INV-77"},
+		{"Invoice # A1234", "A1234"},
+		{"Kundennummer: 10001\nIBAN DE89 3704 0044 0532 0130 00", ""},
+		{"Bestellnummer 4500012345", ""},
+	}
+
+	for _, tt := range tests {
+		if got := labelledInvoiceNumber(tt.text); got != tt.want {
+			t.Errorf("labelledInvoiceNumber(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
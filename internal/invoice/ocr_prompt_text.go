@@ -0,0 +1,144 @@
+package invoice
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ocrPageSeparator matches the separator the OCR service puts between pages, "--- Page N ---"
+var ocrPageSeparator = regexp.MustCompile(`^-{3,}\s*Page\s+(\d+)\s*-{3,}$`)
+
+// ocrWhitespaceRun matches runs of blanks within a line, including the no-break spaces of PDF text
+var ocrWhitespaceRun = regexp.MustCompile(`[ \t\x{00a0}\x{2007}\x{202f}]+`)
+
+// ocrRelevantLine matches lines worth keeping when the OCR text is truncated: amounts, totals,
+// VAT, dates, invoice numbers and payment details
+var ocrRelevantLine = regexp.MustCompile(`(?i)\d[.,]\d{2}\b|summe|gesamt|brutto|netto|mwst|ust|steuer|betrag|total|amount|vat|tax|rechnung|invoice|datum|date|fällig|due|iban|zahl|pay`)
+
+// ocrOmission marks where truncateOCRText left out lines
+const ocrOmission = "[…]"
+
+// ocrPromptMaxCharsFromEnv returns the maximum length of the OCR text in completion prompts set with
+// OCR_PROMPT_MAX_CHARS, or 0 (no limit) if it is unset or invalid
+func ocrPromptMaxCharsFromEnv() int {
+	value := strings.TrimSpace(os.Getenv("OCR_PROMPT_MAX_CHARS"))
+	if value == "" {
+		return 0
+	}
+	maxChars, err := strconv.Atoi(value)
+	if err != nil || maxChars < 0 {
+		return 0
+	}
+	return maxChars
+}
+
+// promptOCRText prepares OCR text for a completion prompt: normalized with the page marker of the
+// prompt language and truncated to OCR_PROMPT_MAX_CHARS. The raw text stays in use for the checks
+// after completion.
+func (s *DefaultInvoiceCompletionService) promptOCRText(text, pageMarker string) string {
+	normalized := normalizeOCRText(text, pageMarker)
+	maxChars := ocrPromptMaxCharsFromEnv()
+	prompted := truncateOCRText(normalized, maxChars)
+
+	s.log.Debug().
+		Int("raw_length", utf8.RuneCountInString(text)).
+		Int("normalized_length", utf8.RuneCountInString(normalized)).
+		Int("prompt_length", utf8.RuneCountInString(prompted)).
+		Int("max_chars", maxChars).
+		Msg("Prepared OCR text for the prompt")
+	return prompted
+}
+
+// normalizeOCRText replaces the "--- Page N ---" separators of the OCR text with pageMarker
+// (formatted with the page number), collapses blanks within lines to single spaces and blank lines
+// to at most one, which saves tokens without changing what ChatGPT reads
+func normalizeOCRText(text, pageMarker string) string {
+	var out []string
+	blank := false
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(ocrWhitespaceRun.ReplaceAllString(line, " "))
+		if match := ocrPageSeparator.FindStringSubmatch(line); match != nil {
+			page, _ := strconv.Atoi(match[1])
+			line = fmt.Sprintf(pageMarker, page)
+		}
+
+		if line == "" {
+			blank = len(out) > 0
+			continue
+		}
+		if blank {
+			out = append(out, "")
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// truncateOCRText shortens text to about maxChars characters, keeping the regions that matter for
+// completion: the head with the parties, invoice number and date, the tail with totals and payment
+// terms, and the lines in between that mention amounts, dates or payment details. Left out lines are
+// marked with "[…]". maxChars of 0 keeps the text unchanged.
+func truncateOCRText(text string, maxChars int) string {
+	if maxChars <= 0 || utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	keep := make([]bool, len(lines))
+	used := 0
+	take := func(i int) bool {
+		length := utf8.RuneCountInString(lines[i]) + 1
+		if keep[i] || used+length > maxChars {
+			return false
+		}
+		keep[i] = true
+		used += length
+		return true
+	}
+
+	// A third of the budget for the head, a third for the tail, the rest for relevant lines
+	for i := 0; i < len(lines) && used < maxChars/3; i++ {
+		if !take(i) {
+			break
+		}
+	}
+	for i := len(lines) - 1; i >= 0 && used < 2*maxChars/3; i-- {
+		if !take(i) {
+			break
+		}
+	}
+	for i := range lines {
+		if ocrRelevantLine.MatchString(lines[i]) {
+			take(i)
+		}
+	}
+
+	// Text without line breaks, e.g. from a single layout block, is cut at the limit
+	if used == 0 {
+		runes := []rune(text)
+		return string(runes[:maxChars]) + "\n" + ocrOmission
+	}
+
+	var out []string
+	omitted := false
+	for i, line := range lines {
+		if !keep[i] {
+			omitted = true
+			continue
+		}
+		if omitted {
+			out = append(out, ocrOmission)
+			omitted = false
+		}
+		out = append(out, line)
+	}
+	if omitted {
+		out = append(out, ocrOmission)
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,114 @@
+package invoice
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"tools/pkg/models"
+)
+
+func TestParsePaymentSchedule(t *testing.T) {
+	may := time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		installments []ChatGPTInstallment
+		gross        int64
+		want         []models.PaymentInstallment
+	}{
+		{
+			name:         "single installment",
+			installments: []ChatGPTInstallment{{Amount: "119,00", DueDate: "2024-04-30"}},
+			gross:        11900,
+		},
+		{
+			name: "ordered by due date",
+			installments: []ChatGPTInstallment{
+				{Amount: "59,50", DueDate: "2024-05-31", Description: " Restzahlung "},
+				{Amount: "59,50", DueDate: "2024-04-30", Description: "Anzahlung 50%"},
+			},
+			gross: 11900,
+			want: []models.PaymentInstallment{
+				{Amount: 5950, DueDate: april, Description: "Anzahlung 50%"},
+				{Amount: 5950, DueDate: may, Description: "Restzahlung"},
+			},
+		},
+		{
+			name: "installments without date last",
+			installments: []ChatGPTInstallment{
+				{Amount: "1.000,00", Description: "nach Abnahme"},
+				{Amount: "2.000,00", DueDate: "2024-05-31"},
+				{Amount: "500,00", DueDate: "31.05.2024"},
+			},
+			gross: 350000,
+			want: []models.PaymentInstallment{
+				{Amount: 200000, DueDate: may},
+				{Amount: 100000, Description: "nach Abnahme"},
+				{Amount: 50000},
+			},
+		},
+		{
+			name: "rounding of one cent per installment",
+			installments: []ChatGPTInstallment{
+				{Amount: "33,33", DueDate: "2024-04-30"},
+				{Amount: "33,33", DueDate: "2024-05-31"},
+				{Amount: "33,33", DueDate: "2024-06-30"},
+			},
+			gross: 10000,
+			want: []models.PaymentInstallment{
+				{Amount: 3333, DueDate: april},
+				{Amount: 3333, DueDate: may},
+				{Amount: 3333, DueDate: time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name: "total different from gross",
+			installments: []ChatGPTInstallment{
+				{Amount: "50,00", DueDate: "2024-04-30"},
+				{Amount: "50,00", DueDate: "2024-05-31"},
+			},
+			gross: 11900,
+		},
+		{
+			name: "gross unknown",
+			installments: []ChatGPTInstallment{
+				{Amount: "50,00", DueDate: "2024-05-31"},
+				{Amount: "50,00", DueDate: "2024-04-30"},
+			},
+			want: []models.PaymentInstallment{
+				{Amount: 5000, DueDate: april},
+				{Amount: 5000, DueDate: may},
+			},
+		},
+		{
+			name: "unparsable amount",
+			installments: []ChatGPTInstallment{
+				{Amount: "59,50", DueDate: "2024-04-30"},
+				{Amount: "Rest", DueDate: "2024-05-31"},
+			},
+			gross: 11900,
+		},
+		{
+			name: "zero amount",
+			installments: []ChatGPTInstallment{
+				{Amount: "119,00", DueDate: "2024-04-30"},
+				{Amount: "0,00", DueDate: "2024-05-31"},
+			},
+			gross: 11900,
+		},
+	}
+
+	s := &DefaultInvoiceCompletionService{log: zerolog.Nop()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AMOUNT_LOCALE", "")
+			if got := s.parsePaymentSchedule(tt.installments, tt.gross); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePaymentSchedule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
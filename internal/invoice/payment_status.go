@@ -0,0 +1,100 @@
+package invoice
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"tools/pkg/models"
+)
+
+var (
+	// paidMarkerPattern finds the notes of invoices and receipts that were paid on issue, e.g.
+	// "Betrag dankend erhalten", "Bezahlt per Kreditkarte" or "PAID"
+	paidMarkerPattern = regexp.MustCompile(`(?i)\b(?:bereits\s+|vollständig\s+|dankend\s+)?(?:bezahlt|beglichen)\b|\b(?:betrag|zahlung)\s+(?:dankend\s+)?erhalten\b|\b(?:paid(?:\s+in\s+full)?|payment\s+received)\b`)
+	// directDebitPattern finds the notes of invoices collected by direct debit, e.g. "Der Betrag wird
+	// per SEPA-Lastschrift eingezogen" or "wird von Ihrem Konto abgebucht"
+	directDebitPattern = regexp.MustCompile(`(?i)\b(?:sepa-?)?(?:basis-?|firmen-?)?lastschrift(?:verfahren|einzug)?\b[^\n]{0,40}?\b(?:eingezogen|abgebucht)\b|\bvon\s+ihrem\s+konto\s+(?:\S+\s+){0,3}?(?:abgebucht|eingezogen)\b|\bzahlungsart\s*:?\s*(?:sepa-?)?lastschrift\b|\b(?:collected|paid|debited)\s+(?:by|via)\s+(?:sepa\s+)?direct\s+debit\b`)
+	// paidNegationPattern finds the words before a marker that turn it into a condition or the
+	// opposite, e.g. "Sollten Sie bereits bezahlt haben" in a reminder or "noch nicht bezahlt"
+	paidNegationPattern = regexp.MustCompile(`(?i)\b(?:nicht|kein\w*|falls|sollten|wenn|sofern|bitte|bis|not|if|unless|please)\b[^\n]{0,30}$`)
+	// paidObligationPattern finds the words after a marker that make it a payment request, e.g.
+	// "muss bis zum 15.03. bezahlt werden" or "Paid: 0,00 €"
+	paidObligationPattern = regexp.MustCompile(`(?i)^\s*(?:werden|sein\b|:?\s*(?:EUR|€)?\s*0[,.]00\b)`)
+	// debitFailurePattern finds returned or failed direct debits, which leave the invoice unpaid
+	debitFailurePattern = regexp.MustCompile(`(?i)\b(?:nicht|not|konnte|could)\b`)
+	// paymentDatePattern finds the date following a marker on the same line, e.g. "am 12.03.2024"
+	paymentDatePattern = regexp.MustCompile(`^[^\n\d]{0,30}?\b(\d{1,2}\.\d{1,2}\.(?:\d{4}|\d{2})|\d{4}-\d{2}-\d{2})\b`)
+	// lineDatePattern finds a date anywhere on the line of a marker, e.g. "wird am 15.04.2024 per
+	// Lastschrift eingezogen"
+	lineDatePattern = regexp.MustCompile(`\b(\d{1,2}\.\d{1,2}\.(?:\d{4}|\d{2})|\d{4}-\d{2}-\d{2})\b`)
+)
+
+// paymentDateLayouts are the layouts of the dates paymentDatePattern and lineDatePattern find
+var paymentDateLayouts = []string{"2.1.2006", "2.1.06", "2006-01-02"}
+
+// detectPaymentStatus reports whether the text marks the document as already paid or collected by
+// direct debit, so that no payment is due. date is the payment or debit date if the marker gives one.
+func detectPaymentStatus(text string) (paid bool, date *time.Time) {
+	var marker []int
+	for _, match := range paidMarkerPattern.FindAllStringIndex(text, -1) {
+		if !paidNegationPattern.MatchString(text[:match[0]]) && !paidObligationPattern.MatchString(text[match[1]:]) {
+			marker = match
+			break
+		}
+	}
+	if marker == nil {
+		for _, match := range directDebitPattern.FindAllStringIndex(text, -1) {
+			if !debitFailurePattern.MatchString(text[match[0]:match[1]]) && !paidNegationPattern.MatchString(text[:match[0]]) {
+				marker = match
+				break
+			}
+		}
+	}
+	if marker == nil {
+		return false, nil
+	}
+
+	dateMatch := paymentDatePattern.FindStringSubmatch(text[marker[1]:])
+	if dateMatch == nil {
+		dateMatch = lineDatePattern.FindStringSubmatch(markerLine(text, marker))
+	}
+	if dateMatch != nil {
+		for _, layout := range paymentDateLayouts {
+			if parsed, err := time.Parse(layout, dateMatch[1]); err == nil {
+				date = &parsed
+				break
+			}
+		}
+	}
+	return true, date
+}
+
+// markerLine returns the line of text the marker at the given index range is on
+func markerLine(text string, marker []int) string {
+	start := strings.LastIndexByte(text[:marker[0]], '\n') + 1
+	end := len(text)
+	if i := strings.IndexByte(text[marker[1]:], '\n'); i >= 0 {
+		end = marker[1] + i
+	}
+	return text[start:end]
+}
+
+// applyPaymentStatusDetection marks the invoice as paid, with the payment date if given, when its
+// text shows it was paid on issue or is collected by direct debit. Payment reminders are never paid.
+func applyPaymentStatusDetection(invoice *models.Invoice, text string, confidence map[string]float32) {
+	if invoice.IsPaid || invoice.SubType == models.InvoiceSubTypeReminder || text == "" {
+		return
+	}
+
+	paid, date := detectPaymentStatus(text)
+	if !paid {
+		return
+	}
+	invoice.IsPaid = true
+	confidence["is_paid"] = 0.8
+	if invoice.PaymentDate == nil && date != nil {
+		invoice.PaymentDate = date
+		confidence["payment_date"] = 0.7
+	}
+}
@@ -0,0 +1,66 @@
+package invoice
+
+import (
+	"regexp"
+	"strings"
+
+	"tools/pkg/models"
+)
+
+var (
+	// prepaymentTitlePattern finds the document titles of deposit and progress billing invoices
+	prepaymentTitlePattern = regexp.MustCompile(`(?i)\b(?:anzahlungs|abschlags|vorauszahlungs)rechnung\b|\brechnung\s+über\s+(?:eine\s+)?(?:anzahlung|abschlagszahlung)\b|\b(?:down|advance)[- ]payment\s+invoice\b|\bdeposit\s+invoice\b`)
+	// finalTitlePattern finds the document titles of final invoices
+	finalTitlePattern = regexp.MustCompile(`(?i)\b(?:schluss|end)rechnung\b|\bschlussabrechnung\b|\bfinal\s+invoice\b`)
+	// prepaymentDeductionPattern finds the deduction of earlier prepayments that marks a final invoice
+	// even without the title, e.g. "abzüglich geleisteter Anzahlungen" or "abzgl. Abschlagszahlung"
+	prepaymentDeductionPattern = regexp.MustCompile(`(?i)(?:\b(?:abzüglich|abzgl\.?|abz\.|less)|\./\.)\s+(?:der\s+)?(?:bereits\s+)?(?:geleistete[nr]?\s+|erhaltene[nr]?\s+|gezahlte[nr]?\s+)?(?:anzahlung|abschlag|abschlagszahlung|vorauszahlung|down payment|advance payment)`)
+	// prepaymentReferencePattern finds the number of the deducted prepayment invoice
+	prepaymentReferencePattern = regexp.MustCompile(`(?i)\b(?:anzahlungs|abschlags)rechnung(?:s-?nr\.?|snummer)?\s*(?:nr\.?|nummer|no\.?)?\s*:?\s*([A-Z0-9][A-Z0-9/_-]{2,})`)
+)
+
+// detectPrepayment classifies the document as a prepayment invoice (Anzahlungs- or
+// Abschlagsrechnung) or a final invoice (Schlussrechnung) from the cues in its text. Both kinds tend
+// to mention the other ("wird mit der Schlussrechnung verrechnet", "Anzahlungsrechnung Nr. 1"), so a
+// deduction of prepayments marks a final invoice and otherwise the title found first wins. reference
+// is the number of a deducted prepayment invoice, if the final invoice quotes one.
+func detectPrepayment(text string) (subType, reference string) {
+	prepayment := prepaymentTitlePattern.FindStringIndex(text)
+	final := finalTitlePattern.FindStringIndex(text)
+
+	switch {
+	case prepaymentDeductionPattern.MatchString(text), final != nil && (prepayment == nil || final[0] < prepayment[0]):
+		if match := prepaymentReferencePattern.FindStringSubmatch(text); match != nil && containsDigit(match[1]) {
+			reference = match[1]
+		}
+		return models.InvoiceSubTypeFinal, reference
+	case prepayment != nil:
+		return models.InvoiceSubTypePrepayment, ""
+	}
+	return "", ""
+}
+
+// applyPrepaymentDetection sets the invoice's sub-type and prepayment reference from text unless
+// they are already known
+func applyPrepaymentDetection(invoice *models.Invoice, text string, confidence map[string]float32) {
+	if invoice.SubType != "" || text == "" {
+		return
+	}
+
+	subType, reference := detectPrepayment(text)
+	if subType == "" {
+		return
+	}
+	invoice.SubType = subType
+	confidence["sub_type"] = 0.8
+	if invoice.PrepaymentReference == "" && reference != "" {
+		invoice.PrepaymentReference = reference
+		confidence["prepayment_reference"] = 0.6
+	}
+}
+
+// containsDigit reports whether s contains a digit; invoice numbers do, words caught by the
+// reference pattern (e.g. "vom") do not
+func containsDigit(s string) bool {
+	return strings.ContainsAny(s, "0123456789")
+}
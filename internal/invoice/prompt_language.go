@@ -0,0 +1,230 @@
+package invoice
+
+import "strings"
+
+// completionPrompts holds the language-specific texts of the completion prompts. The JSON field
+// names and the requested output (German type reasoning and accounting summary) are the same in
+// every language; only the instructions follow the document, so that e.g. English invoices are not
+// read with German-centric hints.
+type completionPrompts struct {
+	system         string // Format with company name, summary instructions, company name and aliases
+	intro          string
+	knownData      string
+	vendor         string // Format with the extracted vendor
+	vendorHint     string
+	customer       string // Format with the extracted customer
+	customerHint   string
+	invoiceNumber  string // Format with the extracted invoice number
+	grossAmount    string // Format with the gross amount and currency
+	companyContext string // Format with the company name
+	aliases        string // Format with the company aliases
+	vatIDs         string // Format with the company VAT IDs
+	companyAddress string // Format with our postal address
+	recipientOurs  string // Format with the extracted recipient address, which is ours
+	recipientOther string // Format with the extracted recipient address, which is not ours
+	typeRules      string
+	ocrText        string
+	pageMarker     string // Replaces the OCR page separators; format with the page number
+	jsonIntro      string
+	fields         map[string]string // Description of each JSON field by name
+	closing        string
+}
+
+// completionPromptsByLanguage maps the ISO 639-1 code of a document's dominant language to its
+// completion prompts. Add an entry to give another language prompts of its own; documents in
+// languages without an entry get the English prompts, documents without a detected language the
+// German ones.
+var completionPromptsByLanguage = map[string]*completionPrompts{
+	"de": &germanCompletionPrompts,
+	"en": &englishCompletionPrompts,
+}
+
+// completionPromptsFor returns the completion prompts for a document whose dominant language is
+// language, e.g. "de", "en" or "fr-FR", and the language the prompts are written in
+func completionPromptsFor(language string) (*completionPrompts, string) {
+	language, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(language)), "-")
+	switch prompts, ok := completionPromptsByLanguage[language]; {
+	case ok:
+		return prompts, language
+	case language == "":
+		return &germanCompletionPrompts, "de"
+	default:
+		return &englishCompletionPrompts, "en"
+	}
+}
+
+var germanCompletionPrompts = completionPrompts{
+	system: `Du analysierst Rechnungen für %s. Deine wichtigste Aufgabe ist die korrekte Bestimmung des Rechnungstyps.
+
+KRITISCH: Bestimme ob diese Rechnung PAYABLE oder RECEIVABLE ist:
+
+** PAYABLE (Eingangsrechnung) = WIR MÜSSEN ZAHLEN **
+- Rechnung VON einem Lieferanten AN unser Unternehmen
+- Wir sind der Käufer/Rechnungsempfänger
+- Zahlungsanweisungen: Geld soll AN den Lieferanten/Verkäufer
+- Bankverbindung gehört dem Verkäufer/Lieferanten
+- Typische Begriffe: "Rechnung an", "Invoice To", "Bill To" + unser Firmenname
+- Lieferant/Verkäufer ist NICHT unser Unternehmen
+
+** RECEIVABLE (Ausgangsrechnung) = WIR BEKOMMEN GELD **
+- Rechnung VON unserem Unternehmen AN einen Kunden
+- Wir sind der Verkäufer/Rechnungssteller
+- Zahlungsanweisungen: Geld soll AN unser Unternehmen
+- Bankverbindung gehört uns
+- Typische Begriffe: "From" + unser Firmenname, wir sind der Absender
+- Kunde ist NICHT unser Unternehmen
+
+ENTSCHEIDUNGSHILFEN:
+1. Wer stellt die Rechnung? (From/Absender) → Wenn wir = RECEIVABLE
+2. Wer soll zahlen? (To/Empfänger) → Wenn wir = PAYABLE  
+3. Wessen Bankdaten stehen drauf? → Wenn unsere = RECEIVABLE
+4. Deutsche Begriffe: "Lieferant", "Anbieter", "Verkäufer" → meist PAYABLE für uns
+
+** INTERNAL = BEIDE SEITEN SIND UNSER UNTERNEHMEN **
+- Lieferant UND Kunde sind unser Unternehmen oder einer unserer Aliases
+- z.B. konzerninterne Verrechnung oder Gutschriftsverfahren
+- Dann NICHT raten: "type": "INTERNAL", der Typ wird manuell festgelegt
+
+%sCompany Context:
+- Our company: %s
+- Aliases: %s
+
+IMPORTANT: Return ONLY valid JSON with NO trailing commas. 
+- Use null for missing values
+- Amounts should be in the original currency format (e.g., "580.00" for 580 euros)
+- Dates should be in YYYY-MM-DD format
+- Ensure the JSON is perfectly formatted with no syntax errors
+- Do NOT add a trailing comma after the last field`,
+	intro:          "Analysiere diese Rechnung und extrahiere die fehlenden Informationen:\n\n",
+	knownData:      "Bereits extrahierte Daten:\n",
+	vendor:         "Vendor/Lieferant: %s\n",
+	vendorHint:     "HINWEIS: Da bereits ein Vendor/Lieferant erkannt wurde, ist dies wahrscheinlich eine PAYABLE Rechnung (Eingangsrechnung)\n",
+	customer:       "Customer/Kunde: %s\n",
+	customerHint:   "HINWEIS: Da bereits ein Customer/Kunde erkannt wurde, ist dies wahrscheinlich eine RECEIVABLE Rechnung (Ausgangsrechnung)\n",
+	invoiceNumber:  "Rechnungsnummer: %s\n",
+	grossAmount:    "Bruttobetrag: %.2f %s\n",
+	companyContext: "\nFIRMEN-KONTEXT für Typ-Bestimmung:\nUnser Unternehmen: %s\n",
+	aliases:        "Unsere Aliases: %s\n",
+	vatIDs:         "Unsere USt-IdNr.: %s (beim Rechnungsempfänger = PAYABLE, beim Aussteller = RECEIVABLE – eindeutiger als der Name)\n",
+	companyAddress: "Unsere Anschrift: %s (im Block 'Rechnung an'/Rechnungsempfänger = PAYABLE, auch wenn unser Name abgekürzt ist)\n",
+	recipientOurs:  "HINWEIS: Die extrahierte Empfängeranschrift \"%s\" ist unsere Anschrift → spricht für PAYABLE\n",
+	recipientOther: "HINWEIS: Die extrahierte Empfängeranschrift \"%s\" ist nicht unsere Anschrift → spricht gegen PAYABLE\n",
+	typeRules: "→ Wenn unser Name im 'Bill To'/'Rechnung an' steht = PAYABLE (wir zahlen)\n" +
+		"→ Wenn unser Name im 'From'/'Von' steht = RECEIVABLE (wir bekommen Geld)\n" +
+		"→ Wenn unser Name in beiden steht = INTERNAL (nicht raten)\n\n",
+	ocrText:    "\nOCR Text:\n",
+	pageMarker: "[Seite %d]",
+	jsonIntro:  "\n\nGib JSON zurück mit diesen Feldern (nur fehlende Felder):\n",
+	fields: map[string]string{
+		"type":               "PAYABLE, RECEIVABLE oder INTERNAL (ERFORDERLICH - siehe Entscheidungshilfen oben)",
+		"type_confidence":    "Konfidenz-Score 0-1 (0.9+ für eindeutige Indikatoren)",
+		"type_reasoning":     "Deutsche Begründung der Typ-Bestimmung mit konkreten Textstellen",
+		"service_date":       "Leistungsdatum/Lieferdatum YYYY-MM-DD (null wenn nicht angegeben)",
+		"purchase_order":     "Bestellnummer/PO-Nummer (null wenn nicht angegeben)",
+		"customer_reference": "Kunden-/Auftragsreferenz wie 'Ihr Zeichen', 'Ihre Referenz', Projekt- oder Auftragsnummer, NICHT die Bestellnummer (null wenn nicht angegeben)",
+		"customer_email":     "NUR bei RECEIVABLE: E-Mail-Adresse des Kunden (Rechnungsempfänger), NICHT unsere eigene (null wenn nicht angegeben)",
+		"customer_contact":   "NUR bei RECEIVABLE: Ansprechpartner beim Kunden, z.B. 'z.Hd. Frau Müller' (null wenn nicht angegeben)",
+		"customer_address":   "Postanschrift im Block 'Rechnung an'/Rechnungsempfänger: Straße Hausnummer, PLZ Ort (null wenn nicht angegeben)",
+		"payment_schedule":   `NUR bei Ratenzahlung/Zahlungsplan mit mehreren Fälligkeiten (z.B. 50% sofort, 50% in 30 Tagen): [{"amount": "Betrag als String", "due_date": "YYYY-MM-DD", "description": "z.B. Anzahlung"}], sonst null`,
+		"vendor":             "vendor/supplier company name",
+		"customer":           "customer/buyer company name",
+		"invoice_number":     "invoice or reference number",
+		"issue_date":         "YYYY-MM-DD",
+		"due_date":           "YYYY-MM-DD",
+		"net_amount":         "amount before tax as string",
+		"vat_amount":         "tax amount as string",
+		"gross_amount":       "total amount as string",
+		"currency":           "currency code like EUR, USD",
+		"description":        "brief invoice description",
+	},
+	closing: "WICHTIG: Stelle sicher dass das JSON KEINE trailing comma nach dem letzten Feld hat. Prüfe die JSON-Syntax sorgfältig!\n" +
+		"AUSSCHLIESSLICH gültiges JSON ohne Text davor oder danach!",
+}
+
+var englishCompletionPrompts = completionPrompts{
+	system: `You analyze invoices for %s. Your most important task is to determine the invoice type correctly.
+
+CRITICAL: Determine whether this invoice is PAYABLE or RECEIVABLE:
+
+** PAYABLE (incoming invoice) = WE HAVE TO PAY **
+- Invoice FROM a supplier TO our company
+- We are the buyer/recipient of the invoice
+- Payment instructions: money goes TO the supplier/seller
+- The bank details belong to the seller/supplier
+- Typical terms: "Invoice To", "Bill To", "Sold To" + our company name
+- The supplier/seller is NOT our company
+
+** RECEIVABLE (outgoing invoice) = WE GET PAID **
+- Invoice FROM our company TO a customer
+- We are the seller/issuer of the invoice
+- Payment instructions: money goes TO our company
+- The bank details are ours
+- Typical terms: "From" + our company name, we are the sender
+- The customer is NOT our company
+
+DECISION GUIDE:
+1. Who issues the invoice? (From/sender) → If us = RECEIVABLE
+2. Who has to pay? (To/recipient) → If us = PAYABLE
+3. Whose bank details are shown? → If ours = RECEIVABLE
+4. The document may be in English, French or another language; judge by the parties, not by the language
+
+** INTERNAL = BOTH PARTIES ARE OUR COMPANY **
+- Supplier AND customer are our company or one of our aliases
+- e.g. intercompany charges or self-billing
+- Do NOT guess then: "type": "INTERNAL", the type is set manually
+
+%sCompany Context:
+- Our company: %s
+- Aliases: %s
+
+IMPORTANT: Return ONLY valid JSON with NO trailing commas.
+- Use null for missing values
+- Amounts should be in the original currency format (e.g., "580.00" for 580 euros)
+- Dates should be in YYYY-MM-DD format
+- Ensure the JSON is perfectly formatted with no syntax errors
+- Do NOT add a trailing comma after the last field`,
+	intro:          "Analyze this invoice and extract the missing information:\n\n",
+	knownData:      "Data extracted so far:\n",
+	vendor:         "Vendor/supplier: %s\n",
+	vendorHint:     "NOTE: Since a vendor/supplier was already recognized, this is probably a PAYABLE invoice (incoming invoice)\n",
+	customer:       "Customer: %s\n",
+	customerHint:   "NOTE: Since a customer was already recognized, this is probably a RECEIVABLE invoice (outgoing invoice)\n",
+	invoiceNumber:  "Invoice number: %s\n",
+	grossAmount:    "Gross amount: %.2f %s\n",
+	companyContext: "\nCOMPANY CONTEXT for the type:\nOur company: %s\n",
+	aliases:        "Our aliases: %s\n",
+	vatIDs:         "Our VAT IDs: %s (given for the recipient = PAYABLE, for the issuer = RECEIVABLE – more reliable than the name)\n",
+	companyAddress: "Our address: %s (in the 'Bill To'/recipient block = PAYABLE, even if our name is abbreviated)\n",
+	recipientOurs:  "NOTE: The extracted recipient address \"%s\" is our address → indicates PAYABLE\n",
+	recipientOther: "NOTE: The extracted recipient address \"%s\" is not our address → speaks against PAYABLE\n",
+	typeRules: "→ If our name is under 'Bill To'/'Invoice To' = PAYABLE (we pay)\n" +
+		"→ If our name is under 'From'/'Seller' = RECEIVABLE (we get paid)\n" +
+		"→ If our name is on both sides = INTERNAL (do not guess)\n\n",
+	ocrText:    "\nOCR text:\n",
+	pageMarker: "[Page %d]",
+	jsonIntro:  "\n\nReturn JSON with these fields (missing fields only):\n",
+	fields: map[string]string{
+		"type":               "PAYABLE, RECEIVABLE or INTERNAL (REQUIRED - see the decision guide above)",
+		"type_confidence":    "confidence score 0-1 (0.9+ for unambiguous indicators)",
+		"type_reasoning":     "reasoning for the type in German, quoting the relevant text",
+		"service_date":       "date of delivery or service (Leistungsdatum) YYYY-MM-DD (null if not stated)",
+		"purchase_order":     "purchase order number (PO number) (null if not stated)",
+		"customer_reference": "customer or order reference such as 'Your reference', project or job number, NOT the purchase order number (null if not stated)",
+		"customer_email":     "ONLY for RECEIVABLE: e-mail address of the customer (invoice recipient), NOT our own (null if not stated)",
+		"customer_contact":   "ONLY for RECEIVABLE: contact person at the customer, e.g. 'Attn: Ms Miller' (null if not stated)",
+		"customer_address":   "postal address in the 'Bill To'/recipient block: street and number, postal code and city (null if not stated)",
+		"payment_schedule":   `ONLY for installments/payment plans with several due dates (e.g. 50% now, 50% in 30 days): [{"amount": "amount as string", "due_date": "YYYY-MM-DD", "description": "e.g. down payment"}], otherwise null`,
+		"vendor":             "vendor/supplier company name",
+		"customer":           "customer/buyer company name",
+		"invoice_number":     "invoice or reference number",
+		"issue_date":         "YYYY-MM-DD",
+		"due_date":           "YYYY-MM-DD",
+		"net_amount":         "amount before tax as string",
+		"vat_amount":         "tax amount as string",
+		"gross_amount":       "total amount as string",
+		"currency":           "currency code like EUR, USD",
+		"description":        "brief invoice description",
+	},
+	closing: "IMPORTANT: Make sure the JSON has NO trailing comma after the last field. Check the JSON syntax carefully!\n" +
+		"ONLY valid JSON without any text before or after it!",
+}
@@ -0,0 +1,172 @@
+package invoice
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"tools/pkg/models"
+)
+
+// addressTypeConfidence is the type confidence of PAYABLE invoices addressed to our postal address.
+// The address is printed by the vendor and survives abbreviated company names, but a tenant at the
+// same address or an old address book entry is possible, so it ranks below our VAT ID.
+const addressTypeConfidence = 0.85
+
+// addressConflictTypeConfidence caps the type confidence of RECEIVABLE invoices whose recipient block
+// holds our own address; below the default TYPE_CONFIDENCE_MIN so the type must be confirmed
+const addressConflictTypeConfidence = 0.5
+
+// addressMatch is the result of comparing the recipient address of an invoice with ours
+type addressMatch int
+
+const (
+	addressUnknown addressMatch = iota // No recipient address, or COMPANY_ADDRESS not set or incomplete
+	addressOurs                        // Same postal code and street with house number
+	addressOther                       // A different postal code or street
+)
+
+// addressPostalCodePattern finds a postal code followed by the city, e.g. "10115 Berlin" or "D-10115 Berlin"
+var addressPostalCodePattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}])(?:[a-z]{1,2}-)?(\d{4,5})\s+\p{L}`)
+
+// umlautReplacer and streetReplacer spell umlauts out and shorten the street suffix, so
+// "Musterstraße 12", "Musterstrasse 12" and "Musterstr. 12" compare equal
+var (
+	umlautReplacer = strings.NewReplacer("ß", "ss", "ä", "ae", "ö", "oe", "ü", "ue")
+	streetReplacer = strings.NewReplacer("strasse", "str")
+)
+
+// normalizeAddressPart reduces part of an address to lowercase letters and digits. Numbers keep a
+// space between them, so the house number does not run into the postal code.
+func normalizeAddressPart(part string) string {
+	part = streetReplacer.Replace(umlautReplacer.Replace(strings.ToLower(part)))
+	var normalized strings.Builder
+	var last rune
+	separated := false
+	for _, r := range part {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			separated = true
+			continue
+		}
+		if separated && unicode.IsDigit(last) && unicode.IsDigit(r) {
+			normalized.WriteRune(' ')
+		}
+		normalized.WriteRune(r)
+		last, separated = r, false
+	}
+	return normalized.String()
+}
+
+// oneLineAddress joins the lines of an extracted address with commas
+func oneLineAddress(address string) string {
+	var lines []string
+	for _, line := range strings.Split(address, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, strings.TrimSuffix(line, ","))
+		}
+	}
+	return strings.Join(lines, ", ")
+}
+
+// addressParts splits our configured address, e.g. "Musterstraße 12, 10115 Berlin", into the
+// normalized street with house number and the postal code. Both are empty if the address lacks one.
+func addressParts(address string) (street, postalCode string) {
+	for _, part := range strings.FieldsFunc(address, func(r rune) bool { return r == ',' || r == '\n' }) {
+		part = strings.ToLower(part)
+		if loc := addressPostalCodePattern.FindStringSubmatchIndex(part); loc != nil && postalCode == "" {
+			// The street may precede the postal code on the same line
+			postalCode = part[loc[2]:loc[3]]
+			part = part[:loc[0]]
+		}
+		normalized := normalizeAddressPart(part)
+		if street == "" && strings.ContainsFunc(normalized, unicode.IsDigit) && strings.ContainsFunc(normalized, unicode.IsLetter) {
+			street = normalized
+		}
+	}
+	if street == "" || postalCode == "" {
+		return "", ""
+	}
+	return street, postalCode
+}
+
+// matchOurAddress compares a recipient address with ours. It is ours if it contains our postal
+// code and our street with house number, wherever the OCR put the line breaks; names, c/o lines
+// and the country are ignored. A recipient address without postal code cannot be compared.
+func matchOurAddress(ours, recipient string) addressMatch {
+	street, postalCode := addressParts(ours)
+	if street == "" {
+		return addressUnknown
+	}
+	matches := addressPostalCodePattern.FindAllStringSubmatch(strings.ToLower(recipient), -1)
+	if len(matches) == 0 {
+		return addressUnknown
+	}
+	for _, match := range matches {
+		if match[1] == postalCode && containsStreet(normalizeAddressPart(recipient), street) {
+			return addressOurs
+		}
+	}
+	return addressOther
+}
+
+// containsStreet reports whether the normalized address contains the normalized street with its
+// complete house number, so "musterstr12" is not found in "musterstr123"
+func containsStreet(address, street string) bool {
+	for offset := 0; ; {
+		index := strings.Index(address[offset:], street)
+		if index < 0 {
+			return false
+		}
+		end := offset + index + len(street)
+		if end == len(address) || !unicode.IsDigit(rune(address[end])) {
+			return true
+		}
+		offset += index + 1
+	}
+}
+
+// recipientAddressMatch compares the recipient address of the invoice with COMPANY_ADDRESS
+func (s *DefaultInvoiceCompletionService) recipientAddressMatch(invoice *models.Invoice) addressMatch {
+	if s.config.CompanyAddress == "" {
+		return addressUnknown
+	}
+	return matchOurAddress(s.config.CompanyAddress, invoice.CustomerAddress)
+}
+
+// checkRecipientAddress confirms the invoice type ChatGPT chose with our address in the recipient
+// block: it raises the confidence of a PAYABLE invoice addressed to us and caps that of a
+// RECEIVABLE one, which then needs confirmation. Types decided by our VAT ID, internal invoices
+// and types completion did not determine are left alone.
+func (s *DefaultInvoiceCompletionService) checkRecipientAddress(invoice *models.Invoice, confidence map[string]float32) {
+	typeConfidence, completed := confidence["type"]
+	if !completed || invoice.Internal || s.recipientAddressMatch(invoice) != addressOurs {
+		return
+	}
+
+	switch invoice.Type {
+	case "PAYABLE":
+		if typeConfidence < addressTypeConfidence {
+			confidence["type"] = addressTypeConfidence
+		}
+		invoice.TypeReasoning = appendTypeReasoning(invoice.TypeReasoning, "Unsere Anschrift steht beim Rechnungsempfänger")
+	case "RECEIVABLE":
+		s.log.Warn().
+			Str("customer_address", invoice.CustomerAddress).
+			Float32("type_confidence", typeConfidence).
+			Msg("Receivable invoice is addressed to our own address, invoice type must be confirmed")
+		if typeConfidence > addressConflictTypeConfidence {
+			confidence["type"] = addressConflictTypeConfidence
+		}
+		invoice.TypeReasoning = appendTypeReasoning(invoice.TypeReasoning,
+			fmt.Sprintf("Widerspruch: unsere Anschrift steht beim Rechnungsempfänger (%s)", invoice.CustomerAddress))
+	}
+}
+
+// appendTypeReasoning adds a sentence to the type reasoning
+func appendTypeReasoning(reasoning, sentence string) string {
+	if reasoning = strings.TrimSpace(reasoning); reasoning == "" {
+		return sentence
+	}
+	return strings.TrimSuffix(reasoning, ".") + ". " + sentence
+}
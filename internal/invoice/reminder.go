@@ -0,0 +1,80 @@
+package invoice
+
+import (
+	"regexp"
+
+	"tools/internal/money"
+	"tools/pkg/models"
+)
+
+var (
+	// reminderTitlePattern finds the document titles of payment reminders, e.g. "1. Mahnung",
+	// "Letzte Mahnung" or "Zahlungserinnerung"
+	reminderTitlePattern = regexp.MustCompile(`(?i)\b(?:zahlungserinnerung|mahnung|payment\s+reminder|reminder\s+notice)\b`)
+	// reminderNegationPattern finds the words before a title that turn it into a payment term of a
+	// regular invoice, e.g. "kommen Sie ohne weitere Mahnung in Verzug"
+	reminderNegationPattern = regexp.MustCompile(`(?i)\b(?:ohne|keine)\s+(?:weitere|vorherige|gesonderte|besondere)?\s*$`)
+	// dunningFeeLinePattern finds a dunning fee charged as its own line, which marks a reminder even
+	// without the title; "Mahngebühren von 5,00 €" in the payment terms of an invoice does not match
+	dunningFeeLinePattern = regexp.MustCompile(`(?im)^[^\S\n]*(?:zzgl\.\s*)?mahngebühr(?:en)?[^\S\n]*:?\s*(?:EUR|€)?\s*(\d{1,3}(?:\.\d{3})*,\d{2}|\d+[.,]\d{2})`)
+	// dunningFeePattern finds the dunning fee anywhere in a reminder
+	dunningFeePattern = regexp.MustCompile(`(?i)\b(?:mahngebühr(?:en)?|mahnkosten|reminder\s+fee)\b[^\d\n]{0,20}?(\d{1,3}(?:\.\d{3})*,\d{2}|\d+[.,]\d{2})`)
+	// reminderReferencePattern finds the number of the reminded invoice
+	reminderReferencePattern = regexp.MustCompile(`(?i)\b(?:rechnung|invoice)(?:s-?nr\.?|snummer)?\s*(?:nr\.?|nummer|no\.?)?\s*:?\s*([A-Z0-9][A-Z0-9/_-]{2,})`)
+)
+
+// detectReminder reports whether the document is a payment reminder (Mahnung, Zahlungserinnerung)
+// rather than an invoice, from its title or a separate dunning fee line. reference is the number of
+// the reminded invoice and fee the dunning fee in cents, if the reminder quotes them.
+func detectReminder(text string) (reminder bool, reference string, fee int64) {
+	for _, match := range reminderTitlePattern.FindAllStringIndex(text, -1) {
+		if !reminderNegationPattern.MatchString(text[:match[0]]) {
+			reminder = true
+			break
+		}
+	}
+
+	feeMatch := dunningFeeLinePattern.FindStringSubmatch(text)
+	if feeMatch != nil {
+		reminder = true
+	} else if reminder {
+		feeMatch = dunningFeePattern.FindStringSubmatch(text)
+	}
+	if !reminder {
+		return false, "", 0
+	}
+
+	if feeMatch != nil {
+		fee, _ = money.ParseCents(feeMatch[1])
+	}
+	for _, match := range reminderReferencePattern.FindAllStringSubmatch(text, -1) {
+		if containsDigit(match[1]) {
+			reference = match[1]
+			break
+		}
+	}
+	return true, reference, fee
+}
+
+// applyReminderDetection marks the invoice as a payment reminder and sets the reminded invoice and
+// dunning fee from text unless the sub-type is already known
+func applyReminderDetection(invoice *models.Invoice, text string, confidence map[string]float32) {
+	if invoice.SubType != "" || text == "" {
+		return
+	}
+
+	reminder, reference, fee := detectReminder(text)
+	if !reminder {
+		return
+	}
+	invoice.SubType = models.InvoiceSubTypeReminder
+	confidence["sub_type"] = 0.8
+	if invoice.ReminderReference == "" && reference != "" {
+		invoice.ReminderReference = reference
+		confidence["reminder_reference"] = 0.6
+	}
+	if invoice.DunningFee == 0 && fee > 0 {
+		invoice.DunningFee = fee
+		confidence["dunning_fee"] = 0.6
+	}
+}
@@ -12,14 +12,16 @@
 //
 // Document AI API Limitations:
 //   - Maximum file size: 20MB for synchronous processing
-//   - Supported formats: PDF, TIFF, GIF, JPEG, PNG, BMP, WEBP
+//   - Supported formats: PDF, TIFF, GIF, JPEG, PNG, BMP, WEBP, detected from the content
+//   - Maximum pages: 15 for PDF and multipage TIFF without async processing
 //   - Processing time: Typically 5-15 seconds per invoice
 //   - Quota limits apply (check Google Cloud Console)
 //
 // Invoice Processing Features:
 //   - Extracts key invoice fields (amounts, dates, parties, etc.)
 //   - Handles multiple currencies
-//   - Provides confidence scores for extracted data
+//   - Provides a confidence score for each populated field, keyed by invoice field name
+//     (e.g. "gross_amount") rather than Document AI entity type, like the completion's scores
 //   - Converts monetary values to cents for precision
 //   - Supports both payable and receivable invoices
 package invoice
@@ -32,7 +34,8 @@ import (
 	"tools/pkg/models"
 )
 
-// InvoiceProcessor defines the interface for invoice processing services.
+// InvoiceProcessor defines the interface for invoice processing services. Its confidence maps use the
+// invoice field names of the completion, so both can be merged into one map per invoice.
 type InvoiceProcessor interface {
 	// ProcessInvoice extracts structured data from an invoice PDF.
 	// Returns a populated Invoice model with extracted information.
@@ -40,8 +43,17 @@ type InvoiceProcessor interface {
 
 	// ProcessInvoiceWithConfidence extracts structured data with confidence scores.
 	// Returns the Invoice model and a map of field names to confidence values (0.0-1.0).
-	// Field names correspond to Document AI entity types (e.g., "invoice_id", "supplier_name").
+	// The map holds an entry for each field that was populated, keyed like the completion fields
+	// (e.g., "invoice_number", "vendor", "gross_amount"), and no others.
 	ProcessInvoiceWithConfidence(ctx context.Context, pdfData io.Reader) (*models.Invoice, map[string]float32, error)
+
+	// ProcessInvoicePages works like ProcessInvoiceWithConfidence but only processes the given
+	// 1-based pages, e.g. to skip attached terms and conditions. A nil or empty slice processes all pages.
+	ProcessInvoicePages(ctx context.Context, pdfData io.Reader, pages []int32) (*models.Invoice, map[string]float32, error)
+
+	// ProcessMultiInvoice splits a PDF containing several invoices at detected document boundaries
+	// and extracts each invoice separately. A single invoice yields a one-element slice.
+	ProcessMultiInvoice(ctx context.Context, pdfData io.Reader) ([]*models.Invoice, error)
 }
 
 // DocumentAIConfig holds configuration for Google Document AI processing.
@@ -64,6 +76,29 @@ type DocumentAIConfig struct {
 	// ProcessorVersion specifies a particular processor version.
 	// If empty, uses the default version.
 	ProcessorVersion string
+
+	// AsyncInputURI and AsyncOutputURI are the gs://bucket/folder locations batch (async)
+	// processing uploads documents to and writes its results to. Async processing is
+	// unavailable if either is empty.
+	AsyncInputURI  string
+	AsyncOutputURI string
+
+	// Async sends every document through batch processing. Otherwise only documents above
+	// MaxPagesSync pages or AsyncThresholdBytes, and synchronous requests that time out, are.
+	Async bool
+
+	// Sync never uses batch processing, not even for large documents or after a synchronous
+	// request timed out. Takes precedence over Async.
+	Sync bool
+
+	// AsyncTimeout is the maximum time to wait for one batch operation, including upload and
+	// download. Default: 10 minutes.
+	AsyncTimeout time.Duration
+
+	// MinFieldConfidence drops entities Document AI is less confident about than this (0.0-1.0)
+	// instead of storing them, so that completion or the fallbacks fill the field if they can.
+	// Default: 0, every entity is kept.
+	MinFieldConfidence float32
 }
 
 // DefaultConfig returns a DocumentAIConfig with sensible defaults.
@@ -80,7 +115,7 @@ type InvoiceProcessingResult struct {
 	Invoice *models.Invoice
 
 	// Confidence contains confidence scores for each extracted field.
-	// Keys are Document AI entity types, values are confidence scores (0.0-1.0).
+	// Keys are invoice field names such as "invoice_number", values are confidence scores (0.0-1.0).
 	Confidence map[string]float32
 
 	// ProcessingTime is how long the Document AI processing took.
@@ -0,0 +1,25 @@
+package invoice
+
+import (
+	"regexp"
+
+	"tools/pkg/models"
+)
+
+// smallBusinessPattern finds the note of a Kleinunternehmer, e.g. "Gemäß § 19 UStG wird keine
+// Umsatzsteuer berechnet" or "Kleinunternehmer im Sinne von §19 Abs. 1 UStG"
+var smallBusinessPattern = regexp.MustCompile(`(?i)kleinunternehmer|§\s*19\s*(?:abs\.?\s*\d\s*)?ustg`)
+
+// markSmallBusiness flags the invoice of a Kleinunternehmer (§19 UStG): its text cites the exemption
+// and it charges no VAT. A gross-only invoice gets the gross amount as net amount, as there is no VAT
+// to split off. It reports whether the invoice was flagged.
+func markSmallBusiness(invoice *models.Invoice, text string) bool {
+	if invoice.VATAmount != 0 || !smallBusinessPattern.MatchString(text) {
+		return false
+	}
+	invoice.SmallBusiness = true
+	if invoice.NetAmount == 0 {
+		invoice.NetAmount = invoice.GrossAmount
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package invoice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Accounting summary styles (CompletionConfig.SummaryStyle, SUMMARY_STYLE)
+const (
+	SummaryStyleTerse    = "terse"    // A few words naming the goods or services
+	SummaryStyleDetailed = "detailed" // Two to three sentences including purpose and reasoning
+)
+
+// summaryExamples are example summaries for the system prompt, each with its Kontierungsvorschlag
+var summaryExamples = []struct {
+	terse, standard, account string
+}{
+	{"5 Laptops, 10 Monitore", "IT-Equipment bestehend aus 5 Laptops und 10 Monitoren für Arbeitsplatzausstattung", "IT-Hardware/Anlagegüter"},
+	{"Papier, Toner, Schreibwaren", "Büromaterialbestellung mit Druckerpapier, Toner und Schreibwaren", "Bürobedarf"},
+	{"Cloud-Hosting Produktion", "Monatliche Cloud-Hosting Gebühren für Produktionsserver", "IT-Infrastruktur/laufende Kosten"},
+	{"Beratung SAP-Migration", "Beratungsleistungen für SAP-Migration", "Externe Dienstleistungen/Projekte"},
+}
+
+// summaryInstructions returns the ACCOUNTING SUMMARY block of the system prompt for the configured
+// style, or an empty string if no summary is requested
+func summaryInstructions(config CompletionConfig) string {
+	if config.NoSummary {
+		return ""
+	}
+
+	var block strings.Builder
+	switch config.SummaryStyle {
+	case SummaryStyleTerse:
+		block.WriteString("ACCOUNTING SUMMARY: Create a terse German summary of a few words naming ONLY the goods/services being billed:\n")
+	case SummaryStyleDetailed:
+		block.WriteString("ACCOUNTING SUMMARY: Create a detailed German prose summary of two to three sentences describing what goods/services are being billed and what they are used for:\n")
+	default:
+		block.WriteString("ACCOUNTING SUMMARY: Create a German prose summary describing ONLY what goods/services are being billed:\n")
+	}
+	block.WriteString("- Focus on WHAT was purchased or what service was provided\n")
+	block.WriteString("- Do NOT mention amounts, dates, or invoice details\n")
+	if !config.NoSummaryAccount {
+		block.WriteString("- Include a suggested German accounting category (Kontierungsvorschlag)\n")
+		if config.SummaryStyle == SummaryStyleDetailed {
+			block.WriteString("- Explain briefly why the category fits\n")
+		}
+	} else {
+		block.WriteString("- Do NOT suggest an accounting category (no Kontierungsvorschlag)\n")
+	}
+
+	block.WriteString("- Examples:\n")
+	for _, example := range summaryExamples {
+		summary := example.standard
+		if config.SummaryStyle == SummaryStyleTerse {
+			summary = example.terse
+		}
+		if !config.NoSummaryAccount {
+			summary += ", Kontierung: " + example.account
+		}
+		fmt.Fprintf(&block, "  * %q\n", summary)
+	}
+	block.WriteString("\n")
+
+	return block.String()
+}
+
+// summaryField returns the accounting_summary line of the JSON fields requested in the user prompt,
+// or an empty string if no summary is requested
+func summaryField(config CompletionConfig) string {
+	if config.NoSummary {
+		return ""
+	}
+
+	description := "German description of goods/services"
+	if config.SummaryLanguage == "en" {
+		description = "English description of goods/services"
+	}
+	switch config.SummaryStyle {
+	case SummaryStyleTerse:
+		description += " in a few words"
+	case SummaryStyleDetailed:
+		description += " in two to three sentences"
+	}
+	if !config.NoSummaryAccount {
+		if config.SummaryLanguage == "en" {
+			description += " and suggested account assignment (Kontierungsvorschlag)"
+		} else {
+			description += " and Kontierungsvorschlag"
+		}
+	}
+
+	return fmt.Sprintf(`  "accounting_summary": %q,`, description)
+}
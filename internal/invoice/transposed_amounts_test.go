@@ -0,0 +1,97 @@
+package invoice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"tools/pkg/models"
+)
+
+func TestCorrectTransposedAmounts(t *testing.T) {
+	tests := []struct {
+		name           string
+		net, vat       int64
+		gross          int64
+		confidence     map[string]float32
+		wantNet        int64
+		wantGross      int64
+		wantConfidence map[string]float32
+	}{
+		{
+			name: "transposed",
+			net:  11900, vat: 1900, gross: 10000,
+			confidence:     map[string]float32{"net_amount": 0.9, "gross_amount": 0.6, "vat_amount": 0.8},
+			wantNet:        10000,
+			wantGross:      11900,
+			wantConfidence: map[string]float32{"net_amount": 0.6, "gross_amount": 0.9, "vat_amount": 0.8},
+		},
+		{
+			name: "transposed within rounding",
+			net:  11902, vat: 1900, gross: 10000,
+			confidence:     map[string]float32{},
+			wantNet:        10000,
+			wantGross:      11902,
+			wantConfidence: map[string]float32{},
+		},
+		{
+			name: "only one confidence is kept in place",
+			net:  10700, vat: 700, gross: 10000,
+			confidence:     map[string]float32{"net_amount": 0.9},
+			wantNet:        10000,
+			wantGross:      10700,
+			wantConfidence: map[string]float32{"net_amount": 0.9},
+		},
+		{
+			name: "swap does not reconcile with VAT",
+			net:  15000, vat: 1900, gross: 10000,
+			confidence:     map[string]float32{"net_amount": 0.9, "gross_amount": 0.6},
+			wantNet:        15000,
+			wantGross:      10000,
+			wantConfidence: map[string]float32{"net_amount": 0.9, "gross_amount": 0.6},
+		},
+		{
+			name: "regular amounts",
+			net:  10000, vat: 1900, gross: 11900,
+			confidence:     map[string]float32{"net_amount": 0.9, "gross_amount": 0.6},
+			wantNet:        10000,
+			wantGross:      11900,
+			wantConfidence: map[string]float32{"net_amount": 0.9, "gross_amount": 0.6},
+		},
+		{
+			name: "tax-free",
+			net:  10000, gross: 10000,
+			wantNet:   10000,
+			wantGross: 10000,
+		},
+		{
+			name: "credit note",
+			net:  -10000, vat: -1900, gross: -11900,
+			wantNet:   -10000,
+			wantGross: -11900,
+		},
+		{
+			name: "gross missing",
+			net:  10000, vat: 1900,
+			wantNet: 10000,
+		},
+	}
+
+	p := &DocumentAIInvoiceProcessor{log: zerolog.Nop()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &models.Invoice{NetAmount: tt.net, VATAmount: tt.vat, GrossAmount: tt.gross}
+
+			p.correctTransposedAmounts(invoice, tt.confidence)
+
+			if invoice.NetAmount != tt.wantNet || invoice.GrossAmount != tt.wantGross || invoice.VATAmount != tt.vat {
+				t.Errorf("net, VAT, gross = %d, %d, %d, want %d, %d, %d",
+					invoice.NetAmount, invoice.VATAmount, invoice.GrossAmount, tt.wantNet, tt.vat, tt.wantGross)
+			}
+			if !reflect.DeepEqual(tt.confidence, tt.wantConfidence) {
+				t.Errorf("confidence = %v, want %v", tt.confidence, tt.wantConfidence)
+			}
+		})
+	}
+}
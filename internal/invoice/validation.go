@@ -194,6 +194,15 @@ func (av *AmountValidation) crossValidateAmounts(result *AmountValidationResult)
 		return // Not enough data for cross-validation
 	}
 
+	// Net can never exceed gross; transposed values that reconcile were already swapped during extraction
+	if invoice.NetAmount > 0 && invoice.GrossAmount > 0 && invoice.NetAmount > invoice.GrossAmount {
+		warning := fmt.Sprintf("Net amount (%.2f) exceeds gross amount (%.2f)",
+			float64(invoice.NetAmount)/100,
+			float64(invoice.GrossAmount)/100)
+		result.Warnings = append(result.Warnings, warning)
+		result.HasDiscrepancy = true
+	}
+
 	// Check if Net + VAT ≈ Gross (within 2 cents tolerance)
 	if invoice.NetAmount > 0 && invoice.VATAmount > 0 && invoice.GrossAmount > 0 {
 		calculated := invoice.NetAmount + invoice.VATAmount
@@ -250,6 +259,15 @@ func (av *AmountValidation) calculateMissingAmounts(invoice *models.Invoice, res
 	}
 }
 
+// isTransposedNetGross reports whether net and gross were swapped, i.e. gross + VAT ≈ net
+// within the same 2 cent tolerance used by cross-validation
+func isTransposedNetGross(net, vat, gross int64) bool {
+	if net <= gross || gross <= 0 {
+		return false
+	}
+	return abs(gross+vat-net) <= 2
+}
+
 // Helper functions
 func maxInt64(a, b int64) int64 {
 	if a > b {
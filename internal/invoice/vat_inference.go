@@ -0,0 +1,110 @@
+package invoice
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+
+	"tools/pkg/models"
+)
+
+// DefaultAssumedVATRate is the VAT rate in percent used to split gross-only invoices when the OCR text names none
+const DefaultAssumedVATRate = 19.0
+
+// vatRatePattern finds a percentage next to a VAT label, e.g. "inkl. 19% MwSt", "USt 7 %" or "MwSt. 19,00%"
+var vatRatePattern = regexp.MustCompile(`(?i)\b(?:mwst|ust|umsatzsteuer|mehrwertsteuer|vat)[^0-9%\n]{0,20}(\d{1,2})(?:[.,]0{1,2})?\s*%|(\d{1,2})(?:[.,]0{1,2})?\s*%[^0-9\n]{0,20}\b(?:mwst|ust|umsatzsteuer|mehrwertsteuer|vat)`)
+
+// isGrossOnly reports whether the invoice has a gross amount but neither net nor VAT
+func isGrossOnly(invoice *models.Invoice) bool {
+	return invoice.GrossAmount != 0 && invoice.NetAmount == 0 && invoice.VATAmount == 0
+}
+
+// detectVATRates returns the distinct German VAT rates (7 or 19 percent) named in the text
+func detectVATRates(text string) []float64 {
+	var rates []float64
+	seen := make(map[float64]bool)
+
+	for _, match := range vatRatePattern.FindAllStringSubmatch(text, -1) {
+		value := match[1]
+		if value == "" {
+			value = match[2]
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || (rate != 7 && rate != 19) || seen[rate] {
+			continue
+		}
+		seen[rate] = true
+		rates = append(rates, rate)
+	}
+
+	return rates
+}
+
+// splitGross back-calculates net and VAT in cents from a gross amount at ratePercent. VAT takes the
+// rounding remainder so net + VAT always equals gross.
+func splitGross(gross int64, ratePercent float64) (net, vat int64) {
+	net = int64(math.Round(float64(gross) * 100 / (100 + ratePercent)))
+	return net, gross - net
+}
+
+// defaultVATRate returns the rate a gross-only invoice is split with if its OCR text names none: the
+// vendor master rate of its counterparty, or the assumed rate with InferVAT. ok is false if the
+// invoice must not be split.
+func (s *DefaultInvoiceCompletionService) defaultVATRate(invoice *models.Invoice) (rate float64, source string, ok bool) {
+	if s.config.VendorVATRate != nil {
+		if rate, ok := s.config.VendorVATRate(invoice); ok {
+			return rate, models.VATRateFromVendor, true
+		}
+	}
+	if s.config.InferVAT {
+		return s.config.AssumedVATRate, models.VATRateAssumed, true
+	}
+	return 0, "", false
+}
+
+// inferVATFromGross fills net and VAT of a gross-only invoice if InferVAT is enabled or its vendor has a
+// default rate. The rate is taken from the OCR text if it names exactly one rate, otherwise the vendor's
+// default or the assumed rate is used. Receipts naming both 7% and 19% cannot be split from the gross
+// total and are left unchanged. The inferred amounts get a low confidence and VATInferredFrom is set so
+// reviewers can tell them apart from extracted ones.
+func (s *DefaultInvoiceCompletionService) inferVATFromGross(invoice *models.Invoice, ocrText string, confidence map[string]float32) {
+	if !isGrossOnly(invoice) {
+		return
+	}
+
+	rate, source, ok := s.defaultVATRate(invoice)
+	if !ok {
+		return
+	}
+	amountConfidence := float32(0.3)
+	if source == models.VATRateFromVendor {
+		amountConfidence = 0.5
+	}
+
+	switch rates := detectVATRates(ocrText); len(rates) {
+	case 0:
+	case 1:
+		rate = rates[0]
+		source = models.VATRateFromText
+		amountConfidence = 0.6
+	default:
+		s.log.Warn().
+			Floats64("rates", rates).
+			Int64("gross_amount", invoice.GrossAmount).
+			Msg("Gross-only invoice names several VAT rates, not inferring net and VAT")
+		return
+	}
+
+	invoice.NetAmount, invoice.VATAmount = splitGross(invoice.GrossAmount, rate)
+	invoice.VATInferredFrom = source
+	confidence["net_amount"] = amountConfidence
+	confidence["vat_amount"] = amountConfidence
+
+	s.log.Warn().
+		Float64("vat_rate", rate).
+		Str("rate_source", source).
+		Int64("gross_amount", invoice.GrossAmount).
+		Int64("inferred_net_amount", invoice.NetAmount).
+		Int64("inferred_vat_amount", invoice.VATAmount).
+		Msg("Inferred net and VAT from gross amount - not extracted from the invoice, please verify")
+}
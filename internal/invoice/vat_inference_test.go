@@ -0,0 +1,150 @@
+package invoice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"tools/pkg/models"
+)
+
+func TestDetectVATRates(t *testing.T) {
+	tests := []struct {
+		text string
+		want []float64
+	}{
+		{"Gesamt 119,00 EUR inkl. 19% MwSt", []float64{19}},
+		{"USt 7 %", []float64{7}},
+		{"MwSt. 19,00%", []float64{19}},
+		{"19 % Umsatzsteuer", []float64{19}},
+		{"VAT 19.0%", []float64{19}},
+		{"7% MwSt auf Speisen\n19% MwSt auf Getränke", []float64{7, 19}},
+		{"MwSt 19%\nUSt 19 %", []float64{19}},
+		{"MwSt 20%", nil},
+		{"Rabatt 19% auf alles", nil},
+		{"Skonto 2% bei Zahlung innerhalb von 7 Tagen", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := detectVATRates(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("detectVATRates(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSplitGross(t *testing.T) {
+	tests := []struct {
+		gross   int64
+		rate    float64
+		wantNet int64
+		wantVAT int64
+	}{
+		{11900, 19, 10000, 1900},
+		{10700, 7, 10000, 700},
+		{999, 19, 839, 160},
+		{1, 19, 1, 0},
+		{-11900, 19, -10000, -1900},
+		{5000, 0, 5000, 0},
+	}
+	for _, tt := range tests {
+		net, vat := splitGross(tt.gross, tt.rate)
+		if net != tt.wantNet || vat != tt.wantVAT {
+			t.Errorf("splitGross(%d, %v) = %d, %d, want %d, %d", tt.gross, tt.rate, net, vat, tt.wantNet, tt.wantVAT)
+		}
+		if net+vat != tt.gross {
+			t.Errorf("splitGross(%d, %v): net + VAT = %d, want gross", tt.gross, tt.rate, net+vat)
+		}
+	}
+}
+
+func TestInferVATFromGross(t *testing.T) {
+	vendorRate := func(invoice *models.Invoice) (float64, bool) {
+		return 7, invoice.Vendor == "Bäckerei Müller"
+	}
+
+	tests := []struct {
+		name           string
+		config         CompletionConfig
+		invoice        models.Invoice
+		text           string
+		wantNet        int64
+		wantVAT        int64
+		wantSource     string
+		wantConfidence float32
+	}{
+		{
+			name:    "inference disabled",
+			invoice: models.Invoice{GrossAmount: 11900},
+			text:    "inkl. 19% MwSt",
+		},
+		{
+			name:    "net already extracted",
+			config:  CompletionConfig{InferVAT: true, AssumedVATRate: 19},
+			invoice: models.Invoice{GrossAmount: 11900, NetAmount: 10000},
+		},
+		{
+			name:           "assumed rate",
+			config:         CompletionConfig{InferVAT: true, AssumedVATRate: 19},
+			invoice:        models.Invoice{GrossAmount: 11900},
+			wantNet:        10000,
+			wantVAT:        1900,
+			wantSource:     models.VATRateAssumed,
+			wantConfidence: 0.3,
+		},
+		{
+			name:           "rate named in the text",
+			config:         CompletionConfig{InferVAT: true, AssumedVATRate: 19},
+			invoice:        models.Invoice{GrossAmount: 10700},
+			text:           "Summe 107,00 EUR inkl. 7% MwSt",
+			wantNet:        10000,
+			wantVAT:        700,
+			wantSource:     models.VATRateFromText,
+			wantConfidence: 0.6,
+		},
+		{
+			name:           "vendor rate without InferVAT",
+			config:         CompletionConfig{VendorVATRate: vendorRate},
+			invoice:        models.Invoice{Vendor: "Bäckerei Müller", GrossAmount: 10700},
+			wantNet:        10000,
+			wantVAT:        700,
+			wantSource:     models.VATRateFromVendor,
+			wantConfidence: 0.5,
+		},
+		{
+			name:    "vendor without rate",
+			config:  CompletionConfig{VendorVATRate: vendorRate},
+			invoice: models.Invoice{Vendor: "Muster GmbH", GrossAmount: 11900},
+		},
+		{
+			name:    "several rates named",
+			config:  CompletionConfig{InferVAT: true, AssumedVATRate: 19},
+			invoice: models.Invoice{GrossAmount: 12600},
+			text:    "7% MwSt 3,50\n19% MwSt 9,50",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &DefaultInvoiceCompletionService{config: tt.config, log: zerolog.Nop()}
+			invoice := tt.invoice
+			confidence := map[string]float32{}
+
+			s.inferVATFromGross(&invoice, tt.text, confidence)
+
+			wantNet, wantVAT := tt.wantNet, tt.wantVAT
+			if tt.wantSource == "" {
+				wantNet, wantVAT = tt.invoice.NetAmount, tt.invoice.VATAmount
+			}
+			if invoice.NetAmount != wantNet || invoice.VATAmount != wantVAT {
+				t.Errorf("net, VAT = %d, %d, want %d, %d", invoice.NetAmount, invoice.VATAmount, wantNet, wantVAT)
+			}
+			if invoice.VATInferredFrom != tt.wantSource {
+				t.Errorf("VATInferredFrom = %q, want %q", invoice.VATInferredFrom, tt.wantSource)
+			}
+			if confidence["net_amount"] != tt.wantConfidence || confidence["vat_amount"] != tt.wantConfidence {
+				t.Errorf("confidence = %v, want %v for net and VAT", confidence, tt.wantConfidence)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package ledger
+
+import (
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// basisCents returns the invoice amount in the amount basis of the booking: the net amount of net
+// bookings, the gross amount otherwise
+func basisCents(inv *models.Invoice, booking *services.DATEVBooking) int64 {
+	if booking.AmountBasis == services.AmountBasisNet {
+		return inv.NetAmount
+	}
+	return inv.GrossAmount
+}
+
+// basisSplitAmount returns the amount of a split in the amount basis of the booking
+func basisSplitAmount(booking *services.DATEVBooking, split services.BookingSplit) float64 {
+	if booking.AmountBasis == services.AmountBasisNet {
+		return split.NetAmount
+	}
+	return split.Amount
+}
@@ -0,0 +1,160 @@
+// Package ledger exports processed invoices as a flat CSV ledger for import into other accounting tools,
+// as a DATEV EXTF Buchungsstapel (WriteEXTF) or as a DATEV XML ledger import (WriteXML).
+// JSONLWriter additionally streams batch results as newline-delimited JSON while a batch is running.
+// The writers produce UTF-8; NewEncodingWriter transcodes their output to Windows-1252 for DATEV.
+//
+// Unlike the German-formatted Google Sheets output, the ledger uses ISO 8601 dates (YYYY-MM-DD) and a dot
+// as decimal separator without thousands separators. The column set is stable; new columns are only
+// ever appended at the end:
+//
+//	date            invoice issue date (YYYY-MM-DD)
+//	invoice_number  invoice number as printed on the document
+//	counterparty    vendor for payables, customer for receivables
+//	net             net amount
+//	vat             VAT amount
+//	gross           gross amount
+//	currency        ISO 4217 currency code
+//	type            PAYABLE or RECEIVABLE
+//	debit_account   DATEV debit account (Sollkonto)
+//	credit_account  DATEV credit account (Habenkonto)
+//	tax_key         DATEV tax key (Steuerschlüssel)
+//	cost_center     cost center (Kostenstelle), may be empty
+//	remarks         notes for the bookkeeper, e.g. a disagreeing datev-batch --sample check; may be empty
+package ledger
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// Columns is the header row of the ledger in column order
+var Columns = []string{
+	"date",
+	"invoice_number",
+	"counterparty",
+	"net",
+	"vat",
+	"gross",
+	"currency",
+	"type",
+	"debit_account",
+	"credit_account",
+	"tax_key",
+	"cost_center",
+	"remarks",
+}
+
+// Entry is one successfully processed invoice together with its booking
+type Entry struct {
+	Invoice *models.Invoice
+	Booking *services.DATEVBooking
+	Remarks []string // Written to the remarks column, separated by "; "
+}
+
+// WriteFile creates or truncates path and writes the ledger to it in the encoding
+func WriteFile(path string, entries []Entry, encoding Encoding) error {
+	const op = "WriteFile"
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("%s: failed to create ledger file: %w", op, err)
+	}
+
+	encoded := NewEncodingWriter(file, encoding)
+	if err := Write(encoded, entries); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := encoded.Close(); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: failed to encode ledger file: %w", op, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("%s: failed to close ledger file: %w", op, err)
+	}
+
+	return nil
+}
+
+// Write writes the header and one row per entry; entries without an invoice are skipped
+func Write(w io.Writer, entries []Entry) error {
+	const op = "Write"
+
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(Columns); err != nil {
+		return fmt.Errorf("%s: failed to write header: %w", op, err)
+	}
+
+	for _, entry := range entries {
+		if entry.Invoice == nil {
+			continue
+		}
+		if err := writer.Write(entryToRecord(entry)); err != nil {
+			return fmt.Errorf("%s: failed to write row: %w", op, err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("%s: failed to flush ledger: %w", op, err)
+	}
+
+	return nil
+}
+
+// entryToRecord converts an entry to a CSV record in Columns order
+func entryToRecord(entry Entry) []string {
+	inv := entry.Invoice
+
+	var date string
+	if !inv.IssueDate.IsZero() {
+		date = inv.IssueDate.Format("2006-01-02")
+	}
+
+	counterparty := inv.Customer
+	if inv.Type == "PAYABLE" {
+		counterparty = inv.Vendor
+	}
+
+	var debitAccount, creditAccount, taxKey, costCenter string
+	if entry.Booking != nil {
+		debitAccount = entry.Booking.DebitAccount
+		creditAccount = entry.Booking.CreditAccount
+		taxKey = entry.Booking.TaxKey
+		costCenter = entry.Booking.CostCenter
+	}
+
+	return []string{
+		date,
+		inv.InvoiceNumber,
+		counterparty,
+		formatCents(inv.NetAmount),
+		formatCents(inv.VATAmount),
+		formatCents(inv.GrossAmount),
+		inv.Currency,
+		inv.Type,
+		debitAccount,
+		creditAccount,
+		taxKey,
+		costCenter,
+		strings.Join(entry.Remarks, "; "),
+	}
+}
+
+// formatCents renders an amount in cents as a dot-decimal string without float rounding, e.g. -1234 -> "-12.34"
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
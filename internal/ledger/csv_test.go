@@ -0,0 +1,59 @@
+package ledger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+func TestWrite(t *testing.T) {
+	entries := []Entry{
+		{
+			Invoice: &models.Invoice{
+				InvoiceNumber: "RE-1001",
+				Type:          "PAYABLE",
+				Vendor:        "Muster, Schmidt & Co. GmbH",
+				Customer:      "Wir GmbH",
+				IssueDate:     time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+				NetAmount:     123456,
+				VATAmount:     23457,
+				GrossAmount:   146913,
+				Currency:      "EUR",
+			},
+			Booking: &services.DATEVBooking{
+				DebitAccount:  "4930",
+				CreditAccount: "1600",
+				TaxKey:        "9",
+				CostCenter:    "100",
+			},
+			Remarks: []string{"Stichprobe (gpt-4o) weicht ab: Sollkonto 4930 ≠ 4980", "Bitte prüfen"},
+		},
+		{
+			Invoice: &models.Invoice{
+				InvoiceNumber: "GS-7",
+				Type:          "RECEIVABLE",
+				Customer:      "Kunde AG",
+				NetAmount:     -5000,
+				VATAmount:     -950,
+				GrossAmount:   -5950,
+				Currency:      "EUR",
+			},
+		},
+		{Invoice: nil},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, entries); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	want := "date,invoice_number,counterparty,net,vat,gross,currency,type,debit_account,credit_account,tax_key,cost_center,remarks\n" +
+		"2024-03-05,RE-1001,\"Muster, Schmidt & Co. GmbH\",1234.56,234.57,1469.13,EUR,PAYABLE,4930,1600,9,100,Stichprobe (gpt-4o) weicht ab: Sollkonto 4930 ≠ 4980; Bitte prüfen\n" +
+		",GS-7,Kunde AG,-50.00,-9.50,-59.50,EUR,RECEIVABLE,,,,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected ledger:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
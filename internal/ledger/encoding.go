@@ -0,0 +1,67 @@
+package ledger
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Encoding is the character encoding of an exported file
+type Encoding string
+
+const (
+	// EncodingUTF8 writes the text unchanged
+	EncodingUTF8 Encoding = "utf8"
+	// EncodingCP1252 writes Windows-1252, which the DATEV import and many German accounting tools expect
+	EncodingCP1252 Encoding = "cp1252"
+)
+
+// ParseEncoding parses an encoding name: utf8 or cp1252, also written as utf-8, windows-1252 or latin1
+func ParseEncoding(name string) (Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "utf8", "utf-8":
+		return EncodingUTF8, nil
+	case "cp1252", "windows-1252", "win1252", "latin1", "iso-8859-1":
+		return EncodingCP1252, nil
+	}
+	return "", fmt.Errorf("invalid encoding: %q (must be 'cp1252' or 'utf8')", name)
+}
+
+// NewEncodingWriter returns a writer that transcodes the UTF-8 text written to it into the encoding
+// before passing it on to w. Characters Windows-1252 cannot represent are written without their
+// accents if that makes them representable (ő -> o), and as '?' otherwise. Close flushes the
+// remaining text; it does not close w.
+func NewEncodingWriter(w io.Writer, encoding Encoding) io.WriteCloser {
+	if encoding != EncodingCP1252 {
+		return nopWriteCloser{w}
+	}
+	return transform.NewWriter(w, transform.Chain(runes.Map(cp1252Fallback), charmap.Windows1252.NewEncoder()))
+}
+
+// cp1252Fallback replaces a rune Windows-1252 cannot represent by its base letter or '?'
+func cp1252Fallback(r rune) rune {
+	if _, ok := charmap.Windows1252.EncodeRune(r); ok {
+		return r
+	}
+	if decomposed := []rune(norm.NFD.String(string(r))); len(decomposed) > 1 {
+		if _, ok := charmap.Windows1252.EncodeRune(decomposed[0]); ok {
+			return decomposed[0]
+		}
+	}
+	return '?'
+}
+
+// nopWriteCloser is a writer whose Close does nothing
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing
+func (nopWriteCloser) Close() error {
+	return nil
+}
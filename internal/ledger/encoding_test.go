@@ -0,0 +1,55 @@
+package ledger
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNewEncodingWriterCP1252(t *testing.T) {
+	var out bytes.Buffer
+	w := NewEncodingWriter(&out, EncodingCP1252)
+	// Write byte by byte to split the multi-byte runes across writes
+	text := "Müller Büromöbel GmbH;Straße;€ 5;Łódź Kraków;日本\r\n"
+	for i := 0; i < len(text); i++ {
+		if _, err := io.WriteString(w, text[i:i+1]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := "M\xfcller B\xfcrom\xf6bel GmbH;Stra\xdfe;\x80 5;?\xf3dz Krak\xf3w;??\r\n"
+	if out.String() != want {
+		t.Errorf("encoded = %q, want %q", out.String(), want)
+	}
+}
+
+func TestNewEncodingWriterUTF8(t *testing.T) {
+	var out bytes.Buffer
+	w := NewEncodingWriter(&out, EncodingUTF8)
+	io.WriteString(w, "Müller €")
+	w.Close()
+	if out.String() != "Müller €" {
+		t.Errorf("encoded = %q, want the text unchanged", out.String())
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	tests := map[string]Encoding{
+		"cp1252":       EncodingCP1252,
+		"Windows-1252": EncodingCP1252,
+		"utf8":         EncodingUTF8,
+		" UTF-8 ":      EncodingUTF8,
+	}
+	for name, want := range tests {
+		if got, err := ParseEncoding(name); err != nil || got != want {
+			t.Errorf("ParseEncoding(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseEncoding("utf16"); err == nil || !strings.Contains(err.Error(), "utf16") {
+		t.Errorf("ParseEncoding(utf16) error = %v, want an invalid encoding error", err)
+	}
+}
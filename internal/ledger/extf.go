@@ -0,0 +1,293 @@
+package ledger
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"tools/pkg/services"
+)
+
+// EXTFColumns is the column header row of the DATEV Buchungsstapel (format version 13) in column order
+var EXTFColumns = buildEXTFColumns()
+
+// Indexes of the EXTFColumns that are filled; all other columns are left empty
+const (
+	extfAmount        = 0   // Umsatz (ohne Soll/Haben-Kz)
+	extfDebitCredit   = 1   // Soll/Haben-Kennzeichen
+	extfCurrency      = 2   // WKZ Umsatz
+	extfAccount       = 6   // Konto
+	extfContraAccount = 7   // Gegenkonto (ohne BU-Schlüssel)
+	extfTaxKey        = 8   // BU-Schlüssel
+	extfDocumentDate  = 9   // Belegdatum
+	extfDocumentField = 10  // Belegfeld 1
+	extfBookingText   = 13  // Buchungstext
+	extfInfoType1     = 20  // Beleginfo - Art 1
+	extfInfoContent1  = 21  // Beleginfo - Inhalt 1
+	extfInfoType2     = 22  // Beleginfo - Art 2
+	extfInfoContent2  = 23  // Beleginfo - Inhalt 2
+	extfCostCenter    = 36  // KOST1 - Kostenstelle
+	extfServiceDate   = 114 // Leistungsdatum
+	extfDueDate       = 116 // Fälligkeit
+)
+
+// EXTFHeader holds the values of the EXTF header line that are not derived from the bookings
+type EXTFHeader struct {
+	ConsultantNumber string    // Beraternummer
+	ClientNumber     string    // Mandantennummer
+	Description      string    // Bezeichnung of the Buchungsstapel, max. 30 characters
+	CreatedAt        time.Time // Erzeugt am; the current time if zero
+}
+
+// WriteEXTF writes a DATEV EXTF Buchungsstapel: the header line, the column header row and one row per
+// booking, per split of a mixed-rate booking, or per net and VAT line of a booking with explicit VAT
+// postings. Entries without an invoice or booking are skipped.
+// DATEV only imports a Buchungsstapel within one fiscal year, assumed to be the calendar year, so
+// entries dated in different years are rejected.
+func WriteEXTF(w io.Writer, header EXTFHeader, entries []Entry) error {
+	const op = "WriteEXTF"
+
+	var rows [][]string
+	var from, to time.Time
+	chart := ""
+	for _, entry := range entries {
+		if entry.Invoice == nil || entry.Booking == nil {
+			continue
+		}
+
+		date := extfDocumentDateOf(entry)
+		if date.IsZero() {
+			return fmt.Errorf("%s: invoice %s has no date", op, entry.Invoice.InvoiceNumber)
+		}
+		if from.IsZero() || date.Before(from) {
+			from = date
+		}
+		if to.IsZero() || date.After(to) {
+			to = date
+		}
+		if chart == "" {
+			chart = strings.TrimPrefix(entry.Booking.ContenrahmenType, "SKR")
+		}
+
+		rows = append(rows, entryToEXTFRows(entry, date)...)
+	}
+
+	if !from.IsZero() && from.Year() != to.Year() {
+		return fmt.Errorf("%s: bookings span the fiscal years %d to %d, export each year separately", op, from.Year(), to.Year())
+	}
+
+	lines := []string{
+		extfHeaderLine(header, from, to, chart),
+		strings.Join(EXTFColumns, ";"),
+	}
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, ";"))
+	}
+
+	if _, err := io.WriteString(w, strings.Join(lines, "\r\n")+"\r\n"); err != nil {
+		return fmt.Errorf("%s: failed to write Buchungsstapel: %w", op, err)
+	}
+
+	return nil
+}
+
+// extfHeaderLine builds the first line of the file, which identifies the format and the client
+func extfHeaderLine(header EXTFHeader, from, to time.Time, chart string) string {
+	createdAt := header.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	if chart == "" {
+		chart = "03"
+	}
+
+	var fiscalYearStart, dateFrom, dateTo string
+	if !from.IsZero() {
+		fiscalYearStart = fmt.Sprintf("%d0101", from.Year())
+		dateFrom = from.Format("20060102")
+		dateTo = to.Format("20060102")
+	}
+
+	fields := []string{
+		extfText("EXTF"),
+		"700", // Versionsnummer
+		"21",  // Formatkategorie Buchungsstapel
+		extfText("Buchungsstapel"),
+		"13", // Formatversion
+		createdAt.Format("20060102150405") + fmt.Sprintf("%03d", createdAt.Nanosecond()/int(time.Millisecond)),
+		"",             // Importiert
+		extfText("RE"), // Herkunft
+		extfText(""),   // Exportiert von
+		extfText(""),   // Importiert von
+		header.ConsultantNumber,
+		header.ClientNumber,
+		fiscalYearStart,
+		"4", // Sachkontenlänge
+		dateFrom,
+		dateTo,
+		extfText(truncateRunes(header.Description, 30)),
+		extfText(""), // Diktatkürzel
+		"1",          // Buchungstyp Finanzbuchführung
+		"0",          // Rechnungslegungszweck
+		"0",          // Festschreibung
+		extfText("EUR"),
+		"",              // reserviert
+		extfText(""),    // Derivatskennzeichen
+		"",              // reserviert
+		"",              // reserviert
+		extfText(chart), // Sachkontenrahmen
+		"",              // Branchen-Lösungs-ID
+		"",              // reserviert
+		extfText(""),    // reserviert
+		extfText(""),    // Anwendungsinformation
+	}
+	return strings.Join(fields, ";")
+}
+
+// entryToEXTFRows converts an entry to one row per booking line in EXTFColumns order
+func entryToEXTFRows(entry Entry, date time.Time) [][]string {
+	inv := entry.Invoice
+	booking := entry.Booking
+
+	// A negative amount is booked to the Haben side of the account
+	type line struct {
+		amount        float64
+		taxKey        string
+		account       string
+		contraAccount string
+	}
+	lines := []line{{booking.Amount, booking.TaxKey, booking.DebitAccount, booking.CreditAccount}}
+	if booking.Amount == 0 {
+		lines[0].amount = float64(basisCents(inv, booking)) / 100
+	}
+	if len(booking.Splits) > 0 {
+		lines = lines[:0]
+		for _, split := range booking.Splits {
+			lines = append(lines, line{basisSplitAmount(booking, split), split.TaxKey, booking.DebitAccount, booking.CreditAccount})
+		}
+	}
+	// Explicit VAT postings are rows against the creditor or debitor without tax key, so that DATEV
+	// does not post the VAT a second time
+	if contra, postings, ok := splitPostings(booking.Postings, inv); ok {
+		lines = lines[:0]
+		for _, posting := range postings {
+			amount := posting.Amount
+			if posting.Side == services.PostingCredit {
+				amount = -amount
+			}
+			lines = append(lines, line{amount, "", posting.Account, contra.Account})
+		}
+	}
+
+	var rows [][]string
+	for _, l := range lines {
+		row := make([]string, len(EXTFColumns))
+
+		debitCredit := "S"
+		if l.amount < 0 {
+			debitCredit = "H"
+		}
+		row[extfAmount] = formatEXTFAmount(math.Abs(l.amount))
+		row[extfDebitCredit] = extfText(debitCredit)
+		row[extfCurrency] = extfText("EUR")
+		row[extfAccount] = l.account
+		row[extfContraAccount] = l.contraAccount
+		row[extfTaxKey] = extfText(l.taxKey)
+		row[extfDocumentDate] = date.Format("0201")
+		row[extfDocumentField] = extfText(extfDocumentNumber(inv.InvoiceNumber))
+		row[extfBookingText] = extfText(truncateRunes(booking.BookingText, 60))
+		row[extfCostCenter] = extfText(booking.CostCenter)
+		// The Beleglink column only takes GUIDs of documents stored in DATEV, so the source PDF is
+		// recorded as Beleginfo
+		if booking.SourceFile != "" {
+			row[extfInfoType1] = extfText("Quelldatei")
+			row[extfInfoContent1] = extfText(truncateRunes(booking.SourceFile, 210))
+		}
+		if booking.SourceSHA256 != "" {
+			row[extfInfoType2] = extfText("SHA-256")
+			row[extfInfoContent2] = extfText(booking.SourceSHA256)
+		}
+		if !inv.ServiceDate.IsZero() {
+			row[extfServiceDate] = inv.ServiceDate.Format("02012006")
+		}
+		if !inv.DueDate.IsZero() {
+			row[extfDueDate] = inv.DueDate.Format("02012006")
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// extfDocumentDateOf returns the Belegdatum: the booking date if set, else the invoice date
+func extfDocumentDateOf(entry Entry) time.Time {
+	if !entry.Booking.BookingDate.IsZero() {
+		return entry.Booking.BookingDate
+	}
+	return entry.Invoice.IssueDate
+}
+
+// formatEXTFAmount renders a positive amount with a decimal comma, e.g. 1234.5 -> "1234,50"
+func formatEXTFAmount(amount float64) string {
+	return strings.Replace(fmt.Sprintf("%.2f", amount), ".", ",", 1)
+}
+
+// extfDocumentNumber reduces an invoice number to the 36 characters DATEV accepts in Belegfeld 1
+func extfDocumentNumber(invoiceNumber string) string {
+	var number strings.Builder
+	for _, r := range invoiceNumber {
+		if r < 128 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("$&%*+-/.", r)) {
+			number.WriteRune(r)
+		}
+	}
+	return truncateRunes(number.String(), 36)
+}
+
+// extfText quotes a text field, doubling quotes inside it
+func extfText(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+// truncateRunes cuts s to at most n characters
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// buildEXTFColumns lists the 125 columns of the Buchungsstapel, expanding the numbered column groups
+func buildEXTFColumns() []string {
+	columns := []string{
+		"Umsatz (ohne Soll/Haben-Kz)", "Soll/Haben-Kennzeichen", "WKZ Umsatz", "Kurs", "Basis-Umsatz",
+		"WKZ Basis-Umsatz", "Konto", "Gegenkonto (ohne BU-Schlüssel)", "BU-Schlüssel", "Belegdatum",
+		"Belegfeld 1", "Belegfeld 2", "Skonto", "Buchungstext", "Postensperre", "Diverse Adressnummer",
+		"Geschäftspartnerbank", "Sachverhalt", "Zinssperre", "Beleglink",
+	}
+	for i := 1; i <= 8; i++ {
+		columns = append(columns, fmt.Sprintf("Beleginfo - Art %d", i), fmt.Sprintf("Beleginfo - Inhalt %d", i))
+	}
+	columns = append(columns,
+		"KOST1 - Kostenstelle", "KOST2 - Kostenstelle", "Kost-Menge", "EU-Land u. UStID (Bestimmung)",
+		"EU-Steuersatz (Bestimmung)", "Abw. Versteuerungsart", "Sachverhalt L+L", "Funktionsergänzung L+L",
+		"BU 49 Hauptfunktionstyp", "BU 49 Hauptfunktionsnummer", "BU 49 Funktionsergänzung",
+	)
+	for i := 1; i <= 20; i++ {
+		columns = append(columns, fmt.Sprintf("Zusatzinformation - Art %d", i), fmt.Sprintf("Zusatzinformation- Inhalt %d", i))
+	}
+	columns = append(columns,
+		"Stück", "Gewicht", "Zahlweise", "Forderungsart", "Veranlagungsjahr", "Zugeordnete Fälligkeit",
+		"Skontotyp", "Auftragsnummer", "Buchungstyp", "USt-Schlüssel (Anzahlungen)",
+		"EU-Mitgliedstaat (Anzahlungen)", "Sachverhalt L+L (Anzahlungen)", "EU-Steuersatz (Anzahlungen)",
+		"Erlöskonto (Anzahlungen)", "Herkunft-Kz", "Buchungs GUID", "KOST-Datum", "SEPA-Mandatsreferenz",
+		"Skontosperre", "Gesellschaftername", "Beteiligtennummer", "Identifikationsnummer", "Zeichnernummer",
+		"Postensperre bis", "Bezeichnung SoBil-Sachverhalt", "Kennzeichen SoBil-Buchung", "Festschreibung",
+		"Leistungsdatum", "Datum Zuord. Steuerperiode", "Fälligkeit", "Generalumkehr (GU)", "Steuersatz",
+		"Land", "Abrechnungsreferenz", "BVV-Position", "EU-Land u. UStID (Ursprung)",
+		"EU-Steuersatz (Ursprung)", "Abw. Skontokonto",
+	)
+	return columns
+}
@@ -0,0 +1,193 @@
+package ledger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+func TestWriteEXTF(t *testing.T) {
+	entries := []Entry{
+		{
+			Invoice: &models.Invoice{
+				InvoiceNumber: "RE 2024/001 (Kopie)",
+				Type:          "PAYABLE",
+				IssueDate:     time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+				ServiceDate:   time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC),
+				GrossAmount:   11900,
+			},
+			Booking: &services.DATEVBooking{
+				BookingText:      `Büromaterial "Muster"`,
+				DebitAccount:     "4930",
+				CreditAccount:    "1600",
+				TaxKey:           "9",
+				CostCenter:       "100",
+				ContenrahmenType: "SKR03",
+				SourceFile:       "rechnung.pdf",
+				SourceSHA256:     "9f86d081",
+			},
+		},
+		{
+			Invoice: &models.Invoice{
+				InvoiceNumber: "GS-7",
+				Type:          "RECEIVABLE",
+				IssueDate:     time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+				GrossAmount:   -5950,
+			},
+			Booking: &services.DATEVBooking{
+				DebitAccount:  "1400",
+				CreditAccount: "8400",
+				Amount:        -59.5,
+				TaxKey:        "3",
+			},
+		},
+		{Invoice: &models.Invoice{InvoiceNumber: "ohne Buchung"}},
+	}
+
+	var buf bytes.Buffer
+	header := EXTFHeader{ConsultantNumber: "1001", ClientNumber: "1", CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	if err := WriteEXTF(&buf, header, entries); err != nil {
+		t.Fatalf("WriteEXTF: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header, column row and 2 bookings, got %d lines:\n%s", len(lines), buf.String())
+	}
+
+	wantHeader := `"EXTF";700;21;"Buchungsstapel";13;20240501120000000;;"RE";"";"";1001;1;20240101;4;20240305;20240401;`
+	if !strings.HasPrefix(lines[0], wantHeader) {
+		t.Errorf("header = %s\nwant prefix %s", lines[0], wantHeader)
+	}
+	if len(EXTFColumns) != 125 || strings.Split(lines[1], ";")[extfServiceDate] != "Leistungsdatum" {
+		t.Errorf("unexpected column row with %d columns", len(EXTFColumns))
+	}
+
+	row := strings.Split(lines[2], ";")
+	if len(row) != len(EXTFColumns) {
+		t.Fatalf("row has %d fields, want %d", len(row), len(EXTFColumns))
+	}
+	want := map[int]string{
+		extfAmount:        "119,00",
+		extfDebitCredit:   `"S"`,
+		extfAccount:       "4930",
+		extfContraAccount: "1600",
+		extfTaxKey:        `"9"`,
+		extfDocumentDate:  "0503",
+		extfDocumentField: `"RE2024/001Kopie"`,
+		extfBookingText:   `"Büromaterial ""Muster"""`,
+		extfCostCenter:    `"100"`,
+		extfServiceDate:   "28022024",
+		extfInfoType1:     `"Quelldatei"`,
+		extfInfoContent1:  `"rechnung.pdf"`,
+		extfInfoType2:     `"SHA-256"`,
+		extfInfoContent2:  `"9f86d081"`,
+	}
+	for index, value := range want {
+		if row[index] != value {
+			t.Errorf("column %q = %s, want %s", EXTFColumns[index], row[index], value)
+		}
+	}
+
+	credit := strings.Split(lines[3], ";")
+	if credit[extfAmount] != "59,50" || credit[extfDebitCredit] != `"H"` || credit[extfInfoType1] != "" {
+		t.Errorf("credit note booked as %s %s, want 59,50 \"H\"", credit[extfAmount], credit[extfDebitCredit])
+	}
+}
+
+func TestWriteEXTFSplitsAndFiscalYears(t *testing.T) {
+	entry := Entry{
+		Invoice: &models.Invoice{InvoiceNumber: "R-1", IssueDate: time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)},
+		Booking: &services.DATEVBooking{
+			DebitAccount:  "3400",
+			CreditAccount: "1600",
+			Splits:        []services.BookingSplit{{Amount: 119, TaxKey: "9"}, {Amount: 10.7, TaxKey: "8"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEXTF(&buf, EXTFHeader{}, []Entry{entry}); err != nil {
+		t.Fatalf("WriteEXTF: %v", err)
+	}
+	if rows := strings.Count(buf.String(), "\r\n") - 2; rows != 2 {
+		t.Errorf("expected one row per split, got %d", rows)
+	}
+	if !strings.Contains(buf.String(), `10,70;"S";"EUR";;;;3400;1600;"8"`) {
+		t.Errorf("7%% split missing:\n%s", buf.String())
+	}
+
+	next := Entry{
+		Invoice: &models.Invoice{InvoiceNumber: "R-2", IssueDate: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		Booking: &services.DATEVBooking{DebitAccount: "3400", CreditAccount: "1600", Amount: 10},
+	}
+	if err := WriteEXTF(&bytes.Buffer{}, EXTFHeader{}, []Entry{entry, next}); err == nil {
+		t.Error("expected an error for bookings in two fiscal years")
+	}
+}
+
+func TestWriteEXTFPostings(t *testing.T) {
+	entry := Entry{
+		Invoice: &models.Invoice{InvoiceNumber: "AR-7", Type: "RECEIVABLE", IssueDate: time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)},
+		Booking: &services.DATEVBooking{
+			DebitAccount:  "10001",
+			CreditAccount: "8400",
+			TaxKey:        "3",
+			Postings: []services.Posting{
+				{Account: "8400", Side: "H", Amount: 100},
+				{Account: "1776", Side: "H", Amount: 19, VAT: true},
+				{Account: "10001", Side: "S", Amount: 119},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEXTF(&buf, EXTFHeader{}, []Entry{entry}); err != nil {
+		t.Fatalf("WriteEXTF: %v", err)
+	}
+	if rows := strings.Count(buf.String(), "\r\n") - 2; rows != 2 {
+		t.Errorf("expected a net and a VAT row against the debitor, got %d rows", rows)
+	}
+	for _, want := range []string{`100,00;"H";"EUR";;;;8400;10001;""`, `19,00;"H";"EUR";;;;1776;10001;""`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing row %s in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestWriteEXTFNetAmountBasis(t *testing.T) {
+	entry := Entry{
+		Invoice: &models.Invoice{InvoiceNumber: "R-3", IssueDate: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), NetAmount: 12000, GrossAmount: 12970},
+		Booking: &services.DATEVBooking{
+			DebitAccount:  "3400",
+			CreditAccount: "1600",
+			AmountBasis:   services.AmountBasisNet,
+			Splits: []services.BookingSplit{
+				{Amount: 119, NetAmount: 100, TaxKey: "9"},
+				{Amount: 10.7, NetAmount: 10, TaxKey: "8"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEXTF(&buf, EXTFHeader{}, []Entry{entry}); err != nil {
+		t.Fatalf("WriteEXTF: %v", err)
+	}
+	for _, want := range []string{`100,00;"S";"EUR";;;;3400;1600;"9"`, `10,00;"S";"EUR";;;;3400;1600;"8"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("net split %s missing:\n%s", want, buf.String())
+		}
+	}
+
+	entry.Booking.Splits = nil
+	buf.Reset()
+	if err := WriteEXTF(&buf, EXTFHeader{}, []Entry{entry}); err != nil {
+		t.Fatalf("WriteEXTF: %v", err)
+	}
+	if !strings.Contains(buf.String(), `120,00;"S"`) {
+		t.Errorf("expected the net invoice amount without a booking amount:\n%s", buf.String())
+	}
+}
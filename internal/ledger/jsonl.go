@@ -0,0 +1,77 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// JSONLWriter writes newline-delimited JSON, one record per line. It is safe for concurrent use so
+// batch workers can stream their results as they complete. Each record is written with a single
+// Write call and is not buffered, so a crashed run leaves all completed records on disk.
+type JSONLWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	count  int
+}
+
+// NewJSONLWriter creates a JSONLWriter on top of w
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	return &JSONLWriter{w: w}
+}
+
+// CreateJSONLFile creates or truncates path and returns a JSONLWriter for it
+func CreateJSONLFile(path string) (*JSONLWriter, error) {
+	const op = "CreateJSONLFile"
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to create JSONL file: %w", op, err)
+	}
+
+	return &JSONLWriter{w: file, closer: file}, nil
+}
+
+// Write encodes record as JSON and appends it as one line
+func (j *JSONLWriter) Write(record interface{}) error {
+	const op = "JSONLWriter.Write"
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("%s: failed to encode record: %w", op, err)
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.w.Write(line); err != nil {
+		return fmt.Errorf("%s: failed to write record: %w", op, err)
+	}
+	j.count++
+
+	return nil
+}
+
+// Count returns the number of records written so far
+func (j *JSONLWriter) Count() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.count
+}
+
+// Close closes the underlying file if the writer was created with CreateJSONLFile
+func (j *JSONLWriter) Close() error {
+	const op = "JSONLWriter.Close"
+
+	if j.closer == nil {
+		return nil
+	}
+	if err := j.closer.Close(); err != nil {
+		return fmt.Errorf("%s: failed to close JSONL file: %w", op, err)
+	}
+	return nil
+}
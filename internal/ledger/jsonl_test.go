@@ -0,0 +1,49 @@
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestJSONLWriterConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewJSONLWriter(&buf)
+
+	type record struct {
+		File   string `json:"file"`
+		Status string `json:"status"`
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := writer.Write(record{File: fmt.Sprintf("invoice-%d.pdf", i), Status: "success"}); err != nil {
+				t.Errorf("Write failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if writer.Count() != 50 {
+		t.Errorf("Count() = %d, want 50", writer.Count())
+	}
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var got record
+		if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+			t.Fatalf("line is not valid JSON: %q: %v", scanner.Text(), err)
+		}
+		seen[got.File] = true
+	}
+	if len(seen) != 50 {
+		t.Errorf("expected 50 distinct records, got %d", len(seen))
+	}
+}
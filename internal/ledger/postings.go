@@ -0,0 +1,51 @@
+package ledger
+
+import (
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+// counterpartySide returns the side of the creditor or debitor line with the gross amount: Haben for
+// payables and Soll for receivables, the other side on credit notes with a negative gross amount
+func counterpartySide(invoice *models.Invoice) string {
+	side := services.PostingCredit
+	if invoice.Type == "RECEIVABLE" {
+		side = services.PostingDebit
+	}
+	if invoice.GrossAmount < 0 {
+		if side == services.PostingDebit {
+			return services.PostingCredit
+		}
+		return services.PostingDebit
+	}
+	return side
+}
+
+// splitPostings separates the posting lines of a booking with explicit VAT into the contra line, the
+// creditor or debitor with the gross amount on the counterparty side of the invoice, and the lines
+// booked against it. The side is taken from the invoice type, not from the number of lines per side,
+// as a tax-free invoice has a single line on both sides. ok is false if the counterparty side does not
+// have exactly one line, which DATEV cannot import as rows against one Gegenkonto.
+func splitPostings(postings []services.Posting, invoice *models.Invoice) (contra services.Posting, lines []services.Posting, ok bool) {
+	contraSide := counterpartySide(invoice)
+	contraIndex := -1
+	for i, posting := range postings {
+		if posting.Side != contraSide {
+			continue
+		}
+		if contraIndex >= 0 {
+			return services.Posting{}, nil, false
+		}
+		contraIndex = i
+	}
+	if contraIndex < 0 {
+		return services.Posting{}, nil, false
+	}
+
+	for i, posting := range postings {
+		if i != contraIndex {
+			lines = append(lines, posting)
+		}
+	}
+	return postings[contraIndex], lines, true
+}
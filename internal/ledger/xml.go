@@ -0,0 +1,184 @@
+package ledger
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"tools/pkg/services"
+)
+
+// xmlNamespace is the namespace of the DATEV Belegverwaltung online ledger import, format version 5.0
+const xmlNamespace = "http://xml.datev.de/bedi/tps/ledger/v050"
+
+// xmlLedgerImport is the root element of the DATEV ledger import
+type xmlLedgerImport struct {
+	XMLName          xml.Name         `xml:"LedgerImport"`
+	Namespace        string           `xml:"xmlns,attr"`
+	Version          string           `xml:"version,attr"`
+	GeneratorInfo    string           `xml:"generator_info,attr"`
+	GeneratingSystem string           `xml:"generating_system,attr"`
+	Consolidates     []xmlConsolidate `xml:"consolidate"`
+}
+
+// xmlConsolidate groups the ledger lines of one invoice
+type xmlConsolidate struct {
+	Amount      string          `xml:"consolidatedAmount,attr"`
+	Date        string          `xml:"consolidatedDate,attr"`
+	InvoiceID   string          `xml:"consolidatedInvoiceId,attr"`
+	Currency    string          `xml:"consolidatedCurrencyCode,attr"`
+	Payables    []xmlLedgerLine `xml:"accountsPayableLedger"`
+	Receivables []xmlLedgerLine `xml:"accountsReceivableLedger"`
+}
+
+// xmlLedgerLine is one accountsPayableLedger or accountsReceivableLedger element. The element order
+// follows the schema.
+type xmlLedgerLine struct {
+	Date           string `xml:"date"`
+	Amount         string `xml:"amount"`
+	AccountNo      string `xml:"accountNo,omitempty"`
+	BUCode         string `xml:"buCode,omitempty"`
+	CostCategoryID string `xml:"costCategoryId,omitempty"`
+	CurrencyCode   string `xml:"currencyCode"`
+	InvoiceID      string `xml:"invoiceId,omitempty"`
+	BookingText    string `xml:"bookingText,omitempty"`
+	VATID          string `xml:"vatId,omitempty"`
+	DueDate        string `xml:"dueDate,omitempty"`
+	BPAccountNo    string `xml:"bpAccountNo,omitempty"`
+	DeliveryDate   string `xml:"deliveryDate,omitempty"`
+	SupplierName   string `xml:"supplierName,omitempty"`
+	CustomerName   string `xml:"customerName,omitempty"`
+}
+
+// WriteXML writes a DATEV XML ledger import (Belegverwaltung online) with one consolidate element per
+// invoice: payables as accountsPayableLedger, receivables as accountsReceivableLedger lines, one per
+// booking, per split of a mixed-rate booking, or per net and VAT line of a booking with explicit VAT
+// postings. Entries without an invoice or booking are skipped.
+func WriteXML(w io.Writer, entries []Entry) error {
+	const op = "WriteXML"
+
+	ledger := xmlLedgerImport{
+		Namespace:        xmlNamespace,
+		Version:          "5.0",
+		GeneratorInfo:    "tools",
+		GeneratingSystem: "tools",
+	}
+	for _, entry := range entries {
+		if entry.Invoice == nil || entry.Booking == nil {
+			continue
+		}
+		ledger.Consolidates = append(ledger.Consolidates, entryToXMLConsolidate(entry))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("%s: failed to write XML header: %w", op, err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(ledger); err != nil {
+		return fmt.Errorf("%s: failed to encode ledger import: %w", op, err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("%s: failed to write XML: %w", op, err)
+	}
+
+	return nil
+}
+
+// entryToXMLConsolidate converts an entry to its consolidate element. The ledger's accountNo is the
+// expense or revenue account; the other account of the booking is only given as the business
+// partner account if it is a personal account (Kreditor/Debitor, five or more digits).
+func entryToXMLConsolidate(entry Entry) xmlConsolidate {
+	inv := entry.Invoice
+	booking := entry.Booking
+
+	date := formatXMLDate(extfDocumentDateOf(entry))
+	currency := inv.Currency
+	if currency == "" {
+		currency = "EUR"
+	}
+
+	account, partnerAccount := booking.DebitAccount, booking.CreditAccount
+	if inv.Type == "RECEIVABLE" {
+		account, partnerAccount = booking.CreditAccount, booking.DebitAccount
+	}
+	if len(partnerAccount) < 5 {
+		partnerAccount = ""
+	}
+
+	base := xmlLedgerLine{
+		Date:           date,
+		AccountNo:      account,
+		CostCategoryID: booking.CostCenter,
+		CurrencyCode:   currency,
+		InvoiceID:      truncateRunes(inv.InvoiceNumber, 36),
+		BookingText:    truncateRunes(booking.BookingText, 60),
+		DueDate:        formatXMLDate(inv.DueDate),
+		BPAccountNo:    partnerAccount,
+		DeliveryDate:   formatXMLDate(inv.ServiceDate),
+	}
+
+	consolidate := xmlConsolidate{
+		Amount:    formatCents(inv.GrossAmount),
+		Date:      date,
+		InvoiceID: base.InvoiceID,
+		Currency:  currency,
+	}
+
+	type part struct {
+		cents   int64
+		taxKey  string
+		account string
+	}
+	parts := []part{{basisCents(inv, booking), booking.TaxKey, account}}
+	if len(booking.Splits) > 0 {
+		parts = parts[:0]
+		for _, split := range booking.Splits {
+			parts = append(parts, part{int64(math.Round(basisSplitAmount(booking, split) * 100)), split.TaxKey, account})
+		}
+	}
+	// Explicit VAT postings become lines without buCode on the expense or revenue and the VAT accounts;
+	// a line on the other side than usual, as on credit notes, is negative
+	if _, postings, ok := splitPostings(booking.Postings, inv); ok {
+		usualSide := services.PostingDebit
+		if inv.Type == "RECEIVABLE" {
+			usualSide = services.PostingCredit
+		}
+		parts = parts[:0]
+		for _, posting := range postings {
+			cents := int64(math.Round(posting.Amount * 100))
+			if posting.Side != usualSide {
+				cents = -cents
+			}
+			parts = append(parts, part{cents, "", posting.Account})
+		}
+	}
+
+	for _, p := range parts {
+		line := base
+		line.Amount = formatCents(p.cents)
+		line.BUCode = p.taxKey
+		line.AccountNo = p.account
+		if inv.Type == "RECEIVABLE" {
+			line.CustomerName = inv.Customer
+			line.VATID = inv.CustomerVATID
+			consolidate.Receivables = append(consolidate.Receivables, line)
+		} else {
+			line.SupplierName = inv.Vendor
+			line.VATID = inv.VendorVATID
+			consolidate.Payables = append(consolidate.Payables, line)
+		}
+	}
+
+	return consolidate
+}
+
+// formatXMLDate renders a date as YYYY-MM-DD, or empty if unset
+func formatXMLDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
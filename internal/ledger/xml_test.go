@@ -0,0 +1,184 @@
+package ledger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+func TestWriteXML(t *testing.T) {
+	entries := []Entry{
+		{
+			Invoice: &models.Invoice{
+				InvoiceNumber: "RE-1001",
+				Type:          "PAYABLE",
+				Vendor:        "Muster & Co. GmbH",
+				IssueDate:     time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+				GrossAmount:   11900,
+				Currency:      "EUR",
+			},
+			Booking: &services.DATEVBooking{DebitAccount: "4930", CreditAccount: "70001", TaxKey: "9", BookingText: "Büromaterial"},
+		},
+		{
+			Invoice: &models.Invoice{
+				InvoiceNumber: "AR-7",
+				Type:          "RECEIVABLE",
+				Customer:      "Kunde AG",
+				IssueDate:     time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC),
+				GrossAmount:   12970,
+			},
+			Booking: &services.DATEVBooking{
+				DebitAccount:  "1400",
+				CreditAccount: "8400",
+				Splits:        []services.BookingSplit{{Amount: 119, TaxKey: "3"}, {Amount: 10.7, TaxKey: "2"}},
+			},
+		},
+		{Invoice: &models.Invoice{InvoiceNumber: "ohne Buchung"}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXML(&buf, entries); err != nil {
+		t.Fatalf("WriteXML: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		`<LedgerImport xmlns="http://xml.datev.de/bedi/tps/ledger/v050" version="5.0"`,
+		`<consolidate consolidatedAmount="119.00" consolidatedDate="2024-03-05" consolidatedInvoiceId="RE-1001" consolidatedCurrencyCode="EUR">`,
+		"<accountNo>4930</accountNo>",
+		"<bpAccountNo>70001</bpAccountNo>",
+		"<supplierName>Muster &amp; Co. GmbH</supplierName>",
+		"<accountNo>8400</accountNo>",
+		"<amount>10.70</amount>",
+		"<customerName>Kunde AG</customerName>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "<consolidate ") != 2 || strings.Count(out, "<accountsReceivableLedger>") != 2 {
+		t.Errorf("expected 2 invoices, the second with one line per split:\n%s", out)
+	}
+	if strings.Contains(out, "<bpAccountNo>1400</bpAccountNo>") {
+		t.Error("a 4-digit account must not be given as business partner account")
+	}
+}
+
+func TestWriteXMLPostings(t *testing.T) {
+	entry := Entry{
+		Invoice: &models.Invoice{InvoiceNumber: "GS-3", Type: "PAYABLE", IssueDate: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), GrossAmount: -5350},
+		Booking: &services.DATEVBooking{
+			DebitAccount:  "3400",
+			CreditAccount: "70001",
+			TaxKey:        "5",
+			Postings: []services.Posting{
+				{Account: "3400", Side: "H", Amount: 50},
+				{Account: "1571", Side: "H", Amount: 3.5, VAT: true},
+				{Account: "70001", Side: "S", Amount: 53.5},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXML(&buf, []Entry{entry}); err != nil {
+		t.Fatalf("WriteXML: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{"<amount>-50.00</amount>", "<accountNo>1571</accountNo>", "<amount>-3.50</amount>", "<bpAccountNo>70001</bpAccountNo>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "<accountsPayableLedger>") != 2 || strings.Contains(out, "<buCode>") {
+		t.Errorf("expected a net and a VAT line without buCode:\n%s", out)
+	}
+}
+
+func TestWriteXMLPostingsTaxFreeReceivable(t *testing.T) {
+	entry := Entry{
+		Invoice: &models.Invoice{InvoiceNumber: "AR-7", Type: "RECEIVABLE", IssueDate: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), GrossAmount: 2500},
+		Booking: &services.DATEVBooking{
+			DebitAccount:  "10001",
+			CreditAccount: "8120",
+			TaxKey:        "0",
+			Postings: []services.Posting{
+				{Account: "8120", Side: "H", Amount: 25},
+				{Account: "10001", Side: "S", Amount: 25},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXML(&buf, []Entry{entry}); err != nil {
+		t.Fatalf("WriteXML: %v", err)
+	}
+	out := buf.String()
+	// Compare each line's amount and account regardless of the indentation
+	compact := strings.Join(strings.Fields(out), "")
+
+	for _, want := range []string{"<amount>25.00</amount><accountNo>8120</accountNo>", "<bpAccountNo>10001</bpAccountNo>"} {
+		if !strings.Contains(compact, want) {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<accountNo>10001</accountNo>") || strings.Contains(out, "-25.00") {
+		t.Errorf("the debitor must be the contra line, not a reversed revenue line:\n%s", out)
+	}
+}
+
+func TestWriteXMLPostingsPayable(t *testing.T) {
+	entry := Entry{
+		Invoice: &models.Invoice{InvoiceNumber: "ER-8", Type: "PAYABLE", IssueDate: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), GrossAmount: 11900},
+		Booking: &services.DATEVBooking{
+			DebitAccount:  "3400",
+			CreditAccount: "70001",
+			TaxKey:        "9",
+			Postings: []services.Posting{
+				{Account: "3400", Side: "S", Amount: 100},
+				{Account: "1576", Side: "S", Amount: 19, VAT: true},
+				{Account: "70001", Side: "H", Amount: 119},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXML(&buf, []Entry{entry}); err != nil {
+		t.Fatalf("WriteXML: %v", err)
+	}
+	out := buf.String()
+	// Compare each line's amount and account regardless of the indentation
+	compact := strings.Join(strings.Fields(out), "")
+
+	for _, want := range []string{"<amount>100.00</amount><accountNo>3400</accountNo>", "<amount>19.00</amount><accountNo>1576</accountNo>", "<bpAccountNo>70001</bpAccountNo>"} {
+		if !strings.Contains(compact, want) {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "<accountsPayableLedger>") != 2 {
+		t.Errorf("expected a net and a VAT line against the creditor:\n%s", out)
+	}
+}
+
+func TestWriteXMLNetAmountBasis(t *testing.T) {
+	entry := Entry{
+		Invoice: &models.Invoice{InvoiceNumber: "RE-1002", Type: "PAYABLE", IssueDate: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), NetAmount: 10000, GrossAmount: 11900},
+		Booking: &services.DATEVBooking{DebitAccount: "4930", CreditAccount: "70001", TaxKey: "9", AmountBasis: services.AmountBasisNet},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXML(&buf, []Entry{entry}); err != nil {
+		t.Fatalf("WriteXML: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "<amount>100.00</amount>") {
+		t.Errorf("expected the net amount as line amount:\n%s", out)
+	}
+	if !strings.Contains(out, `consolidatedAmount="119.00"`) {
+		t.Errorf("the consolidated amount must stay the gross invoice amount:\n%s", out)
+	}
+}
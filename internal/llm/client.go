@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Supported values for LLM_PROVIDER
+const (
+	ProviderOpenAI = "openai"
+	ProviderAzure  = "azure"
+	ProviderLocal  = "local"
+)
+
+// DefaultAzureAPIVersion is used when AZURE_OPENAI_API_VERSION is not set
+const DefaultAzureAPIVersion = "2024-06-01"
+
+// LLMClient abstracts the chat-completion call shared by the completion, booking and reconciliation services
+type LLMClient interface {
+	CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
+// AzureConfig holds the settings needed to reach an Azure OpenAI deployment
+type AzureConfig struct {
+	APIKey     string
+	Endpoint   string // e.g. https://my-resource.openai.azure.com
+	Deployment string // deployment name used for every request; empty maps model names to deployments
+	APIVersion string
+}
+
+// NewOpenAIClient creates a client for the public OpenAI API
+func NewOpenAIClient(apiKey string) (LLMClient, error) {
+	const op = "NewOpenAIClient"
+
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s: OPENAI_API_KEY environment variable is required", op)
+	}
+
+	return openai.NewClient(apiKey), nil
+}
+
+// NewAzureOpenAIClient creates a client for an Azure OpenAI deployment
+func NewAzureOpenAIClient(cfg AzureConfig) (LLMClient, error) {
+	const op = "NewAzureOpenAIClient"
+
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("%s: OPENAI_API_KEY environment variable is required", op)
+	}
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("%s: AZURE_OPENAI_ENDPOINT environment variable is required", op)
+	}
+
+	clientConfig := openai.DefaultAzureConfig(cfg.APIKey, cfg.Endpoint)
+	if cfg.APIVersion != "" {
+		clientConfig.APIVersion = cfg.APIVersion
+	} else {
+		clientConfig.APIVersion = DefaultAzureAPIVersion
+	}
+
+	// Azure routes by deployment rather than model name, so pin all requests to the configured deployment
+	if cfg.Deployment != "" {
+		deployment := cfg.Deployment
+		clientConfig.AzureModelMapperFunc = func(model string) string {
+			return deployment
+		}
+	}
+
+	return openai.NewClientWithConfig(clientConfig), nil
+}
+
+// NewLocalClient creates a client for an OpenAI-compatible server such as Ollama, vLLM or LM Studio
+func NewLocalClient(baseURL, apiKey string) (LLMClient, error) {
+	const op = "NewLocalClient"
+
+	if baseURL == "" {
+		return nil, fmt.Errorf("%s: OPENAI_BASE_URL environment variable is required for the local provider", op)
+	}
+
+	// Most local servers ignore the key, but the OpenAI client always sends one
+	clientConfig := openai.DefaultConfig(apiKey)
+	clientConfig.BaseURL = strings.TrimRight(baseURL, "/")
+
+	return openai.NewClientWithConfig(clientConfig), nil
+}
+
+// NewClientFromEnv creates the LLM client selected by LLM_PROVIDER.
+// Without LLM_PROVIDER, a set OPENAI_BASE_URL selects the local provider, otherwise OpenAI is used.
+func NewClientFromEnv() (LLMClient, error) {
+	const op = "NewClientFromEnv"
+
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	baseURL := os.Getenv("OPENAI_BASE_URL")
+
+	switch provider := ProviderFromEnv(); provider {
+	case ProviderOpenAI:
+		return NewOpenAIClient(apiKey)
+	case ProviderAzure:
+		return NewAzureOpenAIClient(AzureConfig{
+			APIKey:     apiKey,
+			Endpoint:   os.Getenv("AZURE_OPENAI_ENDPOINT"),
+			Deployment: os.Getenv("AZURE_OPENAI_DEPLOYMENT"),
+			APIVersion: os.Getenv("AZURE_OPENAI_API_VERSION"),
+		})
+	case ProviderLocal:
+		return NewLocalClient(baseURL, apiKey)
+	default:
+		return nil, fmt.Errorf("%s: unsupported LLM_PROVIDER %q (use %s, %s or %s)", op, provider, ProviderOpenAI, ProviderAzure, ProviderLocal)
+	}
+}
+
+// ProviderFromEnv returns the configured provider name, applying the OPENAI_BASE_URL fallback
+func ProviderFromEnv() string {
+	if provider := strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER"))); provider != "" {
+		return provider
+	}
+	if os.Getenv("OPENAI_BASE_URL") != "" {
+		return ProviderLocal
+	}
+	return ProviderOpenAI
+}
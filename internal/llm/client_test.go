@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+// clientEnv lists the variables NewClientFromEnv reads; the tests clear the ones a case does not set
+var clientEnv = []string{"LLM_PROVIDER", "OPENAI_API_KEY", "OPENAI_BASE_URL", "AZURE_OPENAI_ENDPOINT", "AZURE_OPENAI_DEPLOYMENT", "AZURE_OPENAI_API_VERSION"}
+
+func TestNewClientFromEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantProvider string
+		wantErr      string
+	}{
+		{
+			name:         "openai by default",
+			env:          map[string]string{"OPENAI_API_KEY": "sk-test"},
+			wantProvider: ProviderOpenAI,
+		},
+		{
+			name:         "openai",
+			env:          map[string]string{"LLM_PROVIDER": " OpenAI ", "OPENAI_API_KEY": "sk-test"},
+			wantProvider: ProviderOpenAI,
+		},
+		{
+			name:         "openai without key",
+			env:          map[string]string{"LLM_PROVIDER": "openai"},
+			wantProvider: ProviderOpenAI,
+			wantErr:      "OPENAI_API_KEY",
+		},
+		{
+			name:         "azure",
+			env:          map[string]string{"LLM_PROVIDER": "azure", "OPENAI_API_KEY": "key", "AZURE_OPENAI_ENDPOINT": "https://muster.openai.azure.com", "AZURE_OPENAI_DEPLOYMENT": "gpt-4o"},
+			wantProvider: ProviderAzure,
+		},
+		{
+			name:         "azure without endpoint",
+			env:          map[string]string{"LLM_PROVIDER": "azure", "OPENAI_API_KEY": "key", "AZURE_OPENAI_DEPLOYMENT": "gpt-4o"},
+			wantProvider: ProviderAzure,
+			wantErr:      "AZURE_OPENAI_ENDPOINT",
+		},
+		{
+			name:         "azure without key",
+			env:          map[string]string{"LLM_PROVIDER": "azure", "AZURE_OPENAI_ENDPOINT": "https://muster.openai.azure.com"},
+			wantProvider: ProviderAzure,
+			wantErr:      "OPENAI_API_KEY",
+		},
+		{
+			name:         "local",
+			env:          map[string]string{"LLM_PROVIDER": "local", "OPENAI_BASE_URL": "http://localhost:11434/v1/"},
+			wantProvider: ProviderLocal,
+		},
+		{
+			name:         "local by base URL",
+			env:          map[string]string{"OPENAI_BASE_URL": "http://localhost:11434/v1"},
+			wantProvider: ProviderLocal,
+		},
+		{
+			name:         "local without base URL",
+			env:          map[string]string{"LLM_PROVIDER": "local"},
+			wantProvider: ProviderLocal,
+			wantErr:      "OPENAI_BASE_URL",
+		},
+		{
+			name:         "unknown provider",
+			env:          map[string]string{"LLM_PROVIDER": "opneai", "OPENAI_API_KEY": "sk-test"},
+			wantProvider: "opneai",
+			wantErr:      `unsupported LLM_PROVIDER "opneai"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range clientEnv {
+				t.Setenv(key, tt.env[key])
+			}
+
+			if got := ProviderFromEnv(); got != tt.wantProvider {
+				t.Errorf("ProviderFromEnv() = %q, want %q", got, tt.wantProvider)
+			}
+
+			client, err := NewClientFromEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewClientFromEnv() error = %v, want it to name %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || client == nil {
+				t.Fatalf("NewClientFromEnv() = %v, %v, want a client", client, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Failure classes of errors that retrying does not fix and that every further request repeats
+const (
+	FailureAuth  = "auth"  // The API key was rejected or lacks access to the model
+	FailureQuota = "quota" // The account's quota or credit is used up
+)
+
+// FailureClass returns FailureAuth for a rejected API key (401, 403) and FailureQuota for an
+// exhausted quota (insufficient_quota), and "" for any other error. A plain rate limit (429 without
+// insufficient_quota) passes within seconds and is not classified.
+func FailureClass(err error) string {
+	var status int
+	var code, errType string
+
+	var apiErr *openai.APIError
+	var requestErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status, errType = apiErr.HTTPStatusCode, apiErr.Type
+		code, _ = apiErr.Code.(string)
+	case errors.As(err, &requestErr):
+		status = requestErr.HTTPStatusCode
+	default:
+		return ""
+	}
+
+	switch {
+	case status == http.StatusUnauthorized, status == http.StatusForbidden, code == "invalid_api_key":
+		return FailureAuth
+	case code == "insufficient_quota", errType == "insufficient_quota":
+		return FailureQuota
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestFailureClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"invalid key", &openai.APIError{HTTPStatusCode: 401, Code: "invalid_api_key"}, FailureAuth},
+		{"no model access", &openai.RequestError{HTTPStatusCode: 403}, FailureAuth},
+		{"quota", &openai.APIError{HTTPStatusCode: 429, Code: "insufficient_quota", Type: "insufficient_quota"}, FailureQuota},
+		{"wrapped", fmt.Errorf("GenerateBooking: ChatGPT request failed: %w", &openai.APIError{HTTPStatusCode: 429, Type: "insufficient_quota"}), FailureQuota},
+		{"rate limit", &openai.APIError{HTTPStatusCode: 429, Code: "rate_limit_exceeded"}, ""},
+		{"server error", &openai.APIError{HTTPStatusCode: 500}, ""},
+		{"timeout", context.DeadlineExceeded, ""},
+		{"other", errors.New("invalid JSON"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FailureClass(tt.err); got != tt.want {
+				t.Errorf("FailureClass() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"strings"
+)
+
+// ExtractJSON returns the JSON payload of a chat-completion response. Models occasionally wrap their
+// answer in a markdown code fence, add explanatory prose around it or leave a trailing comma after the
+// last field despite the prompt telling them not to, so this strips all of that before unmarshaling.
+func ExtractJSON(content string) string {
+	cleaned := strings.TrimSpace(content)
+
+	// Prefer the contents of the first code fence, if any
+	if start := strings.Index(cleaned, "```"); start >= 0 {
+		fenced := cleaned[start+3:]
+		// Skip the language tag on the opening fence line (```json)
+		if newline := strings.IndexByte(fenced, '\n'); newline >= 0 {
+			fenced = fenced[newline+1:]
+		}
+		if end := strings.Index(fenced, "```"); end >= 0 {
+			fenced = fenced[:end]
+		}
+		cleaned = strings.TrimSpace(fenced)
+	}
+
+	// Cut leading and trailing prose down to the outermost object or array
+	if start := strings.IndexAny(cleaned, "{["); start >= 0 {
+		closing := "}"
+		if cleaned[start] == '[' {
+			closing = "]"
+		}
+		if end := strings.LastIndex(cleaned, closing); end > start {
+			cleaned = cleaned[start : end+1]
+		}
+	}
+
+	return removeTrailingCommas(cleaned)
+}
+
+// removeTrailingCommas drops commas directly followed by a closing brace or bracket, ignoring string contents
+func removeTrailingCommas(s string) string {
+	var out strings.Builder
+	out.Grow(len(s))
+
+	inString := false
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if inString {
+			out.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		if c == '"' {
+			inString = true
+		} else if c == ',' {
+			j := i + 1
+			for j < len(s) && strings.IndexByte(" \t\r\n", s[j]) >= 0 {
+				j++
+			}
+			if j < len(s) && (s[j] == '}' || s[j] == ']') {
+				continue
+			}
+		}
+
+		out.WriteByte(c)
+	}
+
+	return out.String()
+}
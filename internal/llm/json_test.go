@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "plain object",
+			content: `{"sollkonto": "4930"}`,
+			want:    `{"sollkonto": "4930"}`,
+		},
+		{
+			name:    "json fence",
+			content: "```json\n{\"sollkonto\": \"4930\"}\n```",
+			want:    `{"sollkonto": "4930"}`,
+		},
+		{
+			name:    "bare fence",
+			content: "```\n{\"matched\": true}\n```",
+			want:    `{"matched": true}`,
+		},
+		{
+			name:    "prose around fence",
+			content: "Hier ist die Buchung:\n```json\n{\"sollkonto\": \"4930\"}\n```\nIch hoffe, das hilft.",
+			want:    `{"sollkonto": "4930"}`,
+		},
+		{
+			name:    "prose without fence",
+			content: "Gerne! {\"type\": \"PAYABLE\"} Bei Fragen melden Sie sich.",
+			want:    `{"type": "PAYABLE"}`,
+		},
+		{
+			name:    "trailing comma in object",
+			content: "{\n  \"type\": \"PAYABLE\",\n  \"vendor\": \"Muster GmbH\",\n}",
+			want:    "{\n  \"type\": \"PAYABLE\",\n  \"vendor\": \"Muster GmbH\"\n}",
+		},
+		{
+			name:    "trailing comma in array",
+			content: `{"aliases": ["A", "B",]}`,
+			want:    `{"aliases": ["A", "B"]}`,
+		},
+		{
+			name:    "comma inside string is kept",
+			content: `{"buchungstext": "Miete, }Büro", "x": 1,}`,
+			want:    `{"buchungstext": "Miete, }Büro", "x": 1}`,
+		},
+		{
+			name:    "escaped quote inside string",
+			content: `{"text": "He said \",}\"",}`,
+			want:    `{"text": "He said \",}\""}`,
+		},
+		{
+			name:    "top-level array",
+			content: "Result: [1, 2, 3,]",
+			want:    `[1, 2, 3]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractJSON(tt.content)
+			if got != tt.want {
+				t.Errorf("ExtractJSON() = %q, want %q", got, tt.want)
+			}
+			if !json.Valid([]byte(got)) {
+				t.Errorf("ExtractJSON() returned invalid JSON: %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ErrPromptRecorded is the error PromptRecorder answers every request with
+var ErrPromptRecorded = errors.New("prompt recorded instead of sent")
+
+// PromptRecorder is an LLMClient that records the requests made to it instead of sending them, to
+// inspect the exact prompts of a run without paying for it. Every request fails with
+// ErrPromptRecorded; retries of a request already recorded are not recorded again.
+type PromptRecorder struct {
+	mu       sync.Mutex
+	requests []openai.ChatCompletionRequest
+}
+
+// NewPromptRecorder creates a recorder without requests
+func NewPromptRecorder() *PromptRecorder {
+	return &PromptRecorder{}
+}
+
+// CreateChatCompletion records the request and fails with ErrPromptRecorded
+func (r *PromptRecorder) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, recorded := range r.requests {
+		if recorded.Model == request.Model && reflect.DeepEqual(recorded.Messages, request.Messages) {
+			return openai.ChatCompletionResponse{}, ErrPromptRecorded
+		}
+	}
+	r.requests = append(r.requests, request)
+	return openai.ChatCompletionResponse{}, ErrPromptRecorded
+}
+
+// Requests returns the recorded requests in the order they were made
+func (r *PromptRecorder) Requests() []openai.ChatCompletionRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]openai.ChatCompletionRequest(nil), r.requests...)
+}
+
+// WriteTo writes the messages of the recorded requests as plain text, each request headed by its
+// number and model and each message by its role
+func (r *PromptRecorder) WriteTo(w io.Writer) (int64, error) {
+	var text strings.Builder
+	for i, request := range r.Requests() {
+		fmt.Fprintf(&text, "===== Request %d (model %s) =====\n", i+1, request.Model)
+		for _, message := range request.Messages {
+			fmt.Fprintf(&text, "----- %s -----\n%s\n", message.Role, strings.TrimRight(message.Content, "\n"))
+		}
+		text.WriteString("\n")
+	}
+	n, err := io.WriteString(w, text.String())
+	return int64(n), err
+}
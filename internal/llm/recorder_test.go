@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestPromptRecorder(t *testing.T) {
+	recorder := NewPromptRecorder()
+	request := openai.ChatCompletionRequest{
+		Model: "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "Du bist ein Buchhalter."},
+			{Role: openai.ChatMessageRoleUser, Content: "Rechnung RE-1001\n"},
+		},
+	}
+
+	// A retry of the same request is recorded once
+	for i := 0; i < 2; i++ {
+		if _, err := recorder.CreateChatCompletion(context.Background(), request); !errors.Is(err, ErrPromptRecorded) {
+			t.Fatalf("CreateChatCompletion() error = %v, want ErrPromptRecorded", err)
+		}
+	}
+	escalated := request
+	escalated.Model = "gpt-4o"
+	recorder.CreateChatCompletion(context.Background(), escalated)
+
+	requests := recorder.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("Requests() = %d requests, want 2", len(requests))
+	}
+	if requests[1].Model != "gpt-4o" {
+		t.Errorf("second request model = %q, want gpt-4o", requests[1].Model)
+	}
+
+	var out strings.Builder
+	if _, err := recorder.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	want := "===== Request 1 (model gpt-4o-mini) =====\n" +
+		"----- system -----\nDu bist ein Buchhalter.\n" +
+		"----- user -----\nRechnung RE-1001\n\n"
+	if !strings.HasPrefix(out.String(), want) {
+		t.Errorf("WriteTo() output =\n%s\nwant prefix\n%s", out.String(), want)
+	}
+}
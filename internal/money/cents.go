@@ -0,0 +1,249 @@
+// Package money parses amounts into cents without going through float64.
+//
+// Amounts are read digit by digit, so "19,999" and "0.015" are rounded by the configured
+// RoundingMode instead of losing a cent to float truncation. German ("1.234,56"), English
+// ("1,234.56") and Swiss ("1'234.56") formats are all accepted, as are spaces grouping thousands:
+// if both separators occur, the last one is the decimal separator; a single comma or dot is a
+// decimal separator and a repeated one groups thousands. The exception is a single separator
+// followed by exactly three digits after a short integer part, as in "1.200" or "1,200", which
+// the configured Locale decides: German amounts use the dot for thousands without decimals,
+// English ones the comma, Swiss ones neither. The German reading therefore also applies to
+// "19.999", which is 19 999,00 rather than 20,00; amounts with three decimals written with a dot
+// round only in the English and Swiss locales.
+package money
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode decides how digits beyond the cent are rounded
+type RoundingMode string
+
+const (
+	// RoundHalfUp rounds half a cent away from zero, as in commercial rounding (default)
+	RoundHalfUp RoundingMode = "half_up"
+
+	// RoundHalfEven rounds half a cent to the even cent (banker's rounding)
+	RoundHalfEven RoundingMode = "half_even"
+
+	// RoundDown drops the digits beyond the cent
+	RoundDown RoundingMode = "down"
+)
+
+// RoundingModeFromEnv returns the rounding mode configured with AMOUNT_ROUNDING, or RoundHalfUp
+// if it is unset or unknown
+func RoundingModeFromEnv() RoundingMode {
+	switch mode := RoundingMode(strings.ToLower(strings.TrimSpace(os.Getenv("AMOUNT_ROUNDING")))); mode {
+	case RoundHalfEven, RoundDown:
+		return mode
+	}
+	return RoundHalfUp
+}
+
+// Locale is the numeric convention that decides whether a single separator followed by exactly
+// three digits groups thousands or separates decimals
+type Locale string
+
+const (
+	// LocaleDE reads "1.200" as 1200,00 and "1,200" as 1,20 (default)
+	LocaleDE Locale = "de"
+	// LocaleCH reads both "1.200" and "1,200" as 1.20; Swiss amounts group thousands with
+	// apostrophes ("1'234.56"), which are accepted in every locale
+	LocaleCH Locale = "ch"
+	// LocaleEN reads "1,200" as 1200.00 and "1.200" as 1.20
+	LocaleEN Locale = "en"
+)
+
+// LocaleFromEnv returns the locale configured with AMOUNT_LOCALE, or LocaleDE if it is unset or
+// unknown
+func LocaleFromEnv() Locale {
+	switch locale := Locale(strings.ToLower(strings.TrimSpace(os.Getenv("AMOUNT_LOCALE")))); locale {
+	case LocaleCH, LocaleEN:
+		return locale
+	}
+	return LocaleDE
+}
+
+// ParseCents parses an amount such as "1.234,56 €", "-19,999" or "EUR 1,234.56" into cents,
+// reading it in the locale configured with AMOUNT_LOCALE and rounding with the mode configured
+// with AMOUNT_ROUNDING
+func ParseCents(amount string) (int64, error) {
+	return ParseCentsWithMode(amount, RoundingModeFromEnv())
+}
+
+// ParseCentsWithMode parses an amount like ParseCents with an explicit rounding mode
+func ParseCentsWithMode(amount string, mode RoundingMode) (int64, error) {
+	return ParseMoney(amount, LocaleFromEnv(), mode)
+}
+
+// ParseMoney parses an amount into cents with an explicit locale and rounding mode. ParseCents is
+// the variant configured from the environment.
+func ParseMoney(amount string, locale Locale, mode RoundingMode) (int64, error) {
+	cleaned, negative := cleanNumber(amount)
+	integer, fraction, err := splitDecimal(cleaned, locale)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse amount: %s: %w", amount, err)
+	}
+
+	var cents int64
+	for _, digit := range integer + padRight(fraction, 2)[:2] {
+		if cents > (1<<63-1)/10-1 {
+			return 0, fmt.Errorf("unable to parse amount: %s: too large", amount)
+		}
+		cents = cents*10 + int64(digit-'0')
+	}
+	if len(fraction) > 2 && roundUp(fraction[2:], cents, mode) {
+		cents++
+	}
+
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// ParseQuantity parses a quantity such as "1,5", "1.000" or "1'000" in the locale configured with
+// AMOUNT_LOCALE. Separators are read like those of amounts, but all decimals are kept.
+func ParseQuantity(quantity string) (float64, error) {
+	return ParseQuantityInLocale(quantity, LocaleFromEnv())
+}
+
+// ParseQuantityInLocale parses a quantity like ParseQuantity with an explicit locale
+func ParseQuantityInLocale(quantity string, locale Locale) (float64, error) {
+	cleaned, negative := cleanNumber(quantity)
+	integer, fraction, err := splitDecimal(cleaned, locale)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse quantity: %s: %w", quantity, err)
+	}
+
+	value, err := strconv.ParseFloat(padRight(integer, 1)+"."+padRight(fraction, 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse quantity: %s: %w", quantity, err)
+	}
+	if negative {
+		value = -value
+	}
+	return value, nil
+}
+
+// cleanNumber strips the currency and grouping spaces and apostrophes from a number and splits off
+// its sign
+func cleanNumber(value string) (cleaned string, negative bool) {
+	cleaned = strings.NewReplacer(
+		" ", "", "\u00a0", "", "\u2009", "", "\u202f", "", "'", "", "\u2019", "",
+		"€", "", "$", "", "EUR", "", "USD", "", "CHF", "", "Fr.", "",
+	).Replace(strings.TrimSpace(value))
+
+	switch {
+	case strings.HasPrefix(cleaned, "-"):
+		negative, cleaned = true, cleaned[1:]
+	case strings.HasPrefix(cleaned, "+"):
+		cleaned = cleaned[1:]
+	}
+	return cleaned, negative
+}
+
+// FromUnitsNanos converts a money value given as whole units and billionths, as in Document AI's
+// normalized money values, to cents with the configured rounding mode
+func FromUnitsNanos(units int64, nanos int32) int64 {
+	// units and nanos have the same sign; round the magnitude and restore it
+	negative := units < 0 || nanos < 0
+	if units < 0 {
+		units = -units
+	}
+	if nanos < 0 {
+		nanos = -nanos
+	}
+
+	cents := units*100 + int64(nanos)/10_000_000
+	if roundUp(fmt.Sprintf("%07d", nanos%10_000_000), cents, RoundingModeFromEnv()) {
+		cents++
+	}
+
+	if negative {
+		cents = -cents
+	}
+	return cents
+}
+
+// splitDecimal splits a cleaned, unsigned amount into its integer and fraction digits
+func splitDecimal(value string, locale Locale) (integer, fraction string, err error) {
+	if value == "" {
+		return "", "", fmt.Errorf("empty amount")
+	}
+
+	decimal := byte(0)
+	lastComma, lastDot := strings.LastIndex(value, ","), strings.LastIndex(value, ".")
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		decimal = ','
+		if lastDot > lastComma {
+			decimal = '.'
+		}
+	case lastComma >= 0 && strings.Count(value, ",") == 1 && !(locale == LocaleEN && thousandsGroup(value, lastComma)):
+		decimal = ','
+	case lastDot >= 0 && strings.Count(value, ".") == 1 && !(locale == LocaleDE && thousandsGroup(value, lastDot)):
+		decimal = '.'
+	}
+
+	integer = value
+	if decimal != 0 {
+		i := strings.LastIndexByte(value, decimal)
+		integer, fraction = value[:i], value[i+1:]
+	}
+	integer = strings.NewReplacer(",", "", ".", "").Replace(integer)
+
+	if integer == "" && fraction == "" {
+		return "", "", fmt.Errorf("no digits")
+	}
+	for _, part := range []string{integer, fraction} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return "", "", fmt.Errorf("invalid character %q", r)
+			}
+		}
+	}
+	return integer, fraction, nil
+}
+
+// thousandsGroup reports whether the only separator in value, at index sep, can separate thousands
+// rather than decimals: one to three integer digits without a leading zero, followed by exactly
+// three digits. "1.200" can be 1200 while "0.015" and "1.20" keep the dot as decimal separator.
+func thousandsGroup(value string, sep int) bool {
+	return sep >= 1 && sep <= 3 && value[0] != '0' && len(value)-sep-1 == 3
+}
+
+// roundUp reports whether the digits beyond the cent round the magnitude cents up to the next cent
+func roundUp(rest string, cents int64, mode RoundingMode) bool {
+	rest = strings.TrimRight(rest, "0")
+	if rest == "" || mode == RoundDown {
+		return false
+	}
+
+	switch {
+	case rest[0] > '5':
+		return true
+	case rest[0] < '5':
+		return false
+	case len(rest) > 1:
+		// More than half a cent
+		return true
+	}
+
+	// Exactly half a cent
+	if mode == RoundHalfEven {
+		return cents%2 == 1
+	}
+	return true
+}
+
+// padRight appends zeros to s up to length n
+func padRight(s string, n int) string {
+	if len(s) >= n {
+		return s
+	}
+	return s + strings.Repeat("0", n-len(s))
+}
@@ -0,0 +1,203 @@
+package money
+
+import "testing"
+
+func TestParseCentsWithMode(t *testing.T) {
+	tests := []struct {
+		amount string
+		mode   RoundingMode
+		want   int64
+	}{
+		{"0.015", RoundHalfUp, 2},
+		{"0.015", RoundHalfEven, 2},
+		{"0.025", RoundHalfEven, 2},
+		{"0.015", RoundDown, 1},
+		{"19,999", RoundHalfUp, 2000},
+		{"19,994", RoundHalfUp, 1999},
+		{"19.9999", RoundHalfUp, 2000},
+		{"0.0150001", RoundHalfEven, 2},
+		{"0.29", RoundHalfUp, 29}, // float64(0.29)*100 truncates to 28
+		{"1.234.567,89 €", RoundHalfUp, 123456789},
+		{"EUR 98.765.432,10", RoundHalfUp, 9876543210},
+		{"1,234,567.89", RoundHalfUp, 123456789},
+		{"1 234,50", RoundHalfUp, 123450},
+		{"1.200", RoundHalfUp, 120000}, // German thousands without decimals, not 1.20
+		{"1.200,00", RoundHalfUp, 120000},
+		{"1200", RoundHalfUp, 120000},
+		{"12.345", RoundHalfUp, 1234500},
+		{"-1.200", RoundHalfUp, -120000},
+		{"1.20", RoundHalfUp, 120},
+		{"1234.567", RoundHalfUp, 123457},
+		{"7.303,08", RoundHalfUp, 730308},
+		{"-12,345", RoundHalfUp, -1235},
+		{"-12,345", RoundDown, -1234},
+		{"+5", RoundHalfUp, 500},
+		{"119", RoundHalfUp, 11900},
+		{",50", RoundHalfUp, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+"/"+string(tt.mode), func(t *testing.T) {
+			got, err := ParseCentsWithMode(tt.amount, tt.mode)
+			if err != nil {
+				t.Fatalf("ParseCentsWithMode(%q) error = %v", tt.amount, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCentsWithMode(%q) = %d, want %d", tt.amount, got, tt.want)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "€", "abc", "12,34,5x", "1-2", "99999999999999999999"} {
+		if got, err := ParseCentsWithMode(invalid, RoundHalfUp); err == nil {
+			t.Errorf("ParseCentsWithMode(%q) = %d, want error", invalid, got)
+		}
+	}
+}
+
+func TestParseCentsRoundingFromEnv(t *testing.T) {
+	t.Setenv("AMOUNT_ROUNDING", "down")
+	if got, _ := ParseCents("19,999"); got != 1999 {
+		t.Errorf("ParseCents with AMOUNT_ROUNDING=down = %d, want 1999", got)
+	}
+
+	t.Setenv("AMOUNT_ROUNDING", "")
+	if got, _ := ParseCents("19,999"); got != 2000 {
+		t.Errorf("ParseCents with default rounding = %d, want 2000", got)
+	}
+}
+
+func TestParseMoneyLocales(t *testing.T) {
+	tests := []struct {
+		amount string
+		locale Locale
+		want   int64
+	}{
+		// Swiss apostrophe grouping
+		{"1'234.56", LocaleCH, 123456},
+		{"CHF 1'234'567.80", LocaleCH, 123456780},
+		{"Fr. 12’500.00", LocaleCH, 1250000},
+		{"1.200", LocaleCH, 120},
+		{"1,200", LocaleCH, 120},
+		{"1'234.56", LocaleDE, 123456},
+		// Space grouping
+		{"1 234,56", LocaleDE, 123456},
+		{"1 234.56", LocaleCH, 123456},
+		{"12 345 678,90 €", LocaleDE, 1234567890},
+		{"1 234.56", LocaleEN, 123456},
+		// German
+		{"1.234,56", LocaleDE, 123456},
+		{"1.200", LocaleDE, 120000},
+		{"1,200", LocaleDE, 120},
+		{"1.234,56", LocaleEN, 123456},
+		// US/English
+		{"1,234.56", LocaleEN, 123456},
+		{"$1,200", LocaleEN, 120000},
+		{"1.200", LocaleEN, 120},
+		{"-12,345", LocaleEN, -1234500},
+		{"1,234.56", LocaleDE, 123456},
+		// A dot before three digits rounds as decimals outside the German locale
+		{"19.999", LocaleEN, 2000},
+		{"19.994", LocaleEN, 1999},
+		{"19.999", LocaleCH, 2000},
+		{"19.999", LocaleDE, 1999900}, // read as thousands, like "12.345"
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+"/"+string(tt.locale), func(t *testing.T) {
+			got, err := ParseMoney(tt.amount, tt.locale, RoundHalfUp)
+			if err != nil {
+				t.Fatalf("ParseMoney(%q, %s) error = %v", tt.amount, tt.locale, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMoney(%q, %s) = %d, want %d", tt.amount, tt.locale, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCentsLocaleFromEnv(t *testing.T) {
+	t.Setenv("AMOUNT_LOCALE", "ch")
+	if got, _ := ParseCents("1.200"); got != 120 {
+		t.Errorf("ParseCents with AMOUNT_LOCALE=ch = %d, want 120", got)
+	}
+
+	t.Setenv("AMOUNT_LOCALE", "fr")
+	if got, _ := ParseCents("1.200"); got != 120000 {
+		t.Errorf("ParseCents with unknown AMOUNT_LOCALE = %d, want 120000", got)
+	}
+}
+
+func TestFromUnitsNanos(t *testing.T) {
+	tests := []struct {
+		units int64
+		nanos int32
+		want  int64
+	}{
+		{119, 0, 11900},
+		{19, 990000000, 1999},
+		{19, 999000000, 2000},
+		{0, 15000000, 2},
+		{-12, -345000000, -1235},
+	}
+
+	for _, tt := range tests {
+		if got := FromUnitsNanos(tt.units, tt.nanos); got != tt.want {
+			t.Errorf("FromUnitsNanos(%d, %d) = %d, want %d", tt.units, tt.nanos, got, tt.want)
+		}
+	}
+}
+
+func TestParseQuantityInLocale(t *testing.T) {
+	tests := []struct {
+		quantity string
+		locale   Locale
+		want     float64
+	}{
+		{"2", LocaleDE, 2},
+		{"1,5", LocaleDE, 1.5},
+		{"0,125", LocaleDE, 0.125},
+		{"1.000", LocaleDE, 1000},
+		{"1.000,5", LocaleDE, 1000.5},
+		{"-3", LocaleDE, -3},
+		{"1.5", LocaleEN, 1.5},
+		{"1,000", LocaleEN, 1000},
+		{"1,000.25", LocaleEN, 1000.25},
+		{"1.000", LocaleEN, 1},
+		{"1'000", LocaleCH, 1000},
+		{"1'000.5", LocaleCH, 1000.5},
+		{"1.000", LocaleCH, 1},
+		{"1,5", LocaleCH, 1.5},
+		{",5", LocaleDE, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.quantity+"/"+string(tt.locale), func(t *testing.T) {
+			got, err := ParseQuantityInLocale(tt.quantity, tt.locale)
+			if err != nil {
+				t.Fatalf("ParseQuantityInLocale(%q, %s) error = %v", tt.quantity, tt.locale, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseQuantityInLocale(%q, %s) = %v, want %v", tt.quantity, tt.locale, got, tt.want)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "Stk", "1,2,3x", "1-2"} {
+		if got, err := ParseQuantityInLocale(invalid, LocaleDE); err == nil {
+			t.Errorf("ParseQuantityInLocale(%q) = %v, want error", invalid, got)
+		}
+	}
+}
+
+func TestParseQuantityLocaleFromEnv(t *testing.T) {
+	t.Setenv("AMOUNT_LOCALE", "en")
+	if got, _ := ParseQuantity("1,000"); got != 1000 {
+		t.Errorf("ParseQuantity with AMOUNT_LOCALE=en = %v, want 1000", got)
+	}
+
+	t.Setenv("AMOUNT_LOCALE", "")
+	if got, _ := ParseQuantity("1,000"); got != 1 {
+		t.Errorf("ParseQuantity with default locale = %v, want 1", got)
+	}
+}
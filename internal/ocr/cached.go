@@ -0,0 +1,117 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"tools/internal/cache"
+	"tools/internal/logger"
+)
+
+// ocrCacheKind is the cache subdirectory for Vision OCR results
+const ocrCacheKind = "vision-ocr"
+
+// CachedOCRService wraps an OCRService and caches its results by PDF content. Cache errors are
+// logged and never fail the OCR.
+type CachedOCRService struct {
+	service OCRService
+	store   *cache.Store
+	force   bool
+	variant string // Distinguishes results of differently configured services
+	log     zerolog.Logger
+}
+
+// NewCachedOCRService wraps service with store. With force set, cached entries are ignored but
+// fresh results are still written back, replacing stale entries.
+func NewCachedOCRService(service OCRService, store *cache.Store, force bool) OCRService {
+	// Deskewed text differs from Vision's original text order, so it is cached separately
+	variant := ""
+	vision, ok := service.(*GoogleVisionOCRService)
+	if limited, isLimited := service.(*LimitedOCRService); isLimited {
+		vision, ok = limited.service.(*GoogleVisionOCRService)
+	}
+	if ok && vision.deskew {
+		variant = "deskew"
+	}
+
+	return &CachedOCRService{
+		service: service,
+		store:   store,
+		force:   force,
+		variant: variant,
+		log:     logger.WithComponent("ocr-cache"),
+	}
+}
+
+// ProcessPDF extracts the text of all pages, using the cache if possible
+func (c *CachedOCRService) ProcessPDF(ctx context.Context, pdfData io.Reader) (string, error) {
+	result, err := c.ProcessPDFPages(ctx, pdfData, nil)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
+
+// ProcessPDFWithMetadata extracts text and metadata of all pages, using the cache if possible
+func (c *CachedOCRService) ProcessPDFWithMetadata(ctx context.Context, pdfData io.Reader) (*OCRResult, error) {
+	return c.ProcessPDFPages(ctx, pdfData, nil)
+}
+
+// ProcessPDFPages extracts the given pages, caching each page selection separately
+func (c *CachedOCRService) ProcessPDFPages(ctx context.Context, pdfData io.Reader, pages []int32) (*OCRResult, error) {
+	const op = "ProcessPDFPages"
+
+	pdfBytes, err := io.ReadAll(pdfData)
+	if err != nil {
+		return nil, WrapOCRError(op, err, "failed to read PDF data")
+	}
+
+	key := cache.Key(pdfBytes, strings.Trim(c.variant+"-"+pagesVariant(pages), "-"))
+	if !c.force {
+		var result OCRResult
+		found, err := c.store.Load(ocrCacheKind, key, &result)
+		if err != nil {
+			c.log.Warn().Err(err).Msg("Failed to read cached OCR result, processing again")
+		} else if found {
+			c.log.Info().Str("key", key).Msg("Using cached OCR result")
+			return &result, nil
+		}
+	}
+
+	result, err := c.service.ProcessPDFPages(ctx, bytes.NewReader(pdfBytes), pages)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.store.Save(ocrCacheKind, key, result); err != nil {
+		c.log.Warn().Err(err).Msg("Failed to cache OCR result")
+	}
+
+	return result, nil
+}
+
+// Close closes the wrapped service if it holds a client
+func (c *CachedOCRService) Close() error {
+	if closer, ok := c.service.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// pagesVariant encodes a page selection for the cache key, e.g. "p1_3"
+func pagesVariant(pages []int32) string {
+	if len(pages) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(pages))
+	for i, page := range pages {
+		parts[i] = fmt.Sprintf("%d", page)
+	}
+	return "p" + strings.Join(parts, "_")
+}
@@ -3,6 +3,8 @@ package ocr
 import (
 	"errors"
 	"fmt"
+
+	"tools/internal/pdf"
 )
 
 // Common OCR processing errors
@@ -14,6 +16,13 @@ var (
 	// ErrInvalidPDF is returned when the provided data is not a valid PDF document.
 	ErrInvalidPDF = errors.New("invalid or corrupted PDF document")
 
+	// ErrUnsupportedFormat is returned when the document is neither a PDF nor a TIFF.
+	ErrUnsupportedFormat = errors.New("unsupported document format (PDF or TIFF expected)")
+
+	// ErrEncryptedPDF is returned when the PDF is password-protected and no
+	// configured password (PDF_PASSWORDS) opens it.
+	ErrEncryptedPDF = pdf.ErrEncryptedPDF
+
 	// ErrOCRFailed is returned when the Google Cloud Vision API fails to process the document.
 	ErrOCRFailed = errors.New("OCR processing failed")
 
@@ -21,8 +30,8 @@ var (
 	// nor GOOGLE_CREDENTIALS environment variables are configured.
 	ErrMissingCredentials = errors.New("missing Google Cloud credentials: set GOOGLE_APPLICATION_CREDENTIALS or GOOGLE_CREDENTIALS environment variable")
 
-	// ErrTooManyPages is returned when the PDF has too many pages for synchronous processing.
-	// Google Cloud Vision API supports up to 5 pages for synchronous processing.
+	// ErrTooManyPages is returned when the PDF or multipage TIFF has too many pages for synchronous
+	// processing. Google Cloud Vision API supports up to 5 pages for synchronous processing.
 	ErrTooManyPages = errors.New("PDF has too many pages (maximum 5 pages for synchronous processing)")
 
 	// ErrEmptyDocument is returned when the PDF contains no readable text.
@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	vision "cloud.google.com/go/vision/v2/apiv1"
 	"cloud.google.com/go/vision/v2/apiv1/visionpb"
 	"google.golang.org/api/option"
+
+	"tools/internal/docformat"
+	"tools/internal/pdf"
 )
 
 const (
@@ -21,15 +26,40 @@ const (
 	MaxPagesSync = 5
 )
 
+// fileLimit is the size and page limit of synchronous file annotation for one document format
+type fileLimit struct {
+	maxBytes int
+	maxPages int
+}
+
+// fileLimits are the limits of the formats the Vision API annotates as files
+var fileLimits = map[docformat.Format]fileLimit{
+	docformat.PDF:  {maxBytes: MaxFileSizeBytes, maxPages: MaxPagesSync},
+	docformat.TIFF: {maxBytes: MaxFileSizeBytes, maxPages: MaxPagesSync},
+}
+
 // GoogleVisionOCRService implements OCRService using Google Cloud Vision API.
 type GoogleVisionOCRService struct {
 	client *vision.ImageAnnotatorClient
+	deskew bool
+}
+
+// VisionOptions tunes the Vision OCR service
+type VisionOptions struct {
+	// Deskew rebuilds the text of rotated or skewed pages (e.g. photographed receipts) in upright
+	// reading order, using the page orientation detected from the word bounding boxes.
+	Deskew bool
 }
 
 // NewGoogleVisionOCRService creates a new OCR service with credentials from environment.
 // It expects either GOOGLE_APPLICATION_CREDENTIALS path or GOOGLE_CREDENTIALS JSON in env.
 func NewGoogleVisionOCRService(ctx context.Context) (OCRService, error) {
-	const op = "NewGoogleVisionOCRService"
+	return NewGoogleVisionOCRServiceWithOptions(ctx, VisionOptions{})
+}
+
+// NewGoogleVisionOCRServiceWithOptions creates an OCR service like NewGoogleVisionOCRService with explicit options.
+func NewGoogleVisionOCRServiceWithOptions(ctx context.Context, options VisionOptions) (OCRService, error) {
+	const op = "NewGoogleVisionOCRServiceWithOptions"
 
 	var client *vision.ImageAnnotatorClient
 	var err error
@@ -56,6 +86,7 @@ func NewGoogleVisionOCRService(ctx context.Context) (OCRService, error) {
 
 	return &GoogleVisionOCRService{
 		client: client,
+		deskew: options.Deskew,
 	}, nil
 }
 
@@ -77,23 +108,57 @@ func (g *GoogleVisionOCRService) ProcessPDF(ctx context.Context, pdfData io.Read
 
 // ProcessPDFWithMetadata extracts text from a PDF document with additional metadata.
 func (g *GoogleVisionOCRService) ProcessPDFWithMetadata(ctx context.Context, pdfData io.Reader) (*OCRResult, error) {
-	const op = "ProcessPDFWithMetadata"
+	return g.ProcessPDFPages(ctx, pdfData, nil)
+}
+
+// ProcessPDFPages extracts text with metadata from the given 1-based pages only. Besides PDFs it
+// accepts TIFF scans; a multipage TIFF with more pages than the synchronous limit needs a page
+// selection, where a PDF is silently cut off after the first pages by the Vision API.
+func (g *GoogleVisionOCRService) ProcessPDFPages(ctx context.Context, pdfData io.Reader, pages []int32) (*OCRResult, error) {
+	const op = "ProcessPDFPages"
 	startTime := time.Now()
 
-	// Read PDF data
+	// The Vision API rejects page selections above the synchronous limit
+	if len(pages) > MaxPagesSync {
+		return nil, WrapOCRError(op, ErrTooManyPages, fmt.Sprintf("%d pages selected", len(pages)))
+	}
+
+	// Read document data
 	pdfBytes, err := io.ReadAll(pdfData)
 	if err != nil {
 		return nil, WrapOCRError(op, err, "failed to read PDF data")
 	}
 
+	// Only PDF and TIFF are annotated as files
+	format, _ := docformat.Detect(pdfBytes)
+	limit, supported := fileLimits[format]
+	if !supported {
+		return nil, WrapOCRError(op, ErrUnsupportedFormat, "expected a PDF or TIFF document")
+	}
+
 	// Validate file size
-	if len(pdfBytes) > MaxFileSizeBytes {
-		return nil, WrapOCRError(op, ErrPDFTooLarge, fmt.Sprintf("file size: %d bytes", len(pdfBytes)))
+	if len(pdfBytes) > limit.maxBytes {
+		return nil, WrapOCRError(op, ErrPDFTooLarge, fmt.Sprintf("%s file size: %d bytes (limit %d MB)", format, len(pdfBytes), limit.maxBytes/(1024*1024)))
 	}
 
-	// Validate PDF header
-	if len(pdfBytes) < 4 || string(pdfBytes[:4]) != "%PDF" {
-		return nil, WrapOCRError(op, ErrInvalidPDF, "missing PDF header")
+	switch format {
+	case docformat.PDF:
+		// The Vision API cannot read encrypted PDFs
+		pdfBytes, err = pdf.Decrypt(pdfBytes, pdf.PasswordsFromEnv())
+		if err != nil {
+			return nil, WrapOCRError(op, err, "failed to decrypt PDF")
+		}
+	case docformat.TIFF:
+		// Rather than returning the first pages only, a long TIFF needs an explicit page selection
+		if len(pages) == 0 {
+			pageCount, err := docformat.PageCount(pdfBytes, format)
+			if err != nil {
+				return nil, WrapOCRError(op, ErrInvalidPDF, fmt.Sprintf("failed to count TIFF pages: %v", err))
+			}
+			if pageCount > limit.maxPages {
+				return nil, WrapOCRError(op, ErrTooManyPages, fmt.Sprintf("multipage TIFF has %d pages (synchronous limit %d), select pages to process", pageCount, limit.maxPages))
+			}
+		}
 	}
 
 	// Prepare the request
@@ -103,14 +168,14 @@ func (g *GoogleVisionOCRService) ProcessPDFWithMetadata(ctx context.Context, pdf
 				InputConfig: &visionpb.InputConfig{
 					GcsSource: nil, // We're using inline content
 					Content:   pdfBytes,
-					MimeType:  "application/pdf",
+					MimeType:  format.MimeType(),
 				},
 				Features: []*visionpb.Feature{
 					{
 						Type: visionpb.Feature_DOCUMENT_TEXT_DETECTION,
 					},
 				},
-				Pages: nil, // Process all pages
+				Pages: pages, // nil processes all pages
 			},
 		},
 	}
@@ -132,7 +197,7 @@ func (g *GoogleVisionOCRService) ProcessPDFWithMetadata(ctx context.Context, pdf
 	}
 
 	// Process the response
-	result, err := g.processVisionResponse(fileResp)
+	result, err := g.processVisionResponse(fileResp, pages)
 	if err != nil {
 		return nil, WrapOCRError(op, err, "failed to process Vision API response")
 	}
@@ -145,7 +210,8 @@ func (g *GoogleVisionOCRService) ProcessPDFWithMetadata(ctx context.Context, pdf
 }
 
 // processVisionResponse processes the Vision API response and extracts text with metadata.
-func (g *GoogleVisionOCRService) processVisionResponse(fileResp *visionpb.AnnotateFileResponse) (*OCRResult, error) {
+// pages holds the requested page numbers so separators show the original page, not the response index.
+func (g *GoogleVisionOCRService) processVisionResponse(fileResp *visionpb.AnnotateFileResponse, pages []int32) (*OCRResult, error) {
 	if len(fileResp.Responses) == 0 {
 		return nil, ErrEmptyDocument
 	}
@@ -153,7 +219,8 @@ func (g *GoogleVisionOCRService) processVisionResponse(fileResp *visionpb.Annota
 	var allText strings.Builder
 	var confidenceSum float32
 	var confidenceCount int
-	var languageSet = make(map[string]bool)
+	var languageCounts = make(map[string]int)
+	var orientations []PageOrientation
 	pageCount := len(fileResp.Responses)
 
 	// Check page limit
@@ -162,8 +229,13 @@ func (g *GoogleVisionOCRService) processVisionResponse(fileResp *visionpb.Annota
 	}
 
 	for pageIdx, page := range fileResp.Responses {
+		pageNumber := pageIdx + 1
+		if pageIdx < len(pages) {
+			pageNumber = int(pages[pageIdx])
+		}
+
 		if page.Error != nil {
-			return nil, fmt.Errorf("error processing page %d: %s", pageIdx+1, page.Error.Message)
+			return nil, fmt.Errorf("error processing page %d: %s", pageNumber, page.Error.Message)
 		}
 
 		// Extract full text annotation
@@ -171,12 +243,19 @@ func (g *GoogleVisionOCRService) processVisionResponse(fileResp *visionpb.Annota
 			// Add page separator (except for first page)
 			if pageIdx > 0 {
 				allText.WriteString("\n\n--- Page ")
-				allText.WriteString(fmt.Sprintf("%d", pageIdx+1))
+				allText.WriteString(fmt.Sprintf("%d", pageNumber))
 				allText.WriteString(" ---\n\n")
 			}
 
 			// Add text content
-			allText.WriteString(page.FullTextAnnotation.Text)
+			text := page.FullTextAnnotation.Text
+			if g.deskew {
+				if corrected, orientation, ok := deskewPage(page.FullTextAnnotation, pageNumber); ok {
+					text = corrected
+					orientations = append(orientations, orientation)
+				}
+			}
+			allText.WriteString(text)
 
 			// Collect confidence scores from text annotations
 			for _, textAnnotation := range page.TextAnnotations {
@@ -195,7 +274,7 @@ func (g *GoogleVisionOCRService) processVisionResponse(fileResp *visionpb.Annota
 								if symbol.Property != nil && symbol.Property.DetectedLanguages != nil {
 									for _, lang := range symbol.Property.DetectedLanguages {
 										if lang.LanguageCode != "" {
-											languageSet[lang.LanguageCode] = true
+											languageCounts[lang.LanguageCode]++
 										}
 									}
 								}
@@ -213,11 +292,17 @@ func (g *GoogleVisionOCRService) processVisionResponse(fileResp *visionpb.Annota
 		avgConfidence = confidenceSum / float32(confidenceCount)
 	}
 
-	// Convert language set to slice
+	// Order the languages by the number of symbols detected in them, the dominant language first
 	var languages []string
-	for lang := range languageSet {
+	for lang := range languageCounts {
 		languages = append(languages, lang)
 	}
+	sort.Slice(languages, func(i, j int) bool {
+		if languageCounts[languages[i]] != languageCounts[languages[j]] {
+			return languageCounts[languages[i]] > languageCounts[languages[j]]
+		}
+		return languages[i] < languages[j]
+	})
 
 	// Check if we extracted any text
 	extractedText := allText.String()
@@ -230,9 +315,30 @@ func (g *GoogleVisionOCRService) processVisionResponse(fileResp *visionpb.Annota
 		PageCount:     pageCount,
 		Confidence:    avgConfidence,
 		LanguageCodes: languages,
+		Orientations:  orientations,
 	}, nil
 }
 
+// deskewPage rebuilds the text of a rotated or skewed page in upright reading order.
+// ok is false if the page is upright or has no word geometry.
+func deskewPage(annotation *visionpb.TextAnnotation, pageNumber int) (string, PageOrientation, bool) {
+	if len(annotation.Pages) != 1 {
+		return "", PageOrientation{}, false
+	}
+
+	rotation, skew, words := detectOrientation(annotation.Pages[0])
+	if len(words) == 0 || !needsDeskew(rotation, skew) {
+		return "", PageOrientation{}, false
+	}
+
+	text := uprightText(words, float64(rotation)+skew)
+	if strings.TrimSpace(text) == "" {
+		return "", PageOrientation{}, false
+	}
+
+	return text, PageOrientation{Page: pageNumber, Rotation: rotation, Skew: math.Round(skew*10) / 10}, true
+}
+
 // Close closes the underlying Vision client.
 func (g *GoogleVisionOCRService) Close() error {
 	if g.client != nil {
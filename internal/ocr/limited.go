@@ -0,0 +1,88 @@
+package ocr
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+)
+
+// LimitedOCRService wraps an OCRService and bounds the number of requests in flight, so that one
+// Vision client shared by many batch workers does not exceed the API quota. Requests waiting for a
+// slot give up when their context ends.
+type LimitedOCRService struct {
+	service OCRService
+	slots   chan struct{}
+}
+
+// NewLimitedOCRService wraps service so that at most maxConcurrent requests run at a time. A limit
+// below 1 returns service unchanged.
+func NewLimitedOCRService(service OCRService, maxConcurrent int) OCRService {
+	if maxConcurrent < 1 {
+		return service
+	}
+	return &LimitedOCRService{
+		service: service,
+		slots:   make(chan struct{}, maxConcurrent),
+	}
+}
+
+// MaxConcurrentFromEnv returns the limit of concurrent Vision requests set in OCR_MAX_CONCURRENT;
+// zero if unset or invalid, leaving them unlimited
+func MaxConcurrentFromEnv() int {
+	limit, err := strconv.Atoi(os.Getenv("OCR_MAX_CONCURRENT"))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// ProcessPDF extracts the text of all pages once a slot is free
+func (l *LimitedOCRService) ProcessPDF(ctx context.Context, pdfData io.Reader) (string, error) {
+	if err := l.acquire(ctx, "ProcessPDF"); err != nil {
+		return "", err
+	}
+	defer l.release()
+	return l.service.ProcessPDF(ctx, pdfData)
+}
+
+// ProcessPDFWithMetadata extracts text and metadata of all pages once a slot is free
+func (l *LimitedOCRService) ProcessPDFWithMetadata(ctx context.Context, pdfData io.Reader) (*OCRResult, error) {
+	if err := l.acquire(ctx, "ProcessPDFWithMetadata"); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return l.service.ProcessPDFWithMetadata(ctx, pdfData)
+}
+
+// ProcessPDFPages extracts the given pages once a slot is free
+func (l *LimitedOCRService) ProcessPDFPages(ctx context.Context, pdfData io.Reader, pages []int32) (*OCRResult, error) {
+	if err := l.acquire(ctx, "ProcessPDFPages"); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return l.service.ProcessPDFPages(ctx, pdfData, pages)
+}
+
+// Close closes the wrapped service if it holds a client
+func (l *LimitedOCRService) Close() error {
+	if closer, ok := l.service.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// acquire waits for a free slot or the end of ctx
+func (l *LimitedOCRService) acquire(ctx context.Context, op string) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return WrapOCRError(op, ctx.Err(), "gave up waiting for a free OCR slot")
+	}
+}
+
+// release frees the slot taken by acquire
+func (l *LimitedOCRService) release() {
+	<-l.slots
+}
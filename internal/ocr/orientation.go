@@ -0,0 +1,201 @@
+package ocr
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/vision/v2/apiv1/visionpb"
+)
+
+// minSkewDegrees is the skew below which a page is read in Vision's original text order
+const minSkewDegrees = 2.0
+
+// PageOrientation describes how a scanned page was turned relative to upright text
+type PageOrientation struct {
+	Page     int     `json:"page"`     // 1-based page number
+	Rotation int     `json:"rotation"` // Clockwise rotation of the text in degrees: 0, 90, 180 or 270
+	Skew     float64 `json:"skew"`     // Remaining skew in degrees after the rotation
+}
+
+// orientedWord is a detected word with its position in page coordinates
+type orientedWord struct {
+	text     string
+	center   point
+	height   float64
+	baseline float64 // Text direction in degrees, measured clockwise in image coordinates
+}
+
+type point struct {
+	x, y float64
+}
+
+// detectOrientation estimates the text direction of a page from the word bounding boxes. Vision
+// returns the box vertices in reading order (top-left first), so the direction from the first to the
+// second vertex is the baseline of the word, regardless of how the page was scanned.
+func detectOrientation(page *visionpb.Page) (rotation int, skew float64, words []orientedWord) {
+	words = pageWords(page)
+	if len(words) == 0 {
+		return 0, 0, nil
+	}
+
+	// The dominant quarter turn decides the rotation, the median deviation from it the skew
+	quarterCounts := make(map[int]int)
+	for _, word := range words {
+		quarterCounts[nearestQuarterTurn(word.baseline)]++
+	}
+	for quarter, count := range quarterCounts {
+		if count > quarterCounts[rotation] || (count == quarterCounts[rotation] && quarter < rotation) {
+			rotation = quarter
+		}
+	}
+
+	var deviations []float64
+	for _, word := range words {
+		if nearestQuarterTurn(word.baseline) == rotation {
+			deviations = append(deviations, normalizeDegrees(word.baseline-float64(rotation)))
+		}
+	}
+	sort.Float64s(deviations)
+	skew = deviations[len(deviations)/2]
+
+	return rotation, skew, words
+}
+
+// uprightText rebuilds the page text in reading order after turning the words back by angle degrees.
+// Words are grouped into lines by their upright vertical position and sorted left to right.
+func uprightText(words []orientedWord, angle float64) string {
+	if len(words) == 0 {
+		return ""
+	}
+
+	radians := angle * math.Pi / 180
+	cos, sin := math.Cos(radians), math.Sin(radians)
+
+	type uprightWord struct {
+		text string
+		x, y float64
+	}
+	upright := make([]uprightWord, len(words))
+	heights := make([]float64, len(words))
+	for i, word := range words {
+		upright[i] = uprightWord{
+			text: word.text,
+			x:    word.center.x*cos + word.center.y*sin,
+			y:    -word.center.x*sin + word.center.y*cos,
+		}
+		heights[i] = word.height
+	}
+	sort.Float64s(heights)
+	lineTolerance := heights[len(heights)/2] / 2
+
+	sort.SliceStable(upright, func(i, j int) bool { return upright[i].y < upright[j].y })
+
+	var lines [][]uprightWord
+	lineY := math.Inf(-1)
+	for _, word := range upright {
+		if len(lines) == 0 || word.y-lineY > lineTolerance {
+			lines = append(lines, nil)
+			lineY = word.y
+		}
+		lines[len(lines)-1] = append(lines[len(lines)-1], word)
+	}
+
+	var text strings.Builder
+	for i, line := range lines {
+		sort.SliceStable(line, func(a, b int) bool { return line[a].x < line[b].x })
+		if i > 0 {
+			text.WriteString("\n")
+		}
+		for j, word := range line {
+			if j > 0 {
+				text.WriteString(" ")
+			}
+			text.WriteString(word.text)
+		}
+	}
+	text.WriteString("\n")
+
+	return text.String()
+}
+
+// needsDeskew reports whether the page text should be rebuilt instead of using Vision's text order
+func needsDeskew(rotation int, skew float64) bool {
+	return rotation != 0 || math.Abs(skew) >= minSkewDegrees
+}
+
+// pageWords collects the words of a page with their geometry. PDF responses use normalized
+// vertices, which are scaled by the page size so angles are not distorted by the aspect ratio.
+func pageWords(page *visionpb.Page) []orientedWord {
+	width, height := float64(page.Width), float64(page.Height)
+	if width <= 0 || height <= 0 {
+		width, height = 1, 1
+	}
+
+	var words []orientedWord
+	for _, block := range page.Blocks {
+		for _, paragraph := range block.Paragraphs {
+			for _, word := range paragraph.Words {
+				vertices := boxPoints(word.BoundingBox, width, height)
+				if len(vertices) != 4 {
+					continue
+				}
+
+				var text strings.Builder
+				for _, symbol := range word.Symbols {
+					text.WriteString(symbol.Text)
+				}
+				if text.Len() == 0 {
+					continue
+				}
+
+				words = append(words, orientedWord{
+					text: text.String(),
+					center: point{
+						x: (vertices[0].x + vertices[1].x + vertices[2].x + vertices[3].x) / 4,
+						y: (vertices[0].y + vertices[1].y + vertices[2].y + vertices[3].y) / 4,
+					},
+					height:   math.Hypot(vertices[3].x-vertices[0].x, vertices[3].y-vertices[0].y),
+					baseline: math.Atan2(vertices[1].y-vertices[0].y, vertices[1].x-vertices[0].x) * 180 / math.Pi,
+				})
+			}
+		}
+	}
+	return words
+}
+
+// boxPoints returns the four corners of a bounding box in page coordinates
+func boxPoints(box *visionpb.BoundingPoly, width, height float64) []point {
+	if box == nil {
+		return nil
+	}
+
+	var points []point
+	if len(box.Vertices) == 4 {
+		for _, vertex := range box.Vertices {
+			points = append(points, point{x: float64(vertex.X), y: float64(vertex.Y)})
+		}
+		return points
+	}
+	for _, vertex := range box.NormalizedVertices {
+		points = append(points, point{x: float64(vertex.X) * width, y: float64(vertex.Y) * height})
+	}
+	return points
+}
+
+// nearestQuarterTurn rounds an angle to 0, 90, 180 or 270 degrees
+func nearestQuarterTurn(degrees float64) int {
+	quarter := int(math.Round(degrees/90)) * 90
+	return ((quarter % 360) + 360) % 360
+}
+
+// normalizeDegrees maps an angle to the range (-180, 180]
+func normalizeDegrees(degrees float64) float64 {
+	degrees = math.Mod(degrees, 360)
+	if degrees > 180 {
+		degrees -= 360
+	} else if degrees <= -180 {
+		degrees += 360
+	}
+	return degrees
+}
@@ -0,0 +1,172 @@
+package ocr
+
+import (
+	"math"
+	"testing"
+
+	"cloud.google.com/go/vision/v2/apiv1/visionpb"
+)
+
+// layoutWord is a word of an upright test page: its text and the center of its box
+type layoutWord struct {
+	text string
+	x, y float64
+}
+
+// invoiceLayout is an upright page of two lines
+var invoiceLayout = []layoutWord{
+	{"Rechnung", 100, 100}, {"RE-1001", 300, 100},
+	{"Summe", 100, 200}, {"119,00", 250, 200}, {"EUR", 380, 200},
+}
+
+// rotatedPage places the words of an upright layout on a page whose text is turned clockwise by angle
+// degrees. The box vertices start at the top-left corner of the word in reading order, like Vision's.
+func rotatedPage(layout []layoutWord, angle float64) *visionpb.Page {
+	radians := angle * math.Pi / 180
+	cos, sin := math.Cos(radians), math.Sin(radians)
+	rotate := func(x, y float64) (float64, float64) { return x*cos - y*sin, x*sin + y*cos }
+
+	const width, height = 80.0, 20.0
+	paragraph := &visionpb.Paragraph{}
+	for _, word := range layout {
+		corners := [][2]float64{
+			{word.x - width/2, word.y - height/2},
+			{word.x + width/2, word.y - height/2},
+			{word.x + width/2, word.y + height/2},
+			{word.x - width/2, word.y + height/2},
+		}
+		// Shift the page so that all rotated coordinates stay positive
+		box := &visionpb.BoundingPoly{}
+		for _, corner := range corners {
+			x, y := rotate(corner[0], corner[1])
+			box.Vertices = append(box.Vertices, &visionpb.Vertex{X: int32(math.Round(x + 1000)), Y: int32(math.Round(y + 1000))})
+		}
+
+		var symbols []*visionpb.Symbol
+		for _, r := range word.text {
+			symbols = append(symbols, &visionpb.Symbol{Text: string(r)})
+		}
+		paragraph.Words = append(paragraph.Words, &visionpb.Word{BoundingBox: box, Symbols: symbols})
+	}
+	return &visionpb.Page{Width: 2000, Height: 2000, Blocks: []*visionpb.Block{{Paragraphs: []*visionpb.Paragraph{paragraph}}}}
+}
+
+func TestDetectOrientation(t *testing.T) {
+	tests := []struct {
+		name         string
+		angle        float64
+		wantRotation int
+		wantSkew     float64
+	}{
+		{"upright", 0, 0, 0},
+		{"slightly skewed", 3, 0, 3},
+		{"skewed the other way", -4, 0, -4},
+		{"quarter turn", 90, 90, 0},
+		{"upside down", 180, 180, 0},
+		{"three quarter turn", 270, 270, 0},
+		{"quarter turn and skewed", 95, 90, 5},
+		{"upside down and skewed", 178, 180, -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rotation, skew, words := detectOrientation(rotatedPage(invoiceLayout, tt.angle))
+			if len(words) != len(invoiceLayout) {
+				t.Fatalf("found %d words, want %d", len(words), len(invoiceLayout))
+			}
+			if rotation != tt.wantRotation || math.Abs(skew-tt.wantSkew) > 0.5 {
+				t.Errorf("detectOrientation() = %d, %.2f, want %d, %.2f", rotation, skew, tt.wantRotation, tt.wantSkew)
+			}
+
+			// Turning the words back by the detected angle restores the reading order
+			if got, want := uprightText(words, float64(rotation)+skew), "Rechnung RE-1001\nSumme 119,00 EUR\n"; got != want {
+				t.Errorf("uprightText() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDetectOrientationEmptyPage(t *testing.T) {
+	rotation, skew, words := detectOrientation(&visionpb.Page{Width: 100, Height: 100})
+	if rotation != 0 || skew != 0 || words != nil {
+		t.Errorf("detectOrientation() of an empty page = %d, %v, %v", rotation, skew, words)
+	}
+	if got := uprightText(nil, 90); got != "" {
+		t.Errorf("uprightText() without words = %q", got)
+	}
+}
+
+func TestPageWordsNormalizedVertices(t *testing.T) {
+	// A word 0.1 wide and 0.02 high on an A4 page in PDF points, read left to right
+	page := &visionpb.Page{Width: 595, Height: 842, Blocks: []*visionpb.Block{{Paragraphs: []*visionpb.Paragraph{{Words: []*visionpb.Word{
+		{
+			BoundingBox: &visionpb.BoundingPoly{NormalizedVertices: []*visionpb.NormalizedVertex{
+				{X: 0.1, Y: 0.1}, {X: 0.2, Y: 0.1}, {X: 0.2, Y: 0.12}, {X: 0.1, Y: 0.12},
+			}},
+			Symbols: []*visionpb.Symbol{{Text: "M"}, {Text: "w"}, {Text: "S"}, {Text: "t"}},
+		},
+		{BoundingBox: &visionpb.BoundingPoly{Vertices: []*visionpb.Vertex{{X: 1, Y: 1}, {X: 2, Y: 1}}}, Symbols: []*visionpb.Symbol{{Text: "x"}}},
+		{BoundingBox: &visionpb.BoundingPoly{Vertices: []*visionpb.Vertex{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 2}, {X: 1, Y: 2}}}},
+	}}}}}}
+
+	words := pageWords(page)
+	if len(words) != 1 {
+		t.Fatalf("pageWords() = %d words, want 1 (boxes without four corners or text are skipped)", len(words))
+	}
+	word := words[0]
+	if word.text != "MwSt" || math.Abs(word.center.x-89.25) > 0.01 || math.Abs(word.center.y-92.62) > 0.01 {
+		t.Errorf("word = %q at %v", word.text, word.center)
+	}
+	if math.Abs(word.height-16.84) > 0.01 || word.baseline != 0 {
+		t.Errorf("height = %.2f, baseline = %.2f, want 16.84 and 0", word.height, word.baseline)
+	}
+}
+
+func TestNeedsDeskew(t *testing.T) {
+	tests := []struct {
+		rotation int
+		skew     float64
+		want     bool
+	}{
+		{0, 0, false},
+		{0, 1.9, false},
+		{0, -1.9, false},
+		{0, 2, true},
+		{0, -2.5, true},
+		{90, 0, true},
+		{180, 0.5, true},
+	}
+	for _, tt := range tests {
+		if got := needsDeskew(tt.rotation, tt.skew); got != tt.want {
+			t.Errorf("needsDeskew(%d, %v) = %v, want %v", tt.rotation, tt.skew, got, tt.want)
+		}
+	}
+}
+
+func TestNearestQuarterTurn(t *testing.T) {
+	tests := []struct {
+		degrees float64
+		want    int
+	}{
+		{0, 0}, {44, 0}, {46, 90}, {-3, 0}, {-90, 270}, {-135.5, 180}, {179, 180}, {-179, 180}, {269, 270}, {359, 0},
+	}
+	for _, tt := range tests {
+		if got := nearestQuarterTurn(tt.degrees); got != tt.want {
+			t.Errorf("nearestQuarterTurn(%v) = %d, want %d", tt.degrees, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeDegrees(t *testing.T) {
+	tests := []struct {
+		degrees float64
+		want    float64
+	}{
+		{0, 0}, {180, 180}, {-180, 180}, {190, -170}, {-190, 170}, {365, 5}, {-725, -5},
+	}
+	for _, tt := range tests {
+		if got := normalizeDegrees(tt.degrees); got != tt.want {
+			t.Errorf("normalizeDegrees(%v) = %v, want %v", tt.degrees, got, tt.want)
+		}
+	}
+}
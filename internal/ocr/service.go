@@ -12,7 +12,7 @@
 //   - Maximum file size: 20MB for synchronous processing
 //   - Maximum pages: 5 pages for synchronous processing
 //   - For larger documents, consider using asynchronous processing with Cloud Storage
-//   - Supported formats: PDF, TIFF
+//   - Supported formats: PDF, TIFF; a multipage TIFF beyond 5 pages needs a page selection
 //
 // Implementation Details:
 //   - Uses synchronous document text detection for PDFs up to 5 pages
@@ -24,6 +24,7 @@ package ocr
 import (
 	"context"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -36,6 +37,10 @@ type OCRService interface {
 	// ProcessPDFWithMetadata extracts text from a PDF document with additional metadata.
 	// Returns detailed results including confidence scores and processing information.
 	ProcessPDFWithMetadata(ctx context.Context, pdfData io.Reader) (*OCRResult, error)
+
+	// ProcessPDFPages works like ProcessPDFWithMetadata but only processes the given 1-based pages.
+	// A nil or empty slice processes all pages.
+	ProcessPDFPages(ctx context.Context, pdfData io.Reader, pages []int32) (*OCRResult, error)
 }
 
 // OCRResult contains the results of OCR processing with metadata.
@@ -53,9 +58,23 @@ type OCRResult struct {
 	// ProcessedAt is the timestamp when the OCR processing completed.
 	ProcessedAt time.Time `json:"processed_at"`
 
-	// LanguageCodes contains the detected languages in the document.
+	// LanguageCodes contains the detected languages in the document, the most frequent first.
 	LanguageCodes []string `json:"language_codes,omitempty"`
 
 	// ProcessingDuration is how long the OCR processing took.
 	ProcessingDuration time.Duration `json:"processing_duration"`
+
+	// Orientations lists the pages whose text was rebuilt because they were rotated or skewed.
+	// Only set when deskewing is enabled.
+	Orientations []PageOrientation `json:"orientation_corrections,omitempty"`
+}
+
+// DominantLanguage returns the ISO 639-1 code of the language most of the text was detected in,
+// e.g. "de" for "de-DE", or an empty string if no language was detected.
+func (r *OCRResult) DominantLanguage() string {
+	if len(r.LanguageCodes) == 0 {
+		return ""
+	}
+	language, _, _ := strings.Cut(r.LanguageCodes[0], "-")
+	return strings.ToLower(language)
 }
\ No newline at end of file
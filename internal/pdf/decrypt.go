@@ -0,0 +1,84 @@
+// Package pdf prepares PDF documents before they are sent to Document AI or Vision.
+//
+// Google rejects encrypted PDFs with an opaque invalid-document error, so password-protected
+// invoices are decrypted locally first. Passwords come from --pdf-password or PDF_PASSWORDS and
+// are tried in order; PDFs that only carry an owner password (printing/copy restrictions) open
+// without one.
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// ErrEncryptedPDF is returned when a PDF is encrypted and none of the passwords opens it
+var ErrEncryptedPDF = errors.New("PDF is password-protected and no supplied password opens it")
+
+func init() {
+	// Keep pdfcpu from creating a config.yml in the user config directory
+	model.ConfigPath = "disable"
+}
+
+// PasswordsFromEnv returns the comma-separated passwords of PDF_PASSWORDS in order
+func PasswordsFromEnv() []string {
+	var passwords []string
+	for _, password := range strings.Split(os.Getenv("PDF_PASSWORDS"), ",") {
+		if password = strings.TrimSpace(password); password != "" {
+			passwords = append(passwords, password)
+		}
+	}
+	return passwords
+}
+
+// IsEncrypted reports whether the PDF declares an encryption dictionary. It is a cheap check on
+// the raw bytes, so unencrypted PDFs never go through pdfcpu.
+func IsEncrypted(data []byte) bool {
+	return bytes.Contains(data, []byte("/Encrypt"))
+}
+
+// Decrypt returns the PDF without encryption. Unencrypted PDFs are returned unchanged. The empty
+// password is tried first, then passwords in order; ErrEncryptedPDF is returned if none works.
+func Decrypt(data []byte, passwords []string) ([]byte, error) {
+	const op = "Decrypt"
+
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+
+	for _, password := range append([]string{""}, passwords...) {
+		ctx, err := api.ReadContext(bytes.NewReader(data), passwordConfig(password))
+		if errors.Is(err, pdfcpu.ErrWrongPassword) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %v", op, ErrEncryptedPDF, err)
+		}
+		if ctx.Encrypt == nil {
+			// "/Encrypt" only appeared in the content
+			return data, nil
+		}
+
+		var decrypted bytes.Buffer
+		if err := api.Decrypt(bytes.NewReader(data), &decrypted, passwordConfig(password)); err != nil {
+			return nil, fmt.Errorf("%s: %w: %v", op, ErrEncryptedPDF, err)
+		}
+		return decrypted.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("%s: %w (%d passwords tried)", op, ErrEncryptedPDF, len(passwords))
+}
+
+// passwordConfig returns a pdfcpu configuration that tries password as user and owner password
+func passwordConfig(password string) *model.Configuration {
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = password
+	conf.OwnerPW = password
+	return conf
+}
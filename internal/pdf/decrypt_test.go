@@ -0,0 +1,91 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// minimalPDF builds a one-page PDF with a correct cross-reference table
+func minimalPDF() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func encryptedPDF(t *testing.T, userPW, ownerPW string) []byte {
+	t.Helper()
+
+	var encrypted bytes.Buffer
+	conf := model.NewAESConfiguration(userPW, ownerPW, 256)
+	if err := api.Encrypt(bytes.NewReader(minimalPDF()), &encrypted, conf); err != nil {
+		t.Fatalf("failed to encrypt test PDF: %v", err)
+	}
+	return encrypted.Bytes()
+}
+
+func TestDecryptUnencrypted(t *testing.T) {
+	data := minimalPDF()
+	got, err := Decrypt(data, nil)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("expected unencrypted PDF unchanged, got err %v", err)
+	}
+}
+
+func TestDecryptWithPassword(t *testing.T) {
+	data := encryptedPDF(t, "geheim", "owner")
+	if !IsEncrypted(data) {
+		t.Fatal("expected encrypted test PDF")
+	}
+
+	got, err := Decrypt(data, []string{"falsch", "geheim"})
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if IsEncrypted(got) {
+		t.Error("expected decrypted PDF without encryption dictionary")
+	}
+}
+
+func TestDecryptOwnerPasswordOnly(t *testing.T) {
+	data := encryptedPDF(t, "", "owner")
+	if _, err := Decrypt(data, nil); err != nil {
+		t.Fatalf("expected PDF with only an owner password to open without password, got %v", err)
+	}
+}
+
+func TestDecryptWrongPassword(t *testing.T) {
+	data := encryptedPDF(t, "geheim", "owner")
+	if _, err := Decrypt(data, []string{"falsch"}); !errors.Is(err, ErrEncryptedPDF) {
+		t.Fatalf("expected ErrEncryptedPDF, got %v", err)
+	}
+}
+
+func TestPasswordsFromEnv(t *testing.T) {
+	t.Setenv("PDF_PASSWORDS", "eins, zwei,,drei")
+	got := PasswordsFromEnv()
+	if len(got) != 3 || got[0] != "eins" || got[1] != "zwei" || got[2] != "drei" {
+		t.Errorf("PasswordsFromEnv() = %q", got)
+	}
+}
@@ -0,0 +1,20 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// PageCount returns the number of pages of an unencrypted PDF
+func PageCount(data []byte) (int, error) {
+	const op = "PageCount"
+
+	count, err := api.PageCount(bytes.NewReader(data), model.NewDefaultConfiguration())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,17 @@
+package pdf
+
+import "testing"
+
+func TestPageCount(t *testing.T) {
+	count, err := PageCount(minimalPDF())
+	if err != nil {
+		t.Fatalf("PageCount() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("PageCount() = %d, want 1", count)
+	}
+
+	if _, err := PageCount([]byte("%PDF-1.4 truncated")); err == nil {
+		t.Error("PageCount() expected an error for a broken PDF")
+	}
+}
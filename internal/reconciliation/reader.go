@@ -3,53 +3,84 @@ package reconciliation
 import (
 	"context"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
 	"tools/internal/logger"
+	"tools/internal/money"
 	"tools/internal/sheets"
 )
 
 // DataReader handles reading reconciliation data from Google Sheets
 type DataReader struct {
 	sheetsService *sheets.Service
+	bankSheet     string
 	log           zerolog.Logger
 }
 
+// DefaultBankSheet is the tab bank transactions are read from unless another one is given
+const DefaultBankSheet = "Bank"
+
 // NewDataReader creates a new data reader for Google Sheets
 func NewDataReader(sheetsService *sheets.Service) *DataReader {
+	return NewDataReaderWithBankSheet(sheetsService, DefaultBankSheet)
+}
+
+// NewDataReaderWithBankSheet creates a data reader that reads bank transactions from the given tab
+// instead of "Bank", e.g. the tab linked in the sheet URL
+func NewDataReaderWithBankSheet(sheetsService *sheets.Service, bankSheet string) *DataReader {
 	return &DataReader{
 		sheetsService: sheetsService,
+		bankSheet:     bankSheet,
 		log:           logger.WithComponent("reconciliation-reader"),
 	}
 }
 
-// ReadBankTransactions reads bank transactions from the "Bank" sheet
+// ReadBankTransactions reads all bank transactions from the bank sheet, "Bank" by default
 func (dr *DataReader) ReadBankTransactions(ctx context.Context) ([]BankTransaction, error) {
-	const op = "ReadBankTransactions"
-	const sheetName = "Bank"
+	return dr.ReadBankTransactionsInRange(ctx, DateRange{})
+}
+
+// ReadBankTransactionsInRange reads bank transactions from the bank sheet, skipping rows dated
+// outside dateRange before they are parsed
+func (dr *DataReader) ReadBankTransactionsInRange(ctx context.Context, dateRange DateRange) ([]BankTransaction, error) {
+	const op = "ReadBankTransactionsInRange"
+	sheetName := dr.bankSheet
 
-	dr.log.Info().Str("sheet", sheetName).Msg("Reading bank transactions")
+	dr.log.Info().
+		Str("sheet", sheetName).
+		Time("from", dateRange.From).
+		Time("to", dateRange.To).
+		Msg("Reading bank transactions")
 
 	// Read data from Bank sheet
 	// Expected columns: A=Datum, B=Transaktionstyp, C=Beschreibung, D=EREF, E=MREF, 
-	// F=CRED, G=SVWZ, H=Empfänger/Absender, I=BIC, J=IBAN, K=Betrag
-	values, err := dr.sheetsService.ReadRange(ctx, sheetName+"!A:K")
+	// F=CRED, G=SVWZ, H=Empfänger/Absender, I=BIC, J=IBAN, K=Betrag, L=Konto (optional)
+	values, err := dr.sheetsService.ReadRange(ctx, sheetName+"!A:L")
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to read Bank sheet: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to read %s sheet: %w", op, sheetName, err)
 	}
 
 	if len(values) == 0 {
-		return nil, fmt.Errorf("%s: Bank sheet is empty", op)
+		return nil, fmt.Errorf("%s: %s sheet is empty", op, sheetName)
 	}
 
 	// Skip header row and parse data
 	var transactions []BankTransaction
+	outOfRange := 0
 	for i, row := range values[1:] {
 		rowNum := i + 2 // Account for header and 0-based indexing
 
+		// Check the date first so old history is dropped without parsing the whole row;
+		// unparseable dates fall through and are reported by parseBankTransaction
+		if !dateRange.IsZero() {
+			if date, err := dr.parseGermanDate(getString(row, 0)); err == nil && !dateRange.Contains(date) {
+				outOfRange++
+				continue
+			}
+		}
+
 		if len(row) < 11 {
 			dr.log.Warn().
 				Int("row", rowNum).
@@ -73,6 +104,7 @@ func (dr *DataReader) ReadBankTransactions(ctx context.Context) ([]BankTransacti
 	dr.log.Info().
 		Int("total_rows", len(values)-1).
 		Int("parsed_transactions", len(transactions)).
+		Int("out_of_range", outOfRange).
 		Str("sheet", sheetName).
 		Msg("Bank transactions read successfully")
 
@@ -87,8 +119,9 @@ func (dr *DataReader) ReadInvoices(ctx context.Context, sheetName string) ([]Inv
 
 	// Read data from the sheet
 	// Expected columns from DATEV batch processing:
-	// A=Datei, B=Rechnungsnr, C=Datum, D=Lieferant/Kunde, E=Netto, F=MwSt, G=Brutto, H=Währung
-	values, err := dr.sheetsService.ReadRange(ctx, sheetName+"!A:H")
+	// A=Datei, B=Rechnungsnr, C=Datum, D=Lieferant/Kunde, E=Netto, F=MwSt, G=Brutto, H=Währung,
+	// optionally S=Bestellnr, T=Kundenreferenz, V=E-Mail Kunde, W=Ansprechpartner, X=Bezahlt
+	values, err := dr.sheetsService.ReadRange(ctx, sheetName+"!A:X")
 	if err != nil {
 		return nil, fmt.Errorf("%s: failed to read %s sheet: %w", op, sheetName, err)
 	}
@@ -152,7 +185,7 @@ func (dr *DataReader) parseBankTransaction(row []interface{}, rowNum int) (BankT
 
 	// Parse amount (column K - index 10)
 	amountStr := getString(row, 10)
-	amount, err := dr.parseGermanAmount(amountStr)
+	amount, err := dr.parseAmount(amountStr)
 	if err != nil {
 		return BankTransaction{}, fmt.Errorf("%s: invalid amount '%s' in row %d: %w", op, amountStr, rowNum, err)
 	}
@@ -169,6 +202,7 @@ func (dr *DataReader) parseBankTransaction(row []interface{}, rowNum int) (BankT
 		BIC:          getString(row, 8),  // BIC
 		IBAN:         getString(row, 9),  // IBAN
 		Amount:       amount,             // Betrag
+		Account:      getString(row, 11), // Konto (optional)
 	}
 
 	return transaction, nil
@@ -194,7 +228,7 @@ func (dr *DataReader) parseInvoiceRow(row []interface{}, rowNum int, invoiceType
 	vatAmountStr := getString(row, 5)
 	grossAmountStr := getString(row, 6)
 
-	netAmount, err := dr.parseGermanAmount(netAmountStr)
+	netAmount, err := dr.parseAmount(netAmountStr)
 	if err != nil {
 		dr.log.Warn().
 			Str("net_amount_str", netAmountStr).
@@ -203,7 +237,7 @@ func (dr *DataReader) parseInvoiceRow(row []interface{}, rowNum int, invoiceType
 		netAmount = 0
 	}
 
-	vatAmount, err := dr.parseGermanAmount(vatAmountStr)
+	vatAmount, err := dr.parseAmount(vatAmountStr)
 	if err != nil {
 		dr.log.Warn().
 			Str("vat_amount_str", vatAmountStr).
@@ -212,7 +246,7 @@ func (dr *DataReader) parseInvoiceRow(row []interface{}, rowNum int, invoiceType
 		vatAmount = 0
 	}
 
-	grossAmount, err := dr.parseGermanAmount(grossAmountStr)
+	grossAmount, err := dr.parseAmount(grossAmountStr)
 	if err != nil {
 		return InvoiceRow{}, fmt.Errorf("%s: invalid gross amount '%s' in row %d: %w", op, grossAmountStr, rowNum, err)
 	}
@@ -233,9 +267,21 @@ func (dr *DataReader) parseInvoiceRow(row []interface{}, rowNum int, invoiceType
 		VATAmount:     vatAmount,
 		GrossAmount:   grossAmount,
 		Currency:      currency,
+		TaxKey:        getString(row, 10), // Steuerschlüssel
+		Status:        getString(row, 15), // Status
+		PurchaseOrder:     getString(row, 18), // Bestellnr
+		CustomerReference: getString(row, 19), // Kundenreferenz
+		CustomerEmail:     getString(row, 21), // E-Mail Kunde
+		CustomerContact:   getString(row, 22), // Ansprechpartner
+		Paid:              getString(row, 23) != "", // Bezahlt
 		Type:          invoiceType,
 	}
 
+	// Processing timestamp (column Q) lets callers date rows whose invoice date could not be extracted
+	if processedAt, err := time.Parse("02.01.2006 15:04:05", getString(row, 16)); err == nil {
+		invoice.ProcessedAt = processedAt
+	}
+
 	// Set vendor or customer based on type
 	if invoiceType == "PAYABLE" {
 		invoice.Vendor = counterParty
@@ -273,60 +319,19 @@ func (dr *DataReader) parseGermanDate(dateStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
 }
 
-// parseGermanAmount parses German amount format (comma as decimal, negative with minus)
-func (dr *DataReader) parseGermanAmount(amountStr string) (float64, error) {
+// parseAmount parses an amount of the sheet in the format of AMOUNT_LOCALE (German by default,
+// negative with minus), see money.ParseCents. The amount is parsed into cents first, so it carries
+// no float error beyond the final conversion to EUR.
+func (dr *DataReader) parseAmount(amountStr string) (float64, error) {
 	if amountStr == "" {
 		return 0, nil // Empty amount is treated as 0
 	}
 
-	// Clean the amount string
-	cleaned := strings.TrimSpace(amountStr)
-
-	// Handle negative amounts
-	isNegative := strings.HasPrefix(cleaned, "-")
-	if isNegative {
-		cleaned = strings.TrimPrefix(cleaned, "-")
-		cleaned = strings.TrimSpace(cleaned)
-	}
-
-	// Remove currency symbols and spaces
-	cleaned = strings.ReplaceAll(cleaned, " ", "")
-	cleaned = strings.ReplaceAll(cleaned, "€", "")
-	cleaned = strings.ReplaceAll(cleaned, "EUR", "")
-	cleaned = strings.ReplaceAll(cleaned, "USD", "")
-
-	// Handle German number format
-	// German format: thousands separator = dot, decimal separator = comma
-	// Examples: "1.234,56" = 1234.56, "1234,56" = 1234.56, "1234" = 1234
-	if strings.Contains(cleaned, ",") {
-		// Check if we have both dot and comma (full German format: 1.234,56)
-		if strings.Contains(cleaned, ".") && strings.Contains(cleaned, ",") {
-			// Remove thousands separators (dots)
-			cleaned = strings.ReplaceAll(cleaned, ".", "")
-			// Replace decimal separator (comma) with dot
-			cleaned = strings.ReplaceAll(cleaned, ",", ".")
-		} else {
-			// Only comma present - likely decimal separator
-			parts := strings.Split(cleaned, ",")
-			if len(parts) == 2 && len(parts[1]) <= 2 {
-				// Replace comma with dot for decimal
-				cleaned = strings.ReplaceAll(cleaned, ",", ".")
-			}
-		}
-	}
-
-	// Parse the cleaned amount
-	amount, err := strconv.ParseFloat(cleaned, 64)
+	cents, err := money.ParseCents(amountStr)
 	if err != nil {
-		return 0, fmt.Errorf("unable to parse amount: %s (cleaned: %s)", amountStr, cleaned)
+		return 0, err
 	}
-
-	// Apply negative sign if present
-	if isNegative {
-		amount = -amount
-	}
-
-	return amount, nil
+	return float64(cents) / 100, nil
 }
 
 // getString safely extracts a string value from a row slice
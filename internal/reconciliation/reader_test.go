@@ -0,0 +1,151 @@
+package reconciliation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tools/internal/sheets"
+	"tools/internal/sheets/sheetstest"
+	"tools/pkg/models"
+)
+
+func TestReadBankTransactions(t *testing.T) {
+	backend := sheetstest.NewMemoryBackend()
+	backend.SetTab("Bank", [][]interface{}{
+		{"Datum", "Transaktionstyp", "Beschreibung", "EREF", "MREF", "CRED", "SVWZ", "Empfänger/Absender", "BIC", "IBAN", "Betrag"},
+		{"15.03.2024", "Überweisung", "RE-1001", "", "", "", "RE-1001 Muster", "Muster GmbH", "COBADEFFXXX", "DE89370400440532013000", "-1.190,00"},
+		{"kein Datum", "Gutschrift", "", "", "", "", "", "", "", "", "50,00"},
+		{"16.03.2024", "Gutschrift"},
+	})
+
+	reader := NewDataReader(sheets.NewServiceWithBackend(backend))
+	transactions, err := reader.ReadBankTransactions(context.Background())
+	if err != nil {
+		t.Fatalf("ReadBankTransactions: %v", err)
+	}
+
+	if len(transactions) != 1 {
+		t.Fatalf("expected 1 valid transaction, got %d", len(transactions))
+	}
+	tx := transactions[0]
+	if tx.Amount != -1190 || tx.CounterParty != "Muster GmbH" || !tx.Date.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected transaction: %+v", tx)
+	}
+}
+
+func TestReadBankTransactionsAccount(t *testing.T) {
+	backend := sheetstest.NewMemoryBackend()
+	backend.SetTab("Bank", [][]interface{}{
+		{"Datum", "Transaktionstyp", "Beschreibung", "EREF", "MREF", "CRED", "SVWZ", "Empfänger/Absender", "BIC", "IBAN", "Betrag", "Konto"},
+		{"15.03.2024", "Gutschrift", "", "", "", "", "RE-1001", "Kunde AG", "", "", "119,00", "DE89370400440532013000"},
+		{"16.03.2024", "Überweisung", "", "", "", "", "", "Muster GmbH", "", "", "-50,00"},
+	})
+
+	reader := NewDataReader(sheets.NewServiceWithBackend(backend))
+	transactions, err := reader.ReadBankTransactions(context.Background())
+	if err != nil {
+		t.Fatalf("ReadBankTransactions: %v", err)
+	}
+
+	if len(transactions) != 2 || transactions[0].Account != "DE89370400440532013000" || transactions[1].Account != "" {
+		t.Errorf("unexpected accounts: %+v", transactions)
+	}
+}
+
+func TestReadBankTransactionsFromLinkedSheet(t *testing.T) {
+	backend := sheetstest.NewMemoryBackend()
+	backend.SetTab("Bank 2025", [][]interface{}{
+		{"Datum", "Transaktionstyp", "Beschreibung", "EREF", "MREF", "CRED", "SVWZ", "Empfänger/Absender", "BIC", "IBAN", "Betrag"},
+		{"15.03.2025", "Gutschrift", "", "", "", "", "RE-2001", "Kunde AG", "", "", "119,00"},
+	})
+
+	reader := NewDataReaderWithBankSheet(sheets.NewServiceWithBackend(backend), "Bank 2025")
+	transactions, err := reader.ReadBankTransactions(context.Background())
+	if err != nil {
+		t.Fatalf("ReadBankTransactions: %v", err)
+	}
+	if len(transactions) != 1 || transactions[0].CounterParty != "Kunde AG" {
+		t.Errorf("unexpected transactions: %+v", transactions)
+	}
+}
+
+func TestReadBankTransactionsInRange(t *testing.T) {
+	backend := sheetstest.NewMemoryBackend()
+	backend.SetTab("Bank", [][]interface{}{
+		{"Datum", "Transaktionstyp", "Beschreibung", "EREF", "MREF", "CRED", "SVWZ", "Empfänger/Absender", "BIC", "IBAN", "Betrag"},
+		{"31.12.2021", "Überweisung", "", "", "", "", "", "Alt GmbH", "", "", "-10,00"},
+		{"01.02.2024", "Überweisung", "", "", "", "", "", "Grenze GmbH", "", "", "-20,00"},
+		{"15.03.2024", "Gutschrift", "", "", "", "", "", "Kunde AG", "", "", "30,00"},
+		{"01.04.2024", "Gutschrift", "", "", "", "", "", "Später AG", "", "", "40,00"},
+	})
+
+	reader := NewDataReader(sheets.NewServiceWithBackend(backend))
+	transactions, err := reader.ReadBankTransactionsInRange(context.Background(), DateRange{
+		From: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 3, 31, 18, 30, 0, 0, time.Local),
+	})
+	if err != nil {
+		t.Fatalf("ReadBankTransactionsInRange: %v", err)
+	}
+
+	if len(transactions) != 2 {
+		t.Fatalf("expected 2 transactions within range, got %+v", transactions)
+	}
+	if transactions[0].CounterParty != "Grenze GmbH" || transactions[1].CounterParty != "Kunde AG" {
+		t.Errorf("unexpected transactions: %+v", transactions)
+	}
+}
+
+func TestReadInvoicesFromBatchOutput(t *testing.T) {
+	ctx := context.Background()
+	backend := sheetstest.NewMemoryBackend()
+	service := sheets.NewServiceWithBackend(backend)
+
+	// Invoices written by datev-batch must be readable by reconcile
+	err := service.WriteBatchResults(ctx, []sheets.BatchResult{{
+		Filename: "ausgang.pdf",
+		Invoice: &models.Invoice{
+			InvoiceNumber: "AR-2024-7",
+			Type:          "RECEIVABLE",
+			Customer:      "Kunde AG",
+			IssueDate:     time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+			NetAmount:     123456,
+			VATAmount:     23457,
+			GrossAmount:   146913,
+			Currency:      "EUR",
+			PurchaseOrder: "PO-88",
+			CustomerEmail: "buchhaltung@kunde.example",
+			IsPaid:        true,
+		},
+		Status: "SUCCESS",
+	}}, "Debitoren")
+	if err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+
+	invoices, err := NewDataReader(service).ReadInvoices(ctx, "Debitoren")
+	if err != nil {
+		t.Fatalf("ReadInvoices: %v", err)
+	}
+
+	if len(invoices) != 1 {
+		t.Fatalf("expected 1 invoice, got %d", len(invoices))
+	}
+	inv := invoices[0]
+	if inv.InvoiceNumber != "AR-2024-7" || inv.Customer != "Kunde AG" || inv.Type != "RECEIVABLE" {
+		t.Errorf("unexpected invoice identity: %+v", inv)
+	}
+	if inv.GrossAmount != 1469.13 || inv.NetAmount != 1234.56 {
+		t.Errorf("unexpected amounts: net=%v gross=%v", inv.NetAmount, inv.GrossAmount)
+	}
+	if inv.PurchaseOrder != "PO-88" || inv.CustomerReference != "" {
+		t.Errorf("unexpected references: po=%q ref=%q", inv.PurchaseOrder, inv.CustomerReference)
+	}
+	if inv.CustomerEmail != "buchhaltung@kunde.example" || inv.CustomerContact != "" {
+		t.Errorf("unexpected contact: email=%q contact=%q", inv.CustomerEmail, inv.CustomerContact)
+	}
+	if !inv.Paid {
+		t.Errorf("expected invoice marked paid in the sheet to be read as paid")
+	}
+}
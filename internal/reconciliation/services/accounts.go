@@ -0,0 +1,82 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"tools/internal/reconciliation"
+)
+
+// AccountMapping maps invoice types (PAYABLE, RECEIVABLE) or counterparties (the invoice's vendor or
+// customer) to our own bank accounts their payments are expected on. Keys are compared ignoring case;
+// a counterparty entry takes precedence over the entry for its invoice type.
+type AccountMapping map[string][]string
+
+// ParseAccountMapping parses a mapping such as
+// "RECEIVABLE=DE89370400440532013000;PAYABLE=DE02120300000000202051,Tagesgeld;Muster GmbH=DE12500105170648489890".
+// Entries are separated by semicolons, accounts by commas. An empty value yields an empty mapping.
+func ParseAccountMapping(value string) (AccountMapping, error) {
+	mapping := AccountMapping{}
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		key, accounts, found := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid account mapping %q (use TYPE=IBAN,IBAN;...)", entry)
+		}
+		for _, account := range strings.Split(accounts, ",") {
+			if account = strings.TrimSpace(account); account != "" {
+				mapping[key] = append(mapping[key], account)
+			}
+		}
+		if len(mapping[key]) == 0 {
+			return nil, fmt.Errorf("account mapping %q lists no accounts", entry)
+		}
+	}
+	return mapping, nil
+}
+
+// accountsFor returns the accounts a payment of the invoice may be booked on, or nil if any account
+// is acceptable
+func (m AccountMapping) accountsFor(invoice reconciliation.InvoiceRow) []string {
+	counterparty := invoice.Vendor
+	if invoice.Type == "RECEIVABLE" {
+		counterparty = invoice.Customer
+	}
+
+	var byType []string
+	for key, accounts := range m {
+		if counterparty != "" && strings.EqualFold(key, strings.TrimSpace(counterparty)) {
+			return accounts
+		}
+		if strings.EqualFold(key, invoice.Type) {
+			byType = accounts
+		}
+	}
+	return byType
+}
+
+// allows reports whether a payment of the invoice may appear on the given account. Transactions
+// without an account, as in sheets without the Konto column, are always allowed.
+func (m AccountMapping) allows(invoice reconciliation.InvoiceRow, account string) bool {
+	if account == "" {
+		return true
+	}
+	allowed := m.accountsFor(invoice)
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, candidate := range allowed {
+		if normalizeAccount(candidate) == normalizeAccount(account) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAccount makes IBANs and account names comparable regardless of spacing and case
+func normalizeAccount(account string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(account), ""))
+}
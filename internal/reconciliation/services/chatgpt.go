@@ -11,6 +11,8 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/sashabaranov/go-openai"
+	"tools/internal/fx"
+	"tools/internal/llm"
 	"tools/internal/logger"
 	"tools/internal/reconciliation"
 )
@@ -22,13 +24,34 @@ type ReconciliationService interface {
 
 // ReconciliationResult contains the results of a reconciliation process
 type ReconciliationResult struct {
-	MatchedInvoices        map[string]string                    // Invoice ID -> Transaction ID
-	UnmatchedInvoices      []reconciliation.InvoiceRow          // Invoices that couldn't be matched
-	UnmatchedTransactions  []reconciliation.BankTransaction     // Transactions that couldn't be matched
-	TotalInvoices          int                                  // Total number of invoices processed
-	TotalTransactions      int                                  // Total number of transactions processed
-	MatchedCount           int                                  // Number of successful matches
-	ProcessingTime         time.Duration                        // Time taken for reconciliation
+	MatchedInvoices       map[string]string                `json:"matched_invoices"`       // Invoice ID -> Transaction ID
+	Matches               []Match                          `json:"matches"`                // Details of every match, in invoice order
+	UnmatchedInvoices     []reconciliation.InvoiceRow      `json:"unmatched_invoices"`     // Invoices that couldn't be matched
+	UnmatchedTransactions []reconciliation.BankTransaction `json:"unmatched_transactions"` // Transactions that couldn't be matched
+	TotalInvoices         int                              `json:"total_invoices"`         // Total number of invoices processed
+	TotalTransactions     int                              `json:"total_transactions"`     // Total number of transactions processed
+	MatchedCount          int                              `json:"matched_count"`          // Number of successful matches
+	RejectedCount         int                              `json:"rejected_count"`         // ChatGPT matches rejected for low confidence
+	RuleMatchedCount      int                              `json:"rule_matched_count"`     // Matches accepted by rule without asking ChatGPT
+	ChatGPTRequests       int                              `json:"chatgpt_requests"`       // Number of invoices sent to ChatGPT
+	ProcessingTime        time.Duration                    `json:"processing_time_ns"`     // Time taken for reconciliation
+}
+
+// Match methods recorded in Match.Method
+const (
+	MatchMethodRule    = "rule"
+	MatchMethodChatGPT = "chatgpt"
+)
+
+// Match describes one accepted invoice-transaction pair for review
+type Match struct {
+	InvoiceID     string                         `json:"invoice_id"`
+	TransactionID string                         `json:"transaction_id"`
+	Invoice       reconciliation.InvoiceRow      `json:"invoice"`
+	Transaction   reconciliation.BankTransaction `json:"transaction"`
+	Confidence    float64                        `json:"confidence"`     // ChatGPT confidence, or the candidate score capped at 1 for rule matches
+	Reason        string                         `json:"reason"`
+	Method        string                         `json:"method"`         // MatchMethodRule or MatchMethodChatGPT
 }
 
 // MatchResult represents the result of matching a single invoice
@@ -41,14 +64,42 @@ type MatchResult struct {
 
 // ChatGPTReconciliationService implements ReconciliationService using ChatGPT for matching
 type ChatGPTReconciliationService struct {
-	openaiClient *openai.Client
+	openaiClient llm.LLMClient
+	options      MatchOptions
 	log          zerolog.Logger
 }
 
-// NewChatGPTReconciliationService creates a new ChatGPT-based reconciliation service
-func NewChatGPTReconciliationService(openaiClient *openai.Client) *ChatGPTReconciliationService {
+// NewChatGPTReconciliationService creates a new ChatGPT-based reconciliation service with the default match options
+func NewChatGPTReconciliationService(openaiClient llm.LLMClient) *ChatGPTReconciliationService {
+	return NewChatGPTReconciliationServiceWithOptions(openaiClient, DefaultMatchOptions())
+}
+
+// NewChatGPTReconciliationServiceWithOptions creates a reconciliation service with custom match options.
+// openaiClient may be nil in MatchModeRules since ChatGPT is never called.
+func NewChatGPTReconciliationServiceWithOptions(openaiClient llm.LLMClient, options MatchOptions) *ChatGPTReconciliationService {
+	defaults := DefaultMatchOptions()
+	if options.Mode == "" {
+		options.Mode = defaults.Mode
+	}
+	if options.MaxCandidates <= 0 {
+		options.MaxCandidates = defaults.MaxCandidates
+	}
+	if options.AutoAcceptScore <= 0 {
+		options.AutoAcceptScore = defaults.AutoAcceptScore
+	}
+	if options.MinConfidence <= 0 {
+		options.MinConfidence = defaults.MinConfidence
+	}
+	if options.BankCurrency == "" {
+		options.BankCurrency = defaults.BankCurrency
+	}
+	if options.FXTolerance <= 0 {
+		options.FXTolerance = defaults.FXTolerance
+	}
+
 	return &ChatGPTReconciliationService{
 		openaiClient: openaiClient,
+		options:      options,
 		log:          logger.WithComponent("reconciliation-chatgpt"),
 	}
 }
@@ -62,6 +113,9 @@ func (s *ChatGPTReconciliationService) ReconcileAll(ctx context.Context, invoice
 		Int("invoices", len(invoices)).
 		Int("transactions", len(transactions)).
 		Str("cutoff_date", cutoffDate.Format("2006-01-02")).
+		Str("mode", string(s.options.Mode)).
+		Int("max_candidates", s.options.MaxCandidates).
+		Bool("deterministic", s.options.Deterministic).
 		Msg("Starting ChatGPT-based reconciliation")
 
 	result := &ReconciliationResult{
@@ -85,6 +139,11 @@ func (s *ChatGPTReconciliationService) ReconcileAll(ctx context.Context, invoice
 
 	// Process each invoice individually
 	for i, invoice := range invoices {
+		// Stop promptly on timeout or Ctrl-C instead of marking the remaining invoices unmatched
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%s: reconciliation canceled after %d of %d invoices: %w", op, i, len(invoices), err)
+		}
+
 		s.log.Debug().
 			Int("invoice_index", i).
 			Str("invoice_number", invoice.InvoiceNumber).
@@ -112,9 +171,59 @@ func (s *ChatGPTReconciliationService) ReconcileAll(ctx context.Context, invoice
 			Int("candidates", len(candidates)).
 			Msgf("Processing invoice %s: Found %d candidate transactions", invoice.InvoiceNumber, len(candidates))
 
+		// Try the deterministic rules first so unambiguous invoices don't cost a ChatGPT call
+		if s.options.Mode != MatchModeAI || s.options.Deterministic {
+			if index := selectByRules(candidates, s.options.AutoAcceptScore); index >= 0 {
+				candidate := candidates[index]
+				invoiceID, transactionID := s.generateInvoiceID(invoice), s.generateTransactionID(candidate.Transaction)
+				result.MatchedInvoices[invoiceID] = transactionID
+				result.Matches = append(result.Matches, Match{
+					InvoiceID:     invoiceID,
+					TransactionID: transactionID,
+					Invoice:       invoice,
+					Transaction:   candidate.Transaction,
+					Confidence:    math.Min(candidate.Score, 1),
+					Reason:        ruleMatchReason(candidate),
+					Method:        MatchMethodRule,
+				})
+				result.MatchedCount++
+				result.RuleMatchedCount++
+				usedTransactionIndices[candidate.OriginalIndex] = true
+
+				s.log.Info().
+					Str("invoice_number", invoice.InvoiceNumber).
+					Str("counterparty", invoice.GetCounterParty()).
+					Float64("invoice_amount", invoice.GrossAmount).
+					Float64("transaction_amount", candidate.Transaction.Amount).
+					Time("transaction_date", candidate.Transaction.Date).
+					Float64("score", candidate.Score).
+					Bool("reference_match", candidate.ReferenceMatch).
+					Float64("invoice_number_score", candidate.InvoiceNumberScore).
+					Msgf("Rule matched invoice %s with transaction from %s (score: %.2f)",
+						invoice.InvoiceNumber,
+						candidate.Transaction.Date.Format("02.01.2006"),
+						candidate.Score)
+				continue
+			}
+
+			if s.options.Mode == MatchModeRules {
+				s.log.Info().
+					Str("invoice_number", invoice.InvoiceNumber).
+					Int("candidates", len(candidates)).
+					Float64("best_score", candidates[0].Score).
+					Msg("No unambiguous candidate, leaving invoice unmatched in rules mode")
+				result.UnmatchedInvoices = append(result.UnmatchedInvoices, invoice)
+				continue
+			}
+		}
+
 		// Use ChatGPT to match this invoice with candidates
+		result.ChatGPTRequests++
 		matchResult, err := s.matchInvoiceWithChatGPT(ctx, invoice, candidates)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("%s: reconciliation canceled after %d of %d invoices: %w", op, i, len(invoices), ctx.Err())
+			}
 			s.log.Warn().
 				Err(err).
 				Str("invoice_number", invoice.InvoiceNumber).
@@ -123,7 +232,26 @@ func (s *ChatGPTReconciliationService) ReconcileAll(ctx context.Context, invoice
 			continue
 		}
 
-		if matchResult.Matched && matchResult.TransactionIndex >= 0 && matchResult.TransactionIndex < len(candidates) {
+		validIndex := matchResult.Matched && matchResult.TransactionIndex >= 0 && matchResult.TransactionIndex < len(candidates)
+		if validIndex && matchResult.Confidence < s.options.MinConfidence {
+			// Leave the transaction available for other invoices instead of consuming it with a guess
+			rejected := candidates[matchResult.TransactionIndex].Transaction
+			result.RejectedCount++
+			result.UnmatchedInvoices = append(result.UnmatchedInvoices, invoice)
+			s.log.Warn().
+				Str("invoice_number", invoice.InvoiceNumber).
+				Str("counterparty", invoice.GetCounterParty()).
+				Float64("transaction_amount", rejected.Amount).
+				Time("transaction_date", rejected.Date).
+				Float64("confidence", matchResult.Confidence).
+				Float64("min_confidence", s.options.MinConfidence).
+				Str("reason", matchResult.Reason).
+				Msgf("Rejected ChatGPT match for invoice %s (confidence %.2f < %.2f)",
+					invoice.InvoiceNumber, matchResult.Confidence, s.options.MinConfidence)
+			continue
+		}
+
+		if validIndex {
 			// Get the actual transaction from the candidates
 			matchedTransaction := candidates[matchResult.TransactionIndex].Transaction
 			actualIndex := candidates[matchResult.TransactionIndex].OriginalIndex
@@ -133,6 +261,15 @@ func (s *ChatGPTReconciliationService) ReconcileAll(ctx context.Context, invoice
 			transactionID := s.generateTransactionID(matchedTransaction)
 
 			result.MatchedInvoices[invoiceID] = transactionID
+			result.Matches = append(result.Matches, Match{
+				InvoiceID:     invoiceID,
+				TransactionID: transactionID,
+				Invoice:       invoice,
+				Transaction:   matchedTransaction,
+				Confidence:    matchResult.Confidence,
+				Reason:        matchResult.Reason,
+				Method:        MatchMethodChatGPT,
+			})
 			result.MatchedCount++
 			usedTransactionIndices[actualIndex] = true
 
@@ -169,6 +306,9 @@ func (s *ChatGPTReconciliationService) ReconcileAll(ctx context.Context, invoice
 	s.log.Info().
 		Int("total_invoices", result.TotalInvoices).
 		Int("matched_count", result.MatchedCount).
+		Int("rule_matched_count", result.RuleMatchedCount).
+		Int("chatgpt_requests", result.ChatGPTRequests).
+		Int("rejected_low_confidence", result.RejectedCount).
 		Int("unmatched_invoices", len(result.UnmatchedInvoices)).
 		Int("unmatched_transactions", len(result.UnmatchedTransactions)).
 		Dur("processing_time", result.ProcessingTime).
@@ -179,10 +319,14 @@ func (s *ChatGPTReconciliationService) ReconcileAll(ctx context.Context, invoice
 
 // TransactionCandidate represents a transaction candidate with its original index and scoring
 type TransactionCandidate struct {
-	Transaction   reconciliation.BankTransaction
-	OriginalIndex int
-	Score         float64 // Higher score = better match (amount precision + date proximity)
-	DaysDiff      int     // Days difference between invoice and transaction
+	Transaction        reconciliation.BankTransaction
+	OriginalIndex      int
+	Score              float64 // Higher score = better match (amount precision + date proximity)
+	DaysDiff           int     // Days difference between invoice and transaction
+	ReferenceMatch     bool    // Remittance information quotes the invoice's PO number or customer reference
+	NameSimilarity     float64 // Similarity of the transaction's counterparty to the invoice's, 0 to 1
+	InvoiceNumberScore float64 // How clearly SVWZ or EREF quote the invoice number, 0 to 1 (see invoiceNumberSimilarity)
+	ExpectedAmount     float64 // Invoice gross amount converted into the bank currency, 0 if no conversion was needed
 }
 
 // filterTransactionsByCutoff filters transactions to only include those before the cutoff date
@@ -212,14 +356,48 @@ func (s *ChatGPTReconciliationService) findCandidateTransactions(invoice reconci
 		Time("invoice_date", invoice.Date).
 		Msg("Searching for candidate transactions with intelligent filtering")
 
+	// Invoices in a foreign currency are compared after conversion at each transaction's date
+	foreign := invoice.Currency != "" && !fx.SameCurrency(invoice.Currency, s.options.BankCurrency)
+	if foreign && s.options.Rates == nil {
+		s.log.Warn().
+			Str("invoice_number", invoice.InvoiceNumber).
+			Str("invoice_currency", invoice.Currency).
+			Str("bank_currency", s.options.BankCurrency).
+			Msg("No exchange rates configured (FX_RATES_FILE), skipping foreign-currency invoice")
+		return nil
+	}
+
 	for i, transaction := range transactions {
 		// Skip already matched transactions to avoid double-matching
 		if usedIndices[i] {
 			continue
 		}
+
+		expectedCents, toleranceCents := invoiceAmountCents, tolerance
+		var expectedAmount float64
+		if foreign {
+			converted, err := s.options.Rates.Convert(invoice.GrossAmount, invoice.Currency, s.options.BankCurrency, transaction.Date)
+			if err != nil {
+				s.log.Debug().Err(err).
+					Str("invoice_number", invoice.InvoiceNumber).
+					Time("transaction_date", transaction.Date).
+					Msg("Skipping transaction without exchange rate")
+				continue
+			}
+			expectedAmount = math.Round(converted*100) / 100
+			expectedCents = int64(math.Round(converted * 100))
+			toleranceCents = int64(math.Round(math.Abs(converted) * s.options.FXTolerance * 100))
+		}
 		
+		if !s.options.Accounts.allows(invoice, transaction.Account) {
+			continue
+		}
+
 		// Convert transaction amount to cents for precise comparison
 		transactionAmountCents := int64(math.Round(transaction.Amount * 100))
+		if s.options.InvertedSign {
+			transactionAmountCents = -transactionAmountCents
+		}
 		
 		// Determine expected transaction direction based on invoice type
 		var isAmountMatch bool
@@ -228,17 +406,17 @@ func (s *ChatGPTReconciliationService) findCandidateTransactions(invoice reconci
 		if invoice.Type == "PAYABLE" {
 			// For payables, we expect negative bank amounts (outgoing payments)
 			// Consider that payables are negative in bank
-			expectedAmountCents := -invoiceAmountCents
+			expectedAmountCents := -expectedCents
 			if transactionAmountCents < 0 {
 				amountDiff = int64(math.Abs(float64(transactionAmountCents - expectedAmountCents)))
-				isAmountMatch = amountDiff <= tolerance
+				isAmountMatch = amountDiff <= toleranceCents
 			}
 		} else if invoice.Type == "RECEIVABLE" {
 			// For receivables, we expect positive bank amounts (incoming payments)
-			expectedAmountCents := invoiceAmountCents
+			expectedAmountCents := expectedCents
 			if transactionAmountCents > 0 {
 				amountDiff = int64(math.Abs(float64(transactionAmountCents - expectedAmountCents)))
-				isAmountMatch = amountDiff <= tolerance
+				isAmountMatch = amountDiff <= toleranceCents
 			}
 		}
 		
@@ -247,7 +425,7 @@ func (s *ChatGPTReconciliationService) findCandidateTransactions(invoice reconci
 			daysDiff := int(math.Abs(transaction.Date.Sub(invoice.Date).Hours() / 24))
 			
 			// Calculate score: amount precision (90%) + date proximity (10%)
-			amountPrecision := 1.0 - (float64(amountDiff) / float64(tolerance))
+			amountPrecision := 1.0 - (float64(amountDiff) / float64(toleranceCents))
 			if amountPrecision < 0 {
 				amountPrecision = 0
 			}
@@ -262,12 +440,25 @@ func (s *ChatGPTReconciliationService) findCandidateTransactions(invoice reconci
 			}
 			
 			score := amountPrecision*0.9 + dateScore*0.1
+
+			// A PO number or customer reference quoted in the payment is the strongest hint there is
+			referenceMatch := matchesInvoiceReference(invoice, transaction)
+			if referenceMatch {
+				score += 1.0
+			}
+			// So is the invoice number, even if OCR or the bank changed its shape
+			invoiceNumberScore := invoiceNumberSimilarity(invoice.InvoiceNumber, transaction)
+			score += invoiceNumberScore
 			
 			candidate := TransactionCandidate{
-				Transaction:   transaction,
-				OriginalIndex: i,
-				Score:         score,
-				DaysDiff:      daysDiff,
+				Transaction:        transaction,
+				OriginalIndex:      i,
+				Score:              score,
+				DaysDiff:           daysDiff,
+				ReferenceMatch:     referenceMatch,
+				NameSimilarity:     counterpartySimilarity(invoice, transaction),
+				InvoiceNumberScore: invoiceNumberScore,
+				ExpectedAmount:     expectedAmount,
 			}
 			
 			candidates = append(candidates, candidate)
@@ -280,22 +471,34 @@ func (s *ChatGPTReconciliationService) findCandidateTransactions(invoice reconci
 				Float64("score", score).
 				Float64("amount_precision", amountPrecision).
 				Float64("date_score", dateScore).
+				Bool("reference_match", referenceMatch).
+				Float64("invoice_number_score", invoiceNumberScore).
+				Float64("name_similarity", candidate.NameSimilarity).
+				Float64("expected_amount", expectedAmount).
 				Msg("Added candidate transaction with scoring")
 		}
 	}
 	
-	// Sort candidates by score (highest first) and limit to max 10
+	// Sort candidates by score (highest first) and limit to the configured maximum. Ties are broken by
+	// transaction date and then bank sheet order so identical inputs always yield the same candidate list.
 	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].Score > candidates[j].Score
+		a, b := candidates[i], candidates[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Transaction.Date.Equal(b.Transaction.Date) {
+			return a.Transaction.Date.Before(b.Transaction.Date)
+		}
+		return a.OriginalIndex < b.OriginalIndex
 	})
 	
-	// Limit to top 10 candidates per invoice
-	maxCandidates := 10
+	// Limit to top candidates per invoice
+	maxCandidates := s.options.MaxCandidates
 	if len(candidates) > maxCandidates {
 		s.log.Debug().
 			Int("total_candidates", len(candidates)).
 			Int("kept_candidates", maxCandidates).
-			Msg("Limiting candidates to top 10 by score")
+			Msgf("Limiting candidates to top %d by score", maxCandidates)
 		candidates = candidates[:maxCandidates]
 	}
 	
@@ -329,6 +532,8 @@ func (s *ChatGPTReconciliationService) matchInvoiceWithChatGPT(ctx context.Conte
 		"mwst":           invoice.VATAmount,
 		"brutto":         invoice.GrossAmount,
 		"waehrung":       invoice.Currency,
+		"bestellnummer":  invoice.PurchaseOrder,
+		"kundenreferenz": invoice.CustomerReference,
 		"typ":            invoice.Type,
 	}, "", "  ")
 	if err != nil {
@@ -338,7 +543,7 @@ func (s *ChatGPTReconciliationService) matchInvoiceWithChatGPT(ctx context.Conte
 	// Prepare candidates data for prompt
 	var candidatesData []map[string]interface{}
 	for _, candidate := range candidates {
-		candidatesData = append(candidatesData, map[string]interface{}{
+		data := map[string]interface{}{
 			"datum":               candidate.Transaction.Date.Format("02.01.2006"),
 			"transaktionstyp":     candidate.Transaction.Type,
 			"beschreibung":        candidate.Transaction.Description,
@@ -349,7 +554,20 @@ func (s *ChatGPTReconciliationService) matchInvoiceWithChatGPT(ctx context.Conte
 			"mref":                candidate.Transaction.MREF,
 			"iban":                candidate.Transaction.IBAN,
 			"bic":                 candidate.Transaction.BIC,
-		})
+		}
+		data["rechnungsnummer_aehnlichkeit"] = candidate.InvoiceNumberScore
+		if !s.options.OmitNameSimilarity {
+			data["namensaehnlichkeit"] = candidate.NameSimilarity
+		}
+		if candidate.ExpectedAmount != 0 {
+			data["rechnungsbetrag_umgerechnet"] = candidate.ExpectedAmount
+		}
+		candidatesData = append(candidatesData, data)
+	}
+
+	nameCriterion := "3. Stimmt der Empfänger/Absender mit dem Lieferanten/Kunden überein?"
+	if !s.options.OmitNameSimilarity {
+		nameCriterion += "\n   \"namensaehnlichkeit\" ist die vorab berechnete Ähnlichkeit von Empfänger/Absender (bzw. Beschreibung) und Lieferant/Kunde\n   (1.0 = gleicher Name ohne Rechtsform, 0.9 = ein Name ist im anderen enthalten, unter 0.5 = kaum Ähnlichkeit)."
 	}
 	
 	candidatesJSON, err := json.MarshalIndent(candidatesData, "", "  ")
@@ -368,9 +586,14 @@ MÖGLICHE TRANSAKTIONEN:
 
 Analysiere folgende Kriterien:
 1. Stimmt der Betrag überein (mit kleiner Toleranz für Rundungsfehler)?
+   Bei Fremdwährungsrechnungen ist "rechnungsbetrag_umgerechnet" der Bruttobetrag zum EZB-Referenzkurs
+   am Buchungstag in Kontowährung; Abweichungen von wenigen Prozent durch Bankkurs und Gebühren sind normal.
 2. Passt das Datum zusammen (Rechnung vor oder am Tag der Transaktion)?
-3. Stimmt der Empfänger/Absender mit dem Lieferanten/Kunden überein?
+%s
 4. Gibt der Verwendungszweck Hinweise auf die Rechnung?
+5. Enthalten Verwendungszweck, EREF oder Beschreibung die Rechnungsnummer, Bestellnummer oder Kundenreferenz?
+   "rechnungsnummer_aehnlichkeit" ist vorab berechnet: 1.0 = Verwendungszweck oder EREF enthalten die Rechnungsnummer,
+   0.8 = gleiche Ziffernfolge in anderer Schreibweise (z.B. "RE240042" für "RG 2024-00042"), 0 = nicht gefunden.
 
 Antworte nur mit JSON im folgenden Format:
 {
@@ -380,15 +603,14 @@ Antworte nur mit JSON im folgenden Format:
   "reason": "Betrag und Lieferant stimmen überein"
 }
 
-Wenn keine Transaktion passt, setze "matched": false und "transaction_index": -1.`, string(invoiceJSON), string(candidatesJSON))
+Wenn keine Transaktion passt, setze "matched": false und "transaction_index": -1.`, string(invoiceJSON), string(candidatesJSON), nameCriterion)
 
 	s.log.Debug().
 		Str("invoice_number", invoice.InvoiceNumber).
 		Int("candidates_count", len(candidates)).
 		Msg("Sending invoice matching request to ChatGPT")
 
-	// Send request to ChatGPT
-	resp, err := s.openaiClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+	request := openai.ChatCompletionRequest{
 		Model: openai.GPT4oMini,
 		Messages: []openai.ChatCompletionMessage{
 			{
@@ -398,7 +620,16 @@ Wenn keine Transaktion passt, setze "matched": false und "transaction_index": -1
 		},
 		Temperature: 0.1,
 		MaxTokens:   1000,
-	})
+	}
+	if s.options.Deterministic {
+		// A zero temperature would be dropped by omitempty and fall back to the API default of 1
+		seed := s.options.Seed
+		request.Temperature = math.SmallestNonzeroFloat32
+		request.Seed = &seed
+	}
+
+	// Send request to ChatGPT
+	resp, err := s.openaiClient.CreateChatCompletion(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("%s: ChatGPT request failed: %w", op, err)
 	}
@@ -411,19 +642,9 @@ Wenn keine Transaktion passt, setze "matched": false und "transaction_index": -1
 
 	// Parse the JSON response
 	var matchResult MatchResult
-	cleanedResponse := strings.TrimSpace(response)
-	
 	// Handle case where ChatGPT returns response wrapped in markdown code blocks
-	if strings.HasPrefix(cleanedResponse, "```json") {
-		cleanedResponse = strings.TrimPrefix(cleanedResponse, "```json")
-		cleanedResponse = strings.TrimSuffix(cleanedResponse, "```")
-		cleanedResponse = strings.TrimSpace(cleanedResponse)
-	} else if strings.HasPrefix(cleanedResponse, "```") {
-		cleanedResponse = strings.TrimPrefix(cleanedResponse, "```")
-		cleanedResponse = strings.TrimSuffix(cleanedResponse, "```")
-		cleanedResponse = strings.TrimSpace(cleanedResponse)
-	}
-	
+	cleanedResponse := llm.ExtractJSON(response)
+
 	if err := json.Unmarshal([]byte(cleanedResponse), &matchResult); err != nil {
 		s.log.Warn().
 			Err(err).
@@ -454,5 +675,28 @@ func (s *ChatGPTReconciliationService) generateInvoiceID(invoice reconciliation.
 
 // generateTransactionID creates a unique identifier for a transaction
 func (s *ChatGPTReconciliationService) generateTransactionID(transaction reconciliation.BankTransaction) string {
+	return transactionID(transaction)
+}
+
+// transactionID identifies a transaction by date, amount and counterparty
+func transactionID(transaction reconciliation.BankTransaction) string {
 	return fmt.Sprintf("TXN_%s_%.2f_%s", transaction.Date.Format("20060102"), transaction.Amount, transaction.CounterParty)
-}
\ No newline at end of file
+}
+
+// matchesInvoiceReference reports whether the invoice's PO number or customer reference appears in the
+// transaction's remittance information. Whitespace and case are ignored since banks often reformat them.
+func matchesInvoiceReference(invoice reconciliation.InvoiceRow, transaction reconciliation.BankTransaction) bool {
+	text := normalizeReference(transaction.SVWZ + " " + transaction.EREF + " " + transaction.Description)
+	for _, ref := range []string{invoice.PurchaseOrder, invoice.CustomerReference} {
+		// Very short references like "1" or "AB" would match almost any transaction
+		if ref := normalizeReference(ref); len(ref) >= 4 && strings.Contains(text, ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeReference lowercases a reference and strips whitespace
+func normalizeReference(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+	"math"
+)
+
+// PaymentTypes looks up the invoice type confirmed by the bank transaction a saved reconciliation run
+// matched to an invoice: an outgoing payment confirms a PAYABLE, an incoming one a RECEIVABLE. For
+// credit notes, with a negative gross amount, the money moves the other way.
+type PaymentTypes struct {
+	byNumber map[string][]paymentType
+}
+
+// paymentType is the type one match confirms for the invoice with the given gross amount
+type paymentType struct {
+	grossCents  int64
+	invoiceType string
+	evidence    string
+}
+
+// NewPaymentTypes indexes the matches of a result file written by WriteResultFile by invoice number
+func NewPaymentTypes(file *ResultFile) *PaymentTypes {
+	types := &PaymentTypes{byNumber: make(map[string][]paymentType)}
+	for _, match := range file.Result.Matches {
+		amount := match.Transaction.Amount
+		if file.InvertedSign {
+			amount = -amount
+		}
+		if amount == 0 || match.Invoice.InvoiceNumber == "" {
+			continue
+		}
+
+		invoiceType, direction := "RECEIVABLE", "eingehende"
+		if amount < 0 {
+			invoiceType, direction = "PAYABLE", "ausgehende"
+		}
+		grossCents := int64(math.Round(match.Invoice.GrossAmount * 100))
+		if grossCents < 0 {
+			invoiceType = oppositeType(invoiceType)
+		}
+
+		key := normalizeReference(match.Invoice.InvoiceNumber)
+		types.byNumber[key] = append(types.byNumber[key], paymentType{
+			grossCents:  grossCents,
+			invoiceType: invoiceType,
+			evidence: fmt.Sprintf("%s Zahlung %.2f EUR vom %s (%s)", direction, math.Abs(match.Transaction.Amount),
+				match.Transaction.Date.Format("02.01.2006"), match.Transaction.CounterParty),
+		})
+	}
+	return types
+}
+
+// PaymentType returns the type confirmed by the payment matched to the invoice with the given number
+// and gross amount in cents, and a description of that payment. ok is false if no match exists or
+// the matches of invoices with this number and amount disagree.
+func (p *PaymentTypes) PaymentType(invoiceNumber string, grossAmount int64) (invoiceType, evidence string, ok bool) {
+	for _, candidate := range p.byNumber[normalizeReference(invoiceNumber)] {
+		// The sheet rounds to cents; anything further off is a different invoice with the same number
+		if diff := candidate.grossCents - grossAmount; diff < -1 || diff > 1 {
+			continue
+		}
+		if invoiceType != "" && candidate.invoiceType != invoiceType {
+			return "", "", false
+		}
+		invoiceType, evidence = candidate.invoiceType, candidate.evidence
+	}
+	return invoiceType, evidence, invoiceType != ""
+}
+
+// oppositeType returns RECEIVABLE for PAYABLE and the other way round
+func oppositeType(invoiceType string) string {
+	if invoiceType == "PAYABLE" {
+		return "RECEIVABLE"
+	}
+	return "PAYABLE"
+}
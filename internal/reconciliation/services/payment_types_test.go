@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+
+	"tools/internal/reconciliation"
+)
+
+func TestPaymentTypes(t *testing.T) {
+	file := &ResultFile{Result: &ReconciliationResult{Matches: []Match{
+		{
+			Invoice:     reconciliation.InvoiceRow{InvoiceNumber: "R-1", GrossAmount: 119, Type: "PAYABLE"},
+			Transaction: reconciliation.BankTransaction{Date: day(5), CounterParty: "Muster GmbH", Amount: -119},
+		},
+		{
+			Invoice:     reconciliation.InvoiceRow{InvoiceNumber: "2024-17", GrossAmount: 59.50, Type: "RECEIVABLE"},
+			Transaction: reconciliation.BankTransaction{Date: day(9), CounterParty: "Kunde AG", Amount: 59.50},
+		},
+		{
+			// Credit note of a vendor: the money comes back
+			Invoice:     reconciliation.InvoiceRow{InvoiceNumber: "GS-3", GrossAmount: -23.80, Type: "PAYABLE"},
+			Transaction: reconciliation.BankTransaction{Date: day(12), CounterParty: "Muster GmbH", Amount: 23.80},
+		},
+	}}}
+	types := NewPaymentTypes(file)
+
+	tests := []struct {
+		number string
+		gross  int64
+		want   string
+	}{
+		{"R-1", 11900, "PAYABLE"},
+		{"r-1", 11900, "PAYABLE"},
+		{"2024-17", 5950, "RECEIVABLE"},
+		{"GS-3", -2380, "PAYABLE"},
+		{"R-1", 50000, ""}, // Same number, different invoice
+		{"R-9", 11900, ""},
+	}
+	for _, tt := range tests {
+		got, evidence, ok := types.PaymentType(tt.number, tt.gross)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("PaymentType(%q, %d) = %q, %v, want %q", tt.number, tt.gross, got, ok, tt.want)
+		}
+		if ok && evidence == "" {
+			t.Errorf("PaymentType(%q, %d) describes no payment", tt.number, tt.gross)
+		}
+	}
+
+	// With the inverted sign convention the same export shows the opposite directions
+	file.InvertedSign = true
+	if got, _, _ := NewPaymentTypes(file).PaymentType("R-1", 11900); got != "RECEIVABLE" {
+		t.Errorf("inverted PaymentType(R-1) = %q, want RECEIVABLE", got)
+	}
+}
+
+func TestPaymentTypesAmbiguous(t *testing.T) {
+	file := &ResultFile{Result: &ReconciliationResult{Matches: []Match{
+		{
+			Invoice:     reconciliation.InvoiceRow{InvoiceNumber: "1001", GrossAmount: 100},
+			Transaction: reconciliation.BankTransaction{Date: day(3), Amount: -100},
+		},
+		{
+			Invoice:     reconciliation.InvoiceRow{InvoiceNumber: "1001", GrossAmount: 100},
+			Transaction: reconciliation.BankTransaction{Date: day(4), Amount: 100},
+		},
+	}}}
+
+	if got, _, ok := NewPaymentTypes(file).PaymentType("1001", 10000); ok {
+		t.Errorf("PaymentType of contradicting matches = %q, want none", got)
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ProposalFile is the reviewable form of a reconciliation run written by reconcile --review.
+// Reviewers set Approved on the proposals they accept (or delete the others) before
+// reconcile --apply writes the approved matches to the sheet.
+type ProposalFile struct {
+	GeneratedAt           time.Time  `json:"generated_at"`
+	CutoffDate            string     `json:"cutoff_date"` // YYYY-MM-DD
+	Proposals             []Proposal `json:"proposals"`
+	UnmatchedInvoices     int        `json:"unmatched_invoices"`
+	UnmatchedTransactions int        `json:"unmatched_transactions"`
+}
+
+// Proposal is one suggested invoice-transaction pair
+type Proposal struct {
+	Approved bool `json:"approved"`
+
+	InvoiceID     string  `json:"invoice_id"`
+	InvoiceNumber string  `json:"invoice_number"`
+	InvoiceType   string  `json:"invoice_type"` // PAYABLE or RECEIVABLE
+	InvoiceDate   string  `json:"invoice_date"` // YYYY-MM-DD
+	Counterparty  string  `json:"counterparty"`
+	GrossAmount   float64 `json:"gross_amount"`
+
+	TransactionID           string  `json:"transaction_id"`
+	TransactionDate         string  `json:"transaction_date"` // YYYY-MM-DD
+	TransactionAmount       float64 `json:"transaction_amount"`
+	TransactionCounterparty string  `json:"transaction_counterparty"`
+	RemittanceInfo          string  `json:"remittance_info,omitempty"` // Verwendungszweck
+
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason"`
+	Method     string  `json:"method"` // rule or chatgpt
+}
+
+// NewProposalFile converts the matches of a reconciliation run into unapproved proposals
+func NewProposalFile(result *ReconciliationResult, cutoffDate time.Time) *ProposalFile {
+	file := &ProposalFile{
+		GeneratedAt:           time.Now(),
+		CutoffDate:            cutoffDate.Format("2006-01-02"),
+		Proposals:             []Proposal{},
+		UnmatchedInvoices:     len(result.UnmatchedInvoices),
+		UnmatchedTransactions: len(result.UnmatchedTransactions),
+	}
+
+	for _, match := range result.Matches {
+		file.Proposals = append(file.Proposals, Proposal{
+			InvoiceID:               match.InvoiceID,
+			InvoiceNumber:           match.Invoice.InvoiceNumber,
+			InvoiceType:             match.Invoice.Type,
+			InvoiceDate:             formatProposalDate(match.Invoice.Date),
+			Counterparty:            match.Invoice.GetCounterParty(),
+			GrossAmount:             match.Invoice.GrossAmount,
+			TransactionID:           match.TransactionID,
+			TransactionDate:         formatProposalDate(match.Transaction.Date),
+			TransactionAmount:       match.Transaction.Amount,
+			TransactionCounterparty: match.Transaction.CounterParty,
+			RemittanceInfo:          match.Transaction.SVWZ,
+			Confidence:              match.Confidence,
+			Reason:                  match.Reason,
+			Method:                  match.Method,
+		})
+	}
+
+	return file
+}
+
+// formatProposalDate formats a date for the proposal file, empty if unknown
+func formatProposalDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// WriteProposalFile writes the proposals as indented JSON. The file is replaced atomically so an
+// interrupted run never leaves a half-written review file behind.
+func WriteProposalFile(path string, file *ProposalFile) error {
+	const op = "WriteProposalFile"
+
+	if err := writeJSONFile(path, file); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// writeJSONFile writes v as indented JSON via a temp file in the same directory, so readers never
+// see a partially written file
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// ReadProposalFile reads a proposal file written by WriteProposalFile, possibly edited by hand
+func ReadProposalFile(path string) (*ProposalFile, error) {
+	const op = "ReadProposalFile"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read proposals: %w", op, err)
+	}
+
+	var file ProposalFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse %s: %w", op, path, err)
+	}
+
+	return &file, nil
+}
+
+// Approved returns the approved proposals. Approving two proposals for the same invoice or the same
+// transaction is rejected, since each can only be matched once.
+func (f *ProposalFile) Approved() ([]Proposal, error) {
+	const op = "Approved"
+
+	var approved []Proposal
+	invoices := make(map[string]bool)
+	transactions := make(map[string]bool)
+	for _, proposal := range f.Proposals {
+		if !proposal.Approved {
+			continue
+		}
+		if proposal.InvoiceID == "" || proposal.TransactionID == "" {
+			return nil, fmt.Errorf("%s: approved proposal for invoice %q lacks invoice_id or transaction_id", op, proposal.InvoiceNumber)
+		}
+		if invoices[proposal.InvoiceID] {
+			return nil, fmt.Errorf("%s: invoice %s is approved more than once", op, proposal.InvoiceID)
+		}
+		if transactions[proposal.TransactionID] {
+			return nil, fmt.Errorf("%s: transaction %s is approved for more than one invoice", op, proposal.TransactionID)
+		}
+		invoices[proposal.InvoiceID] = true
+		transactions[proposal.TransactionID] = true
+		approved = append(approved, proposal)
+	}
+
+	return approved, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tools/internal/reconciliation"
+)
+
+func TestProposalFileRoundTrip(t *testing.T) {
+	result := &ReconciliationResult{
+		Matches: []Match{
+			{
+				InvoiceID:     "PAYABLE_R-1_20240601",
+				TransactionID: "TXN_20240605_-119.00_Muster GmbH",
+				Invoice:       reconciliation.InvoiceRow{InvoiceNumber: "R-1", Date: day(1), Vendor: "Muster GmbH", GrossAmount: 119, Type: "PAYABLE"},
+				Transaction:   reconciliation.BankTransaction{Date: day(5), CounterParty: "Muster GmbH", Amount: -119},
+				Confidence:    0.97,
+				Method:        MatchMethodRule,
+			},
+		},
+		UnmatchedInvoices: []reconciliation.InvoiceRow{{InvoiceNumber: "R-2"}},
+	}
+
+	path := filepath.Join(t.TempDir(), "proposals.json")
+	if err := WriteProposalFile(path, NewProposalFile(result, day(30))); err != nil {
+		t.Fatalf("WriteProposalFile failed: %v", err)
+	}
+
+	file, err := ReadProposalFile(path)
+	if err != nil {
+		t.Fatalf("ReadProposalFile failed: %v", err)
+	}
+	if len(file.Proposals) != 1 || file.UnmatchedInvoices != 1 || file.CutoffDate != "2024-06-30" {
+		t.Fatalf("unexpected proposal file: %+v", file)
+	}
+	proposal := file.Proposals[0]
+	if proposal.Approved || proposal.Counterparty != "Muster GmbH" || proposal.TransactionDate != "2024-06-05" {
+		t.Errorf("unexpected proposal: %+v", proposal)
+	}
+
+	if approved, err := file.Approved(); err != nil || len(approved) != 0 {
+		t.Errorf("expected nothing approved before review, got %d, %v", len(approved), err)
+	}
+	file.Proposals[0].Approved = true
+	if approved, err := file.Approved(); err != nil || len(approved) != 1 {
+		t.Errorf("expected one approved proposal, got %d, %v", len(approved), err)
+	}
+}
+
+func TestProposalFileRejectsDoubleApproval(t *testing.T) {
+	file := &ProposalFile{Proposals: []Proposal{
+		{Approved: true, InvoiceID: "A", TransactionID: "TXN_1"},
+		{Approved: true, InvoiceID: "B", TransactionID: "TXN_1"},
+	}}
+	if _, err := file.Approved(); err == nil {
+		t.Error("expected error when one transaction is approved for two invoices")
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"tools/internal/reconciliation"
+)
+
+// ResultFile is a saved reconciliation run written by reconcile --save-result and read back by
+// reconcile --continue to re-attempt only the invoices that were left unmatched
+type ResultFile struct {
+	GeneratedAt  time.Time             `json:"generated_at"`
+	CutoffDate   string                `json:"cutoff_date"`             // YYYY-MM-DD
+	InvertedSign bool                  `json:"inverted_sign,omitempty"` // The bank export shows outgoing payments as positive amounts
+	Result       *ReconciliationResult `json:"result"`
+}
+
+// WriteResultFile saves a reconciliation result as indented JSON, replacing path atomically.
+// invertedSign records the sign convention of the bank transactions for readers of their direction.
+func WriteResultFile(path string, result *ReconciliationResult, cutoffDate time.Time, invertedSign bool) error {
+	const op = "WriteResultFile"
+
+	file := &ResultFile{
+		GeneratedAt:  time.Now(),
+		CutoffDate:   cutoffDate.Format("2006-01-02"),
+		InvertedSign: invertedSign,
+		Result:       result,
+	}
+	if err := writeJSONFile(path, file); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ReadResultFile reads a result file written by WriteResultFile
+func ReadResultFile(path string) (*ResultFile, error) {
+	const op = "ReadResultFile"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read result file: %w", op, err)
+	}
+
+	var file ResultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse %s: %w", op, path, err)
+	}
+	if file.Result == nil {
+		return nil, fmt.Errorf("%s: %s contains no reconciliation result", op, path)
+	}
+	if file.Result.MatchedInvoices == nil {
+		file.Result.MatchedInvoices = make(map[string]string)
+	}
+
+	return &file, nil
+}
+
+// ContinueReconciliation re-runs matching for the invoices prior left unmatched. Transactions already
+// matched in prior are excluded, so newly imported bank data is matched against the open invoices
+// only. The result merges prior's matches with the new ones.
+func ContinueReconciliation(ctx context.Context, service ReconciliationService, prior *ReconciliationResult, transactions []reconciliation.BankTransaction, cutoffDate time.Time) (*ReconciliationResult, error) {
+	const op = "ContinueReconciliation"
+
+	used := make(map[string]bool)
+	for _, transactionID := range prior.MatchedInvoices {
+		used[transactionID] = true
+	}
+
+	var open []reconciliation.BankTransaction
+	for _, transaction := range transactions {
+		if !used[transactionID(transaction)] {
+			open = append(open, transaction)
+		}
+	}
+
+	next, err := service.ReconcileAll(ctx, prior.UnmatchedInvoices, open, cutoffDate)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return mergeResults(prior, next), nil
+}
+
+// mergeResults combines a prior run with a run over its unmatched invoices. Totals describe the
+// whole invoice set of the prior run; the unmatched lists and per-run counters come from next.
+func mergeResults(prior, next *ReconciliationResult) *ReconciliationResult {
+	merged := &ReconciliationResult{
+		MatchedInvoices:       make(map[string]string, len(prior.MatchedInvoices)+len(next.MatchedInvoices)),
+		Matches:               append(append([]Match{}, prior.Matches...), next.Matches...),
+		UnmatchedInvoices:     next.UnmatchedInvoices,
+		UnmatchedTransactions: next.UnmatchedTransactions,
+		TotalInvoices:         prior.TotalInvoices,
+		TotalTransactions:     len(prior.MatchedInvoices) + next.TotalTransactions,
+		MatchedCount:          prior.MatchedCount + next.MatchedCount,
+		RejectedCount:         next.RejectedCount,
+		RuleMatchedCount:      prior.RuleMatchedCount + next.RuleMatchedCount,
+		ChatGPTRequests:       next.ChatGPTRequests,
+		ProcessingTime:        next.ProcessingTime,
+	}
+	for invoiceID, transactionID := range prior.MatchedInvoices {
+		merged.MatchedInvoices[invoiceID] = transactionID
+	}
+	for invoiceID, transactionID := range next.MatchedInvoices {
+		merged.MatchedInvoices[invoiceID] = transactionID
+	}
+	return merged
+}
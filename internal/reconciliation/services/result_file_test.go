@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"tools/internal/reconciliation"
+)
+
+func TestContinueReconciliationFromResultFile(t *testing.T) {
+	invoices := []reconciliation.InvoiceRow{
+		{InvoiceNumber: "R-1", Date: day(1), Vendor: "Muster GmbH", GrossAmount: 119, Type: "PAYABLE"},
+		{InvoiceNumber: "R-2", Date: day(2), Vendor: "Spät KG", GrossAmount: 59.50, Type: "PAYABLE"},
+	}
+	firstImport := []reconciliation.BankTransaction{
+		{Date: day(5), CounterParty: "Muster GmbH", Amount: -119},
+	}
+
+	svc := NewChatGPTReconciliationServiceWithOptions(nil, MatchOptions{Mode: MatchModeRules})
+	first, err := svc.ReconcileAll(context.Background(), invoices, firstImport, day(30))
+	if err != nil {
+		t.Fatalf("ReconcileAll failed: %v", err)
+	}
+	if first.MatchedCount != 1 || len(first.UnmatchedInvoices) != 1 {
+		t.Fatalf("first run: matched %d, unmatched %d", first.MatchedCount, len(first.UnmatchedInvoices))
+	}
+
+	path := filepath.Join(t.TempDir(), "result.json")
+	if err := WriteResultFile(path, first, day(30), false); err != nil {
+		t.Fatalf("WriteResultFile failed: %v", err)
+	}
+	prior, err := ReadResultFile(path)
+	if err != nil {
+		t.Fatalf("ReadResultFile failed: %v", err)
+	}
+	if prior.CutoffDate != "2024-06-30" || len(prior.Result.Matches) != 1 || !prior.Result.UnmatchedInvoices[0].Date.Equal(day(2)) {
+		t.Fatalf("result did not round-trip: %+v", prior.Result)
+	}
+
+	// The second import repeats the already matched payment and adds the missing one
+	secondImport := append(firstImport, reconciliation.BankTransaction{Date: day(9), CounterParty: "Spät KG", Amount: -59.50})
+	merged, err := ContinueReconciliation(context.Background(), svc, prior.Result, secondImport, day(30))
+	if err != nil {
+		t.Fatalf("ContinueReconciliation failed: %v", err)
+	}
+
+	if merged.MatchedCount != 2 || len(merged.Matches) != 2 || len(merged.MatchedInvoices) != 2 {
+		t.Errorf("merged matches = %d (%d details, %d ids), want 2", merged.MatchedCount, len(merged.Matches), len(merged.MatchedInvoices))
+	}
+	if len(merged.UnmatchedInvoices) != 0 || len(merged.UnmatchedTransactions) != 0 {
+		t.Errorf("expected nothing left open, got %d invoices, %d transactions", len(merged.UnmatchedInvoices), len(merged.UnmatchedTransactions))
+	}
+	if merged.TotalInvoices != 2 || merged.Matches[1].Invoice.InvoiceNumber != "R-2" {
+		t.Errorf("unexpected merged result: %+v", merged)
+	}
+}
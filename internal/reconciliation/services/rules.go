@@ -0,0 +1,145 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"tools/internal/fx"
+)
+
+// MatchMode selects how invoices are matched with their candidate transactions
+type MatchMode string
+
+const (
+	// MatchModeAI sends every invoice with candidates to ChatGPT
+	MatchModeAI MatchMode = "ai"
+	// MatchModeHybrid accepts unambiguous candidates by rule and asks ChatGPT only for the rest
+	MatchModeHybrid MatchMode = "hybrid"
+	// MatchModeRules never calls ChatGPT; ambiguous invoices stay unmatched
+	MatchModeRules MatchMode = "rules"
+)
+
+const (
+	defaultMaxCandidates   = 10
+	defaultAutoAcceptScore = 0.95
+	defaultMinConfidence   = 0.7
+	defaultBankCurrency    = "EUR"
+	defaultFXTolerance     = 0.03
+)
+
+// ParseMatchMode converts a --mode flag value into a MatchMode
+func ParseMatchMode(value string) (MatchMode, error) {
+	switch mode := MatchMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case MatchModeAI, MatchModeHybrid, MatchModeRules:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown match mode %q (use ai, hybrid or rules)", value)
+	}
+}
+
+// MatchOptions configures candidate selection and the deterministic matching rules
+type MatchOptions struct {
+	Mode MatchMode
+	// MaxCandidates limits the candidates per invoice, keeping the best scores
+	MaxCandidates int
+	// AutoAcceptScore is the minimum candidate score for a match without ChatGPT. An exact amount
+	// paid within a month scores about 0.97; a quoted PO number or customer reference adds 1.0, the
+	// invoice number 1.0 if quoted as is and 0.8 in another shape.
+	AutoAcceptScore float64
+	// MinConfidence rejects ChatGPT matches reported with a lower confidence. A weak match is worse than
+	// none because it consumes the transaction for every later invoice.
+	MinConfidence float64
+	// Deterministic makes runs over identical inputs reproducible as far as possible: the rules are tried
+	// before ChatGPT in every mode, and ChatGPT is called with temperature 0 and Seed. Candidate ordering
+	// is always stable. The ChatGPT answer itself stays outside that guarantee, since OpenAI only makes a
+	// best effort to return the same completion for the same seed.
+	Deterministic bool
+	// Seed is sent to ChatGPT in deterministic mode
+	Seed int
+	// OmitNameSimilarity leaves the locally computed counterparty similarity out of the ChatGPT prompt,
+	// so the model judges the names on its own
+	OmitNameSimilarity bool
+	// BankCurrency is the currency of the bank account the transactions were booked on. Invoices in
+	// another currency are converted into it at the transaction date before amounts are compared.
+	BankCurrency string
+	// Rates provides the exchange rates for foreign-currency invoices. Without rates such invoices
+	// get no candidates.
+	Rates *fx.Rates
+	// FXTolerance is the relative amount tolerance for converted invoices; it absorbs the spread
+	// between the reference rate and the rate the bank charged, plus fees
+	FXTolerance float64
+	// InvertedSign declares that the bank export shows outgoing payments as positive and incoming
+	// payments as negative amounts, the opposite of the usual convention
+	InvertedSign bool
+	// Accounts restricts the own bank accounts a payment may be booked on per invoice type or
+	// counterparty, so that a receivable only matches the account customers pay into. Transactions
+	// without an account and invoices without an entry are not restricted.
+	Accounts AccountMapping
+}
+
+// DefaultMatchOptions returns hybrid matching with the top 10 candidates per invoice
+func DefaultMatchOptions() MatchOptions {
+	return MatchOptions{
+		Mode:            MatchModeHybrid,
+		MaxCandidates:   defaultMaxCandidates,
+		AutoAcceptScore: defaultAutoAcceptScore,
+		MinConfidence:   defaultMinConfidence,
+		BankCurrency:    defaultBankCurrency,
+		FXTolerance:     defaultFXTolerance,
+	}
+}
+
+// selectByRules returns the index of the candidate that can be accepted without ChatGPT, or -1 when
+// the choice is ambiguous. Candidates must be sorted by score, best first. If exactly one candidate
+// quotes the invoice's reference or number, it is accepted if it reaches the threshold and nothing is
+// accepted otherwise. Without such a candidate, one is accepted if it is the only one at or above the
+// threshold.
+func selectByRules(candidates []TransactionCandidate, threshold float64) int {
+	if len(candidates) == 0 || candidates[0].Score < threshold {
+		return -1
+	}
+
+	// A fuzzy invoice number adds less than a quoted reference, so the quoting candidate need not be first
+	quoting := -1
+	for i, candidate := range candidates {
+		if !quotesInvoice(candidate) {
+			continue
+		}
+		if quoting >= 0 {
+			quoting = -1
+			break
+		}
+		quoting = i
+	}
+	if quoting >= 0 {
+		if candidates[quoting].Score >= threshold {
+			return quoting
+		}
+		return -1
+	}
+
+	if len(candidates) == 1 || candidates[1].Score < threshold {
+		return 0
+	}
+
+	return -1
+}
+
+// quotesInvoice reports whether the candidate's remittance information quotes the invoice's PO number,
+// customer reference or invoice number
+func quotesInvoice(candidate TransactionCandidate) bool {
+	return candidate.ReferenceMatch || candidate.InvoiceNumberScore > 0
+}
+
+// ruleMatchReason explains a rule match in the words of the ChatGPT reasons
+func ruleMatchReason(candidate TransactionCandidate) string {
+	switch {
+	case candidate.ReferenceMatch:
+		return "Verwendungszweck enthält Bestellnummer oder Kundenreferenz"
+	case candidate.InvoiceNumberScore >= quotedInvoiceNumberScore:
+		return "Verwendungszweck oder EREF enthält die Rechnungsnummer"
+	case candidate.InvoiceNumberScore > 0:
+		return "Verwendungszweck oder EREF enthält die Rechnungsnummer in anderer Schreibweise"
+	}
+	return fmt.Sprintf("Einziger Kandidat mit passendem Betrag und Datum (Score %.2f, %d Tage Abstand)", candidate.Score, candidate.DaysDiff)
+}
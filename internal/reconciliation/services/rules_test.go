@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"tools/internal/fx"
+	"tools/internal/llm"
+	"tools/internal/reconciliation"
+)
+
+// countingClient answers every matching request with the first candidate and counts the calls
+type countingClient struct {
+	calls      int
+	confidence float64 // Reported match confidence, 0.9 if unset
+	last       openai.ChatCompletionRequest
+}
+
+func (c *countingClient) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	c.calls++
+	c.last = request
+	confidence := c.confidence
+	if confidence == 0 {
+		confidence = 0.9
+	}
+	content := fmt.Sprintf(`{"matched": true, "transaction_index": 0, "confidence": %.2f, "reason": "test"}`, confidence)
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: content}},
+		},
+	}, nil
+}
+
+func day(d int) time.Time {
+	return time.Date(2024, 6, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseMatchMode(t *testing.T) {
+	for _, value := range []string{"ai", "Hybrid", " rules "} {
+		if _, err := ParseMatchMode(value); err != nil {
+			t.Errorf("ParseMatchMode(%q) failed: %v", value, err)
+		}
+	}
+	if _, err := ParseMatchMode("magic"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestSelectByRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []TransactionCandidate
+		want       int
+	}{
+		{"no candidates", nil, -1},
+		{"single above threshold", []TransactionCandidate{{Score: 0.99}}, 0},
+		{"single below threshold", []TransactionCandidate{{Score: 0.5}}, -1},
+		{"runner-up below threshold", []TransactionCandidate{{Score: 0.99}, {Score: 0.6}}, 0},
+		{"two above threshold", []TransactionCandidate{{Score: 0.99}, {Score: 0.98}}, -1},
+		{"only one quotes the reference", []TransactionCandidate{{Score: 1.99, ReferenceMatch: true}, {Score: 0.99}}, 0},
+		{"both quote the reference", []TransactionCandidate{{Score: 1.99, ReferenceMatch: true}, {Score: 1.98, ReferenceMatch: true}}, -1},
+		{"only the runner-up quotes the invoice number", []TransactionCandidate{{Score: 1.2}, {Score: 1.1, InvoiceNumberScore: 0.8}}, 1},
+		{"invoice number quoted below threshold", []TransactionCandidate{{Score: 0.99}, {Score: 0.9, InvoiceNumberScore: 0.8}}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectByRules(tt.candidates, 0.95); got != tt.want {
+				t.Errorf("selectByRules() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileAllModes(t *testing.T) {
+	invoices := []reconciliation.InvoiceRow{
+		// Exactly one matching payment
+		{InvoiceNumber: "R-1", Date: day(1), Vendor: "Muster GmbH", GrossAmount: 119, Type: "PAYABLE"},
+		// Two identical payments, only ChatGPT can decide
+		{InvoiceNumber: "R-2", Date: day(2), Vendor: "Abo AG", GrossAmount: 49.99, Type: "PAYABLE"},
+	}
+	transactions := []reconciliation.BankTransaction{
+		{Date: day(5), CounterParty: "Muster GmbH", Amount: -119},
+		{Date: day(3), CounterParty: "Abo AG", Amount: -49.99},
+		{Date: day(4), CounterParty: "Abo AG", Amount: -49.99},
+	}
+
+	tests := []struct {
+		mode         MatchMode
+		wantMatched  int
+		wantRule     int
+		wantLLMCalls int
+	}{
+		{MatchModeRules, 1, 1, 0},
+		{MatchModeHybrid, 2, 1, 1},
+		{MatchModeAI, 2, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			client := &countingClient{}
+			var llmClient llm.LLMClient = client
+			if tt.mode == MatchModeRules {
+				llmClient = nil
+			}
+
+			svc := NewChatGPTReconciliationServiceWithOptions(llmClient, MatchOptions{Mode: tt.mode})
+			result, err := svc.ReconcileAll(context.Background(), invoices, transactions, day(30))
+			if err != nil {
+				t.Fatalf("ReconcileAll failed: %v", err)
+			}
+
+			if result.MatchedCount != tt.wantMatched || result.RuleMatchedCount != tt.wantRule {
+				t.Errorf("matched = %d (rule %d), want %d (rule %d)", result.MatchedCount, result.RuleMatchedCount, tt.wantMatched, tt.wantRule)
+			}
+			if client.calls != tt.wantLLMCalls || result.ChatGPTRequests != tt.wantLLMCalls {
+				t.Errorf("ChatGPT calls = %d (counted %d), want %d", client.calls, result.ChatGPTRequests, tt.wantLLMCalls)
+			}
+		})
+	}
+}
+
+func TestFindCandidateTransactionsMaxCandidates(t *testing.T) {
+	invoice := reconciliation.InvoiceRow{Date: day(1), GrossAmount: 10, Type: "PAYABLE"}
+	var transactions []reconciliation.BankTransaction
+	for i := 1; i <= 5; i++ {
+		transactions = append(transactions, reconciliation.BankTransaction{Date: day(i), Amount: -10})
+	}
+
+	svc := NewChatGPTReconciliationServiceWithOptions(nil, MatchOptions{MaxCandidates: 3})
+	candidates := svc.findCandidateTransactions(invoice, transactions, map[int]bool{})
+	if len(candidates) != 3 {
+		t.Fatalf("expected 3 candidates, got %d", len(candidates))
+	}
+	if candidates[0].OriginalIndex != 0 {
+		t.Errorf("expected closest payment first, got index %d", candidates[0].OriginalIndex)
+	}
+}
+
+func TestReconcileAllRejectsLowConfidence(t *testing.T) {
+	invoices := []reconciliation.InvoiceRow{
+		{InvoiceNumber: "R-1", Date: day(1), Vendor: "Muster GmbH", GrossAmount: 119, Type: "PAYABLE"},
+	}
+	transactions := []reconciliation.BankTransaction{
+		{Date: day(5), CounterParty: "Muster GmbH", Amount: -119},
+	}
+
+	client := &countingClient{confidence: 0.4}
+	svc := NewChatGPTReconciliationServiceWithOptions(client, MatchOptions{Mode: MatchModeAI, MinConfidence: 0.7})
+	result, err := svc.ReconcileAll(context.Background(), invoices, transactions, day(30))
+	if err != nil {
+		t.Fatalf("ReconcileAll failed: %v", err)
+	}
+
+	if result.MatchedCount != 0 || result.RejectedCount != 1 {
+		t.Errorf("matched = %d, rejected = %d, want 0 and 1", result.MatchedCount, result.RejectedCount)
+	}
+	if len(result.UnmatchedInvoices) != 1 || len(result.UnmatchedTransactions) != 1 {
+		t.Errorf("expected invoice and transaction back in the unmatched pools, got %d invoices and %d transactions",
+			len(result.UnmatchedInvoices), len(result.UnmatchedTransactions))
+	}
+}
+
+func TestFindCandidateTransactionsStableTiebreak(t *testing.T) {
+	// Payments two days before and after the invoice score the same
+	invoice := reconciliation.InvoiceRow{Date: day(10), GrossAmount: 10, Type: "PAYABLE"}
+	transactions := []reconciliation.BankTransaction{
+		{Date: day(12), Amount: -10},
+		{Date: day(8), Amount: -10},
+		{Date: day(12), Amount: -10},
+	}
+
+	svc := NewChatGPTReconciliationServiceWithOptions(nil, MatchOptions{})
+	for run := 0; run < 10; run++ {
+		candidates := svc.findCandidateTransactions(invoice, transactions, map[int]bool{})
+		var order []int
+		for _, candidate := range candidates {
+			order = append(order, candidate.OriginalIndex)
+		}
+		if fmt.Sprint(order) != "[1 0 2]" {
+			t.Fatalf("candidate order = %v, want [1 0 2] (earlier date, then sheet order)", order)
+		}
+	}
+}
+
+func TestReconcileAllDeterministic(t *testing.T) {
+	invoices := []reconciliation.InvoiceRow{
+		{InvoiceNumber: "R-1", Date: day(1), Vendor: "Muster GmbH", GrossAmount: 119, Type: "PAYABLE"},
+		{InvoiceNumber: "R-2", Date: day(2), Vendor: "Abo AG", GrossAmount: 49.99, Type: "PAYABLE"},
+	}
+	transactions := []reconciliation.BankTransaction{
+		{Date: day(5), CounterParty: "Muster GmbH", Amount: -119},
+		{Date: day(3), CounterParty: "Abo AG", Amount: -49.99},
+		{Date: day(4), CounterParty: "Abo AG", Amount: -49.99},
+	}
+
+	client := &countingClient{}
+	svc := NewChatGPTReconciliationServiceWithOptions(client, MatchOptions{Mode: MatchModeAI, Deterministic: true, Seed: 42})
+	result, err := svc.ReconcileAll(context.Background(), invoices, transactions, day(30))
+	if err != nil {
+		t.Fatalf("ReconcileAll failed: %v", err)
+	}
+
+	// The unambiguous invoice is matched by rule even in ai mode
+	if result.RuleMatchedCount != 1 || client.calls != 1 {
+		t.Errorf("rule matches = %d, ChatGPT calls = %d, want 1 and 1", result.RuleMatchedCount, client.calls)
+	}
+	if client.last.Seed == nil || *client.last.Seed != 42 || client.last.Temperature > 0.001 {
+		t.Errorf("expected seed 42 and zero temperature, got seed %v temperature %v", client.last.Seed, client.last.Temperature)
+	}
+}
+
+func TestForeignCurrencyCandidates(t *testing.T) {
+	rates, err := fx.Parse(strings.NewReader("Date,USD,\n2024-06-03,1.0800,\n2024-06-10,1.0750,\n"))
+	if err != nil {
+		t.Fatalf("fx.Parse failed: %v", err)
+	}
+
+	// $540 at 1.08 is 500 EUR; the bank charged 507.50 including its spread
+	invoice := reconciliation.InvoiceRow{InvoiceNumber: "INV-7", Date: day(1), Vendor: "Cloud Inc", GrossAmount: 540, Currency: "USD", Type: "PAYABLE"}
+	transactions := []reconciliation.BankTransaction{
+		{Date: day(4), CounterParty: "Cloud Inc", Amount: -507.50},
+		{Date: day(5), CounterParty: "Cloud Inc", Amount: -540},
+	}
+
+	svc := NewChatGPTReconciliationServiceWithOptions(nil, MatchOptions{Rates: rates})
+	candidates := svc.findCandidateTransactions(invoice, transactions, map[int]bool{})
+	if len(candidates) != 1 || candidates[0].OriginalIndex != 0 || candidates[0].ExpectedAmount != 500 {
+		t.Fatalf("candidates = %+v, want only the converted payment with expected amount 500", candidates)
+	}
+
+	// Without rates a foreign-currency invoice has no candidates rather than false ones
+	svc = NewChatGPTReconciliationServiceWithOptions(nil, MatchOptions{})
+	if candidates := svc.findCandidateTransactions(invoice, transactions, map[int]bool{}); len(candidates) != 0 {
+		t.Errorf("expected no candidates without rates, got %d", len(candidates))
+	}
+}
+
+func TestFindCandidateTransactionsAccounts(t *testing.T) {
+	accounts, err := ParseAccountMapping("RECEIVABLE=DE89 3704 0044 0532 0130 00; PAYABLE=Tagesgeld,DE02120300000000202051;Sonder AG=Sonderkonto")
+	if err != nil {
+		t.Fatalf("ParseAccountMapping failed: %v", err)
+	}
+
+	transactions := []reconciliation.BankTransaction{
+		{Date: day(2), Amount: 100, Account: "DE02120300000000202051"},
+		{Date: day(3), Amount: 100, Account: "de89370400440532013000"},
+		{Date: day(4), Amount: 100},
+		{Date: day(5), Amount: 100, Account: "Sonderkonto"},
+	}
+	svc := NewChatGPTReconciliationServiceWithOptions(nil, MatchOptions{Accounts: accounts})
+
+	invoice := reconciliation.InvoiceRow{Date: day(1), Customer: "Kunde AG", GrossAmount: 100, Type: "RECEIVABLE"}
+	candidates := svc.findCandidateTransactions(invoice, transactions, map[int]bool{})
+	if len(candidates) != 2 || candidates[0].OriginalIndex != 1 || candidates[1].OriginalIndex != 2 {
+		t.Errorf("candidates = %+v, want the receivables account and the transaction without account", candidates)
+	}
+
+	// A counterparty entry overrides the one for its invoice type
+	invoice.Customer = "sonder ag"
+	candidates = svc.findCandidateTransactions(invoice, transactions, map[int]bool{})
+	if len(candidates) != 2 || candidates[0].OriginalIndex != 2 || candidates[1].OriginalIndex != 3 {
+		t.Errorf("candidates = %+v, want the counterparty's account and the transaction without account", candidates)
+	}
+
+	for _, invalid := range []string{"DE89370400440532013000", "PAYABLE=", "=DE02"} {
+		if _, err := ParseAccountMapping(invalid); err == nil {
+			t.Errorf("ParseAccountMapping(%q) succeeded, want error", invalid)
+		}
+	}
+}
+
+func TestFindCandidateTransactionsInvertedSign(t *testing.T) {
+	invoice := reconciliation.InvoiceRow{Date: day(1), GrossAmount: 10, Type: "PAYABLE"}
+	transactions := []reconciliation.BankTransaction{
+		{Date: day(2), Amount: -10},
+		{Date: day(3), Amount: 10},
+	}
+
+	svc := NewChatGPTReconciliationServiceWithOptions(nil, MatchOptions{InvertedSign: true})
+	candidates := svc.findCandidateTransactions(invoice, transactions, map[int]bool{})
+	if len(candidates) != 1 || candidates[0].OriginalIndex != 1 {
+		t.Errorf("candidates = %+v, want only the positive amount as payment", candidates)
+	}
+}
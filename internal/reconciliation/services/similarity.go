@@ -0,0 +1,192 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"tools/internal/reconciliation"
+	"tools/internal/vendors"
+)
+
+// containedNameSimilarity is the score of a name whose words all appear in the other name, e.g.
+// "Hetzner" in "Hetzner Online". It is below 1 because "Deutsche" is contained in many names.
+const containedNameSimilarity = 0.9
+
+// counterpartySimilarity scores how well a transaction's counterparty matches the invoice's vendor or
+// customer, from 0 to 1. The Empfänger/Absender column is the owner of the paying or receiving IBAN;
+// the description is also checked since payment providers put the merchant there.
+func counterpartySimilarity(invoice reconciliation.InvoiceRow, transaction reconciliation.BankTransaction) float64 {
+	name := invoice.GetCounterParty()
+	similarity := nameSimilarity(name, transaction.CounterParty)
+	if wordsContained(vendors.NormalizeName(name), vendors.NormalizeName(transaction.Description)) {
+		similarity = math.Max(similarity, containedNameSimilarity)
+	}
+	return math.Round(similarity*100) / 100
+}
+
+// nameSimilarity compares two names after normalization (case, punctuation and legal forms are
+// ignored): 1 for equal names, containedNameSimilarity if one name's words all appear in the other,
+// otherwise the Dice coefficient of their character bigrams.
+func nameSimilarity(a, b string) float64 {
+	a, b = vendors.NormalizeName(a), vendors.NormalizeName(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	similarity := diceCoefficient(a, b)
+	if wordsContained(a, b) || wordsContained(b, a) {
+		similarity = math.Max(similarity, containedNameSimilarity)
+	}
+	return similarity
+}
+
+// wordsContained reports whether every word of name appears in text. Names shorter than three
+// letters are ignored since they would appear almost anywhere.
+func wordsContained(name, text string) bool {
+	if len(name) < 3 || text == "" {
+		return false
+	}
+
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		words[word] = true
+	}
+	for _, word := range strings.Fields(name) {
+		if !words[word] {
+			return false
+		}
+	}
+	return true
+}
+
+// diceCoefficient returns 2·|common bigrams| / (|bigrams a| + |bigrams b|), counting repeated bigrams
+func diceCoefficient(a, b string) float64 {
+	bigramsA, bigramsB := bigrams(a), bigrams(b)
+	if len(bigramsA) == 0 || len(bigramsB) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int)
+	for _, bigram := range bigramsA {
+		counts[bigram]++
+	}
+	common := 0
+	for _, bigram := range bigramsB {
+		if counts[bigram] > 0 {
+			counts[bigram]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(bigramsA)+len(bigramsB))
+}
+
+// bigrams splits s into overlapping pairs of runes, e.g. "abc" -> "ab", "bc"
+func bigrams(s string) []string {
+	runes := []rune(s)
+	var pairs []string
+	for i := 0; i+1 < len(runes); i++ {
+		pairs = append(pairs, string(runes[i:i+2]))
+	}
+	return pairs
+}
+
+// Scores of invoiceNumberSimilarity
+const (
+	quotedInvoiceNumberScore   = 1.0 // The reference contains the invoice number
+	reshapedInvoiceNumberScore = 0.8 // The reference contains the invoice number's digits in another shape
+)
+
+var (
+	// digitTokenPattern finds numbers whose digit groups are separated by spaces or punctuation, e.g.
+	// "2024-00042" or "2024 / 42"
+	digitTokenPattern = regexp.MustCompile(`\d+(?:[-/._ ]+\d+)*`)
+	digitRunPattern   = regexp.MustCompile(`\d+`)
+)
+
+// invoiceNumberSimilarity scores how clearly a transaction's Verwendungszweck (SVWZ) or EREF quotes the
+// invoice number: quotedInvoiceNumberScore if it contains the number ignoring case, spaces and
+// punctuation, reshapedInvoiceNumberScore if it contains the same digit sequence in another shape
+// (see digitKeys), e.g. "RE240042" for "RG 2024-00042", otherwise 0.
+func invoiceNumberSimilarity(invoiceNumber string, transaction reconciliation.BankTransaction) float64 {
+	number := alphanumeric(invoiceNumber)
+	// Very short numbers like "17" would match almost any reference
+	if len(number) < 4 {
+		return 0
+	}
+
+	references := []string{transaction.SVWZ, transaction.EREF}
+	for _, reference := range references {
+		if strings.Contains(alphanumeric(reference), number) {
+			return quotedInvoiceNumberScore
+		}
+	}
+
+	numberKeys := digitKeys(invoiceNumber)
+	for _, reference := range references {
+		for key := range digitKeys(reference) {
+			if numberKeys[key] {
+				return reshapedInvoiceNumberScore
+			}
+		}
+	}
+	return 0
+}
+
+// alphanumeric lowercases s and strips everything but letters and digits
+func alphanumeric(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// digitKeys returns the shapes of the numbers in text that are compared across spellings: the digit
+// groups of each number joined after dropping leading zeros and shortening four-digit years to two
+// digits, so that "2024-00042" and "24-0042" both become "2442". A number written without separators,
+// like "240042", may have its groups run together and is also split at every position. Keys shorter
+// than three digits are left out.
+func digitKeys(text string) map[string]bool {
+	keys := make(map[string]bool)
+	add := func(key string) {
+		if len(key) >= 3 {
+			keys[key] = true
+		}
+	}
+
+	for _, token := range digitTokenPattern.FindAllString(text, -1) {
+		runs := digitRunPattern.FindAllString(token, -1)
+		var joined strings.Builder
+		for _, run := range runs {
+			joined.WriteString(canonicalDigitGroup(run))
+		}
+		add(joined.String())
+
+		if len(runs) == 1 {
+			run := runs[0]
+			for i := 1; i < len(run); i++ {
+				add(canonicalDigitGroup(run[:i]) + canonicalDigitGroup(run[i:]))
+			}
+		}
+	}
+	return keys
+}
+
+// canonicalDigitGroup shortens a four-digit year like "2024" to "24" and drops the leading zeros of
+// any other digit group
+func canonicalDigitGroup(group string) string {
+	if len(group) == 4 && (strings.HasPrefix(group, "19") || strings.HasPrefix(group, "20")) {
+		return group[2:]
+	}
+	if trimmed := strings.TrimLeft(group, "0"); trimmed != "" {
+		return trimmed
+	}
+	return "0"
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"tools/internal/reconciliation"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		min, max float64
+	}{
+		{"Muster GmbH", "MUSTER GMBH", 1, 1},
+		{"Hetzner Online GmbH", "Hetzner", 0.9, 0.9},
+		{"Telekom Deutschland GmbH", "Telekom Deutschlnd", 0.7, 0.95},
+		{"Muster GmbH", "Abo AG", 0, 0.2},
+		{"", "Muster GmbH", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := nameSimilarity(tt.a, tt.b); got < tt.min || got > tt.max {
+			t.Errorf("nameSimilarity(%q, %q) = %.2f, want %.2f-%.2f", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+
+	// Payment providers put the merchant into the description
+	invoice := reconciliation.InvoiceRow{Vendor: "Spotify AB", Type: "PAYABLE"}
+	transaction := reconciliation.BankTransaction{CounterParty: "PayPal Europe", Description: "PP.1234.PP Spotify AB Abo"}
+	if got := counterpartySimilarity(invoice, transaction); got != containedNameSimilarity {
+		t.Errorf("counterpartySimilarity = %.2f, want %.2f", got, containedNameSimilarity)
+	}
+}
+
+func TestNameSimilarityInPrompt(t *testing.T) {
+	invoices := []reconciliation.InvoiceRow{
+		{InvoiceNumber: "R-2", Date: day(2), Vendor: "Abo AG", GrossAmount: 49.99, Type: "PAYABLE"},
+	}
+	transactions := []reconciliation.BankTransaction{
+		{Date: day(3), CounterParty: "Abo AG", Amount: -49.99},
+		{Date: day(4), CounterParty: "Anderer Verein", Amount: -49.99},
+	}
+
+	for _, omit := range []bool{false, true} {
+		client := &countingClient{}
+		svc := NewChatGPTReconciliationServiceWithOptions(client, MatchOptions{Mode: MatchModeAI, OmitNameSimilarity: omit})
+		if _, err := svc.ReconcileAll(context.Background(), invoices, transactions, day(30)); err != nil {
+			t.Fatalf("ReconcileAll failed: %v", err)
+		}
+
+		prompt := client.last.Messages[0].Content
+		if got := strings.Contains(prompt, `"namensaehnlichkeit": 1`); got == omit {
+			t.Errorf("OmitNameSimilarity=%v: prompt contains similarity = %v", omit, got)
+		}
+	}
+}
+
+func TestInvoiceNumberSimilarity(t *testing.T) {
+	tests := []struct {
+		number string
+		svwz   string
+		eref   string
+		want   float64
+	}{
+		{"RE-2024-0042", "Rechnung re 2024 0042 vom 01.06.", "", quotedInvoiceNumberScore},
+		{"RE-2024-0042", "", "RE20240042", quotedInvoiceNumberScore},
+		{"RG 2024-00042", "RE240042 Muster GmbH", "", reshapedInvoiceNumberScore},
+		{"RE240042", "", "Rg. 2024/00042", reshapedInvoiceNumberScore},
+		{"RE-04711", "Rechnung 4711", "", reshapedInvoiceNumberScore},
+		{"RG 2024-00042", "RE240043 Muster GmbH", "", 0},
+		{"RG 2024-00042", "Miete Juni 2024", "", 0},
+		{"R-17", "Kundennummer R-17", "", 0},
+		{"", "RE240042", "", 0},
+	}
+	for _, tt := range tests {
+		transaction := reconciliation.BankTransaction{SVWZ: tt.svwz, EREF: tt.eref}
+		if got := invoiceNumberSimilarity(tt.number, transaction); got != tt.want {
+			t.Errorf("invoiceNumberSimilarity(%q, SVWZ %q, EREF %q) = %.1f, want %.1f", tt.number, tt.svwz, tt.eref, got, tt.want)
+		}
+	}
+}
+
+func TestInvoiceNumberMatchDecidesAmongSimilarCandidates(t *testing.T) {
+	invoices := []reconciliation.InvoiceRow{
+		{InvoiceNumber: "RG 2024-00042", Date: day(2), Vendor: "Muster GmbH", GrossAmount: 119, Type: "PAYABLE"},
+	}
+	transactions := []reconciliation.BankTransaction{
+		{Date: day(5), CounterParty: "Muster GmbH", Amount: -119, SVWZ: "RE240041"},
+		{Date: day(6), CounterParty: "Muster GmbH", Amount: -119, SVWZ: "RE240042"},
+	}
+
+	client := &countingClient{}
+	svc := NewChatGPTReconciliationServiceWithOptions(client, MatchOptions{Mode: MatchModeRules})
+	result, err := svc.ReconcileAll(context.Background(), invoices, transactions, day(30))
+	if err != nil {
+		t.Fatalf("ReconcileAll failed: %v", err)
+	}
+	if len(result.Matches) != 1 || result.Matches[0].Transaction.SVWZ != "RE240042" {
+		t.Fatalf("expected the transaction quoting the reshaped invoice number, got %+v", result.Matches)
+	}
+	if reason := result.Matches[0].Reason; !strings.Contains(reason, "anderer Schreibweise") {
+		t.Errorf("reason = %q", reason)
+	}
+}
@@ -6,30 +6,68 @@ import (
 
 // BankTransaction represents a bank transaction from the Bank sheet
 type BankTransaction struct {
-	Date         time.Time // Datum - column A
-	Type         string    // Transaktionstyp - column B
-	Description  string    // Beschreibung - column C
-	EREF         string    // End-to-End Reference - column D
-	MREF         string    // Mandate Reference - column E
-	CRED         string    // Creditor ID - column F
-	SVWZ         string    // Verwendungszweck - column G
-	CounterParty string    // Empfänger/Absender - column H
-	BIC          string    // Bank Identifier Code - column I
-	IBAN         string    // International Bank Account Number - column J
-	Amount       float64   // Betrag (negative for outgoing, positive for incoming) - column K
+	Date         time.Time `json:"date"`         // Datum - column A
+	Type         string    `json:"type"`         // Transaktionstyp - column B
+	Description  string    `json:"description"`  // Beschreibung - column C
+	EREF         string    `json:"eref"`         // End-to-End Reference - column D
+	MREF         string    `json:"mref"`         // Mandate Reference - column E
+	CRED         string    `json:"cred"`         // Creditor ID - column F
+	SVWZ         string    `json:"svwz"`         // Verwendungszweck - column G
+	CounterParty string    `json:"counterparty"` // Empfänger/Absender - column H
+	BIC          string    `json:"bic"`          // Bank Identifier Code - column I
+	IBAN         string    `json:"iban"`         // International Bank Account Number - column J
+	Amount       float64   `json:"amount"`       // Betrag (negative for outgoing, positive for incoming) - column K
+	Account      string    `json:"account,omitempty"` // Konto, our own IBAN or account name - column L (optional)
 }
 
 // InvoiceRow represents an invoice from Kreditoren or Debitoren sheets
 type InvoiceRow struct {
-	InvoiceNumber string    // Rechnungsnr - column B
-	Date          time.Time // Datum - column C
-	Vendor        string    // Lieferant (for Kreditoren) - column D
-	Customer      string    // Kunde (for Debitoren) - column D
-	NetAmount     float64   // Netto - column E
-	VATAmount     float64   // MwSt - column F
-	GrossAmount   float64   // Brutto - column G
-	Currency      string    // Währung - column H
-	Type          string    // "PAYABLE" for Kreditoren, "RECEIVABLE" for Debitoren
+	InvoiceNumber     string    `json:"invoice_number"`     // Rechnungsnr - column B
+	Date              time.Time `json:"date"`               // Datum - column C
+	Vendor            string    `json:"vendor"`             // Lieferant (for Kreditoren) - column D
+	Customer          string    `json:"customer"`           // Kunde (for Debitoren) - column D
+	NetAmount         float64   `json:"net_amount"`         // Netto - column E
+	VATAmount         float64   `json:"vat_amount"`         // MwSt - column F
+	GrossAmount       float64   `json:"gross_amount"`       // Brutto - column G
+	Currency          string    `json:"currency"`           // Währung - column H
+	TaxKey            string    `json:"tax_key"`            // Steuerschlüssel - column K (optional)
+	Status            string    `json:"status"`             // Status written by datev-batch: success, warning or error - column P (optional)
+	ProcessedAt       time.Time `json:"processed_at"`       // Verarbeitet - column Q (optional, zero if missing)
+	PurchaseOrder     string    `json:"purchase_order"`     // Bestellnr - column S (optional)
+	CustomerReference string    `json:"customer_reference"` // Kundenreferenz - column T (optional)
+	CustomerEmail     string    `json:"customer_email,omitempty"`   // E-Mail Kunde, receivables only - column V (optional)
+	CustomerContact   string    `json:"customer_contact,omitempty"` // Ansprechpartner, receivables only - column W (optional)
+	Paid              bool      `json:"paid,omitempty"`             // Bezahlt: marked paid on issue or collected by direct debit - column X (optional)
+	Type              string    `json:"type"`               // "PAYABLE" for Kreditoren, "RECEIVABLE" for Debitoren
+}
+
+// DateRange limits which bank transactions are loaded. A zero From or To leaves that side unbounded;
+// both bounds are inclusive and compared by calendar day.
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Contains reports whether date lies within the range
+func (r DateRange) Contains(date time.Time) bool {
+	day := truncateToDay(date)
+	if !r.From.IsZero() && day.Before(truncateToDay(r.From)) {
+		return false
+	}
+	if !r.To.IsZero() && day.After(truncateToDay(r.To)) {
+		return false
+	}
+	return true
+}
+
+// IsZero reports whether the range is unbounded on both sides
+func (r DateRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// truncateToDay drops the time of day so bounds given as timestamps include the whole day
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
 }
 
 // ReconciliationData holds all data read from Google Sheets
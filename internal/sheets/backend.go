@@ -0,0 +1,125 @@
+package sheets
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// SheetsBackend is the storage layer behind Service. The Google implementation talks to the
+// Sheets API; tests can substitute the in-memory backend from the sheetstest package.
+type SheetsBackend interface {
+	// ReadRange returns the values of an A1 range such as "Bank!A:K"
+	ReadRange(ctx context.Context, rangeSpec string) ([][]interface{}, error)
+
+	// Append adds rows after the last row of the table found in rangeSpec
+	Append(ctx context.Context, rangeSpec string, values [][]interface{}) error
+
+	// Update overwrites the cells starting at the top-left corner of rangeSpec
+	Update(ctx context.Context, rangeSpec string, values [][]interface{}) error
+
+	// EnsureSheet creates the tab if it is missing and returns its sheet ID
+	EnsureSheet(ctx context.Context, sheetName string) (int64, error)
+
+	// SheetTitle returns the title of the tab with the given sheet ID, the gid of its URL
+	SheetTitle(ctx context.Context, sheetID int64) (string, error)
+
+	// BatchUpdate applies formatting and structural requests to the spreadsheet
+	BatchUpdate(ctx context.Context, requests []*sheets.Request) error
+}
+
+// googleBackend implements SheetsBackend against the Google Sheets API
+type googleBackend struct {
+	sheetsService *sheets.Service
+	spreadsheetID string
+}
+
+// ReadRange reads values via the Values.Get endpoint
+func (b *googleBackend) ReadRange(ctx context.Context, rangeSpec string) ([][]interface{}, error) {
+	resp, err := b.sheetsService.Spreadsheets.Values.Get(b.spreadsheetID, rangeSpec).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+// Append appends values, letting Sheets interpret numbers and dates as if typed by a user
+func (b *googleBackend) Append(ctx context.Context, rangeSpec string, values [][]interface{}) error {
+	valueRange := &sheets.ValueRange{Values: values}
+	_, err := b.sheetsService.Spreadsheets.Values.Append(
+		b.spreadsheetID,
+		rangeSpec,
+		valueRange,
+	).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+	return err
+}
+
+// Update overwrites values, interpreting numbers and dates like Append so that a row updated by
+// UpsertBatchResults is formatted like an appended one
+func (b *googleBackend) Update(ctx context.Context, rangeSpec string, values [][]interface{}) error {
+	valueRange := &sheets.ValueRange{Values: values}
+	_, err := b.sheetsService.Spreadsheets.Values.Update(
+		b.spreadsheetID,
+		rangeSpec,
+		valueRange,
+	).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+	return err
+}
+
+// EnsureSheet looks the tab up by title and adds it if necessary
+func (b *googleBackend) EnsureSheet(ctx context.Context, sheetName string) (int64, error) {
+	const op = "EnsureSheet"
+
+	spreadsheet, err := b.sheetsService.Spreadsheets.Get(b.spreadsheetID).Context(ctx).Do()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get spreadsheet: %w", op, err)
+	}
+
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.Title == sheetName {
+			return sheet.Properties.SheetId, nil
+		}
+	}
+
+	batchUpdateReq := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{AddSheet: &sheets.AddSheetRequest{
+				Properties: &sheets.SheetProperties{
+					Title: sheetName,
+				},
+			}},
+		},
+	}
+
+	resp, err := b.sheetsService.Spreadsheets.BatchUpdate(b.spreadsheetID, batchUpdateReq).Context(ctx).Do()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to create sheet: %w", op, err)
+	}
+
+	return resp.Replies[0].AddSheet.Properties.SheetId, nil
+}
+
+// SheetTitle looks the tab up by sheet ID
+func (b *googleBackend) SheetTitle(ctx context.Context, sheetID int64) (string, error) {
+	const op = "SheetTitle"
+
+	spreadsheet, err := b.sheetsService.Spreadsheets.Get(b.spreadsheetID).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to get spreadsheet: %w", op, err)
+	}
+
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.SheetId == sheetID {
+			return sheet.Properties.Title, nil
+		}
+	}
+	return "", fmt.Errorf("%s: no sheet with gid %d", op, sheetID)
+}
+
+// BatchUpdate sends the requests in a single spreadsheet batch update
+func (b *googleBackend) BatchUpdate(ctx context.Context, requests []*sheets.Request) error {
+	batchUpdateReq := &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}
+	_, err := b.sheetsService.Spreadsheets.BatchUpdate(b.spreadsheetID, batchUpdateReq).Context(ctx).Do()
+	return err
+}
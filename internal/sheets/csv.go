@@ -0,0 +1,60 @@
+package sheets
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"tools/internal/logger"
+)
+
+// WriteCSV writes the results in the column layout of the Kreditoren and Debitoren sheets as a
+// semicolon-separated CSV with decimal commas, the format German spreadsheet programs open directly
+func WriteCSV(w io.Writer, results []BatchResult) error {
+	const op = "WriteCSV"
+
+	s := &Service{log: logger.WithComponent("sheets")}
+	rows, err := s.convertResultsToRows(results)
+	if err != nil {
+		return fmt.Errorf("%s: failed to convert results to rows: %w", op, err)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+
+	if err := writer.Write(BatchHeaders); err != nil {
+		return fmt.Errorf("%s: failed to write header: %w", op, err)
+	}
+
+	for _, row := range rows {
+		values := s.rowToValues(row)
+		record := make([]string, len(values))
+		for i, value := range values {
+			record[i] = formatCSVValue(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("%s: failed to write row: %w", op, err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("%s: failed to flush CSV: %w", op, err)
+	}
+
+	return nil
+}
+
+// formatCSVValue renders a sheet cell value, amounts and confidence with a decimal comma
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strings.Replace(strconv.FormatFloat(v, 'f', 2, 64), ".", ",", 1)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,51 @@
+package sheets
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+func TestWriteCSV(t *testing.T) {
+	results := []BatchResult{
+		{
+			Filename: "a.pdf",
+			Invoice: &models.Invoice{
+				InvoiceNumber: "RE-1",
+				Type:          "PAYABLE",
+				Vendor:        "Muster; Söhne",
+				IssueDate:     time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+				NetAmount:     123456,
+				VATAmount:     23457,
+				GrossAmount:   146913,
+			},
+			Booking: &services.DATEVBooking{DebitAccount: "4930", CreditAccount: "1600", TaxKey: "9", SourceSHA256: "9f86d081"},
+			Status:  "success",
+		},
+		{Filename: "b.pdf", Error: errors.New("kaputt"), Status: "error"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, results); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if lines[0] != strings.Join(BatchHeaders, ";") {
+		t.Errorf("header = %s", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], `a.pdf;RE-1;05.03.2024;"Muster; Söhne";1234,56;234,57;1469,13;EUR;4930;1600;9;`) {
+		t.Errorf("row = %s", lines[1])
+	}
+	if !strings.HasSuffix(strings.TrimSpace(lines[1]), ";9f86d081;;;") {
+		t.Errorf("expected source hash before the empty customer contact and paid columns, row = %s", lines[1])
+	}
+	if !strings.Contains(lines[2], "Fehler: kaputt;;error;") {
+		t.Errorf("error row = %s", lines[2])
+	}
+}
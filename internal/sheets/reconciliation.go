@@ -0,0 +1,101 @@
+package sheets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReconciliationSheet is the tab that approved reconciliation matches are written to
+const ReconciliationSheet = "Abgleich"
+
+// MatchRow is one approved invoice-transaction match
+type MatchRow struct {
+	InvoiceID         string
+	InvoiceNumber     string
+	InvoiceType       string
+	InvoiceDate       string
+	Counterparty      string
+	GrossAmount       float64
+	TransactionID     string
+	TransactionDate   string
+	TransactionAmount float64
+	TransactionParty  string
+	Confidence        float64
+	Reason            string
+	Method            string
+}
+
+var matchHeaders = []interface{}{
+	"Rechnungs-ID", "Rechnungsnr", "Typ", "Datum", "Lieferant/Kunde", "Brutto",
+	"Transaktions-ID", "Buchungsdatum", "Betrag", "Empfänger/Absender",
+	"Konfidenz", "Begründung", "Methode", "Freigegeben",
+}
+
+// WriteMatches appends approved matches to sheetName, creating the tab with headers if needed.
+// Invoices that already have a row are skipped, so applying the same review twice does not
+// duplicate matches. It returns the number of written and skipped rows.
+func (s *Service) WriteMatches(ctx context.Context, rows []MatchRow, sheetName string) (int, int, error) {
+	const op = "WriteMatches"
+
+	s.log.Info().
+		Str("sheet", sheetName).
+		Int("rows", len(rows)).
+		Msg("Writing approved matches to Google Sheet")
+
+	sheetID, err := s.backend.EnsureSheet(ctx, sheetName)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	existing, err := s.backend.ReadRange(ctx, sheetName+"!A:A")
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: failed to read existing matches: %w", op, err)
+	}
+
+	if len(existing) == 0 || len(existing[0]) == 0 {
+		if err := s.backend.Update(ctx, sheetName+"!A1:N1", [][]interface{}{matchHeaders}); err != nil {
+			return 0, 0, fmt.Errorf("%s: failed to add headers: %w", op, err)
+		}
+		if err := s.formatHeaders(ctx, sheetID, sheetName); err != nil {
+			s.log.Warn().Err(err).Msg("Failed to format headers, continuing anyway")
+		}
+	}
+
+	matched := make(map[string]bool)
+	for i, values := range existing {
+		if i > 0 {
+			matched[strings.TrimSpace(cellString(values, 0))] = true
+		}
+	}
+
+	approvedAt := time.Now().Format("2006-01-02 15:04:05")
+	var toAppend [][]interface{}
+	skipped := 0
+	for _, row := range rows {
+		if matched[row.InvoiceID] {
+			skipped++
+			continue
+		}
+		matched[row.InvoiceID] = true
+		toAppend = append(toAppend, []interface{}{
+			row.InvoiceID, row.InvoiceNumber, row.InvoiceType, row.InvoiceDate, row.Counterparty, row.GrossAmount,
+			row.TransactionID, row.TransactionDate, row.TransactionAmount, row.TransactionParty,
+			confidenceValue(row.Confidence), row.Reason, row.Method, approvedAt,
+		})
+	}
+
+	if len(toAppend) > 0 {
+		if err := s.backend.Append(ctx, sheetName+"!A:N", toAppend); err != nil {
+			return 0, skipped, fmt.Errorf("%s: failed to append matches: %w", op, err)
+		}
+	}
+
+	s.log.Info().
+		Int("rows_written", len(toAppend)).
+		Int("rows_skipped", skipped).
+		Msg("Successfully wrote approved matches to Google Sheet")
+
+	return len(toAppend), skipped, nil
+}
@@ -0,0 +1,39 @@
+package sheets_test
+
+import (
+	"context"
+	"testing"
+
+	"tools/internal/sheets"
+	"tools/internal/sheets/sheetstest"
+)
+
+func TestWriteMatchesSkipsAppliedInvoices(t *testing.T) {
+	ctx := context.Background()
+	backend := sheetstest.NewMemoryBackend()
+	service := sheets.NewServiceWithBackend(backend)
+
+	first := []sheets.MatchRow{
+		{InvoiceID: "PAYABLE_RE-1_20240601", InvoiceNumber: "RE-1", TransactionID: "TXN_1", Confidence: 0.97, Method: "rule"},
+	}
+	if written, skipped, err := service.WriteMatches(ctx, first, sheets.ReconciliationSheet); err != nil || written != 1 || skipped != 0 {
+		t.Fatalf("first WriteMatches = %d, %d, %v, want 1, 0", written, skipped, err)
+	}
+
+	second := append(first, sheets.MatchRow{InvoiceID: "PAYABLE_RE-2_20240602", InvoiceNumber: "RE-2", TransactionID: "TXN_2", Method: "chatgpt"})
+	written, skipped, err := service.WriteMatches(ctx, second, sheets.ReconciliationSheet)
+	if err != nil {
+		t.Fatalf("second WriteMatches: %v", err)
+	}
+	if written != 1 || skipped != 1 {
+		t.Errorf("got written=%d skipped=%d, want 1 and 1", written, skipped)
+	}
+
+	tab := backend.Tab(sheets.ReconciliationSheet)
+	if len(tab) != 3 {
+		t.Fatalf("expected header + 2 rows, got %d", len(tab))
+	}
+	if tab[0][0] != "Rechnungs-ID" || tab[2][1] != "RE-2" || tab[2][10] != "" {
+		t.Errorf("unexpected rows: %v", tab)
+	}
+}
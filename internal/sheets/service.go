@@ -3,8 +3,10 @@ package sheets
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,30 +21,50 @@ import (
 
 // Service handles Google Sheets operations
 type Service struct {
-	sheetsService *sheets.Service
-	spreadsheetID string
-	log           zerolog.Logger
+	backend SheetsBackend
+	log     zerolog.Logger
+
+	// linkedSheetID is the gid of the tab the sheet URL points to, if hasLinkedSheet is set
+	linkedSheetID  int64
+	hasLinkedSheet bool
+}
+
+// BatchHeaders is the header row of the Kreditoren and Debitoren sheets, columns A to X
+var BatchHeaders = []string{
+	"Datei", "Rechnungsnr", "Datum", "Lieferant/Kunde", "Netto",
+	"MwSt", "Brutto", "Währung", "Sollkonto", "Habenkonto",
+	"Steuerschlüssel", "Buchungstext", "Kostenstelle", "Beschreibung",
+	"Fälligkeit", "Status", "Verarbeitet", "Konfidenz",
+	"Bestellnr", "Kundenreferenz", "Quelle",
+	"E-Mail Kunde", "Ansprechpartner", "Bezahlt",
 }
 
 // BatchRow represents a row to be written to the sheet
 type BatchRow struct {
-	Filename         string
-	InvoiceNumber    string
-	Date             string
-	VendorCustomer   string
-	NetAmount        float64
-	VATAmount        float64
-	GrossAmount      float64
-	Currency         string
-	DebitAccount     string
-	CreditAccount    string
-	TaxKey           string
-	BookingText      string
-	CostCenter       string
-	Description      string
-	DueDate          string
-	Status           string
-	ProcessedAt      string
+	Filename          string
+	InvoiceNumber     string
+	Date              string
+	VendorCustomer    string
+	NetAmount         float64
+	VATAmount         float64
+	GrossAmount       float64
+	Currency          string
+	DebitAccount      string
+	CreditAccount     string
+	TaxKey            string
+	BookingText       string
+	CostCenter        string
+	Description       string
+	DueDate           string
+	Status            string
+	ProcessedAt       string
+	Confidence        float64 // Overall extraction confidence 0-1, 0 if unknown
+	PurchaseOrder     string
+	CustomerReference string
+	SourceSHA256      string // SHA-256 of the source PDF, to find the exact file behind a booking
+	CustomerEmail     string // Receivables only: who to chase if the invoice stays unpaid
+	CustomerContact   string
+	Paid              string // Payment date, or "ja" without one, if the invoice was marked paid on issue
 }
 
 // NewSheetsService creates a new Google Sheets service
@@ -59,6 +81,12 @@ func NewSheetsService(ctx context.Context, sheetURL string) (*Service, error) {
 
 	log.Debug().Str("spreadsheet_id", spreadsheetID).Msg("Extracted spreadsheet ID")
 
+	// A link to a specific tab carries its sheet ID as gid, e.g. .../edit#gid=123456
+	linkedSheetID, hasLinkedSheet := extractSheetGID(sheetURL)
+	if hasLinkedSheet {
+		log.Debug().Int64("gid", linkedSheetID).Msg("Extracted linked sheet ID")
+	}
+
 	// Get Google credentials
 	var creds []byte
 	if credsFile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); credsFile != "" {
@@ -85,12 +113,24 @@ func NewSheetsService(ctx context.Context, sheetURL string) (*Service, error) {
 	}
 
 	return &Service{
-		sheetsService: sheetsService,
-		spreadsheetID: spreadsheetID,
-		log:           log,
+		backend: &googleBackend{
+			sheetsService: sheetsService,
+			spreadsheetID: spreadsheetID,
+		},
+		log:            log,
+		linkedSheetID:  linkedSheetID,
+		hasLinkedSheet: hasLinkedSheet,
 	}, nil
 }
 
+// NewServiceWithBackend creates a service on top of an explicit backend, e.g. an in-memory fake in tests
+func NewServiceWithBackend(backend SheetsBackend) *Service {
+	return &Service{
+		backend: backend,
+		log:     logger.WithComponent("sheets"),
+	}
+}
+
 // extractSpreadsheetID extracts the spreadsheet ID from a Google Sheets URL
 func extractSpreadsheetID(url string) (string, error) {
 	// Pattern for Google Sheets URLs
@@ -104,6 +144,47 @@ func extractSpreadsheetID(url string) (string, error) {
 	return matches[1], nil
 }
 
+// sheetGIDPattern finds the sheet ID in the fragment or query of a Google Sheets URL
+var sheetGIDPattern = regexp.MustCompile(`[#?&]gid=(\d+)`)
+
+// extractSheetGID extracts the sheet ID (gid) of the linked tab from a Google Sheets URL; ok is
+// false if the URL does not link to a specific tab
+func extractSheetGID(url string) (gid int64, ok bool) {
+	matches := sheetGIDPattern.FindStringSubmatch(url)
+	if matches == nil {
+		return 0, false
+	}
+	gid, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return gid, true
+}
+
+// SheetTitle resolves a sheet ID, the gid in the URL of a tab, to the tab's title
+func (s *Service) SheetTitle(ctx context.Context, gid int64) (string, error) {
+	const op = "SheetTitle"
+
+	title, err := s.backend.SheetTitle(ctx, gid)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return title, nil
+}
+
+// LinkedSheet returns the title of the tab the sheet URL links to with its gid. ok is false if
+// the URL does not name a tab, as for services created with NewServiceWithBackend.
+func (s *Service) LinkedSheet(ctx context.Context) (title string, ok bool, err error) {
+	if !s.hasLinkedSheet {
+		return "", false, nil
+	}
+	title, err = s.SheetTitle(ctx, s.linkedSheetID)
+	if err != nil {
+		return "", false, err
+	}
+	return title, true, nil
+}
+
 // WriteBatchResults writes batch processing results to the specified sheet
 func (s *Service) WriteBatchResults(ctx context.Context, results []BatchResult, sheetName string) error {
 	const op = "WriteBatchResults"
@@ -132,16 +213,7 @@ func (s *Service) WriteBatchResults(ctx context.Context, results []BatchResult,
 	}
 
 	// Write to sheet
-	valueRange := &sheets.ValueRange{
-		Values: values,
-	}
-
-	_, err = s.sheetsService.Spreadsheets.Values.Append(
-		s.spreadsheetID,
-		sheetName+"!A:Q", // A to Q covers all our columns
-		valueRange,
-	).ValueInputOption("USER_ENTERED").Context(ctx).Do()
-
+	err = s.backend.Append(ctx, sheetName+"!A:X", values) // A to X covers all our columns
 	if err != nil {
 		return fmt.Errorf("%s: failed to append values to sheet: %w", op, err)
 	}
@@ -155,11 +227,13 @@ func (s *Service) WriteBatchResults(ctx context.Context, results []BatchResult,
 
 // BatchResult represents the result of processing a single PDF (imported from cmd package concept)
 type BatchResult struct {
-	Filename string
-	Invoice  *models.Invoice
-	Booking  *services.DATEVBooking
-	Error    error
-	Status   string
+	Filename   string
+	Invoice    *models.Invoice
+	Booking    *services.DATEVBooking
+	Error      error
+	Status     string
+	Confidence map[string]float32 // Per-field confidence from Document AI and completion
+	Remarks    []string           // Notes for the bookkeeper appended to the description, e.g. a disagreeing sample check
 }
 
 // convertResultsToRows converts BatchResult slice to BatchRow slice
@@ -174,9 +248,16 @@ func (s *Service) convertResultsToRows(results []BatchResult) ([]BatchRow, error
 			ProcessedAt: processedAt,
 		}
 
+		if overall, ok := overallConfidence(result.Confidence); ok {
+			row.Confidence = math.Round(overall*100) / 100
+		}
+
 		// Handle error cases
 		if result.Error != nil {
 			row.Description = fmt.Sprintf("Fehler: %s", result.Error.Error())
+			if result.Status == "skipped" || result.Status == "skipped-duplicate" {
+				row.Description = fmt.Sprintf("Übersprungen: %s", result.Error.Error())
+			}
 			rows = append(rows, row)
 			continue
 		}
@@ -189,11 +270,15 @@ func (s *Service) convertResultsToRows(results []BatchResult) ([]BatchRow, error
 			row.VATAmount = float64(result.Invoice.VATAmount) / 100
 			row.GrossAmount = float64(result.Invoice.GrossAmount) / 100
 			row.Description = result.Invoice.AccountingSummary
+			row.PurchaseOrder = result.Invoice.PurchaseOrder
+			row.CustomerReference = result.Invoice.CustomerReference
 			
 			if result.Invoice.Type == "PAYABLE" {
 				row.VendorCustomer = result.Invoice.Vendor
 			} else {
 				row.VendorCustomer = result.Invoice.Customer
+				row.CustomerEmail = result.Invoice.CustomerEmail
+				row.CustomerContact = result.Invoice.CustomerContact
 			}
 
 			if !result.Invoice.IssueDate.IsZero() {
@@ -202,6 +287,12 @@ func (s *Service) convertResultsToRows(results []BatchResult) ([]BatchRow, error
 			if !result.Invoice.DueDate.IsZero() {
 				row.DueDate = result.Invoice.DueDate.Format("02.01.2006")
 			}
+			if result.Invoice.IsPaid {
+				row.Paid = "ja"
+				if result.Invoice.PaymentDate != nil && !result.Invoice.PaymentDate.IsZero() {
+					row.Paid = result.Invoice.PaymentDate.Format("02.01.2006")
+				}
+			}
 		}
 
 		// Fill booking data
@@ -211,6 +302,16 @@ func (s *Service) convertResultsToRows(results []BatchResult) ([]BatchRow, error
 			row.TaxKey = result.Booking.TaxKey
 			row.BookingText = result.Booking.BookingText
 			row.CostCenter = result.Booking.CostCenter
+			row.SourceSHA256 = result.Booking.SourceSHA256
+		}
+
+		// Remarks follow the accounting summary so that the reason for a warning is visible in the row
+		if len(result.Remarks) > 0 {
+			parts := result.Remarks
+			if row.Description != "" {
+				parts = append([]string{row.Description}, parts...)
+			}
+			row.Description = strings.Join(parts, "; ")
 		}
 
 		rows = append(rows, row)
@@ -239,81 +340,77 @@ func (s *Service) rowToValues(row BatchRow) []interface{} {
 		row.DueDate,          // O: Fälligkeit
 		row.Status,           // P: Status
 		row.ProcessedAt,      // Q: Verarbeitet
+		confidenceValue(row.Confidence), // R: Konfidenz
+		row.PurchaseOrder,    // S: Bestellnr
+		row.CustomerReference, // T: Kundenreferenz
+		row.SourceSHA256,     // U: Quelle
+		row.CustomerEmail,    // V: E-Mail Kunde
+		row.CustomerContact,  // W: Ansprechpartner
+		row.Paid,             // X: Bezahlt
 	}
 }
 
-// ensureSheetWithHeaders ensures the sheet exists and has proper headers
-func (s *Service) ensureSheetWithHeaders(ctx context.Context, sheetName string) error {
-	const op = "ensureSheetWithHeaders"
+// confidenceValue leaves the cell empty when no confidence is known
+func confidenceValue(confidence float64) interface{} {
+	if confidence == 0 {
+		return ""
+	}
+	return confidence
+}
 
-	// Check if sheet exists
-	spreadsheet, err := s.sheetsService.Spreadsheets.Get(s.spreadsheetID).Context(ctx).Do()
-	if err != nil {
-		return fmt.Errorf("%s: failed to get spreadsheet: %w", op, err)
+// overallConfidence averages the per-field confidence scores; ok is false when there are none
+func overallConfidence(confidence map[string]float32) (float64, bool) {
+	if len(confidence) == 0 {
+		return 0, false
 	}
 
-	// Look for existing sheet
-	var sheetExists bool
-	var sheetID int64
-	for _, sheet := range spreadsheet.Sheets {
-		if sheet.Properties.Title == sheetName {
-			sheetExists = true
-			sheetID = sheet.Properties.SheetId
-			break
-		}
+	var sum float64
+	for _, conf := range confidence {
+		sum += float64(conf)
 	}
 
-	// Create sheet if it doesn't exist
-	if !sheetExists {
-		s.log.Info().Str("sheet", sheetName).Msg("Creating new sheet")
-		
-		addSheetReq := &sheets.AddSheetRequest{
-			Properties: &sheets.SheetProperties{
-				Title: sheetName,
-			},
-		}
+	return sum / float64(len(confidence)), true
+}
 
-		batchUpdateReq := &sheets.BatchUpdateSpreadsheetRequest{
-			Requests: []*sheets.Request{
-				{AddSheet: addSheetReq},
-			},
-		}
+// ensureSheetWithHeaders ensures the sheet exists and has proper headers
+func (s *Service) ensureSheetWithHeaders(ctx context.Context, sheetName string) error {
+	const op = "ensureSheetWithHeaders"
 
-		resp, err := s.sheetsService.Spreadsheets.BatchUpdate(s.spreadsheetID, batchUpdateReq).Context(ctx).Do()
-		if err != nil {
-			return fmt.Errorf("%s: failed to create sheet: %w", op, err)
-		}
-		
-		sheetID = resp.Replies[0].AddSheet.Properties.SheetId
+	// Create sheet if it doesn't exist
+	sheetID, err := s.backend.EnsureSheet(ctx, sheetName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	// Check if headers exist
-	headerRange := fmt.Sprintf("%s!A1:Q1", sheetName)
-	resp, err := s.sheetsService.Spreadsheets.Values.Get(s.spreadsheetID, headerRange).Context(ctx).Do()
+	headerRange := fmt.Sprintf("%s!A1:X1", sheetName)
+	existing, err := s.backend.ReadRange(ctx, headerRange)
 	if err != nil {
 		return fmt.Errorf("%s: failed to get headers: %w", op, err)
 	}
 
-	// Add headers if they don't exist or are empty
-	if len(resp.Values) == 0 || len(resp.Values[0]) == 0 {
-		s.log.Info().Str("sheet", sheetName).Msg("Adding headers to sheet")
-		
-		headers := [][]interface{}{
-			{
-				"Datei", "Rechnungsnr", "Datum", "Lieferant/Kunde", "Netto", 
-				"MwSt", "Brutto", "Währung", "Sollkonto", "Habenkonto", 
-				"Steuerschlüssel", "Buchungstext", "Kostenstelle", "Beschreibung", 
-				"Fälligkeit", "Status", "Verarbeitet",
-			},
+	headers := [][]interface{}{make([]interface{}, len(BatchHeaders))}
+	for i, header := range BatchHeaders {
+		headers[0][i] = header
+	}
+
+	// Sheets created before the confidence column existed only need the missing header cell
+	if len(existing) > 0 && len(existing[0]) > 0 && len(existing[0]) < len(headers[0]) {
+		s.log.Info().Str("sheet", sheetName).Msg("Extending headers with new columns")
+		if err := s.backend.Update(ctx, headerRange, headers); err != nil {
+			return fmt.Errorf("%s: failed to extend headers: %w", op, err)
 		}
+		if err := s.formatConfidenceColumn(ctx, sheetID); err != nil {
+			s.log.Warn().Err(err).Msg("Failed to format confidence column, continuing anyway")
+		}
+		return nil
+	}
 
-		valueRange := &sheets.ValueRange{Values: headers}
-		_, err = s.sheetsService.Spreadsheets.Values.Update(
-			s.spreadsheetID,
-			headerRange,
-			valueRange,
-		).ValueInputOption("RAW").Context(ctx).Do()
+	// Add headers if they don't exist or are empty
+	if len(existing) == 0 || len(existing[0]) == 0 {
+		s.log.Info().Str("sheet", sheetName).Msg("Adding headers to sheet")
 
+		err = s.backend.Update(ctx, headerRange, headers)
 		if err != nil {
 			return fmt.Errorf("%s: failed to add headers: %w", op, err)
 		}
@@ -323,6 +420,10 @@ func (s *Service) ensureSheetWithHeaders(ctx context.Context, sheetName string)
 		if err != nil {
 			s.log.Warn().Err(err).Msg("Failed to format headers, continuing anyway")
 		}
+
+		if err := s.formatConfidenceColumn(ctx, sheetID); err != nil {
+			s.log.Warn().Err(err).Msg("Failed to format confidence column, continuing anyway")
+		}
 	}
 
 	return nil
@@ -341,7 +442,7 @@ func (s *Service) formatHeaders(ctx context.Context, sheetID int64, sheetName st
 					StartRowIndex: 0,
 					EndRowIndex:   1,
 					StartColumnIndex: 0,
-					EndColumnIndex: 17, // A to Q
+					EndColumnIndex: 24, // A to X
 				},
 				Cell: &sheets.CellData{
 					UserEnteredFormat: &sheets.CellFormat{
@@ -365,14 +466,13 @@ func (s *Service) formatHeaders(ctx context.Context, sheetID int64, sheetName st
 					SheetId:    sheetID,
 					Dimension:  "COLUMNS",
 					StartIndex: 0,
-					EndIndex:   17,
+					EndIndex:   24,
 				},
 			},
 		},
 	}
 
-	batchUpdateReq := &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}
-	_, err := s.sheetsService.Spreadsheets.BatchUpdate(s.spreadsheetID, batchUpdateReq).Context(ctx).Do()
+	err := s.backend.BatchUpdate(ctx, requests)
 	if err != nil {
 		return fmt.Errorf("%s: failed to format headers: %w", op, err)
 	}
@@ -380,6 +480,54 @@ func (s *Service) formatHeaders(ctx context.Context, sheetID int64, sheetName st
 	return nil
 }
 
+// formatConfidenceColumn colours the Konfidenz column red below 0.7, yellow below 0.9 and green otherwise
+func (s *Service) formatConfidenceColumn(ctx context.Context, sheetID int64) error {
+	const op = "formatConfidenceColumn"
+
+	confidenceRange := &sheets.GridRange{
+		SheetId:          sheetID,
+		StartRowIndex:    1, // Skip header
+		StartColumnIndex: 17,
+		EndColumnIndex:   18, // R
+	}
+
+	rule := func(index int64, conditionType string, values []string, red, green, blue float64) *sheets.Request {
+		var conditionValues []*sheets.ConditionValue
+		for _, v := range values {
+			conditionValues = append(conditionValues, &sheets.ConditionValue{UserEnteredValue: v})
+		}
+		return &sheets.Request{
+			AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+				Index: index,
+				Rule: &sheets.ConditionalFormatRule{
+					Ranges: []*sheets.GridRange{confidenceRange},
+					BooleanRule: &sheets.BooleanRule{
+						Condition: &sheets.BooleanCondition{
+							Type:   conditionType,
+							Values: conditionValues,
+						},
+						Format: &sheets.CellFormat{
+							BackgroundColor: &sheets.Color{Red: red, Green: green, Blue: blue},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	requests := []*sheets.Request{
+		rule(0, "NUMBER_LESS", []string{"0.7"}, 0.96, 0.8, 0.8),
+		rule(1, "NUMBER_LESS", []string{"0.9"}, 1.0, 0.95, 0.75),
+		rule(2, "NUMBER_GREATER_THAN_EQ", []string{"0.9"}, 0.85, 0.92, 0.83),
+	}
+
+	if err := s.backend.BatchUpdate(ctx, requests); err != nil {
+		return fmt.Errorf("%s: failed to add conditional formatting: %w", op, err)
+	}
+
+	return nil
+}
+
 // normalizeCurrency standardizes currency codes to consistent format
 func (s *Service) normalizeCurrency(currency string) string {
 	if currency == "" {
@@ -419,15 +567,15 @@ func (s *Service) ReadRange(ctx context.Context, rangeSpec string) ([][]interfac
 		Str("range", rangeSpec).
 		Msg("Reading range from spreadsheet")
 
-	resp, err := s.sheetsService.Spreadsheets.Values.Get(s.spreadsheetID, rangeSpec).Context(ctx).Do()
+	values, err := s.backend.ReadRange(ctx, rangeSpec)
 	if err != nil {
 		return nil, fmt.Errorf("%s: failed to read range %s: %w", op, rangeSpec, err)
 	}
 
 	s.log.Debug().
-		Int("rows", len(resp.Values)).
+		Int("rows", len(values)).
 		Str("range", rangeSpec).
 		Msg("Successfully read range from spreadsheet")
 
-	return resp.Values, nil
+	return values, nil
 }
\ No newline at end of file
@@ -0,0 +1,24 @@
+package sheets
+
+import "testing"
+
+func TestExtractSheetGID(t *testing.T) {
+	tests := []struct {
+		url    string
+		wantID int64
+		wantOK bool
+	}{
+		{"https://docs.google.com/spreadsheets/d/abc123/edit#gid=1234567", 1234567, true},
+		{"https://docs.google.com/spreadsheets/d/abc123/edit?gid=42#gid=42", 42, true},
+		{"https://docs.google.com/spreadsheets/d/abc123/edit#gid=0", 0, true},
+		{"https://docs.google.com/spreadsheets/d/abc123/edit", 0, false},
+		{"https://docs.google.com/spreadsheets/d/abc123/edit#gid=", 0, false},
+	}
+
+	for _, tt := range tests {
+		gid, ok := extractSheetGID(tt.url)
+		if gid != tt.wantID || ok != tt.wantOK {
+			t.Errorf("extractSheetGID(%q) = %d, %v, want %d, %v", tt.url, gid, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
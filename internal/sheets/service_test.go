@@ -0,0 +1,253 @@
+package sheets_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tools/internal/sheets"
+	"tools/internal/sheets/sheetstest"
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+var _ sheets.SheetsBackend = (*sheetstest.MemoryBackend)(nil)
+
+func TestWriteBatchResultsCreatesSheetWithHeaders(t *testing.T) {
+	ctx := context.Background()
+	backend := sheetstest.NewMemoryBackend()
+	service := sheets.NewServiceWithBackend(backend)
+
+	results := []sheets.BatchResult{
+		{
+			Filename: "rechnung.pdf",
+			Invoice: &models.Invoice{
+				InvoiceNumber:     "RE-1001",
+				Type:              "PAYABLE",
+				Vendor:            "Muster GmbH",
+				IssueDate:         time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+				NetAmount:         10000,
+				VATAmount:         1900,
+				GrossAmount:       11900,
+				Currency:          "€",
+				PurchaseOrder:     "PO-4711",
+				CustomerReference: "Projekt Nord",
+			},
+			Booking: &services.DATEVBooking{
+				DebitAccount:  "4930",
+				CreditAccount: "1600",
+				TaxKey:        "9",
+			},
+			Status:     "SUCCESS",
+			Confidence: map[string]float32{"invoice_number": 0.9, "gross_amount": 0.6},
+		},
+		{
+			Filename: "kaputt.pdf",
+			Error:    errors.New("document unreadable"),
+			Status:   "ERROR",
+		},
+	}
+
+	if err := service.WriteBatchResults(ctx, results, "Kreditoren"); err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+
+	tab := backend.Tab("Kreditoren")
+	if len(tab) != 3 {
+		t.Fatalf("expected header + 2 rows, got %d rows", len(tab))
+	}
+	if tab[0][0] != "Datei" || tab[0][17] != "Konfidenz" {
+		t.Errorf("unexpected header row: %v", tab[0])
+	}
+
+	row := tab[1]
+	want := map[int]interface{}{0: "rechnung.pdf", 1: "RE-1001", 2: "15.03.2024", 3: "Muster GmbH", 6: 119.0, 7: "EUR", 8: "4930", 9: "1600", 15: "SUCCESS", 17: 0.75, 18: "PO-4711", 19: "Projekt Nord"}
+	for col, value := range want {
+		if row[col] != value {
+			t.Errorf("column %d: got %v, want %v", col, row[col], value)
+		}
+	}
+
+	if got := tab[2][13]; got != "Fehler: document unreadable" {
+		t.Errorf("error row description: got %v", got)
+	}
+	if got := tab[2][17]; got != "" {
+		t.Errorf("error row should have no confidence, got %v", got)
+	}
+
+	if len(backend.Requests) == 0 || backend.Requests[0].RepeatCell == nil {
+		t.Errorf("expected header formatting request, got %v", backend.Requests)
+	}
+	var conditionalRules int
+	for _, req := range backend.Requests {
+		if req.AddConditionalFormatRule != nil {
+			conditionalRules++
+		}
+	}
+	if conditionalRules != 3 {
+		t.Errorf("expected 3 conditional format rules for the confidence column, got %d", conditionalRules)
+	}
+
+	// A second write must not duplicate the header
+	if err := service.WriteBatchResults(ctx, results[:1], "Kreditoren"); err != nil {
+		t.Fatalf("second WriteBatchResults: %v", err)
+	}
+	if tab := backend.Tab("Kreditoren"); len(tab) != 4 || tab[3][0] != "rechnung.pdf" {
+		t.Errorf("expected appended row without new header, got %v", tab)
+	}
+}
+
+func TestWriteBatchResultsCustomerContact(t *testing.T) {
+	backend := sheetstest.NewMemoryBackend()
+	service := sheets.NewServiceWithBackend(backend)
+
+	contact := models.Invoice{
+		InvoiceNumber:   "AR-2024-17",
+		Type:            "RECEIVABLE",
+		Customer:        "Kunde AG",
+		CustomerEmail:   "buchhaltung@kunde.example",
+		CustomerContact: "Frau Müller",
+		GrossAmount:     11900,
+	}
+	payable := contact
+	payable.Type = "PAYABLE"
+	results := []sheets.BatchResult{
+		{Filename: "ar.pdf", Invoice: &contact, Status: "success"},
+		{Filename: "er.pdf", Invoice: &payable, Status: "success"},
+	}
+	if err := service.WriteBatchResults(context.Background(), results, "Debitoren"); err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+
+	tab := backend.Tab("Debitoren")
+	if tab[1][21] != "buchhaltung@kunde.example" || tab[1][22] != "Frau Müller" {
+		t.Errorf("receivable row contact = %v, %v", tab[1][21], tab[1][22])
+	}
+	// On payables the customer is our own company
+	if tab[2][21] != "" || tab[2][22] != "" {
+		t.Errorf("payable row should have no customer contact, got %v, %v", tab[2][21], tab[2][22])
+	}
+}
+
+func TestWriteBatchResultsPaid(t *testing.T) {
+	backend := sheetstest.NewMemoryBackend()
+	service := sheets.NewServiceWithBackend(backend)
+
+	paidOn := time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC)
+	results := []sheets.BatchResult{
+		{Filename: "dated.pdf", Invoice: &models.Invoice{InvoiceNumber: "1", Type: "PAYABLE", IsPaid: true, PaymentDate: &paidOn}, Status: "success"},
+		{Filename: "undated.pdf", Invoice: &models.Invoice{InvoiceNumber: "2", Type: "PAYABLE", IsPaid: true}, Status: "success"},
+		{Filename: "open.pdf", Invoice: &models.Invoice{InvoiceNumber: "3", Type: "PAYABLE"}, Status: "success"},
+	}
+	if err := service.WriteBatchResults(context.Background(), results, "Kreditoren"); err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+
+	tab := backend.Tab("Kreditoren")
+	for i, want := range []string{"12.03.2024", "ja", ""} {
+		if tab[i+1][23] != want {
+			t.Errorf("row %d Bezahlt = %v, want %q", i+1, tab[i+1][23], want)
+		}
+	}
+}
+
+func TestWriteBatchResultsSkippedDescription(t *testing.T) {
+	backend := sheetstest.NewMemoryBackend()
+	service := sheets.NewServiceWithBackend(backend)
+
+	results := []sheets.BatchResult{
+		{Filename: "mahnung.pdf", Error: errors.New("Mahnung ohne Gebühr"), Status: "skipped"},
+		{Filename: "kopie.pdf", Error: errors.New("Duplikat von rechnung.pdf"), Status: "skipped-duplicate"},
+		{Filename: "kaputt.pdf", Error: errors.New("document unreadable"), Status: "error"},
+	}
+	if err := service.WriteBatchResults(context.Background(), results, "Kreditoren"); err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+
+	tab := backend.Tab("Kreditoren")
+	for i, want := range []string{"Übersprungen: Mahnung ohne Gebühr", "Übersprungen: Duplikat von rechnung.pdf", "Fehler: document unreadable"} {
+		if tab[i+1][13] != want {
+			t.Errorf("row %d Beschreibung = %v, want %q", i+1, tab[i+1][13], want)
+		}
+	}
+}
+
+func TestWriteBatchResultsRemarks(t *testing.T) {
+	backend := sheetstest.NewMemoryBackend()
+	service := sheets.NewServiceWithBackend(backend)
+
+	remark := "Stichprobe (gpt-4o) weicht ab: Sollkonto 4930 ≠ 4980"
+	results := []sheets.BatchResult{
+		{Filename: "summary.pdf", Invoice: &models.Invoice{InvoiceNumber: "1", Type: "PAYABLE", AccountingSummary: "Büromaterial"}, Status: "warning", Remarks: []string{remark}},
+		{Filename: "plain.pdf", Invoice: &models.Invoice{InvoiceNumber: "2", Type: "PAYABLE"}, Status: "warning", Remarks: []string{remark}},
+		{Filename: "none.pdf", Invoice: &models.Invoice{InvoiceNumber: "3", Type: "PAYABLE", AccountingSummary: "Miete"}, Status: "success"},
+	}
+	if err := service.WriteBatchResults(context.Background(), results, "Kreditoren"); err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+
+	tab := backend.Tab("Kreditoren")
+	for i, want := range []string{"Büromaterial; " + remark, remark, "Miete"} {
+		if tab[i+1][13] != want {
+			t.Errorf("row %d Beschreibung = %v, want %q", i+1, tab[i+1][13], want)
+		}
+	}
+}
+
+func TestWriteBatchResultsExtendsLegacyHeaders(t *testing.T) {
+	backend := sheetstest.NewMemoryBackend()
+	legacy := []interface{}{
+		"Datei", "Rechnungsnr", "Datum", "Lieferant/Kunde", "Netto",
+		"MwSt", "Brutto", "Währung", "Sollkonto", "Habenkonto",
+		"Steuerschlüssel", "Buchungstext", "Kostenstelle", "Beschreibung",
+		"Fälligkeit", "Status", "Verarbeitet",
+	}
+	backend.SetTab("Kreditoren", [][]interface{}{legacy})
+
+	service := sheets.NewServiceWithBackend(backend)
+	if err := service.WriteBatchResults(context.Background(), []sheets.BatchResult{{Filename: "a.pdf", Status: "SUCCESS"}}, "Kreditoren"); err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+
+	tab := backend.Tab("Kreditoren")
+	if len(tab) != 2 {
+		t.Fatalf("expected header + 1 row, got %d rows", len(tab))
+	}
+	if len(tab[0]) != 24 || tab[0][17] != "Konfidenz" || tab[0][19] != "Kundenreferenz" || tab[0][20] != "Quelle" || tab[0][22] != "Ansprechpartner" || tab[0][23] != "Bezahlt" {
+		t.Errorf("expected header extended with Konfidenz and reference columns, got %v", tab[0])
+	}
+}
+
+func TestReadRangeUnknownSheet(t *testing.T) {
+	service := sheets.NewServiceWithBackend(sheetstest.NewMemoryBackend())
+
+	if _, err := service.ReadRange(context.Background(), "Bank!A:K"); err == nil {
+		t.Fatal("expected error for missing sheet")
+	}
+}
+
+func TestSheetTitle(t *testing.T) {
+	backend := sheetstest.NewMemoryBackend()
+	backend.SetTab("Kreditoren", nil)
+	backend.SetTab("Bank 2025", nil)
+	service := sheets.NewServiceWithBackend(backend)
+
+	gid, _ := backend.SheetID("Bank 2025")
+	title, err := service.SheetTitle(context.Background(), gid)
+	if err != nil {
+		t.Fatalf("SheetTitle() error = %v", err)
+	}
+	if title != "Bank 2025" {
+		t.Errorf("SheetTitle(%d) = %q, want %q", gid, title, "Bank 2025")
+	}
+
+	if _, err := service.SheetTitle(context.Background(), 999); err == nil {
+		t.Error("SheetTitle(999) error = nil, want error for unknown gid")
+	}
+
+	// Without a sheet URL no tab is linked
+	if _, ok, err := service.LinkedSheet(context.Background()); ok || err != nil {
+		t.Errorf("LinkedSheet() = ok %v, err %v, want no linked tab", ok, err)
+	}
+}
@@ -0,0 +1,277 @@
+// Package sheetstest provides an in-memory sheets backend for tests that must not reach Google
+package sheetstest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// MemoryBackend implements sheets.SheetsBackend by keeping every tab as a grid of cell values
+type MemoryBackend struct {
+	mu       sync.Mutex
+	tabs     map[string][][]interface{}
+	sheetIDs map[string]int64
+	nextID   int64
+
+	// Requests records every BatchUpdate request in the order it was received
+	Requests []*sheets.Request
+}
+
+// NewMemoryBackend creates an empty in-memory spreadsheet
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		tabs:     make(map[string][][]interface{}),
+		sheetIDs: make(map[string]int64),
+		nextID:   1,
+	}
+}
+
+// SetTab replaces the contents of a tab, creating it if necessary
+func (m *MemoryBackend) SetTab(sheetName string, rows [][]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addTab(sheetName)
+	m.tabs[sheetName] = copyRows(rows)
+}
+
+// Tab returns a copy of a tab's contents, or nil if the tab does not exist
+func (m *MemoryBackend) Tab(sheetName string) [][]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return copyRows(m.tabs[sheetName])
+}
+
+// ReadRange returns the cells inside rangeSpec, dropping trailing empty rows like the Sheets API
+func (m *MemoryBackend) ReadRange(ctx context.Context, rangeSpec string) ([][]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, err := parseRange(rangeSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, ok := m.tabs[r.sheet]
+	if !ok {
+		return nil, fmt.Errorf("unable to parse range: %s", rangeSpec)
+	}
+
+	var values [][]interface{}
+	for i := r.startRow; i < len(rows) && (r.endRow < 0 || i <= r.endRow); i++ {
+		row := rows[i]
+		var cells []interface{}
+		for j := r.startCol; j < len(row) && (r.endCol < 0 || j <= r.endCol); j++ {
+			cells = append(cells, row[j])
+		}
+		values = append(values, cells)
+	}
+
+	for len(values) > 0 && len(values[len(values)-1]) == 0 {
+		values = values[:len(values)-1]
+	}
+
+	return values, nil
+}
+
+// Append adds the rows below the last row of the tab, starting at the range's first column
+func (m *MemoryBackend) Append(ctx context.Context, rangeSpec string, values [][]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, err := parseRange(rangeSpec)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.tabs[r.sheet]; !ok {
+		return fmt.Errorf("unable to parse range: %s", rangeSpec)
+	}
+
+	for _, row := range values {
+		cells := make([]interface{}, r.startCol, r.startCol+len(row))
+		for j := range cells {
+			cells[j] = ""
+		}
+		m.tabs[r.sheet] = append(m.tabs[r.sheet], append(cells, row...))
+	}
+
+	return nil
+}
+
+// Update overwrites cells starting at the range's top-left corner, growing the grid as needed
+func (m *MemoryBackend) Update(ctx context.Context, rangeSpec string, values [][]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, err := parseRange(rangeSpec)
+	if err != nil {
+		return err
+	}
+	rows, ok := m.tabs[r.sheet]
+	if !ok {
+		return fmt.Errorf("unable to parse range: %s", rangeSpec)
+	}
+
+	for i, row := range values {
+		rowIdx := r.startRow + i
+		for len(rows) <= rowIdx {
+			rows = append(rows, nil)
+		}
+		for j, value := range row {
+			colIdx := r.startCol + j
+			for len(rows[rowIdx]) <= colIdx {
+				rows[rowIdx] = append(rows[rowIdx], "")
+			}
+			rows[rowIdx][colIdx] = value
+		}
+	}
+	m.tabs[r.sheet] = rows
+
+	return nil
+}
+
+// EnsureSheet creates an empty tab if it does not exist yet
+func (m *MemoryBackend) EnsureSheet(ctx context.Context, sheetName string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.addTab(sheetName), nil
+}
+
+// SheetTitle returns the name of the tab with the given ID
+func (m *MemoryBackend) SheetTitle(ctx context.Context, sheetID int64) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, id := range m.sheetIDs {
+		if id == sheetID {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no sheet with gid %d", sheetID)
+}
+
+// SheetID returns the ID of a tab, which tests use as the gid of its URL; ok is false if the tab
+// does not exist
+func (m *MemoryBackend) SheetID(sheetName string) (id int64, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, ok = m.sheetIDs[sheetName]
+	return id, ok
+}
+
+// BatchUpdate records the requests and applies AddSheet requests
+func (m *MemoryBackend) BatchUpdate(ctx context.Context, requests []*sheets.Request) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, req := range requests {
+		if req.AddSheet != nil && req.AddSheet.Properties != nil {
+			m.addTab(req.AddSheet.Properties.Title)
+		}
+	}
+	m.Requests = append(m.Requests, requests...)
+
+	return nil
+}
+
+// addTab creates the tab if missing and returns its ID; callers must hold mu
+func (m *MemoryBackend) addTab(sheetName string) int64 {
+	if id, ok := m.sheetIDs[sheetName]; ok {
+		return id
+	}
+	id := m.nextID
+	m.nextID++
+	m.sheetIDs[sheetName] = id
+	m.tabs[sheetName] = [][]interface{}{}
+	return id
+}
+
+// a1Range is a parsed A1 range with 0-based inclusive bounds; -1 means unbounded
+type a1Range struct {
+	sheet    string
+	startRow int
+	endRow   int
+	startCol int
+	endCol   int
+}
+
+// parseRange parses ranges of the form "Sheet!A:K", "Sheet!A1:Q1" or "Sheet!A2"
+func parseRange(rangeSpec string) (a1Range, error) {
+	sheet, cells, found := strings.Cut(rangeSpec, "!")
+	if !found || sheet == "" {
+		return a1Range{}, fmt.Errorf("unsupported range %q: sheet name required", rangeSpec)
+	}
+	sheet = strings.Trim(sheet, "'")
+
+	start, end, hasEnd := strings.Cut(cells, ":")
+	startCol, startRow, err := parseCell(start)
+	if err != nil {
+		return a1Range{}, fmt.Errorf("unsupported range %q: %w", rangeSpec, err)
+	}
+
+	r := a1Range{sheet: sheet, startRow: 0, endRow: -1, startCol: startCol, endCol: -1}
+	if startRow >= 0 {
+		r.startRow = startRow
+	}
+	if !hasEnd {
+		// A single cell
+		r.endCol = startCol
+		r.endRow = r.startRow
+		return r, nil
+	}
+
+	endCol, endRow, err := parseCell(end)
+	if err != nil {
+		return a1Range{}, fmt.Errorf("unsupported range %q: %w", rangeSpec, err)
+	}
+	r.endCol = endCol
+	r.endRow = endRow
+
+	return r, nil
+}
+
+// parseCell parses "B7" into column 1, row 6; the row is -1 when omitted ("B")
+func parseCell(cell string) (int, int, error) {
+	cell = strings.ToUpper(strings.TrimSpace(cell))
+
+	i := 0
+	col := 0
+	for i < len(cell) && cell[i] >= 'A' && cell[i] <= 'Z' {
+		col = col*26 + int(cell[i]-'A'+1)
+		i++
+	}
+	if col == 0 {
+		return 0, 0, fmt.Errorf("missing column in %q", cell)
+	}
+
+	if i == len(cell) {
+		return col - 1, -1, nil
+	}
+
+	row, err := strconv.Atoi(cell[i:])
+	if err != nil || row < 1 {
+		return 0, 0, fmt.Errorf("invalid row in %q", cell)
+	}
+
+	return col - 1, row - 1, nil
+}
+
+// copyRows deep-copies a grid so callers cannot mutate the backend's state
+func copyRows(rows [][]interface{}) [][]interface{} {
+	if rows == nil {
+		return nil
+	}
+	out := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		out[i] = append([]interface{}(nil), row...)
+	}
+	return out
+}
@@ -0,0 +1,155 @@
+package sheets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// BookingKey identifies an invoice across batch runs. Invoices are keyed by counterparty and
+// invoice number; rows without an invoice number fall back to the source file name.
+func BookingKey(invoiceNumber, counterparty, filename string) string {
+	invoiceNumber = strings.ToLower(strings.TrimSpace(invoiceNumber))
+	if invoiceNumber == "" {
+		return fileKey(filename)
+	}
+	return strings.ToLower(strings.TrimSpace(counterparty)) + "|" + invoiceNumber
+}
+
+// fileKey is the BookingKey fallback for rows without an invoice number
+func fileKey(filename string) string {
+	return "file:" + strings.ToLower(strings.TrimSpace(filename))
+}
+
+// UpsertBatchResults writes batch results like WriteBatchResults, but overwrites rows that already
+// exist in the sheet (matched by BookingKey, or by file name for earlier error rows) instead of
+// appending duplicates. It returns the number of updated and appended rows.
+func (s *Service) UpsertBatchResults(ctx context.Context, results []BatchResult, sheetName string) (int, int, error) {
+	const op = "UpsertBatchResults"
+
+	s.log.Info().
+		Str("sheet", sheetName).
+		Int("rows", len(results)).
+		Msg("Upserting batch results to Google Sheet")
+
+	rows, err := s.convertResultsToRows(results)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: failed to convert results to rows: %w", op, err)
+	}
+
+	if err := s.ensureSheetWithHeaders(ctx, sheetName); err != nil {
+		return 0, 0, fmt.Errorf("%s: failed to ensure sheet exists: %w", op, err)
+	}
+
+	// Map keys of the existing rows to their 1-based sheet row number
+	existing, err := s.backend.ReadRange(ctx, sheetName+"!A:X")
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: failed to read existing rows: %w", op, err)
+	}
+
+	rowByKey := make(map[string]int)
+	rowByFile := make(map[string]int)
+	for i, values := range existing {
+		if i == 0 {
+			continue // Header
+		}
+		filename := cellString(values, 0)
+		rowByKey[BookingKey(cellString(values, 1), cellString(values, 3), filename)] = i + 1
+		if filename != "" {
+			rowByFile[fileKey(filename)] = i + 1
+		}
+	}
+
+	var toAppend [][]interface{}
+	pendingByKey := make(map[string]int)
+	updated := 0
+	for _, row := range rows {
+		values := s.rowToValues(row)
+		key := BookingKey(row.InvoiceNumber, row.VendorCustomer, row.Filename)
+
+		rowNum, found := rowByKey[key]
+		if !found {
+			// A corrected invoice replaces the error row an earlier run wrote for the same file
+			rowNum, found = rowByFile[fileKey(row.Filename)]
+		}
+
+		if found {
+			rangeSpec := fmt.Sprintf("%s!A%d:X%d", sheetName, rowNum, rowNum)
+			if err := s.backend.Update(ctx, rangeSpec, [][]interface{}{values}); err != nil {
+				return updated, 0, fmt.Errorf("%s: failed to update row %d: %w", op, rowNum, err)
+			}
+			updated++
+			continue
+		}
+
+		// Duplicates within the same batch collapse onto one new row
+		if idx, pending := pendingByKey[key]; pending {
+			toAppend[idx] = values
+			continue
+		}
+		pendingByKey[key] = len(toAppend)
+		toAppend = append(toAppend, values)
+	}
+
+	if len(toAppend) > 0 {
+		if err := s.backend.Append(ctx, sheetName+"!A:X", toAppend); err != nil {
+			return updated, 0, fmt.Errorf("%s: failed to append values to sheet: %w", op, err)
+		}
+	}
+
+	s.log.Info().
+		Int("rows_updated", updated).
+		Int("rows_appended", len(toAppend)).
+		Msg("Successfully upserted batch results to Google Sheet")
+
+	return updated, len(toAppend), nil
+}
+
+// cellString returns a sheet cell as trimmed string, empty if the row is shorter
+func cellString(row []interface{}, index int) string {
+	if index >= len(row) || row[index] == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v", row[index]))
+}
+
+// BookedRow is an invoice a sheet row records as booked
+type BookedRow struct {
+	Row          int    // 1-based sheet row number
+	Filename     string // Datei
+	Key          string // BookingKey of counterparty and invoice number
+	SourceSHA256 string // SHA-256 of the source PDF, empty for rows written before the Quelle column
+}
+
+// BookedRows returns the rows of the sheet with a booked invoice, i.e. with an invoice number and
+// the status success or warning. Error and skipped rows are left out, since their files were not
+// booked.
+func (s *Service) BookedRows(ctx context.Context, sheetName string) ([]BookedRow, error) {
+	const op = "BookedRows"
+
+	existing, err := s.backend.ReadRange(ctx, sheetName+"!A:X")
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read %s: %w", op, sheetName, err)
+	}
+
+	var booked []BookedRow
+	for i, values := range existing {
+		if i == 0 {
+			continue // Header
+		}
+		invoiceNumber := cellString(values, 1)
+		status := cellString(values, 15)
+		if invoiceNumber == "" || (status != "success" && status != "warning") {
+			continue
+		}
+		filename := cellString(values, 0)
+		booked = append(booked, BookedRow{
+			Row:          i + 1,
+			Filename:     filename,
+			Key:          BookingKey(invoiceNumber, cellString(values, 3), filename),
+			SourceSHA256: strings.ToLower(cellString(values, 20)),
+		})
+	}
+
+	return booked, nil
+}
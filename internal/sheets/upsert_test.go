@@ -0,0 +1,105 @@
+package sheets_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tools/internal/sheets"
+	"tools/internal/sheets/sheetstest"
+	"tools/pkg/models"
+	"tools/pkg/services"
+)
+
+func payable(number, vendor string, gross int64) *models.Invoice {
+	return &models.Invoice{InvoiceNumber: number, Type: "PAYABLE", Vendor: vendor, GrossAmount: gross, Currency: "EUR"}
+}
+
+func TestUpsertBatchResults(t *testing.T) {
+	ctx := context.Background()
+	backend := sheetstest.NewMemoryBackend()
+	service := sheets.NewServiceWithBackend(backend)
+
+	first := []sheets.BatchResult{
+		{Filename: "a.pdf", Invoice: payable("RE-1", "Muster GmbH", 10000), Status: "success"},
+		{Filename: "b.pdf", Error: errors.New("timeout"), Status: "error"},
+		{Filename: "c.pdf", Invoice: payable("RE-3", "Beispiel AG", 30000), Status: "success"},
+	}
+	if err := service.WriteBatchResults(ctx, first, "Kreditoren"); err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+
+	second := []sheets.BatchResult{
+		{Filename: "a.pdf", Invoice: payable("RE-1", "Muster GmbH", 12000), Status: "success"},
+		{Filename: "b.pdf", Invoice: payable("RE-2", "Neu KG", 20000), Status: "success"},
+		{Filename: "d.pdf", Invoice: payable("RE-4", "Vierte GmbH", 40000), Status: "success"},
+	}
+	updated, appended, err := service.UpsertBatchResults(ctx, second, "Kreditoren")
+	if err != nil {
+		t.Fatalf("UpsertBatchResults: %v", err)
+	}
+	if updated != 2 || appended != 1 {
+		t.Errorf("got updated=%d appended=%d, want 2 and 1", updated, appended)
+	}
+
+	tab := backend.Tab("Kreditoren")
+	if len(tab) != 5 {
+		t.Fatalf("expected header + 4 rows, got %d", len(tab))
+	}
+
+	checks := []struct {
+		row    int
+		number string
+		gross  float64
+	}{
+		{1, "RE-1", 120.0}, // updated in place
+		{2, "RE-2", 200.0}, // error row replaced by corrected invoice
+		{3, "RE-3", 300.0}, // untouched
+		{4, "RE-4", 400.0}, // appended
+	}
+	for _, c := range checks {
+		if tab[c.row][1] != c.number || tab[c.row][6] != c.gross {
+			t.Errorf("row %d: got number=%v gross=%v, want %s %.2f", c.row, tab[c.row][1], tab[c.row][6], c.number, c.gross)
+		}
+	}
+}
+
+func TestBookingKey(t *testing.T) {
+	if sheets.BookingKey(" RE-1 ", "Muster GmbH", "a.pdf") != sheets.BookingKey("re-1", "muster gmbh", "other.pdf") {
+		t.Error("expected key to ignore case, whitespace and file name when an invoice number is present")
+	}
+	if sheets.BookingKey("", "Muster GmbH", "a.pdf") == sheets.BookingKey("", "Muster GmbH", "b.pdf") {
+		t.Error("expected rows without invoice number to be keyed by file name")
+	}
+}
+
+func TestBookedRows(t *testing.T) {
+	ctx := context.Background()
+	backend := sheetstest.NewMemoryBackend()
+	service := sheets.NewServiceWithBackend(backend)
+
+	results := []sheets.BatchResult{
+		{Filename: "a.pdf", Invoice: payable("RE-1", "Muster GmbH", 10000), Booking: &services.DATEVBooking{SourceSHA256: "ABC123"}, Status: "success"},
+		{Filename: "b.pdf", Error: errors.New("timeout"), Status: "error"},
+		{Filename: "c.pdf", Invoice: payable("", "Beispiel AG", 30000), Status: "warning"},
+		{Filename: "d.pdf", Invoice: payable("RE-4", "Vierte GmbH", 40000), Status: "warning"},
+	}
+	if err := service.WriteBatchResults(ctx, results, "Kreditoren"); err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+
+	booked, err := service.BookedRows(ctx, "Kreditoren")
+	if err != nil {
+		t.Fatalf("BookedRows: %v", err)
+	}
+	if len(booked) != 2 {
+		t.Fatalf("expected the two rows with an invoice number, got %+v", booked)
+	}
+	want := sheets.BookedRow{Row: 2, Filename: "a.pdf", Key: sheets.BookingKey("RE-1", "Muster GmbH", "a.pdf"), SourceSHA256: "abc123"}
+	if booked[0] != want {
+		t.Errorf("booked[0] = %+v, want %+v", booked[0], want)
+	}
+	if booked[1].Row != 5 || booked[1].Key != sheets.BookingKey("RE-4", "Vierte GmbH", "") {
+		t.Errorf("booked[1] = %+v", booked[1])
+	}
+}
@@ -0,0 +1,168 @@
+// Package stats summarizes invoices read from a datev-batch sheet (Kreditoren or Debitoren)
+// into monthly totals for reporting.
+//
+// Amounts are aggregated in cents to avoid float rounding across many rows. Rows without an
+// invoice date (typically error rows) are assigned to the month they were processed in.
+package stats
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"tools/internal/reconciliation"
+)
+
+// Status values written to the sheet by datev-batch
+const (
+	StatusSuccess = "success"
+	StatusWarning = "warning"
+	StatusError   = "error"
+
+	// StatusSkipped and StatusSkippedDuplicate mark reminders without a fee and copies of a file or
+	// invoice booked in another row
+	StatusSkipped          = "skipped"
+	StatusSkippedDuplicate = "skipped-duplicate"
+)
+
+// Summary holds the aggregated figures for one sheet and month
+type Summary struct {
+	Sheet        string          `json:"sheet"`
+	Month        string          `json:"month"` // YYYY-MM
+	InvoiceCount int             `json:"invoice_count"`
+	NetCents     int64           `json:"net_amount_cents"`
+	VATCents     int64           `json:"vat_amount_cents"`
+	GrossCents   int64           `json:"gross_amount_cents"`
+	TaxKeys      []TaxKeyTotal   `json:"tax_keys"`
+	TopParties   []PartyTotal    `json:"top_counterparties"`
+	Status       StatusBreakdown `json:"status"`
+}
+
+// TaxKeyTotal aggregates the invoices booked with one tax key
+type TaxKeyTotal struct {
+	TaxKey     string `json:"tax_key"` // Empty when the row has no tax key
+	Count      int    `json:"count"`
+	NetCents   int64  `json:"net_amount_cents"`
+	VATCents   int64  `json:"vat_amount_cents"`
+	GrossCents int64  `json:"gross_amount_cents"`
+}
+
+// PartyTotal aggregates the spend (or revenue) for one vendor or customer
+type PartyTotal struct {
+	Name       string `json:"name"`
+	Count      int    `json:"count"`
+	GrossCents int64  `json:"gross_amount_cents"`
+}
+
+// StatusBreakdown counts the rows per datev-batch status
+type StatusBreakdown struct {
+	Success int `json:"success"`
+	Warning int `json:"warning"`
+	Error   int `json:"error"`
+	Skipped int `json:"skipped"` // Skipped reminders and duplicates
+	Unknown int `json:"unknown"` // Rows without a status, e.g. entered by hand
+}
+
+// Summarize aggregates the invoices dated in the given month. Error and skipped rows are only
+// counted in the status breakdown since they carry no usable amounts or repeat another row's. topN limits the counterparty list;
+// zero or less returns all counterparties.
+func Summarize(sheet string, invoices []reconciliation.InvoiceRow, month time.Time, topN int) Summary {
+	summary := Summary{
+		Sheet: sheet,
+		Month: month.Format("2006-01"),
+	}
+
+	taxKeys := make(map[string]*TaxKeyTotal)
+	parties := make(map[string]*PartyTotal)
+
+	for _, invoice := range invoices {
+		if !inMonth(invoiceMonthDate(invoice), month) {
+			continue
+		}
+
+		status := strings.ToLower(strings.TrimSpace(invoice.Status))
+		switch status {
+		case StatusSuccess:
+			summary.Status.Success++
+		case StatusWarning:
+			summary.Status.Warning++
+		case StatusError:
+			summary.Status.Error++
+			continue
+		case StatusSkipped, StatusSkippedDuplicate:
+			summary.Status.Skipped++
+			continue
+		default:
+			summary.Status.Unknown++
+		}
+
+		net, vat, gross := toCents(invoice.NetAmount), toCents(invoice.VATAmount), toCents(invoice.GrossAmount)
+		summary.InvoiceCount++
+		summary.NetCents += net
+		summary.VATCents += vat
+		summary.GrossCents += gross
+
+		taxKey := taxKeys[invoice.TaxKey]
+		if taxKey == nil {
+			taxKey = &TaxKeyTotal{TaxKey: invoice.TaxKey}
+			taxKeys[invoice.TaxKey] = taxKey
+		}
+		taxKey.Count++
+		taxKey.NetCents += net
+		taxKey.VATCents += vat
+		taxKey.GrossCents += gross
+
+		// Group counterparties case-insensitively but keep the first spelling for display
+		name := strings.TrimSpace(invoice.GetCounterParty())
+		partyKey := strings.ToLower(name)
+		party := parties[partyKey]
+		if party == nil {
+			party = &PartyTotal{Name: name}
+			parties[partyKey] = party
+		}
+		party.Count++
+		party.GrossCents += gross
+	}
+
+	for _, taxKey := range taxKeys {
+		summary.TaxKeys = append(summary.TaxKeys, *taxKey)
+	}
+	sort.Slice(summary.TaxKeys, func(i, j int) bool {
+		return summary.TaxKeys[i].TaxKey < summary.TaxKeys[j].TaxKey
+	})
+
+	for _, party := range parties {
+		summary.TopParties = append(summary.TopParties, *party)
+	}
+	sort.Slice(summary.TopParties, func(i, j int) bool {
+		a, b := summary.TopParties[i], summary.TopParties[j]
+		if a.GrossCents != b.GrossCents {
+			return a.GrossCents > b.GrossCents
+		}
+		return a.Name < b.Name
+	})
+	if topN > 0 && len(summary.TopParties) > topN {
+		summary.TopParties = summary.TopParties[:topN]
+	}
+
+	return summary
+}
+
+// invoiceMonthDate returns the date that decides which month an invoice belongs to
+func invoiceMonthDate(invoice reconciliation.InvoiceRow) time.Time {
+	if !invoice.Date.IsZero() {
+		return invoice.Date
+	}
+	return invoice.ProcessedAt
+}
+
+// inMonth reports whether date falls into the calendar month of month
+func inMonth(date, month time.Time) bool {
+	return !date.IsZero() && date.Year() == month.Year() && date.Month() == month.Month()
+}
+
+// toCents converts a sheet amount in euros to cents
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
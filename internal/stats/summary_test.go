@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"tools/internal/reconciliation"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestSummarize(t *testing.T) {
+	invoices := []reconciliation.InvoiceRow{
+		{Vendor: "Muster GmbH", Date: date(2024, 6, 3), NetAmount: 100, VATAmount: 19, GrossAmount: 119, TaxKey: "9", Status: "success", Type: "PAYABLE"},
+		{Vendor: "muster gmbh", Date: date(2024, 6, 20), NetAmount: 50.1, VATAmount: 9.52, GrossAmount: 59.62, TaxKey: "9", Status: "warning", Type: "PAYABLE"},
+		{Vendor: "Bäckerei Süß", Date: date(2024, 6, 11), NetAmount: 10, VATAmount: 0.7, GrossAmount: 10.7, TaxKey: "8", Status: "success", Type: "PAYABLE"},
+		{Vendor: "Hosting AG", Date: date(2024, 6, 30), NetAmount: 20, GrossAmount: 20, Type: "PAYABLE"},
+		// Error row without invoice date falls back to the processing date
+		{Status: "error", ProcessedAt: date(2024, 6, 15), Type: "PAYABLE"},
+		// Skipped reminders and copies keep out of the totals
+		{Vendor: "Muster GmbH", Date: date(2024, 6, 3), NetAmount: 100, VATAmount: 19, GrossAmount: 119, TaxKey: "9", Status: "skipped-duplicate", Type: "PAYABLE"},
+		{Vendor: "Hosting AG", Date: date(2024, 6, 25), Status: "skipped", Type: "PAYABLE"},
+		// Outside the month
+		{Vendor: "Muster GmbH", Date: date(2024, 7, 1), NetAmount: 1000, GrossAmount: 1190, TaxKey: "9", Status: "success", Type: "PAYABLE"},
+		{Status: "error", ProcessedAt: date(2024, 5, 31), Type: "PAYABLE"},
+	}
+
+	summary := Summarize("Kreditoren", invoices, date(2024, 6, 1), 2)
+
+	if summary.Month != "2024-06" || summary.Sheet != "Kreditoren" {
+		t.Errorf("unexpected header: %+v", summary)
+	}
+	if summary.InvoiceCount != 4 {
+		t.Errorf("InvoiceCount = %d, want 4", summary.InvoiceCount)
+	}
+	if summary.NetCents != 18010 || summary.VATCents != 2922 || summary.GrossCents != 20932 {
+		t.Errorf("totals = %d/%d/%d, want 18010/2922/20932", summary.NetCents, summary.VATCents, summary.GrossCents)
+	}
+
+	wantStatus := StatusBreakdown{Success: 2, Warning: 1, Error: 1, Skipped: 2, Unknown: 1}
+	if summary.Status != wantStatus {
+		t.Errorf("Status = %+v, want %+v", summary.Status, wantStatus)
+	}
+
+	if len(summary.TaxKeys) != 3 {
+		t.Fatalf("expected 3 tax keys, got %+v", summary.TaxKeys)
+	}
+	if tk := summary.TaxKeys[2]; tk.TaxKey != "9" || tk.Count != 2 || tk.GrossCents != 17862 {
+		t.Errorf("tax key 9 = %+v", tk)
+	}
+	if summary.TaxKeys[0].TaxKey != "" || summary.TaxKeys[0].Count != 1 {
+		t.Errorf("expected rows without tax key grouped under empty key, got %+v", summary.TaxKeys[0])
+	}
+
+	if len(summary.TopParties) != 2 {
+		t.Fatalf("expected top 2 counterparties, got %+v", summary.TopParties)
+	}
+	if top := summary.TopParties[0]; top.Name != "Muster GmbH" || top.Count != 2 || top.GrossCents != 17862 {
+		t.Errorf("top counterparty = %+v", top)
+	}
+	if summary.TopParties[1].Name != "Hosting AG" {
+		t.Errorf("second counterparty = %+v", summary.TopParties[1])
+	}
+}
+
+func TestSummarizeEmptyMonth(t *testing.T) {
+	summary := Summarize("Debitoren", nil, date(2024, 1, 1), 5)
+	if summary.InvoiceCount != 0 || summary.GrossCents != 0 || len(summary.TopParties) != 0 {
+		t.Errorf("expected empty summary, got %+v", summary)
+	}
+}
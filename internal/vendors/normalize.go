@@ -0,0 +1,79 @@
+package vendors
+
+import (
+	"strings"
+	"unicode"
+)
+
+// legalForms are company suffixes that do not distinguish vendors
+var legalForms = map[string]bool{
+	"gmbh": true, "mbh": true, "ag": true, "kg": true, "ohg": true, "ug": true, "gbr": true,
+	"se": true, "ev": true, "co": true, "kgaa": true, "sarl": true, "sàrl": true, "sa": true,
+	"sas": true, "bv": true, "nv": true, "ltd": true, "limited": true, "inc": true, "llc": true,
+	"plc": true, "corp": true, "haftungsbeschränkt": true, "und": true,
+}
+
+// domainSuffixes are stripped from names written as web addresses, e.g. "AMAZON.DE"
+var domainSuffixes = []string{".de", ".com", ".eu", ".net", ".org", ".at", ".ch", ".lu", ".io"}
+
+// NormalizeName reduces a vendor name to lowercase words without legal forms, web domains and
+// punctuation, e.g. "Amazon EU S.à r.l." -> "amazon eu"
+func NormalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "www.")
+	for _, suffix := range domainSuffixes {
+		name = strings.TrimSuffix(name, suffix)
+	}
+
+	// Join dotted abbreviations ("s.à r.l.", "e.v.") before splitting into words
+	name = strings.ReplaceAll(name, ".", "")
+	name = strings.NewReplacer("sà rl", "sarl", "sa rl", "sarl", "&", " ").Replace(name)
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var kept []string
+	for _, word := range words {
+		if legalForms[word] {
+			continue
+		}
+		kept = append(kept, word)
+	}
+
+	return strings.Join(kept, " ")
+}
+
+// NamesOverlap reports whether all words of the shorter normalized name start the longer one,
+// e.g. "amazon" and "amazon eu". Single-letter names never match.
+func NamesOverlap(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+
+	wordsA, wordsB := strings.Fields(a), strings.Fields(b)
+	if len(wordsA) > len(wordsB) {
+		wordsA, wordsB = wordsB, wordsA
+	}
+	if len(wordsA[0]) < 3 {
+		return false
+	}
+
+	for i, word := range wordsA {
+		if wordsB[i] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeVATID removes spaces and punctuation and uppercases a VAT ID, e.g. "de 123.456.789" -> "DE123456789"
+func NormalizeVATID(vatID string) string {
+	var normalized strings.Builder
+	for _, r := range strings.ToUpper(vatID) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			normalized.WriteRune(r)
+		}
+	}
+	return normalized.String()
+}
@@ -0,0 +1,225 @@
+// Package vendors keeps a local vendor master so the same business partner is booked, reported and
+// reconciled under one name, no matter how an invoice spells it ("Amazon", "Amazon EU Sarl",
+// "AMAZON.DE").
+//
+// The master is a JSON file (VENDOR_MASTER_FILE) holding a list of vendors, for example:
+//
+//	[
+//	  {"id": "V00001", "name": "Amazon EU S.à r.l.", "vat_id": "LU20260743",
+//	   "aliases": ["Amazon", "AMAZON.DE"], "reviewed": true},
+//	  {"id": "V00002", "name": "Hosting AG", "default_vat_rate": 19, "reviewed": true}
+//	]
+//
+// Names are matched by VAT ID first, then by normalized name or alias, and finally by a unique
+// fuzzy match. Unknown vendors are appended with reviewed=false so they can be checked by hand.
+package vendors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vendor is one entry of the vendor master
+type Vendor struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`             // Preferred name used in bookings and reports
+	VATID    string    `json:"vat_id,omitempty"` // USt-IdNr., e.g. DE123456789
+	Aliases  []string  `json:"aliases,omitempty"`
+	Reviewed bool      `json:"reviewed"` // False for vendors added automatically
+	AddedAt  time.Time `json:"added_at,omitempty"`
+
+	// DefaultVATRate is the VAT rate in percent the vendor always charges, e.g. 19, or 0 for a
+	// foreign supplier. Gross-only invoices of the vendor are split into net and VAT with it; nil if
+	// the rate varies.
+	DefaultVATRate *float64 `json:"default_vat_rate,omitempty"`
+}
+
+// Match describes how an invoice name was resolved
+type Match struct {
+	Vendor Vendor
+	By     string // "vat_id", "name", "fuzzy" or "new"
+}
+
+// Store is a vendor master backed by a JSON file. It is safe for concurrent use.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	vendors []Vendor
+}
+
+// LoadStore reads the vendor master from path. A missing file yields an empty store that
+// is created on the first new vendor.
+func LoadStore(path string) (*Store, error) {
+	const op = "LoadStore"
+
+	store := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read vendor master: %w", op, err)
+	}
+
+	if err := json.Unmarshal(data, &store.vendors); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse vendor master %s: %w", op, path, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, vendor := range store.vendors {
+		if vendor.ID == "" || vendor.Name == "" {
+			return nil, fmt.Errorf("%s: vendor entry without id or name in %s", op, path)
+		}
+		if seen[vendor.ID] {
+			return nil, fmt.Errorf("%s: duplicate vendor id %q in %s", op, vendor.ID, path)
+		}
+		seen[vendor.ID] = true
+		if rate := vendor.DefaultVATRate; rate != nil && (*rate < 0 || *rate >= 100) {
+			return nil, fmt.Errorf("%s: invalid default_vat_rate %v of vendor %q in %s", op, *rate, vendor.ID, path)
+		}
+	}
+
+	return store, nil
+}
+
+// Lookup finds the vendor for an invoice name and VAT ID without changing the store
+func (s *Store) Lookup(name, vatID string) (Match, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lookup(name, vatID)
+}
+
+// DefaultVATRate returns the default VAT rate of the vendor found for an invoice name and VAT ID
+// like Lookup, and false if the vendor is unknown or has none
+func (s *Store) DefaultVATRate(name, vatID string) (float64, bool) {
+	match, ok := s.Lookup(name, vatID)
+	if !ok || match.Vendor.DefaultVATRate == nil {
+		return 0, false
+	}
+	return *match.Vendor.DefaultVATRate, true
+}
+
+// Resolve finds the vendor like Lookup and appends unknown vendors for review. The store is
+// saved whenever a vendor was added.
+func (s *Store) Resolve(name, vatID string) (Match, error) {
+	const op = "Resolve"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if match, ok := s.lookup(name, vatID); ok {
+		return match, nil
+	}
+
+	vendor := Vendor{
+		ID:      s.nextID(),
+		Name:    strings.TrimSpace(name),
+		VATID:   NormalizeVATID(vatID),
+		AddedAt: time.Now(),
+	}
+	s.vendors = append(s.vendors, vendor)
+
+	if err := s.save(); err != nil {
+		s.vendors = s.vendors[:len(s.vendors)-1]
+		return Match{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return Match{Vendor: vendor, By: "new"}, nil
+}
+
+// Vendors returns a copy of all entries
+func (s *Store) Vendors() []Vendor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Vendor(nil), s.vendors...)
+}
+
+// lookup implements Lookup; the caller holds the lock
+func (s *Store) lookup(name, vatID string) (Match, bool) {
+	if vatID = NormalizeVATID(vatID); vatID != "" {
+		for _, vendor := range s.vendors {
+			if NormalizeVATID(vendor.VATID) == vatID {
+				return Match{Vendor: vendor, By: "vat_id"}, true
+			}
+		}
+	}
+
+	key := NormalizeName(name)
+	if key == "" {
+		return Match{}, false
+	}
+
+	for _, vendor := range s.vendors {
+		for _, candidate := range vendor.names() {
+			if NormalizeName(candidate) == key {
+				return Match{Vendor: vendor, By: "name"}, true
+			}
+		}
+	}
+
+	// Fuzzy matches are only trusted if exactly one vendor qualifies
+	var fuzzy []Vendor
+	for _, vendor := range s.vendors {
+		for _, candidate := range vendor.names() {
+			if NamesOverlap(NormalizeName(candidate), key) {
+				fuzzy = append(fuzzy, vendor)
+				break
+			}
+		}
+	}
+	if len(fuzzy) == 1 {
+		return Match{Vendor: fuzzy[0], By: "fuzzy"}, true
+	}
+
+	return Match{}, false
+}
+
+// nextID returns the next free sequential vendor ID; the caller holds the lock
+func (s *Store) nextID() string {
+	highest := 0
+	for _, vendor := range s.vendors {
+		var number int
+		if _, err := fmt.Sscanf(vendor.ID, "V%d", &number); err == nil && number > highest {
+			highest = number
+		}
+	}
+	return fmt.Sprintf("V%05d", highest+1)
+}
+
+// save writes the store atomically; the caller holds the lock
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.vendors, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode vendor master: %w", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create vendor master directory: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write vendor master: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write vendor master: %w", err)
+	}
+	return nil
+}
+
+// names returns the preferred name followed by the aliases
+func (v Vendor) names() []string {
+	return append([]string{v.Name}, v.Aliases...)
+}
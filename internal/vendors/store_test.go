@@ -0,0 +1,134 @@
+package vendors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Amazon", "amazon"},
+		{"Amazon EU S.à r.l.", "amazon eu"},
+		{"Amazon EU Sarl", "amazon eu"},
+		{"AMAZON.DE", "amazon"},
+		{"Muster GmbH & Co. KG", "muster"},
+		{"Bäckerei Süß e.V.", "bäckerei süß"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeName(tt.name); got != tt.want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStoreResolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vendors.json")
+	master := `[
+  {"id": "V00001", "name": "Amazon EU S.à r.l.", "vat_id": "LU20260743", "reviewed": true},
+  {"id": "V00002", "name": "Hosting AG", "aliases": ["hosting.de"], "reviewed": true}
+]`
+	if err := os.WriteFile(path, []byte(master), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := LoadStore(path)
+	if err != nil {
+		t.Fatalf("LoadStore failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		vatID string
+		want  string
+		by    string
+	}{
+		{"Amazon Services", "LU 2026 0743", "V00001", "vat_id"},
+		{"AMAZON EU SARL", "", "V00001", "name"},
+		{"AMAZON.DE", "", "V00001", "fuzzy"},
+		{"Hosting.de", "", "V00002", "name"},
+	}
+	for _, tt := range tests {
+		match, err := store.Resolve(tt.name, tt.vatID)
+		if err != nil {
+			t.Fatalf("Resolve(%q) failed: %v", tt.name, err)
+		}
+		if match.Vendor.ID != tt.want || match.By != tt.by {
+			t.Errorf("Resolve(%q) = %s by %s, want %s by %s", tt.name, match.Vendor.ID, match.By, tt.want, tt.by)
+		}
+	}
+
+	// Unknown vendors are appended for review and found again after reloading
+	match, err := store.Resolve("Neuer Lieferant GmbH", "DE123456789")
+	if err != nil {
+		t.Fatalf("Resolve of new vendor failed: %v", err)
+	}
+	if match.By != "new" || match.Vendor.ID != "V00003" || match.Vendor.Reviewed {
+		t.Errorf("unexpected new vendor %+v", match)
+	}
+
+	reloaded, err := LoadStore(path)
+	if err != nil {
+		t.Fatalf("reloading store failed: %v", err)
+	}
+	if match, ok := reloaded.Lookup("Neuer Lieferant", ""); !ok || match.Vendor.ID != "V00003" {
+		t.Errorf("expected new vendor after reload, got %+v (found %v)", match, ok)
+	}
+}
+
+func TestStoreDefaultVATRate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vendors.json")
+	master := `[
+  {"id": "V00001", "name": "Hosting AG", "default_vat_rate": 19, "reviewed": true},
+  {"id": "V00002", "name": "Cloud Services Ltd", "vat_id": "IE6388047V", "default_vat_rate": 0, "reviewed": true},
+  {"id": "V00003", "name": "Baumarkt GmbH", "reviewed": true}
+]`
+	if err := os.WriteFile(path, []byte(master), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := LoadStore(path)
+	if err != nil {
+		t.Fatalf("LoadStore failed: %v", err)
+	}
+
+	if rate, ok := store.DefaultVATRate("Hosting AG", ""); !ok || rate != 19 {
+		t.Errorf("DefaultVATRate(Hosting AG) = %v, %v, want 19", rate, ok)
+	}
+	if rate, ok := store.DefaultVATRate("", "IE 6388047V"); !ok || rate != 0 {
+		t.Errorf("DefaultVATRate by VAT ID = %v, %v, want 0", rate, ok)
+	}
+	if _, ok := store.DefaultVATRate("Baumarkt GmbH", ""); ok {
+		t.Error("expected no default rate for vendor without one")
+	}
+	if _, ok := store.DefaultVATRate("Unbekannt GmbH", ""); ok {
+		t.Error("expected no default rate for unknown vendor")
+	}
+
+	invalid := `[{"id": "V00001", "name": "Hosting AG", "default_vat_rate": 119}]`
+	if err := os.WriteFile(path, []byte(invalid), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadStore(path); err == nil {
+		t.Error("expected error for default_vat_rate of 119")
+	}
+}
+
+func TestStoreAmbiguousFuzzyMatch(t *testing.T) {
+	store, err := LoadStore(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadStore of missing file failed: %v", err)
+	}
+	store.vendors = []Vendor{
+		{ID: "V00001", Name: "Deutsche Bahn AG"},
+		{ID: "V00002", Name: "Deutsche Post AG"},
+	}
+
+	if match, ok := store.Lookup("Deutsche", ""); ok {
+		t.Errorf("expected no match for ambiguous name, got %+v", match)
+	}
+}
@@ -16,6 +16,17 @@ func main() {
 		log.Printf("Warning: Could not load .env file: %v", err)
 	}
 
+	// Apply the config file; variables from the environment or .env take precedence. Only a file
+	// given with --config must load, a broken default file is skipped with a warning.
+	configPath := cmd.ConfigPath(os.Args[1:])
+	configFile, err := config.ApplyFile(configPath)
+	if err != nil {
+		if configPath != "" {
+			log.Fatalf("Failed to load config file: %v", err)
+		}
+		log.Printf("Warning: Ignoring config file: %v", err)
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -33,9 +44,10 @@ func main() {
 
 	// Log application startup
 	log := logger.WithComponent("main")
-	log.Info().Msg("Starting Tools CLI application")
+	log.Info().Str("config_file", configFile).Msg("Starting Tools CLI application")
 
 	// Execute CLI commands
+	cmd.SetConfig(cfg, err)
 	cmd.Execute()
 
 	// Log application shutdown
@@ -7,14 +7,32 @@ type Invoice struct {
 	ID            string // Unique invoice identifier
 	InvoiceNumber string // Human-readable invoice number
 	Type          string // "RECEIVABLE" (customer invoice) or "PAYABLE" (supplier invoice)
+	TypeReasoning string // Why completion chose Type; empty if completion did not determine it
+	SubType       string // InvoiceSubTypePrepayment, InvoiceSubTypeFinal or InvoiceSubTypeReminder; empty for a regular invoice
+	Internal      bool   // Vendor and customer are both our company (intercompany, self-billing); Type is empty until confirmed
+
+	// Number of the prepayment invoice a final invoice deducts, if it quotes one
+	PrepaymentReference string
+
+	// Number of the invoice a reminder (Mahnung) is about, if it quotes one, and the dunning fee
+	// (Mahngebühr) in cents it charges on top
+	ReminderReference string
+	DunningFee        int64
 
 	// Parties
-	Vendor   string // Vendor/supplier name (for payable) or your company name (for receivable)
-	Customer string // Customer name (for receivable) or your company name (for payable)
+	Vendor          string // Vendor/supplier name (for payable) or your company name (for receivable)
+	Customer        string // Customer name (for receivable) or your company name (for payable)
+	VendorVATID     string // Vendor's VAT ID (USt-IdNr.) as printed on the invoice
+	CustomerVATID   string // Customer's VAT ID (USt-IdNr.) as printed on the invoice
+	CustomerAddress string // Postal address in the recipient block (Rechnungsempfänger) as printed on the invoice
+	CustomerEmail   string // Customer's e-mail address, to chase unpaid receivables
+	CustomerContact string // Customer's contact person (Ansprechpartner), to chase unpaid receivables
+	PartnerID       string // Vendor master ID of the counterparty; empty without a vendor master
 
 	// Dates
 	IssueDate   time.Time  // Date invoice was issued
 	DueDate     time.Time  // Payment due date
+	ServiceDate time.Time  // Delivery/service date (Leistungsdatum), determines the VAT tax point
 	PaymentDate *time.Time // Actual payment date (nil if unpaid)
 
 	// Amounts (store as cents/smallest currency unit to avoid float issues)
@@ -23,13 +41,107 @@ type Invoice struct {
 	GrossAmount int64  // Total amount (net + VAT)
 	Currency    string // Currency code (EUR, USD, etc.)
 
+	// Vendor is a Kleinunternehmer (§19 UStG): the invoice cites the exemption and charges no VAT,
+	// so it is booked with tax key 0 and no input tax
+	SmallBusiness bool
+
+	// How net and VAT were back-calculated from the gross amount because the invoice showed no VAT:
+	// VATRateFromText, VATRateFromVendor or VATRateAssumed; empty if they were extracted
+	VATInferredFrom string
+
+	// Ceiling in cents (MAX_INVOICE_AMOUNT) the gross amount exceeds, most likely an OCR or parse
+	// error that must be confirmed before booking; 0 if the amount is plausible
+	ExceededAmountCeiling int64
+
 	// Status
 	IsPaid bool // Payment status flag
 
+	// Positions, if the document lists them
+	LineItems []LineItem
+
+	// Installments if the invoice splits the payment (e.g. 50% now, 50% in 30 days); nil for a
+	// single payment of GrossAmount on DueDate, see Installments
+	PaymentSchedule []PaymentInstallment
+
+	// Scan quality reported by Document AI; nil if it reported neither quality scores nor orientation
+	InputQuality *InputQuality
+
 	// Optional metadata
-	Reference        string    // External reference number
+	PurchaseOrder     string   // Purchase order number (Bestellnummer), used for payment matching
+	CustomerReference string   // Customer/order reference (Ihr Zeichen, Kundenreferenz), used in booking texts
 	Description      string    // Brief description/notes
 	AccountingSummary string   // German accounting summary describing goods/services and suggested categorization
+	OCRText          string    // Full OCR text completion extracted from; only kept if requested, for debugging extractions
 	CreatedAt        time.Time // Record creation timestamp
 	UpdatedAt        time.Time // Last update timestamp
-}
\ No newline at end of file
+}
+
+// Invoice sub-types that need special booking
+const (
+	InvoiceSubTypePrepayment = "PREPAYMENT" // Anzahlungs- or Abschlagsrechnung, booked to a prepayment account
+	InvoiceSubTypeFinal      = "FINAL"      // Schlussrechnung that deducts earlier prepayments
+	InvoiceSubTypeReminder   = "REMINDER"   // Mahnung or Zahlungserinnerung for an invoice already booked; only a dunning fee is booked
+)
+
+// Sources of the VAT rate used to split a gross-only invoice, see Invoice.VATInferredFrom
+const (
+	VATRateFromText   = "ocr_text"      // The only VAT rate named in the OCR text
+	VATRateFromVendor = "vendor_master" // Default VAT rate of the vendor in the vendor master
+	VATRateAssumed    = "assumed"       // ASSUMED_VAT_RATE
+)
+
+// Installments returns the payment schedule, or a single installment of GrossAmount due on DueDate
+// if the invoice has none. Consumers that create per-payment entries should use this rather than
+// GrossAmount and DueDate.
+func (inv *Invoice) Installments() []PaymentInstallment {
+	if len(inv.PaymentSchedule) > 0 {
+		return inv.PaymentSchedule
+	}
+	return []PaymentInstallment{{Amount: inv.GrossAmount, DueDate: inv.DueDate}}
+}
+
+// InputQuality summarizes the page-level quality signals of a scanned invoice
+type InputQuality struct {
+	Score        float32  // Lowest page quality score, 0..1 where 1 is perfect; 1 if no page was scored
+	Defects      []string // Defects detected with at least LowInputQualityScore confidence, e.g. "blurry" or "dark"
+	RotatedPages []int    // 1-based pages that were not upright in the scan
+}
+
+// LowInputQualityScore is the quality score below which a scan should be redone
+const LowInputQualityScore = 0.5
+
+// IsLow reports whether the scan is poor enough that its extraction should not be trusted without a
+// re-scan or manual check
+func (q *InputQuality) IsLow() bool {
+	return q != nil && (q.Score < LowInputQualityScore || len(q.Defects) > 0)
+}
+
+// PaymentInstallment is one payment of an invoice's payment schedule
+type PaymentInstallment struct {
+	Amount      int64     // Installment amount in cents
+	DueDate     time.Time // Zero if the invoice gives no date for this installment
+	Description string    // e.g. "Anzahlung 50%" or "Restzahlung", as printed
+}
+
+// LineItem is one position of an invoice
+type LineItem struct {
+	Description string
+	Quantity    float64  // 0 if not printed
+	NetAmount   int64    // Line amount in cents
+	VATRate     *float64 // VAT rate in percent printed for this line; nil if the line shows none
+}
+
+// FieldChange is one invoice field changed by a processing step, e.g. completion filling in a
+// vendor that Document AI did not extract
+type FieldChange struct {
+	Field  string `json:"field"`  // Field name, e.g. "vendor" or "gross_amount"
+	Kind   string `json:"kind"`   // FieldFilled or FieldOverwritten
+	Before string `json:"before"` // Formatted value before the step; empty if the field was empty
+	After  string `json:"after"`  // Formatted value after the step
+}
+
+// Kinds of FieldChange
+const (
+	FieldFilled      = "filled"
+	FieldOverwritten = "overwritten"
+)
@@ -18,6 +18,12 @@ type BookingService interface {
 
 	// GenerateBookingFromPDFWithType processes PDF with manual type override
 	GenerateBookingFromPDFWithType(ctx context.Context, pdfData io.Reader, typeOverride string) (*DATEVBooking, *models.Invoice, error)
+
+	// GenerateBookingFromPDFWithConfidence is GenerateBookingFromPDFWithType returning per-field confidence scores
+	GenerateBookingFromPDFWithConfidence(ctx context.Context, pdfData io.Reader, typeOverride string) (*DATEVBooking, *models.Invoice, map[string]float32, error)
+
+	// Close releases the clients the service created; injected clients are left to their owner
+	Close() error
 }
 
 // DATEVBooking represents a complete DATEV accounting entry
@@ -32,6 +38,9 @@ type DATEVBooking struct {
 	BookingDate     time.Time `json:"booking_date"`   // Buchungsdatum
 	DocumentNumber  string  `json:"document_number"`  // Belegnummer
 	AccountingPeriod string `json:"accounting_period"` // Buchungsperiode (MMYYYY)
+
+	// Number of the prepayment invoice a final invoice (Schlussrechnung) deducts; empty otherwise
+	PrepaymentReference string `json:"prepayment_reference,omitempty"`
 	
 	// Additional information
 	Explanation     string `json:"explanation"`      // Erläuterung der Buchung
@@ -40,8 +49,66 @@ type DATEVBooking struct {
 	DebitAccountName  string `json:"debit_account_name"`  // Name des Sollkontos
 	CreditAccountName string `json:"credit_account_name"` // Name des Habenkontos
 	TaxKeyDescription string `json:"tax_key_description"` // Beschreibung des Steuerschlüssels
+	Warnings          []string `json:"warnings,omitempty"` // Plausibility problems that need manual review
+
+	// Template is set when the AI booking step failed: the invoice data is filled in but the
+	// accounts and tax key are blank and must be completed by hand
+	Template bool `json:"template,omitempty"`
+
+	// Split bookings for invoices with several VAT rates, one per rate; empty for single-rate invoices
+	Splits []BookingSplit `json:"splits,omitempty"`
+
+	// Balanced posting lines with the VAT on its own account, for DATEV setups in which the tax key
+	// does not post the VAT; empty unless explicit VAT postings are enabled
+	Postings []Posting `json:"postings,omitempty"`
+
+	// Whether Amount and the exported split amounts are gross (AmountBasisGross, also if empty) or
+	// net (AmountBasisNet)
+	AmountBasis string `json:"amount_basis,omitempty"`
+
+	// Fields the completion step filled in or overwrote in the Document AI extraction
+	CompletionChanges []models.FieldChange `json:"completion_changes,omitempty"`
+
+	// Source document, so an auditor can find the PDF a booking was generated from
+	SourceFile   string `json:"source_file,omitempty"`   // File name of the PDF; empty if the service only got its content
+	SourceSHA256 string `json:"source_sha256,omitempty"` // Hex SHA-256 of the PDF file
 	
 	// Metadata
 	GeneratedAt   time.Time `json:"generated_at"`   // Timestamp of generation
 	ContenrahmenType string `json:"kontenrahmen_type"` // SKR03 or SKR04
-}
\ No newline at end of file
+}
+
+// BookingSplit is the part of a mixed-rate invoice booked with one tax key
+type BookingSplit struct {
+	Amount    float64 `json:"amount"`     // Bruttobetrag in EUR
+	TaxKey    string  `json:"tax_key"`    // Steuerschlüssel
+	VATRate   float64 `json:"vat_rate"`   // Steuersatz in Prozent
+	NetAmount float64 `json:"net_amount"` // Nettobetrag in EUR
+	VATAmount float64 `json:"vat_amount"` // MwSt in EUR
+}
+
+// Amount bases of a booking (BOOKING_AMOUNT_BASIS). DATEV reads the amount of a line with a tax
+// key as gross and derives the VAT from it, so a net amount must only be combined with a tax key
+// where the VAT is posted separately, e.g. by explicit VAT postings; otherwise DATEV derives the VAT
+// from the net amount and books too little of it.
+const (
+	AmountBasisGross = "gross" // Bruttobetrag (Sollstellung), the VAT is derived by the tax key (default)
+	AmountBasisNet   = "net"   // Nettobetrag, for setups that book the VAT on lines of their own
+)
+
+// Sides of a posting line
+const (
+	PostingDebit  = "S" // Soll
+	PostingCredit = "H" // Haben
+)
+
+// Posting is one line of a booking whose VAT is posted explicitly instead of by tax key. The lines of
+// a booking balance: the Soll amounts sum to the Haben amounts.
+type Posting struct {
+	Account     string  `json:"account"`                // Konto
+	AccountName string  `json:"account_name,omitempty"` // Name des Kontos
+	Side        string  `json:"side"`                   // PostingDebit or PostingCredit
+	Amount      float64 `json:"amount"`                 // Betrag in EUR, never negative
+	VATRate     float64 `json:"vat_rate,omitempty"`     // Steuersatz der Netto- oder Steuerzeile in Prozent
+	VAT         bool    `json:"vat,omitempty"`          // The line posts the VAT (Vorsteuer or Umsatzsteuer)
+}